@@ -0,0 +1,50 @@
+// acp-replay: an ACP agent that plays back a recorded fixture over stdio.
+// Point a driver's launch_command at it to run flows without a live model:
+//
+//	acp-replay [--realtime] <fixture.json>
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpreplay"
+)
+
+func main() {
+	fs := flag.NewFlagSet("acp-replay", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	realtime := fs.Bool("realtime", false, "wait for recorded offsets between steps")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: acp-replay [--realtime] <fixture.json>")
+		os.Exit(2)
+	}
+
+	fixture, err := acpreplay.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	var opts []acpreplay.Option
+	if *realtime {
+		opts = append(opts, acpreplay.WithRealtime())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := acpreplay.Serve(ctx, fixture, os.Stdin, os.Stdout, opts...); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
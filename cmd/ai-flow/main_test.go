@@ -105,12 +105,12 @@ func TestExecutorCommandForwardsFlags(t *testing.T) {
 		runMCPServe:    func([]string) error { return nil },
 		runOrchestrate: func([]string) error { return nil },
 	})
-	cmd.SetArgs([]string{"executor", "--nats-url", "nats://local", "--agents", "claude,codex", "--max-concurrent", "4"})
+	cmd.SetArgs([]string{"executor", "--nats-url", "nats://local", "--nats-creds", "/tmp/creds.json", "--worker-id", "exec-1", "--agents", "claude,codex", "--max-concurrent", "4", "--labels", "gpu=true"})
 
 	if err := cmd.Execute(); err != nil {
 		t.Fatalf("Execute() error = %v", err)
 	}
-	want := []string{"--nats-url", "nats://local", "--agents", "claude,codex", "--max-concurrent", "4"}
+	want := []string{"--nats-url", "nats://local", "--nats-creds", "/tmp/creds.json", "--worker-id", "exec-1", "--agents", "claude,codex", "--max-concurrent", "4", "--labels", "gpu=true"}
 	if !reflect.DeepEqual(gotArgs, want) {
 		t.Fatalf("executor args = %#v, want %#v", gotArgs, want)
 	}
@@ -345,3 +345,195 @@ func TestProfileCommandForwardsArgs(t *testing.T) {
 		t.Fatalf("profile args = %#v, want %#v", gotArgs, want)
 	}
 }
+
+func TestDriverCommandForwardsArgs(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+	cmd := newRootCmd(commandDeps{
+		out:     &bytes.Buffer{},
+		err:     &bytes.Buffer{},
+		version: versionString,
+		runDriver: func(args []string) error {
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	})
+	cmd.SetArgs([]string{"driver", "doctor", "codex-acp", "--json"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"doctor", "codex-acp", "--json"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("driver args = %#v, want %#v", gotArgs, want)
+	}
+}
+
+func TestTraceCommandForwardsArgs(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+	cmd := newRootCmd(commandDeps{
+		out:     &bytes.Buffer{},
+		err:     &bytes.Buffer{},
+		version: versionString,
+		runTrace: func(args []string) error {
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	})
+	cmd.SetArgs([]string{"trace", "export", "--run", "42", "--out", "fixture.json"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"export", "--run", "42", "--out", "fixture.json"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("trace args = %#v, want %#v", gotArgs, want)
+	}
+}
+
+func TestDBCommandForwardsArgs(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+	cmd := newRootCmd(commandDeps{
+		out:     &bytes.Buffer{},
+		err:     &bytes.Buffer{},
+		version: versionString,
+		runDB: func(args []string) error {
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	})
+	cmd.SetArgs([]string{"db", "prune", "--dry-run"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"prune", "--dry-run"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("db args = %#v, want %#v", gotArgs, want)
+	}
+}
+
+func TestBackupCommandForwardsArgs(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+	cmd := newRootCmd(commandDeps{
+		out:     &bytes.Buffer{},
+		err:     &bytes.Buffer{},
+		version: versionString,
+		runBackup: func(args []string) error {
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	})
+	cmd.SetArgs([]string{"backup", "create", "--exclude-secrets"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"create", "--exclude-secrets"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("backup args = %#v, want %#v", gotArgs, want)
+	}
+}
+
+func TestTokenCommandForwardsArgs(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+	cmd := newRootCmd(commandDeps{
+		out:     &bytes.Buffer{},
+		err:     &bytes.Buffer{},
+		version: versionString,
+		runToken: func(args []string) error {
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	})
+	cmd.SetArgs([]string{"token", "create", "--name", "ci", "--scopes", "issues:write"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"create", "--name", "ci", "--scopes", "issues:write"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("token args = %#v, want %#v", gotArgs, want)
+	}
+}
+
+func TestAuditCommandForwardsArgs(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+	cmd := newRootCmd(commandDeps{
+		out:     &bytes.Buffer{},
+		err:     &bytes.Buffer{},
+		version: versionString,
+		runAudit: func(args []string) error {
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	})
+	cmd.SetArgs([]string{"audit", "verify", "--json"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"verify", "--json"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("audit args = %#v, want %#v", gotArgs, want)
+	}
+}
+
+func TestProvenanceCommandForwardsArgs(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+	cmd := newRootCmd(commandDeps{
+		out:     &bytes.Buffer{},
+		err:     &bytes.Buffer{},
+		version: versionString,
+		runProvenance: func(args []string) error {
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	})
+	cmd.SetArgs([]string{"provenance", "verify", "3f786850e387", "--json"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"verify", "3f786850e387", "--json"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("provenance args = %#v, want %#v", gotArgs, want)
+	}
+}
+
+func TestSecretCommandForwardsArgs(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+	cmd := newRootCmd(commandDeps{
+		out:     &bytes.Buffer{},
+		err:     &bytes.Buffer{},
+		version: versionString,
+		runSecret: func(args []string) error {
+			gotArgs = append([]string(nil), args...)
+			return nil
+		},
+	})
+	cmd.SetArgs([]string{"secret", "set", "--name", "NPM_TOKEN", "--project", "3"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"set", "--name", "NPM_TOKEN", "--project", "3"}
+	if !reflect.DeepEqual(gotArgs, want) {
+		t.Fatalf("secret args = %#v, want %#v", gotArgs, want)
+	}
+}
@@ -23,6 +23,14 @@ type commandDeps struct {
 	runOrchestrate func([]string) error
 	runRuntime     func([]string) error
 	runProfile     func([]string) error
+	runDriver      func([]string) error
+	runTrace       func([]string) error
+	runDB          func([]string) error
+	runBackup      func([]string) error
+	runToken       func([]string) error
+	runAudit       func([]string) error
+	runSecret      func([]string) error
+	runProvenance  func([]string) error
 }
 
 func defaultCommandDeps() commandDeps {
@@ -37,6 +45,14 @@ func defaultCommandDeps() commandDeps {
 		runOrchestrate: appcmd.RunOrchestrate,
 		runRuntime:     appcmd.RunRuntime,
 		runProfile:     appcmd.RunProfile,
+		runDriver:      appcmd.RunDriver,
+		runTrace:       appcmd.RunTrace,
+		runDB:          appcmd.RunDB,
+		runBackup:      appcmd.RunBackup,
+		runToken:       appcmd.RunToken,
+		runAudit:       appcmd.RunAudit,
+		runSecret:      appcmd.RunSecret,
+		runProvenance:  appcmd.RunProvenance,
 	}
 }
 
@@ -71,6 +87,14 @@ func newRootCmd(deps commandDeps) *cobra.Command {
 		newOrchestrateCmd(deps),
 		newRuntimeCmd(deps),
 		newProfileCmd(deps),
+		newDriverCmd(deps),
+		newTraceCmd(deps),
+		newDBCmd(deps),
+		newBackupCmd(deps),
+		newTokenCmd(deps),
+		newAuditCmd(deps),
+		newProvenanceCmd(deps),
+		newSecretCmd(deps),
 	)
 	return rootCmd
 }
@@ -107,29 +131,44 @@ func newServerCmd(deps commandDeps) *cobra.Command {
 
 func newExecutorCmd(deps commandDeps) *cobra.Command {
 	var natsURL string
+	var natsCreds string
+	var workerID string
 	var agents string
+	var labels string
 	var maxConcurrent int
 	cmd := &cobra.Command{
 		Use:   "executor",
 		Short: "Run the executor worker",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			forwardArgs := make([]string, 0, 6)
+			forwardArgs := make([]string, 0, 8)
 			if cmd.Flags().Changed("nats-url") {
 				forwardArgs = append(forwardArgs, "--nats-url", natsURL)
 			}
+			if cmd.Flags().Changed("nats-creds") {
+				forwardArgs = append(forwardArgs, "--nats-creds", natsCreds)
+			}
+			if cmd.Flags().Changed("worker-id") {
+				forwardArgs = append(forwardArgs, "--worker-id", workerID)
+			}
 			if cmd.Flags().Changed("agents") {
 				forwardArgs = append(forwardArgs, "--agents", agents)
 			}
 			if cmd.Flags().Changed("max-concurrent") {
 				forwardArgs = append(forwardArgs, "--max-concurrent", strconv.Itoa(maxConcurrent))
 			}
+			if cmd.Flags().Changed("labels") {
+				forwardArgs = append(forwardArgs, "--labels", labels)
+			}
 			return deps.runExecutor(forwardArgs)
 		},
 	}
 	cmd.Flags().StringVar(&natsURL, "nats-url", "", "NATS server URL")
+	cmd.Flags().StringVar(&natsCreds, "nats-creds", "", "Credentials file generated by an embedded NATS server")
+	cmd.Flags().StringVar(&workerID, "worker-id", "", "Stable worker identity (defaults to <hostname>-<pid>)")
 	cmd.Flags().StringVar(&agents, "agents", "", "Comma-separated agent types")
 	cmd.Flags().IntVar(&maxConcurrent, "max-concurrent", 2, "Maximum concurrent executions")
+	cmd.Flags().StringVar(&labels, "labels", "", "Comma-separated key=value routing labels (e.g. gpu=true,region=eu)")
 	return cmd
 }
 
@@ -235,3 +274,99 @@ func newProfileCmd(deps commandDeps) *cobra.Command {
 	}
 	return cmd
 }
+
+func newDriverCmd(deps commandDeps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                "driver",
+		Short:              "Inspect agent drivers (doctor <driver-id>)",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deps.runDriver(args)
+		},
+	}
+	return cmd
+}
+
+func newTraceCmd(deps commandDeps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                "trace",
+		Short:              "Record or export ACP replay fixtures (record|export)",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deps.runTrace(args)
+		},
+	}
+	return cmd
+}
+
+func newDBCmd(deps commandDeps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                "db",
+		Short:              "Maintain the runtime database (migrate|prune)",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deps.runDB(args)
+		},
+	}
+	return cmd
+}
+
+func newBackupCmd(deps commandDeps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                "backup",
+		Short:              "Back up or restore the data directory (create|list|restore)",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deps.runBackup(args)
+		},
+	}
+	return cmd
+}
+
+func newTokenCmd(deps commandDeps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                "token",
+		Short:              "Manage API tokens for automation (create|list|revoke)",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deps.runToken(args)
+		},
+	}
+	return cmd
+}
+
+func newAuditCmd(deps commandDeps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                "audit",
+		Short:              "Verify the hash-chained audit journal and files (verify)",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deps.runAudit(args)
+		},
+	}
+	return cmd
+}
+
+func newProvenanceCmd(deps commandDeps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                "provenance",
+		Short:              "Verify signed provenance of agent-produced commits (verify <sha>)",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deps.runProvenance(args)
+		},
+	}
+	return cmd
+}
+
+func newSecretCmd(deps commandDeps) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                "secret",
+		Short:              "Manage encrypted vault secrets injected into agents (set|list|delete)",
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return deps.runSecret(args)
+		},
+	}
+	return cmd
+}
@@ -167,6 +167,11 @@ func (c *Client) Initialize(ctx context.Context, caps ClientCapabilities) error
 	return nil
 }
 
+// AgentCapabilities returns the capabilities the agent advertised during initialize.
+func (c *Client) AgentCapabilities() acpproto.AgentCapabilities {
+	return c.agentCaps
+}
+
 // SupportsSSEMCP reports whether the agent advertised SSE MCP capability.
 func (c *Client) SupportsSSEMCP() bool {
 	return c.agentCaps.McpCapabilities.Sse
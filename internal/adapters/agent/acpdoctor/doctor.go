@@ -0,0 +1,411 @@
+// Package acpdoctor runs a scripted ACP conversation against a driver and
+// reports which protocol features it actually supports.
+package acpdoctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	acpproto "github.com/coder/acp-go-sdk"
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+	"github.com/yoke233/zhanggui/internal/adapters/sandbox"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+const (
+	defaultStepTimeout = 2 * time.Minute
+	cancelGrace        = 15 * time.Second
+	cancelDelay        = 2 * time.Second
+
+	inputFileName  = "doctor-input.txt"
+	outputFileName = "doctor-output.txt"
+	inputMarker    = "AI_FLOW_DOCTOR_INPUT"
+	terminalMarker = "ai-flow-doctor"
+)
+
+// Check names, in the order they run.
+const (
+	CheckInitialize  = "initialize"
+	CheckNewSession  = "new_session"
+	CheckPrompt      = "prompt"
+	CheckFSRead      = "fs_read"
+	CheckFSWrite     = "fs_write"
+	CheckTerminal    = "terminal"
+	CheckPermission  = "permission"
+	CheckCancel      = "cancel"
+	CheckLoadSession = "load_session"
+)
+
+// Status is the outcome of a single check.
+type Status string
+
+const (
+	StatusPass        Status = "pass"
+	StatusFail        Status = "fail"
+	StatusUnsupported Status = "unsupported"
+	// StatusNotObserved means the agent completed the prompt without using the feature.
+	StatusNotObserved Status = "not_observed"
+	StatusSkipped     Status = "skipped"
+)
+
+// CheckResult is one row of the doctor report.
+type CheckResult struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+}
+
+// Matrix is the capability matrix observed during the conversation.
+type Matrix struct {
+	FSRead            bool `json:"fs_read"`
+	FSWrite           bool `json:"fs_write"`
+	Terminal          bool `json:"terminal"`
+	Permission        bool `json:"permission"`
+	PermissionOptions bool `json:"permission_options"`
+	Cancel            bool `json:"cancel"`
+	LoadSession       bool `json:"load_session"`
+	MCPSSE            bool `json:"mcp_sse"`
+	MCPHTTP           bool `json:"mcp_http"`
+	ImagePrompt       bool `json:"image_prompt"`
+}
+
+// Report is the result of a doctor run.
+type Report struct {
+	DriverID        string                  `json:"driver_id"`
+	Command         string                  `json:"command"`
+	Args            []string                `json:"args,omitempty"`
+	StartedAt       time.Time               `json:"started_at"`
+	DurationMS      int64                   `json:"duration_ms"`
+	Checks          []CheckResult           `json:"checks"`
+	Matrix          Matrix                  `json:"matrix"`
+	CapabilitiesMax core.DriverCapabilities `json:"capabilities_max"`
+	Warnings        []string                `json:"warnings,omitempty"`
+	OK              bool                    `json:"ok"`
+}
+
+// Check returns the result for name, or nil when the check did not run.
+func (r *Report) Check(name string) *CheckResult {
+	if r == nil {
+		return nil
+	}
+	for i := range r.Checks {
+		if r.Checks[i].Name == name {
+			return &r.Checks[i]
+		}
+	}
+	return nil
+}
+
+// Config describes the driver under test.
+type Config struct {
+	DriverID string
+	Driver   core.DriverConfig
+
+	// Sandbox prepares the launch environment exactly like a real run. Optional.
+	Sandbox sandbox.Sandbox
+
+	// WorkDir is the session working directory. A temp dir is used when empty.
+	WorkDir string
+
+	// StepTimeout bounds each protocol step. Default: 2m.
+	StepTimeout time.Duration
+}
+
+// Run launches the driver and executes the scripted conversation. Protocol
+// failures are reported as failed checks; the error is reserved for invalid
+// configuration or local setup problems.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	driverID := strings.TrimSpace(cfg.DriverID)
+	if driverID == "" {
+		driverID = strings.TrimSpace(cfg.Driver.ID)
+	}
+	if strings.TrimSpace(cfg.Driver.LaunchCommand) == "" {
+		return nil, errors.New("driver doctor: launch command is required")
+	}
+	if cfg.StepTimeout <= 0 {
+		cfg.StepTimeout = defaultStepTimeout
+	}
+
+	workDir := strings.TrimSpace(cfg.WorkDir)
+	if workDir == "" {
+		dir, err := os.MkdirTemp("", "ai-flow-doctor-*")
+		if err != nil {
+			return nil, fmt.Errorf("driver doctor: create work dir: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		workDir = dir
+	}
+	if err := os.WriteFile(filepath.Join(workDir, inputFileName), []byte(inputMarker+"\n"), 0o644); err != nil {
+		return nil, fmt.Errorf("driver doctor: seed input file: %w", err)
+	}
+
+	d := &doctor{
+		cfg:     cfg,
+		workDir: workDir,
+		report: &Report{
+			DriverID:        driverID,
+			Command:         cfg.Driver.LaunchCommand,
+			Args:            append([]string(nil), cfg.Driver.LaunchArgs...),
+			StartedAt:       time.Now().UTC(),
+			CapabilitiesMax: cfg.Driver.CapabilitiesMax,
+		},
+		recorder: newRecorder(workDir),
+	}
+	d.run(ctx, driverID)
+	d.finish()
+	return d.report, nil
+}
+
+type doctor struct {
+	cfg      Config
+	workDir  string
+	report   *Report
+	recorder *recorder
+}
+
+func (d *doctor) run(ctx context.Context, driverID string) {
+	remaining := []string{CheckNewSession, CheckPrompt, CheckFSRead, CheckFSWrite, CheckTerminal, CheckPermission, CheckCancel, CheckLoadSession}
+
+	launch := acpclient.LaunchConfig{
+		Command: d.cfg.Driver.LaunchCommand,
+		Args:    append([]string(nil), d.cfg.Driver.LaunchArgs...),
+		WorkDir: d.workDir,
+		Env:     acpclient.CloneEnv(d.cfg.Driver.Env),
+	}
+	if d.cfg.Sandbox != nil {
+		profile := &core.AgentProfile{
+			ID:       "doctor-" + driverID,
+			DriverID: driverID,
+			Driver:   d.cfg.Driver,
+			Role:     core.RoleWorker,
+		}
+		prepared, err := d.cfg.Sandbox.Prepare(ctx, sandbox.PrepareInput{
+			Profile: profile,
+			Launch:  launch,
+			Scope:   fmt.Sprintf("doctor-%s-%d", driverID, time.Now().UnixNano()),
+		})
+		if err != nil {
+			d.add(CheckInitialize, StatusFail, fmt.Sprintf("sandbox prepare: %v", err), 0)
+			d.skip(remaining, "initialize failed")
+			return
+		}
+		launch = prepared
+	}
+
+	started := time.Now()
+	client, err := acpclient.New(launch, d.recorder, acpclient.WithEventHandler(d.recorder))
+	if err != nil {
+		d.add(CheckInitialize, StatusFail, fmt.Sprintf("launch: %v", err), time.Since(started))
+		d.skip(remaining, "initialize failed")
+		return
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = client.Close(closeCtx)
+	}()
+
+	stepCtx, cancel := context.WithTimeout(ctx, d.cfg.StepTimeout)
+	err = client.Initialize(stepCtx, acpclient.ClientCapabilities{FSRead: true, FSWrite: true, Terminal: true})
+	cancel()
+	if err != nil {
+		d.add(CheckInitialize, StatusFail, err.Error(), time.Since(started))
+		d.skip(remaining, "initialize failed")
+		return
+	}
+	caps := client.AgentCapabilities()
+	d.report.Matrix.MCPSSE = caps.McpCapabilities.Sse
+	d.report.Matrix.MCPHTTP = caps.McpCapabilities.Http
+	d.report.Matrix.ImagePrompt = caps.PromptCapabilities.Image
+	d.add(CheckInitialize, StatusPass, fmt.Sprintf("loadSession=%t mcp.sse=%t mcp.http=%t", caps.LoadSession, caps.McpCapabilities.Sse, caps.McpCapabilities.Http), time.Since(started))
+
+	started = time.Now()
+	stepCtx, cancel = context.WithTimeout(ctx, d.cfg.StepTimeout)
+	sessionID, err := client.NewSession(stepCtx, acpproto.NewSessionRequest{Cwd: d.sessionCwd(launch), McpServers: []acpproto.McpServer{}})
+	cancel()
+	if err != nil {
+		d.add(CheckNewSession, StatusFail, err.Error(), time.Since(started))
+		d.skip(remaining[1:], "new session failed")
+		return
+	}
+	d.add(CheckNewSession, StatusPass, "session "+string(sessionID), time.Since(started))
+
+	d.runPrompt(ctx, client, sessionID)
+	d.runCancel(ctx, client, sessionID)
+	d.runLoadSession(ctx, client, sessionID, caps.LoadSession, launch)
+}
+
+func (d *doctor) runPrompt(ctx context.Context, client *acpclient.Client, sessionID acpproto.SessionId) {
+	started := time.Now()
+	stepCtx, cancel := context.WithTimeout(ctx, d.cfg.StepTimeout)
+	result, err := client.PromptText(stepCtx, sessionID, scriptedPrompt())
+	cancel()
+	if err != nil {
+		d.add(CheckPrompt, StatusFail, err.Error(), time.Since(started))
+	} else {
+		d.add(CheckPrompt, StatusPass, "stop_reason="+string(result.StopReason), time.Since(started))
+	}
+
+	obs := d.recorder.snapshot()
+	d.observed(CheckFSRead, obs.fsRead, "agent called fs/read_text_file", "agent never called fs/read_text_file")
+	d.observed(CheckFSWrite, obs.fsWrite, "agent called fs/write_text_file", "agent never called fs/write_text_file")
+	d.observed(CheckTerminal, obs.terminal, "agent called terminal/create", "agent never called terminal/create")
+	switch {
+	case obs.permission && obs.permissionOptions:
+		d.add(CheckPermission, StatusPass, "agent requested permission with options", 0)
+	case obs.permission:
+		d.add(CheckPermission, StatusPass, "agent requested permission without selectable options", 0)
+	default:
+		d.add(CheckPermission, StatusNotObserved, "agent never called session/request_permission", 0)
+	}
+	d.report.Matrix.FSRead = obs.fsRead
+	d.report.Matrix.FSWrite = obs.fsWrite
+	d.report.Matrix.Terminal = obs.terminal
+	d.report.Matrix.Permission = obs.permission
+	d.report.Matrix.PermissionOptions = obs.permissionOptions
+}
+
+func (d *doctor) runCancel(ctx context.Context, client *acpclient.Client, sessionID acpproto.SessionId) {
+	started := time.Now()
+	stepCtx, cancel := context.WithTimeout(ctx, d.cfg.StepTimeout)
+	defer cancel()
+
+	type promptOutcome struct {
+		result *acpclient.PromptResult
+		err    error
+	}
+	done := make(chan promptOutcome, 1)
+	go func() {
+		result, err := client.PromptText(stepCtx, sessionID, "Count slowly from 1 to 500, one number per line. Do not use any tools.")
+		done <- promptOutcome{result: result, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		detail := "prompt finished before cancel could be sent"
+		if out.err != nil {
+			detail = "prompt failed before cancel: " + out.err.Error()
+		}
+		d.add(CheckCancel, StatusNotObserved, detail, time.Since(started))
+		return
+	case <-time.After(cancelDelay):
+	case <-stepCtx.Done():
+	}
+
+	if err := client.Cancel(stepCtx, acpproto.CancelNotification{SessionId: sessionID}); err != nil {
+		d.add(CheckCancel, StatusFail, "send session/cancel: "+err.Error(), time.Since(started))
+		return
+	}
+	select {
+	case out := <-done:
+		switch {
+		case out.err != nil:
+			d.add(CheckCancel, StatusFail, "prompt errored after cancel: "+out.err.Error(), time.Since(started))
+		case out.result.StopReason == acpproto.StopReasonCancelled:
+			d.report.Matrix.Cancel = true
+			d.add(CheckCancel, StatusPass, "stop_reason=cancelled", time.Since(started))
+		default:
+			d.add(CheckCancel, StatusFail, "prompt ended with stop_reason="+string(out.result.StopReason)+" instead of cancelled", time.Since(started))
+		}
+	case <-time.After(cancelGrace):
+		d.add(CheckCancel, StatusFail, fmt.Sprintf("prompt did not stop within %s of session/cancel", cancelGrace), time.Since(started))
+	}
+}
+
+func (d *doctor) runLoadSession(ctx context.Context, client *acpclient.Client, sessionID acpproto.SessionId, advertised bool, launch acpclient.LaunchConfig) {
+	if !advertised {
+		d.add(CheckLoadSession, StatusUnsupported, "agent does not advertise loadSession", 0)
+		return
+	}
+	started := time.Now()
+	stepCtx, cancel := context.WithTimeout(ctx, d.cfg.StepTimeout)
+	defer cancel()
+	if _, err := client.LoadSession(stepCtx, acpproto.LoadSessionRequest{
+		SessionId:  sessionID,
+		Cwd:        d.sessionCwd(launch),
+		McpServers: []acpproto.McpServer{},
+	}); err != nil {
+		d.add(CheckLoadSession, StatusFail, err.Error(), time.Since(started))
+		return
+	}
+	d.report.Matrix.LoadSession = true
+	d.add(CheckLoadSession, StatusPass, "session/load succeeded", time.Since(started))
+}
+
+func (d *doctor) sessionCwd(launch acpclient.LaunchConfig) string {
+	if cwd := strings.TrimSpace(launch.SessionCwd); cwd != "" {
+		return cwd
+	}
+	return d.workDir
+}
+
+func (d *doctor) observed(name string, ok bool, pass string, miss string) {
+	if ok {
+		d.add(name, StatusPass, pass, 0)
+		return
+	}
+	d.add(name, StatusNotObserved, miss, 0)
+}
+
+func (d *doctor) add(name string, status Status, detail string, elapsed time.Duration) {
+	d.report.Checks = append(d.report.Checks, CheckResult{
+		Name:       name,
+		Status:     status,
+		Detail:     detail,
+		DurationMS: elapsed.Milliseconds(),
+	})
+}
+
+func (d *doctor) skip(names []string, reason string) {
+	for _, name := range names {
+		d.add(name, StatusSkipped, reason, 0)
+	}
+}
+
+// finish compares the observed matrix with CapabilitiesMax and sets OK.
+func (d *doctor) finish() {
+	r := d.report
+	r.DurationMS = time.Since(r.StartedAt).Milliseconds()
+	r.Warnings = CompareCapabilities(r.Matrix, r.CapabilitiesMax)
+	r.OK = true
+	for _, check := range r.Checks {
+		if check.Status == StatusFail || check.Status == StatusSkipped {
+			r.OK = false
+			break
+		}
+	}
+}
+
+// CompareCapabilities lists mismatches between what the agent did and what
+// the driver config claims it may do.
+func CompareCapabilities(m Matrix, max core.DriverCapabilities) []string {
+	var warnings []string
+	compare := func(name string, observed bool, declared bool, method string) {
+		switch {
+		case declared && !observed:
+			warnings = append(warnings, fmt.Sprintf("capabilities_max.%s is true but the agent never called %s", name, method))
+		case observed && !declared:
+			warnings = append(warnings, fmt.Sprintf("agent called %s but capabilities_max.%s is false; profiles cannot grant it", method, name))
+		}
+	}
+	compare("fs_read", m.FSRead, max.FSRead, "fs/read_text_file")
+	compare("fs_write", m.FSWrite, max.FSWrite, "fs/write_text_file")
+	compare("terminal", m.Terminal, max.Terminal, "terminal/create")
+	return warnings
+}
+
+func scriptedPrompt() string {
+	return strings.Join([]string{
+		"This is an automated ACP conformance check. Use the client-provided tools for every step:",
+		"1. Read the file " + inputFileName + " in the current directory.",
+		"2. Write a file named " + outputFileName + " whose content is exactly the text you read.",
+		"3. Run the shell command `echo " + terminalMarker + "` in a terminal.",
+		"Ask for permission if your policy requires it. Reply with DONE when finished.",
+	}, "\n")
+}
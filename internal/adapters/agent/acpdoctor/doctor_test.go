@@ -0,0 +1,93 @@
+package acpdoctor
+
+import (
+	"context"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func fakeAgentDriver(t *testing.T) core.DriverConfig {
+	t.Helper()
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("runtime.Caller failed")
+	}
+	fakeAgent := filepath.Join(filepath.Dir(thisFile), "..", "acpclient", "testdata", "fake_agent.go")
+	return core.DriverConfig{
+		ID:              "fake",
+		LaunchCommand:   "go",
+		LaunchArgs:      []string{"run", fakeAgent},
+		CapabilitiesMax: core.DriverCapabilities{FSRead: true, FSWrite: true, Terminal: false},
+	}
+}
+
+func TestRunAgainstFakeAgent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping driver doctor integration test in short mode")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	report, err := Run(ctx, Config{DriverID: "fake", Driver: fakeAgentDriver(t), WorkDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := map[string]Status{
+		CheckInitialize:  StatusPass,
+		CheckNewSession:  StatusPass,
+		CheckPrompt:      StatusPass,
+		CheckFSRead:      StatusNotObserved,
+		CheckFSWrite:     StatusPass,
+		CheckTerminal:    StatusNotObserved,
+		CheckPermission:  StatusPass,
+		CheckCancel:      StatusNotObserved,
+		CheckLoadSession: StatusUnsupported,
+	}
+	for name, status := range want {
+		got := report.Check(name)
+		if got == nil || got.Status != status {
+			t.Fatalf("check %s = %+v, want %s", name, got, status)
+		}
+	}
+	if !report.OK {
+		t.Fatalf("report.OK = false: %+v", report.Checks)
+	}
+	if !report.Matrix.FSWrite || report.Matrix.FSRead || !report.Matrix.Permission {
+		t.Fatalf("unexpected matrix: %+v", report.Matrix)
+	}
+	if len(report.Warnings) != 1 {
+		t.Fatalf("warnings = %v, want the fs_read mismatch only", report.Warnings)
+	}
+}
+
+func TestRunReportsLaunchFailure(t *testing.T) {
+	report, err := Run(context.Background(), Config{
+		DriverID: "missing",
+		Driver:   core.DriverConfig{LaunchCommand: filepath.Join(t.TempDir(), "no-such-agent")},
+		WorkDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report.OK {
+		t.Fatal("expected report to fail")
+	}
+	if got := report.Check(CheckInitialize); got == nil || got.Status != StatusFail {
+		t.Fatalf("initialize check = %+v, want fail", got)
+	}
+	if got := report.Check(CheckLoadSession); got == nil || got.Status != StatusSkipped {
+		t.Fatalf("load_session check = %+v, want skipped", got)
+	}
+}
+
+func TestCompareCapabilities(t *testing.T) {
+	warnings := CompareCapabilities(Matrix{FSRead: true, Terminal: true}, core.DriverCapabilities{FSRead: true, FSWrite: true})
+	if len(warnings) != 2 {
+		t.Fatalf("CompareCapabilities() = %v, want fs_write and terminal mismatches", warnings)
+	}
+}
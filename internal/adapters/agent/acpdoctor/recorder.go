@@ -0,0 +1,76 @@
+package acpdoctor
+
+import (
+	"context"
+	"sync"
+
+	acpproto "github.com/coder/acp-go-sdk"
+	acphandler "github.com/yoke233/zhanggui/internal/adapters/agent/acp"
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+)
+
+// recorder serves client-side ACP requests with the regular handler and
+// notes which ones the agent used.
+type recorder struct {
+	*acphandler.ACPHandler
+
+	mu  sync.Mutex
+	obs observations
+}
+
+type observations struct {
+	fsRead            bool
+	fsWrite           bool
+	terminal          bool
+	permission        bool
+	permissionOptions bool
+	updates           int
+}
+
+var _ acpproto.Client = (*recorder)(nil)
+
+func newRecorder(workDir string) *recorder {
+	h := acphandler.NewACPHandler(workDir, "", nil)
+	h.SetSuppressEvents(true)
+	return &recorder{ACPHandler: h}
+}
+
+func (r *recorder) snapshot() observations {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.obs
+}
+
+func (r *recorder) note(fn func(*observations)) {
+	r.mu.Lock()
+	fn(&r.obs)
+	r.mu.Unlock()
+}
+
+func (r *recorder) ReadTextFile(ctx context.Context, req acpproto.ReadTextFileRequest) (acpproto.ReadTextFileResponse, error) {
+	r.note(func(o *observations) { o.fsRead = true })
+	return r.ACPHandler.ReadTextFile(ctx, req)
+}
+
+func (r *recorder) WriteTextFile(ctx context.Context, req acpproto.WriteTextFileRequest) (acpproto.WriteTextFileResponse, error) {
+	r.note(func(o *observations) { o.fsWrite = true })
+	return r.ACPHandler.WriteTextFile(ctx, req)
+}
+
+func (r *recorder) CreateTerminal(ctx context.Context, req acpproto.CreateTerminalRequest) (acpproto.CreateTerminalResponse, error) {
+	r.note(func(o *observations) { o.terminal = true })
+	return r.ACPHandler.CreateTerminal(ctx, req)
+}
+
+func (r *recorder) RequestPermission(ctx context.Context, req acpproto.RequestPermissionRequest) (acpproto.RequestPermissionResponse, error) {
+	r.note(func(o *observations) {
+		o.permission = true
+		o.permissionOptions = o.permissionOptions || len(req.Options) > 0
+	})
+	return r.ACPHandler.RequestPermission(ctx, req)
+}
+
+func (r *recorder) HandleSessionUpdate(context.Context, acpclient.SessionUpdate) error {
+	r.note(func(o *observations) { o.updates++ })
+	return nil
+}
@@ -0,0 +1,39 @@
+package acpdoctor
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/adapters/sandbox"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// DriverResolver resolves a configured driver by ID.
+type DriverResolver interface {
+	ResolveDriverConfig(driverID string) (*core.DriverConfig, error)
+}
+
+// Service runs the doctor for configured drivers.
+type Service struct {
+	Drivers DriverResolver
+	Sandbox sandbox.Sandbox
+}
+
+// NewService creates a doctor service backed by the given driver resolver and sandbox.
+func NewService(drivers DriverResolver, sb sandbox.Sandbox) *Service {
+	return &Service{Drivers: drivers, Sandbox: sb}
+}
+
+// DiagnoseDriver resolves driverID and runs the scripted conversation against it.
+func (s *Service) DiagnoseDriver(ctx context.Context, driverID string) (*Report, error) {
+	if s == nil || s.Drivers == nil {
+		return nil, errors.New("driver doctor: driver resolver is not configured")
+	}
+	driverID = strings.TrimSpace(driverID)
+	driver, err := s.Drivers.ResolveDriverConfig(driverID)
+	if err != nil {
+		return nil, err
+	}
+	return Run(ctx, Config{DriverID: driverID, Driver: *driver, Sandbox: s.Sandbox})
+}
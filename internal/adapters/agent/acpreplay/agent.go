@@ -0,0 +1,337 @@
+package acpreplay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	acpproto "github.com/coder/acp-go-sdk"
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+)
+
+const agentName = "acp-replay"
+
+// ErrNoMatchingTurn is returned from session/prompt when every recorded turn
+// has already been replayed.
+var ErrNoMatchingTurn = errors.New("no recorded turn left to replay")
+
+// Option configures a replay agent.
+type Option func(*Agent)
+
+// WithRealtime makes the agent wait for each step's recorded offset instead of
+// replaying as fast as possible.
+func WithRealtime() Option {
+	return func(a *Agent) { a.realtime = true }
+}
+
+// Agent is an ACP agent that answers prompts from a Fixture.
+type Agent struct {
+	fixture  *Fixture
+	regexps  []*regexp.Regexp
+	realtime bool
+
+	conn *acpproto.AgentSideConnection
+
+	mu       sync.Mutex
+	used     []bool
+	sessions map[acpproto.SessionId]struct{}
+	seq      int
+}
+
+var (
+	_ acpproto.Agent       = (*Agent)(nil)
+	_ acpproto.AgentLoader = (*Agent)(nil)
+)
+
+// NewAgent validates f and returns an agent that replays it. Call
+// SetConnection before serving requests.
+func NewAgent(f *Fixture, opts ...Option) (*Agent, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	a := &Agent{
+		fixture:  f,
+		regexps:  make([]*regexp.Regexp, len(f.Turns)),
+		used:     make([]bool, len(f.Turns)),
+		sessions: make(map[acpproto.SessionId]struct{}),
+	}
+	for i, turn := range f.Turns {
+		if turn.Match.Regex != "" {
+			a.regexps[i] = regexp.MustCompile(turn.Match.Regex)
+		}
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a, nil
+}
+
+// SetConnection binds the agent to the connection it sends updates on.
+func (a *Agent) SetConnection(conn *acpproto.AgentSideConnection) {
+	a.conn = conn
+}
+
+// Serve replays f over r/w until the peer disconnects or ctx is done.
+func Serve(ctx context.Context, f *Fixture, r io.Reader, w io.Writer, opts ...Option) error {
+	agent, err := NewAgent(f, opts...)
+	if err != nil {
+		return err
+	}
+	conn := acpproto.NewAgentSideConnection(agent, w, r)
+	agent.SetConnection(conn)
+	select {
+	case <-conn.Done():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NewClient starts an in-process replay agent for f and returns an ACP client
+// connected to it. Closing the client stops the agent.
+func NewClient(f *Fixture, h acpproto.Client, opts ...acpclient.Option) (*acpclient.Client, error) {
+	agent, err := NewAgent(f)
+	if err != nil {
+		return nil, err
+	}
+	agentPipe, clientPipe := net.Pipe()
+	agent.SetConnection(acpproto.NewAgentSideConnection(agent, agentPipe, agentPipe))
+
+	client, err := acpclient.NewWithIO(acpclient.LaunchConfig{Command: agentName}, h, clientPipe, clientPipe, opts...)
+	if err != nil {
+		_ = clientPipe.Close()
+		_ = agentPipe.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (a *Agent) Initialize(context.Context, acpproto.InitializeRequest) (acpproto.InitializeResponse, error) {
+	return acpproto.InitializeResponse{
+		ProtocolVersion:   acpproto.ProtocolVersionNumber,
+		AgentCapabilities: acpproto.AgentCapabilities{LoadSession: true},
+		AgentInfo:         &acpproto.Implementation{Name: agentName, Version: strconv.Itoa(FixtureVersion)},
+	}, nil
+}
+
+func (a *Agent) Authenticate(context.Context, acpproto.AuthenticateRequest) (acpproto.AuthenticateResponse, error) {
+	return acpproto.AuthenticateResponse{}, nil
+}
+
+func (a *Agent) NewSession(context.Context, acpproto.NewSessionRequest) (acpproto.NewSessionResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.seq++
+	id := acpproto.SessionId("replay-session-" + strconv.Itoa(a.seq))
+	a.sessions[id] = struct{}{}
+	return acpproto.NewSessionResponse{SessionId: id}, nil
+}
+
+func (a *Agent) LoadSession(_ context.Context, req acpproto.LoadSessionRequest) (acpproto.LoadSessionResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.sessions[req.SessionId]; !ok {
+		return acpproto.LoadSessionResponse{}, acpproto.NewInvalidParams(map[string]any{"sessionId": req.SessionId, "error": "session not found"})
+	}
+	return acpproto.LoadSessionResponse{}, nil
+}
+
+func (a *Agent) Cancel(context.Context, acpproto.CancelNotification) error {
+	// The connection cancels the in-flight prompt context; nothing else to do.
+	return nil
+}
+
+func (a *Agent) SetSessionMode(context.Context, acpproto.SetSessionModeRequest) (acpproto.SetSessionModeResponse, error) {
+	return acpproto.SetSessionModeResponse{}, nil
+}
+
+func (a *Agent) SetSessionConfigOption(context.Context, acpproto.SetSessionConfigOptionRequest) (acpproto.SetSessionConfigOptionResponse, error) {
+	return acpproto.SetSessionConfigOptionResponse{ConfigOptions: []acpproto.SessionConfigOption{}}, nil
+}
+
+func (a *Agent) Prompt(ctx context.Context, req acpproto.PromptRequest) (acpproto.PromptResponse, error) {
+	if a.conn == nil {
+		return acpproto.PromptResponse{}, errors.New("replay agent has no connection")
+	}
+	turn, err := a.nextTurn(promptText(req.Prompt))
+	if err != nil {
+		return acpproto.PromptResponse{}, acpproto.NewInternalError(map[string]any{"error": err.Error()})
+	}
+
+	start := time.Now()
+	terminals := map[string]string{}
+	for i, step := range turn.Steps {
+		if a.realtime {
+			if wait := time.Duration(step.OffsetMs)*time.Millisecond - time.Since(start); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return acpproto.PromptResponse{StopReason: acpproto.StopReasonCancelled}, nil
+		}
+		if err := a.replayStep(ctx, req.SessionId, step, terminals); err != nil {
+			if ctx.Err() != nil {
+				return acpproto.PromptResponse{StopReason: acpproto.StopReasonCancelled}, nil
+			}
+			return acpproto.PromptResponse{}, acpproto.NewInternalError(map[string]any{"error": fmt.Sprintf("replay step %d: %v", i, err)})
+		}
+	}
+
+	stop := turn.StopReason
+	if stop == "" {
+		stop = acpproto.StopReasonEndTurn
+	}
+	return acpproto.PromptResponse{StopReason: stop, Usage: turn.Usage}, nil
+}
+
+// nextTurn picks the turn answering prompt: an unused turn with a matching
+// hash, then one with a matching regex, then the next unused turn in order.
+func (a *Agent) nextTurn(prompt string) (*Turn, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	pick := -1
+	hash := PromptHash(prompt)
+	for i, turn := range a.fixture.Turns {
+		if !a.used[i] && turn.Match.Hash == hash {
+			pick = i
+			break
+		}
+	}
+	if pick < 0 {
+		for i, re := range a.regexps {
+			if !a.used[i] && re != nil && re.MatchString(prompt) {
+				pick = i
+				break
+			}
+		}
+	}
+	if pick < 0 {
+		for i := range a.fixture.Turns {
+			if !a.used[i] {
+				pick = i
+				break
+			}
+		}
+	}
+	if pick < 0 {
+		return nil, ErrNoMatchingTurn
+	}
+	a.used[pick] = true
+	return &a.fixture.Turns[pick], nil
+}
+
+func (a *Agent) replayStep(ctx context.Context, sessionID acpproto.SessionId, step Step, terminals map[string]string) error {
+	if len(step.Update) > 0 {
+		var update acpproto.SessionUpdate
+		if err := json.Unmarshal(step.Update, &update); err != nil {
+			return fmt.Errorf("decode session update: %w", err)
+		}
+		return a.conn.SessionUpdate(ctx, acpproto.SessionNotification{SessionId: sessionID, Update: update})
+	}
+
+	// Legacy method names are replayed as their current equivalents.
+	switch step.Method {
+	case "fs/read_text_file", "fs/read_file":
+		var req acpproto.ReadTextFileRequest
+		if err := json.Unmarshal(step.Params, &req); err != nil {
+			return err
+		}
+		req.SessionId = sessionID
+		_, err := a.conn.ReadTextFile(ctx, req)
+		return err
+	case "fs/write_text_file", "fs/write_file":
+		var req acpproto.WriteTextFileRequest
+		if err := json.Unmarshal(step.Params, &req); err != nil {
+			return err
+		}
+		req.SessionId = sessionID
+		_, err := a.conn.WriteTextFile(ctx, req)
+		return err
+	case "session/request_permission", "request_permission":
+		var req acpproto.RequestPermissionRequest
+		if err := json.Unmarshal(step.Params, &req); err != nil {
+			return err
+		}
+		req.SessionId = sessionID
+		_, err := a.conn.RequestPermission(ctx, req)
+		return err
+	case "terminal/create":
+		var req acpproto.CreateTerminalRequest
+		if err := json.Unmarshal(step.Params, &req); err != nil {
+			return err
+		}
+		req.SessionId = sessionID
+		resp, err := a.conn.CreateTerminal(ctx, req)
+		if err != nil {
+			return err
+		}
+		var recorded acpproto.CreateTerminalResponse
+		_ = json.Unmarshal(step.Result, &recorded)
+		terminals[recorded.TerminalId] = resp.TerminalId
+		return nil
+	case "terminal/output":
+		var req acpproto.TerminalOutputRequest
+		if err := json.Unmarshal(step.Params, &req); err != nil {
+			return err
+		}
+		req.SessionId, req.TerminalId = sessionID, liveTerminal(terminals, req.TerminalId)
+		_, err := a.conn.TerminalOutput(ctx, req)
+		return err
+	case "terminal/wait_for_exit":
+		var req acpproto.WaitForTerminalExitRequest
+		if err := json.Unmarshal(step.Params, &req); err != nil {
+			return err
+		}
+		req.SessionId, req.TerminalId = sessionID, liveTerminal(terminals, req.TerminalId)
+		_, err := a.conn.WaitForTerminalExit(ctx, req)
+		return err
+	case "terminal/kill":
+		var req acpproto.KillTerminalCommandRequest
+		if err := json.Unmarshal(step.Params, &req); err != nil {
+			return err
+		}
+		req.SessionId, req.TerminalId = sessionID, liveTerminal(terminals, req.TerminalId)
+		_, err := a.conn.KillTerminalCommand(ctx, req)
+		return err
+	case "terminal/release":
+		var req acpproto.ReleaseTerminalRequest
+		if err := json.Unmarshal(step.Params, &req); err != nil {
+			return err
+		}
+		req.SessionId, req.TerminalId = sessionID, liveTerminal(terminals, req.TerminalId)
+		_, err := a.conn.ReleaseTerminal(ctx, req)
+		return err
+	default:
+		if strings.HasPrefix(step.Method, "_") {
+			_, err := a.conn.CallExtension(ctx, step.Method, step.Params)
+			return err
+		}
+		return fmt.Errorf("unsupported request method %q", step.Method)
+	}
+}
+
+// liveTerminal maps a recorded terminal ID to the one the client returned
+// during replay. An unknown ID falls back to the only live terminal, if any.
+func liveTerminal(terminals map[string]string, recorded string) string {
+	if id, ok := terminals[recorded]; ok {
+		return id
+	}
+	if len(terminals) == 1 {
+		for _, id := range terminals {
+			return id
+		}
+	}
+	return recorded
+}
@@ -0,0 +1,231 @@
+package acpreplay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	acpproto "github.com/coder/acp-go-sdk"
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type rpcEnvelope struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// FromJSONTrace builds a fixture from a raw JSON-RPC trace captured with
+// acpclient.WithTraceRecorder. Each session/prompt request becomes a turn;
+// the updates and agent requests received before its response become steps.
+func FromJSONTrace(records []acpclient.JSONTraceRecord) (*Fixture, error) {
+	f := &Fixture{Version: FixtureVersion, Source: SourceTrace, CreatedAt: time.Now().UTC()}
+
+	var (
+		current     *Turn
+		promptID    string
+		turnStartMs int64
+		pending     = map[string]int{}
+	)
+	for _, rec := range records {
+		var msg rpcEnvelope
+		if err := json.Unmarshal(rec.JSON, &msg); err != nil {
+			return nil, fmt.Errorf("decode trace record %d: %w", rec.Sequence, err)
+		}
+		switch rec.Direction {
+		case acpclient.TraceDirectionSend:
+			if msg.Method == "" {
+				if idx, ok := pending[string(msg.ID)]; ok && current != nil {
+					current.Steps[idx].Result = msg.Result
+					delete(pending, string(msg.ID))
+				}
+				continue
+			}
+			if msg.Method != "session/prompt" {
+				continue
+			}
+			var req acpproto.PromptRequest
+			if err := json.Unmarshal(msg.Params, &req); err != nil {
+				return nil, fmt.Errorf("decode session/prompt record %d: %w", rec.Sequence, err)
+			}
+			prompt := promptText(req.Prompt)
+			f.Turns = append(f.Turns, Turn{Prompt: prompt, Match: Match{Hash: PromptHash(prompt)}})
+			current = &f.Turns[len(f.Turns)-1]
+			promptID = string(msg.ID)
+			turnStartMs = rec.OffsetMs
+			clear(pending)
+
+		case acpclient.TraceDirectionRecv:
+			if current == nil {
+				continue
+			}
+			switch {
+			case msg.Method == "session/update":
+				var note struct {
+					Update json.RawMessage `json:"update"`
+				}
+				if err := json.Unmarshal(msg.Params, &note); err != nil || len(note.Update) == 0 {
+					continue
+				}
+				current.Steps = append(current.Steps, Step{OffsetMs: rec.OffsetMs - turnStartMs, Update: note.Update})
+			case msg.Method != "":
+				if len(msg.ID) > 0 {
+					pending[string(msg.ID)] = len(current.Steps)
+				}
+				current.Steps = append(current.Steps, Step{OffsetMs: rec.OffsetMs - turnStartMs, Method: msg.Method, Params: msg.Params})
+			case string(msg.ID) == promptID && len(msg.Result) > 0:
+				var resp acpproto.PromptResponse
+				if err := json.Unmarshal(msg.Result, &resp); err == nil {
+					current.StopReason = resp.StopReason
+					current.Usage = resp.Usage
+				}
+				current = nil
+			}
+		}
+	}
+	if len(f.Turns) == 0 {
+		return nil, errors.New("trace contains no session/prompt requests")
+	}
+	return f, nil
+}
+
+// FromRun builds a single-turn fixture from a persisted run and its
+// run.agent_output events. Streaming chunks are not persisted, so each stored
+// message or thought is replayed as one chunk.
+func FromRun(run *core.Run, events []*core.Event, usage *core.UsageRecord) (*Fixture, error) {
+	if run == nil {
+		return nil, errors.New("run is nil")
+	}
+	prompt, _ := run.Input["prompt"].(string)
+	profileID, _ := run.Input["profile_id"].(string)
+	turn := Turn{
+		Prompt:     prompt,
+		Match:      Match{Hash: PromptHash(prompt)},
+		StopReason: acpproto.StopReasonEndTurn,
+	}
+	if run.Status == core.RunCancelled {
+		turn.StopReason = acpproto.StopReasonCancelled
+	}
+	if usage != nil {
+		turn.Usage = &acpproto.Usage{
+			InputTokens:  int(usage.InputTokens),
+			OutputTokens: int(usage.OutputTokens),
+			TotalTokens:  int(usage.TotalTokens),
+		}
+	}
+
+	var start time.Time
+	seenTools := map[string]bool{}
+	for _, ev := range events {
+		if ev == nil || ev.Type != core.EventRunAgentOutput {
+			continue
+		}
+		update := updateFromEvent(ev.Data, seenTools)
+		if update == nil {
+			continue
+		}
+		if start.IsZero() {
+			start = ev.Timestamp
+		}
+		raw, err := json.Marshal(update)
+		if err != nil {
+			return nil, err
+		}
+		turn.Steps = append(turn.Steps, Step{OffsetMs: ev.Timestamp.Sub(start).Milliseconds(), Update: raw})
+	}
+
+	return &Fixture{
+		Version:   FixtureVersion,
+		Source:    SourceRun,
+		DriverID:  run.AgentID,
+		ProfileID: profileID,
+		RunID:     run.ID,
+		CreatedAt: time.Now().UTC(),
+		Turns:     []Turn{turn},
+	}, nil
+}
+
+func updateFromEvent(data map[string]any, seenTools map[string]bool) map[string]any {
+	kind, _ := data["type"].(string)
+	content, _ := data["content"].(string)
+	toolCallID, _ := data["tool_call_id"].(string)
+	switch kind {
+	case acpclient.UpdateTypeAgentMessage, acpclient.UpdateTypeAgentThought:
+		if content == "" {
+			return nil
+		}
+		return map[string]any{
+			"sessionUpdate": kind + "_chunk",
+			"content":       map[string]any{"type": "text", "text": content},
+		}
+	case acpclient.UpdateTypeToolCall:
+		if toolCallID == "" {
+			return nil
+		}
+		update := map[string]any{
+			"sessionUpdate": acpclient.UpdateTypeToolCall,
+			"toolCallId":    toolCallID,
+			"title":         content,
+			"status":        "in_progress",
+		}
+		if seenTools[toolCallID] {
+			update["sessionUpdate"] = acpclient.UpdateTypeToolCallUpdate
+		}
+		seenTools[toolCallID] = true
+		return update
+	case acpclient.UpdateTypeToolCallCompleted:
+		if toolCallID == "" {
+			return nil
+		}
+		rawOutput := map[string]any{"exit_code": intValue(data["exit_code"]), "stdout": content}
+		if stderr, _ := data["stderr"].(string); stderr != "" {
+			rawOutput["stderr"] = stderr
+		}
+		update := map[string]any{
+			"sessionUpdate": acpclient.UpdateTypeToolCallUpdate,
+			"toolCallId":    toolCallID,
+			"status":        acpclient.ToolCallStatusCompleted,
+			"rawOutput":     rawOutput,
+		}
+		if title, _ := data["title"].(string); title != "" {
+			update["title"] = title
+		}
+		return update
+	case acpclient.UpdateTypeUsageUpdate:
+		return map[string]any{
+			"sessionUpdate": acpclient.UpdateTypeUsageUpdate,
+			"size":          intValue(data["usage_size"]),
+			"used":          intValue(data["usage_used"]),
+		}
+	}
+	return nil
+}
+
+func promptText(blocks []acpproto.ContentBlock) string {
+	var parts []string
+	for _, block := range blocks {
+		if block.Text != nil && block.Text.Text != "" {
+			parts = append(parts, block.Text.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func intValue(v any) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	case json.Number:
+		i, _ := n.Int64()
+		return i
+	}
+	return 0
+}
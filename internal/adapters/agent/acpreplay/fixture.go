@@ -0,0 +1,135 @@
+// Package acpreplay plays back recorded ACP conversations so tests can drive
+// the engine deterministically without a live model.
+package acpreplay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	acpproto "github.com/coder/acp-go-sdk"
+)
+
+// FixtureVersion is the on-disk format version written by Save.
+const FixtureVersion = 1
+
+// Fixture sources.
+const (
+	SourceTrace = "trace"
+	SourceRun   = "run"
+)
+
+// Fixture is a recorded conversation: an ordered list of prompt turns, each
+// with the session updates and agent-to-client requests the agent emitted.
+type Fixture struct {
+	Version   int       `json:"version"`
+	Source    string    `json:"source,omitempty"`
+	DriverID  string    `json:"driver_id,omitempty"`
+	ProfileID string    `json:"profile_id,omitempty"`
+	RunID     int64     `json:"run_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Turns     []Turn    `json:"turns"`
+}
+
+// Turn is one session/prompt exchange.
+type Turn struct {
+	Prompt     string              `json:"prompt,omitempty"`
+	Match      Match               `json:"match,omitempty"`
+	Steps      []Step              `json:"steps,omitempty"`
+	StopReason acpproto.StopReason `json:"stop_reason,omitempty"`
+	Usage      *acpproto.Usage     `json:"usage,omitempty"`
+}
+
+// Match selects which incoming prompt a turn answers. Hash is compared first,
+// then Regex; a prompt matching neither falls back to the next unused turn.
+type Match struct {
+	Hash  string `json:"hash,omitempty"`
+	Regex string `json:"regex,omitempty"`
+}
+
+// Step is either a session update (Update set) or an agent-to-client request
+// such as fs/write_text_file or session/request_permission (Method set).
+// Result holds the client's recorded response, when one was captured.
+type Step struct {
+	OffsetMs int64           `json:"offset_ms,omitempty"`
+	Update   json.RawMessage `json:"update,omitempty"`
+	Method   string          `json:"method,omitempty"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+}
+
+// PromptHash returns the match hash for a prompt. Whitespace is collapsed so
+// trivial formatting differences do not break a fixture.
+func PromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(prompt), " ")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Validate checks that the fixture can be replayed.
+func (f *Fixture) Validate() error {
+	if f == nil {
+		return errors.New("fixture is nil")
+	}
+	if f.Version > FixtureVersion {
+		return fmt.Errorf("fixture version %d is newer than supported version %d", f.Version, FixtureVersion)
+	}
+	if len(f.Turns) == 0 {
+		return errors.New("fixture has no turns")
+	}
+	for i, turn := range f.Turns {
+		if turn.Match.Regex != "" {
+			if _, err := regexp.Compile(turn.Match.Regex); err != nil {
+				return fmt.Errorf("turn %d: invalid match regex: %w", i, err)
+			}
+		}
+		for j, step := range turn.Steps {
+			if len(step.Update) == 0 && step.Method == "" {
+				return fmt.Errorf("turn %d step %d: neither update nor method is set", i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// Load reads and validates a fixture file.
+func Load(path string) (*Fixture, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("decode fixture %s: %w", path, err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Save writes the fixture as indented JSON, creating parent directories.
+func Save(path string, f *Fixture) error {
+	if f == nil {
+		return errors.New("fixture is nil")
+	}
+	if f.Version == 0 {
+		f.Version = FixtureVersion
+	}
+	raw, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
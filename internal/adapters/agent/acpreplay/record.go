@@ -0,0 +1,105 @@
+package acpreplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	acpproto "github.com/coder/acp-go-sdk"
+	acphandler "github.com/yoke233/zhanggui/internal/adapters/agent/acp"
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+)
+
+// RecordConfig describes a live conversation to capture as a fixture.
+type RecordConfig struct {
+	DriverID string
+	Launch   acpclient.LaunchConfig
+	Prompts  []string
+	// Handler serves the agent's client-side requests. Defaults to the regular
+	// ACP handler rooted at Launch.WorkDir.
+	Handler acpproto.Client
+	// Timeout bounds each prompt. Zero means no limit beyond ctx.
+	Timeout time.Duration
+}
+
+type traceBuffer struct {
+	mu      sync.Mutex
+	records []acpclient.JSONTraceRecord
+}
+
+func (b *traceBuffer) RecordJSONTrace(rec acpclient.JSONTraceRecord) {
+	b.mu.Lock()
+	b.records = append(b.records, rec)
+	b.mu.Unlock()
+}
+
+func (b *traceBuffer) snapshot() []acpclient.JSONTraceRecord {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]acpclient.JSONTraceRecord(nil), b.records...)
+}
+
+// Record launches the agent, sends each prompt in one session and converts
+// the captured JSON-RPC trace into a fixture.
+func Record(ctx context.Context, cfg RecordConfig) (*Fixture, error) {
+	if strings.TrimSpace(cfg.Launch.Command) == "" {
+		return nil, errors.New("record: launch command is required")
+	}
+	if len(cfg.Prompts) == 0 {
+		return nil, errors.New("record: at least one prompt is required")
+	}
+	handler := cfg.Handler
+	if handler == nil {
+		h := acphandler.NewACPHandler(cfg.Launch.WorkDir, "", nil)
+		h.SetSuppressEvents(true)
+		handler = h
+	}
+
+	trace := &traceBuffer{}
+	client, err := acpclient.New(cfg.Launch, handler, acpclient.WithTraceRecorder(trace))
+	if err != nil {
+		return nil, fmt.Errorf("record: launch: %w", err)
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = client.Close(closeCtx)
+	}()
+
+	if err := client.Initialize(ctx, acpclient.ClientCapabilities{FSRead: true, FSWrite: true, Terminal: true}); err != nil {
+		return nil, fmt.Errorf("record: initialize: %w", err)
+	}
+	cwd := cfg.Launch.SessionCwd
+	if cwd == "" {
+		cwd = cfg.Launch.WorkDir
+	}
+	if abs, err := filepath.Abs(cwd); err == nil {
+		cwd = abs
+	}
+	sessionID, err := client.NewSession(ctx, acpproto.NewSessionRequest{Cwd: cwd, McpServers: []acpproto.McpServer{}})
+	if err != nil {
+		return nil, fmt.Errorf("record: new session: %w", err)
+	}
+	for i, prompt := range cfg.Prompts {
+		promptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if cfg.Timeout > 0 {
+			promptCtx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		}
+		_, err := client.PromptText(promptCtx, sessionID, prompt)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("record: prompt %d: %w", i+1, err)
+		}
+	}
+
+	f, err := FromJSONTrace(trace.snapshot())
+	if err != nil {
+		return nil, err
+	}
+	f.DriverID = cfg.DriverID
+	return f, nil
+}
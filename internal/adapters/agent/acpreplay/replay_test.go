@@ -0,0 +1,250 @@
+package acpreplay
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	acpproto "github.com/coder/acp-go-sdk"
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type captureHandler struct {
+	acpclient.NopHandler
+
+	mu      sync.Mutex
+	updates []acpclient.SessionUpdate
+	writes  []acpproto.WriteTextFileRequest
+}
+
+func (h *captureHandler) WriteTextFile(_ context.Context, req acpproto.WriteTextFileRequest) (acpproto.WriteTextFileResponse, error) {
+	h.mu.Lock()
+	h.writes = append(h.writes, req)
+	h.mu.Unlock()
+	return acpproto.WriteTextFileResponse{}, nil
+}
+
+func (h *captureHandler) HandleSessionUpdate(_ context.Context, update acpclient.SessionUpdate) error {
+	h.mu.Lock()
+	h.updates = append(h.updates, update)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *captureHandler) types() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]string, 0, len(h.updates))
+	for _, u := range h.updates {
+		out = append(out, u.Type)
+	}
+	return out
+}
+
+func textUpdate(text string) json.RawMessage {
+	raw, _ := json.Marshal(map[string]any{
+		"sessionUpdate": "agent_message_chunk",
+		"content":       map[string]any{"type": "text", "text": text},
+	})
+	return raw
+}
+
+func startReplay(t *testing.T, f *Fixture) (*acpclient.Client, *captureHandler, acpproto.SessionId) {
+	t.Helper()
+	h := &captureHandler{}
+	client, err := NewClient(f, h, acpclient.WithEventHandler(h))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Initialize(ctx, acpclient.ClientCapabilities{FSRead: true, FSWrite: true}); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	sessionID, err := client.NewSession(ctx, acpproto.NewSessionRequest{Cwd: t.TempDir(), McpServers: []acpproto.McpServer{}})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	return client, h, sessionID
+}
+
+func TestNewClientReplaysUpdatesRequestsAndUsage(t *testing.T) {
+	usage, _ := json.Marshal(map[string]any{"sessionUpdate": "usage_update", "size": 1000, "used": 42})
+	f := &Fixture{Turns: []Turn{{
+		Prompt: "write the file",
+		Match:  Match{Hash: PromptHash("write the file")},
+		Steps: []Step{
+			{Update: textUpdate("hello ")},
+			{Method: "fs/write_text_file", Params: json.RawMessage(`{"sessionId":"recorded","path":"out.txt","content":"payload"}`)},
+			{Update: textUpdate("world")},
+			{Update: usage},
+		},
+		StopReason: acpproto.StopReasonEndTurn,
+		Usage:      &acpproto.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
+	}}}
+	client, h, sessionID := startReplay(t, f)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := client.PromptText(ctx, sessionID, "write   the file")
+	if err != nil {
+		t.Fatalf("PromptText() error = %v", err)
+	}
+	if result.Text != "hello world" {
+		t.Fatalf("text = %q, want %q", result.Text, "hello world")
+	}
+	if result.StopReason != acpproto.StopReasonEndTurn || result.Usage == nil || result.Usage.TotalTokens != 15 {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(h.writes) != 1 || h.writes[0].Path != "out.txt" || h.writes[0].SessionId != sessionID {
+		t.Fatalf("writes = %+v", h.writes)
+	}
+	if got := strings.Join(h.types(), ","); !strings.Contains(got, "usage_update") {
+		t.Fatalf("update types = %s, want usage_update", got)
+	}
+
+	if _, err := client.PromptText(ctx, sessionID, "again"); err == nil {
+		t.Fatal("expected error once all turns are replayed")
+	}
+}
+
+func TestNextTurnMatchesByHashThenRegexThenOrder(t *testing.T) {
+	f := &Fixture{Turns: []Turn{
+		{Prompt: "first", Steps: []Step{{Update: textUpdate("one")}}},
+		{Match: Match{Regex: `(?i)review`}, Steps: []Step{{Update: textUpdate("two")}}},
+		{Match: Match{Hash: PromptHash("exact prompt")}, Steps: []Step{{Update: textUpdate("three")}}},
+	}}
+	agent, err := NewAgent(f)
+	if err != nil {
+		t.Fatalf("NewAgent() error = %v", err)
+	}
+
+	for _, tc := range []struct {
+		prompt string
+		want   int
+	}{
+		{"exact  prompt", 2},
+		{"please Review this", 1},
+		{"anything", 0},
+	} {
+		turn, err := agent.nextTurn(tc.prompt)
+		if err != nil {
+			t.Fatalf("nextTurn(%q) error = %v", tc.prompt, err)
+		}
+		if turn != &f.Turns[tc.want] {
+			t.Fatalf("nextTurn(%q) picked %+v, want turn %d", tc.prompt, turn, tc.want)
+		}
+	}
+	if _, err := agent.nextTurn("more"); err != ErrNoMatchingTurn {
+		t.Fatalf("nextTurn() error = %v, want ErrNoMatchingTurn", err)
+	}
+}
+
+func TestFromRunBuildsReplayableTurn(t *testing.T) {
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	run := &core.Run{ID: 7, AgentID: "worker", Status: core.RunSucceeded, Input: map[string]any{"prompt": "do it", "profile_id": "worker-1"}}
+	events := []*core.Event{
+		{Type: core.EventRunStarted, Timestamp: base},
+		{Type: core.EventRunAgentOutput, Timestamp: base, Data: map[string]any{"type": "agent_thought", "content": "thinking"}},
+		{Type: core.EventRunAgentOutput, Timestamp: base.Add(time.Second), Data: map[string]any{"type": "tool_call", "tool_call_id": "t1", "content": "ls"}},
+		{Type: core.EventRunAgentOutput, Timestamp: base.Add(2 * time.Second), Data: map[string]any{"type": "tool_call_completed", "tool_call_id": "t1", "content": "a.txt", "exit_code": float64(0)}},
+		{Type: core.EventRunAgentOutput, Timestamp: base.Add(3 * time.Second), Data: map[string]any{"type": "agent_message", "content": "done"}},
+		{Type: core.EventRunAgentOutput, Timestamp: base.Add(3 * time.Second), Data: map[string]any{"type": "usage_update", "usage_size": float64(200), "usage_used": float64(50)}},
+	}
+	f, err := FromRun(run, events, &core.UsageRecord{InputTokens: 3, OutputTokens: 4, TotalTokens: 7})
+	if err != nil {
+		t.Fatalf("FromRun() error = %v", err)
+	}
+	if f.RunID != 7 || f.ProfileID != "worker-1" || len(f.Turns) != 1 {
+		t.Fatalf("fixture = %+v", f)
+	}
+	turn := f.Turns[0]
+	if turn.Match.Hash != PromptHash("do it") || len(turn.Steps) != 5 || turn.Steps[2].OffsetMs != 2000 {
+		t.Fatalf("turn = %+v", turn)
+	}
+
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := Save(path, f); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	client, h, sessionID := startReplay(t, loaded)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := client.PromptText(ctx, sessionID, "do it")
+	if err != nil {
+		t.Fatalf("PromptText() error = %v", err)
+	}
+	if result.Text != "done" || result.Usage == nil || result.Usage.TotalTokens != 7 {
+		t.Fatalf("result = %+v", result)
+	}
+	want := "agent_thought_chunk,tool_call,tool_call_update,agent_message_chunk,usage_update"
+	if got := strings.Join(h.types(), ","); got != want {
+		t.Fatalf("update types = %s, want %s", got, want)
+	}
+	if status := h.updates[2].Status; status != acpclient.ToolCallStatusCompleted {
+		t.Fatalf("tool call status = %q, want completed", status)
+	}
+}
+
+func TestLoadRejectsInvalidFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	if err := Save(path, &Fixture{Turns: []Turn{{Match: Match{Regex: "("}, Steps: []Step{{Update: textUpdate("x")}}}}}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid match regex") {
+		t.Fatalf("Load() error = %v, want invalid regex", err)
+	}
+}
+
+func TestRecordFakeAgentAndReplay(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping record integration test in short mode")
+	}
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("runtime.Caller failed")
+	}
+	fakeAgent := filepath.Join(filepath.Dir(thisFile), "..", "acpclient", "testdata", "fake_agent.go")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	f, err := Record(ctx, RecordConfig{
+		DriverID: "fake",
+		Launch:   acpclient.LaunchConfig{Command: "go", Args: []string{"run", fakeAgent}, WorkDir: t.TempDir()},
+		Prompts:  []string{"hello"},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if f.DriverID != "fake" || len(f.Turns) != 1 {
+		t.Fatalf("fixture = %+v", f)
+	}
+	turn := f.Turns[0]
+	if turn.Prompt != "hello" || turn.Usage == nil || turn.Usage.TotalTokens != 15 {
+		t.Fatalf("turn = %+v", turn)
+	}
+
+	client, h, sessionID := startReplay(t, f)
+	result, err := client.PromptText(ctx, sessionID, "hello")
+	if err != nil {
+		t.Fatalf("PromptText() error = %v", err)
+	}
+	if result.Text != "FAKE_REPLY prompt=hello" {
+		t.Fatalf("text = %q", result.Text)
+	}
+	if len(h.writes) != 1 || h.writes[0].Path != "from-fake.txt" {
+		t.Fatalf("writes = %+v", h.writes)
+	}
+}
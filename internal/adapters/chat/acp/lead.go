@@ -22,6 +22,7 @@ import (
 	acpproto "github.com/coder/acp-go-sdk"
 	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
 	eventbridge "github.com/yoke233/zhanggui/internal/adapters/events/bridge"
+	"github.com/yoke233/zhanggui/internal/adapters/leakscan"
 	v2sandbox "github.com/yoke233/zhanggui/internal/adapters/sandbox"
 	workspaceclone "github.com/yoke233/zhanggui/internal/adapters/workspace/clone"
 	workspacegit "github.com/yoke233/zhanggui/internal/adapters/workspace/git"
@@ -62,6 +63,9 @@ type LeadAgentConfig struct {
 	ChangeRequestProviders func(token string) []flowapp.ChangeRequestProvider
 	// GitPAT is the personal access token for SCM operations.
 	GitPAT string
+	// LeakScan scans staged changes and PR text before SubmitCode and
+	// CreatePR publish them; findings abort the operation.
+	LeakScan *leakscan.Policy
 
 	// GC controls workspace resource reclamation.
 	GC GCConfig
@@ -1414,6 +1418,17 @@ func (l *LeadAgent) CreatePR(ctx context.Context, sessionID, title, body string)
 		title = record.Branch
 	}
 
+	scanner := l.cfg.LeakScan.ForProject(record.ProjectName)
+	findings, err := scanner.ScanRange(ctx, record.WorkDir, "main", record.Branch)
+	if err != nil {
+		return nil, fmt.Errorf("leak scan: %w", err)
+	}
+	findings = append(findings, scanner.ScanText("pr_title", title)...)
+	findings = append(findings, scanner.ScanText("pr_body", body)...)
+	if len(findings) > 0 {
+		return nil, leakScanError(findings)
+	}
+
 	// Try configured SCM provider (token-based) first.
 	if provider, repo, err := l.detectSCMProvider(ctx, record.RepoPath); err == nil {
 		cr, _, createErr := provider.EnsureOpen(ctx, repo, flowapp.EnsureOpenInput{
@@ -1478,6 +1493,15 @@ func (l *LeadAgent) SubmitCode(ctx context.Context, sessionID string, message st
 		return nil, errors.New("no changes to submit")
 	}
 
+	findings, err := l.cfg.LeakScan.ForProject(record.ProjectName).ScanStaged(ctx, record.WorkDir)
+	if err != nil {
+		return nil, fmt.Errorf("leak scan: %w", err)
+	}
+	if len(findings) > 0 {
+		_ = gitRun(ctx, record.WorkDir, nil, "reset", "-q")
+		return nil, leakScanError(findings)
+	}
+
 	if err := gitRun(ctx, record.WorkDir, nil,
 		"-c", "user.name=ai-flow",
 		"-c", "user.email=ai-flow@local",
@@ -1497,6 +1521,11 @@ func (l *LeadAgent) SubmitCode(ctx context.Context, sessionID string, message st
 	return l.saveSubmitted(record, strings.TrimSpace(sha)), nil
 }
 
+func leakScanError(findings []core.LeakFinding) error {
+	return fmt.Errorf("leak scan found %d potential secret(s); remove them before publishing:\n%s",
+		len(findings), leakscan.Summary(findings))
+}
+
 // RefreshPR queries the SCM provider or gh CLI to update the PR state.
 func (l *LeadAgent) RefreshPR(ctx context.Context, sessionID string) (*chatapp.GitStats, error) {
 	sessionID = strings.TrimSpace(sessionID)
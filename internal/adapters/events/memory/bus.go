@@ -2,31 +2,123 @@ package memory
 
 import (
 	"context"
+	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/yoke233/zhanggui/internal/core"
 )
 
-// Bus is an in-memory channel-based EventBus implementation.
+// Bus is an in-memory channel-based EventBus implementation. Every published
+// event is stamped with a monotonic sequence number (Event.Seq).
 type Bus struct {
 	mu   sync.RWMutex
 	subs []*sub
+
+	// pubMu serialises Publish so sequence numbers reach every subscriber in
+	// order; it also guards the per-subscriber overflow state.
+	pubMu sync.Mutex
+	seq   atomic.Int64
+
+	// reserve persists a ceiling before seq passes it; reserved is the
+	// current ceiling. Both are guarded by pubMu.
+	reserve      func(ctx context.Context, upTo int64) error
+	reserveBlock int64
+	reserved     int64
+
+	// maxWait bounds how long Publish waits on a full Backpressure
+	// subscriber before dropping the event for it.
+	maxWait time.Duration
 }
 
+// defaultMaxWait is the Backpressure wait when WithBackpressureWait is not
+// given. It is long enough to ride out a slow flush but short enough that a
+// stalled consumer cannot wedge every producer.
+const defaultMaxWait = 5 * time.Second
+
 type sub struct {
 	types  map[core.EventType]struct{}
 	ch     chan core.Event
 	cancel func()
 	done   bool
+
+	notifyOverflow bool
+	backpressure   bool
+	dropFrom       int64
+	dropTo         int64
+	dropped        int64
+}
+
+// Option configures a Bus.
+type Option func(*Bus)
+
+// WithStartSeq continues sequence numbering after seq, typically the highest
+// sequence number already persisted.
+func WithStartSeq(seq int64) Option {
+	return func(b *Bus) { b.seq.Store(seq) }
+}
+
+// WithSeqReservation makes the bus call reserve before issuing a sequence
+// number above the last reserved ceiling, raising the ceiling by block at a
+// time. Persisting the ceiling and seeding WithStartSeq from it keeps a
+// restarted bus from reissuing numbers of events that were never persisted.
+func WithSeqReservation(block int64, reserve func(ctx context.Context, upTo int64) error) Option {
+	return func(b *Bus) {
+		if block <= 0 {
+			block = 1
+		}
+		b.reserveBlock = block
+		b.reserve = reserve
+	}
+}
+
+// WithBackpressureWait sets how long Publish waits on a full Backpressure
+// subscriber before dropping the event for it.
+func WithBackpressureWait(d time.Duration) Option {
+	return func(b *Bus) { b.maxWait = d }
 }
 
 // NewBus creates a new in-memory EventBus.
-func NewBus() *Bus {
-	return &Bus{}
+func NewBus(opts ...Option) *Bus {
+	b := &Bus{maxWait: defaultMaxWait}
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.reserved = b.seq.Load()
+	return b
+}
+
+// LastSeq returns the sequence number of the most recently published event.
+func (b *Bus) LastSeq() int64 {
+	return b.seq.Load()
 }
 
-// Publish sends an event to all matching subscribers.
-func (b *Bus) Publish(_ context.Context, event core.Event) {
+// Publish assigns the next sequence number to event (unless it already has
+// one) and sends it to all matching subscribers. A subscriber whose buffer is
+// full misses the event; for a Backpressure subscriber Publish first waits
+// up to the bus's backpressure wait or until ctx ends. Subscribers that
+// asked for NotifyOverflow or Backpressure receive an EventStreamOverflow
+// marker ahead of their next delivered event.
+func (b *Bus) Publish(ctx context.Context, event core.Event) {
+	b.pubMu.Lock()
+	defer b.pubMu.Unlock()
+
+	if event.Seq == 0 {
+		event.Seq = b.seq.Add(1)
+	} else if event.Seq > b.seq.Load() {
+		b.seq.Store(event.Seq)
+	}
+	if b.reserve != nil && event.Seq > b.reserved {
+		upTo := event.Seq + b.reserveBlock - 1
+		if err := b.reserve(context.WithoutCancel(ctx), upTo); err != nil {
+			// Publishing goes on; the next event retries the reservation.
+			slog.Warn("event bus: reserve sequence numbers failed", "up_to", upTo, "error", err)
+		} else {
+			b.reserved = upTo
+		}
+	}
+
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
@@ -39,11 +131,66 @@ func (b *Bus) Publish(_ context.Context, event core.Event) {
 				continue
 			}
 		}
-		select {
-		case sub.ch <- event:
-		default:
+		sub.deliver(ctx, event, b.maxWait)
+	}
+}
+
+func (s *sub) deliver(ctx context.Context, event core.Event, maxWait time.Duration) {
+	var wait <-chan time.Time
+	if s.backpressure {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		wait = timer.C
+	}
+	if s.dropped > 0 {
+		marker := core.Event{
+			Type: core.EventStreamOverflow,
+			Data: map[string]any{
+				"from_seq": s.dropFrom,
+				"to_seq":   s.dropTo,
+				"dropped":  s.dropped,
+			},
+			Timestamp: time.Now().UTC(),
+		}
+		if !s.send(ctx, marker, wait) {
+			s.drop(event.Seq)
+			return
 		}
+		s.dropped = 0
+	}
+	if !s.send(ctx, event, wait) && (s.notifyOverflow || s.backpressure) {
+		s.drop(event.Seq)
+	}
+}
+
+// send puts event into the subscriber's buffer. With a nil wait it gives up
+// at once when the buffer is full; otherwise it blocks until wait fires or
+// ctx ends.
+func (s *sub) send(ctx context.Context, event core.Event, wait <-chan time.Time) bool {
+	select {
+	case s.ch <- event:
+		return true
+	default:
+	}
+	if wait == nil {
+		return false
+	}
+	select {
+	case s.ch <- event:
+		return true
+	case <-wait:
+		slog.Warn("event bus: subscriber still full, dropping event", "seq", event.Seq, "type", event.Type)
+	case <-ctx.Done():
+	}
+	return false
+}
+
+func (s *sub) drop(seq int64) {
+	if s.dropped == 0 {
+		s.dropFrom = seq
 	}
+	s.dropTo = seq
+	s.dropped++
 }
 
 // Subscribe creates a new subscription. If opts.Types is empty, all events are received.
@@ -60,8 +207,10 @@ func (b *Bus) Subscribe(opts core.SubscribeOpts) *core.Subscription {
 	}
 
 	sub := &sub{
-		types: types,
-		ch:    ch,
+		types:          types,
+		ch:             ch,
+		notifyOverflow: opts.NotifyOverflow,
+		backpressure:   opts.Backpressure,
 	}
 
 	b.mu.Lock()
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestBusAssignsMonotonicSeq(t *testing.T) {
+	b := NewBus(WithStartSeq(41))
+	sub := b.Subscribe(core.SubscribeOpts{})
+	defer sub.Cancel()
+
+	for i := 0; i < 3; i++ {
+		b.Publish(context.Background(), core.Event{Type: core.EventWorkItemStarted})
+	}
+	for want := int64(42); want <= 44; want++ {
+		if ev := <-sub.C; ev.Seq != want {
+			t.Fatalf("seq = %d, want %d", ev.Seq, want)
+		}
+	}
+	if got := b.LastSeq(); got != 44 {
+		t.Fatalf("LastSeq = %d, want 44", got)
+	}
+}
+
+func TestBusReservesSeqBlocks(t *testing.T) {
+	var reserved []int64
+	b := NewBus(WithStartSeq(10), WithSeqReservation(4, func(_ context.Context, upTo int64) error {
+		reserved = append(reserved, upTo)
+		return nil
+	}))
+	for i := 0; i < 6; i++ {
+		b.Publish(context.Background(), core.Event{Type: core.EventWorkItemStarted})
+	}
+	// seq 11 reserves through 14, seq 15 through 18.
+	if len(reserved) != 2 || reserved[0] != 14 || reserved[1] != 18 {
+		t.Fatalf("reserved = %v, want [14 18]", reserved)
+	}
+}
+
+func TestBusReportsOverflow(t *testing.T) {
+	b := NewBus()
+	notified := b.Subscribe(core.SubscribeOpts{BufferSize: 1, NotifyOverflow: true})
+	defer notified.Cancel()
+	silent := b.Subscribe(core.SubscribeOpts{BufferSize: 1})
+	defer silent.Cancel()
+
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		b.Publish(ctx, core.Event{Type: core.EventWorkItemStarted})
+	}
+	if ev := <-notified.C; ev.Seq != 1 {
+		t.Fatalf("first event seq = %d, want 1", ev.Seq)
+	}
+	if ev := <-silent.C; ev.Seq != 1 {
+		t.Fatalf("silent first event seq = %d, want 1", ev.Seq)
+	}
+
+	b.Publish(ctx, core.Event{Type: core.EventWorkItemStarted})
+	marker := <-notified.C
+	if marker.Type != core.EventStreamOverflow || marker.Data["from_seq"] != int64(2) || marker.Data["to_seq"] != int64(4) || marker.Data["dropped"] != int64(3) {
+		t.Fatalf("marker = %+v", marker)
+	}
+	// The marker took the only slot, so seq 5 extends a new gap.
+	b.Publish(ctx, core.Event{Type: core.EventWorkItemStarted})
+	marker = <-notified.C
+	if marker.Type != core.EventStreamOverflow || marker.Data["from_seq"] != int64(5) || marker.Data["to_seq"] != int64(5) {
+		t.Fatalf("second marker = %+v", marker)
+	}
+	if ev := <-silent.C; ev.Seq != 5 {
+		t.Fatalf("silent subscriber got seq %d, want 5 without a marker", ev.Seq)
+	}
+}
+
+func TestBusBackpressureWaitsThenReportsDrop(t *testing.T) {
+	b := NewBus(WithBackpressureWait(50 * time.Millisecond))
+	sub := b.Subscribe(core.SubscribeOpts{BufferSize: 1, Backpressure: true})
+	defer sub.Cancel()
+
+	ctx := context.Background()
+	b.Publish(ctx, core.Event{Type: core.EventWorkItemStarted})
+	published := make(chan struct{})
+	go func() {
+		b.Publish(ctx, core.Event{Type: core.EventWorkItemStarted})
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatal("Publish returned while the subscriber was full")
+	case <-time.After(20 * time.Millisecond):
+	}
+	for want := int64(1); want <= 2; want++ {
+		if ev := <-sub.C; ev.Seq != want {
+			t.Fatalf("seq = %d, want %d", ev.Seq, want)
+		}
+	}
+	<-published
+
+	// Nobody reads: seq 4 waits out the limit, seq 5's publisher gives up.
+	b.Publish(ctx, core.Event{Type: core.EventWorkItemStarted})
+	start := time.Now()
+	b.Publish(ctx, core.Event{Type: core.EventWorkItemStarted})
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Fatalf("Publish waited %v, want about the 50ms limit", waited)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	b.Publish(cancelled, core.Event{Type: core.EventWorkItemStarted})
+
+	if ev := <-sub.C; ev.Seq != 3 {
+		t.Fatalf("seq = %d, want 3", ev.Seq)
+	}
+	go b.Publish(ctx, core.Event{Type: core.EventWorkItemStarted})
+	marker := <-sub.C
+	if marker.Type != core.EventStreamOverflow || marker.Data["from_seq"] != int64(4) || marker.Data["to_seq"] != int64(5) {
+		t.Fatalf("marker = %+v", marker)
+	}
+	if ev := <-sub.C; ev.Seq != 6 {
+		t.Fatalf("seq = %d, want 6", ev.Seq)
+	}
+}
@@ -35,6 +35,9 @@ type ACPExecutorConfig struct {
 	TokenRegistry            *httpx.TokenRegistry
 	ServerAddr               string // e.g. "http://127.0.0.1:8080"
 	AuditLogger              *audit.Logger
+	// Secrets resolves vault references in MCP server headers and env.
+	// Launch env references are expanded by the sandbox.
+	Secrets core.SecretResolver
 
 	// ActionContextBuilder generates per-run reference materials.
 	// When nil, action-context is not injected (graceful degradation).
@@ -100,6 +103,10 @@ func NewACPActionExecutor(cfg ACPExecutorConfig) flowapp.ActionExecutor {
 			}
 		}
 
+		for k, v := range resolveActionEnv(action) {
+			extraEnv[k] = v
+		}
+
 		launchCfg, err := acpclient.PrepareLaunch(execCtx, acpclient.BootstrapConfig{
 			Profile:  profile,
 			WorkDir:  workDir,
@@ -158,7 +165,8 @@ func NewACPActionExecutor(cfg ACPExecutorConfig) flowapp.ActionExecutor {
 		acpCaps := acpclient.InitCapabilities(profile)
 
 		reuse := profile.Session.Reuse
-		mcpFactory := buildActionMCPFactory(action, profile, run.ID, cfg.MCPResolver)
+		mcpFactory := withVaultMCPServers(execCtx, buildActionMCPFactory(action, profile, run.ID, cfg.MCPResolver),
+			cfg.Secrets, action.WorkItemID, profile.ID)
 		publishRunAudit(execCtx, cfg.Bus, cfg.AuditLogger, action, run, "session.acquire", "started", map[string]any{
 			"agent_id":      profile.ID,
 			"session_reuse": reuse,
@@ -179,6 +187,7 @@ func NewACPActionExecutor(cfg ACPExecutorConfig) flowapp.ActionExecutor {
 			MaxTurns:        profile.Session.MaxTurns,
 			ExtraSkills:     extraSkills,
 			EphemeralSkills: ephemeralSkills,
+			Requirements:    resolveExecutorRequirements(action),
 		})
 		if err != nil {
 			publishRunAudit(execCtx, cfg.Bus, cfg.AuditLogger, action, run, "session.acquire", "failed", map[string]any{
@@ -366,6 +375,30 @@ func resolveActionAgent(ctx context.Context, registry core.AgentRegistry, action
 	return registry.ResolveForAction(ctx, action)
 }
 
+// resolveExecutorRequirements reads action.Config["executor_requirements"], a
+// label map the distributed session manager matches against executor labels.
+func resolveExecutorRequirements(action *core.Action) map[string]string {
+	if action == nil {
+		return nil
+	}
+	raw, ok := action.Config["executor_requirements"].(map[string]any)
+	if !ok || len(raw) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(raw))
+	for k, v := range raw {
+		key := strings.TrimSpace(k)
+		if key == "" || v == nil {
+			continue
+		}
+		out[key] = strings.TrimSpace(fmt.Sprint(v))
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
 func buildActionMCPFactory(action *core.Action, profile *core.AgentProfile, runID int64, resolver func(profileID string, agentSupportsSSE bool) []acpproto.McpServer) func(agentSupportsSSE bool) []acpproto.McpServer {
 	if resolver == nil || action == nil || profile == nil || !profile.MCP.Enabled {
 		return nil
@@ -4,17 +4,19 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
 
+	"github.com/yoke233/zhanggui/internal/adapters/leakscan"
 	workspaceclone "github.com/yoke233/zhanggui/internal/adapters/workspace/clone"
 	flowapp "github.com/yoke233/zhanggui/internal/application/flow"
 	"github.com/yoke233/zhanggui/internal/core"
 )
 
-func runBuiltinGitCommitPush(ctx context.Context, bus core.EventBus, tokens flowapp.SCMTokens, action *core.Action, run *core.Run) error {
+func runBuiltinGitCommitPush(ctx context.Context, bus core.EventBus, tokens flowapp.SCMTokens, scanner *leakscan.Scanner, provenance core.ProvenanceRecorder, action *core.Action, run *core.Run) error {
 	ws := flowapp.WorkspaceFromContext(ctx)
 	if ws == nil || strings.TrimSpace(ws.Path) == "" {
 		return fmt.Errorf("builtin git_commit_push: workspace is required")
@@ -41,6 +43,16 @@ func runBuiltinGitCommitPush(ctx context.Context, bus core.EventBus, tokens flow
 		})
 	}
 
+	findings, err := scanner.ScanStaged(ctx, ws.Path)
+	if err != nil {
+		return fmt.Errorf("builtin git_commit_push: leak scan: %w", err)
+	}
+	if len(findings) > 0 {
+		// Leave the files in the worktree for the agent to fix, but unstaged.
+		_ = gitRun(ctx, ws.Path, nil, "reset", "-q")
+		return blockOnLeaks(ctx, bus, action, run, "git_commit_push", findings)
+	}
+
 	if err := gitRun(ctx, ws.Path, nil,
 		"-c", "user.name=ai-flow",
 		"-c", "user.email=ai-flow@local",
@@ -50,9 +62,11 @@ func runBuiltinGitCommitPush(ctx context.Context, bus core.EventBus, tokens flow
 	}
 
 	// Ensure origin is HTTPS so GIT_ASKPASS works for PAT auth (avoid SSH prompts).
+	repoName := ""
 	originURL, err := gitOutput(ctx, ws.Path, nil, "remote", "get-url", "origin")
 	if err == nil {
 		if remote, parseErr := workspaceclone.ParseRemoteURL(strings.TrimSpace(originURL)); parseErr == nil {
+			repoName = fmt.Sprintf("%s/%s/%s", remote.Host, remote.Owner, remote.Repo)
 			if strings.EqualFold(strings.TrimSpace(remote.Host), "github.com") {
 				httpsURL := fmt.Sprintf("https://github.com/%s/%s.git", remote.Owner, remote.Repo)
 				_ = gitRun(ctx, ws.Path, nil, "remote", "set-url", "origin", httpsURL)
@@ -85,7 +99,7 @@ func runBuiltinGitCommitPush(ctx context.Context, bus core.EventBus, tokens flow
 		branch = "HEAD"
 	}
 
-	pushWithToken := func(tok string) error {
+	pushWithToken := func(tok string, args ...string) error {
 		askpassPath, cleanup, err := writeAskPassCmd(tok)
 		if err != nil {
 			return err
@@ -95,22 +109,48 @@ func runBuiltinGitCommitPush(ctx context.Context, bus core.EventBus, tokens flow
 			"GIT_ASKPASS=" + askpassPath,
 			"GIT_TERMINAL_PROMPT=0",
 		}
-		return gitRun(ctx, ws.Path, env, "push", "-u", "origin", branch)
+		return gitRun(ctx, ws.Path, env, append([]string{"push"}, args...)...)
 	}
 
-	if err := pushWithToken(token); err != nil {
+	if err := pushWithToken(token, "-u", "origin", branch); err != nil {
 		return err
 	}
 
 	sha, _ := gitOutput(ctx, ws.Path, nil, "rev-parse", "HEAD")
-	return storeBuiltinArtifact(ctx, bus, action, run, "git_commit_push: pushed changes", map[string]any{
+	sha = strings.TrimSpace(sha)
+	metadata := map[string]any{
 		"commit":    "pushed",
 		"branch":    branch,
-		"head_sha":  strings.TrimSpace(sha),
+		"head_sha":  sha,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 		"repo_path": repoPath,
 		"worktree":  ws.Path,
-	})
+	}
+
+	// Provenance is best effort: the commit is already published.
+	if provenance != nil && sha != "" {
+		resource, err := provenance.RecordProvenance(ctx, core.ProvenanceSubject{
+			Kind:       core.ProvenanceCommit,
+			WorkItemID: action.WorkItemID,
+			ActionID:   action.ID,
+			RunID:      run.ID,
+			CommitSHA:  sha,
+			Repo:       repoName,
+			Ref:        branch,
+			WorkDir:    ws.Path,
+		})
+		if err != nil {
+			slog.Warn("builtin git_commit_push: record provenance failed", "action_id", action.ID, "commit", sha, "error", err)
+		} else {
+			metadata["provenance_resource_id"] = resource.ID
+			if ref, _ := resource.Metadata["git_note_ref"].(string); ref != "" {
+				if err := pushWithToken(token, "origin", ref); err != nil {
+					slog.Warn("builtin git_commit_push: push provenance notes failed", "action_id", action.ID, "ref", ref, "error", err)
+				}
+			}
+		}
+	}
+	return storeBuiltinArtifact(ctx, bus, action, run, "git_commit_push: pushed changes", metadata)
 }
 
 func gitHasChanges(ctx context.Context, dir string) (bool, error) {
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/yoke233/zhanggui/internal/adapters/leakscan"
 	"github.com/yoke233/zhanggui/internal/core"
 )
 
@@ -42,6 +43,31 @@ func storeBuiltinArtifact(ctx context.Context, bus core.EventBus, action *core.A
 	return nil
 }
 
+// blockOnLeaks fails a builtin that was about to publish credentials. The run
+// is marked need_help so the action blocks for rework instead of retrying,
+// and the findings go out with an action.need_help event.
+func blockOnLeaks(ctx context.Context, bus core.EventBus, action *core.Action, runRec *core.Run, builtin string, findings []core.LeakFinding) error {
+	reason := fmt.Sprintf("leak scan found %d potential secret(s); remove them (or allow-list false positives) before publishing:\n%s",
+		len(findings), leakscan.Summary(findings))
+	runRec.ErrorKind = core.ErrKindNeedHelp
+	runRec.ResultMetadata = map[string]any{"leak_findings": findings}
+	if bus != nil {
+		bus.Publish(ctx, core.Event{
+			Type:       core.EventActionNeedHelp,
+			WorkItemID: action.WorkItemID,
+			ActionID:   action.ID,
+			RunID:      runRec.ID,
+			Timestamp:  time.Now().UTC(),
+			Data: map[string]any{
+				"reason":        reason,
+				"builtin":       builtin,
+				"leak_findings": findings,
+			},
+		})
+	}
+	return fmt.Errorf("builtin %s: %s", builtin, reason)
+}
+
 // writeAskPassCmd writes a temporary Windows .cmd askpass helper that returns
 // x-access-token as username and the provided token as password.
 func writeAskPassCmd(token string) (path string, cleanup func(), err error) {
@@ -258,6 +258,7 @@ func (n *noopStore) FindAgentContext(context.Context, string, int64) (*core.Agen
 }
 func (n *noopStore) UpdateAgentContext(context.Context, *core.AgentContext) error { panic("unused") }
 func (n *noopStore) CreateEvent(context.Context, *core.Event) (int64, error)      { panic("unused") }
+func (n *noopStore) BatchCreateEvents(context.Context, []*core.Event) error       { panic("unused") }
 func (n *noopStore) ListEvents(context.Context, core.EventFilter) ([]*core.Event, error) {
 	panic("unused")
 }
@@ -382,6 +383,7 @@ func (n *noopStore) GetRunProbeRoute(context.Context, int64) (*core.RunProbeRout
 func (n *noopStore) AppendJournal(context.Context, *core.JournalEntry) (int64, error) {
 	panic("unused")
 }
+func (n *noopStore) EnqueueJournal(context.Context, *core.JournalEntry) error       { panic("unused") }
 func (n *noopStore) BatchAppendJournal(context.Context, []*core.JournalEntry) error { panic("unused") }
 func (n *noopStore) ListJournal(context.Context, core.JournalFilter) ([]*core.JournalEntry, error) {
 	panic("unused")
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/yoke233/zhanggui/internal/adapters/leakscan"
 	scmadapter "github.com/yoke233/zhanggui/internal/adapters/scm"
 	flowapp "github.com/yoke233/zhanggui/internal/application/flow"
 	"github.com/yoke233/zhanggui/internal/core"
@@ -13,7 +14,7 @@ import (
 
 // runBuiltinSCMOpenPR creates or finds an open change request using the registered SCM providers.
 // It is provider-agnostic (GitHub PR today; GitLab MR later).
-func runBuiltinSCMOpenPR(ctx context.Context, bus core.EventBus, tokens flowapp.SCMTokens, action *core.Action, run *core.Run) error {
+func runBuiltinSCMOpenPR(ctx context.Context, bus core.EventBus, tokens flowapp.SCMTokens, scanner *leakscan.Scanner, action *core.Action, run *core.Run) error {
 	ws := flowapp.WorkspaceFromContext(ctx)
 	if ws == nil || strings.TrimSpace(ws.Path) == "" {
 		return fmt.Errorf("builtin scm_open_pr: workspace is required")
@@ -52,6 +53,16 @@ func runBuiltinSCMOpenPR(ctx context.Context, bus core.EventBus, tokens flowapp.
 		}
 	}
 
+	findings, err := scanner.ScanRange(ctx, ws.Path, baseBranch, headBranch)
+	if err != nil {
+		return fmt.Errorf("builtin scm_open_pr: leak scan: %w", err)
+	}
+	findings = append(findings, scanner.ScanText("change_request_title", title)...)
+	findings = append(findings, scanner.ScanText("change_request_body", body)...)
+	if len(findings) > 0 {
+		return blockOnLeaks(ctx, bus, action, run, "scm_open_pr", findings)
+	}
+
 	originURL, err := gitOutput(ctx, ws.Path, nil, "remote", "get-url", "origin")
 	if err != nil {
 		return fmt.Errorf("builtin scm_open_pr: resolve origin url: %w", err)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/yoke233/zhanggui/internal/adapters/leakscan"
 	flowapp "github.com/yoke233/zhanggui/internal/application/flow"
 	"github.com/yoke233/zhanggui/internal/core"
 )
@@ -15,6 +16,14 @@ type CompositeStepExecutorConfig struct {
 
 	SCMTokens flowapp.SCMTokens
 
+	// LeakScan scans what git_commit_push and scm_open_pr are about to
+	// publish; findings block them. Nil disables the scan.
+	LeakScan *leakscan.Policy
+
+	// Provenance signs a provenance statement for every commit git_commit_push
+	// publishes. Nil disables it.
+	Provenance core.ProvenanceRecorder
+
 	// UpgradeFunc is called by the self_upgrade builtin to trigger a restart
 	// with a newly built binary. If nil, self_upgrade is disabled.
 	UpgradeFunc UpgradeFunc
@@ -43,9 +52,9 @@ func NewCompositeActionExecutor(cfg CompositeStepExecutorConfig) flowapp.ActionE
 		case "":
 			// fallthrough to ACP
 		case "git_commit_push":
-			return runBuiltinGitCommitPush(ctx, cfg.Bus, cfg.SCMTokens, action, run)
+			return runBuiltinGitCommitPush(ctx, cfg.Bus, cfg.SCMTokens, cfg.LeakScan.ForWorkItem(ctx, action.WorkItemID), cfg.Provenance, action, run)
 		case "scm_open_pr", "github_open_pr":
-			return runBuiltinSCMOpenPR(ctx, cfg.Bus, cfg.SCMTokens, action, run)
+			return runBuiltinSCMOpenPR(ctx, cfg.Bus, cfg.SCMTokens, cfg.LeakScan.ForWorkItem(ctx, action.WorkItemID), action, run)
 		case "self_upgrade":
 			return runBuiltinSelfUpgrade(ctx, cfg.Bus, action, run, cfg.UpgradeFunc)
 		default:
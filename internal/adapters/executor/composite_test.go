@@ -3,12 +3,18 @@ package executor
 import (
 	"context"
 	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+	"github.com/yoke233/zhanggui/internal/adapters/leakscan"
 	flowapp "github.com/yoke233/zhanggui/internal/application/flow"
 	runtimeapp "github.com/yoke233/zhanggui/internal/application/runtime"
 	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/config"
 )
 
 type recordingSink struct {
@@ -88,6 +94,94 @@ func TestNewCompositeActionExecutor(t *testing.T) {
 			t.Fatalf("ACP executor not called as expected: called=%v step=%p run=%p", called, gotStep, gotRun)
 		}
 	})
+
+	t.Run("git_commit_push blocks leaked secrets", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git not available")
+		}
+		dir := t.TempDir()
+		if _, err := gitOutput(context.Background(), dir, nil, "init", "-q"); err != nil {
+			t.Fatalf("git init: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("DB_PASSWORD=\"s3cr3t-value\"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		policy, err := leakscan.NewPolicy(config.LeakScanConfig{Enabled: true, BlockedPaths: []string{".env"}}, nil)
+		if err != nil {
+			t.Fatalf("NewPolicy: %v", err)
+		}
+		execFn := NewCompositeActionExecutor(CompositeStepExecutorConfig{LeakScan: policy})
+		ctx := flowapp.ContextWithWorkspace(context.Background(), &core.Workspace{Path: dir})
+		run := &core.Run{ID: 9}
+		err = execFn(ctx, &core.Action{ID: 4, Config: map[string]any{"builtin": "git_commit_push"}}, run)
+		if err == nil || !strings.Contains(err.Error(), "- .env: File must not be committed (blocked-path)") {
+			t.Fatalf("execFn() error = %v", err)
+		}
+		if run.ErrorKind != core.ErrKindNeedHelp || run.ResultMetadata["leak_findings"] == nil {
+			t.Fatalf("run = %+v", run)
+		}
+		if _, err := gitOutput(context.Background(), dir, nil, "rev-parse", "--verify", "HEAD"); err == nil {
+			t.Fatal("a commit was created despite the findings")
+		}
+		if staged, _ := gitOutput(context.Background(), dir, nil, "diff", "--cached", "--name-only"); strings.TrimSpace(staged) != "" {
+			t.Fatalf("files left staged: %q", staged)
+		}
+	})
+
+	t.Run("git_commit_push records provenance and pushes notes", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git not available")
+		}
+		ctx := context.Background()
+		remote := t.TempDir()
+		if _, err := gitOutput(ctx, remote, nil, "init", "-q", "--bare"); err != nil {
+			t.Fatalf("git init --bare: %v", err)
+		}
+		dir := t.TempDir()
+		if _, err := gitOutput(ctx, dir, nil, "init", "-q"); err != nil {
+			t.Fatalf("git init: %v", err)
+		}
+		if _, err := gitOutput(ctx, dir, nil, "remote", "add", "origin", remote); err != nil {
+			t.Fatalf("git remote add: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		recorder := &stubProvenanceRecorder{}
+		execFn := NewCompositeActionExecutor(CompositeStepExecutorConfig{
+			SCMTokens:  flowapp.SCMTokens{GitHub: "test-token"},
+			Provenance: recorder,
+		})
+		wsCtx := flowapp.ContextWithWorkspace(ctx, &core.Workspace{Path: dir})
+		run := &core.Run{ID: 9}
+		if err := execFn(wsCtx, &core.Action{ID: 4, WorkItemID: 2, Config: map[string]any{"builtin": "git_commit_push"}}, run); err != nil {
+			t.Fatalf("execFn() error = %v", err)
+		}
+		sha, _ := run.ResultMetadata["head_sha"].(string)
+		if recorder.subject.CommitSHA != sha || recorder.subject.Kind != core.ProvenanceCommit || recorder.subject.RunID != 9 {
+			t.Fatalf("subject = %+v, head_sha = %q", recorder.subject, sha)
+		}
+		if run.ResultMetadata["provenance_resource_id"] != int64(11) {
+			t.Fatalf("result metadata = %+v", run.ResultMetadata)
+		}
+		if _, err := gitOutput(ctx, remote, nil, "notes", "--ref", "refs/notes/provenance", "show", sha); err != nil {
+			t.Fatalf("provenance note was not pushed: %v", err)
+		}
+	})
+}
+
+// stubProvenanceRecorder adds a note the way the provenance service does.
+type stubProvenanceRecorder struct {
+	subject core.ProvenanceSubject
+}
+
+func (s *stubProvenanceRecorder) RecordProvenance(ctx context.Context, subject core.ProvenanceSubject) (*core.Resource, error) {
+	s.subject = subject
+	if _, err := gitOutput(ctx, subject.WorkDir, nil, "-c", "user.name=t", "-c", "user.email=t@local",
+		"notes", "--ref", "refs/notes/provenance", "add", "-m", "{}", subject.CommitSHA); err != nil {
+		return nil, err
+	}
+	return &core.Resource{ID: 11, Metadata: map[string]any{"git_note_ref": "refs/notes/provenance"}}, nil
 }
 
 var _ runtimeapp.EventSink = (*recordingSink)(nil)
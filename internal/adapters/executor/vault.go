@@ -0,0 +1,164 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	acpproto "github.com/coder/acp-go-sdk"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// resolveActionEnv reads the extra agent environment of an action:
+// action.Config["env"] maps variable names to values, which may reference
+// vault secrets as "${vault.NAME}", and action.Config["secrets"] lists vault
+// secrets exported under their own name. References are expanded by the
+// sandbox at launch, so the values never enter the action record.
+func resolveActionEnv(action *core.Action) map[string]string {
+	if action == nil {
+		return nil
+	}
+	out := map[string]string{}
+	if names, ok := action.Config["secrets"].([]any); ok {
+		for _, raw := range names {
+			name, _ := raw.(string)
+			if name = strings.TrimSpace(name); core.ValidVaultSecretName(name) {
+				out[name] = core.VaultPlaceholder(name)
+			} else {
+				slog.Warn("action env: ignoring invalid secret name", "action_id", action.ID, "name", raw)
+			}
+		}
+	}
+	if env, ok := action.Config["env"].(map[string]any); ok {
+		for k, v := range env {
+			key := strings.TrimSpace(k)
+			if key == "" || v == nil {
+				continue
+			}
+			out[key] = fmt.Sprint(v)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// withVaultMCPServers wraps an MCP factory so that "${vault.NAME}" references
+// in server headers and env, such as those produced by a "vault.NAME"
+// auth_secret_ref, are replaced with the secrets visible to the run. A server
+// whose references cannot be resolved is dropped rather than started with a
+// literal placeholder.
+func withVaultMCPServers(ctx context.Context, factory func(bool) []acpproto.McpServer, secrets core.SecretResolver, workItemID int64, profileID string) func(bool) []acpproto.McpServer {
+	if factory == nil {
+		return nil
+	}
+	return func(agentSupportsSSE bool) []acpproto.McpServer {
+		servers := factory(agentSupportsSSE)
+		refs := mcpVaultRefs(servers)
+		if len(refs) == 0 {
+			return servers
+		}
+		var values map[string]string
+		if secrets != nil {
+			var err error
+			values, err = secrets.ResolveSecrets(ctx, workItemID, profileID)
+			if err != nil {
+				slog.Warn("mcp: resolve vault secrets", "work_item_id", workItemID, "profile", profileID, "error", err)
+			}
+		}
+		out := servers[:0]
+		for _, server := range servers {
+			expanded, err := expandMCPServer(server, values)
+			if err != nil {
+				slog.Warn("mcp: dropping server with unresolved vault reference",
+					"server", mcpServerName(server), "profile", profileID, "error", err)
+				continue
+			}
+			out = append(out, expanded)
+		}
+		return out
+	}
+}
+
+func mcpVaultRefs(servers []acpproto.McpServer) []string {
+	var values []string
+	for _, server := range servers {
+		for _, h := range mcpHeaders(server) {
+			values = append(values, h.Value)
+		}
+		if server.Stdio != nil {
+			for _, e := range server.Stdio.Env {
+				values = append(values, e.Value)
+			}
+		}
+	}
+	return core.VaultRefNames(values...)
+}
+
+func expandMCPServer(server acpproto.McpServer, values map[string]string) (acpproto.McpServer, error) {
+	expandHeaders := func(in []acpproto.HttpHeader) ([]acpproto.HttpHeader, error) {
+		out := make([]acpproto.HttpHeader, len(in))
+		for i, h := range in {
+			v, err := core.ExpandVaultRefs(h.Value, values)
+			if err != nil {
+				return nil, fmt.Errorf("header %s: %w", h.Name, err)
+			}
+			out[i] = acpproto.HttpHeader{Name: h.Name, Value: v}
+		}
+		return out, nil
+	}
+	var err error
+	switch {
+	case server.Sse != nil:
+		sse := *server.Sse
+		if sse.Headers, err = expandHeaders(sse.Headers); err != nil {
+			return server, err
+		}
+		server.Sse = &sse
+	case server.Http != nil:
+		h := *server.Http
+		if h.Headers, err = expandHeaders(h.Headers); err != nil {
+			return server, err
+		}
+		server.Http = &h
+	case server.Stdio != nil:
+		stdio := *server.Stdio
+		env := make([]acpproto.EnvVariable, len(stdio.Env))
+		for i, e := range stdio.Env {
+			v, err := core.ExpandVaultRefs(e.Value, values)
+			if err != nil {
+				return server, fmt.Errorf("env %s: %w", e.Name, err)
+			}
+			env[i] = acpproto.EnvVariable{Name: e.Name, Value: v}
+		}
+		stdio.Env = env
+		server.Stdio = &stdio
+	}
+	return server, nil
+}
+
+func mcpHeaders(server acpproto.McpServer) []acpproto.HttpHeader {
+	switch {
+	case server.Sse != nil:
+		return server.Sse.Headers
+	case server.Http != nil:
+		return server.Http.Headers
+	default:
+		return nil
+	}
+}
+
+func mcpServerName(server acpproto.McpServer) string {
+	switch {
+	case server.Sse != nil:
+		return server.Sse.Name
+	case server.Http != nil:
+		return server.Http.Name
+	case server.Stdio != nil:
+		return server.Stdio.Name
+	default:
+		return ""
+	}
+}
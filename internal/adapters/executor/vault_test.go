@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	acpproto "github.com/coder/acp-go-sdk"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type stubSecretResolver map[string]string
+
+func (s stubSecretResolver) ResolveSecrets(context.Context, int64, string) (map[string]string, error) {
+	return s, nil
+}
+
+func TestResolveActionEnv(t *testing.T) {
+	env := resolveActionEnv(&core.Action{Config: map[string]any{
+		"secrets": []any{"NPM_TOKEN", "bad-name"},
+		"env":     map[string]any{"API_URL": "https://staging", "API_KEY": "${vault.STAGING_KEY}", "RETRIES": float64(3)},
+	}})
+	want := map[string]string{
+		"NPM_TOKEN": "${vault.NPM_TOKEN}",
+		"API_URL":   "https://staging",
+		"API_KEY":   "${vault.STAGING_KEY}",
+		"RETRIES":   "3",
+	}
+	if len(env) != len(want) {
+		t.Fatalf("env = %v, want %v", env, want)
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Fatalf("env[%s] = %q, want %q", k, env[k], v)
+		}
+	}
+	if env := resolveActionEnv(&core.Action{}); env != nil {
+		t.Fatalf("env without config = %v", env)
+	}
+}
+
+func TestWithVaultMCPServers(t *testing.T) {
+	factory := func(bool) []acpproto.McpServer {
+		return []acpproto.McpServer{
+			{Sse: &acpproto.McpServerSseInline{Name: "tracker", Headers: []acpproto.HttpHeader{{Name: "Authorization", Value: "Bearer ${vault.TRACKER_TOKEN}"}}}},
+			{Stdio: &acpproto.McpServerStdio{Name: "db", Env: []acpproto.EnvVariable{{Name: "DB_URL", Value: "${vault.MISSING}"}}}},
+			{Stdio: &acpproto.McpServerStdio{Name: "plain", Env: []acpproto.EnvVariable{{Name: "MODE", Value: "ro"}}}},
+		}
+	}
+	wrapped := withVaultMCPServers(context.Background(), factory, stubSecretResolver{"TRACKER_TOKEN": "tr-1"}, 1, "worker")
+	servers := wrapped(true)
+	if len(servers) != 2 || servers[0].Sse == nil || servers[1].Stdio == nil || servers[1].Stdio.Name != "plain" {
+		t.Fatalf("servers = %+v", servers)
+	}
+	if got := servers[0].Sse.Headers[0].Value; got != "Bearer tr-1" {
+		t.Fatalf("header = %q", got)
+	}
+	if withVaultMCPServers(context.Background(), nil, nil, 0, "") != nil {
+		t.Fatal("expected nil factory")
+	}
+}
@@ -127,6 +127,10 @@ func (h *Handler) updateAction(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid action ID", "BAD_ID")
 		return
 	}
+	expectedVersion, ok := readIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	existing, err := h.store.GetAction(r.Context(), id)
 	if err == core.ErrNotFound {
@@ -138,6 +142,10 @@ func (h *Handler) updateAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkIfMatch(w, expectedVersion, existing.Version) {
+		return
+	}
+
 	// Only allow editing pending actions.
 	if existing.Status != core.ActionPending {
 		writeError(w, http.StatusConflict, "only pending actions can be edited", "INVALID_STATE")
@@ -198,9 +206,13 @@ func (h *Handler) updateAction(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.store.UpdateAction(r.Context(), existing); err != nil {
+		if writeVersionConflict(w, r, err) {
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	setETag(w, existing.Version)
 	writeJSON(w, http.StatusOK, existing)
 }
 
@@ -253,6 +265,7 @@ func (h *Handler) getAction(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	setETag(w, s.Version)
 	writeJSON(w, http.StatusOK, s)
 }
 
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -70,7 +71,10 @@ func (h *Handler) actionDecision(w http.ResponseWriter, r *http.Request) {
 	}
 	signalSource := core.SignalSourceHuman
 	actor := "human"
-	if info, ok := httpx.AuthFromContext(r.Context()); ok {
+	actorUserID, username, signedIn := sessionUser(r)
+	if signedIn {
+		actor = username
+	} else if info, ok := httpx.AuthFromContext(r.Context()); ok {
 		role := strings.TrimSpace(info.Role)
 		if role != "" && role != "admin" {
 			signalSource = core.SignalSourceAgent
@@ -79,13 +83,14 @@ func (h *Handler) actionDecision(w http.ResponseWriter, r *http.Request) {
 	}
 
 	sig := &core.ActionSignal{
-		ActionID:   actionID,
-		WorkItemID: action.WorkItemID,
-		Type:       sigType,
-		Source:     signalSource,
-		Payload:    payload,
-		Actor:      actor,
-		CreatedAt:  time.Now().UTC(),
+		ActionID:    actionID,
+		WorkItemID:  action.WorkItemID,
+		Type:        sigType,
+		Source:      signalSource,
+		Payload:     payload,
+		Actor:       actor,
+		ActorUserID: actorUserID,
+		CreatedAt:   time.Now().UTC(),
 	}
 	id, err := h.store.CreateActionSignal(r.Context(), sig)
 	if err != nil {
@@ -158,6 +163,10 @@ type actionUnblockRequest struct {
 	Instructions string `json:"instructions,omitempty"` // optional: forwarded to agent as SignalInstruction
 }
 
+// errActionNotBlocked reports an unblock that lost a race with another
+// transition of the action.
+var errActionNotBlocked = errors.New("action is not blocked")
+
 func (h *Handler) actionUnblock(w http.ResponseWriter, r *http.Request) {
 	actionID, ok := urlParamInt64(r, "actionID")
 	if !ok {
@@ -199,6 +208,9 @@ func (h *Handler) actionUnblock(w http.ResponseWriter, r *http.Request) {
 		Actor:      "human",
 		CreatedAt:  time.Now().UTC(),
 	}
+	if userID, username, ok := sessionUser(r); ok {
+		sig.Actor, sig.ActorUserID = username, userID
+	}
 	sigID, err := h.store.CreateActionSignal(r.Context(), sig)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
@@ -210,23 +222,45 @@ func (h *Handler) actionUnblock(w http.ResponseWriter, r *http.Request) {
 	// that will be picked up by ResolveLatestFeedback and forwarded to the agent.
 	if strings.TrimSpace(req.Instructions) != "" {
 		instrSig := &core.ActionSignal{
-			ActionID:   actionID,
-			WorkItemID: action.WorkItemID,
-			Type:       core.SignalInstruction,
-			Source:     core.SignalSourceHuman,
-			Summary:    "human instruction on unblock",
-			Content:    strings.TrimSpace(req.Instructions),
-			Payload:    map[string]any{"reason": req.Reason, "instructions": req.Instructions},
-			Actor:      "human",
-			CreatedAt:  time.Now().UTC(),
+			ActionID:    actionID,
+			WorkItemID:  action.WorkItemID,
+			Type:        core.SignalInstruction,
+			Source:      core.SignalSourceHuman,
+			Summary:     "human instruction on unblock",
+			Content:     strings.TrimSpace(req.Instructions),
+			Payload:     map[string]any{"reason": req.Reason, "instructions": req.Instructions},
+			Actor:       sig.Actor,
+			ActorUserID: sig.ActorUserID,
+			CreatedAt:   time.Now().UTC(),
 		}
 		_, _ = h.store.CreateActionSignal(r.Context(), instrSig)
 	}
 
-	// Transition action back to pending for retry.
-	action.Status = core.ActionPending
-	if err := h.store.UpdateAction(r.Context(), action); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+	// Transition action back to pending for retry, re-reading it when the
+	// engine wrote it since it was loaded above.
+	err = core.RetryOnConflict(func() error {
+		current, err := h.store.GetAction(r.Context(), actionID)
+		if err != nil {
+			return err
+		}
+		if current.Status != core.ActionBlocked {
+			return errActionNotBlocked
+		}
+		current.Status = core.ActionPending
+		if err := h.store.UpdateAction(r.Context(), current); err != nil {
+			return err
+		}
+		action = current
+		return nil
+	})
+	if errors.Is(err, errActionNotBlocked) {
+		writeError(w, http.StatusConflict, err.Error(), "INVALID_STATE")
+		return
+	}
+	if err != nil {
+		if !writeVersionConflict(w, r, err) {
+			writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		}
 		return
 	}
 
@@ -269,6 +303,10 @@ func (h *Handler) listPendingDecisions(w http.ResponseWriter, r *http.Request) {
 	if actions == nil {
 		actions = []*core.Action{}
 	}
+	if actions, err = h.visibleActions(r, actions, "issues:read"); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
 
 	// Enrich each action with latest context signal and recent signals.
 	items := make([]pendingDecisionItem, 0, len(actions))
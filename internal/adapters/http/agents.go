@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpdoctor"
 	"github.com/yoke233/zhanggui/internal/core"
 	"github.com/yoke233/zhanggui/internal/platform/config"
 	"github.com/yoke233/zhanggui/internal/platform/configruntime"
@@ -29,11 +30,16 @@ type DriverConfigService interface {
 	DeleteProfileConfig(ctx context.Context, profileID string) (*configruntime.Snapshot, error)
 }
 
-func registerAgentRoutes(r chi.Router, registry core.AgentRegistry, drivers DriverConfigService) {
+// DriverDoctor runs the ACP conformance check for a configured driver.
+type DriverDoctor interface {
+	DiagnoseDriver(ctx context.Context, driverID string) (*acpdoctor.Report, error)
+}
+
+func registerAgentRoutes(r chi.Router, registry core.AgentRegistry, drivers DriverConfigService, doctor DriverDoctor) {
 	if registry == nil && drivers == nil {
 		return
 	}
-	a := &agentsHandler{registry: registry, drivers: drivers}
+	a := &agentsHandler{registry: registry, drivers: drivers, doctor: doctor}
 
 	// Profiles
 	if registry != nil {
@@ -48,12 +54,14 @@ func registerAgentRoutes(r chi.Router, registry core.AgentRegistry, drivers Driv
 		r.Post("/agents/drivers", a.createDriver)
 		r.Put("/agents/drivers/{driverID}", a.updateDriver)
 		r.Delete("/agents/drivers/{driverID}", a.deleteDriver)
+		r.Post("/agents/drivers/{driverID}/doctor", a.doctorDriver)
 	}
 }
 
 type agentsHandler struct {
 	registry core.AgentRegistry
 	drivers  DriverConfigService
+	doctor   DriverDoctor
 }
 
 // --- Profiles ---
@@ -181,6 +189,20 @@ func (a *agentsHandler) deleteDriver(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (a *agentsHandler) doctorDriver(w http.ResponseWriter, r *http.Request) {
+	if a.doctor == nil {
+		writeError(w, http.StatusServiceUnavailable, "driver doctor is not configured", "DOCTOR_UNAVAILABLE")
+		return
+	}
+	driverID := strings.TrimSpace(chi.URLParam(r, "driverID"))
+	report, err := a.doctor.DiagnoseDriver(r.Context(), driverID)
+	if err != nil {
+		writeDriverError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
 // writeRegistryError maps registry errors to HTTP status codes.
 func writeRegistryError(w http.ResponseWriter, err error) {
 	msg := err.Error()
@@ -8,7 +8,10 @@ import (
 )
 
 func (h *Handler) getProjectErrorRanking(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	data, err := h.store.ProjectErrorRanking(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "ANALYTICS_ERROR")
@@ -21,7 +24,10 @@ func (h *Handler) getProjectErrorRanking(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *Handler) getWorkItemBottleneckActions(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	data, err := h.store.WorkItemBottleneckActions(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "ANALYTICS_ERROR")
@@ -34,7 +40,10 @@ func (h *Handler) getWorkItemBottleneckActions(w http.ResponseWriter, r *http.Re
 }
 
 func (h *Handler) getRunDurationStats(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	data, err := h.store.RunDurationStats(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "ANALYTICS_ERROR")
@@ -47,7 +56,10 @@ func (h *Handler) getRunDurationStats(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getErrorBreakdown(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	data, err := h.store.ErrorBreakdown(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "ANALYTICS_ERROR")
@@ -60,7 +72,10 @@ func (h *Handler) getErrorBreakdown(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getRecentFailures(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	data, err := h.store.RecentFailures(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "ANALYTICS_ERROR")
@@ -73,7 +88,10 @@ func (h *Handler) getRecentFailures(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getWorkItemStatusDistribution(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	data, err := h.store.WorkItemStatusDistribution(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "ANALYTICS_ERROR")
@@ -87,7 +105,10 @@ func (h *Handler) getWorkItemStatusDistribution(w http.ResponseWriter, r *http.R
 
 // getAnalyticsSummary returns all analytics data in a single request for the dashboard.
 func (h *Handler) getAnalyticsSummary(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 
 	type summary struct {
 		ProjectErrors  []core.ProjectErrorRank     `json:"project_errors"`
@@ -150,6 +171,33 @@ func (h *Handler) getAnalyticsSummary(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, s)
 }
 
+// analyticsFilter parses the analytics filter and pins it to a project for
+// session users limited to some projects, since the aggregates cannot be
+// filtered per row. Such a user must name a project unless they belong to
+// exactly one.
+func (h *Handler) analyticsFilter(w http.ResponseWriter, r *http.Request) (core.AnalyticsFilter, bool) {
+	filter := parseAnalyticsFilter(r)
+	if filter.ProjectID != nil {
+		return filter, true
+	}
+	allowed, restricted, err := h.sessionProjectFilter(r, "runs:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return filter, false
+	}
+	if !restricted {
+		return filter, true
+	}
+	if len(allowed) != 1 {
+		writeError(w, http.StatusBadRequest, "project_id is required", "PROJECT_REQUIRED")
+		return filter, false
+	}
+	for id := range allowed {
+		filter.ProjectID = &id
+	}
+	return filter, true
+}
+
 func parseAnalyticsFilter(r *http.Request) core.AnalyticsFilter {
 	f := core.AnalyticsFilter{}
 	if pid, ok := queryInt64(r, "project_id"); ok {
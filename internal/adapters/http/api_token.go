@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/application/tokenapp"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// apiTokenRole is the AuthInfo role of requests made with an issued token.
+const apiTokenRole = "api-token"
+
+type createAPITokenRequest struct {
+	Name     string   `json:"name"`
+	Owner    string   `json:"owner"`
+	Scopes   []string `json:"scopes"`
+	Projects []string `json:"projects"`
+	// ExpiresAt and ExpiresIn ("720h") set a custom expiry; ExpiresIn "0"
+	// issues a token that never expires.
+	ExpiresAt *time.Time `json:"expires_at"`
+	ExpiresIn string     `json:"expires_in"`
+}
+
+type createAPITokenResponse struct {
+	*core.APIToken
+	// Token is the secret; it is only returned here.
+	Token string `json:"token"`
+}
+
+func (h *Handler) tokenService() *tokenapp.Service {
+	return tokenapp.New(tokenapp.Config{Store: h.store})
+}
+
+// NewStoredTokenResolver resolves tokens issued through /tokens against
+// store. Wire it with httpx.TokenRegistry.SetStoredTokenResolver.
+func NewStoredTokenResolver(store core.APITokenStore) httpx.StoredTokenResolver {
+	svc := tokenapp.New(tokenapp.Config{Store: store})
+	return func(ctx context.Context, secret, remoteAddr string) (httpx.AuthInfo, bool) {
+		token, err := svc.Resolve(ctx, secret, remoteAddr)
+		if err != nil {
+			return httpx.AuthInfo{}, false
+		}
+		return httpx.AuthInfo{
+			Role:      apiTokenRole,
+			Scopes:    token.Scopes,
+			Submitter: token.Owner,
+			Projects:  token.Projects,
+		}, true
+	}
+}
+
+func registerAPITokenAdminRoutes(r chi.Router, h *Handler) {
+	r.Get("/tokens", h.listAPITokens)
+	r.Post("/tokens", h.createAPIToken)
+	r.Get("/tokens/{tokenID}", h.getAPIToken)
+	r.Delete("/tokens/{tokenID}", h.revokeAPIToken)
+}
+
+func (h *Handler) listAPITokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.tokenService().List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func (h *Handler) createAPIToken(w http.ResponseWriter, r *http.Request) {
+	var req createAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	in := tokenapp.CreateInput{
+		Name:      req.Name,
+		Owner:     req.Owner,
+		CreatedBy: tokenIssuer(r),
+		Scopes:    req.Scopes,
+		Projects:  req.Projects,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if raw := strings.TrimSpace(req.ExpiresIn); raw != "" {
+		if req.ExpiresAt != nil {
+			writeError(w, http.StatusBadRequest, "set expires_at or expires_in, not both", "BAD_REQUEST")
+			return
+		}
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl < 0 {
+			writeError(w, http.StatusBadRequest, "expires_in must be a duration such as 720h, or 0 for no expiry", "BAD_REQUEST")
+			return
+		}
+		if ttl == 0 {
+			in.NoExpiry = true
+		} else {
+			exp := time.Now().UTC().Add(ttl)
+			in.ExpiresAt = &exp
+		}
+	}
+	token, secret, err := h.tokenService().Create(r.Context(), in)
+	if err != nil {
+		writeAPITokenError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, createAPITokenResponse{APIToken: token, Token: secret})
+}
+
+func (h *Handler) getAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlParamInt64(r, "tokenID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid token ID", "BAD_ID")
+		return
+	}
+	token, err := h.tokenService().Get(r.Context(), id)
+	if err != nil {
+		writeAPITokenError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, token)
+}
+
+// DELETE /tokens/{tokenID} revokes the token; the record is kept for audit.
+func (h *Handler) revokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlParamInt64(r, "tokenID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid token ID", "BAD_ID")
+		return
+	}
+	token, err := h.tokenService().Revoke(r.Context(), id)
+	if err != nil {
+		writeAPITokenError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, token)
+}
+
+// tokenIssuer names who issued a token: the signed-in user, or the
+// submitter or role of the token used for the request.
+func tokenIssuer(r *http.Request) string {
+	if ref := sessionUserRef(r); ref != "" {
+		return ref
+	}
+	info, ok := httpx.AuthFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	if submitter := strings.TrimSpace(info.Submitter); submitter != "" {
+		return submitter
+	}
+	return info.Role
+}
+
+func writeAPITokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, core.ErrNotFound):
+		writeError(w, http.StatusNotFound, "token not found", "NOT_FOUND")
+	case errors.Is(err, tokenapp.ErrInvalidInput):
+		writeError(w, http.StatusBadRequest, err.Error(), "INVALID_TOKEN_REQUEST")
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+	}
+}
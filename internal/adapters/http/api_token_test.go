@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestAPITokenLifecycle(t *testing.T) {
+	s := newSessionTestServer(t)
+	ctx := context.Background()
+	allowed, err := s.store.CreateProject(ctx, &core.Project{Name: "backend", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	other, err := s.store.CreateProject(ctx, &core.Project{Name: "frontend", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	resp := s.do(t, http.MethodPost, "/tokens", "admin-token", nil, map[string]any{
+		"name":       "ci",
+		"owner":      "ci-bot",
+		"scopes":     []string{"issues:read", "issues:write"},
+		"projects":   []string{"backend"},
+		"expires_in": "720h",
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create token: status %d", resp.StatusCode)
+	}
+	var created createAPITokenResponse
+	if err := decodeJSON(resp, &created); err != nil {
+		t.Fatalf("decode token: %v", err)
+	}
+	if !strings.HasPrefix(created.Token, "zgt_") || created.ExpiresAt == nil || created.CreatedBy != "admin" {
+		t.Fatalf("created = %+v", created.APIToken)
+	}
+
+	// The token works within its project whitelist only.
+	resp = s.do(t, http.MethodPost, "/work-items", created.Token, nil, map[string]any{"title": "ci", "project_id": allowed})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create work item in allowed project: status %d", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodPost, "/work-items", created.Token, nil, map[string]any{"title": "ci", "project_id": other})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("create work item in other project: status %d, want 403", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodGet, "/projects/"+itoa64(other), created.Token, nil, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("read other project: status %d, want 403", resp.StatusCode)
+	}
+	// Token management needs the admin scope.
+	resp = s.do(t, http.MethodGet, "/tokens", created.Token, nil, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("list tokens with ci token: status %d, want 403", resp.StatusCode)
+	}
+
+	resp = s.do(t, http.MethodGet, "/tokens", "admin-token", nil, nil)
+	var tokens []*core.APIToken
+	if err := decodeJSON(resp, &tokens); err != nil || len(tokens) != 1 {
+		t.Fatalf("list tokens = %v, %v", tokens, err)
+	}
+	if tokens[0].LastUsedAt == nil || tokens[0].LastUsedIP == "" {
+		t.Fatalf("last use not recorded: %+v", tokens[0])
+	}
+
+	resp = s.do(t, http.MethodDelete, "/tokens/"+itoa64(created.ID), "admin-token", nil, nil)
+	var revoked core.APIToken
+	if err := decodeJSON(resp, &revoked); err != nil || revoked.RevokedAt == nil {
+		t.Fatalf("revoke = %+v, %v", revoked, err)
+	}
+	resp = s.do(t, http.MethodGet, "/work-items?project_id="+itoa64(allowed), created.Token, nil, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("revoked token: status %d, want 401", resp.StatusCode)
+	}
+
+	resp = s.do(t, http.MethodPost, "/tokens", "admin-token", nil, map[string]any{"name": "bad", "scopes": []string{"*"}, "expires_in": "soon"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid expires_in: status %d, want 400", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodGet, "/tokens/999", "admin-token", nil, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing token: status %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,311 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/audit"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+const (
+	// auditBodyLimit caps how much of a request body is parsed into the
+	// audit entry; larger bodies are recorded by size only.
+	auditBodyLimit = 64 << 10
+	// auditValueLimit caps each string value kept from a request body.
+	auditValueLimit = 512
+	// auditErrorLimit caps the error response captured for failed requests.
+	auditErrorLimit = 1 << 10
+
+	defaultAuditLogLimit = 100
+	maxAuditLogLimit     = 1000
+	auditExportPageSize  = 500
+)
+
+// WithAuditRedactor sets the redactor applied to request bodies recorded in
+// the audit log. The default uses the basic redaction rules.
+func WithAuditRedactor(r *audit.Redactor) HandlerOption {
+	return func(h *Handler) { h.auditRedactor = r }
+}
+
+func registerAuditLogAdminRoutes(r chi.Router, h *Handler) {
+	r.Get("/admin/audit-log", h.listAuditLog)
+}
+
+// auditMutations records every mutating request made by a person or an
+// automation token in the audit log, after the handler has run. Requests of
+// agent tokens are left to the journal. Like enforceProjectRoles it runs
+// inline after routing so the route pattern and URL params are known.
+func (h *Handler) auditMutations(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		info, _ := httpx.AuthFromContext(r.Context())
+		if info.Agent || h.store == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		started := time.Now()
+		changes := h.captureAuditBody(r)
+		rec := &auditRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		entry := &core.AuditLogEntry{
+			Actor:      auditActor(info),
+			UserID:     info.UserID,
+			Role:       info.Role,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Changes:    changes,
+			Status:     rec.status,
+			Outcome:    core.AuditOutcomeForStatus(rec.status),
+			Error:      auditErrorMessage(rec.errBody.Bytes()),
+			RemoteIP:   httpx.ClientIP(r),
+			UserAgent:  r.UserAgent(),
+			DurationMs: time.Since(started).Milliseconds(),
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			entry.Route = rctx.RoutePattern()
+			for i, key := range rctx.URLParams.Keys {
+				if key == "*" || i >= len(rctx.URLParams.Values) {
+					continue
+				}
+				if entry.ResourceIDs == nil {
+					entry.ResourceIDs = make(map[string]string)
+				}
+				entry.ResourceIDs[key] = rctx.URLParams.Values[i]
+			}
+		}
+		// The entry outlives a client that disconnects mid-request.
+		if _, err := h.store.AppendAuditLog(context.WithoutCancel(r.Context()), entry); err != nil {
+			slog.Warn("audit log: append failed", "method", entry.Method, "path", entry.Path, "error", err)
+		}
+	})
+}
+
+func auditActor(info httpx.AuthInfo) string {
+	switch {
+	case info.UserID != 0:
+		return core.UserRef(info.UserID)
+	case strings.TrimSpace(info.Submitter) != "":
+		return strings.TrimSpace(info.Submitter)
+	case info.Role != "":
+		return info.Role
+	default:
+		return "anonymous"
+	}
+}
+
+// captureAuditBody reads the JSON request body for the audit entry and
+// hands an identical body on to the handler.
+func (h *Handler) captureAuditBody(r *http.Request) map[string]any {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "" && mediaType != "application/json" {
+		// Uploads and other payloads are recorded by type and size only.
+		return map[string]any{"content_type": mediaType, "bytes": r.ContentLength}
+	}
+	buf, err := io.ReadAll(io.LimitReader(r.Body, auditBodyLimit+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	if err != nil || len(buf) == 0 {
+		return nil
+	}
+	if len(buf) > auditBodyLimit {
+		return map[string]any{"content_type": "application/json", "bytes": r.ContentLength, "truncated": true}
+	}
+	var body any
+	if err := json.Unmarshal(buf, &body); err != nil {
+		return map[string]any{"content_type": "application/json", "bytes": len(buf), "invalid": true}
+	}
+	fields, ok := body.(map[string]any)
+	if !ok {
+		fields = map[string]any{"body": body}
+	}
+	redactor := h.auditRedactor
+	if redactor == nil {
+		redactor = audit.NewRedactor("")
+	}
+	return truncateAuditValues(redactor.RedactData(fields)).(map[string]any)
+}
+
+func truncateAuditValues(v any) any {
+	switch typed := v.(type) {
+	case string:
+		if len(typed) > auditValueLimit {
+			return typed[:auditValueLimit] + "...(truncated)"
+		}
+		return typed
+	case map[string]any:
+		for k, item := range typed {
+			typed[k] = truncateAuditValues(item)
+		}
+		return typed
+	case []any:
+		for i, item := range typed {
+			typed[i] = truncateAuditValues(item)
+		}
+		return typed
+	default:
+		return v
+	}
+}
+
+// auditErrorMessage extracts the "error" field of a JSON error response.
+func auditErrorMessage(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		return payload.Error
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// auditRecorder keeps the status and the start of error responses.
+type auditRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	errBody     bytes.Buffer
+}
+
+func (r *auditRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *auditRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	if r.status >= 400 && r.errBody.Len() < auditErrorLimit {
+		r.errBody.Write(p[:min(len(p), auditErrorLimit-r.errBody.Len())])
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach Flush and Hijack.
+func (r *auditRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
+
+// GET /admin/audit-log lists audited requests, newest first. Filters:
+// actor, method, route (prefix), resource (name=value), outcome, since and
+// until (RFC 3339), limit and offset. format=jsonl exports every match as
+// JSON lines instead.
+func (h *Handler) listAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditLogFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+		return
+	}
+	if r.URL.Query().Get("format") == "jsonl" {
+		h.exportAuditLog(w, r, filter)
+		return
+	}
+	entries, err := h.store.ListAuditLog(r.Context(), filter)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (h *Handler) exportAuditLog(w http.ResponseWriter, r *http.Request, filter core.AuditLogFilter) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+	enc := json.NewEncoder(w)
+	filter.Limit = auditExportPageSize
+	for offset := 0; ; offset += auditExportPageSize {
+		filter.Offset = offset
+		entries, err := h.store.ListAuditLog(r.Context(), filter)
+		if err != nil {
+			// Headers are already out after the first page; end the stream.
+			if offset == 0 {
+				writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+			}
+			slog.Warn("audit log: export failed", "error", err)
+			return
+		}
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				return
+			}
+		}
+		if len(entries) < auditExportPageSize {
+			return
+		}
+	}
+}
+
+func parseAuditLogFilter(r *http.Request) (core.AuditLogFilter, error) {
+	q := r.URL.Query()
+	filter := core.AuditLogFilter{
+		Actor:       strings.TrimSpace(q.Get("actor")),
+		Method:      strings.ToUpper(strings.TrimSpace(q.Get("method"))),
+		RoutePrefix: strings.TrimSpace(q.Get("route")),
+		Resource:    strings.TrimSpace(q.Get("resource")),
+		Outcome:     core.AuditOutcome(strings.TrimSpace(q.Get("outcome"))),
+		Limit:       defaultAuditLogLimit,
+	}
+	if filter.Resource != "" {
+		if key, _, ok := strings.Cut(filter.Resource, "="); !ok || key == "" {
+			return filter, errBadQuery("resource must be name=value, e.g. actionID=42")
+		}
+	}
+	switch filter.Outcome {
+	case "", core.AuditOutcomeSuccess, core.AuditOutcomeDenied, core.AuditOutcomeFailed:
+	default:
+		return filter, errBadQuery("outcome must be success, denied or failed")
+	}
+	for name, dst := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		raw := strings.TrimSpace(q.Get(name))
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return filter, errBadQuery(name + " must be an RFC 3339 time")
+		}
+		*dst = &t
+	}
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return filter, errBadQuery("limit must be a positive integer")
+		}
+		filter.Limit = min(n, maxAuditLogLimit)
+	}
+	if raw := q.Get("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return filter, errBadQuery("offset must be a non-negative integer")
+		}
+		filter.Offset = n
+	}
+	return filter, nil
+}
+
+type errBadQuery string
+
+func (e errBadQuery) Error() string { return string(e) }
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestAuditLogRecordsMutations(t *testing.T) {
+	s := newSessionTestServer(t)
+
+	resp := s.do(t, http.MethodPost, "/admin/users", "admin-token", nil, map[string]any{"username": "alice", "password": "correct horse"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create user: status %d", resp.StatusCode)
+	}
+	var alice core.User
+	if err := decodeJSON(resp, &alice); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	cookie := s.login(t, "alice", "correct horse")
+
+	// Reads are not audited; a denied mutation is.
+	s.do(t, http.MethodGet, "/admin/users", "admin-token", nil, nil)
+	resp = s.do(t, http.MethodPut, "/admin/users/"+itoa64(alice.ID), "", cookie, map[string]any{"admin": true})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("self-promote: status %d, want 403", resp.StatusCode)
+	}
+
+	resp = s.do(t, http.MethodGet, "/admin/audit-log", "admin-token", nil, nil)
+	var entries []*core.AuditLogEntry
+	if err := decodeJSON(resp, &entries); err != nil {
+		t.Fatalf("decode audit log: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("audit log has %d entries, want create, login and update", len(entries))
+	}
+	denied, login, created := entries[0], entries[1], entries[2]
+	if created.Actor != "admin" || created.Method != http.MethodPost || created.Route != "/api/admin/users" ||
+		created.Status != http.StatusCreated || created.Outcome != core.AuditOutcomeSuccess {
+		t.Fatalf("create entry = %+v", created)
+	}
+	if created.Changes["username"] != "alice" || created.Changes["password"] != "[REDACTED]" {
+		t.Fatalf("create changes = %v", created.Changes)
+	}
+	if login.Actor != "anonymous" || login.Changes["password"] != "[REDACTED]" {
+		t.Fatalf("login entry = %+v", login)
+	}
+	if denied.Actor != core.UserRef(alice.ID) || denied.Route != "/api/admin/users/{userID}" ||
+		denied.ResourceIDs["userID"] != itoa64(alice.ID) || denied.Outcome != core.AuditOutcomeDenied || denied.Error == "" {
+		t.Fatalf("denied entry = %+v", denied)
+	}
+
+	resp = s.do(t, http.MethodGet, "/admin/audit-log?resource=userID="+itoa64(alice.ID)+"&outcome=denied", "admin-token", nil, nil)
+	entries = nil
+	if err := decodeJSON(resp, &entries); err != nil || len(entries) != 1 || entries[0].ID != denied.ID {
+		t.Fatalf("filtered audit log = %v, %v", entries, err)
+	}
+	resp = s.do(t, http.MethodGet, "/admin/audit-log?resource=userID", "admin-token", nil, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad resource filter: status %d, want 400", resp.StatusCode)
+	}
+
+	resp = s.do(t, http.MethodGet, "/admin/audit-log?format=jsonl&route=/api/admin/users", "admin-token", nil, nil)
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("export content type = %q", ct)
+	}
+	var lines []core.AuditLogEntry
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var entry core.AuditLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("export line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, entry)
+	}
+	if len(lines) != 2 || !strings.HasPrefix(lines[1].Route, "/api/admin/users") {
+		t.Fatalf("export = %+v", lines)
+	}
+
+	// Only admins read the audit log.
+	resp = s.do(t, http.MethodGet, "/admin/audit-log", "", cookie, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("audit log as viewer: status %d, want 403", resp.StatusCode)
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yoke233/zhanggui/internal/platform/backup"
+)
+
+// BackupService creates and lists data-dir backups. Implemented by *backup.Service.
+type BackupService interface {
+	CreateBackup(ctx context.Context, excludeSecrets bool) (*backup.Result, error)
+	ListBackups() ([]backup.Info, error)
+}
+
+type createBackupRequest struct {
+	ExcludeSecrets bool `json:"exclude_secrets"`
+}
+
+// registerBackupAdminRoutes exposes backup creation and listing. Restore is
+// deliberately CLI-only (`ai-flow backup restore`): it swaps the data dir and
+// the database out from under the process, so it cannot run inside the server
+// it would replace.
+func registerBackupAdminRoutes(r chi.Router, h *Handler) {
+	r.Get("/admin/backups", h.listBackups)
+	r.Post("/admin/backups", h.createBackup)
+}
+
+func (h *Handler) listBackups(w http.ResponseWriter, r *http.Request) {
+	if h.backups == nil {
+		writeError(w, http.StatusServiceUnavailable, "backups are not available", "BACKUP_UNAVAILABLE")
+		return
+	}
+	list, err := h.backups.ListBackups()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "BACKUP_FAILED")
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *Handler) createBackup(w http.ResponseWriter, r *http.Request) {
+	if h.backups == nil {
+		writeError(w, http.StatusServiceUnavailable, "backups are not available", "BACKUP_UNAVAILABLE")
+		return
+	}
+	var req createBackupRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+			return
+		}
+	}
+	result, err := h.backups.CreateBackup(r.Context(), req.ExcludeSecrets)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "BACKUP_FAILED")
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/platform/backup"
+)
+
+type stubBackupService struct {
+	excludeSecrets []bool
+	list           []backup.Info
+}
+
+func (s *stubBackupService) CreateBackup(_ context.Context, excludeSecrets bool) (*backup.Result, error) {
+	s.excludeSecrets = append(s.excludeSecrets, excludeSecrets)
+	return &backup.Result{
+		Path:     "/data/backups/ai-flow-backup-manual-20260101T000000Z.tar.gz",
+		Manifest: &backup.Manifest{FormatVersion: backup.FormatVersion, SecretsExcluded: excludeSecrets},
+	}, nil
+}
+
+func (s *stubBackupService) ListBackups() ([]backup.Info, error) {
+	return s.list, nil
+}
+
+func TestAPI_BackupsCreateAndList(t *testing.T) {
+	h, ts := setupAPI(t)
+
+	resp, err := get(ts, "/admin/backups")
+	if err != nil {
+		t.Fatalf("list backups: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without backup service, got %d", resp.StatusCode)
+	}
+
+	svc := &stubBackupService{list: []backup.Info{{Name: "ai-flow-backup-manual-20260101T000000Z.tar.gz", Size: 42}}}
+	h.backups = svc
+
+	resp, err = post(ts, "/admin/backups", map[string]any{"exclude_secrets": true})
+	if err != nil {
+		t.Fatalf("create backup: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var created backup.Result
+	if err := decodeJSON(resp, &created); err != nil {
+		t.Fatalf("decode backup: %v", err)
+	}
+	if created.Manifest == nil || !created.Manifest.SecretsExcluded || len(svc.excludeSecrets) != 1 || !svc.excludeSecrets[0] {
+		t.Fatalf("unexpected backup result: %#v (calls %v)", created, svc.excludeSecrets)
+	}
+
+	resp, err = get(ts, "/admin/backups")
+	if err != nil {
+		t.Fatalf("list backups: %v", err)
+	}
+	var list []backup.Info
+	if err := decodeJSON(resp, &list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list) != 1 || list[0].Size != 42 {
+		t.Fatalf("unexpected list: %#v", list)
+	}
+}
@@ -58,6 +58,24 @@ func (h *chatHandlers) listSessions(w http.ResponseWriter, r *http.Request) {
 		}
 		resp = filtered
 	}
+	allowed, restricted, err := h.handler.sessionProjectFilter(r, "chat:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	if restricted {
+		visible := resp[:0]
+		for _, s := range resp {
+			var projectID *int64
+			if s.ProjectID > 0 {
+				projectID = &s.ProjectID
+			}
+			if projectVisible(allowed, projectID) {
+				visible = append(visible, s)
+			}
+		}
+		resp = visible
+	}
 
 	writeJSON(w, http.StatusOK, resp)
 }
@@ -31,6 +31,11 @@ type Store interface {
 	core.JournalStore
 	core.NotificationStore
 	core.InspectionStore
+	core.UserStore
+	core.APITokenStore
+	core.AuditLogStore
+	core.VaultStore
+	core.IdempotencyStore
 	DeleteResourcesByThread(ctx context.Context, threadID int64) error
 	GetThreadMessage(ctx context.Context, id int64) (*core.ThreadMessage, error)
 	DeleteActionIODeclsByWorkItem(ctx context.Context, workItemID int64) error
@@ -52,6 +57,12 @@ type EventBus interface {
 	Subscribe(opts core.SubscribeOpts) *core.Subscription
 }
 
+// EventLogSyncer waits until events published up to a bus sequence number are
+// readable from the event log. Implemented by *flow.EventPersister.
+type EventLogSyncer interface {
+	Sync(ctx context.Context, seq int64) error
+}
+
 // LeadChatService is the chat contract required by the HTTP adapter.
 type LeadChatService interface {
 	Chat(ctx context.Context, req chatapp.Request) (*chatapp.Response, error)
@@ -83,6 +83,9 @@ func (h *Handler) createDAGTemplate(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionProject(w, r, req.ProjectID, "issues:write") {
+		return
+	}
 	if req.Name == "" {
 		writeError(w, http.StatusBadRequest, "name is required", "MISSING_NAME")
 		return
@@ -129,10 +132,19 @@ func (h *Handler) listDAGTemplates(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
-	if templates == nil {
-		templates = []*core.DAGTemplate{}
+	allowed, restricted, err := h.sessionProjectFilter(r, "issues:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	visible := make([]*core.DAGTemplate, 0, len(templates))
+	for _, t := range templates {
+		if restricted && !projectVisible(allowed, t.ProjectID) {
+			continue
+		}
+		visible = append(visible, t)
 	}
-	writeJSON(w, http.StatusOK, templates)
+	writeJSON(w, http.StatusOK, visible)
 }
 
 // GET /templates/{templateID}
@@ -151,6 +163,7 @@ func (h *Handler) getDAGTemplate(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	setETag(w, t.Version)
 	writeJSON(w, http.StatusOK, t)
 }
 
@@ -161,6 +174,10 @@ func (h *Handler) updateDAGTemplate(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid template ID", "BAD_ID")
 		return
 	}
+	expectedVersion, ok := readIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	existing, err := h.store.GetDAGTemplate(r.Context(), id)
 	if err == core.ErrNotFound {
@@ -171,12 +188,18 @@ func (h *Handler) updateDAGTemplate(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	if !checkIfMatch(w, expectedVersion, existing.Version) {
+		return
+	}
 
 	var req updateDAGTemplateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionProject(w, r, req.ProjectID, "issues:write") {
+		return
+	}
 
 	if req.Name != nil {
 		existing.Name = *req.Name
@@ -202,9 +225,13 @@ func (h *Handler) updateDAGTemplate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.store.UpdateDAGTemplate(r.Context(), existing); err != nil {
+		if writeVersionConflict(w, r, err) {
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	setETag(w, existing.Version)
 	writeJSON(w, http.StatusOK, existing)
 }
 
@@ -352,6 +379,9 @@ func (h *Handler) createWorkItemFromTemplate(w http.ResponseWriter, r *http.Requ
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionProject(w, r, req.ProjectID, "issues:write") {
+		return
+	}
 	if req.Title == "" {
 		req.Title = tmpl.Name
 	}
@@ -51,7 +51,7 @@ func (h *Handler) adoptWorkItemFinalDeliverable(w http.ResponseWriter, r *http.R
 
 	workItem, err := h.workItemService().AdoptDeliverable(r.Context(), workItemID, req.DeliverableID)
 	if err != nil {
-		if writeWorkItemAppError(w, err) {
+		if writeVersionConflict(w, r, err) || writeWorkItemAppError(w, err) {
 			return
 		}
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// setETag exposes a row version as a strong entity tag.
+func setETag(w http.ResponseWriter, version int64) {
+	if version <= 0 {
+		return
+	}
+	w.Header().Set("ETag", `"`+strconv.FormatInt(version, 10)+`"`)
+}
+
+// ifMatchVersion parses the If-Match request header. It returns 0 when the
+// header is absent or "*", and ok=false when it is not a version tag. A weak
+// tag is reported separately: If-Match uses strong comparison (RFC 7232
+// §3.1), so it never matches.
+func ifMatchVersion(r *http.Request) (version int64, weak, ok bool) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" || raw == "*" {
+		return 0, false, true
+	}
+	if strings.HasPrefix(raw, "W/") {
+		raw = strings.TrimPrefix(raw, "W/")
+		weak = true
+	}
+	raw = strings.Trim(raw, `"`)
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || v <= 0 {
+		return 0, false, false
+	}
+	return v, weak, true
+}
+
+// readIfMatch is ifMatchVersion for handlers: it writes 400 on a malformed
+// header, 412 on a weak tag, and reports whether the handler may continue.
+func readIfMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	version, weak, ok := ifMatchVersion(r)
+	if weak {
+		writeError(w, http.StatusPreconditionFailed, "If-Match requires a strong ETag", "VERSION_CONFLICT")
+		return 0, false
+	}
+	if !ok {
+		writeError(w, http.StatusBadRequest, "If-Match must be a version ETag", "BAD_IF_MATCH")
+		return 0, false
+	}
+	return version, true
+}
+
+// checkIfMatch rejects the request with 412 when expected is set and differs
+// from the current version.
+func checkIfMatch(w http.ResponseWriter, expected, current int64) bool {
+	if expected == 0 || expected == current {
+		return true
+	}
+	setETag(w, current)
+	writeError(w, http.StatusPreconditionFailed, "resource was modified; refetch and retry", "VERSION_CONFLICT")
+	return false
+}
+
+// writeVersionConflict maps a *core.VersionConflictError to 412 when the
+// client sent If-Match and 409 otherwise. It reports whether err was handled.
+func writeVersionConflict(w http.ResponseWriter, r *http.Request, err error) bool {
+	var conflict *core.VersionConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	status := http.StatusConflict
+	if strings.TrimSpace(r.Header.Get("If-Match")) != "" {
+		status = http.StatusPreconditionFailed
+	}
+	setETag(w, conflict.Actual)
+	writeError(w, status, conflict.Error(), "VERSION_CONFLICT")
+	return true
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func putIfMatch(t *testing.T, url, etag string, body any) *http.Response {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("put %s: %v", url, err)
+	}
+	return resp
+}
+
+func TestAPI_WorkItemIfMatch(t *testing.T) {
+	_, ts := setupAPI(t)
+
+	resp, err := post(ts, "/work-items", map[string]any{"title": "etag"})
+	if err != nil {
+		t.Fatalf("create work item: %v", err)
+	}
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if err := decodeJSON(resp, &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	resp, err = get(ts, "/work-items/"+itoa64(created.ID))
+	if err != nil {
+		t.Fatalf("get work item: %v", err)
+	}
+	resp.Body.Close()
+	etag := resp.Header.Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("expected ETag \"1\", got %q", etag)
+	}
+
+	url := ts.URL + "/work-items/" + itoa64(created.ID)
+	resp = putIfMatch(t, url, etag, map[string]any{"title": "first"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("ETag"); got != `"2"` {
+		t.Fatalf("expected ETag \"2\" after update, got %q", got)
+	}
+
+	resp = putIfMatch(t, url, etag, map[string]any{"title": "stale"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for stale If-Match, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("ETag"); got != `"2"` {
+		t.Fatalf("expected current ETag on 412, got %q", got)
+	}
+
+	// If-Match uses strong comparison, so a weak tag never matches.
+	resp = putIfMatch(t, url, `W/"2"`, map[string]any{"title": "weak"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 for weak If-Match, got %d", resp.StatusCode)
+	}
+
+	resp = putIfMatch(t, url, "not-a-version", map[string]any{"title": "bad"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for malformed If-Match, got %d", resp.StatusCode)
+	}
+
+	resp = putIfMatch(t, url, "", map[string]any{"title": "blind"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 without If-Match, got %d", resp.StatusCode)
+	}
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -17,18 +19,60 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true }, // allow all origins for dev
 }
 
+const (
+	// eventReplayLimit caps how many events a reconnecting stream client may
+	// replay; further behind, it gets stream.resync and must reload state.
+	eventReplayLimit = 1000
+	// eventSyncTimeout bounds how long a since_seq read waits for in-flight
+	// events to be written before reading what is already persisted.
+	eventSyncTimeout = 2 * time.Second
+)
+
+// syncEventLog waits for events already published on the bus to reach the
+// event log so a since_seq read does not skip them. The sync also flushes
+// journal entries queued behind them. It returns the bus sequence number it
+// synced to (0 when the bus is not sequenced).
+func (h *Handler) syncEventLog(ctx context.Context) int64 {
+	seqBus, ok := h.bus.(core.SequencedBus)
+	if !ok {
+		return 0
+	}
+	last := seqBus.LastSeq()
+	if h.eventSync != nil {
+		ctx, cancel := context.WithTimeout(ctx, eventSyncTimeout)
+		defer cancel()
+		if err := h.eventSync.Sync(ctx, last); err != nil {
+			slog.Debug("event log sync incomplete", "seq", last, "error", err)
+		}
+	}
+	return last
+}
+
 func (h *Handler) listEvents(w http.ResponseWriter, r *http.Request) {
 	filter := buildEventFilter(r)
+	if filter.SinceSeq != nil {
+		h.syncEventLog(r.Context())
+	}
 
 	events, err := h.store.ListEvents(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
-	if events == nil {
-		events = []*core.Event{}
+	allowed, restricted, err := h.sessionProjectFilter(r, "runs:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
 	}
-	writeJSON(w, http.StatusOK, events)
+	resolver := newEventProjectResolver(h.store)
+	visible := make([]*core.Event, 0, len(events))
+	for _, ev := range events {
+		if restricted && !eventVisible(r.Context(), resolver, allowed, *ev) {
+			continue
+		}
+		visible = append(visible, ev)
+	}
+	writeJSON(w, http.StatusOK, visible)
 }
 
 func (h *Handler) listWorkItemEvents(w http.ResponseWriter, r *http.Request) {
@@ -61,6 +105,9 @@ func (h *Handler) listThreadEvents(w http.ResponseWriter, r *http.Request) {
 
 	filter := buildEventFilter(r)
 	filter.ThreadID = &threadID
+	if filter.SinceSeq != nil {
+		h.syncEventLog(r.Context())
+	}
 
 	events, err := h.store.ListEvents(r.Context(), filter)
 	if err != nil {
@@ -106,6 +153,12 @@ func buildEventFilter(r *http.Request) core.EventFilter {
 			}
 		}
 	}
+	if s := r.URL.Query().Get("since_seq"); s != "" {
+		if seq, err := strconv.ParseInt(s, 10, 64); err == nil && seq >= 0 {
+			filter.SinceSeq = &seq
+			filter.Offset = 0
+		}
+	}
 	filter.SessionID = strings.TrimSpace(r.URL.Query().Get("session_id"))
 	return filter
 }
@@ -114,6 +167,17 @@ func buildEventFilter(r *http.Request) core.EventFilter {
 type wsConnState struct {
 	mu        sync.Mutex
 	threadIDs map[int64]bool
+	// authorize checks the connection's caller against a project; nil
+	// allows everything.
+	authorize func(projectID int64, scope string) (bool, error)
+}
+
+// allowProject reports whether the connection may use scope in projectID.
+func (s *wsConnState) allowProject(projectID int64, scope string) (bool, error) {
+	if s == nil || s.authorize == nil || projectID <= 0 {
+		return true, nil
+	}
+	return s.authorize(projectID, scope)
 }
 
 func (s *wsConnState) subscribeThread(id int64) {
@@ -177,7 +241,21 @@ func threadIDFromEventData(data map[string]any) (int64, bool) {
 // Query params:
 //   - work_item_id: optional, filter events to a specific work item
 //   - types: optional, comma-separated event types to subscribe to
+//   - since_seq: optional, replay persisted events after this sequence number
+//     before streaming live ones. Transient chunk events are never persisted,
+//     so they are not replayed.
+//
+// Besides events, the stream may carry two control messages: stream.overflow
+// ({from_seq, to_seq, dropped}) when the connection fell behind and events
+// were skipped, and stream.resync ({reason, since_seq, last_seq}) when the
+// requested replay is too large. After stream.overflow a client can reconnect
+// with since_seq to fill the gap; after stream.resync it must reload state.
 func (h *Handler) wsEvents(w http.ResponseWriter, r *http.Request) {
+	allowed, restricted, err := h.sessionProjectFilter(r, "runs:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return // Upgrade writes its own error
@@ -207,15 +285,77 @@ func (h *Handler) wsEvents(w http.ResponseWriter, r *http.Request) {
 		workItemFilter, _ = strconv.ParseInt(s, 10, 64)
 	}
 	sessionFilter := strings.TrimSpace(r.URL.Query().Get("session_id"))
+	var sinceSeq *int64
+	if s := r.URL.Query().Get("since_seq"); s != "" {
+		if seq, err := strconv.ParseInt(s, 10, 64); err == nil && seq >= 0 {
+			sinceSeq = &seq
+		}
+	}
 
-	connState := &wsConnState{}
+	// A reconnecting client names the threads it was subscribed to, so their
+	// events are replayed too and not only delivered live.
+	connState := &wsConnState{
+		authorize: func(projectID int64, scope string) (bool, error) {
+			return h.projectAccess(r.Context(), &projectID, scope)
+		},
+	}
+	for _, raw := range strings.Split(r.URL.Query().Get("thread_ids"), ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil || id <= 0 {
+			continue
+		}
+		if _, err := h.store.GetThread(r.Context(), id); err == nil {
+			connState.subscribeThread(id)
+		}
+	}
+	resolver := newEventProjectResolver(h.store)
+	matches := func(ev core.Event) bool {
+		// Apply work item filter if specified.
+		if workItemFilter != 0 && ev.WorkItemID != workItemFilter {
+			return false
+		}
+		if sessionFilter != "" {
+			eventSessionID, _ := ev.Data["session_id"].(string)
+			if strings.TrimSpace(eventSessionID) != sessionFilter {
+				return false
+			}
+		}
+		// Thread events are only forwarded to connections subscribed to that thread.
+		if isThreadEvent(ev.Type) {
+			tid, ok := threadIDFromEventData(ev.Data)
+			if !ok || !connState.isThreadSubscribed(tid) {
+				return false
+			}
+		}
+		// Session users only see events of projects they may read.
+		return !restricted || eventVisible(r.Context(), resolver, allowed, ev)
+	}
 
+	// Subscribe before replaying so nothing published meanwhile is missed;
+	// live events already covered by the replay are skipped by sequence.
 	sub := h.bus.Subscribe(core.SubscribeOpts{
-		Types:      types,
-		BufferSize: 64,
+		Types:          types,
+		BufferSize:     64,
+		NotifyOverflow: true,
 	})
 	defer sub.Cancel()
 
+	var lastSent int64
+	if sinceSeq != nil {
+		filter := core.EventFilter{SinceSeq: sinceSeq, Types: types}
+		if workItemFilter != 0 {
+			filter.WorkItemID = &workItemFilter
+		}
+		lastSent, err = h.replayEvents(r.Context(), filter, matches,
+			func(ev core.Event) error { return writeJSON(ev) },
+			func(data map[string]any) error {
+				return writeJSON(wsOutboundMessage{Type: "stream.resync", Data: data})
+			})
+		if err != nil {
+			return
+		}
+	}
+
 	// Read pump: detect client disconnect.
 	done := make(chan struct{})
 	go func() {
@@ -237,22 +377,17 @@ func (h *Handler) wsEvents(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
-			// Apply work item filter if specified.
-			if workItemFilter != 0 && ev.WorkItemID != workItemFilter {
+			if ev.Type == core.EventStreamOverflow {
+				if err := writeJSON(wsOutboundMessage{Type: string(core.EventStreamOverflow), Data: ev.Data}); err != nil {
+					return
+				}
 				continue
 			}
-			if sessionFilter != "" {
-				eventSessionID, _ := ev.Data["session_id"].(string)
-				if strings.TrimSpace(eventSessionID) != sessionFilter {
-					continue
-				}
+			if ev.Seq != 0 && ev.Seq <= lastSent {
+				continue
 			}
-			// Thread events are only forwarded to connections subscribed to that thread.
-			if isThreadEvent(ev.Type) {
-				tid, ok := threadIDFromEventData(ev.Data)
-				if !ok || !connState.isThreadSubscribed(tid) {
-					continue
-				}
+			if !matches(ev) {
+				continue
 			}
 
 			if err := writeJSON(ev); err != nil {
@@ -262,11 +397,74 @@ func (h *Handler) wsEvents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// replayEvents sends persisted events matching filter (SinceSeq must be set)
+// and returns the sequence number the live stream should continue from. When
+// the gap exceeds eventReplayLimit, resync is called instead.
+func (h *Handler) replayEvents(ctx context.Context, filter core.EventFilter, matches func(core.Event) bool, send func(core.Event) error, resync func(data map[string]any) error) (int64, error) {
+	last := h.syncEventLog(ctx)
+	since := *filter.SinceSeq
+	if reason := h.eventLogGap(ctx, since); reason != "" {
+		return last, resync(map[string]any{"reason": reason, "since_seq": since, "last_seq": last})
+	}
+	filter.Limit = eventReplayLimit + 1
+	filter.Offset = 0
+	events, err := h.store.ListEvents(ctx, filter)
+	if err != nil || len(events) > eventReplayLimit {
+		reason := "too_far_behind"
+		if err != nil {
+			reason = "replay_failed"
+		}
+		return last, resync(map[string]any{"reason": reason, "since_seq": since, "last_seq": last})
+	}
+	for _, ev := range events {
+		if ev.Seq > last {
+			last = ev.Seq
+		}
+		if !matches(*ev) {
+			continue
+		}
+		if err := send(*ev); err != nil {
+			return last, err
+		}
+	}
+	return last, nil
+}
+
+// eventPruneStore is implemented by stores that remember how far retention
+// has pruned the event log.
+type eventPruneStore interface {
+	PrunedEventSeq(ctx context.Context) (int64, error)
+}
+
+// eventLossReporter is implemented by event log syncers that know the
+// highest sequence number of an event they failed to persist.
+type eventLossReporter interface {
+	LostSeq() int64
+}
+
+// eventLogGap reports why the event log cannot replay everything after
+// since: "pruned" when retention deleted events past it, "events_lost" when
+// an event past it was never written. It returns "" when the log is complete.
+func (h *Handler) eventLogGap(ctx context.Context, since int64) string {
+	if pruner, ok := h.store.(eventPruneStore); ok {
+		pruned, err := pruner.PrunedEventSeq(ctx)
+		if err != nil {
+			slog.Debug("read pruned event seq", "error", err)
+		} else if pruned > since {
+			return "pruned"
+		}
+	}
+	if reporter, ok := h.eventSync.(eventLossReporter); ok && reporter.LostSeq() > since {
+		return "events_lost"
+	}
+	return ""
+}
+
 func (h *Handler) handleWSClientMessage(msg wsMessage, writeJSON func(v any) error, state *wsConnState) {
 	msgType := strings.TrimSpace(msg.Type)
 	switch msgType {
 	case "chat.send":
-		h.handleWSChatSend(msg, writeJSON)
+		h.handleWSChatSend(msg, writeJSON, state)
 	case "chat.set_config":
 		h.handleWSChatSetConfig(msg, writeJSON)
 	case "chat.set_mode":
@@ -292,7 +490,7 @@ func (h *Handler) handleWSClientMessage(msg wsMessage, writeJSON func(v any) err
 	}
 }
 
-func (h *Handler) handleWSChatSend(msg wsMessage, writeJSON func(v any) error) {
+func (h *Handler) handleWSChatSend(msg wsMessage, writeJSON func(v any) error, state *wsConnState) {
 	if h.lead == nil {
 		_ = writeJSON(wsOutboundMessage{
 			Type: "chat.error",
@@ -319,6 +517,22 @@ func (h *Handler) handleWSChatSend(msg wsMessage, writeJSON func(v any) error) {
 		}
 	}
 
+	if allowed, err := state.allowProject(req.ProjectID, "chat:write"); err != nil || !allowed {
+		code, text := "FORBIDDEN", "forbidden: missing chat:write in this project"
+		if err != nil {
+			code, text = "STORE_ERROR", err.Error()
+		}
+		_ = writeJSON(wsOutboundMessage{
+			Type: "chat.error",
+			Data: wsErrorPayload{
+				Code:      code,
+				RequestID: strings.TrimSpace(req.RequestID),
+				Error:     text,
+			},
+		})
+		return
+	}
+
 	accepted, err := h.lead.StartChat(h.backgroundContext(), chatapp.Request{
 		SessionID:   strings.TrimSpace(req.SessionID),
 		Message:     req.Message,
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yoke233/zhanggui/internal/application/eventsub"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// EventSubscriptionService manages outbound event subscriptions and their
+// delivery log. Implemented by *eventsub.Service.
+type EventSubscriptionService interface {
+	CreateSubscription(ctx context.Context, sub *core.EventSubscription) (*core.EventSubscription, error)
+	GetSubscription(ctx context.Context, id int64) (*core.EventSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*core.EventSubscription, error)
+	UpdateSubscription(ctx context.Context, sub *core.EventSubscription) error
+	RotateSecret(ctx context.Context, sub *core.EventSubscription) (string, error)
+	DeleteSubscription(ctx context.Context, id int64) error
+	ListDeliveries(ctx context.Context, filter core.EventDeliveryFilter) ([]*core.EventDelivery, error)
+	Replay(ctx context.Context, subscriptionID, deliveryID int64) (*core.EventDelivery, error)
+	ReplaySince(ctx context.Context, subscriptionID, sinceSeq int64) (int, error)
+}
+
+type createEventSubscriptionRequest struct {
+	Name       string           `json:"name"`
+	TargetURL  string           `json:"target_url"`
+	EventTypes []core.EventType `json:"event_types"`
+	ProjectID  *int64           `json:"project_id"`
+	Secret     string           `json:"secret"`
+	Enabled    *bool            `json:"enabled"`
+}
+
+type updateEventSubscriptionRequest struct {
+	Name         *string           `json:"name"`
+	TargetURL    *string           `json:"target_url"`
+	EventTypes   *[]core.EventType `json:"event_types"`
+	ProjectID    *int64            `json:"project_id"`
+	ClearProject bool              `json:"clear_project"`
+	Enabled      *bool             `json:"enabled"`
+	RotateSecret bool              `json:"rotate_secret"`
+}
+
+// eventSubscriptionWithSecret is returned when a secret is created or
+// rotated; it is the only time the secret is shown.
+type eventSubscriptionWithSecret struct {
+	*core.EventSubscription
+	Secret string `json:"secret"`
+}
+
+type replayEventSubscriptionRequest struct {
+	SinceSeq int64 `json:"since_seq"`
+}
+
+func registerEventSubscriptionAdminRoutes(r chi.Router, h *Handler) {
+	r.Get("/admin/event-subscriptions", h.listEventSubscriptions)
+	r.Post("/admin/event-subscriptions", h.createEventSubscription)
+	r.Get("/admin/event-subscriptions/{subscriptionID}", h.getEventSubscription)
+	r.Put("/admin/event-subscriptions/{subscriptionID}", h.updateEventSubscription)
+	r.Delete("/admin/event-subscriptions/{subscriptionID}", h.deleteEventSubscription)
+	r.Get("/admin/event-subscriptions/{subscriptionID}/deliveries", h.listEventDeliveries)
+	r.Post("/admin/event-subscriptions/{subscriptionID}/deliveries/{deliveryID}/replay", h.replayEventDelivery)
+	r.Post("/admin/event-subscriptions/{subscriptionID}/replay", h.replayEventSubscription)
+}
+
+func (h *Handler) eventSubscriptionsAvailable(w http.ResponseWriter) bool {
+	if h.eventSubs == nil {
+		writeError(w, http.StatusServiceUnavailable, "event subscriptions are not available", "EVENT_SUBSCRIPTIONS_UNAVAILABLE")
+		return false
+	}
+	return true
+}
+
+func writeEventSubscriptionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, core.ErrNotFound):
+		writeError(w, http.StatusNotFound, "event subscription not found", "NOT_FOUND")
+	case errors.Is(err, eventsub.ErrInvalidSubscription):
+		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error(), "EVENT_SUBSCRIPTION_FAILED")
+	}
+}
+
+func (h *Handler) listEventSubscriptions(w http.ResponseWriter, r *http.Request) {
+	if !h.eventSubscriptionsAvailable(w) {
+		return
+	}
+	subs, err := h.eventSubs.ListSubscriptions(r.Context())
+	if err != nil {
+		writeEventSubscriptionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subs)
+}
+
+func (h *Handler) createEventSubscription(w http.ResponseWriter, r *http.Request) {
+	if !h.eventSubscriptionsAvailable(w) {
+		return
+	}
+	var req createEventSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	sub := &core.EventSubscription{
+		Name:       strings.TrimSpace(req.Name),
+		TargetURL:  req.TargetURL,
+		EventTypes: req.EventTypes,
+		ProjectID:  req.ProjectID,
+		Secret:     req.Secret,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+	created, err := h.eventSubs.CreateSubscription(r.Context(), sub)
+	if err != nil {
+		writeEventSubscriptionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, eventSubscriptionWithSecret{EventSubscription: created, Secret: created.Secret})
+}
+
+func (h *Handler) loadEventSubscription(w http.ResponseWriter, r *http.Request) (*core.EventSubscription, bool) {
+	if !h.eventSubscriptionsAvailable(w) {
+		return nil, false
+	}
+	id, ok := urlParamInt64(r, "subscriptionID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid subscription ID", "BAD_ID")
+		return nil, false
+	}
+	sub, err := h.eventSubs.GetSubscription(r.Context(), id)
+	if err != nil {
+		writeEventSubscriptionError(w, err)
+		return nil, false
+	}
+	return sub, true
+}
+
+func (h *Handler) getEventSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.loadEventSubscription(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, sub)
+}
+
+func (h *Handler) updateEventSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.loadEventSubscription(w, r)
+	if !ok {
+		return
+	}
+	var req updateEventSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	if req.Name != nil {
+		sub.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.TargetURL != nil {
+		sub.TargetURL = *req.TargetURL
+	}
+	if req.EventTypes != nil {
+		sub.EventTypes = *req.EventTypes
+	}
+	if req.ProjectID != nil {
+		sub.ProjectID = req.ProjectID
+	} else if req.ClearProject {
+		sub.ProjectID = nil
+	}
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+	if req.RotateSecret {
+		secret, err := h.eventSubs.RotateSecret(r.Context(), sub)
+		if err != nil {
+			writeEventSubscriptionError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, eventSubscriptionWithSecret{EventSubscription: sub, Secret: secret})
+		return
+	}
+	if err := h.eventSubs.UpdateSubscription(r.Context(), sub); err != nil {
+		writeEventSubscriptionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sub)
+}
+
+func (h *Handler) deleteEventSubscription(w http.ResponseWriter, r *http.Request) {
+	if !h.eventSubscriptionsAvailable(w) {
+		return
+	}
+	id, ok := urlParamInt64(r, "subscriptionID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid subscription ID", "BAD_ID")
+		return
+	}
+	if err := h.eventSubs.DeleteSubscription(r.Context(), id); err != nil {
+		writeEventSubscriptionError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) listEventDeliveries(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.loadEventSubscription(w, r)
+	if !ok {
+		return
+	}
+	filter := core.EventDeliveryFilter{
+		SubscriptionID: &sub.ID,
+		Limit:          queryInt(r, "limit", 50),
+		Offset:         queryInt(r, "offset", 0),
+	}
+	switch status := core.EventDeliveryStatus(r.URL.Query().Get("status")); status {
+	case "", core.EventDeliveryPending, core.EventDeliverySucceeded, core.EventDeliveryDead:
+		filter.Status = status
+	default:
+		writeError(w, http.StatusBadRequest, "status must be pending, succeeded or dead", "BAD_REQUEST")
+		return
+	}
+	deliveries, err := h.eventSubs.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		writeEventSubscriptionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+func (h *Handler) replayEventDelivery(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.loadEventSubscription(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, ok := urlParamInt64(r, "deliveryID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid delivery ID", "BAD_ID")
+		return
+	}
+	replayed, err := h.eventSubs.Replay(r.Context(), sub.ID, deliveryID)
+	if err != nil {
+		writeEventSubscriptionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, replayed)
+}
+
+func (h *Handler) replayEventSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := h.loadEventSubscription(w, r)
+	if !ok {
+		return
+	}
+	var req replayEventSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	if req.SinceSeq < 0 {
+		writeError(w, http.StatusBadRequest, "since_seq must be >= 0", "BAD_REQUEST")
+		return
+	}
+	queued, err := h.eventSubs.ReplaySince(r.Context(), sub.ID, req.SinceSeq)
+	if err != nil {
+		writeEventSubscriptionError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{"queued": queued})
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	membus "github.com/yoke233/zhanggui/internal/adapters/events/memory"
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/application/eventsub"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestAPI_EventSubscriptionsCRUDAndReplay(t *testing.T) {
+	h, ts := setupAPI(t)
+
+	resp, err := get(ts, "/admin/event-subscriptions")
+	if err != nil {
+		t.Fatalf("list subscriptions: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without event subscription service, got %d", resp.StatusCode)
+	}
+
+	store := h.store.(*sqlite.Store)
+	h.eventSubs = eventsub.New(store, membus.NewBus(), eventsub.Config{})
+
+	resp, err = post(ts, "/admin/event-subscriptions", map[string]any{"target_url": "not a url"})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid target, got %d", resp.StatusCode)
+	}
+
+	resp, err = post(ts, "/admin/event-subscriptions", map[string]any{
+		"name":        "ops",
+		"target_url":  "https://hooks.example.test/ai-flow",
+		"event_types": []string{"gate.rejected", "run.failed"},
+	})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var created struct {
+		core.EventSubscription
+		Secret string `json:"secret"`
+	}
+	if err := decodeJSON(resp, &created); err != nil {
+		t.Fatalf("decode subscription: %v", err)
+	}
+	if created.ID == 0 || !created.Enabled || created.Secret == "" || len(created.EventTypes) != 2 {
+		t.Fatalf("unexpected subscription: %+v", created)
+	}
+	base := "/admin/event-subscriptions/" + itoa64(created.ID)
+
+	resp, err = get(ts, base)
+	if err != nil {
+		t.Fatalf("get subscription: %v", err)
+	}
+	var fetched map[string]any
+	if err := decodeJSON(resp, &fetched); err != nil {
+		t.Fatalf("decode subscription: %v", err)
+	}
+	if _, leaked := fetched["secret"]; leaked {
+		t.Fatalf("secret returned by GET: %v", fetched)
+	}
+
+	resp, err = put(ts, base, map[string]any{"enabled": false})
+	if err != nil {
+		t.Fatalf("update subscription: %v", err)
+	}
+	var updated core.EventSubscription
+	if err := decodeJSON(resp, &updated); err != nil {
+		t.Fatalf("decode update: %v", err)
+	}
+	if updated.Enabled || updated.Name != "ops" {
+		t.Fatalf("unexpected update: %+v", updated)
+	}
+
+	ctx := context.Background()
+	if _, err := store.CreateEvent(ctx, &core.Event{Type: core.EventRunFailed, RunID: 3, Seq: 1}); err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+	if _, err := put(ts, base, map[string]any{"enabled": true}); err != nil {
+		t.Fatalf("enable subscription: %v", err)
+	}
+	resp, err = post(ts, base+"/replay", map[string]any{"since_seq": 0})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	var replay struct {
+		Queued int `json:"queued"`
+	}
+	if err := decodeJSON(resp, &replay); err != nil || replay.Queued != 1 {
+		t.Fatalf("replay queued = %d, %v", replay.Queued, err)
+	}
+
+	resp, err = get(ts, base+"/deliveries?status=pending")
+	if err != nil {
+		t.Fatalf("list deliveries: %v", err)
+	}
+	var deliveries []core.EventDelivery
+	if err := decodeJSON(resp, &deliveries); err != nil {
+		t.Fatalf("decode deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].EventType != core.EventRunFailed || deliveries[0].CloudEventID != "1" {
+		t.Fatalf("unexpected deliveries: %+v", deliveries)
+	}
+
+	resp, err = post(ts, base+"/deliveries/"+itoa64(deliveries[0].ID)+"/replay", nil)
+	if err != nil {
+		t.Fatalf("replay delivery: %v", err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+
+	resp, err = deleteReq(ts, base)
+	if err != nil {
+		t.Fatalf("delete subscription: %v", err)
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+	resp, _ = get(ts, base)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	runtimeapp "github.com/yoke233/zhanggui/internal/application/runtime"
+)
+
+func registerExecutorRoutes(r chi.Router, h *Handler) {
+	r.Get("/executors", h.listExecutors)
+	r.Get("/executors/{executorID}", h.getExecutor)
+}
+
+func registerExecutorAdminRoutes(r chi.Router, h *Handler) {
+	r.Post("/executors/{executorID}/cordon", h.controlExecutor(runtimeapp.ExecutorCordon))
+	r.Post("/executors/{executorID}/uncordon", h.controlExecutor(runtimeapp.ExecutorUncordon))
+	r.Post("/executors/{executorID}/drain", h.controlExecutor(runtimeapp.ExecutorDrain))
+}
+
+func (h *Handler) listExecutors(w http.ResponseWriter, r *http.Request) {
+	if h.fleet == nil {
+		writeError(w, http.StatusServiceUnavailable, "executor fleet is only available with the nats session manager", "FLEET_UNAVAILABLE")
+		return
+	}
+	executors := h.fleet.ListExecutors(r.Context())
+	if executors == nil {
+		executors = []runtimeapp.ExecutorInfo{}
+	}
+	writeJSON(w, http.StatusOK, executors)
+}
+
+func (h *Handler) getExecutor(w http.ResponseWriter, r *http.Request) {
+	if h.fleet == nil {
+		writeError(w, http.StatusServiceUnavailable, "executor fleet is only available with the nats session manager", "FLEET_UNAVAILABLE")
+		return
+	}
+	info, err := h.fleet.GetExecutor(r.Context(), strings.TrimSpace(chi.URLParam(r, "executorID")))
+	if errors.Is(err, runtimeapp.ErrExecutorNotFound) {
+		writeError(w, http.StatusNotFound, "executor not found", "NOT_FOUND")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "FLEET_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (h *Handler) controlExecutor(action runtimeapp.ExecutorControlAction) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.fleet == nil {
+			writeError(w, http.StatusServiceUnavailable, "executor fleet is only available with the nats session manager", "FLEET_UNAVAILABLE")
+			return
+		}
+		info, err := h.fleet.ControlExecutor(r.Context(), strings.TrimSpace(chi.URLParam(r, "executorID")), action)
+		if errors.Is(err, runtimeapp.ErrExecutorNotFound) {
+			writeError(w, http.StatusNotFound, "executor not found", "NOT_FOUND")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error(), "EXECUTOR_CONTROL_FAILED")
+			return
+		}
+		writeJSON(w, http.StatusOK, info)
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	runtimeapp "github.com/yoke233/zhanggui/internal/application/runtime"
+)
+
+type stubExecutorFleet struct {
+	executors  map[string]runtimeapp.ExecutorInfo
+	lastAction runtimeapp.ExecutorControlAction
+}
+
+func (s *stubExecutorFleet) ListExecutors(context.Context) []runtimeapp.ExecutorInfo {
+	out := make([]runtimeapp.ExecutorInfo, 0, len(s.executors))
+	for _, info := range s.executors {
+		out = append(out, info)
+	}
+	return out
+}
+
+func (s *stubExecutorFleet) GetExecutor(_ context.Context, id string) (*runtimeapp.ExecutorInfo, error) {
+	info, ok := s.executors[id]
+	if !ok {
+		return nil, runtimeapp.ErrExecutorNotFound
+	}
+	return &info, nil
+}
+
+func (s *stubExecutorFleet) ControlExecutor(_ context.Context, id string, action runtimeapp.ExecutorControlAction) (*runtimeapp.ExecutorInfo, error) {
+	info, ok := s.executors[id]
+	if !ok {
+		return nil, runtimeapp.ErrExecutorNotFound
+	}
+	s.lastAction = action
+	info.Cordoned = action != runtimeapp.ExecutorUncordon
+	info.Draining = action == runtimeapp.ExecutorDrain
+	s.executors[id] = info
+	return &info, nil
+}
+
+func TestAPI_ExecutorsUnavailableWithoutFleet(t *testing.T) {
+	_, ts := setupAPI(t)
+
+	resp, err := get(ts, "/executors")
+	if err != nil {
+		t.Fatalf("list executors: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", resp.StatusCode)
+	}
+}
+
+func TestAPI_ExecutorsListAndDrain(t *testing.T) {
+	h, ts := setupAPI(t)
+	fleet := &stubExecutorFleet{executors: map[string]runtimeapp.ExecutorInfo{
+		"exec-1": {ID: "exec-1", Capacity: 2, Health: runtimeapp.ExecutorHealthy, Labels: map[string]string{"gpu": "true"}},
+	}}
+	h.fleet = fleet
+
+	resp, err := get(ts, "/executors")
+	if err != nil {
+		t.Fatalf("list executors: %v", err)
+	}
+	var list []runtimeapp.ExecutorInfo
+	if err := decodeJSON(resp, &list); err != nil {
+		t.Fatalf("decode executors: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != "exec-1" || list[0].Labels["gpu"] != "true" {
+		t.Fatalf("unexpected executors: %#v", list)
+	}
+
+	resp, err = post(ts, "/executors/exec-1/drain", nil)
+	if err != nil {
+		t.Fatalf("drain executor: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var got runtimeapp.ExecutorInfo
+	if err := decodeJSON(resp, &got); err != nil {
+		t.Fatalf("decode drain response: %v", err)
+	}
+	if !got.Draining || !got.Cordoned || fleet.lastAction != runtimeapp.ExecutorDrain {
+		t.Fatalf("unexpected drain response: %#v (action %q)", got, fleet.lastAction)
+	}
+
+	resp, err = get(ts, "/executors/missing")
+	if err != nil {
+		t.Fatalf("get missing executor: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/yoke233/zhanggui/internal/adapters/http/server"
@@ -13,6 +15,9 @@ import (
 	inspectionapp "github.com/yoke233/zhanggui/internal/application/inspection"
 	probeapp "github.com/yoke233/zhanggui/internal/application/probe"
 	requirementapp "github.com/yoke233/zhanggui/internal/application/requirementapp"
+	runtimeapp "github.com/yoke233/zhanggui/internal/application/runtime"
+	"github.com/yoke233/zhanggui/internal/application/vaultapp"
+	"github.com/yoke233/zhanggui/internal/audit"
 	"github.com/yoke233/zhanggui/internal/core"
 	skillset "github.com/yoke233/zhanggui/internal/skills"
 )
@@ -39,6 +44,18 @@ type Handler struct {
 	inspectionEngine    *inspectionapp.Engine
 	dataDir             string
 	drivers             DriverConfigService
+	driverDoctor        DriverDoctor
+	fleet               runtimeapp.ExecutorFleet
+	retention           RetentionRunner
+	backups             BackupService
+	writer              WriterStatsProvider
+	rateLimiter         *httpx.APIRateLimiter
+	idempotencyTTL      time.Duration
+	eventSync           EventLogSyncer
+	eventSubs           EventSubscriptionService
+	oidc                *OIDCLogin
+	auditRedactor       *audit.Redactor
+	vault               *vaultapp.Service
 	backgroundCtx       context.Context
 }
 
@@ -145,6 +162,47 @@ func WithDriverConfigService(service DriverConfigService) HandlerOption {
 	return func(h *Handler) { h.drivers = service }
 }
 
+// WithDriverDoctor enables the driver conformance check endpoint.
+func WithDriverDoctor(doctor DriverDoctor) HandlerOption {
+	return func(h *Handler) { h.driverDoctor = doctor }
+}
+
+// WithExecutorFleet exposes the distributed executor registry.
+func WithExecutorFleet(fleet runtimeapp.ExecutorFleet) HandlerOption {
+	return func(h *Handler) { h.fleet = fleet }
+}
+
+// WithRetentionService enables the data-retention admin endpoints.
+func WithRetentionService(svc RetentionRunner) HandlerOption {
+	return func(h *Handler) { h.retention = svc }
+}
+
+// WithBackupService enables the data-dir backup admin endpoints.
+func WithBackupService(svc BackupService) HandlerOption {
+	return func(h *Handler) { h.backups = svc }
+}
+
+// WithEventSubscriptions enables the outbound event subscription admin endpoints.
+func WithEventSubscriptions(svc EventSubscriptionService) HandlerOption {
+	return func(h *Handler) { h.eventSubs = svc }
+}
+
+// WithWriterStats exposes the event write-behind pipeline counters.
+func WithWriterStats(provider WriterStatsProvider) HandlerOption {
+	return func(h *Handler) { h.writer = provider }
+}
+
+// WithAPIRateLimiter throttles API callers and exposes the limiter counters.
+func WithAPIRateLimiter(limiter *httpx.APIRateLimiter) HandlerOption {
+	return func(h *Handler) { h.rateLimiter = limiter }
+}
+
+// WithEventLogSyncer lets since_seq reads wait for in-flight events to reach
+// the event log.
+func WithEventLogSyncer(syncer EventLogSyncer) HandlerOption {
+	return func(h *Handler) { h.eventSync = syncer }
+}
+
 // WithBackgroundContext sets the application-scoped context used by async adapter work.
 func WithBackgroundContext(ctx context.Context) HandlerOption {
 	return func(h *Handler) { h.backgroundCtx = ctx }
@@ -160,9 +218,27 @@ func (h *Handler) backgroundContext() context.Context {
 // Register mounts all workflow routes onto the given chi router.
 // Caller is responsible for mounting this under a prefix like /api.
 func (h *Handler) Register(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		if h.rateLimiter != nil {
+			r.Use(h.rateLimiter.Middleware)
+		}
+		// Audit first so that requests denied by project roles are recorded.
+		r.Use(h.auditMutations)
+		r.Use(h.enforceProjectRoles)
+		r.Use(h.idempotentRequests)
+		h.registerRoutes(r)
+	})
+}
+
+func (h *Handler) registerRoutes(r chi.Router) {
+	// Accounts, sessions and project memberships
+	registerUserRoutes(r, h)
+
 	// Scheduler stats
 	r.Get("/stats", h.getStats)
 	r.Get("/scheduler/stats", h.getSchedulerStats)
+	r.Get("/store/writer/stats", h.getWriterStats)
+	r.Get("/ratelimit/stats", h.getRateLimitStats)
 	r.Get("/system/sandbox-support", h.getSandboxSupport)
 
 	// Projects
@@ -175,6 +251,9 @@ func (h *Handler) Register(r chi.Router) {
 	// Resource Spaces (project-level path spaces)
 	r.Post("/projects/{projectID}/spaces", h.createResourceSpace)
 	r.Get("/projects/{projectID}/spaces", h.listResourceSpaces)
+
+	// Secret vault (values are write-only)
+	registerVaultRoutes(r, h)
 	r.Get("/spaces/{spaceID}", h.getResourceSpace)
 	r.Put("/spaces/{spaceID}", h.updateResourceSpace)
 	r.Delete("/spaces/{spaceID}", h.deleteResourceSpace)
@@ -223,6 +302,10 @@ func (h *Handler) Register(r chi.Router) {
 
 	// Events
 	r.Get("/events", h.listEvents)
+	r.Get("/events/stream", h.streamEvents)
+
+	// Full-text search
+	r.Get("/search", h.search)
 
 	// Analytics
 	r.Get("/analytics/summary", h.getAnalyticsSummary)
@@ -252,7 +335,10 @@ func (h *Handler) Register(r chi.Router) {
 	r.Get("/ws", h.wsEvents)
 
 	// Agents (drivers + profiles)
-	registerAgentRoutes(r, h.registry, h.drivers)
+	registerAgentRoutes(r, h.registry, h.drivers, h.driverDoctor)
+
+	// Remote executors (NATS session manager only)
+	registerExecutorRoutes(r, h)
 
 	// Feature Manifest (per-project feature checklist)
 	r.Get("/projects/{projectID}/manifest", h.getManifest)
@@ -302,6 +388,14 @@ func (h *Handler) Register(r chi.Router) {
 		r.Get("/runs/{runID}/probe/latest", h.getLatestRunProbe)
 		r.Post("/admin/system-event", h.sendSystemEvent)
 		r.Delete("/manifest/entries/{entryID}", h.deleteManifestEntry)
+		registerExecutorAdminRoutes(r, h)
+		registerRetentionAdminRoutes(r, h)
+		registerBackupAdminRoutes(r, h)
+		registerEventSubscriptionAdminRoutes(r, h)
+		registerUserAdminRoutes(r, h)
+		registerAPITokenAdminRoutes(r, h)
+		registerAuditLogAdminRoutes(r, h)
+		registerVaultAdminRoutes(r, h)
 		registerSkillRoutes(r, h.skillsRoot, h.registry, h.skillGitHubImporter)
 	})
 }
@@ -329,6 +423,7 @@ func (h *Handler) registerWorkItemRoutes(r chi.Router, basePath string) {
 	r.Post(basePath+"/generate-title", h.generateTitle)
 	r.Post(basePath+"/{workItemID}/save-as-template", h.saveWorkItemAsTemplate)
 	r.Get(basePath+"/{workItemID}/events", h.listWorkItemEvents)
+	r.Get(basePath+"/{workItemID}/timeline", h.getWorkItemTimeline)
 	r.Get(basePath+"/{workItemID}/cron", h.getWorkItemCronStatus)
 	r.Post(basePath+"/{workItemID}/cron", h.setupWorkItemCron)
 	r.Delete(basePath+"/{workItemID}/cron", h.disableWorkItemCron)
@@ -373,3 +468,12 @@ func queryInt64(r *http.Request, name string) (int64, bool) {
 	}
 	return v, true
 }
+
+// queryBool reports whether an optional query parameter is set to a truthy value.
+func queryBool(r *http.Request, key string) bool {
+	switch strings.ToLower(strings.TrimSpace(r.URL.Query().Get(key))) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
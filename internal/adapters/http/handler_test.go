@@ -2074,3 +2074,196 @@ func TestAPI_UpdateLLMConfig_BadRequest(t *testing.T) {
 		t.Fatalf("expected 400, got %d", resp.StatusCode)
 	}
 }
+
+type stubWriterStats struct{}
+
+func (stubWriterStats) Stats() flowapp.BatchWriterStats {
+	return flowapp.BatchWriterStats{Enqueued: 5, EventsWritten: 4, Coalesced: 1}
+}
+
+func TestAPI_WriterStats(t *testing.T) {
+	h, ts := setupAPI(t)
+
+	resp, err := get(ts, "/store/writer/stats")
+	if err != nil {
+		t.Fatalf("get writer stats: %v", err)
+	}
+	var disabled map[string]any
+	if err := decodeJSON(resp, &disabled); err != nil || disabled["enabled"] != false {
+		t.Fatalf("expected disabled writer stats, got %v (%v)", disabled, err)
+	}
+
+	h.writer = stubWriterStats{}
+	resp, err = get(ts, "/store/writer/stats")
+	if err != nil {
+		t.Fatalf("get writer stats: %v", err)
+	}
+	var body struct {
+		Enabled bool                     `json:"enabled"`
+		Stats   flowapp.BatchWriterStats `json:"stats"`
+	}
+	if err := decodeJSON(resp, &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !body.Enabled || body.Stats.EventsWritten != 4 || body.Stats.Coalesced != 1 {
+		t.Fatalf("writer stats = %+v", body)
+	}
+}
+
+// setupSequencedAPI wires a persister so since_seq reads can sync with the bus.
+func setupSequencedAPI(t *testing.T) (*Handler, *membus.Bus, *httptest.Server) {
+	t.Helper()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "seq.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	bus := membus.NewBus()
+	persister := flowapp.NewEventPersister(store, bus)
+	if err := persister.Start(context.Background()); err != nil {
+		t.Fatalf("start persister: %v", err)
+	}
+	t.Cleanup(persister.Stop)
+
+	h := NewHandler(store, bus, nil, WithEventLogSyncer(persister))
+	r := chi.NewRouter()
+	h.Register(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	return h, bus, ts
+}
+
+func TestAPI_ListEvents_SinceSeq(t *testing.T) {
+	_, bus, ts := setupSequencedAPI(t)
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: int64(i)})
+	}
+
+	resp, err := get(ts, "/events?since_seq=1&limit=1")
+	if err != nil {
+		t.Fatalf("get events: %v", err)
+	}
+	var events []core.Event
+	if err := decodeJSON(resp, &events); err != nil {
+		t.Fatalf("decode events: %v", err)
+	}
+	if len(events) != 1 || events[0].Seq != 2 {
+		t.Fatalf("first page = %+v, want seq 2", events)
+	}
+
+	resp, _ = get(ts, "/events?since_seq="+itoa64(events[0].Seq))
+	events = nil
+	decodeJSON(resp, &events)
+	if len(events) != 1 || events[0].Seq != 3 || events[0].WorkItemID != 3 {
+		t.Fatalf("second page = %+v, want seq 3", events)
+	}
+}
+
+func TestAPI_WebSocket_ResumeSinceSeq(t *testing.T) {
+	h, bus, ts := setupSequencedAPI(t)
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: int64(i)})
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+ts.URL[4:]+"/ws?since_seq=1", nil)
+	if err != nil {
+		t.Fatalf("ws dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	for _, want := range []int64{2, 3} {
+		var ev core.Event
+		if err := conn.ReadJSON(&ev); err != nil {
+			t.Fatalf("read replayed event: %v", err)
+		}
+		if ev.Seq != want {
+			t.Fatalf("replayed seq = %d, want %d", ev.Seq, want)
+		}
+	}
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemCompleted, WorkItemID: 3})
+	var ev core.Event
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatalf("read live event: %v", err)
+	}
+	if ev.Seq != 4 || ev.Type != core.EventWorkItemCompleted {
+		t.Fatalf("live event = %+v, want seq 4 without duplicates", ev)
+	}
+
+	// A client further behind than the replay window is told to resync.
+	backlog := make([]*core.Event, eventReplayLimit+1)
+	for i := range backlog {
+		backlog[i] = &core.Event{Type: core.EventWorkItemStarted, Seq: int64(100 + i)}
+	}
+	if err := h.store.BatchCreateEvents(ctx, backlog); err != nil {
+		t.Fatalf("seed backlog: %v", err)
+	}
+	conn2, _, err := websocket.DefaultDialer.Dial("ws"+ts.URL[4:]+"/ws?since_seq=4", nil)
+	if err != nil {
+		t.Fatalf("ws dial: %v", err)
+	}
+	defer conn2.Close()
+	conn2.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg struct {
+		Type string         `json:"type"`
+		Data map[string]any `json:"data"`
+	}
+	if err := conn2.ReadJSON(&msg); err != nil {
+		t.Fatalf("read resync: %v", err)
+	}
+	if msg.Type != "stream.resync" || msg.Data["reason"] != "too_far_behind" {
+		t.Fatalf("message = %+v, want stream.resync", msg)
+	}
+}
+
+func TestAPI_WebSocket_ResumeReplaysThreadsAndResyncsAfterPrune(t *testing.T) {
+	h, bus, ts := setupSequencedAPI(t)
+	ctx := context.Background()
+	threadID, err := h.store.CreateThread(ctx, &core.Thread{Title: "replay", Status: core.ThreadActive})
+	if err != nil {
+		t.Fatalf("create thread: %v", err)
+	}
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: 1})
+	bus.Publish(ctx, core.Event{Type: core.EventThreadMessage, Data: map[string]any{"thread_id": threadID}})
+
+	// Thread events are replayed to a client that names the thread.
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+ts.URL[4:]+"/ws?since_seq=1&thread_ids="+itoa64(threadID), nil)
+	if err != nil {
+		t.Fatalf("ws dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var ev core.Event
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatalf("read replayed event: %v", err)
+	}
+	if ev.Seq != 2 || ev.Type != core.EventThreadMessage {
+		t.Fatalf("replayed event = %+v, want thread message seq 2", ev)
+	}
+
+	// Once retention pruned events past the cursor, the client must resync.
+	if _, err := h.store.(*sqlite.Store).PruneExpired(ctx, core.RetentionPruneInput{
+		Target: core.RetentionEventLog,
+		Before: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	conn2, _, err := websocket.DefaultDialer.Dial("ws"+ts.URL[4:]+"/ws?since_seq=1", nil)
+	if err != nil {
+		t.Fatalf("ws dial: %v", err)
+	}
+	defer conn2.Close()
+	conn2.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg struct {
+		Type string         `json:"type"`
+		Data map[string]any `json:"data"`
+	}
+	if err := conn2.ReadJSON(&msg); err != nil {
+		t.Fatalf("read resync: %v", err)
+	}
+	if msg.Type != "stream.resync" || msg.Data["reason"] != "pruned" {
+		t.Fatalf("message = %+v, want stream.resync after prune", msg)
+	}
+}
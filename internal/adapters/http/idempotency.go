@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+const (
+	idempotencyKeyHeader  = "Idempotency-Key"
+	defaultIdempotencyTTL = 24 * time.Hour
+	// idempotencyPendingLease bounds how long a request that never finished,
+	// e.g. because the server stopped, keeps its key locked.
+	idempotencyPendingLease = 10 * time.Minute
+	maxIdempotencyKeyLength = 255
+	// idempotencyResponseLimit caps the response kept for replay; a larger
+	// response releases the key instead.
+	idempotencyResponseLimit = 1 << 20
+)
+
+// idempotentRoutes are the POST routes, without the /api prefix, that honour
+// an Idempotency-Key header: those creating records or starting agents.
+var idempotentRoutes = map[string]bool{
+	"/projects":                    true,
+	"/work-items":                  true,
+	"/work-items/{workItemID}/run": true,
+	"/templates/{templateID}/create-work-item": true,
+	"/threads":                               true,
+	"/threads/{threadID}/agents":             true,
+	"/threads/{threadID}/create-work-item":   true,
+	"/chat":                                  true,
+	"/chat/sessions/{sessionID}/submit-code": true,
+	"/chat/sessions/{sessionID}/create-pr":   true,
+	"/ceo/submit":                            true,
+	"/requirements/create-thread":            true,
+	"/inspections/trigger":                   true,
+}
+
+// WithIdempotencyTTL sets how long responses to requests sent with an
+// Idempotency-Key header are replayed. The default is 24 hours.
+func WithIdempotencyTTL(ttl time.Duration) HandlerOption {
+	return func(h *Handler) { h.idempotencyTTL = ttl }
+}
+
+// idempotentRequests replays the stored response when a request to an
+// idempotent route is retried with the same Idempotency-Key. Keys belong to
+// the caller; reusing one for a different request is rejected with 422 and a
+// retry while the first request is still running with 409. Only successful
+// responses are kept, so a failed request may be retried with its key.
+func (h *Handler) idempotentRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+		if key == "" || r.Method != http.MethodPost || h.store == nil || !idempotentRoutes[idempotencyRoute(r)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeError(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters", "BAD_REQUEST")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "read request body: "+err.Error(), "BAD_REQUEST")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// The record outlives a client that disconnects mid-request.
+		ctx := context.WithoutCancel(r.Context())
+		scope := httpx.CallerKey(r)
+		hash := idempotencyRequestHash(r, body)
+		existing, reserved, err := h.store.ReserveIdempotencyKey(ctx, &core.IdempotencyRecord{
+			Scope:       scope,
+			Key:         key,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			RequestHash: hash,
+			ExpiresAt:   time.Now().Add(idempotencyPendingLease),
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error(), "IDEMPOTENCY_FAILED")
+			return
+		}
+		if !reserved {
+			switch {
+			case existing.RequestHash != hash:
+				writeError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request", "IDEMPOTENCY_KEY_REUSED")
+			case existing.Pending():
+				w.Header().Set("Retry-After", "1")
+				writeError(w, http.StatusConflict, "a request with this Idempotency-Key is still in progress", "IDEMPOTENCY_KEY_IN_PROGRESS")
+			default:
+				if existing.ContentType != "" {
+					w.Header().Set("Content-Type", existing.ContentType)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(existing.Status)
+				_, _ = w.Write(existing.Body)
+			}
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if rec.status < 200 || rec.status >= 300 || rec.overflow {
+			if err := h.store.ReleaseIdempotencyKey(ctx, scope, key); err != nil {
+				slog.Warn("idempotency: release key failed", "path", r.URL.Path, "error", err)
+			}
+			return
+		}
+		ttl := h.idempotencyTTL
+		if ttl <= 0 {
+			ttl = defaultIdempotencyTTL
+		}
+		if err := h.store.CompleteIdempotencyKey(ctx, scope, key, rec.status, w.Header().Get("Content-Type"), rec.body.Bytes(), time.Now().Add(ttl)); err != nil {
+			slog.Warn("idempotency: store response failed", "path", r.URL.Path, "error", err)
+		}
+	})
+}
+
+func idempotencyRoute(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	return strings.TrimPrefix(rctx.RoutePattern(), "/api")
+}
+
+// idempotencyRequestHash covers the method, the URL and the body.
+func idempotencyRequestHash(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// idempotencyRecorder keeps the status and body of the response for replay.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+func (r *idempotencyRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *idempotencyRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	if !r.overflow {
+		if r.body.Len()+len(p) > idempotencyResponseLimit {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(p)
+		}
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach Flush and Hijack.
+func (r *idempotencyRecorder) Unwrap() http.ResponseWriter { return r.ResponseWriter }
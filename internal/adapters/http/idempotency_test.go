@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestIdempotencyKeyReplaysResponse(t *testing.T) {
+	s := newSessionTestServer(t)
+	post := func(key string, body any) (*http.Response, []byte) {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req, err := http.NewRequest(http.MethodPost, s.url+"/projects", bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("NewRequest: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer admin-token")
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST /projects: %v", err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return resp, out
+	}
+
+	first, firstBody := post("create-backend", map[string]any{"name": "backend"})
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", first.StatusCode, firstBody)
+	}
+	retry, retryBody := post("create-backend", map[string]any{"name": "backend"})
+	if retry.StatusCode != http.StatusCreated || !bytes.Equal(retryBody, firstBody) || retry.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry: status %d, replayed %q, body %s", retry.StatusCode, retry.Header.Get("Idempotent-Replayed"), retryBody)
+	}
+	projects, err := s.store.ListProjects(context.Background(), 10, 0)
+	if err != nil || len(projects) != 1 {
+		t.Fatalf("projects = %d, %v; want one despite the retry", len(projects), err)
+	}
+
+	reused, reusedBody := post("create-backend", map[string]any{"name": "frontend"})
+	if reused.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("reused key: status %d, body %s", reused.StatusCode, reusedBody)
+	}
+	var apiErr apiError
+	if err := json.Unmarshal(reusedBody, &apiErr); err != nil || apiErr.Code != "IDEMPOTENCY_KEY_REUSED" {
+		t.Fatalf("reused key body = %s", reusedBody)
+	}
+
+	// A failed request releases its key.
+	if resp, body := post("create-frontend", map[string]any{}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid create: status %d, body %s", resp.StatusCode, body)
+	}
+	if resp, body := post("create-frontend", map[string]any{"name": "frontend"}); resp.StatusCode != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatalf("create after failure: status %d, body %s", resp.StatusCode, body)
+	}
+
+	// A retry while the first request is still running is refused.
+	scope := "token:" + httpx.HashToken("admin-token")[:16]
+	data, _ := json.Marshal(map[string]any{"name": "docs"})
+	req, _ := http.NewRequest(http.MethodPost, "/api/projects", bytes.NewReader(data))
+	if _, reserved, err := s.store.ReserveIdempotencyKey(context.Background(), &core.IdempotencyRecord{
+		Scope:       scope,
+		Key:         "create-docs",
+		Method:      http.MethodPost,
+		Path:        "/api/projects",
+		RequestHash: idempotencyRequestHash(req, data),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}); err != nil || !reserved {
+		t.Fatalf("ReserveIdempotencyKey: reserved=%v, %v", reserved, err)
+	}
+	if resp, body := post("create-docs", map[string]any{"name": "docs"}); resp.StatusCode != http.StatusConflict || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("in-progress key: status %d, body %s", resp.StatusCode, body)
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -89,6 +90,10 @@ func (h *Handler) listInitiatives(w http.ResponseWriter, r *http.Request) {
 		writeInitiativeAppFailure(w, err, "LIST_INITIATIVES_FAILED")
 		return
 	}
+	if items, err = h.visibleInitiatives(r, items); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
 	writeJSON(w, http.StatusOK, items)
 }
 
@@ -154,6 +159,9 @@ func (h *Handler) addInitiativeItem(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionWorkItem(w, r, req.WorkItemID, "issues:write") {
+		return
+	}
 	item, err := h.initiativeService().AddWorkItem(r.Context(), initiativeapp.AddInitiativeItemInput{
 		InitiativeID: initiativeID,
 		WorkItemID:   req.WorkItemID,
@@ -303,6 +311,9 @@ func (h *Handler) linkInitiativeThread(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionThread(w, r, req.ThreadID, "chat:write") {
+		return
+	}
 	link, err := h.initiativeService().LinkThread(r.Context(), initiativeapp.LinkThreadInput{
 		InitiativeID: initiativeID,
 		ThreadID:     req.ThreadID,
@@ -346,3 +357,35 @@ func (h *Handler) deleteInitiativeThread(w http.ResponseWriter, r *http.Request)
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
+
+// visibleInitiatives keeps the initiatives a session user may read: those
+// with a work item in one of the user's projects, and those without work
+// items, which follow the unscoped rule.
+func (h *Handler) visibleInitiatives(r *http.Request, initiatives []*core.Initiative) ([]*core.Initiative, error) {
+	allowed, restricted, err := h.sessionProjectFilter(r, "issues:read")
+	if err != nil || !restricted {
+		return initiatives, err
+	}
+	visible := make([]*core.Initiative, 0, len(initiatives))
+	for _, initiative := range initiatives {
+		items, err := h.store.ListInitiativeItems(r.Context(), initiative.ID)
+		if err != nil {
+			return nil, err
+		}
+		ok := len(items) == 0 && projectVisible(allowed, nil)
+		for _, item := range items {
+			projectID, err := h.workItemProject(r.Context(), item.WorkItemID)
+			if err != nil && !errors.Is(err, core.ErrNotFound) {
+				return nil, err
+			}
+			if projectID != nil && allowed[*projectID] {
+				ok = true
+				break
+			}
+		}
+		if ok {
+			visible = append(visible, initiative)
+		}
+	}
+	return visible, nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -71,10 +72,19 @@ func (h *Handler) listNotifications(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
-	if notifications == nil {
-		notifications = []*core.Notification{}
+	allowed, restricted, err := h.sessionProjectFilter(r, "chat:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	visible := make([]*core.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		if restricted && !projectVisible(allowed, n.ProjectID) {
+			continue
+		}
+		visible = append(visible, n)
 	}
-	writeJSON(w, http.StatusOK, notifications)
+	writeJSON(w, http.StatusOK, visible)
 }
 
 func (h *Handler) createNotification(w http.ResponseWriter, r *http.Request) {
@@ -83,6 +93,9 @@ func (h *Handler) createNotification(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionProject(w, r, req.ProjectID, "chat:write") {
+		return
+	}
 	title := strings.TrimSpace(req.Title)
 	if title == "" {
 		writeError(w, http.StatusBadRequest, "title is required", "MISSING_TITLE")
@@ -208,10 +221,43 @@ func (h *Handler) deleteNotification(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) getUnreadCount(w http.ResponseWriter, r *http.Request) {
-	count, err := h.store.CountUnreadNotifications(r.Context())
+	allowed, restricted, err := h.sessionProjectFilter(r, "chat:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	var count int
+	if restricted {
+		count, err = h.countVisibleUnread(r.Context(), allowed)
+	} else {
+		count, err = h.store.CountUnreadNotifications(r.Context())
+	}
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
 	writeJSON(w, http.StatusOK, unreadCountResponse{Count: count})
 }
+
+// countVisibleUnread counts the unread notifications a session user may
+// read. CountUnreadNotifications has no project predicate, so it pages
+// through the unread list instead.
+func (h *Handler) countVisibleUnread(ctx context.Context, allowed map[int64]bool) (int, error) {
+	const pageSize = 500
+	unread := false
+	count := 0
+	for offset := 0; ; offset += pageSize {
+		page, err := h.store.ListNotifications(ctx, core.NotificationFilter{Read: &unread, Limit: pageSize, Offset: offset})
+		if err != nil {
+			return 0, err
+		}
+		for _, n := range page {
+			if projectVisible(allowed, n.ProjectID) {
+				count++
+			}
+		}
+		if len(page) < pageSize {
+			return count, nil
+		}
+	}
+}
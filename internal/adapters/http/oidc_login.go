@@ -0,0 +1,183 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yoke233/zhanggui/internal/adapters/oidc"
+	"github.com/yoke233/zhanggui/internal/application/userapp"
+	"golang.org/x/oauth2"
+)
+
+// OIDCLogin enables console single sign-on through an OpenID Connect
+// provider.
+type OIDCLogin struct {
+	Provider *oidc.Provider
+	// UsernameClaim and GroupsClaim name the ID token claims read on login.
+	// Defaults: "preferred_username" and "groups".
+	UsernameClaim string
+	GroupsClaim   string
+	Groups        userapp.GroupMapping
+}
+
+// WithOIDCLogin enables /auth/oidc/login and /auth/oidc/callback.
+func WithOIDCLogin(login *OIDCLogin) HandlerOption {
+	return func(h *Handler) { h.oidc = login }
+}
+
+// oidcStateCookieName carries the state, nonce and PKCE verifier of one
+// sign-in attempt from the login redirect to the callback.
+const oidcStateCookieName = "zhanggui_oidc"
+
+const oidcStateTTL = 10 * time.Minute
+
+type oidcState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	ReturnTo string `json:"r,omitempty"`
+}
+
+type authProvidersResponse struct {
+	Password bool `json:"password"`
+	OIDC     bool `json:"oidc"`
+}
+
+func registerOIDCRoutes(r chi.Router, h *Handler) {
+	// Exempt from token auth (see httpx.publicAuthPaths).
+	r.Get("/auth/providers", h.authProviders)
+	r.Get("/auth/oidc/login", h.oidcLogin)
+	r.Get("/auth/oidc/callback", h.oidcCallback)
+}
+
+// GET /auth/providers tells the console which sign-in methods to offer.
+func (h *Handler) authProviders(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, authProvidersResponse{Password: true, OIDC: h.oidc != nil})
+}
+
+// GET /auth/oidc/login?return_to=/path redirects the browser to the
+// identity provider.
+func (h *Handler) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		writeError(w, http.StatusNotFound, "single sign-on is not configured", "OIDC_DISABLED")
+		return
+	}
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "INTERNAL")
+		return
+	}
+	nonce, err := oidc.RandomString(24)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "INTERNAL")
+		return
+	}
+	st := oidcState{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier(), ReturnTo: safeReturnTo(r.URL.Query().Get("return_to"))}
+	loginURL, err := h.oidc.Provider.AuthCodeURL(r.Context(), st.State, st.Nonce, st.Verifier)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error(), "OIDC_UNAVAILABLE")
+		return
+	}
+	raw, _ := json.Marshal(st)
+	http.SetCookie(w, oidcStateCookie(r, base64.RawURLEncoding.EncodeToString(raw), int(oidcStateTTL/time.Second)))
+	http.Redirect(w, r, loginURL, http.StatusFound)
+}
+
+// GET /auth/oidc/callback completes the sign-in: it checks state, redeems the
+// code, provisions the user and sets the session cookie.
+func (h *Handler) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		writeError(w, http.StatusNotFound, "single sign-on is not configured", "OIDC_DISABLED")
+		return
+	}
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		writeError(w, http.StatusUnauthorized, "identity provider: "+e+" "+q.Get("error_description"), "OIDC_DENIED")
+		return
+	}
+	st, ok := readOIDCState(r)
+	// The state cookie is single use.
+	http.SetCookie(w, oidcStateCookie(r, "", -1))
+	if !ok {
+		writeError(w, http.StatusBadRequest, "sign-in attempt expired or not started here", "OIDC_STATE_MISSING")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(st.State)) != 1 {
+		writeError(w, http.StatusBadRequest, "state mismatch", "OIDC_STATE_MISMATCH")
+		return
+	}
+	claims, err := h.oidc.Provider.Exchange(r.Context(), q.Get("code"), st.Verifier, st.Nonce)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error(), "OIDC_LOGIN_FAILED")
+		return
+	}
+
+	usernameClaim, groupsClaim := h.oidc.UsernameClaim, h.oidc.GroupsClaim
+	if usernameClaim == "" {
+		usernameClaim = "preferred_username"
+	}
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	username, _ := claims.Raw[usernameClaim].(string)
+	result, err := h.userService().LoginExternal(r.Context(), userapp.ExternalLoginInput{
+		Provider:    h.oidc.Provider.Issuer(),
+		Subject:     claims.Subject,
+		Username:    username,
+		DisplayName: claims.Name,
+		Email:       claims.Email,
+		Groups:      claims.Strings(groupsClaim),
+		UserAgent:   r.UserAgent(),
+		RemoteAddr:  r.RemoteAddr,
+	}, h.oidc.Groups)
+	if err != nil {
+		writeUserAppError(w, err)
+		return
+	}
+	http.SetCookie(w, sessionCookie(r, result.Token, result.Session.ExpiresAt))
+	returnTo := st.ReturnTo
+	if returnTo == "" {
+		returnTo = "/"
+	}
+	http.Redirect(w, r, returnTo, http.StatusFound)
+}
+
+func readOIDCState(r *http.Request) (oidcState, bool) {
+	var st oidcState
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil || cookie.Value == "" {
+		return st, false
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || json.Unmarshal(raw, &st) != nil || st.State == "" {
+		return st, false
+	}
+	return st, true
+}
+
+// oidcStateCookie is Lax rather than Strict: the callback is a cross-site
+// top-level redirect from the identity provider. A negative maxAge clears it.
+func oidcStateCookie(r *http.Request, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   secureRequest(r),
+	}
+}
+
+// safeReturnTo keeps post-login redirects on this site.
+func safeReturnTo(raw string) string {
+	if !strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "//") || strings.HasPrefix(raw, "/\\") {
+		return ""
+	}
+	return raw
+}
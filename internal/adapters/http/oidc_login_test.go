@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/adapters/oidc"
+	"github.com/yoke233/zhanggui/internal/adapters/oidc/oidctest"
+	"github.com/yoke233/zhanggui/internal/application/userapp"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// newOIDCTestServer starts the API with single sign-on against an in-process
+// identity provider.
+func newOIDCTestServer(t *testing.T, mapping userapp.GroupMapping) (*sessionTestServer, *oidctest.Server) {
+	t.Helper()
+	idp := oidctest.NewServer("zhanggui", "s3cret")
+	t.Cleanup(idp.Close)
+
+	login := &OIDCLogin{Groups: mapping}
+	s := newSessionTestServer(t, WithOIDCLogin(login))
+	provider, err := oidc.New(oidc.Config{
+		Issuer:       idp.URL,
+		ClientID:     "zhanggui",
+		ClientSecret: "s3cret",
+		RedirectURL:  s.url + "/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatalf("oidc.New: %v", err)
+	}
+	login.Provider = provider
+	return s, idp
+}
+
+var noRedirects = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+// ssoLogin walks the browser through login, the provider and the callback,
+// returning the callback response.
+func ssoLogin(t *testing.T, s *sessionTestServer, returnTo string, tamperState bool) *http.Response {
+	t.Helper()
+	resp, err := noRedirects.Get(s.url + "/auth/oidc/login?return_to=" + url.QueryEscape(returnTo))
+	if err != nil {
+		t.Fatalf("GET login: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("login status = %d", resp.StatusCode)
+	}
+	stateCookies := resp.Cookies()
+
+	resp, err = noRedirects.Get(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("GET authorize: %v", err)
+	}
+	resp.Body.Close()
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if tamperState {
+		q := callback.Query()
+		q.Set("state", "forged")
+		callback.RawQuery = q.Encode()
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, callback.String(), nil)
+	for _, c := range stateCookies {
+		req.AddCookie(c)
+	}
+	resp, err = noRedirects.Do(req)
+	if err != nil {
+		t.Fatalf("GET callback: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func sessionCookieOf(resp *http.Response) *http.Cookie {
+	for _, c := range resp.Cookies() {
+		if c.Name == httpx.SessionCookieName && c.Value != "" {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestOIDCLoginProvisionsUserWithGroupRoles(t *testing.T) {
+	s, idp := newOIDCTestServer(t, userapp.GroupMapping{
+		Roles: []userapp.GroupRole{{Group: "backend", Project: "api", Role: core.ProjectRoleOperator}},
+	})
+	projectID, err := s.store.CreateProject(context.Background(), &core.Project{Name: "api", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	resp := s.do(t, http.MethodGet, "/auth/providers", "", nil, nil)
+	var providers authProvidersResponse
+	if err := decodeJSON(resp, &providers); err != nil || !providers.OIDC {
+		t.Fatalf("providers = %+v, %v", providers, err)
+	}
+
+	idp.SetUser(map[string]any{
+		"sub":                "idp-42",
+		"preferred_username": "Carol Smith",
+		"name":               "Carol Smith",
+		"email":              "carol@example.com",
+		"groups":             []string{"backend"},
+	})
+	resp = ssoLogin(t, s, "/projects/1", false)
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/projects/1" {
+		t.Fatalf("callback = %d to %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	cookie := sessionCookieOf(resp)
+	if cookie == nil {
+		t.Fatalf("callback set no session cookie")
+	}
+
+	resp = s.do(t, http.MethodGet, "/auth/me", "", cookie, nil)
+	var me meResponse
+	if err := decodeJSON(resp, &me); err != nil {
+		t.Fatalf("decode me: %v", err)
+	}
+	if me.User == nil || me.User.Username != "Carol-Smith" || me.User.DisplayName != "Carol Smith" {
+		t.Fatalf("me.user = %+v", me.User)
+	}
+	if len(me.Memberships) != 1 || me.Memberships[0].ProjectID != projectID || me.Memberships[0].Role != core.ProjectRoleOperator {
+		t.Fatalf("memberships = %+v", me.Memberships)
+	}
+
+	// The operator role from the group applies to project routes.
+	resp = s.do(t, http.MethodPost, "/work-items", "", cookie, map[string]any{"title": "sso", "project_id": projectID})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create work item: status %d", resp.StatusCode)
+	}
+
+	// An admin revokes every session of the user.
+	resp = s.do(t, http.MethodDelete, "/admin/users/"+itoa64(me.User.ID)+"/sessions", "admin-token", nil, nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("revoke sessions: status %d", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodGet, "/auth/me", "", cookie, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("me after revoke: status %d, want 401", resp.StatusCode)
+	}
+
+	// Signing in again reuses the provisioned account.
+	cookie = sessionCookieOf(ssoLogin(t, s, "", false))
+	resp = s.do(t, http.MethodGet, "/auth/me", "", cookie, nil)
+	var again meResponse
+	if err := decodeJSON(resp, &again); err != nil || again.User == nil || again.User.ID != me.User.ID {
+		t.Fatalf("second login user = %+v, %v", again.User, err)
+	}
+}
+
+func TestOIDCCallbackRejectsForgedState(t *testing.T) {
+	s, _ := newOIDCTestServer(t, userapp.GroupMapping{})
+
+	resp := ssoLogin(t, s, "//evil.example.com", true)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("forged state: status %d, want 400", resp.StatusCode)
+	}
+	if sessionCookieOf(resp) != nil {
+		t.Fatalf("forged state set a session cookie")
+	}
+	if got := safeReturnTo("//evil.example.com"); got != "" {
+		t.Fatalf("safeReturnTo kept open redirect %q", got)
+	}
+}
@@ -84,10 +84,19 @@ func (h *Handler) listProjects(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
-	if projects == nil {
-		projects = []*core.Project{}
+	allowed, restricted, err := h.sessionProjectFilter(r, "projects:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	visible := make([]*core.Project, 0, len(projects))
+	for _, project := range projects {
+		if restricted && !allowed[project.ID] {
+			continue
+		}
+		visible = append(visible, project)
 	}
-	writeJSON(w, http.StatusOK, projects)
+	writeJSON(w, http.StatusOK, visible)
 }
 
 func (h *Handler) updateProject(w http.ResponseWriter, r *http.Request) {
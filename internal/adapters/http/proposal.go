@@ -51,6 +51,17 @@ func registerProposalRoutes(r chi.Router, h *Handler) {
 	r.Post("/proposals/{proposalID}/revise", h.reviseThreadProposal)
 }
 
+// authorizeDraftProjects checks that the caller may create work items in
+// the projects named by drafts, which approving the proposal does.
+func (h *Handler) authorizeDraftProjects(w http.ResponseWriter, r *http.Request, drafts []core.ProposalWorkItemDraft) bool {
+	for _, draft := range drafts {
+		if !h.authorizeSessionProject(w, r, draft.ProjectID, "issues:write") {
+			return false
+		}
+	}
+	return true
+}
+
 func (h *Handler) createThreadProposal(w http.ResponseWriter, r *http.Request) {
 	threadID, ok := urlParamInt64(r, "threadID")
 	if !ok {
@@ -62,6 +73,9 @@ func (h *Handler) createThreadProposal(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeDraftProjects(w, r, req.WorkItemDrafts) {
+		return
+	}
 	proposal, err := h.proposalService().CreateProposal(r.Context(), proposalapp.CreateProposalInput{
 		ThreadID:        threadID,
 		Title:           req.Title,
@@ -116,6 +130,7 @@ func (h *Handler) getThreadProposal(w http.ResponseWriter, r *http.Request) {
 		writeProposalAppFailure(w, err, "GET_PROPOSAL_FAILED")
 		return
 	}
+	setETag(w, proposal.Version)
 	writeJSON(w, http.StatusOK, proposal)
 }
 
@@ -125,11 +140,18 @@ func (h *Handler) updateThreadProposal(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid proposal ID", "BAD_ID")
 		return
 	}
+	expectedVersion, ok := readIfMatch(w, r)
+	if !ok {
+		return
+	}
 	var req updateProposalRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if req.WorkItemDrafts != nil && !h.authorizeDraftProjects(w, r, *req.WorkItemDrafts) {
+		return
+	}
 	proposal, err := h.proposalService().UpdateProposal(r.Context(), proposalapp.UpdateProposalInput{
 		ID:              proposalID,
 		Title:           req.Title,
@@ -139,11 +161,16 @@ func (h *Handler) updateThreadProposal(w http.ResponseWriter, r *http.Request) {
 		WorkItemDrafts:  req.WorkItemDrafts,
 		SourceMessageID: req.SourceMessageID,
 		Metadata:        req.Metadata,
+		ExpectedVersion: expectedVersion,
 	})
 	if err != nil {
+		if writeVersionConflict(w, r, err) {
+			return
+		}
 		writeProposalAppFailure(w, err, "UPDATE_PROPOSAL_FAILED")
 		return
 	}
+	setETag(w, proposal.Version)
 	writeJSON(w, http.StatusOK, proposal)
 }
 
@@ -166,16 +193,27 @@ func (h *Handler) replaceThreadProposalDrafts(w http.ResponseWriter, r *http.Req
 		writeError(w, http.StatusBadRequest, "invalid proposal ID", "BAD_ID")
 		return
 	}
+	expectedVersion, ok := readIfMatch(w, r)
+	if !ok {
+		return
+	}
 	var req replaceProposalDraftsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
-	proposal, err := h.proposalService().ReplaceDrafts(r.Context(), proposalID, req.WorkItemDrafts)
+	if !h.authorizeDraftProjects(w, r, req.WorkItemDrafts) {
+		return
+	}
+	proposal, err := h.proposalService().ReplaceDrafts(r.Context(), proposalID, expectedVersion, req.WorkItemDrafts)
 	if err != nil {
+		if writeVersionConflict(w, r, err) {
+			return
+		}
 		writeProposalAppFailure(w, err, "REPLACE_PROPOSAL_DRAFTS_FAILED")
 		return
 	}
+	setETag(w, proposal.Version)
 	writeJSON(w, http.StatusOK, proposal)
 }
 
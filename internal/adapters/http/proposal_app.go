@@ -48,6 +48,8 @@ func writeProposalAppFailure(w http.ResponseWriter, err error, fallbackCode stri
 		writeError(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
 	case errors.Is(err, core.ErrInvalidTransition):
 		writeError(w, http.StatusConflict, err.Error(), "INVALID_STATE")
+	case errors.Is(err, core.ErrVersionConflict):
+		writeError(w, http.StatusConflict, err.Error(), "VERSION_CONFLICT")
 	default:
 		if fallbackCode == "" {
 			fallbackCode = "PROPOSAL_FAILED"
@@ -0,0 +1,599 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// routeResources maps the first path segment of a route to the resource half
+// of its "resource:action" scope. Routes outside this map are not project
+// scoped: reads are open to any signed-in user and writes need an admin.
+var routeResources = map[string]string{
+	"projects":          "projects",
+	"spaces":            "projects",
+	"manifest":          "projects",
+	"work-items":        "issues",
+	"actions":           "issues",
+	"io-decls":          "issues",
+	"templates":         "issues",
+	"initiatives":       "issues",
+	"proposals":         "issues",
+	"pending-decisions": "issues",
+	"resources":         "issues",
+	"runs":              "runs",
+	"artifacts":         "runs",
+	"events":            "runs",
+	"analytics":         "runs",
+	"stats":             "runs",
+	"scheduler":         "runs",
+	"search":            "runs",
+	"ws":                "runs",
+	"threads":           "chat",
+	"messages":          "chat",
+	"chat":              "chat",
+	"requirements":      "chat",
+	"ceo":               "chat",
+	"notifications":     "chat",
+}
+
+// unscopedRoutes are open to every signed-in user regardless of method.
+var unscopedRoutes = map[string]bool{
+	"auth":   true,
+	"themes": true,
+	"utils":  true,
+}
+
+// routeScope returns the scope a user session needs for the matched route,
+// or "" when none is required.
+func routeScope(r *http.Request) string {
+	segments := strings.Split(strings.Trim(routePattern(r), "/"), "/")
+	first := segments[0]
+	write := r.Method != http.MethodGet && r.Method != http.MethodHead
+
+	switch {
+	case unscopedRoutes[first]:
+		return ""
+	case first == "actions" && len(segments) == 3 && segments[2] == "decision":
+		return core.ScopeGatesApprove
+	case first == "projects" && len(segments) >= 3 && segments[2] == "members":
+		if write {
+			return core.ScopeMembersWrite
+		}
+		return core.ScopeMembersRead
+	case first == "projects" && len(segments) >= 3 && segments[2] == "secrets":
+		if write {
+			return core.ScopeSecretsWrite
+		}
+		return core.ScopeSecretsRead
+	case first == "projects" && len(segments) == 1 && write:
+		// Creating a project is not tied to an existing membership.
+		return httpx.ScopeAdmin
+	}
+	resource, ok := routeResources[first]
+	if !ok {
+		if write {
+			return httpx.ScopeAdmin
+		}
+		return ""
+	}
+	if write {
+		return resource + ":write"
+	}
+	return resource + ":read"
+}
+
+// routePattern returns the matched route pattern without the /api prefix.
+func routePattern(r *http.Request) string {
+	pattern := ""
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		pattern = rctx.RoutePattern()
+	}
+	return strings.TrimPrefix(pattern, "/api")
+}
+
+// enforceProjectRoles applies project roles to requests signed in with a
+// console session. Token requests keep their token scopes, limited to the
+// token's project whitelist, and admin users hold every scope. It runs
+// inline after routing so URL params are known.
+func (h *Handler) enforceProjectRoles(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, ok := httpx.AuthFromContext(r.Context())
+		if ok && info.UserID == 0 && len(info.Projects) > 0 {
+			h.enforceTokenProjects(w, r, next, info.Projects)
+			return
+		}
+		if !ok || info.UserID == 0 || info.HasScope(httpx.ScopeAll) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scope := routeScope(r)
+		if scope == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if scope == httpx.ScopeAdmin {
+			writeForbidden(w, scope)
+			return
+		}
+		projects, err := h.requestProjects(r)
+		switch {
+		case errors.Is(err, core.ErrNotFound):
+			// Let the handler report the missing resource.
+			next.ServeHTTP(w, r)
+			return
+		case errors.Is(err, errProjectUnresolved):
+			writeForbidden(w, scope)
+			return
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+			return
+		}
+		if len(projects) == 0 && !h.authorizeUser(w, r, info.UserID, nil, scope) {
+			return
+		}
+		for _, projectID := range projects {
+			if !h.authorizeUser(w, r, info.UserID, &projectID, scope) {
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// enforceTokenProjects rejects requests of a project-restricted token that
+// target another project.
+func (h *Handler) enforceTokenProjects(w http.ResponseWriter, r *http.Request, next http.Handler, allowed []string) {
+	if routeScope(r) == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+	projects, err := h.requestProjects(r)
+	switch {
+	case errors.Is(err, errProjectUnresolved):
+		writeTokenProjectForbidden(w)
+		return
+	case err != nil && !errors.Is(err, core.ErrNotFound):
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	resolver := newEventProjectResolver(h.store)
+	for _, projectID := range projects {
+		if !resolver.projectAllowed(r.Context(), projectID, allowed) {
+			writeTokenProjectForbidden(w)
+			return
+		}
+	}
+	next.ServeHTTP(w, r)
+}
+
+func writeTokenProjectForbidden(w http.ResponseWriter) {
+	writeError(w, http.StatusForbidden, "token is not allowed to access this project", "FORBIDDEN")
+}
+
+// authorizeUser checks a session user's project role and writes 403 when
+// it does not grant scope.
+func (h *Handler) authorizeUser(w http.ResponseWriter, r *http.Request, userID int64, projectID *int64, scope string) bool {
+	allowed, err := h.userService().Authorize(r.Context(), userID, projectID, scope)
+	if err != nil && !errors.Is(err, core.ErrNotFound) {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return false
+	}
+	if !allowed {
+		writeForbidden(w, scope)
+		return false
+	}
+	return true
+}
+
+// authorizeSessionProject is authorizeUser for handlers that learn the
+// project from the request body, e.g. creating a work item. Token requests
+// are allowed unless the token's project whitelist excludes the project.
+func (h *Handler) authorizeSessionProject(w http.ResponseWriter, r *http.Request, projectID *int64, scope string) bool {
+	allowed, err := h.projectAccess(r.Context(), projectID, scope)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return false
+	}
+	if !allowed {
+		if info, _ := httpx.AuthFromContext(r.Context()); info.UserID == 0 {
+			writeTokenProjectForbidden(w)
+		} else {
+			writeForbidden(w, scope)
+		}
+	}
+	return allowed
+}
+
+// projectAccess reports whether the caller in ctx may use scope in
+// projectID; nil means the object is not tied to a project.
+func (h *Handler) projectAccess(ctx context.Context, projectID *int64, scope string) (bool, error) {
+	info, ok := httpx.AuthFromContext(ctx)
+	if ok && info.UserID == 0 && len(info.Projects) > 0 && projectID != nil {
+		return newEventProjectResolver(h.store).projectAllowed(ctx, *projectID, info.Projects), nil
+	}
+	if !ok || info.UserID == 0 || info.HasScope(httpx.ScopeAll) || projectID == nil {
+		return true, nil
+	}
+	allowed, err := h.userService().Authorize(ctx, info.UserID, projectID, scope)
+	if errors.Is(err, core.ErrNotFound) {
+		return false, nil
+	}
+	return allowed, err
+}
+
+// authorizeSessionWorkItem is authorizeSessionProject for the project of a
+// work item named in the request body, e.g. one being linked. A missing work
+// item is left to the handler to report.
+func (h *Handler) authorizeSessionWorkItem(w http.ResponseWriter, r *http.Request, workItemID int64, scope string) bool {
+	projectID, err := h.workItemProject(r.Context(), workItemID)
+	if errors.Is(err, core.ErrNotFound) {
+		return true
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return false
+	}
+	return h.authorizeSessionProject(w, r, projectID, scope)
+}
+
+// authorizeSessionThread is authorizeSessionWorkItem for a thread named in
+// the request body.
+func (h *Handler) authorizeSessionThread(w http.ResponseWriter, r *http.Request, threadID int64, scope string) bool {
+	thread, err := h.store.GetThread(r.Context(), threadID)
+	if errors.Is(err, core.ErrNotFound) {
+		return true
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return false
+	}
+	return h.authorizeSessionProject(w, r, threadProject(thread), scope)
+}
+
+func writeForbidden(w http.ResponseWriter, scope string) {
+	writeJSON(w, http.StatusForbidden, map[string]string{
+		"error":          "forbidden: missing " + scope + " in this project",
+		"code":           "FORBIDDEN",
+		"required_scope": scope,
+	})
+}
+
+// errProjectUnresolved reports a project-scoped resource whose owner is
+// gone, so its project cannot be checked. Such requests are denied rather
+// than treated as project-less.
+var errProjectUnresolved = errors.New("project of the requested resource not found")
+
+// projectParams are the URL params requestProjects resolves, in order.
+var projectParams = []string{
+	"projectID", "workItemID", "actionID", "runID", "threadID", "templateID",
+	"spaceID", "notificationID", "initiativeID", "proposalID", "resourceID",
+	"artifactID", "declID", "messageID", "entryID", "sessionID",
+}
+
+// requestProjects finds the projects a request targets from its URL params,
+// falling back to the project_id query parameter. A route naming several
+// resources, e.g. an initiative item, targets all their projects. An empty
+// result means the resources are not tied to a project. core.ErrNotFound
+// means none of the named resources exists.
+func (h *Handler) requestProjects(r *http.Request) ([]int64, error) {
+	var projects []int64
+	matched, notFound := false, false
+	chatRoute := strings.HasPrefix(routePattern(r), "/chat/")
+	for _, param := range projectParams {
+		if param == "sessionID" && !chatRoute {
+			continue // user session routes share the param name
+		}
+		var ids []int64
+		var err error
+		if param == "sessionID" {
+			id := strings.TrimSpace(chi.URLParam(r, param))
+			if id == "" {
+				continue
+			}
+			ids, err = h.chatSessionProjects(r.Context(), id)
+		} else {
+			id, ok := urlParamInt64(r, param)
+			if !ok {
+				continue
+			}
+			ids, err = h.paramProjects(r.Context(), param, id)
+		}
+		matched = true
+		if errors.Is(err, core.ErrNotFound) {
+			notFound = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, ids...)
+	}
+	if notFound && len(projects) == 0 {
+		return nil, core.ErrNotFound
+	}
+	if !matched {
+		if id, ok := queryInt64(r, "project_id"); ok {
+			return []int64{id}, nil
+		}
+	}
+	return projects, nil
+}
+
+// paramProjects resolves the resource named by URL param to its projects.
+// core.ErrNotFound means the resource itself is missing; a missing owner is
+// errProjectUnresolved.
+func (h *Handler) paramProjects(ctx context.Context, param string, id int64) ([]int64, error) {
+	switch param {
+	case "projectID":
+		return []int64{id}, nil
+	case "workItemID":
+		projectID, err := h.workItemProject(ctx, id)
+		return projectIDs(projectID), err
+	case "actionID":
+		action, err := h.store.GetAction(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return h.ownerWorkItemProjects(ctx, action.WorkItemID)
+	case "runID":
+		run, err := h.store.GetRun(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return h.ownerWorkItemProjects(ctx, run.WorkItemID)
+	case "threadID":
+		thread, err := h.store.GetThread(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return projectIDs(threadProject(thread)), nil
+	case "templateID":
+		tmpl, err := h.store.GetDAGTemplate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return projectIDs(tmpl.ProjectID), nil
+	case "spaceID":
+		space, err := h.store.GetResourceSpace(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return []int64{space.ProjectID}, nil
+	case "notificationID":
+		n, err := h.store.GetNotification(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return projectIDs(n.ProjectID), nil
+	case "initiativeID":
+		return h.initiativeProjects(ctx, id)
+	case "proposalID":
+		proposal, err := h.store.GetThreadProposal(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		projects, err := h.ownerThreadProjects(ctx, proposal.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		// Approving creates the drafted work items in their projects.
+		for _, draft := range proposal.WorkItemDrafts {
+			projects = append(projects, projectIDs(draft.ProjectID)...)
+		}
+		return projects, nil
+	case "resourceID":
+		resource, err := h.store.GetResource(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return projectIDs(&resource.ProjectID), nil
+	case "artifactID":
+		return h.deliverableProjects(ctx, id)
+	case "declID":
+		decl, err := h.store.GetActionIODecl(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		action, err := h.store.GetAction(ctx, decl.ActionID)
+		if err != nil {
+			return nil, ownerLookupError(err)
+		}
+		return h.ownerWorkItemProjects(ctx, action.WorkItemID)
+	case "messageID":
+		msg, err := h.store.GetThreadMessage(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return h.ownerThreadProjects(ctx, msg.ThreadID)
+	case "entryID":
+		entry, err := h.store.GetFeatureEntry(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return []int64{entry.ProjectID}, nil
+	}
+	return nil, nil
+}
+
+// initiativeProjects returns the projects of an initiative's work items.
+func (h *Handler) initiativeProjects(ctx context.Context, initiativeID int64) ([]int64, error) {
+	if _, err := h.store.GetInitiative(ctx, initiativeID); err != nil {
+		return nil, err
+	}
+	items, err := h.store.ListInitiativeItems(ctx, initiativeID)
+	if err != nil {
+		return nil, err
+	}
+	var projects []int64
+	for _, item := range items {
+		ids, err := h.ownerWorkItemProjects(ctx, item.WorkItemID)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, ids...)
+	}
+	return projects, nil
+}
+
+// deliverableProjects returns the projects of the work item, thread or run
+// a deliverable belongs to.
+func (h *Handler) deliverableProjects(ctx context.Context, deliverableID int64) ([]int64, error) {
+	deliverable, err := h.store.GetDeliverable(ctx, deliverableID)
+	if err != nil {
+		return nil, err
+	}
+	var projects []int64
+	if deliverable.WorkItemID != nil {
+		ids, err := h.ownerWorkItemProjects(ctx, *deliverable.WorkItemID)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, ids...)
+	}
+	if deliverable.ThreadID != nil {
+		ids, err := h.ownerThreadProjects(ctx, *deliverable.ThreadID)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, ids...)
+	}
+	if deliverable.ProducerType == core.DeliverableProducerRun {
+		run, err := h.store.GetRun(ctx, deliverable.ProducerID)
+		if err != nil {
+			return nil, ownerLookupError(err)
+		}
+		ids, err := h.ownerWorkItemProjects(ctx, run.WorkItemID)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, ids...)
+	}
+	return projects, nil
+}
+
+// chatSessionProjects returns the project a lead chat session works in.
+func (h *Handler) chatSessionProjects(ctx context.Context, sessionID string) ([]int64, error) {
+	if h.lead == nil {
+		return nil, nil
+	}
+	session, err := h.lead.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return projectIDs(&session.ProjectID), nil
+}
+
+// ownerWorkItemProjects is workItemProject for the work item owning the
+// requested resource.
+func (h *Handler) ownerWorkItemProjects(ctx context.Context, workItemID int64) ([]int64, error) {
+	projectID, err := h.workItemProject(ctx, workItemID)
+	if err != nil {
+		return nil, ownerLookupError(err)
+	}
+	return projectIDs(projectID), nil
+}
+
+// ownerThreadProjects returns the focus project of the thread owning the
+// requested resource.
+func (h *Handler) ownerThreadProjects(ctx context.Context, threadID int64) ([]int64, error) {
+	thread, err := h.store.GetThread(ctx, threadID)
+	if err != nil {
+		return nil, ownerLookupError(err)
+	}
+	return projectIDs(threadProject(thread)), nil
+}
+
+func ownerLookupError(err error) error {
+	if errors.Is(err, core.ErrNotFound) {
+		return errProjectUnresolved
+	}
+	return err
+}
+
+// projectIDs returns id as a one-element list, or nil when unset.
+func projectIDs(id *int64) []int64 {
+	if id == nil || *id <= 0 {
+		return nil
+	}
+	return []int64{*id}
+}
+
+// threadProject returns the project a thread is focused on, or nil.
+func threadProject(thread *core.Thread) *int64 {
+	if thread.FocusProjectID > 0 {
+		return &thread.FocusProjectID
+	}
+	return nil
+}
+
+func (h *Handler) workItemProject(ctx context.Context, workItemID int64) (*int64, error) {
+	item, err := h.store.GetWorkItem(ctx, workItemID)
+	if err != nil {
+		return nil, err
+	}
+	return item.ProjectID, nil
+}
+
+// sessionProjectFilter returns the projects a session user may read with
+// scope, for filtering list responses. ok is false for token requests and
+// admin users, which see everything.
+func (h *Handler) sessionProjectFilter(r *http.Request, scope string) (allowed map[int64]bool, ok bool, err error) {
+	info, authed := httpx.AuthFromContext(r.Context())
+	if !authed || info.UserID == 0 || info.HasScope(httpx.ScopeAll) {
+		return nil, false, nil
+	}
+	ids, all, err := h.userService().ProjectsWithScope(r.Context(), info.UserID, scope)
+	if err != nil || all {
+		return nil, false, err
+	}
+	return ids, true, nil
+}
+
+// projectVisible applies a sessionProjectFilter result to one row. Rows
+// without a project follow the unscoped rule: any matching membership.
+func projectVisible(allowed map[int64]bool, projectID *int64) bool {
+	if projectID == nil {
+		return len(allowed) > 0
+	}
+	return allowed[*projectID]
+}
+
+// eventVisible applies a sessionProjectFilter result to an event, whose
+// project is looked up through resolver.
+func eventVisible(ctx context.Context, resolver *eventProjectResolver, allowed map[int64]bool, ev core.Event) bool {
+	if projectID, ok := resolver.projectOf(ctx, ev); ok {
+		return allowed[projectID]
+	}
+	return len(allowed) > 0
+}
+
+// visibleActions drops the actions whose work item lies in a project the
+// session user may not read with scope.
+func (h *Handler) visibleActions(r *http.Request, actions []*core.Action, scope string) ([]*core.Action, error) {
+	allowed, restricted, err := h.sessionProjectFilter(r, scope)
+	if err != nil || !restricted {
+		return actions, err
+	}
+	projects := make(map[int64]*int64)
+	visible := make([]*core.Action, 0, len(actions))
+	for _, action := range actions {
+		projectID, cached := projects[action.WorkItemID]
+		if !cached {
+			projectID, err = h.workItemProject(r.Context(), action.WorkItemID)
+			if err != nil && !errors.Is(err, core.ErrNotFound) {
+				return nil, err
+			}
+			projects[action.WorkItemID] = projectID
+		}
+		if projectVisible(allowed, projectID) {
+			visible = append(visible, action)
+		}
+	}
+	return visible, nil
+}
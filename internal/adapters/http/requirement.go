@@ -48,6 +48,11 @@ func (h *Handler) createThreadFromRequirement(w http.ResponseWriter, r *http.Req
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	for _, projectID := range requirementThreadProjects(req) {
+		if !h.authorizeSessionProject(w, r, &projectID, "chat:write") {
+			return
+		}
+	}
 	if strings.TrimSpace(req.OwnerID) == "" {
 		req.OwnerID = "human"
 	}
@@ -75,6 +80,21 @@ func (h *Handler) createThreadFromRequirement(w http.ResponseWriter, r *http.Req
 	})
 }
 
+// requirementThreadProjects lists the projects a requirement thread gets
+// context refs for: the configured ones, or else the analysis matches.
+func requirementThreadProjects(input requirementapp.CreateThreadInput) []int64 {
+	var projects []int64
+	for _, ref := range input.ThreadConfig.ContextRefs {
+		projects = append(projects, projectIDs(&ref.ProjectID)...)
+	}
+	if input.Analysis != nil {
+		for _, item := range input.Analysis.MatchedProjects {
+			projects = append(projects, projectIDs(&item.ProjectID)...)
+		}
+	}
+	return projects
+}
+
 func buildRequirementInitialMessage(input requirementapp.CreateThreadInput) string {
 	var b strings.Builder
 	b.WriteString("以下是本次新需求，请开始协作分析并收敛：\n\n")
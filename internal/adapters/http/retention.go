@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	retentionapp "github.com/yoke233/zhanggui/internal/application/retention"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// RetentionRunner runs the data-retention service. Implemented by *retention.Service.
+type RetentionRunner interface {
+	Run(ctx context.Context, opts retentionapp.RunOptions) (*core.RetentionReport, error)
+}
+
+func registerRetentionAdminRoutes(r chi.Router, h *Handler) {
+	r.Get("/admin/retention/report", h.getRetentionReport)
+	r.Post("/admin/retention/prune", h.pruneRetention)
+}
+
+// getRetentionReport returns a dry-run report of what retention would prune.
+func (h *Handler) getRetentionReport(w http.ResponseWriter, r *http.Request) {
+	h.runRetention(w, r, retentionapp.RunOptions{DryRun: true})
+}
+
+func (h *Handler) pruneRetention(w http.ResponseWriter, r *http.Request) {
+	h.runRetention(w, r, retentionapp.RunOptions{
+		DryRun: queryBool(r, "dry_run"),
+		Vacuum: queryBool(r, "vacuum"),
+	})
+}
+
+func (h *Handler) runRetention(w http.ResponseWriter, r *http.Request, opts retentionapp.RunOptions) {
+	if h.retention == nil {
+		writeError(w, http.StatusServiceUnavailable, "retention service is not enabled", "RETENTION_UNAVAILABLE")
+		return
+	}
+	report, err := h.retention.Run(r.Context(), opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "RETENTION_FAILED")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// search runs a ranked full-text query across work items, thread messages,
+// journal entries, run results and deliverables.
+//
+// GET /search?q=&kind=work_item,run&project_id=&limit=&offset=
+func (h *Handler) search(w http.ResponseWriter, r *http.Request) {
+	searcher, ok := h.store.(core.SearchStore)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "full-text search is not supported by this store", "SEARCH_UNAVAILABLE")
+		return
+	}
+	q := core.SearchQuery{
+		Query:  strings.TrimSpace(r.URL.Query().Get("q")),
+		Limit:  queryInt(r, "limit", 20),
+		Offset: queryInt(r, "offset", 0),
+	}
+	for _, raw := range r.URL.Query()["kind"] {
+		for _, part := range strings.Split(raw, ",") {
+			kind := core.SearchKind(strings.TrimSpace(part))
+			if kind == "" {
+				continue
+			}
+			if !kind.Valid() {
+				writeError(w, http.StatusBadRequest, "unknown search kind: "+string(kind), "BAD_REQUEST")
+				return
+			}
+			q.Kinds = append(q.Kinds, kind)
+		}
+	}
+	if projectID, ok := queryInt64(r, "project_id"); ok {
+		q.ProjectID = &projectID
+	}
+
+	hits, err := searcher.Search(r.Context(), q)
+	if errors.Is(err, core.ErrEmptySearchQuery) {
+		writeError(w, http.StatusBadRequest, "q is required", "BAD_REQUEST")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "SEARCH_FAILED")
+		return
+	}
+	allowed, restricted, err := h.sessionProjectFilter(r, "runs:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	visible := make([]core.SearchHit, 0, len(hits))
+	for _, hit := range hits {
+		if restricted && !projectVisible(allowed, hit.ProjectID) {
+			continue
+		}
+		visible = append(visible, hit)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"query": q.Query, "results": visible})
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestAPI_SearchReturnsRankedSnippets(t *testing.T) {
+	h, ts := setupAPI(t)
+	ctx := context.Background()
+
+	projectID, err := h.store.CreateProject(ctx, &core.Project{Name: "search"})
+	if err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	wiID, err := h.store.CreateWorkItem(ctx, &core.WorkItem{ProjectID: &projectID, Title: "Rotate webhook secrets", Body: "Secrets leak into logs.", Status: core.WorkItemOpen})
+	if err != nil {
+		t.Fatalf("create work item: %v", err)
+	}
+	if _, err := h.store.CreateWorkItem(ctx, &core.WorkItem{Title: "Unrelated webhook retry", Status: core.WorkItemOpen}); err != nil {
+		t.Fatalf("create work item: %v", err)
+	}
+
+	resp, err := get(ts, "/search?q=webhook&kind=work_item&project_id="+itoa64(projectID))
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var body struct {
+		Results []core.SearchHit `json:"results"`
+	}
+	if err := decodeJSON(resp, &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body.Results) != 1 || body.Results[0].RefID != wiID || !strings.Contains(body.Results[0].Snippet, "<mark>webhook</mark>") {
+		t.Fatalf("results = %+v", body.Results)
+	}
+
+	for _, path := range []string{"/search?q=", "/search?q=webhook&kind=bogus"} {
+		resp, err := get(ts, path)
+		if err != nil {
+			t.Fatalf("search %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", path, resp.StatusCode)
+		}
+	}
+}
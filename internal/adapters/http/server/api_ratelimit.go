@@ -0,0 +1,435 @@
+package httpx
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+// defaultRateLimitGroup names the bucket every request draws from.
+const defaultRateLimitGroup = "default"
+
+// APIRateLimitSource returns the current API limits and a version that
+// changes whenever they do, e.g. the runtime config snapshot version.
+type APIRateLimitSource func() (config.RuntimeRateLimitConfig, int64)
+
+// APIRateLimiter enforces token-bucket limits per caller and route group.
+// Limits are re-read from the source on every request; a new version
+// recompiles the rules and starts every bucket full again.
+type APIRateLimiter struct {
+	source APIRateLimitSource
+	now    func() time.Time
+
+	mu        sync.Mutex
+	loaded    bool
+	version   int64
+	policy    rateLimitPolicy
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	counts    map[string]*RateLimitGroupStats
+}
+
+// RateLimitGroupStats counts decisions for one route group.
+type RateLimitGroupStats struct {
+	Allowed  int64 `json:"allowed"`
+	Rejected int64 `json:"rejected"`
+}
+
+// APIRateLimitStats is a snapshot of the limiter counters. Counters are kept
+// across config reloads.
+type APIRateLimitStats struct {
+	Enabled       bool                           `json:"enabled"`
+	ConfigVersion int64                          `json:"config_version"`
+	Buckets       int                            `json:"buckets"`
+	Allowed       int64                          `json:"allowed"`
+	Rejected      int64                          `json:"rejected"`
+	Groups        map[string]RateLimitGroupStats `json:"groups"`
+}
+
+// NewAPIRateLimiter creates a limiter reading its limits from source.
+func NewAPIRateLimiter(source APIRateLimitSource) *APIRateLimiter {
+	return &APIRateLimiter{
+		source:  source,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+		counts:  make(map[string]*RateLimitGroupStats),
+	}
+}
+
+// Middleware rejects requests over their caller's limits with 429 and sets
+// RateLimit-* headers on every limited response. It must run after
+// authentication; agent tokens are exempt.
+func (l *APIRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, authenticated := AuthFromContext(r.Context())
+		if authenticated && info.Agent {
+			next.ServeHTTP(w, r)
+			return
+		}
+		d := l.decide(r, info, authenticated)
+		if !d.limited {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(d.rule.burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(d.reset))
+		h.Set("RateLimit-Policy", d.rule.policy())
+		if !d.allowed {
+			h.Set("Retry-After", strconv.Itoa(d.retryAfter))
+			WriteJSON(w, http.StatusTooManyRequests, map[string]any{
+				"error":               fmt.Sprintf("rate limit exceeded for %s requests, retry in %ds", d.rule.group, d.retryAfter),
+				"code":                "RATE_LIMITED",
+				"group":               d.rule.group,
+				"retry_after_seconds": d.retryAfter,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Stats returns the allowed and rejected counters per group.
+func (l *APIRateLimiter) Stats() APIRateLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refreshLocked()
+	out := APIRateLimitStats{
+		Enabled:       l.policy.enabled,
+		ConfigVersion: l.version,
+		Buckets:       len(l.buckets),
+		Groups:        make(map[string]RateLimitGroupStats, len(l.counts)),
+	}
+	for name, c := range l.counts {
+		out.Groups[name] = *c
+		out.Allowed += c.Allowed
+		out.Rejected += c.Rejected
+	}
+	return out
+}
+
+type rateLimitDecision struct {
+	limited    bool
+	allowed    bool
+	rule       rateLimitRule
+	remaining  int
+	reset      int
+	retryAfter int
+}
+
+func (l *APIRateLimiter) decide(r *http.Request, info AuthInfo, authenticated bool) rateLimitDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refreshLocked()
+	if !l.policy.enabled {
+		return rateLimitDecision{}
+	}
+	now := l.now()
+	l.pruneLocked(now)
+
+	caller := callerKey(r, info, authenticated)
+	rules := []rateLimitRule{l.policy.ruleFor(defaultRateLimitGroup, l.policy.base, info, authenticated)}
+	counted := defaultRateLimitGroup
+	if group := l.policy.match(r); group != nil {
+		rules = append(rules, l.policy.ruleFor(group.name, group.rule, info, authenticated))
+		counted = group.name
+	}
+
+	buckets := make([]*tokenBucket, len(rules))
+	for i, rule := range rules {
+		key := caller + "|" + rule.group
+		b := l.buckets[key]
+		if b == nil {
+			b = &tokenBucket{rule: rule, tokens: float64(rule.burst), updated: now}
+			l.buckets[key] = b
+		}
+		b.refill(now)
+		buckets[i] = b
+	}
+
+	// Draw from every bucket only when all of them have a token, so that a
+	// request rejected by its group does not also drain the default bucket.
+	for i, b := range buckets {
+		if b.tokens < 1 {
+			l.countLocked(rules[i].group).Rejected++
+			return rateLimitDecision{
+				limited:    true,
+				rule:       rules[i],
+				reset:      b.secondsUntilFull(),
+				retryAfter: seconds((1 - b.tokens) / rules[i].perSecond()),
+			}
+		}
+	}
+	d := rateLimitDecision{limited: true, allowed: true, remaining: math.MaxInt}
+	for i, b := range buckets {
+		b.tokens--
+		if remaining := int(b.tokens); remaining < d.remaining {
+			d.rule, d.remaining, d.reset = rules[i], remaining, b.secondsUntilFull()
+		}
+	}
+	l.countLocked(counted).Allowed++
+	return d
+}
+
+func (l *APIRateLimiter) refreshLocked() {
+	if l.source == nil {
+		return
+	}
+	cfg, version := l.source()
+	if l.loaded && version == l.version {
+		return
+	}
+	l.policy = compileRateLimitPolicy(cfg)
+	l.version = version
+	l.loaded = true
+	l.buckets = make(map[string]*tokenBucket)
+}
+
+// pruneLocked drops buckets that have refilled completely; a fresh bucket
+// starts full, so forgetting them changes nothing for the caller.
+func (l *APIRateLimiter) pruneLocked(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.rule.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *APIRateLimiter) countLocked(group string) *RateLimitGroupStats {
+	c := l.counts[group]
+	if c == nil {
+		c = &RateLimitGroupStats{}
+		l.counts[group] = c
+	}
+	return c
+}
+
+// CallerKey identifies who sent r: the console user, a token hash prefix or
+// the client IP. It keys rate limit buckets and idempotency records.
+func CallerKey(r *http.Request) string {
+	info, ok := AuthFromContext(r.Context())
+	return callerKey(r, info, ok)
+}
+
+func callerKey(r *http.Request, info AuthInfo, authenticated bool) string {
+	switch {
+	case authenticated && info.UserID > 0:
+		return "user:" + strconv.FormatInt(info.UserID, 10)
+	case authenticated && info.TokenID != "":
+		return "token:" + info.TokenID[:min(16, len(info.TokenID))]
+	default:
+		return "ip:" + extractClientIP(r)
+	}
+}
+
+type tokenBucket struct {
+	rule    rateLimitRule
+	tokens  float64
+	updated time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.rule.burst), b.tokens+elapsed*b.rule.perSecond())
+	}
+	b.updated = now
+}
+
+func (b *tokenBucket) secondsUntilFull() int {
+	return seconds((float64(b.rule.burst) - b.tokens) / b.rule.perSecond())
+}
+
+func seconds(v float64) int {
+	if v <= 0 {
+		return 0
+	}
+	return int(math.Ceil(v))
+}
+
+type rateLimitRule struct {
+	group     string
+	perMinute int
+	burst     int
+}
+
+func newRateLimitRule(group string, perMinute, burst int) rateLimitRule {
+	if burst <= 0 {
+		burst = perMinute
+	}
+	return rateLimitRule{group: group, perMinute: perMinute, burst: burst}
+}
+
+func (r rateLimitRule) perSecond() float64 {
+	return float64(r.perMinute) / 60
+}
+
+// policy renders the rule as "<burst>;w=<seconds to refill the burst>".
+func (r rateLimitRule) policy() string {
+	return fmt.Sprintf("%d;w=%d", r.burst, seconds(float64(r.burst)/r.perSecond()))
+}
+
+type rateLimitGroup struct {
+	name   string
+	rule   rateLimitRule
+	routes []routePattern
+}
+
+type rateLimitQuota struct {
+	kind  string
+	value string
+	group string
+	rule  rateLimitRule
+}
+
+type rateLimitPolicy struct {
+	enabled bool
+	base    rateLimitRule
+	groups  []rateLimitGroup
+	quotas  []rateLimitQuota
+}
+
+// compileRateLimitPolicy assumes cfg passed config validation; malformed
+// routes and quotas are skipped rather than rejected here.
+func compileRateLimitPolicy(cfg config.RuntimeRateLimitConfig) rateLimitPolicy {
+	p := rateLimitPolicy{
+		enabled: cfg.Enabled && cfg.RequestsPerMinute > 0,
+		base:    newRateLimitRule(defaultRateLimitGroup, cfg.RequestsPerMinute, cfg.Burst),
+	}
+	for _, g := range cfg.Groups {
+		name := strings.TrimSpace(g.Name)
+		if name == "" || g.RequestsPerMinute <= 0 {
+			continue
+		}
+		group := rateLimitGroup{name: name, rule: newRateLimitRule(name, g.RequestsPerMinute, g.Burst)}
+		for _, route := range g.Routes {
+			if pattern, ok := compileRoutePattern(route); ok {
+				group.routes = append(group.routes, pattern)
+			}
+		}
+		p.groups = append(p.groups, group)
+	}
+	for _, q := range cfg.Quotas {
+		kind, value, ok := strings.Cut(strings.TrimSpace(q.Caller), ":")
+		if !ok || value == "" || q.RequestsPerMinute <= 0 {
+			continue
+		}
+		group := strings.TrimSpace(q.Group)
+		if group == "" {
+			group = defaultRateLimitGroup
+		}
+		if kind == "token" {
+			value = strings.ToLower(value)
+		}
+		p.quotas = append(p.quotas, rateLimitQuota{
+			kind:  kind,
+			value: value,
+			group: group,
+			rule:  newRateLimitRule(group, q.RequestsPerMinute, q.Burst),
+		})
+	}
+	return p
+}
+
+// match returns the first group with a route matching the request.
+func (p rateLimitPolicy) match(r *http.Request) *rateLimitGroup {
+	segments := pathSegments(strings.TrimPrefix(r.URL.Path, "/api"))
+	for i := range p.groups {
+		for _, route := range p.groups[i].routes {
+			if route.match(r.Method, segments) {
+				return &p.groups[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ruleFor returns the first quota for the caller in group, or fallback.
+func (p rateLimitPolicy) ruleFor(group string, fallback rateLimitRule, info AuthInfo, authenticated bool) rateLimitRule {
+	if !authenticated {
+		return fallback
+	}
+	for _, q := range p.quotas {
+		if q.group == group && q.matches(info) {
+			return q.rule
+		}
+	}
+	return fallback
+}
+
+func (q rateLimitQuota) matches(info AuthInfo) bool {
+	switch q.kind {
+	case "user":
+		return info.UserID > 0 && strconv.FormatInt(info.UserID, 10) == q.value
+	case "submitter":
+		return info.Submitter == q.value
+	case "role":
+		return info.Role == q.value
+	case "token":
+		return info.TokenID != "" && strings.HasPrefix(info.TokenID, q.value)
+	default:
+		return false
+	}
+}
+
+// routePattern is a compiled "METHOD /path" group route.
+type routePattern struct {
+	method   string
+	segments []string
+	subtree  bool
+}
+
+func compileRoutePattern(route string) (routePattern, bool) {
+	fields := strings.Fields(route)
+	var p routePattern
+	switch len(fields) {
+	case 1:
+	case 2:
+		p.method = strings.ToUpper(fields[0])
+	default:
+		return routePattern{}, false
+	}
+	pattern := fields[len(fields)-1]
+	if !strings.HasPrefix(pattern, "/") {
+		return routePattern{}, false
+	}
+	if pattern == "/**" || strings.HasSuffix(pattern, "/**") {
+		p.subtree = true
+		pattern = strings.TrimSuffix(pattern, "/**")
+	}
+	if pattern != "" {
+		p.segments = pathSegments(pattern)
+	}
+	return p, true
+}
+
+func (p routePattern) match(method string, segments []string) bool {
+	if p.method != "" && p.method != method {
+		return false
+	}
+	if len(segments) < len(p.segments) || (!p.subtree && len(segments) != len(p.segments)) {
+		return false
+	}
+	for i, pattern := range p.segments {
+		if ok, _ := path.Match(pattern, segments[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func pathSegments(p string) []string {
+	return strings.Split(strings.Trim(p, "/"), "/")
+}
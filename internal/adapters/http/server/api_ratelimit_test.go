@@ -0,0 +1,195 @@
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+type fakeRateLimitSource struct {
+	cfg     config.RuntimeRateLimitConfig
+	version int64
+}
+
+func (s *fakeRateLimitSource) get() (config.RuntimeRateLimitConfig, int64) {
+	return s.cfg, s.version
+}
+
+func newTestAPIRateLimiter(src *fakeRateLimitSource, now *time.Time) http.Handler {
+	l := NewAPIRateLimiter(src.get)
+	l.now = func() time.Time { return *now }
+	return l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func serveAs(h http.Handler, method, target string, info *AuthInfo) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	if info != nil {
+		req = req.WithContext(context.WithValue(req.Context(), authInfoKey, *info))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIRateLimiter_GroupBucketAndHeaders(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	src := &fakeRateLimitSource{cfg: config.RuntimeRateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 600,
+		Burst:             100,
+		Groups: []config.RuntimeRateLimitGroupConfig{{
+			Name:              "llm",
+			Routes:            []string{"POST /chat", "POST /work-items/*/generate-actions"},
+			RequestsPerMinute: 6,
+			Burst:             2,
+		}},
+	}, version: 1}
+	h := newTestAPIRateLimiter(src, &now)
+	alice := &AuthInfo{Role: "operator", TokenID: "aaaaaaaaaaaaaaaaaaaa"}
+	bob := &AuthInfo{Role: "operator", TokenID: "bbbbbbbbbbbbbbbbbbbb"}
+
+	for i := 0; i < 2; i++ {
+		rec := serveAs(h, http.MethodPost, "/api/chat", alice)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("request %d: status = %d", i, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Fatalf("RateLimit-Limit = %q, want the stricter group bucket", got)
+		}
+	}
+	if got := serveAs(h, http.MethodPost, "/api/chat", alice).Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Fatalf("RateLimit-Remaining on rejection = %q", got)
+	}
+
+	rec := serveAs(h, http.MethodPost, "/api/work-items/7/generate-actions", alice)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 for the shared group bucket", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "10" || rec.Header().Get("RateLimit-Policy") != "2;w=20" {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["code"] != "RATE_LIMITED" || body["group"] != "llm" {
+		t.Fatalf("body = %v", body)
+	}
+
+	// Other routes and other callers are unaffected.
+	if rec := serveAs(h, http.MethodGet, "/api/chat", alice); rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "100" {
+		t.Fatalf("GET /chat: status = %d, headers = %v", rec.Code, rec.Header())
+	}
+	if rec := serveAs(h, http.MethodPost, "/api/chat", bob); rec.Code != http.StatusNoContent {
+		t.Fatalf("other token: status = %d", rec.Code)
+	}
+
+	now = now.Add(10 * time.Second)
+	if rec := serveAs(h, http.MethodPost, "/api/chat", alice); rec.Code != http.StatusNoContent {
+		t.Fatalf("after refill: status = %d", rec.Code)
+	}
+}
+
+func TestAPIRateLimiter_QuotasAndExemptions(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	src := &fakeRateLimitSource{cfg: config.RuntimeRateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		Burst:             1,
+		Quotas: []config.RuntimeRateLimitQuotaConfig{
+			{Caller: "submitter:ci-bot", RequestsPerMinute: 60, Burst: 3},
+		},
+	}, version: 1}
+	h := newTestAPIRateLimiter(src, &now)
+
+	bot := &AuthInfo{Submitter: "ci-bot", TokenID: "cccccccccccccccccccc"}
+	for i := 0; i < 3; i++ {
+		if rec := serveAs(h, http.MethodGet, "/api/work-items", bot); rec.Code != http.StatusNoContent {
+			t.Fatalf("quota request %d: status = %d", i, rec.Code)
+		}
+	}
+	if rec := serveAs(h, http.MethodGet, "/api/work-items", bot); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 after the quota burst", rec.Code)
+	}
+
+	agent := &AuthInfo{Agent: true, TokenID: "dddddddddddddddddddd"}
+	for i := 0; i < 3; i++ {
+		if rec := serveAs(h, http.MethodGet, "/api/work-items", agent); rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("agent request %d: status = %d, headers = %v", i, rec.Code, rec.Header())
+		}
+	}
+
+	// Without auth the client IP is the caller.
+	serveAs(h, http.MethodGet, "/api/work-items", nil)
+	if rec := serveAs(h, http.MethodGet, "/api/work-items", nil); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("anonymous: status = %d", rec.Code)
+	}
+}
+
+func TestAPIRateLimiter_ReloadsOnVersionChange(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	src := &fakeRateLimitSource{cfg: config.RuntimeRateLimitConfig{Enabled: true, RequestsPerMinute: 60, Burst: 1}, version: 1}
+	l := NewAPIRateLimiter(src.get)
+	l.now = func() time.Time { return now }
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	user := &AuthInfo{UserID: 4}
+
+	serveAs(h, http.MethodPost, "/api/projects", user)
+	if rec := serveAs(h, http.MethodPost, "/api/projects", user); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+
+	src.cfg.Burst = 5
+	src.version = 2
+	if rec := serveAs(h, http.MethodPost, "/api/projects", user); rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "5" {
+		t.Fatalf("after reload: status = %d, headers = %v", rec.Code, rec.Header())
+	}
+
+	src.cfg.Enabled = false
+	src.version = 3
+	for i := 0; i < 10; i++ {
+		if rec := serveAs(h, http.MethodPost, "/api/projects", user); rec.Code != http.StatusNoContent {
+			t.Fatalf("disabled: status = %d", rec.Code)
+		}
+	}
+
+	stats := l.Stats()
+	if stats.Enabled || stats.ConfigVersion != 3 || stats.Allowed != 2 || stats.Rejected != 1 || stats.Groups["default"].Rejected != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestRoutePatternMatch(t *testing.T) {
+	cases := []struct {
+		route, method, path string
+		want                bool
+	}{
+		{"POST /chat", http.MethodPost, "/chat", true},
+		{"POST /chat", http.MethodGet, "/chat", false},
+		{"/chat", http.MethodGet, "/chat", true},
+		{"POST /threads/*/agents", http.MethodPost, "/threads/12/agents", true},
+		{"POST /threads/*/agents", http.MethodPost, "/threads/12/agents/3", false},
+		{"/admin/**", http.MethodDelete, "/admin/audit/events", true},
+		{"/admin/**", http.MethodGet, "/administer", false},
+		{"/**", http.MethodGet, "/anything/at/all", true},
+	}
+	for _, tc := range cases {
+		p, ok := compileRoutePattern(tc.route)
+		if !ok {
+			t.Fatalf("compileRoutePattern(%q) failed", tc.route)
+		}
+		if got := p.match(tc.method, pathSegments(tc.path)); got != tc.want {
+			t.Errorf("%q matches %s %s = %v, want %v", tc.route, tc.method, tc.path, got, tc.want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
@@ -32,6 +33,14 @@ type AuthInfo struct {
 	Scopes    []string
 	Submitter string
 	Projects  []string
+	// UserID is set when the request carries a console session cookie
+	// instead of a token; project roles then apply on top of Scopes.
+	UserID int64
+	// Agent marks tokens minted by GenerateScopedToken for agent sessions.
+	Agent bool
+	// TokenID is the HashToken of a header or query token; it keys API rate
+	// limits without keeping the secret.
+	TokenID string
 }
 
 func (a AuthInfo) HasScope(required string) bool {
@@ -44,10 +53,47 @@ func AuthFromContext(ctx context.Context) (AuthInfo, bool) {
 }
 
 type TokenRegistry struct {
-	mu            sync.RWMutex
-	entries       map[string]tokenRegistryEntry
+	mu      sync.RWMutex
+	entries map[string]tokenRegistryEntry
+	// revoked holds HashToken of revoked signed tokens.
 	revoked       map[string]struct{}
+	revocations   RevocationStore
 	signingSecret []byte
+	sessions      SessionResolver
+	stored        StoredTokenResolver
+}
+
+// SessionCookieName is the cookie that carries a console login session.
+const SessionCookieName = "zhanggui_session"
+
+// SessionResolver maps a session cookie value to the signed-in user.
+type SessionResolver func(ctx context.Context, token string) (AuthInfo, bool)
+
+// StoredTokenResolver maps a bearer token issued through the API to its
+// owner. remoteAddr is the client IP, recorded as the token's last use.
+type StoredTokenResolver func(ctx context.Context, token, remoteAddr string) (AuthInfo, bool)
+
+// RevocationStore persists revoked signed tokens so that a restart does not
+// make them valid again.
+type RevocationStore interface {
+	RevokeTokenHash(ctx context.Context, tokenHash string, expiresAt time.Time) error
+	ListRevokedTokenHashes(ctx context.Context, now time.Time) ([]string, error)
+}
+
+// HashToken is the hex SHA-256 under which tokens are stored and revoked.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// publicAuthPaths are reachable without credentials so that a user can sign
+// in and out; failed attempts still count toward the IP rate limit.
+var publicAuthPaths = map[string]bool{
+	"/api/auth/login":         true,
+	"/api/auth/logout":        true,
+	"/api/auth/providers":     true,
+	"/api/auth/oidc/login":    true,
+	"/api/auth/oidc/callback": true,
 }
 
 type tokenRegistryEntry struct {
@@ -55,6 +101,7 @@ type tokenRegistryEntry struct {
 	scopes    []string
 	submitter string
 	projects  []string
+	agent     bool
 }
 
 const (
@@ -116,22 +163,76 @@ func (r *TokenRegistry) GenerateScopedToken(role string, scopes []string, submit
 		role:      role,
 		scopes:    scopes,
 		submitter: submitter,
+		agent:     true,
 	}
 	r.mu.Unlock()
 	return token, nil
 }
 
-// RemoveToken removes a previously added runtime token.
+// RemoveToken removes a previously added runtime token. Signed tokens are
+// revoked, durably when a RevocationStore is set.
 func (r *TokenRegistry) RemoveToken(token string) {
 	if r == nil {
 		return
 	}
 	r.mu.Lock()
 	delete(r.entries, token)
-	if token = strings.TrimSpace(token); token != "" {
-		r.revoked[token] = struct{}{}
+	token = strings.TrimSpace(token)
+	hash := HashToken(token)
+	if token != "" {
+		r.revoked[hash] = struct{}{}
 	}
+	store := r.revocations
 	r.mu.Unlock()
+	if store == nil {
+		return
+	}
+	exp, ok := signedTokenExpiry(token)
+	if !ok || !time.Now().UTC().Before(exp) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := store.RevokeTokenHash(ctx, hash, exp); err != nil {
+		slog.Warn("auth: persist token revocation failed", "error", err)
+	}
+}
+
+// SetRevocationStore loads the persisted revocations of signed tokens and
+// records later ones in store.
+func (r *TokenRegistry) SetRevocationStore(ctx context.Context, store RevocationStore) error {
+	if r == nil || store == nil {
+		return nil
+	}
+	hashes, err := store.ListRevokedTokenHashes(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, hash := range hashes {
+		r.revoked[hash] = struct{}{}
+	}
+	r.revocations = store
+	return nil
+}
+
+// signedTokenExpiry reads the expiry of an rt1 token without verifying it;
+// it only bounds how long a revocation is kept.
+func signedTokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != signedScopedTokenPrefix {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims signedScopedTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0).UTC(), true
 }
 
 func (r *TokenRegistry) Lookup(token string) (AuthInfo, bool) {
@@ -147,7 +248,7 @@ func (r *TokenRegistry) Lookup(token string) (AuthInfo, bool) {
 	if trimmed == "" {
 		return AuthInfo{}, false
 	}
-	if _, revoked := r.revoked[trimmed]; revoked {
+	if _, revoked := r.revoked[HashToken(trimmed)]; revoked {
 		return AuthInfo{}, false
 	}
 	for registered, entry := range r.entries {
@@ -157,6 +258,7 @@ func (r *TokenRegistry) Lookup(token string) (AuthInfo, bool) {
 				Scopes:    entry.scopes,
 				Submitter: entry.submitter,
 				Projects:  entry.projects,
+				Agent:     entry.agent,
 			}, true
 		}
 	}
@@ -166,6 +268,55 @@ func (r *TokenRegistry) Lookup(token string) (AuthInfo, bool) {
 	return AuthInfo{}, false
 }
 
+// SetSessionResolver enables console session cookies alongside tokens.
+func (r *TokenRegistry) SetSessionResolver(resolver SessionResolver) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.sessions = resolver
+	r.mu.Unlock()
+}
+
+// SetStoredTokenResolver enables bearer tokens issued through the API
+// alongside configured and signed tokens.
+func (r *TokenRegistry) SetStoredTokenResolver(resolver StoredTokenResolver) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.stored = resolver
+	r.mu.Unlock()
+}
+
+// LookupStored resolves a bearer token issued through the API.
+func (r *TokenRegistry) LookupStored(ctx context.Context, token, remoteAddr string) (AuthInfo, bool) {
+	if r == nil {
+		return AuthInfo{}, false
+	}
+	r.mu.RLock()
+	resolver := r.stored
+	r.mu.RUnlock()
+	if resolver == nil || strings.TrimSpace(token) == "" {
+		return AuthInfo{}, false
+	}
+	return resolver(ctx, strings.TrimSpace(token), remoteAddr)
+}
+
+// LookupSession resolves a session cookie value.
+func (r *TokenRegistry) LookupSession(ctx context.Context, token string) (AuthInfo, bool) {
+	if r == nil {
+		return AuthInfo{}, false
+	}
+	r.mu.RLock()
+	resolver := r.sessions
+	r.mu.RUnlock()
+	if resolver == nil || strings.TrimSpace(token) == "" {
+		return AuthInfo{}, false
+	}
+	return resolver(ctx, token)
+}
+
 func (r *TokenRegistry) IsEmpty() bool {
 	if r == nil {
 		return true
@@ -221,6 +372,7 @@ func (r *TokenRegistry) lookupSignedScopedToken(token string) (AuthInfo, bool) {
 		Role:      claims.Role,
 		Scopes:    append([]string(nil), claims.Scopes...),
 		Submitter: claims.Submitter,
+		Agent:     true,
 	}, true
 }
 
@@ -231,7 +383,16 @@ func TokenAuthMiddleware(registry *TokenRegistry, opts ...AuthMiddlewareOption)
 	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicAuthPaths[r.URL.Path] {
+				serveCountingAuthFailures(w, r, next, cfg.rateLimiter)
+				return
+			}
 			token, source := extractRequestTokenWithSource(r)
+			if token == "" {
+				if cookie, err := r.Cookie(SessionCookieName); err == nil && cookie.Value != "" {
+					token, source = cookie.Value, "cookie"
+				}
+			}
 			if token == "" {
 				if cfg.rateLimiter != nil {
 					ip := extractClientIP(r)
@@ -240,7 +401,17 @@ func TokenAuthMiddleware(registry *TokenRegistry, opts ...AuthMiddlewareOption)
 				WriteJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 				return
 			}
-			info, ok := registry.Lookup(token)
+			var info AuthInfo
+			var ok bool
+			if source == "cookie" {
+				info, ok = registry.LookupSession(r.Context(), token)
+			} else {
+				info, ok = registry.Lookup(token)
+				if !ok {
+					info, ok = registry.LookupStored(r.Context(), token, extractClientIP(r))
+				}
+				info.TokenID = HashToken(token)
+			}
 			if !ok {
 				if cfg.rateLimiter != nil {
 					ip := extractClientIP(r)
@@ -255,7 +426,7 @@ func TokenAuthMiddleware(registry *TokenRegistry, opts ...AuthMiddlewareOption)
 			if cfg.rateLimiter != nil {
 				cfg.rateLimiter.Reset(extractClientIP(r))
 			}
-			if source == "query" && cfg.logger != nil && !isWebSocketUpgrade(r) {
+			if source == "query" && cfg.logger != nil && !isWebSocketUpgrade(r) && !isEventStreamRequest(r) {
 				cfg.logger.Printf("SECURITY WARNING: token passed via URL query parameter from %s — use Authorization header instead", extractClientIP(r))
 			}
 			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authInfoKey, info)))
@@ -263,6 +434,33 @@ func TokenAuthMiddleware(registry *TokenRegistry, opts ...AuthMiddlewareOption)
 	}
 }
 
+// serveCountingAuthFailures serves a public auth endpoint and feeds its 401
+// responses into the rate limiter.
+func serveCountingAuthFailures(w http.ResponseWriter, r *http.Request, next http.Handler, rl *RateLimiter) {
+	rec := &authStatusRecorder{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, r)
+	if rl == nil {
+		return
+	}
+	ip := extractClientIP(r)
+	switch {
+	case rec.status == http.StatusUnauthorized:
+		rl.RecordFailure(ip)
+	case rec.status < 300:
+		rl.Reset(ip)
+	}
+}
+
+type authStatusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *authStatusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
 type authMiddlewareCfg struct {
 	rateLimiter *RateLimiter
 	logger      *log.Logger
@@ -323,6 +521,15 @@ func isWebSocketUpgrade(r *http.Request) bool {
 	return strings.EqualFold(strings.TrimSpace(r.Header.Get("Upgrade")), "websocket")
 }
 
+// isEventStreamRequest reports an EventSource request, which like a WebSocket
+// handshake cannot set an Authorization header.
+func isEventStreamRequest(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
 func scopeMatches(userScopes []string, required string) bool {
 	for _, s := range userScopes {
 		if s == ScopeAll || s == required {
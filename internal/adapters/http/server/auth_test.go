@@ -2,11 +2,13 @@ package httpx
 
 import (
 	"bytes"
+	"context"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/yoke233/zhanggui/internal/platform/config"
 )
@@ -82,6 +84,9 @@ func TestTokenRegistry_GeneratedScopedTokenSurvivesRegistryRebuild(t *testing.T)
 	if info.Submitter != "agent/run-7" {
 		t.Fatalf("Submitter = %q, want %q", info.Submitter, "agent/run-7")
 	}
+	if !info.Agent {
+		t.Fatal("Agent = false, want true for a generated token")
+	}
 }
 
 func TestTokenRegistry_RemoveTokenRevokesGeneratedScopedTokenInProcess(t *testing.T) {
@@ -97,3 +102,85 @@ func TestTokenRegistry_RemoveTokenRevokesGeneratedScopedTokenInProcess(t *testin
 		t.Fatal("Lookup() = true, want false after RemoveToken")
 	}
 }
+
+type memRevocationStore struct {
+	hashes map[string]time.Time
+}
+
+func (m *memRevocationStore) RevokeTokenHash(_ context.Context, hash string, expiresAt time.Time) error {
+	m.hashes[hash] = expiresAt
+	return nil
+}
+
+func (m *memRevocationStore) ListRevokedTokenHashes(_ context.Context, now time.Time) ([]string, error) {
+	var out []string
+	for hash, exp := range m.hashes {
+		if exp.After(now) {
+			out = append(out, hash)
+		}
+	}
+	return out, nil
+}
+
+func TestTokenRegistry_RevocationSurvivesRegistryRebuild(t *testing.T) {
+	tokens := map[string]config.TokenEntry{
+		"admin": {Token: "persistent-admin-token", Scopes: []string{"*"}},
+	}
+	store := &memRevocationStore{hashes: map[string]time.Time{}}
+	ctx := context.Background()
+
+	issuer := NewTokenRegistry(tokens)
+	if err := issuer.SetRevocationStore(ctx, store); err != nil {
+		t.Fatalf("SetRevocationStore() error = %v", err)
+	}
+	token, err := issuer.GenerateScopedToken("agent-action-42", []string{"action:42"}, "agent/run-7")
+	if err != nil {
+		t.Fatalf("GenerateScopedToken() error = %v", err)
+	}
+	issuer.RemoveToken(token)
+	if _, ok := store.hashes[HashToken(token)]; !ok {
+		t.Fatal("revocation was not persisted")
+	}
+
+	reloaded := NewTokenRegistry(tokens)
+	if _, ok := reloaded.Lookup(token); !ok {
+		t.Fatal("Lookup() = false before loading revocations")
+	}
+	if err := reloaded.SetRevocationStore(ctx, store); err != nil {
+		t.Fatalf("SetRevocationStore() error = %v", err)
+	}
+	if _, ok := reloaded.Lookup(token); ok {
+		t.Fatal("Lookup() = true, want false after loading persisted revocation")
+	}
+}
+
+func TestTokenAuthMiddleware_FallsBackToStoredTokens(t *testing.T) {
+	registry := NewTokenRegistry(map[string]config.TokenEntry{
+		"admin": {Token: "secret-token", Scopes: []string{"*"}},
+	})
+	var gotIP string
+	registry.SetStoredTokenResolver(func(_ context.Context, token, remoteAddr string) (AuthInfo, bool) {
+		gotIP = remoteAddr
+		return AuthInfo{Role: "token", Submitter: "ci-bot"}, token == "zgt_ci"
+	})
+	var submitter string
+	handler := TokenAuthMiddleware(registry)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := AuthFromContext(r.Context())
+		submitter = info.Submitter
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for token, want := range map[string]int{"zgt_ci": http.StatusNoContent, "zgt_other": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = "10.1.2.3:5555"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("%s: status = %d, want %d", token, rec.Code, want)
+		}
+	}
+	if submitter != "ci-bot" || gotIP != "10.1.2.3" {
+		t.Fatalf("submitter = %q, ip = %q", submitter, gotIP)
+	}
+}
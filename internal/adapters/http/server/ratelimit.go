@@ -138,6 +138,11 @@ func RateLimitMiddleware(rl *RateLimiter) func(http.Handler) http.Handler {
 }
 
 // extractClientIP returns the client IP from X-Forwarded-For, X-Real-IP, or RemoteAddr.
+// ClientIP returns the client address used for rate limiting and auditing.
+func ClientIP(r *http.Request) string {
+	return extractClientIP(r)
+}
+
 func extractClientIP(r *http.Request) string {
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		// Take the first IP (the original client).
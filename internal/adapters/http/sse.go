@@ -0,0 +1,345 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// sseHeartbeatInterval is how often an idle stream sends a comment line so
+// proxies and load balancers keep the connection open.
+var sseHeartbeatInterval = 15 * time.Second
+
+// notificationLevelRank orders notification levels for min_level filtering.
+var notificationLevelRank = map[core.NotificationLevel]int{
+	core.NotificationLevelInfo:    0,
+	core.NotificationLevelSuccess: 1,
+	core.NotificationLevelWarning: 2,
+	core.NotificationLevelError:   3,
+}
+
+// streamEvents serves GET /events/stream as text/event-stream.
+// Query params (all optional):
+//   - types: comma-separated event types
+//   - project_id, work_item_id, thread_id: scope filters
+//   - min_level: drop notification.created events below this level
+//   - since_seq: replay persisted events after this sequence number
+//
+// Each event is sent with its sequence number as the SSE id, so a client
+// reconnecting with Last-Event-ID resumes where it stopped. Tokens restricted
+// to projects only receive events that resolve to one of those projects, and
+// session users only those of projects their roles let them read.
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := &eventStreamFilter{resolver: newEventProjectResolver(h.store)}
+	for _, t := range strings.Split(q.Get("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			f.types = append(f.types, core.EventType(t))
+		}
+	}
+	for key, dst := range map[string]**int64{"project_id": &f.projectID, "work_item_id": &f.workItemID, "thread_id": &f.threadID} {
+		s := q.Get(key)
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid "+key, "BAD_REQUEST")
+			return
+		}
+		*dst = &id
+	}
+	f.minLevel = -1
+	if s := strings.TrimSpace(q.Get("min_level")); s != "" {
+		rank, ok := notificationLevelRank[core.NotificationLevel(s)]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "min_level must be one of info, success, warning, error", "BAD_REQUEST")
+			return
+		}
+		f.minLevel = rank
+	}
+	if info, ok := httpx.AuthFromContext(r.Context()); ok && len(info.Projects) > 0 {
+		f.allowedProjects = info.Projects
+		if f.projectID != nil && !f.resolver.projectAllowed(r.Context(), *f.projectID, f.allowedProjects) {
+			writeError(w, http.StatusForbidden, "token is not allowed to read this project", "FORBIDDEN")
+			return
+		}
+	}
+	allowed, restricted, err := h.sessionProjectFilter(r, "runs:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	if restricted {
+		f.sessionProjects = allowed
+	}
+	sinceSeq, err := parseLastEventID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ctx := r.Context()
+	sub := h.bus.Subscribe(core.SubscribeOpts{Types: f.types, BufferSize: 256, NotifyOverflow: true})
+	defer sub.Cancel()
+
+	if _, err := fmt.Fprintf(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	send := func(id int64, event string, data any) error {
+		if err := writeSSE(w, id, event, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	var lastSent int64
+	if sinceSeq != nil {
+		filter := core.EventFilter{SinceSeq: sinceSeq, Types: f.types, WorkItemID: f.workItemID}
+		lastSent, err = h.replayEvents(ctx, filter,
+			func(ev core.Event) bool { return f.matches(ctx, ev) },
+			func(ev core.Event) error { return send(ev.Seq, string(ev.Type), ev) },
+			func(data map[string]any) error { return send(0, "stream.resync", data) })
+		if err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprintf(w, ": heartbeat %d\n\n", time.Now().Unix()); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if ev.Type == core.EventStreamOverflow {
+				if err := send(0, string(core.EventStreamOverflow), ev.Data); err != nil {
+					return
+				}
+				continue
+			}
+			if ev.Seq != 0 && ev.Seq <= lastSent {
+				continue
+			}
+			if !f.matches(ctx, ev) {
+				continue
+			}
+			if err := send(ev.Seq, string(ev.Type), ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// parseLastEventID reads the resume cursor from the Last-Event-ID header
+// (sent by EventSource on reconnect) or the since_seq query parameter.
+func parseLastEventID(r *http.Request) (*int64, error) {
+	raw := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if raw == "" {
+		raw = strings.TrimSpace(r.URL.Query().Get("since_seq"))
+	}
+	if raw == "" {
+		return nil, nil
+	}
+	seq, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || seq < 0 {
+		return nil, fmt.Errorf("invalid Last-Event-ID %q", raw)
+	}
+	return &seq, nil
+}
+
+func writeSSE(w http.ResponseWriter, id int64, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	if id > 0 {
+		fmt.Fprintf(&b, "id: %d\n", id)
+	}
+	fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", event, payload)
+	_, err = w.Write([]byte(b.String()))
+	return err
+}
+
+type eventStreamFilter struct {
+	types           []core.EventType
+	projectID       *int64
+	workItemID      *int64
+	threadID        *int64
+	minLevel        int
+	allowedProjects []string
+	// sessionProjects is the sessionProjectFilter result for a session
+	// user; nil means the user is not restricted.
+	sessionProjects map[int64]bool
+	resolver        *eventProjectResolver
+}
+
+func (f *eventStreamFilter) matches(ctx context.Context, ev core.Event) bool {
+	if f.workItemID != nil && ev.WorkItemID != *f.workItemID {
+		return false
+	}
+	if f.threadID != nil {
+		if tid, ok := threadIDFromEventData(ev.Data); !ok || tid != *f.threadID {
+			return false
+		}
+	}
+	if f.minLevel >= 0 && ev.Type == core.EventNotificationCreated {
+		if rank, ok := notificationLevelRank[notificationLevelOf(ev)]; !ok || rank < f.minLevel {
+			return false
+		}
+	}
+	if f.sessionProjects != nil && !eventVisible(ctx, f.resolver, f.sessionProjects, ev) {
+		return false
+	}
+	if f.projectID == nil && len(f.allowedProjects) == 0 {
+		return true
+	}
+	projectID, ok := f.resolver.projectOf(ctx, ev)
+	if !ok {
+		return false
+	}
+	if f.projectID != nil && projectID != *f.projectID {
+		return false
+	}
+	return len(f.allowedProjects) == 0 || f.resolver.projectAllowed(ctx, projectID, f.allowedProjects)
+}
+
+// notificationLevelOf reads the level of a notification.created event, whose
+// payload is a *core.Notification when live and a decoded map when replayed.
+func notificationLevelOf(ev core.Event) core.NotificationLevel {
+	switch n := ev.Data["notification"].(type) {
+	case *core.Notification:
+		if n != nil {
+			return n.Level
+		}
+	case core.Notification:
+		return n.Level
+	case map[string]any:
+		level, _ := n["level"].(string)
+		return core.NotificationLevel(level)
+	}
+	return ""
+}
+
+// eventProjectResolver maps events to their project, caching lookups for the
+// lifetime of one stream.
+type eventProjectResolver struct {
+	store      Store
+	workItems  map[int64]int64
+	threads    map[int64]int64
+	allowedIDs map[int64]bool
+}
+
+func newEventProjectResolver(store Store) *eventProjectResolver {
+	return &eventProjectResolver{
+		store:      store,
+		workItems:  make(map[int64]int64),
+		threads:    make(map[int64]int64),
+		allowedIDs: make(map[int64]bool),
+	}
+}
+
+func (r *eventProjectResolver) projectOf(ctx context.Context, ev core.Event) (int64, bool) {
+	if id, ok := int64FromAny(ev.Data["project_id"]); ok && id > 0 {
+		return id, true
+	}
+	if ev.Type == core.EventNotificationCreated {
+		switch n := ev.Data["notification"].(type) {
+		case *core.Notification:
+			if n != nil && n.ProjectID != nil {
+				return *n.ProjectID, true
+			}
+		case map[string]any:
+			if id, ok := int64FromAny(n["project_id"]); ok && id > 0 {
+				return id, true
+			}
+		}
+	}
+	if ev.WorkItemID > 0 {
+		projectID, cached := r.workItems[ev.WorkItemID]
+		if !cached {
+			if wi, err := r.store.GetWorkItem(ctx, ev.WorkItemID); err == nil && wi.ProjectID != nil {
+				projectID = *wi.ProjectID
+			}
+			r.workItems[ev.WorkItemID] = projectID
+		}
+		return projectID, projectID > 0
+	}
+	if tid, ok := threadIDFromEventData(ev.Data); ok {
+		projectID, cached := r.threads[tid]
+		if !cached {
+			if thread, err := r.store.GetThread(ctx, tid); err == nil {
+				projectID = thread.FocusProjectID
+			}
+			r.threads[tid] = projectID
+		}
+		return projectID, projectID > 0
+	}
+	return 0, false
+}
+
+// projectAllowed reports whether a token's project whitelist, which may list
+// project IDs or names, admits projectID.
+func (r *eventProjectResolver) projectAllowed(ctx context.Context, projectID int64, allowed []string) bool {
+	if ok, cached := r.allowedIDs[projectID]; cached {
+		return ok
+	}
+	idStr := strconv.FormatInt(projectID, 10)
+	var name string
+	if p, err := r.store.GetProject(ctx, projectID); err == nil {
+		name = p.Name
+	}
+	ok := false
+	for _, a := range allowed {
+		a = strings.TrimSpace(a)
+		if a == idStr || (name != "" && a == name) {
+			ok = true
+			break
+		}
+	}
+	r.allowedIDs[projectID] = ok
+	return ok
+}
+
+func int64FromAny(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	case json.Number:
+		id, err := n.Int64()
+		return id, err == nil
+	}
+	return 0, false
+}
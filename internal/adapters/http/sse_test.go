@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+type sseMessage struct {
+	id    string
+	event string
+	data  string
+}
+
+// openSSE connects to an event stream and returns a reader of SSE messages.
+func openSSE(t *testing.T, url string, header http.Header) (*http.Response, func() sseMessage) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	reader := bufio.NewReader(resp.Body)
+	next := func() sseMessage {
+		t.Helper()
+		var msg sseMessage
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case line == "":
+				if msg.event != "" {
+					return msg
+				}
+			case strings.HasPrefix(line, "id: "):
+				msg.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				msg.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				msg.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+	return resp, next
+}
+
+func TestAPI_EventStreamFiltersAndResumes(t *testing.T) {
+	_, bus, ts := setupSequencedAPI(t)
+	ctx := context.Background()
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: 1})
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: 2})
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemCompleted, WorkItemID: 2})
+
+	resp, next := openSSE(t, ts.URL+"/events/stream?work_item_id=2&types=work_item.started,work_item.completed",
+		http.Header{"Last-Event-ID": {"1"}})
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	for _, want := range []sseMessage{{id: "2", event: "work_item.started"}, {id: "3", event: "work_item.completed"}} {
+		if got := next(); got.id != want.id || got.event != want.event {
+			t.Fatalf("replayed = %+v, want %+v", got, want)
+		}
+	}
+
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemCompleted, WorkItemID: 1})
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemFailed, WorkItemID: 2})
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: 2})
+	got := next()
+	var ev core.Event
+	if err := json.Unmarshal([]byte(got.data), &ev); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if got.id != "6" || ev.Seq != 6 || ev.WorkItemID != 2 || ev.Type != core.EventWorkItemStarted {
+		t.Fatalf("live message = %+v", got)
+	}
+}
+
+func TestAPI_EventStreamProjectScopeAndLevel(t *testing.T) {
+	h, bus, _ := setupSequencedAPI(t)
+	ctx := context.Background()
+	alpha, _ := h.store.CreateProject(ctx, &core.Project{Name: "alpha"})
+	beta, _ := h.store.CreateProject(ctx, &core.Project{Name: "beta"})
+	alphaItem, _ := h.store.CreateWorkItem(ctx, &core.WorkItem{ProjectID: &alpha, Title: "a", Status: core.WorkItemOpen})
+	betaItem, _ := h.store.CreateWorkItem(ctx, &core.WorkItem{ProjectID: &beta, Title: "b", Status: core.WorkItemOpen})
+
+	registry := httpx.NewTokenRegistry(map[string]config.TokenEntry{
+		"viewer": {Token: "viewer-token", Scopes: []string{"*"}, Projects: []string{"alpha"}},
+	})
+	r := chi.NewRouter()
+	r.Use(httpx.TokenAuthMiddleware(registry))
+	h.Register(r)
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	auth := http.Header{"Authorization": {"Bearer viewer-token"}}
+
+	resp, _ := openSSE(t, ts.URL+"/events/stream?project_id="+itoa64(beta), auth)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("out-of-scope project status = %d, want 403", resp.StatusCode)
+	}
+	resp, _ = openSSE(t, ts.URL+"/events/stream?min_level=loud", auth)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad min_level status = %d, want 400", resp.StatusCode)
+	}
+
+	_, next := openSSE(t, ts.URL+"/events/stream?min_level=warning", auth)
+	time.Sleep(50 * time.Millisecond) // let the subscription register
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: betaItem})
+	bus.Publish(ctx, core.Event{Type: core.EventType("system_event")})
+	bus.Publish(ctx, core.Event{Type: core.EventNotificationCreated, Data: map[string]any{
+		"notification": &core.Notification{Level: core.NotificationLevelInfo, ProjectID: &alpha},
+	}})
+	bus.Publish(ctx, core.Event{Type: core.EventNotificationCreated, Data: map[string]any{
+		"notification": &core.Notification{Level: core.NotificationLevelError, ProjectID: &alpha, Title: "boom"},
+	}})
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: alphaItem})
+
+	if got := next(); got.event != string(core.EventNotificationCreated) || !strings.Contains(got.data, "boom") {
+		t.Fatalf("first message = %+v, want error notification", got)
+	}
+	if got := next(); got.event != string(core.EventWorkItemStarted) || !strings.Contains(got.data, `"work_item_id":`+itoa64(alphaItem)) {
+		t.Fatalf("second message = %+v, want alpha work item", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	issueapp "github.com/yoke233/zhanggui/internal/application/flow"
 	"github.com/yoke233/zhanggui/internal/core"
 )
 
@@ -26,6 +27,20 @@ func (h *Handler) getStats(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	allowed, restricted, err := h.sessionProjectFilter(r, "runs:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	if restricted {
+		visible := issues[:0]
+		for _, iss := range issues {
+			if iss != nil && projectVisible(allowed, iss.ProjectID) {
+				visible = append(visible, iss)
+			}
+		}
+		issues = visible
+	}
 
 	total := len(issues)
 	active := 0
@@ -87,6 +102,40 @@ func (h *Handler) getSchedulerStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// WriterStatsProvider reports batched event writer counters. Implemented by
+// *flow.BatchWriter.
+type WriterStatsProvider interface {
+	Stats() issueapp.BatchWriterStats
+}
+
+func (h *Handler) getWriterStats(w http.ResponseWriter, r *http.Request) {
+	if h.writer == nil {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"enabled": false,
+			"message": "batched writer is not configured",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled": true,
+		"stats":   h.writer.Stats(),
+	})
+}
+
+func (h *Handler) getRateLimitStats(w http.ResponseWriter, r *http.Request) {
+	if h.rateLimiter == nil {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"enabled": false,
+			"message": "API rate limiting is not configured",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled": true,
+		"stats":   h.rateLimiter.Stats(),
+	})
+}
+
 func listAllWorkItems(ctx context.Context, store core.WorkItemStore) ([]*core.WorkItem, error) {
 	const pageSize = 500
 	offset := 0
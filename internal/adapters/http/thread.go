@@ -115,6 +115,10 @@ func (h *Handler) createThread(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.TrimSpace(req.OwnerID) == "" {
+		req.OwnerID = sessionUserRef(r)
+	}
+
 	result, err := h.threadService().CreateThread(r.Context(), threadapp.CreateThreadInput{
 		Title:    req.Title,
 		OwnerID:  req.OwnerID,
@@ -147,10 +151,19 @@ func (h *Handler) listThreads(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
-	if threads == nil {
-		threads = []*core.Thread{}
+	allowed, restricted, err := h.sessionProjectFilter(r, "chat:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	visible := make([]*core.Thread, 0, len(threads))
+	for _, thread := range threads {
+		if restricted && !projectVisible(allowed, threadProject(thread)) {
+			continue
+		}
+		visible = append(visible, thread)
 	}
-	writeJSON(w, http.StatusOK, threads)
+	writeJSON(w, http.StatusOK, visible)
 }
 
 func (h *Handler) getThread(w http.ResponseWriter, r *http.Request) {
@@ -169,6 +182,7 @@ func (h *Handler) getThread(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	setETag(w, thread.Version)
 	writeJSON(w, http.StatusOK, thread)
 }
 
@@ -178,6 +192,10 @@ func (h *Handler) updateThread(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid thread ID", "BAD_ID")
 		return
 	}
+	expectedVersion, ok := readIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	thread, err := h.store.GetThread(r.Context(), threadID)
 	if err != nil {
@@ -188,6 +206,9 @@ func (h *Handler) updateThread(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	if !checkIfMatch(w, expectedVersion, thread.Version) {
+		return
+	}
 
 	var req updateThreadRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -223,9 +244,13 @@ func (h *Handler) updateThread(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.store.UpdateThread(r.Context(), thread); err != nil {
+		if writeVersionConflict(w, r, err) {
+			return
+		}
 		writeError(w, http.StatusInternalServerError, err.Error(), "UPDATE_THREAD_FAILED")
 		return
 	}
+	setETag(w, thread.Version)
 	writeJSON(w, http.StatusOK, thread)
 }
 
@@ -263,6 +288,10 @@ func (h *Handler) createThreadMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if strings.TrimSpace(req.SenderID) == "" {
+		req.SenderID = sessionUserRef(r)
+	}
+
 	_, msg, err := h.createThreadMessageAndRoute(r.Context(), threadMessageInput{
 		ThreadID:         threadID,
 		SenderID:         req.SenderID,
@@ -337,10 +366,23 @@ func (h *Handler) addThreadParticipant(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if strings.TrimSpace(req.UserID) == "" {
+		req.UserID = sessionUserRef(r)
+	}
 	if strings.TrimSpace(req.UserID) == "" {
 		writeError(w, http.StatusBadRequest, "user_id is required", "MISSING_USER_ID")
 		return
 	}
+	if userID, ok := core.ParseUserRef(req.UserID); ok {
+		if _, err := h.store.GetUser(r.Context(), userID); err != nil {
+			if err == core.ErrNotFound {
+				writeError(w, http.StatusBadRequest, "user "+req.UserID+" does not exist", "UNKNOWN_USER")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+			return
+		}
+	}
 
 	p := &core.ThreadMember{
 		ThreadID: threadID,
@@ -439,6 +481,9 @@ func (h *Handler) createThreadWorkItemLink(w http.ResponseWriter, r *http.Reques
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionWorkItem(w, r, req.WorkItemID, "issues:write") {
+		return
+	}
 	link, err := h.threadService().LinkThreadWorkItem(r.Context(), threadapp.LinkThreadWorkItemInput{
 		ThreadID:     threadID,
 		WorkItemID:   req.WorkItemID,
@@ -522,6 +567,9 @@ func (h *Handler) createThreadContextRef(w http.ResponseWriter, r *http.Request)
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionProject(w, r, &req.ProjectID, "chat:write") {
+		return
+	}
 
 	ref, err := h.threadService().CreateThreadContextRef(r.Context(), threadapp.CreateThreadContextRefInput{
 		ThreadID:  threadID,
@@ -620,6 +668,9 @@ func (h *Handler) createWorkItemFromThread(w http.ResponseWriter, r *http.Reques
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionProject(w, r, req.ProjectID, "issues:write") {
+		return
+	}
 
 	result, err := h.threadService().CreateWorkItemFromThread(r.Context(), threadapp.CreateWorkItemFromThreadInput{
 		ThreadID:      threadID,
@@ -646,6 +697,9 @@ func threadGrantedBy(r *http.Request) string {
 	if r == nil {
 		return ""
 	}
+	if ref := sessionUserRef(r); ref != "" {
+		return ref
+	}
 	if info, ok := httpx.AuthFromContext(r.Context()); ok {
 		if submitter := strings.TrimSpace(info.Submitter); submitter != "" {
 			return submitter
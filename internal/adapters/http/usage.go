@@ -8,7 +8,10 @@ import (
 
 // getUsageSummary returns aggregated usage analytics: totals, by project, by agent, by profile.
 func (h *Handler) getUsageSummary(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	ctx := r.Context()
 
 	totals, err := h.store.UsageTotals(ctx, filter)
@@ -55,7 +58,10 @@ func (h *Handler) getUsageSummary(w http.ResponseWriter, r *http.Request) {
 
 // getUsageByProject returns usage aggregated per project.
 func (h *Handler) getUsageByProject(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	data, err := h.store.UsageByProject(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "USAGE_ERROR")
@@ -69,7 +75,10 @@ func (h *Handler) getUsageByProject(w http.ResponseWriter, r *http.Request) {
 
 // getUsageByAgent returns usage aggregated per agent.
 func (h *Handler) getUsageByAgent(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	data, err := h.store.UsageByAgent(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "USAGE_ERROR")
@@ -83,7 +92,10 @@ func (h *Handler) getUsageByAgent(w http.ResponseWriter, r *http.Request) {
 
 // getUsageByProfile returns usage aggregated per profile.
 func (h *Handler) getUsageByProfile(w http.ResponseWriter, r *http.Request) {
-	filter := parseAnalyticsFilter(r)
+	filter, ok := h.analyticsFilter(w, r)
+	if !ok {
+		return
+	}
 	data, err := h.store.UsageByProfile(r.Context(), filter)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "USAGE_ERROR")
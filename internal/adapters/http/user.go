@@ -0,0 +1,339 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/application/userapp"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	User      *core.User `json:"user"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+type meResponse struct {
+	User        *core.User            `json:"user,omitempty"`
+	Memberships []*core.ProjectMember `json:"memberships,omitempty"`
+	Role        string                `json:"role,omitempty"`
+	Submitter   string                `json:"submitter,omitempty"`
+	Scopes      []string              `json:"scopes,omitempty"`
+}
+
+type createUserRequest struct {
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	Password    string `json:"password"`
+	Admin       bool   `json:"admin"`
+}
+
+type updateUserRequest struct {
+	DisplayName *string `json:"display_name"`
+	Password    *string `json:"password"`
+	Admin       *bool   `json:"admin"`
+	Disabled    *bool   `json:"disabled"`
+}
+
+type setProjectMemberRequest struct {
+	Role string `json:"role"`
+}
+
+func registerUserRoutes(r chi.Router, h *Handler) {
+	// Login and logout are exempt from token auth (see httpx.publicAuthPaths).
+	r.Post("/auth/login", h.login)
+	r.Post("/auth/logout", h.logout)
+	r.Get("/auth/me", h.getMe)
+	registerOIDCRoutes(r, h)
+
+	r.Get("/projects/{projectID}/members", h.listProjectMembers)
+	r.Put("/projects/{projectID}/members/{userID}", h.setProjectMember)
+	r.Delete("/projects/{projectID}/members/{userID}", h.deleteProjectMember)
+}
+
+func registerUserAdminRoutes(r chi.Router, h *Handler) {
+	r.Get("/admin/users", h.listUsers)
+	r.Post("/admin/users", h.createUser)
+	r.Get("/admin/users/{userID}", h.getUser)
+	r.Put("/admin/users/{userID}", h.updateUser)
+	r.Get("/admin/users/{userID}/identities", h.listUserIdentities)
+	r.Get("/admin/users/{userID}/sessions", h.listUserSessions)
+	r.Delete("/admin/users/{userID}/sessions", h.revokeUserSessions)
+	r.Delete("/admin/users/{userID}/sessions/{sessionID}", h.revokeUserSession)
+}
+
+func (h *Handler) login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	result, err := h.userService().Login(r.Context(), userapp.LoginInput{
+		Username:   req.Username,
+		Password:   req.Password,
+		UserAgent:  r.UserAgent(),
+		RemoteAddr: r.RemoteAddr,
+	})
+	if err != nil {
+		writeUserAppError(w, err)
+		return
+	}
+	http.SetCookie(w, sessionCookie(r, result.Token, result.Session.ExpiresAt))
+	writeJSON(w, http.StatusOK, loginResponse{User: result.User, ExpiresAt: result.Session.ExpiresAt})
+}
+
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(httpx.SessionCookieName); err == nil && cookie.Value != "" {
+		if err := h.userService().Logout(r.Context(), cookie.Value); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+			return
+		}
+	}
+	http.SetCookie(w, sessionCookie(r, "", time.Unix(0, 0)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionCookie builds the console session cookie; an empty value with a
+// past expiry clears it.
+func sessionCookie(r *http.Request, value string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     httpx.SessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   secureRequest(r),
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
+
+// secureRequest reports whether the browser reached us over HTTPS, directly
+// or through a TLS-terminating proxy.
+func secureRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// GET /auth/me describes the caller: the user and memberships of a console
+// session, or the role and scopes of a token.
+func (h *Handler) getMe(w http.ResponseWriter, r *http.Request) {
+	info, ok := httpx.AuthFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "not signed in", "UNAUTHORIZED")
+		return
+	}
+	if info.UserID == 0 {
+		writeJSON(w, http.StatusOK, meResponse{Role: info.Role, Submitter: info.Submitter, Scopes: info.Scopes})
+		return
+	}
+	user, err := h.store.GetUser(r.Context(), info.UserID)
+	if err != nil {
+		writeUserAppError(w, err)
+		return
+	}
+	memberships, err := h.store.ListUserMemberships(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, meResponse{User: user, Memberships: memberships, Role: info.Role})
+}
+
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.store.ListUsers(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	var req createUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	user, err := h.userService().CreateUser(r.Context(), userapp.CreateUserInput{
+		Username:    req.Username,
+		DisplayName: req.DisplayName,
+		Password:    req.Password,
+		Admin:       req.Admin,
+	})
+	if err != nil {
+		writeUserAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, user)
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlParamInt64(r, "userID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid user ID", "BAD_ID")
+		return
+	}
+	user, err := h.store.GetUser(r.Context(), id)
+	if err != nil {
+		writeUserAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlParamInt64(r, "userID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid user ID", "BAD_ID")
+		return
+	}
+	var req updateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	user, err := h.userService().UpdateUser(r.Context(), id, userapp.UpdateUserInput{
+		DisplayName: req.DisplayName,
+		Password:    req.Password,
+		Admin:       req.Admin,
+		Disabled:    req.Disabled,
+	})
+	if err != nil {
+		writeUserAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (h *Handler) listUserIdentities(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlParamInt64(r, "userID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid user ID", "BAD_ID")
+		return
+	}
+	identities, err := h.store.ListUserIdentities(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, identities)
+}
+
+func (h *Handler) listUserSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlParamInt64(r, "userID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid user ID", "BAD_ID")
+		return
+	}
+	sessions, err := h.store.ListUserSessions(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+// DELETE /admin/users/{userID}/sessions signs the user out everywhere.
+func (h *Handler) revokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlParamInt64(r, "userID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid user ID", "BAD_ID")
+		return
+	}
+	if err := h.store.DeleteUserSessions(r.Context(), id); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) revokeUserSession(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlParamInt64(r, "userID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid user ID", "BAD_ID")
+		return
+	}
+	sessionID, ok := urlParamInt64(r, "sessionID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid session ID", "BAD_ID")
+		return
+	}
+	if err := h.userService().RevokeSession(r.Context(), id, sessionID); err != nil {
+		writeUserAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) listProjectMembers(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := urlParamInt64(r, "projectID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid project ID", "BAD_ID")
+		return
+	}
+	members, err := h.store.ListProjectMembers(r.Context(), projectID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+func (h *Handler) setProjectMember(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := urlParamInt64(r, "projectID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid project ID", "BAD_ID")
+		return
+	}
+	userID, ok := urlParamInt64(r, "userID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid user ID", "BAD_ID")
+		return
+	}
+	var req setProjectMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	role, err := core.ParseProjectRole(req.Role)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "INVALID_ROLE")
+		return
+	}
+	member, err := h.userService().SetProjectMember(r.Context(), projectID, userID, role)
+	if err != nil {
+		writeUserAppError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, member)
+}
+
+func (h *Handler) deleteProjectMember(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := urlParamInt64(r, "projectID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid project ID", "BAD_ID")
+		return
+	}
+	userID, ok := urlParamInt64(r, "userID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid user ID", "BAD_ID")
+		return
+	}
+	if err := h.store.DeleteProjectMember(r.Context(), projectID, userID); err != nil {
+		writeUserAppError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/application/userapp"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func (h *Handler) userService() *userapp.Service {
+	return userapp.New(userapp.Config{Store: h.store})
+}
+
+// NewSessionResolver resolves console session cookies against store. Wire it
+// with httpx.TokenRegistry.SetSessionResolver.
+func NewSessionResolver(store userapp.Store) httpx.SessionResolver {
+	svc := userapp.New(userapp.Config{Store: store})
+	return func(ctx context.Context, token string) (httpx.AuthInfo, bool) {
+		user, _, err := svc.ResolveSession(ctx, token)
+		if err != nil {
+			return httpx.AuthInfo{}, false
+		}
+		return sessionAuthInfo(user), true
+	}
+}
+
+func sessionAuthInfo(user *core.User) httpx.AuthInfo {
+	info := httpx.AuthInfo{Role: "user", Submitter: user.Username, UserID: user.ID}
+	if user.Admin {
+		info.Role = "admin"
+		info.Scopes = []string{httpx.ScopeAll}
+	}
+	return info
+}
+
+// sessionUser returns the signed-in user ID and username of a console
+// session request.
+func sessionUser(r *http.Request) (int64, string, bool) {
+	info, ok := httpx.AuthFromContext(r.Context())
+	if !ok || info.UserID == 0 {
+		return 0, "", false
+	}
+	return info.UserID, info.Submitter, true
+}
+
+// sessionUserRef is the thread participant ID of the signed-in user, or ""
+// for token requests.
+func sessionUserRef(r *http.Request) string {
+	if userID, _, ok := sessionUser(r); ok {
+		return core.UserRef(userID)
+	}
+	return ""
+}
+
+func writeUserAppError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, core.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error(), "NOT_FOUND")
+	case errors.Is(err, userapp.ErrInvalidCredentials):
+		writeError(w, http.StatusUnauthorized, err.Error(), "INVALID_CREDENTIALS")
+	case errors.Is(err, userapp.ErrUsernameTaken):
+		writeError(w, http.StatusConflict, err.Error(), "USERNAME_TAKEN")
+	case errors.Is(err, userapp.ErrInvalidUser):
+		writeError(w, http.StatusBadRequest, err.Error(), "INVALID_USER")
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+	}
+}
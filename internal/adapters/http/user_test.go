@@ -0,0 +1,487 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	membus "github.com/yoke233/zhanggui/internal/adapters/events/memory"
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+type sessionTestServer struct {
+	url   string // API root, ".../api"
+	store *sqlite.Store
+}
+
+func newSessionTestServer(t *testing.T, opts ...HandlerOption) *sessionTestServer {
+	t.Helper()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	handler := NewHandler(store, membus.NewBus(), nil, opts...)
+	registry := httpx.NewTokenRegistry(map[string]config.TokenEntry{
+		"admin": {Token: "admin-token", Scopes: []string{"*"}},
+	})
+	registry.SetSessionResolver(NewSessionResolver(store))
+	registry.SetStoredTokenResolver(NewStoredTokenResolver(store))
+	server := httpx.NewServer(httpx.Config{Auth: registry, RouteRegistrar: handler.Register})
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return &sessionTestServer{url: ts.URL + "/api", store: store}
+}
+
+// do sends a JSON request authenticated with either a bearer token or a
+// session cookie.
+func (s *sessionTestServer) do(t *testing.T, method, path, token string, cookie *http.Cookie, body any) *http.Response {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, s.url+path, reader)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func (s *sessionTestServer) login(t *testing.T, username, password string) *http.Cookie {
+	t.Helper()
+	resp := s.do(t, http.MethodPost, "/auth/login", "", nil, map[string]string{"username": username, "password": password})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login %s: status %d", username, resp.StatusCode)
+	}
+	for _, c := range resp.Cookies() {
+		if c.Name == httpx.SessionCookieName {
+			if !c.HttpOnly {
+				t.Fatalf("session cookie must be HttpOnly")
+			}
+			return c
+		}
+	}
+	t.Fatalf("login %s: no session cookie", username)
+	return nil
+}
+
+func TestSessionLoginAndProjectRoles(t *testing.T) {
+	s := newSessionTestServer(t)
+	ctx := context.Background()
+
+	projectA, err := s.store.CreateProject(ctx, &core.Project{Name: "a", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	projectB, err := s.store.CreateProject(ctx, &core.Project{Name: "b", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	// Users are managed with the admin token.
+	resp := s.do(t, http.MethodPost, "/admin/users", "admin-token", nil, map[string]any{"username": "alice", "password": "correct horse"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create user: status %d", resp.StatusCode)
+	}
+	var alice core.User
+	if err := decodeJSON(resp, &alice); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp = s.do(t, http.MethodPut, "/projects/"+itoa64(projectA)+"/members/"+itoa64(alice.ID), "admin-token", nil, map[string]string{"role": "operator"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set operator: status %d", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodPut, "/projects/"+itoa64(projectB)+"/members/"+itoa64(alice.ID), "admin-token", nil, map[string]string{"role": "viewer"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set viewer: status %d", resp.StatusCode)
+	}
+
+	resp = s.do(t, http.MethodPost, "/auth/login", "", nil, map[string]string{"username": "alice", "password": "wrong password"})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("bad login: status %d, want 401", resp.StatusCode)
+	}
+	cookie := s.login(t, "alice", "correct horse")
+
+	resp = s.do(t, http.MethodGet, "/auth/me", "", cookie, nil)
+	var me meResponse
+	if err := decodeJSON(resp, &me); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if me.User == nil || me.User.ID != alice.ID || len(me.Memberships) != 2 {
+		t.Fatalf("me = %+v", me)
+	}
+
+	// Operator in A may create work items there; viewer in B may not.
+	resp = s.do(t, http.MethodPost, "/work-items", "", cookie, map[string]any{"title": "in a", "project_id": projectA})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create in operator project: status %d", resp.StatusCode)
+	}
+	var item core.WorkItem
+	if err := decodeJSON(resp, &item); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp = s.do(t, http.MethodPost, "/work-items", "", cookie, map[string]any{"title": "in b", "project_id": projectB})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("create in viewer project: status %d, want 403", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodPut, "/projects/"+itoa64(projectB), "", cookie, map[string]string{"name": "renamed"})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("update viewer project: status %d, want 403", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodGet, "/projects/"+itoa64(projectB), "", cookie, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("read viewer project: status %d", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodGet, "/admin/users", "", cookie, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("admin route: status %d, want 403", resp.StatusCode)
+	}
+
+	// Approving a gate needs the approver role.
+	actionID, err := s.store.CreateAction(ctx, &core.Action{
+		WorkItemID: item.ID,
+		Name:       "review",
+		Type:       core.ActionGate,
+		Status:     core.ActionBlocked,
+	})
+	if err != nil {
+		t.Fatalf("CreateAction: %v", err)
+	}
+	decision := map[string]string{"decision": "approve", "reason": "looks good"}
+	resp = s.do(t, http.MethodPost, "/actions/"+itoa64(actionID)+"/decision", "", cookie, decision)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("operator decision: status %d, want 403", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodPut, "/projects/"+itoa64(projectA)+"/members/"+itoa64(alice.ID), "admin-token", nil, map[string]string{"role": "approver"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set approver: status %d", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodPost, "/actions/"+itoa64(actionID)+"/decision", "", cookie, decision)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("approver decision: status %d", resp.StatusCode)
+	}
+	sig, err := s.store.GetLatestActionSignal(ctx, actionID, core.SignalApprove)
+	if err != nil {
+		t.Fatalf("GetLatestActionSignal: %v", err)
+	}
+	if sig.ActorUserID != alice.ID || sig.Actor != "alice" || sig.Source != core.SignalSourceHuman {
+		t.Fatalf("signal actor = %q/%d/%s, want alice/%d/human", sig.Actor, sig.ActorUserID, sig.Source, alice.ID)
+	}
+
+	// Logout revokes the session.
+	resp = s.do(t, http.MethodPost, "/auth/logout", "", cookie, nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("logout: status %d", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodGet, "/auth/me", "", cookie, nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("me after logout: status %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestSessionThreadParticipantsLinkUsers(t *testing.T) {
+	s := newSessionTestServer(t)
+
+	resp := s.do(t, http.MethodPost, "/admin/users", "admin-token", nil, map[string]any{"username": "root", "password": "correct horse", "admin": true})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create user: status %d", resp.StatusCode)
+	}
+	var root core.User
+	if err := decodeJSON(resp, &root); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	cookie := s.login(t, "root", "correct horse")
+
+	resp = s.do(t, http.MethodPost, "/threads", "", cookie, map[string]string{"title": "design"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create thread: status %d", resp.StatusCode)
+	}
+	var thread core.Thread
+	if err := decodeJSON(resp, &thread); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if thread.OwnerID != core.UserRef(root.ID) {
+		t.Fatalf("thread owner = %q, want %q", thread.OwnerID, core.UserRef(root.ID))
+	}
+
+	resp = s.do(t, http.MethodPost, "/threads/"+itoa64(thread.ID)+"/participants", "", cookie, map[string]string{"user_id": "user:9999"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unknown user participant: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestSessionViewerListsOnlyTheirProjects(t *testing.T) {
+	s := newSessionTestServer(t)
+	ctx := context.Background()
+
+	projectA, err := s.store.CreateProject(ctx, &core.Project{Name: "a", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	projectB, err := s.store.CreateProject(ctx, &core.Project{Name: "b", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	itemA, err := s.store.CreateWorkItem(ctx, &core.WorkItem{Title: "shared rollout a", ProjectID: &projectA, Status: core.WorkItemOpen})
+	if err != nil {
+		t.Fatalf("CreateWorkItem: %v", err)
+	}
+	itemB, err := s.store.CreateWorkItem(ctx, &core.WorkItem{Title: "shared rollout b", ProjectID: &projectB, Status: core.WorkItemOpen})
+	if err != nil {
+		t.Fatalf("CreateWorkItem: %v", err)
+	}
+	threadA, err := s.store.CreateThread(ctx, &core.Thread{Title: "thread a", Status: core.ThreadActive, FocusProjectID: projectA})
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	if _, err := s.store.CreateThread(ctx, &core.Thread{Title: "thread b", Status: core.ThreadActive, FocusProjectID: projectB}); err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	for _, id := range []int64{itemA, itemB} {
+		if _, err := s.store.CreateEvent(ctx, &core.Event{Type: core.EventWorkItemStarted, WorkItemID: id, Timestamp: time.Now()}); err != nil {
+			t.Fatalf("CreateEvent: %v", err)
+		}
+	}
+
+	resp := s.do(t, http.MethodPost, "/admin/users", "admin-token", nil, map[string]any{"username": "bob", "password": "correct horse"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create user: status %d", resp.StatusCode)
+	}
+	var bob core.User
+	if err := decodeJSON(resp, &bob); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp = s.do(t, http.MethodPut, "/projects/"+itoa64(projectA)+"/members/"+itoa64(bob.ID), "admin-token", nil, map[string]string{"role": "viewer"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set viewer: status %d", resp.StatusCode)
+	}
+	cookie := s.login(t, "bob", "correct horse")
+
+	resp = s.do(t, http.MethodGet, "/threads", "", cookie, nil)
+	var threads []core.Thread
+	if err := decodeJSON(resp, &threads); err != nil {
+		t.Fatalf("decode threads: %v", err)
+	}
+	if len(threads) != 1 || threads[0].ID != threadA {
+		t.Fatalf("threads = %+v, want only thread %d", threads, threadA)
+	}
+
+	resp = s.do(t, http.MethodGet, "/search?q=shared", "", cookie, nil)
+	var search struct {
+		Results []core.SearchHit `json:"results"`
+	}
+	if err := decodeJSON(resp, &search); err != nil {
+		t.Fatalf("decode search: %v", err)
+	}
+	if len(search.Results) == 0 {
+		t.Fatal("search returned no results from the viewer's project")
+	}
+	for _, hit := range search.Results {
+		if hit.ProjectID == nil || *hit.ProjectID != projectA {
+			t.Fatalf("search hit %+v is outside project %d", hit, projectA)
+		}
+	}
+
+	resp = s.do(t, http.MethodGet, "/events", "", cookie, nil)
+	var events []core.Event
+	if err := decodeJSON(resp, &events); err != nil {
+		t.Fatalf("decode events: %v", err)
+	}
+	if len(events) != 1 || events[0].WorkItemID != itemA {
+		t.Fatalf("events = %+v, want only the event of work item %d", events, itemA)
+	}
+
+	// Project B is still closed when asked for directly.
+	resp = s.do(t, http.MethodGet, "/events?project_id="+itoa64(projectB), "", cookie, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("events of another project: status %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestSessionCannotReachAnotherProjectByID(t *testing.T) {
+	s := newSessionTestServer(t)
+	ctx := context.Background()
+
+	projectA, err := s.store.CreateProject(ctx, &core.Project{Name: "a", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	projectB, err := s.store.CreateProject(ctx, &core.Project{Name: "b", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	// Objects owned by project B, each reached through its own ID.
+	itemB, err := s.store.CreateWorkItem(ctx, &core.WorkItem{Title: "b item", ProjectID: &projectB, Status: core.WorkItemOpen})
+	if err != nil {
+		t.Fatalf("CreateWorkItem: %v", err)
+	}
+	actionB, err := s.store.CreateAction(ctx, &core.Action{WorkItemID: itemB, Name: "build", Type: core.ActionExec, Status: core.ActionPending})
+	if err != nil {
+		t.Fatalf("CreateAction: %v", err)
+	}
+	runB, err := s.store.CreateRun(ctx, &core.Run{ActionID: actionB, WorkItemID: itemB, Status: core.RunSucceeded})
+	if err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	threadB, err := s.store.CreateThread(ctx, &core.Thread{Title: "b thread", Status: core.ThreadActive, FocusProjectID: projectB})
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	initiativeB, err := s.store.CreateInitiative(ctx, &core.Initiative{Title: "b initiative", Status: core.InitiativeProposed})
+	if err != nil {
+		t.Fatalf("CreateInitiative: %v", err)
+	}
+	if _, err := s.store.CreateInitiativeItem(ctx, &core.InitiativeItem{InitiativeID: initiativeB, WorkItemID: itemB}); err != nil {
+		t.Fatalf("CreateInitiativeItem: %v", err)
+	}
+	proposalB, err := s.store.CreateThreadProposal(ctx, &core.ThreadProposal{ThreadID: threadB, Title: "b proposal", Status: core.ProposalOpen})
+	if err != nil {
+		t.Fatalf("CreateThreadProposal: %v", err)
+	}
+	messageB, err := s.store.CreateThreadMessage(ctx, &core.ThreadMessage{ThreadID: threadB, SenderID: "human", Role: "human", Content: "hi"})
+	if err != nil {
+		t.Fatalf("CreateThreadMessage: %v", err)
+	}
+	resourceB, err := s.store.CreateResource(ctx, &core.Resource{ProjectID: projectB, RunID: &runB, StorageKind: "local", URI: "file:///tmp/b.txt", Role: "output", FileName: "b.txt"})
+	if err != nil {
+		t.Fatalf("CreateResource: %v", err)
+	}
+	artifactB, err := s.store.CreateDeliverable(ctx, &core.Deliverable{Kind: core.DeliverableDocument, ProducerType: core.DeliverableProducerRun, ProducerID: runB, Status: core.DeliverableFinal})
+	if err != nil {
+		t.Fatalf("CreateDeliverable: %v", err)
+	}
+	declB, err := s.store.CreateActionIODecl(ctx, &core.ActionIODecl{ActionID: actionB, Direction: core.IOInput, ResourceID: &resourceB, Path: "in.txt"})
+	if err != nil {
+		t.Fatalf("CreateActionIODecl: %v", err)
+	}
+	entryB, err := s.store.CreateFeatureEntry(ctx, &core.FeatureEntry{ProjectID: projectB, Key: "login", Description: "login", Status: core.FeaturePending})
+	if err != nil {
+		t.Fatalf("CreateFeatureEntry: %v", err)
+	}
+
+	resp := s.do(t, http.MethodPost, "/admin/users", "admin-token", nil, map[string]any{"username": "carol", "password": "correct horse"})
+	var carol core.User
+	if err := decodeJSON(resp, &carol); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	resp = s.do(t, http.MethodPut, "/projects/"+itoa64(projectA)+"/members/"+itoa64(carol.ID), "admin-token", nil, map[string]string{"role": "approver"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set approver: status %d", resp.StatusCode)
+	}
+	cookie := s.login(t, "carol", "correct horse")
+
+	// An approver in A holds every role scope, just not in B.
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodGet, "/initiatives/" + itoa64(initiativeB)},
+		{http.MethodPost, "/initiatives/" + itoa64(initiativeB) + "/approve"},
+		{http.MethodGet, "/proposals/" + itoa64(proposalB)},
+		{http.MethodPost, "/proposals/" + itoa64(proposalB) + "/approve"},
+		{http.MethodGet, "/resources/" + itoa64(resourceB)},
+		{http.MethodGet, "/resources/" + itoa64(resourceB) + "/download"},
+		{http.MethodDelete, "/resources/" + itoa64(resourceB)},
+		{http.MethodGet, "/artifacts/" + itoa64(artifactB)},
+		{http.MethodDelete, "/io-decls/" + itoa64(declB)},
+		{http.MethodGet, "/messages/" + itoa64(messageB) + "/resources"},
+		{http.MethodGet, "/manifest/entries/" + itoa64(entryB)},
+		{http.MethodPut, "/manifest/entries/" + itoa64(entryB)},
+	} {
+		resp = s.do(t, tc.method, tc.path, "", cookie, map[string]any{})
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("%s %s: status %d, want 403", tc.method, tc.path, resp.StatusCode)
+		}
+	}
+
+	// Objects created or moved through the request body are checked too.
+	template := map[string]any{"name": "t", "project_id": projectB, "actions": []map[string]any{{"name": "build", "type": "exec"}}}
+	for _, tc := range []struct {
+		path string
+		body any
+	}{
+		{"/templates", template},
+		{"/threads/" + itoa64(threadB) + "/create-work-item", map[string]any{"title": "x", "project_id": projectB}},
+		{"/notifications", map[string]any{"title": "x", "project_id": projectB}},
+		{"/initiatives/" + itoa64(initiativeB) + "/items", map[string]any{"work_item_id": itemB}},
+	} {
+		resp = s.do(t, http.MethodPost, tc.path, "", cookie, tc.body)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("POST %s: status %d, want 403", tc.path, resp.StatusCode)
+		}
+	}
+	threadA, err := s.store.CreateThread(ctx, &core.Thread{Title: "a thread", Status: core.ThreadActive, FocusProjectID: projectA})
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	for _, tc := range []struct {
+		path string
+		body any
+	}{
+		{"/threads/" + itoa64(threadA) + "/context-refs", map[string]any{"project_id": projectB, "access": "read"}},
+		{"/threads/" + itoa64(threadA) + "/links/work-items", map[string]any{"work_item_id": itemB}},
+		{"/threads/" + itoa64(threadA) + "/proposals", map[string]any{"title": "p", "work_item_drafts": []map[string]any{{"temp_id": "1", "title": "x", "project_id": projectB}}}},
+	} {
+		resp = s.do(t, http.MethodPost, tc.path, "", cookie, tc.body)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("POST %s: status %d, want 403", tc.path, resp.StatusCode)
+		}
+	}
+	resp = s.do(t, http.MethodPost, "/templates", "", cookie, map[string]any{"name": "t", "project_id": projectA, "actions": template["actions"]})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create template in own project: status %d", resp.StatusCode)
+	}
+
+	// A resource whose owner is gone cannot be checked, so it is denied.
+	orphan, err := s.store.CreateActionIODecl(ctx, &core.ActionIODecl{ActionID: actionB + 100, Direction: core.IOInput, ResourceID: &resourceB, Path: "in.txt"})
+	if err != nil {
+		t.Fatalf("CreateActionIODecl: %v", err)
+	}
+	resp = s.do(t, http.MethodDelete, "/io-decls/"+itoa64(orphan), "", cookie, nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("orphaned io decl: status %d, want 403", resp.StatusCode)
+	}
+
+	// A token limited to project A is held to the same boundary.
+	resp = s.do(t, http.MethodPost, "/tokens", "admin-token", nil, map[string]any{"name": "ci", "scopes": []string{"*"}, "projects": []string{"a"}})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create token: status %d", resp.StatusCode)
+	}
+	var token createAPITokenResponse
+	if err := decodeJSON(resp, &token); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, path := range []string{"/artifacts/" + itoa64(artifactB), "/proposals/" + itoa64(proposalB), "/initiatives/" + itoa64(initiativeB)} {
+		resp = s.do(t, http.MethodGet, path, token.Token, nil, nil)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("token GET %s: status %d, want 403", path, resp.StatusCode)
+		}
+	}
+	resp = s.do(t, http.MethodPost, "/notifications", token.Token, nil, map[string]any{"title": "x", "project_id": projectB})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("token notification in B: status %d, want 403", resp.StatusCode)
+	}
+}
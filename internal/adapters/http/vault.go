@@ -0,0 +1,215 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/application/vaultapp"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// WithVault enables the secret vault endpoints.
+func WithVault(svc *vaultapp.Service) HandlerOption {
+	return func(h *Handler) { h.vault = svc }
+}
+
+// setVaultSecretRequest stores a secret. The value travels as "secret" so
+// the request audit log redacts it like any other credential field.
+type setVaultSecretRequest struct {
+	ProjectID   int64  `json:"project_id"`
+	ProfileID   string `json:"profile_id"`
+	Name        string `json:"name"`
+	Secret      string `json:"secret"`
+	Description string `json:"description"`
+}
+
+func registerVaultRoutes(r chi.Router, h *Handler) {
+	r.With(requireTokenScope(core.ScopeSecretsRead)).Get("/projects/{projectID}/secrets", h.listProjectSecrets)
+	r.With(requireTokenScope(core.ScopeSecretsWrite)).Post("/projects/{projectID}/secrets", h.setProjectSecret)
+	r.With(requireTokenScope(core.ScopeSecretsWrite)).Delete("/projects/{projectID}/secrets/{secretID}", h.deleteProjectSecret)
+}
+
+func registerVaultAdminRoutes(r chi.Router, h *Handler) {
+	r.Get("/admin/secrets", h.listVaultSecrets)
+	r.Post("/admin/secrets", h.setVaultSecret)
+	r.Delete("/admin/secrets/{secretID}", h.deleteVaultSecret)
+}
+
+// requireTokenScope checks scope for token requests. Session users are
+// checked against their project role by enforceProjectRoles instead.
+func requireTokenScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if info, ok := httpx.AuthFromContext(r.Context()); ok && info.UserID == 0 && !info.HasScope(scope) {
+				writeForbidden(w, scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (h *Handler) listProjectSecrets(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.vaultProject(w, r)
+	if !ok {
+		return
+	}
+	filter := core.VaultSecretFilter{ProjectID: &projectID}
+	if profile, set := r.URL.Query()["profile_id"]; set {
+		filter.ProfileID = &profile[0]
+	}
+	secrets, err := h.vault.List(r.Context(), filter)
+	if err != nil {
+		writeVaultError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, secrets)
+}
+
+func (h *Handler) setProjectSecret(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.vaultProject(w, r)
+	if !ok {
+		return
+	}
+	var req setVaultSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	req.ProjectID = projectID
+	h.storeVaultSecret(w, r, req)
+}
+
+func (h *Handler) deleteProjectSecret(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.vaultProject(w, r)
+	if !ok {
+		return
+	}
+	h.removeVaultSecret(w, r, &projectID)
+}
+
+// GET /admin/secrets lists secrets of every scope; project_id=0 selects
+// global secrets.
+func (h *Handler) listVaultSecrets(w http.ResponseWriter, r *http.Request) {
+	if !h.vaultAvailable(w) {
+		return
+	}
+	var filter core.VaultSecretFilter
+	q := r.URL.Query()
+	if raw := strings.TrimSpace(q.Get("project_id")); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 0 {
+			writeError(w, http.StatusBadRequest, "invalid project_id", "BAD_QUERY")
+			return
+		}
+		filter.ProjectID = &id
+	}
+	if profile, set := q["profile_id"]; set {
+		filter.ProfileID = &profile[0]
+	}
+	filter.Name = strings.TrimSpace(q.Get("name"))
+	secrets, err := h.vault.List(r.Context(), filter)
+	if err != nil {
+		writeVaultError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, secrets)
+}
+
+func (h *Handler) setVaultSecret(w http.ResponseWriter, r *http.Request) {
+	if !h.vaultAvailable(w) {
+		return
+	}
+	var req setVaultSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
+		return
+	}
+	h.storeVaultSecret(w, r, req)
+}
+
+func (h *Handler) deleteVaultSecret(w http.ResponseWriter, r *http.Request) {
+	if !h.vaultAvailable(w) {
+		return
+	}
+	h.removeVaultSecret(w, r, nil)
+}
+
+func (h *Handler) storeVaultSecret(w http.ResponseWriter, r *http.Request, req setVaultSecretRequest) {
+	secret, err := h.vault.Set(r.Context(), vaultapp.SetInput{
+		ProjectID:   req.ProjectID,
+		ProfileID:   req.ProfileID,
+		Name:        req.Name,
+		Value:       req.Secret,
+		Description: req.Description,
+		CreatedBy:   tokenIssuer(r),
+	})
+	if err != nil {
+		writeVaultError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, secret)
+}
+
+// removeVaultSecret deletes the secret in the URL, which must belong to
+// projectID when set.
+func (h *Handler) removeVaultSecret(w http.ResponseWriter, r *http.Request, projectID *int64) {
+	id, ok := urlParamInt64(r, "secretID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid secret ID", "BAD_ID")
+		return
+	}
+	secret, err := h.vault.Get(r.Context(), id)
+	if err == nil && projectID != nil && secret.ProjectID != *projectID {
+		err = core.ErrNotFound
+	}
+	if err == nil {
+		err = h.vault.Delete(r.Context(), id)
+	}
+	if err != nil {
+		writeVaultError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// vaultProject resolves the project of a project secrets route.
+func (h *Handler) vaultProject(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if !h.vaultAvailable(w) {
+		return 0, false
+	}
+	projectID, ok := urlParamInt64(r, "projectID")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid project ID", "BAD_ID")
+		return 0, false
+	}
+	if _, err := h.store.GetProject(r.Context(), projectID); err != nil {
+		writeVaultError(w, err)
+		return 0, false
+	}
+	return projectID, true
+}
+
+func (h *Handler) vaultAvailable(w http.ResponseWriter) bool {
+	if h.vault == nil {
+		writeError(w, http.StatusServiceUnavailable, "secret vault is not configured", "VAULT_UNAVAILABLE")
+		return false
+	}
+	return true
+}
+
+func writeVaultError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, core.ErrNotFound):
+		writeError(w, http.StatusNotFound, "not found", "NOT_FOUND")
+	case errors.Is(err, vaultapp.ErrInvalidInput):
+		writeError(w, http.StatusBadRequest, err.Error(), "INVALID_SECRET")
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error(), "VAULT_ERROR")
+	}
+}
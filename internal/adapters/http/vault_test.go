@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/application/vaultapp"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestVaultSecretEndpoints(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "vault.key")
+	var vault *vaultapp.Service
+	s := newSessionTestServer(t, func(h *Handler) {
+		vault = vaultapp.New(vaultapp.Config{Store: h.store.(*sqlite.Store), KeyFile: keyFile})
+		WithVault(vault)(h)
+	})
+	ctx := context.Background()
+	projectID, err := s.store.CreateProject(ctx, &core.Project{Name: "backend", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	base := "/projects/" + itoa64(projectID) + "/secrets"
+
+	resp := s.do(t, http.MethodPost, base, "admin-token", nil, map[string]any{
+		"name": "NPM_TOKEN", "secret": "npm-s3cret", "profile_id": "worker", "description": "publish",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set secret: status %d", resp.StatusCode)
+	}
+	var secret core.VaultSecret
+	if err := decodeJSON(resp, &secret); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if secret.ProjectID != projectID || secret.ProfileID != "worker" || secret.CreatedBy != "admin" {
+		t.Fatalf("secret = %+v", secret)
+	}
+	resp = s.do(t, http.MethodPost, "/admin/secrets", "admin-token", nil, map[string]any{"name": "SHARED", "secret": "shared-value"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("set global secret: status %d", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodPost, base, "admin-token", nil, map[string]any{"name": "bad name", "secret": "x"})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid name: status %d, want 400", resp.StatusCode)
+	}
+
+	// Listings never carry values; the audit log redacts them.
+	resp = s.do(t, http.MethodGet, base, "admin-token", nil, nil)
+	var listed []map[string]any
+	if err := decodeJSON(resp, &listed); err != nil || len(listed) != 1 || listed[0]["name"] != "NPM_TOKEN" {
+		t.Fatalf("list = %v, %v", listed, err)
+	}
+	for _, v := range listed[0] {
+		if str, _ := v.(string); strings.Contains(str, "npm-s3cret") {
+			t.Fatalf("listing leaks the value: %v", listed[0])
+		}
+	}
+	resp = s.do(t, http.MethodGet, "/admin/audit-log?route="+url.QueryEscape("/api/projects/{projectID}/secrets"), "admin-token", nil, nil)
+	var entries []*core.AuditLogEntry
+	if err := decodeJSON(resp, &entries); err != nil || len(entries) == 0 || entries[len(entries)-1].Changes["secret"] != "[REDACTED]" {
+		t.Fatalf("audit entries = %+v, %v", entries, err)
+	}
+
+	got, err := vault.Resolve(ctx, projectID, "worker")
+	if err != nil || got["NPM_TOKEN"] != "npm-s3cret" || got["SHARED"] != "shared-value" {
+		t.Fatalf("Resolve = %v, %v", got, err)
+	}
+
+	// Project members need the admin role to manage secrets.
+	resp = s.do(t, http.MethodPost, "/admin/users", "admin-token", nil, map[string]any{"username": "olivia", "password": "correct horse"})
+	var op core.User
+	if err := decodeJSON(resp, &op); err != nil {
+		t.Fatalf("decode user: %v", err)
+	}
+	if err := s.store.UpsertProjectMember(ctx, &core.ProjectMember{ProjectID: projectID, UserID: op.ID, Role: core.ProjectRoleOperator}); err != nil {
+		t.Fatalf("UpsertProjectMember: %v", err)
+	}
+	cookie := s.login(t, "olivia", "correct horse")
+	if resp := s.do(t, http.MethodGet, base, "", cookie, nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("operator list: status %d, want 403", resp.StatusCode)
+	}
+
+	resp = s.do(t, http.MethodDelete, "/projects/999/secrets/"+itoa64(secret.ID), "admin-token", nil, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("delete via other project: status %d, want 404", resp.StatusCode)
+	}
+	resp = s.do(t, http.MethodDelete, base+"/"+itoa64(secret.ID), "admin-token", nil, nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("delete: status %d", resp.StatusCode)
+	}
+}
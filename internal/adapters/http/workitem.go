@@ -56,6 +56,9 @@ func (h *Handler) createWorkItem(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionProject(w, r, req.ProjectID, "issues:write") {
+		return
+	}
 	workItem, err := h.workItemService().CreateWorkItem(r.Context(), workitemapp.CreateWorkItemInput{
 		ProjectID:          req.ProjectID,
 		ResourceSpaceID:    req.ResourceSpaceID,
@@ -101,6 +104,7 @@ func (h *Handler) getWorkItem(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	setETag(w, workItem.Version)
 	writeJSON(w, http.StatusOK, workItem)
 }
 
@@ -142,10 +146,19 @@ func (h *Handler) listWorkItems(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
-	if workItems == nil {
-		workItems = []*core.WorkItem{}
+	allowed, restricted, err := h.sessionProjectFilter(r, "issues:read")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
+		return
+	}
+	visible := make([]*core.WorkItem, 0, len(workItems))
+	for _, item := range workItems {
+		if restricted && !projectVisible(allowed, item.ProjectID) {
+			continue
+		}
+		visible = append(visible, item)
 	}
-	writeJSON(w, http.StatusOK, workItems)
+	writeJSON(w, http.StatusOK, visible)
 }
 
 func (h *Handler) updateWorkItem(w http.ResponseWriter, r *http.Request) {
@@ -154,12 +167,19 @@ func (h *Handler) updateWorkItem(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid work item ID", "BAD_ID")
 		return
 	}
+	expectedVersion, ok := readIfMatch(w, r)
+	if !ok {
+		return
+	}
 
 	var req updateWorkItemRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid JSON body", "BAD_REQUEST")
 		return
 	}
+	if !h.authorizeSessionProject(w, r, req.ProjectID, "issues:write") {
+		return
+	}
 
 	updated, err := h.workItemService().UpdateWorkItem(r.Context(), workitemapp.UpdateWorkItemInput{
 		ID:                 id,
@@ -181,14 +201,16 @@ func (h *Handler) updateWorkItem(w http.ResponseWriter, r *http.Request) {
 		DependsOn:          req.DependsOn,
 		EscalationPath:     req.EscalationPath,
 		Metadata:           req.Metadata,
+		ExpectedVersion:    expectedVersion,
 	})
 	if err != nil {
-		if writeWorkItemAppError(w, err) {
+		if writeVersionConflict(w, r, err) || writeWorkItemAppError(w, err) {
 			return
 		}
 		writeError(w, http.StatusInternalServerError, err.Error(), "STORE_ERROR")
 		return
 	}
+	setETag(w, updated.Version)
 	writeJSON(w, http.StatusOK, updated)
 }
 
@@ -246,7 +268,7 @@ func (h *Handler) runWorkItem(w http.ResponseWriter, r *http.Request) {
 
 	result, err := h.workItemService().RunWorkItem(r.Context(), id)
 	if err != nil {
-		if writeWorkItemAppError(w, err) {
+		if writeVersionConflict(w, r, err) || writeWorkItemAppError(w, err) {
 			return
 		}
 		writeError(w, http.StatusInternalServerError, err.Error(), "SCHEDULER_ERROR")
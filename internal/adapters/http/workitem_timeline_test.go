@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestAPI_WorkItemTimeline(t *testing.T) {
+	_, ts := setupAPI(t)
+
+	resp, err := post(ts, "/work-items", map[string]any{"title": "timeline", "priority": "low"})
+	if err != nil {
+		t.Fatalf("create work item: %v", err)
+	}
+	var created core.WorkItem
+	if err := decodeJSON(resp, &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	base := "/work-items/" + itoa64(created.ID)
+
+	resp, err = post(ts, base+"/actions", map[string]any{"name": "impl", "type": "exec", "agent_role": "worker"})
+	if err != nil {
+		t.Fatalf("create action: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 creating action, got %d", resp.StatusCode)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	mid := time.Now().UTC()
+	time.Sleep(10 * time.Millisecond)
+
+	resp = putIfMatch(t, ts.URL+base, "", map[string]any{"priority": "high"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 updating work item, got %d", resp.StatusCode)
+	}
+
+	resp, err = get(ts, base+"/timeline?at="+url.QueryEscape(mid.Format(time.RFC3339Nano)))
+	if err != nil {
+		t.Fatalf("get timeline: %v", err)
+	}
+	var past core.WorkItemSnapshot
+	if err := decodeJSON(resp, &past); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+	if !past.Exists || !past.Complete {
+		t.Fatalf("expected complete snapshot, got exists=%v complete=%v", past.Exists, past.Complete)
+	}
+	if past.State.Priority != core.PriorityLow || past.State.Title != "timeline" {
+		t.Fatalf("unexpected past state: %+v", past.State)
+	}
+	if len(past.Actions) != 1 || past.Actions[0].State.Name != "impl" {
+		t.Fatalf("expected one action in snapshot, got %+v", past.Actions)
+	}
+
+	resp, err = get(ts, base+"/timeline")
+	if err != nil {
+		t.Fatalf("get timeline: %v", err)
+	}
+	var now core.WorkItemSnapshot
+	if err := decodeJSON(resp, &now); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+	if now.State.Priority != core.PriorityHigh {
+		t.Fatalf("expected current priority high, got %q", now.State.Priority)
+	}
+
+	resp, err = get(ts, base+"/timeline?from="+url.QueryEscape(mid.Format(time.RFC3339Nano)))
+	if err != nil {
+		t.Fatalf("get timeline diff: %v", err)
+	}
+	var diff struct {
+		Changes []core.TimelineChange `json:"changes"`
+		Entries []*core.JournalEntry  `json:"entries"`
+	}
+	if err := decodeJSON(resp, &diff); err != nil {
+		t.Fatalf("decode diff: %v", err)
+	}
+	if len(diff.Changes) != 1 || diff.Changes[0].Field != "priority" || diff.Changes[0].To != "high" {
+		t.Fatalf("expected a single priority change, got %+v", diff.Changes)
+	}
+	if len(diff.Entries) != 1 || diff.Entries[0].Kind != core.JournalStateChange {
+		t.Fatalf("expected one state_change entry in range, got %+v", diff.Entries)
+	}
+
+	resp, err = get(ts, base+"/timeline?at=yesterday")
+	if err != nil {
+		t.Fatalf("get timeline: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad timestamp, got %d", resp.StatusCode)
+	}
+
+	resp, err = get(ts, "/work-items/999999/timeline")
+	if err != nil {
+		t.Fatalf("get timeline: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for missing work item, got %d", resp.StatusCode)
+	}
+}
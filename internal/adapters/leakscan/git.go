@@ -0,0 +1,73 @@
+package leakscan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// ScanStaged scans what `git add` staged in dir, before it is committed.
+func (s *Scanner) ScanStaged(ctx context.Context, dir string) ([]core.LeakFinding, error) {
+	if s == nil {
+		return nil, nil
+	}
+	diff, err := gitDiff(ctx, dir, "--cached")
+	if err != nil {
+		return nil, err
+	}
+	return s.ScanDiff(diff), nil
+}
+
+// emptyTree is the id of git's empty tree object.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// ScanRange scans the changes head brings on top of its merge base with
+// base, i.e. what a change request from head into base publishes. When base
+// does not resolve it scans head's whole tree instead, so a missing base
+// never lets a change request through unscanned.
+func (s *Scanner) ScanRange(ctx context.Context, dir, base, head string) ([]core.LeakFinding, error) {
+	if s == nil {
+		return nil, nil
+	}
+	for _, ref := range []string{"origin/" + base, base} {
+		if _, err := gitOutput(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+			continue
+		}
+		diff, err := gitDiff(ctx, dir, ref+"..."+head)
+		if err != nil {
+			return nil, err
+		}
+		return s.ScanDiff(diff), nil
+	}
+	diff, err := gitDiff(ctx, dir, emptyTree, head)
+	if err != nil {
+		return nil, fmt.Errorf("base %q does not resolve: %w", base, err)
+	}
+	return s.ScanDiff(diff), nil
+}
+
+func gitDiff(ctx context.Context, dir string, args ...string) (string, error) {
+	return gitOutput(ctx, dir, append([]string{
+		"-c", "core.quotePath=false",
+		"diff", "--no-color", "--no-ext-diff", "-U0",
+	}, args...)...)
+}
+
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,141 @@
+package leakscan
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+// ProjectLookup resolves the project of a work item, so the per-project
+// settings apply to builtin actions and run results.
+type ProjectLookup interface {
+	GetWorkItem(ctx context.Context, id int64) (*core.WorkItem, error)
+	GetProject(ctx context.Context, id int64) (*core.Project, error)
+}
+
+// Policy holds the global scan settings and the per-project overrides, and
+// hands out the Scanner that applies to a project. A nil *Policy scans
+// nothing.
+type Policy struct {
+	lookup ProjectLookup
+	// scanners is keyed by project name; "" holds the global scanner. A nil
+	// entry means scanning is disabled.
+	scanners    map[string]*Scanner
+	hasProjects bool
+}
+
+// NewPolicy compiles cfg. lookup may be nil, in which case ForWorkItem only
+// applies the global settings.
+func NewPolicy(cfg config.LeakScanConfig, lookup ProjectLookup, extra ...Detector) (*Policy, error) {
+	allow, err := compilePatterns(cfg.AllowPatterns)
+	if err != nil {
+		return nil, err
+	}
+	base := Options{
+		EntropyThreshold: cfg.EntropyThreshold,
+		EntropyMinLength: cfg.EntropyMinLength,
+		BlockedPaths:     cfg.BlockedPaths,
+		AllowPaths:       cfg.AllowPaths,
+		AllowPatterns:    allow,
+	}
+	for _, rc := range cfg.Rules {
+		re, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("leak scan rule %q: %w", rc.ID, err)
+		}
+		description := strings.TrimSpace(rc.Description)
+		if description == "" {
+			description = rc.ID
+		}
+		base.Rules = append(base.Rules, Rule{ID: rc.ID, Description: description, Pattern: re})
+	}
+
+	p := &Policy{
+		lookup:      lookup,
+		scanners:    make(map[string]*Scanner, len(cfg.Projects)+1),
+		hasProjects: len(cfg.Projects) > 0,
+	}
+	if cfg.Enabled {
+		p.scanners[""] = NewScanner(base, extra...)
+	}
+	for _, pc := range cfg.Projects {
+		name := strings.TrimSpace(pc.Project)
+		enabled := cfg.Enabled
+		if pc.Enabled != nil {
+			enabled = *pc.Enabled
+		}
+		if !enabled {
+			p.scanners[name] = nil
+			continue
+		}
+		opts := base
+		if pc.EntropyThreshold != nil {
+			opts.EntropyThreshold = *pc.EntropyThreshold
+		}
+		opts.AllowPaths = append(append([]string(nil), base.AllowPaths...), pc.AllowPaths...)
+		projectAllow, err := compilePatterns(pc.AllowPatterns)
+		if err != nil {
+			return nil, fmt.Errorf("leak scan project %q: %w", name, err)
+		}
+		opts.AllowPatterns = append(append([]*regexp.Regexp(nil), base.AllowPatterns...), projectAllow...)
+		p.scanners[name] = NewScanner(opts, extra...)
+	}
+	return p, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("leak scan allow pattern %q: %w", pattern, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// ForProject returns the scanner for a project name, or nil when scanning is
+// disabled for it. An empty or unknown name gets the global settings.
+func (p *Policy) ForProject(name string) *Scanner {
+	if p == nil {
+		return nil
+	}
+	if s, ok := p.scanners[strings.TrimSpace(name)]; ok {
+		return s
+	}
+	return p.scanners[""]
+}
+
+// ForWorkItem returns the scanner for the project of a work item.
+func (p *Policy) ForWorkItem(ctx context.Context, workItemID int64) *Scanner {
+	if p == nil {
+		return nil
+	}
+	return p.ForProject(p.projectName(ctx, workItemID))
+}
+
+// ScrubText scans text produced for a work item and returns it redacted.
+// It satisfies the flow engine's result scanner port.
+func (p *Policy) ScrubText(ctx context.Context, workItemID int64, name, text string) (string, []core.LeakFinding) {
+	return p.ForWorkItem(ctx, workItemID).ScrubText(name, text)
+}
+
+func (p *Policy) projectName(ctx context.Context, workItemID int64) string {
+	if p.lookup == nil || workItemID <= 0 || !p.hasProjects {
+		return ""
+	}
+	workItem, err := p.lookup.GetWorkItem(ctx, workItemID)
+	if err != nil || workItem == nil || workItem.ProjectID == nil {
+		return ""
+	}
+	project, err := p.lookup.GetProject(ctx, *workItem.ProjectID)
+	if err != nil || project == nil {
+		return ""
+	}
+	return project.Name
+}
@@ -0,0 +1,357 @@
+// Package leakscan finds credentials in staged diffs and agent output before
+// they leave the machine: regex rules for well-known token formats, a Shannon
+// entropy rule for random-looking strings, blocked file names such as .env,
+// and allow lists to clear known-safe paths and values.
+package leakscan
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// Match is one candidate secret a Detector found on a line.
+type Match struct {
+	Rule        string
+	Description string
+	Secret      string
+}
+
+// Detector inspects a single line of text. Implementations are plugged into
+// a Scanner next to the built-in regex and entropy rules.
+type Detector interface {
+	Detect(line string) []Match
+}
+
+// Rule is a regex Detector. When Pattern has a capture group, the first group
+// is the secret; otherwise the whole match is.
+type Rule struct {
+	ID          string
+	Description string
+	Pattern     *regexp.Regexp
+}
+
+// Detect implements Detector.
+func (r Rule) Detect(line string) []Match {
+	if r.Pattern == nil {
+		return nil
+	}
+	var out []Match
+	for _, m := range r.Pattern.FindAllStringSubmatch(line, -1) {
+		secret := m[0]
+		if len(m) > 1 && m[1] != "" {
+			secret = m[1]
+		}
+		out = append(out, Match{Rule: r.ID, Description: r.Description, Secret: secret})
+	}
+	return out
+}
+
+// BuiltinRules are the regex rules every scanner starts with.
+var BuiltinRules = []Rule{
+	{ID: "private-key", Description: "Private key block", Pattern: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----`)},
+	{ID: "aws-access-key-id", Description: "AWS access key ID", Pattern: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{ID: "github-token", Description: "GitHub token", Pattern: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})\b`)},
+	{ID: "gitlab-token", Description: "GitLab personal access token", Pattern: regexp.MustCompile(`\b(glpat-[A-Za-z0-9_\-]{20,})\b`)},
+	{ID: "slack-token", Description: "Slack token", Pattern: regexp.MustCompile(`\b(xox[abprs]-[A-Za-z0-9-]{10,})\b`)},
+	{ID: "google-api-key", Description: "Google API key", Pattern: regexp.MustCompile(`\b(AIza[0-9A-Za-z_\-]{35})\b`)},
+	{ID: "stripe-secret-key", Description: "Stripe secret key", Pattern: regexp.MustCompile(`\b((?:sk|rk)_live_[0-9A-Za-z]{24,})\b`)},
+	{ID: "llm-api-key", Description: "OpenAI or Anthropic API key", Pattern: regexp.MustCompile(`\b(sk-(?:ant-|proj-)?[A-Za-z0-9_\-]{32,})\b`)},
+	{ID: "credential-assignment", Description: "Hard-coded credential", Pattern: regexp.MustCompile(`(?i)\b(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|client[_-]?secret)["']?\s*[:=]\s*["']([^"'\s$]{8,})["']`)},
+}
+
+// EntropyDetector flags tokens of at least MinLength characters whose Shannon
+// entropy, in bits per character, reaches Threshold. Tokens need both letters
+// and digits, which keeps identifiers and prose out.
+type EntropyDetector struct {
+	Threshold float64
+	MinLength int
+}
+
+var entropyTokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_\-=]+`)
+
+// Detect implements Detector.
+func (d EntropyDetector) Detect(line string) []Match {
+	if d.Threshold <= 0 || d.MinLength <= 0 {
+		return nil
+	}
+	var out []Match
+	for _, token := range entropyTokenPattern.FindAllString(line, -1) {
+		token = strings.TrimRight(token, "=")
+		if len(token) < d.MinLength || !strings.ContainsAny(token, "0123456789") || !strings.ContainsFunc(token, isLetter) {
+			continue
+		}
+		if shannonEntropy(token) >= d.Threshold {
+			out = append(out, Match{Rule: "high-entropy-string", Description: "High-entropy string", Secret: token})
+		}
+	}
+	return out
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len(s))
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// Options configures a Scanner.
+type Options struct {
+	Rules            []Rule
+	EntropyThreshold float64
+	EntropyMinLength int
+	BlockedPaths     []string
+	AllowPaths       []string
+	AllowPatterns    []*regexp.Regexp
+}
+
+// Scanner runs detectors over diffs and text. A nil *Scanner finds nothing.
+type Scanner struct {
+	detectors     []Detector
+	blockedPaths  []string
+	allowPaths    []string
+	allowPatterns []*regexp.Regexp
+}
+
+// NewScanner builds a scanner from the built-in rules, opts and any extra
+// detectors.
+func NewScanner(opts Options, extra ...Detector) *Scanner {
+	s := &Scanner{
+		blockedPaths:  opts.BlockedPaths,
+		allowPaths:    opts.AllowPaths,
+		allowPatterns: opts.AllowPatterns,
+	}
+	for _, rule := range BuiltinRules {
+		s.detectors = append(s.detectors, rule)
+	}
+	for _, rule := range opts.Rules {
+		s.detectors = append(s.detectors, rule)
+	}
+	s.detectors = append(s.detectors, EntropyDetector{Threshold: opts.EntropyThreshold, MinLength: opts.EntropyMinLength})
+	s.detectors = append(s.detectors, extra...)
+	return s
+}
+
+// ScanDiff scans the added lines of a unified diff, as produced by
+// `git diff --cached -U0`, and flags added files on the blocked path list.
+func (s *Scanner) ScanDiff(diff string) []core.LeakFinding {
+	if s == nil {
+		return nil
+	}
+	var (
+		findings []core.LeakFinding
+		file     string
+		deleted  bool
+		inHunk   bool
+		line     int
+	)
+	flushFile := func() {
+		if file != "" && !deleted && !s.pathAllowed(file) && s.pathBlocked(file) {
+			findings = append(findings, core.LeakFinding{
+				Rule:        "blocked-path",
+				Description: "File must not be committed",
+				File:        file,
+			})
+		}
+	}
+	for _, raw := range strings.Split(diff, "\n") {
+		raw = strings.TrimSuffix(raw, "\r")
+		switch {
+		case strings.HasPrefix(raw, "diff --git "):
+			flushFile()
+			file, deleted, inHunk = diffGitPath(raw), false, false
+		case !inHunk && strings.HasPrefix(raw, "deleted file mode"):
+			deleted = true
+		case !inHunk && strings.HasPrefix(raw, "+++ "):
+			if p := strings.TrimPrefix(raw, "+++ "); p != "/dev/null" {
+				file = strings.TrimPrefix(p, "b/")
+			}
+		case strings.HasPrefix(raw, "@@"):
+			inHunk = true
+			line = hunkStartLine(raw)
+		case inHunk && strings.HasPrefix(raw, "+"):
+			if !s.pathAllowed(file) {
+				findings = append(findings, s.scanLine(file, line, raw[1:])...)
+			}
+			line++
+		case inHunk && strings.HasPrefix(raw, " "):
+			line++
+		}
+	}
+	flushFile()
+	return findings
+}
+
+// ScanText scans free text, such as a run result, reported under name.
+func (s *Scanner) ScanText(name, text string) []core.LeakFinding {
+	_, findings := s.ScrubText(name, text)
+	return findings
+}
+
+// ScrubText scans text and returns it with every finding replaced by
+// "[REDACTED]".
+func (s *Scanner) ScrubText(name, text string) (string, []core.LeakFinding) {
+	if s == nil || text == "" {
+		return text, nil
+	}
+	var findings []core.LeakFinding
+	var secrets []string
+	for i, line := range strings.Split(text, "\n") {
+		for _, m := range s.detectLine(line) {
+			findings = append(findings, newFinding(m, name, i+1))
+			secrets = append(secrets, m.Secret)
+		}
+	}
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, "[REDACTED]")
+	}
+	return text, findings
+}
+
+func (s *Scanner) scanLine(file string, line int, text string) []core.LeakFinding {
+	var out []core.LeakFinding
+	for _, m := range s.detectLine(text) {
+		out = append(out, newFinding(m, file, line))
+	}
+	return out
+}
+
+// detectLine runs every detector and drops allow-listed values and matches
+// already covered by an earlier, more specific rule.
+func (s *Scanner) detectLine(line string) []Match {
+	var out []Match
+	for _, d := range s.detectors {
+		for _, m := range d.Detect(line) {
+			if strings.TrimSpace(m.Secret) == "" || s.valueAllowed(m.Secret) || overlaps(out, m.Secret) {
+				continue
+			}
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func overlaps(found []Match, secret string) bool {
+	for _, m := range found {
+		if strings.Contains(m.Secret, secret) || strings.Contains(secret, m.Secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) valueAllowed(secret string) bool {
+	for _, re := range s.allowPatterns {
+		if re.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) pathAllowed(file string) bool {
+	return matchAnyPath(s.allowPaths, file)
+}
+
+func (s *Scanner) pathBlocked(file string) bool {
+	return matchAnyPath(s.blockedPaths, file)
+}
+
+// matchAnyPath matches slash-separated globs. A pattern without a slash
+// matches the base name at any depth; "dir/**" matches everything below dir.
+func matchAnyPath(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if file == prefix || strings.HasPrefix(file, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		target := file
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(file)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func diffGitPath(header string) string {
+	rest := strings.TrimPrefix(header, "diff --git ")
+	if i := strings.LastIndex(rest, " b/"); i >= 0 {
+		return rest[i+3:]
+	}
+	return ""
+}
+
+// hunkStartLine reads the new-file start line of "@@ -a,b +c,d @@".
+func hunkStartLine(header string) int {
+	i := strings.Index(header, "+")
+	if i < 0 {
+		return 0
+	}
+	rest := header[i+1:]
+	if j := strings.IndexAny(rest, ", "); j >= 0 {
+		rest = rest[:j]
+	}
+	n, _ := strconv.Atoi(rest)
+	return n
+}
+
+func newFinding(m Match, file string, line int) core.LeakFinding {
+	return core.LeakFinding{
+		Rule:        m.Rule,
+		Description: m.Description,
+		File:        file,
+		Line:        line,
+		Preview:     maskSecret(m.Secret),
+	}
+}
+
+// maskSecret keeps just enough of a secret to recognize its kind.
+func maskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return secret[:4] + "****"
+}
+
+// Summary describes findings in one line per finding, for error messages and
+// need_help reasons.
+func Summary(findings []core.LeakFinding) string {
+	lines := make([]string, 0, len(findings))
+	for _, f := range findings {
+		where := f.File
+		if f.Line > 0 {
+			where = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		entry := fmt.Sprintf("- %s: %s (%s)", where, f.Description, f.Rule)
+		if f.Preview != "" {
+			entry += " " + f.Preview
+		}
+		lines = append(lines, entry)
+	}
+	return strings.Join(lines, "\n")
+}
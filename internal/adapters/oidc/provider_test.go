@@ -0,0 +1,117 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/oidc/oidctest"
+	"golang.org/x/oauth2"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	t.Helper()
+	idp := oidctest.NewServer("zhanggui", "s3cret")
+	t.Cleanup(idp.Close)
+	p, err := New(Config{
+		Issuer:       idp.URL,
+		ClientID:     "zhanggui",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://console.test/api/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return p, idp
+}
+
+// authorize follows the provider login URL and returns the code it redirects
+// back with.
+func authorize(t *testing.T, loginURL, wantState string) string {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(loginURL)
+	if err != nil {
+		t.Fatalf("GET authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d", resp.StatusCode)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("parse redirect: %v", err)
+	}
+	if got := loc.Query().Get("state"); got != wantState {
+		t.Fatalf("state = %q, want %q", got, wantState)
+	}
+	return loc.Query().Get("code")
+}
+
+func TestProvider_CodeFlowWithPKCE(t *testing.T) {
+	p, idp := newTestProvider(t)
+	idp.SetUser(map[string]any{
+		"sub":                "u-123",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"groups":             []string{"eng", "ops"},
+	})
+	ctx := context.Background()
+
+	verifier := oauth2.GenerateVerifier()
+	loginURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.Contains(loginURL, "code_challenge_method=S256") {
+		t.Fatalf("login URL lacks PKCE challenge: %s", loginURL)
+	}
+	code := authorize(t, loginURL, "state-1")
+
+	if _, err := p.Exchange(ctx, code, oauth2.GenerateVerifier(), "nonce-1"); err == nil {
+		t.Fatalf("Exchange with wrong verifier succeeded")
+	}
+
+	code = authorize(t, loginURL, "state-1")
+	claims, err := p.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != "u-123" || claims.PreferredUsername != "alice" || claims.Email != "alice@example.com" {
+		t.Fatalf("claims = %+v", claims)
+	}
+	if groups := claims.Strings("groups"); len(groups) != 2 || groups[0] != "eng" {
+		t.Fatalf("groups = %v", groups)
+	}
+}
+
+func TestProvider_VerifyRejectsBadTokens(t *testing.T) {
+	p, idp := newTestProvider(t)
+	ctx := context.Background()
+
+	valid := idp.SignIDToken(map[string]any{"sub": "u-1", "nonce": "n"})
+	if _, err := p.Verify(ctx, valid, "n"); err != nil {
+		t.Fatalf("Verify(valid): %v", err)
+	}
+
+	cases := map[string]string{
+		"wrong nonce":    valid,
+		"wrong audience": idp.SignIDToken(map[string]any{"sub": "u-1", "nonce": "n", "aud": "other"}),
+		"wrong issuer":   idp.SignIDToken(map[string]any{"sub": "u-1", "nonce": "n", "iss": "https://evil.test"}),
+		"expired":        idp.SignIDToken(map[string]any{"sub": "u-1", "nonce": "n", "exp": time.Now().Add(-time.Hour).Unix()}),
+		"no subject":     idp.SignIDToken(map[string]any{"nonce": "n"}),
+		"tampered":       valid[:strings.LastIndex(valid, ".")] + ".AAAA",
+	}
+	for name, token := range cases {
+		nonce := "n"
+		if name == "wrong nonce" {
+			nonce = "other"
+		}
+		if _, err := p.Verify(ctx, token, nonce); err == nil {
+			t.Errorf("%s: Verify succeeded", name)
+		}
+	}
+}
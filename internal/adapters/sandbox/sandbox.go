@@ -35,6 +35,10 @@ type PrepareInput struct {
 	// These directories are linked directly into the agent's skills dir,
 	// bypassing the global skillsRoot. Used for per-execution materials.
 	EphemeralSkills map[string]string
+
+	// WorkItemID selects the project whose vault secrets may be injected;
+	// 0 limits injection to global secrets.
+	WorkItemID int64
 }
 
 // NoopSandbox leaves launch config unchanged.
@@ -0,0 +1,64 @@
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// VaultSandbox expands "${vault.NAME}" references in the launch environment
+// with secrets from the vault before delegating to Base, so that driver env
+// and action config can name a credential without holding its value.
+type VaultSandbox struct {
+	Base    Sandbox
+	Secrets core.SecretResolver
+}
+
+func (s VaultSandbox) Prepare(ctx context.Context, in PrepareInput) (acpclient.LaunchConfig, error) {
+	base := s.Base
+	if base == nil {
+		base = NoopSandbox{}
+	}
+	env, err := s.expandEnv(ctx, in)
+	if err != nil {
+		return acpclient.LaunchConfig{}, err
+	}
+	in.Launch.Env = env
+	return base.Prepare(ctx, in)
+}
+
+func (s VaultSandbox) expandEnv(ctx context.Context, in PrepareInput) (map[string]string, error) {
+	keys := make([]string, 0, len(in.Launch.Env))
+	for k, v := range in.Launch.Env {
+		if core.HasVaultRefs(v) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return in.Launch.Env, nil
+	}
+	if s.Secrets == nil {
+		return nil, fmt.Errorf("env %s references the vault, which is not configured", keys[0])
+	}
+	profileID := ""
+	if in.Profile != nil {
+		profileID = in.Profile.ID
+	}
+	secrets, err := s.Secrets.ResolveSecrets(ctx, in.WorkItemID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve vault secrets: %w", err)
+	}
+	sort.Strings(keys)
+	env := acpclient.CloneEnv(in.Launch.Env)
+	for _, k := range keys {
+		expanded, err := core.ExpandVaultRefs(env[k], secrets)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", k, err)
+		}
+		env[k] = expanded
+	}
+	return env, nil
+}
@@ -0,0 +1,68 @@
+package sandbox
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type stubSecretResolver struct {
+	secrets    map[string]string
+	workItemID int64
+	profileID  string
+	calls      int
+}
+
+func (r *stubSecretResolver) ResolveSecrets(_ context.Context, workItemID int64, profileID string) (map[string]string, error) {
+	r.calls++
+	r.workItemID, r.profileID = workItemID, profileID
+	return r.secrets, nil
+}
+
+func TestVaultSandboxExpandsEnv(t *testing.T) {
+	resolver := &stubSecretResolver{secrets: map[string]string{"NPM_TOKEN": "npm-123", "DB_PASS": "hunter22"}}
+	sb := VaultSandbox{Base: NoopSandbox{}, Secrets: resolver}
+	env := map[string]string{
+		"NPM_TOKEN":    "${vault.NPM_TOKEN}",
+		"DATABASE_URL": "postgres://app:${vault.DB_PASS}@db/app",
+		"PLAIN":        "$HOME/${vault}",
+	}
+	launch, err := sb.Prepare(context.Background(), PrepareInput{
+		Profile:    &core.AgentProfile{ID: "worker"},
+		Launch:     acpclient.LaunchConfig{Command: "agent", Env: env},
+		WorkItemID: 7,
+	})
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if launch.Env["NPM_TOKEN"] != "npm-123" || launch.Env["DATABASE_URL"] != "postgres://app:hunter22@db/app" || launch.Env["PLAIN"] != "$HOME/${vault}" {
+		t.Fatalf("env = %v", launch.Env)
+	}
+	if resolver.workItemID != 7 || resolver.profileID != "worker" {
+		t.Fatalf("resolved for work item %d profile %q", resolver.workItemID, resolver.profileID)
+	}
+	if env["NPM_TOKEN"] != "${vault.NPM_TOKEN}" {
+		t.Fatalf("input env was modified: %v", env)
+	}
+
+	// No references, no vault lookup.
+	if _, err := sb.Prepare(context.Background(), PrepareInput{Launch: acpclient.LaunchConfig{Env: map[string]string{"A": "b"}}}); err != nil || resolver.calls != 1 {
+		t.Fatalf("Prepare without refs: calls=%d, %v", resolver.calls, err)
+	}
+
+	_, err = sb.Prepare(context.Background(), PrepareInput{
+		Launch: acpclient.LaunchConfig{Env: map[string]string{"KEY": "${vault.MISSING}"}},
+	})
+	if err == nil || !strings.Contains(err.Error(), `"MISSING"`) {
+		t.Fatalf("Prepare with missing secret err = %v", err)
+	}
+	_, err = VaultSandbox{Base: NoopSandbox{}}.Prepare(context.Background(), PrepareInput{
+		Launch: acpclient.LaunchConfig{Env: map[string]string{"KEY": "${vault.NPM_TOKEN}"}},
+	})
+	if err == nil {
+		t.Fatalf("Prepare without a vault should fail on references")
+	}
+}
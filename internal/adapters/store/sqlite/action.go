@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -23,8 +24,10 @@ func (s *Store) CreateAction(ctx context.Context, st *core.Action) (int64, error
 		return 0, fmt.Errorf("insert action: %w", err)
 	}
 	st.ID = model.ID
+	st.Version = 1
 	st.CreatedAt = now
 	st.UpdatedAt = now
+	s.journalActionState(ctx, nil, st)
 	return model.ID, nil
 }
 
@@ -58,18 +61,29 @@ func (s *Store) ListActionsByWorkItem(ctx context.Context, issueID int64) ([]*co
 }
 
 func (s *Store) UpdateActionStatus(ctx context.Context, id int64, status core.ActionStatus) error {
-	result := s.orm.WithContext(ctx).Model(&ActionModel{}).
-		Where("id = ?", id).
-		Updates(map[string]any{
-			"status":     string(status),
-			"updated_at": time.Now().UTC(),
-		})
-	if result.Error != nil {
-		return fmt.Errorf("update action status: %w", result.Error)
-	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
+	var before *core.Action
+	err := s.withActionSnapshot(ctx, id, func(tx *gorm.DB, snapshot *core.Action) error {
+		if snapshot == nil {
+			return core.ErrNotFound
+		}
+		before = snapshot
+		err := tx.Model(&ActionModel{}).
+			Where("id = ?", id).
+			Updates(bumpVersion(map[string]any{
+				"status":     string(status),
+				"updated_at": time.Now().UTC(),
+			})).Error
+		if err != nil {
+			return fmt.Errorf("update action status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	after := *before
+	after.Status = status
+	s.journalActionState(ctx, before, &after)
 	return nil
 }
 
@@ -77,43 +91,61 @@ func (s *Store) UpdateAction(ctx context.Context, st *core.Action) error {
 	now := time.Now().UTC()
 	model := actionModelFromCore(st)
 	model.UpdatedAt = now
-
-	result := s.orm.WithContext(ctx).Model(&ActionModel{}).
-		Where("id = ?", st.ID).
-		Updates(map[string]any{
-			"name":                  model.Name,
-			"description":           model.Description,
-			"type":                  model.Type,
-			"status":                model.Status,
-			"position":              model.Position,
-			"depends_on":            model.DependsOn,
-			"agent_role":            model.AgentRole,
-			"required_capabilities": model.RequiredCapabilities,
-			"acceptance_criteria":   model.AcceptanceCriteria,
-			"timeout_ms":            model.TimeoutMs,
-			"config":                model.Config,
-			"max_retries":           model.MaxRetries,
-			"retry_count":           model.RetryCount,
-			"updated_at":            model.UpdatedAt,
-		})
-	if result.Error != nil {
-		return fmt.Errorf("update action: %w", result.Error)
+	updates := map[string]any{
+		"name":                  model.Name,
+		"description":           model.Description,
+		"type":                  model.Type,
+		"status":                model.Status,
+		"position":              model.Position,
+		"depends_on":            model.DependsOn,
+		"agent_role":            model.AgentRole,
+		"required_capabilities": model.RequiredCapabilities,
+		"acceptance_criteria":   model.AcceptanceCriteria,
+		"timeout_ms":            model.TimeoutMs,
+		"config":                model.Config,
+		"max_retries":           model.MaxRetries,
+		"retry_count":           model.RetryCount,
+		"updated_at":            model.UpdatedAt,
 	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
+
+	var before *core.Action
+	var version int64
+	err := s.withActionSnapshot(ctx, st.ID, func(tx *gorm.DB, snapshot *core.Action) error {
+		before = snapshot
+		var err error
+		version, err = versionedUpdate(tx, &ActionModel{}, "action", st.ID, st.Version, updates)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) || errors.Is(err, core.ErrVersionConflict) {
+			return err
+		}
+		return fmt.Errorf("update action: %w", err)
 	}
+	st.Version = version
 	st.UpdatedAt = now
+	if before != nil {
+		s.journalActionState(ctx, before, st)
+	}
 	return nil
 }
 
 func (s *Store) DeleteAction(ctx context.Context, id int64) error {
-	result := s.orm.WithContext(ctx).Delete(&ActionModel{}, id)
-	if result.Error != nil {
-		return fmt.Errorf("delete action %d: %w", id, result.Error)
-	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
+	var before *core.Action
+	err := s.withActionSnapshot(ctx, id, func(tx *gorm.DB, snapshot *core.Action) error {
+		if snapshot == nil {
+			return core.ErrNotFound
+		}
+		before = snapshot
+		if err := tx.Delete(&ActionModel{}, id).Error; err != nil {
+			return fmt.Errorf("delete action %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.journalActionState(ctx, before, nil)
 	return nil
 }
 
@@ -134,24 +166,37 @@ func (s *Store) BatchCreateActions(ctx context.Context, actions []*core.Action)
 	}
 	for i := range models {
 		actions[i].ID = models[i].ID
+		actions[i].Version = 1
 		actions[i].CreatedAt = now
 		actions[i].UpdatedAt = now
+		s.journalActionState(ctx, nil, actions[i])
 	}
 	return nil
 }
 
 func (s *Store) UpdateActionDependsOn(ctx context.Context, id int64, dependsOn []int64) error {
-	result := s.orm.WithContext(ctx).Model(&ActionModel{}).
-		Where("id = ?", id).
-		Updates(map[string]any{
-			"depends_on": JSONField[[]int64]{Data: dependsOn},
-			"updated_at": time.Now().UTC(),
-		})
-	if result.Error != nil {
-		return fmt.Errorf("update action depends_on: %w", result.Error)
-	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
+	var before *core.Action
+	err := s.withActionSnapshot(ctx, id, func(tx *gorm.DB, snapshot *core.Action) error {
+		if snapshot == nil {
+			return core.ErrNotFound
+		}
+		before = snapshot
+		err := tx.Model(&ActionModel{}).
+			Where("id = ?", id).
+			Updates(bumpVersion(map[string]any{
+				"depends_on": JSONField[[]int64]{Data: dependsOn},
+				"updated_at": time.Now().UTC(),
+			})).Error
+		if err != nil {
+			return fmt.Errorf("update action depends_on: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	after := *before
+	after.DependsOn = dependsOn
+	s.journalActionState(ctx, before, &after)
 	return nil
 }
@@ -21,7 +21,7 @@ func (s *Store) CreateActionSignal(ctx context.Context, sig *core.ActionSignal)
 
 	// Dual-write to activity_journal.
 	if entry := core.ActionSignalToJournalEntry(sig); entry != nil {
-		_, _ = s.AppendJournal(ctx, entry)
+		_ = s.EnqueueJournal(ctx, entry)
 	}
 	return model.ID, nil
 }
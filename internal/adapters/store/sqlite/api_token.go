@@ -0,0 +1,168 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// APITokenModel is the GORM model for API tokens issued through /tokens.
+type APITokenModel struct {
+	ID         int64               `gorm:"column:id;primaryKey;autoIncrement"`
+	Name       string              `gorm:"column:name;not null"`
+	TokenHash  string              `gorm:"column:token_hash;not null;uniqueIndex:idx_api_tokens_hash"`
+	Prefix     string              `gorm:"column:prefix;not null;default:''"`
+	Owner      string              `gorm:"column:owner;not null;default:''"`
+	CreatedBy  string              `gorm:"column:created_by;not null;default:''"`
+	Scopes     JSONField[[]string] `gorm:"column:scopes;type:text"`
+	Projects   JSONField[[]string] `gorm:"column:projects;type:text"`
+	ExpiresAt  *time.Time          `gorm:"column:expires_at"`
+	RevokedAt  *time.Time          `gorm:"column:revoked_at"`
+	LastUsedAt *time.Time          `gorm:"column:last_used_at"`
+	LastUsedIP string              `gorm:"column:last_used_ip;not null;default:''"`
+	CreatedAt  time.Time           `gorm:"column:created_at"`
+}
+
+func (APITokenModel) TableName() string { return "api_tokens" }
+
+// TokenRevocationModel remembers revoked signed tokens until they expire.
+type TokenRevocationModel struct {
+	TokenHash string    `gorm:"column:token_hash;primaryKey"`
+	ExpiresAt time.Time `gorm:"column:expires_at;not null;index:idx_token_revocations_expires"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (TokenRevocationModel) TableName() string { return "token_revocations" }
+
+func migrateAPITokensUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&APITokenModel{}, &TokenRevocationModel{})
+}
+
+func migrateAPITokensDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&TokenRevocationModel{}, &APITokenModel{})
+}
+
+func (m *APITokenModel) toCore() *core.APIToken {
+	return &core.APIToken{
+		ID:         m.ID,
+		Name:       m.Name,
+		TokenHash:  m.TokenHash,
+		Prefix:     m.Prefix,
+		Owner:      m.Owner,
+		CreatedBy:  m.CreatedBy,
+		Scopes:     m.Scopes.Data,
+		Projects:   m.Projects.Data,
+		ExpiresAt:  m.ExpiresAt,
+		RevokedAt:  m.RevokedAt,
+		LastUsedAt: m.LastUsedAt,
+		LastUsedIP: m.LastUsedIP,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+func (s *Store) CreateAPIToken(ctx context.Context, t *core.APIToken) (int64, error) {
+	t.CreatedAt = time.Now().UTC()
+	model := &APITokenModel{
+		Name:      t.Name,
+		TokenHash: t.TokenHash,
+		Prefix:    t.Prefix,
+		Owner:     t.Owner,
+		CreatedBy: t.CreatedBy,
+		Scopes:    JSONField[[]string]{Data: t.Scopes},
+		Projects:  JSONField[[]string]{Data: t.Projects},
+		ExpiresAt: t.ExpiresAt,
+		CreatedAt: t.CreatedAt,
+	}
+	if err := s.orm.WithContext(ctx).Create(model).Error; err != nil {
+		return 0, fmt.Errorf("insert api token: %w", err)
+	}
+	t.ID = model.ID
+	return model.ID, nil
+}
+
+func (s *Store) GetAPIToken(ctx context.Context, id int64) (*core.APIToken, error) {
+	return s.getAPIToken(ctx, "id = ?", id)
+}
+
+func (s *Store) GetAPITokenByHash(ctx context.Context, tokenHash string) (*core.APIToken, error) {
+	return s.getAPIToken(ctx, "token_hash = ?", tokenHash)
+}
+
+func (s *Store) getAPIToken(ctx context.Context, where string, arg any) (*core.APIToken, error) {
+	var model APITokenModel
+	err := s.orm.WithContext(ctx).Where(where, arg).First(&model).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, core.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api token: %w", err)
+	}
+	return model.toCore(), nil
+}
+
+func (s *Store) ListAPITokens(ctx context.Context) ([]*core.APIToken, error) {
+	var models []APITokenModel
+	if err := s.orm.WithContext(ctx).Order("id ASC").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list api tokens: %w", err)
+	}
+	out := make([]*core.APIToken, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
+
+// RevokeAPIToken keeps the first revocation time when called again.
+func (s *Store) RevokeAPIToken(ctx context.Context, id int64, at time.Time) error {
+	if _, err := s.GetAPIToken(ctx, id); err != nil {
+		return err
+	}
+	err := s.orm.WithContext(ctx).Model(&APITokenModel{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+	if err != nil {
+		return fmt.Errorf("revoke api token %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *Store) TouchAPIToken(ctx context.Context, id int64, at time.Time, ip string) error {
+	err := s.orm.WithContext(ctx).Model(&APITokenModel{}).Where("id = ?", id).Updates(map[string]any{
+		"last_used_at": at,
+		"last_used_ip": ip,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("touch api token %d: %w", id, err)
+	}
+	return nil
+}
+
+// RevokeTokenHash also drops revocations that have expired, since the
+// tokens they block no longer verify.
+func (s *Store) RevokeTokenHash(ctx context.Context, tokenHash string, expiresAt time.Time) error {
+	now := time.Now().UTC()
+	return s.writeTx(ctx, func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", now).Delete(&TokenRevocationModel{}).Error; err != nil {
+			return fmt.Errorf("prune token revocations: %w", err)
+		}
+		model := &TokenRevocationModel{TokenHash: tokenHash, ExpiresAt: expiresAt, CreatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(model).Error; err != nil {
+			return fmt.Errorf("insert token revocation: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) ListRevokedTokenHashes(ctx context.Context, now time.Time) ([]string, error) {
+	var hashes []string
+	err := s.orm.WithContext(ctx).Model(&TokenRevocationModel{}).
+		Where("expires_at > ?", now).Order("token_hash ASC").Pluck("token_hash", &hashes).Error
+	if err != nil {
+		return nil, fmt.Errorf("list token revocations: %w", err)
+	}
+	return hashes, nil
+}
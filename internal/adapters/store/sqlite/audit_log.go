@@ -0,0 +1,146 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+)
+
+// AuditLogModel is the GORM model for the API request audit log.
+type AuditLogModel struct {
+	ID          int64                        `gorm:"column:id;primaryKey;autoIncrement"`
+	Actor       string                       `gorm:"column:actor;not null;default:'';index:idx_audit_log_actor"`
+	UserID      int64                        `gorm:"column:user_id;not null;default:0"`
+	Role        string                       `gorm:"column:role;not null;default:''"`
+	Method      string                       `gorm:"column:method;not null"`
+	Route       string                       `gorm:"column:route;not null;default:''"`
+	Path        string                       `gorm:"column:path;not null;default:''"`
+	ResourceIDs JSONField[map[string]string] `gorm:"column:resource_ids;type:text"`
+	Changes     JSONField[map[string]any]    `gorm:"column:changes;type:text"`
+	Status      int                          `gorm:"column:status;not null"`
+	Outcome     string                       `gorm:"column:outcome;not null"`
+	Error       string                       `gorm:"column:error;not null;default:''"`
+	RemoteIP    string                       `gorm:"column:remote_ip;not null;default:''"`
+	UserAgent   string                       `gorm:"column:user_agent;not null;default:''"`
+	DurationMs  int64                        `gorm:"column:duration_ms;not null;default:0"`
+	CreatedAt   time.Time                    `gorm:"column:created_at;index:idx_audit_log_created"`
+}
+
+func (AuditLogModel) TableName() string { return "audit_log" }
+
+func migrateAuditLogUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&AuditLogModel{})
+}
+
+func migrateAuditLogDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&AuditLogModel{})
+}
+
+func auditLogModelFromCore(e *core.AuditLogEntry) *AuditLogModel {
+	return &AuditLogModel{
+		ID:          e.ID,
+		Actor:       e.Actor,
+		UserID:      e.UserID,
+		Role:        e.Role,
+		Method:      e.Method,
+		Route:       e.Route,
+		Path:        e.Path,
+		ResourceIDs: JSONField[map[string]string]{Data: e.ResourceIDs},
+		Changes:     JSONField[map[string]any]{Data: e.Changes},
+		Status:      e.Status,
+		Outcome:     string(e.Outcome),
+		Error:       e.Error,
+		RemoteIP:    e.RemoteIP,
+		UserAgent:   e.UserAgent,
+		DurationMs:  e.DurationMs,
+		CreatedAt:   e.CreatedAt,
+	}
+}
+
+func (m *AuditLogModel) toCore() *core.AuditLogEntry {
+	return &core.AuditLogEntry{
+		ID:          m.ID,
+		Actor:       m.Actor,
+		UserID:      m.UserID,
+		Role:        m.Role,
+		Method:      m.Method,
+		Route:       m.Route,
+		Path:        m.Path,
+		ResourceIDs: m.ResourceIDs.Data,
+		Changes:     m.Changes.Data,
+		Status:      m.Status,
+		Outcome:     core.AuditOutcome(m.Outcome),
+		Error:       m.Error,
+		RemoteIP:    m.RemoteIP,
+		UserAgent:   m.UserAgent,
+		DurationMs:  m.DurationMs,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+func (s *Store) AppendAuditLog(ctx context.Context, entry *core.AuditLogEntry) (int64, error) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	model := auditLogModelFromCore(entry)
+	if err := s.orm.WithContext(ctx).Create(model).Error; err != nil {
+		return 0, fmt.Errorf("insert audit log entry: %w", err)
+	}
+	entry.ID = model.ID
+	return model.ID, nil
+}
+
+// auditResourceKey limits resource filter keys to route parameter names, as
+// they are interpolated into a JSON path.
+var auditResourceKey = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+func (s *Store) ListAuditLog(ctx context.Context, filter core.AuditLogFilter) ([]*core.AuditLogEntry, error) {
+	q := s.orm.WithContext(ctx).Model(&AuditLogModel{})
+	if filter.Actor != "" {
+		q = q.Where("actor = ?", filter.Actor)
+	}
+	if filter.Method != "" {
+		q = q.Where("method = ?", strings.ToUpper(filter.Method))
+	}
+	if filter.RoutePrefix != "" {
+		q = q.Where(`route LIKE ? ESCAPE '\'`, escapeLike(filter.RoutePrefix)+"%")
+	}
+	if filter.Resource != "" {
+		key, value, ok := strings.Cut(filter.Resource, "=")
+		if !ok || !auditResourceKey.MatchString(key) {
+			return nil, fmt.Errorf("invalid resource filter %q (want name=value)", filter.Resource)
+		}
+		q = q.Where("json_extract(resource_ids, ?) = ?", "$."+key, value)
+	}
+	if filter.Outcome != "" {
+		q = q.Where("outcome = ?", string(filter.Outcome))
+	}
+	if filter.Since != nil {
+		q = q.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		q = q.Where("created_at < ?", *filter.Until)
+	}
+	q = q.Order("id DESC")
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		q = q.Offset(filter.Offset)
+	}
+
+	var models []AuditLogModel
+	if err := q.Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list audit log: %w", err)
+	}
+	out := make([]*core.AuditLogEntry, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
@@ -2,6 +2,7 @@ package sqlite
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -19,6 +20,7 @@ func (s *Store) CreateDAGTemplate(ctx context.Context, t *core.DAGTemplate) (int
 		return 0, fmt.Errorf("insert dag_template: %w", err)
 	}
 	t.ID = model.ID
+	t.Version = 1
 	t.CreatedAt = now
 	t.UpdatedAt = now
 	return model.ID, nil
@@ -78,23 +80,22 @@ func (s *Store) UpdateDAGTemplate(ctx context.Context, t *core.DAGTemplate) erro
 	now := time.Now().UTC()
 	model := dagTemplateModelFromCore(t)
 	model.UpdatedAt = now
-	result := s.orm.WithContext(ctx).Model(&DAGTemplateModel{}).
-		Where("id = ?", t.ID).
-		Updates(map[string]any{
-			"name":        model.Name,
-			"description": model.Description,
-			"project_id":  model.ProjectID,
-			"tags":        model.Tags,
-			"metadata":    model.Metadata,
-			"actions":     model.Actions,
-			"updated_at":  model.UpdatedAt,
-		})
-	if result.Error != nil {
-		return fmt.Errorf("update dag_template: %w", result.Error)
-	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
+	version, err := versionedUpdate(s.orm.WithContext(ctx), &DAGTemplateModel{}, "dag template", t.ID, t.Version, map[string]any{
+		"name":        model.Name,
+		"description": model.Description,
+		"project_id":  model.ProjectID,
+		"tags":        model.Tags,
+		"metadata":    model.Metadata,
+		"actions":     model.Actions,
+		"updated_at":  model.UpdatedAt,
+	})
+	if err != nil {
+		if errors.Is(err, core.ErrNotFound) || errors.Is(err, core.ErrVersionConflict) {
+			return err
+		}
+		return fmt.Errorf("update dag_template: %w", err)
 	}
+	t.Version = version
 	t.UpdatedAt = now
 	return nil
 }
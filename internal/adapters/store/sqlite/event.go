@@ -25,6 +25,33 @@ func (s *Store) CreateEvent(ctx context.Context, e *core.Event) (int64, error) {
 	return model.ID, nil
 }
 
+func (s *Store) BatchCreateEvents(ctx context.Context, events []*core.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	models := make([]*EventModel, 0, len(events))
+	for _, e := range events {
+		if e.Timestamp.IsZero() {
+			e.Timestamp = now
+		}
+		if e.Category == "" {
+			e.Category = core.EventCategoryDomain
+		}
+		models = append(models, eventModelFromCore(e))
+	}
+	err := s.writeTx(ctx, func(tx *gorm.DB) error {
+		return tx.CreateInBatches(models, 100).Error
+	})
+	if err != nil {
+		return fmt.Errorf("batch insert events: %w", err)
+	}
+	for i, m := range models {
+		events[i].ID = m.ID
+	}
+	return nil
+}
+
 func (s *Store) ListEvents(ctx context.Context, filter core.EventFilter) ([]*core.Event, error) {
 	query := s.orm.WithContext(ctx).Model(&EventModel{})
 	if filter.WorkItemID != nil {
@@ -60,6 +87,17 @@ func (s *Store) ListEvents(ctx context.Context, filter core.EventFilter) ([]*cor
 	}
 
 	var models []EventModel
+	if filter.SinceSeq != nil {
+		// Cursor reads page forward from the cursor in sequence order.
+		if err := query.Where("seq > ?", *filter.SinceSeq).Order("seq ASC").Find(&models).Error; err != nil {
+			return nil, fmt.Errorf("list events: %w", err)
+		}
+		events := make([]*core.Event, 0, len(models))
+		for i := range models {
+			events = append(events, models[i].toCore())
+		}
+		return events, nil
+	}
 	// Fetch the most recent events first (DESC) so that LIMIT returns the
 	// latest N rather than the earliest N, then reverse to chronological order.
 	if err := query.Order("id DESC").Find(&models).Error; err != nil {
@@ -76,6 +114,68 @@ func (s *Store) ListEvents(ctx context.Context, filter core.EventFilter) ([]*cor
 	return events, nil
 }
 
+// EventSeqStateModel is the single-row GORM model tracking event sequence
+// numbers that event_log alone cannot reconstruct: the ceiling the bus has
+// reserved (covering transient events that are never persisted) and the
+// highest seq removed by retention.
+type EventSeqStateModel struct {
+	ID          int64 `gorm:"column:id;primaryKey"`
+	ReservedSeq int64 `gorm:"column:reserved_seq;not null;default:0"`
+	PrunedSeq   int64 `gorm:"column:pruned_seq;not null;default:0"`
+}
+
+func (EventSeqStateModel) TableName() string { return "event_seq_state" }
+
+const eventSeqStateID = 1
+
+func migrateEventSeqStateUp(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&EventSeqStateModel{}); err != nil {
+		return err
+	}
+	return tx.Exec(`INSERT OR IGNORE INTO event_seq_state (id, reserved_seq, pruned_seq) VALUES (?, 0, 0)`, eventSeqStateID).Error
+}
+
+func migrateEventSeqStateDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&EventSeqStateModel{})
+}
+
+// MaxEventSeq returns the highest sequence number the event bus may already
+// have handed out: the larger of the highest persisted seq and the reserved
+// ceiling. Seeding the bus with it keeps sequence numbers increasing across
+// restarts, including those of transient events that were never persisted.
+func (s *Store) MaxEventSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	err := s.orm.WithContext(ctx).Raw(`SELECT MAX(
+		(SELECT COALESCE(MAX(seq), 0) FROM event_log),
+		(SELECT COALESCE(MAX(reserved_seq), 0) FROM event_seq_state))`).Scan(&seq).Error
+	if err != nil {
+		return 0, fmt.Errorf("max event seq: %w", err)
+	}
+	return seq, nil
+}
+
+// ReserveEventSeq records that sequence numbers up to upTo may be issued, so
+// a restart never reuses them.
+func (s *Store) ReserveEventSeq(ctx context.Context, upTo int64) error {
+	err := s.writeTx(ctx, func(tx *gorm.DB) error {
+		return tx.Exec(`UPDATE event_seq_state SET reserved_seq = MAX(reserved_seq, ?) WHERE id = ?`, upTo, eventSeqStateID).Error
+	})
+	if err != nil {
+		return fmt.Errorf("reserve event seq: %w", err)
+	}
+	return nil
+}
+
+// PrunedEventSeq returns the highest seq retention has deleted from
+// event_log; replays from before it are incomplete.
+func (s *Store) PrunedEventSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	if err := s.orm.WithContext(ctx).Raw(`SELECT COALESCE(MAX(pruned_seq), 0) FROM event_seq_state`).Scan(&seq).Error; err != nil {
+		return 0, fmt.Errorf("pruned event seq: %w", err)
+	}
+	return seq, nil
+}
+
 func (s *Store) GetLatestRunEventTime(ctx context.Context, runID int64, eventType core.EventType) (*time.Time, error) {
 	var model EventModel
 	err := s.orm.WithContext(ctx).
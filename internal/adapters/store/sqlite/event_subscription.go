@@ -0,0 +1,283 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+)
+
+// EventSubscriptionModel is the GORM model for outbound event subscriptions.
+type EventSubscriptionModel struct {
+	ID         int64                       `gorm:"column:id;primaryKey;autoIncrement"`
+	Name       string                      `gorm:"column:name;not null;default:''"`
+	TargetURL  string                      `gorm:"column:target_url;not null"`
+	EventTypes JSONField[[]core.EventType] `gorm:"column:event_types;type:text"`
+	ProjectID  *int64                      `gorm:"column:project_id"`
+	Secret     string                      `gorm:"column:secret;not null;default:''"`
+	Enabled    bool                        `gorm:"column:enabled;not null;default:false"`
+	CreatedAt  time.Time                   `gorm:"column:created_at"`
+	UpdatedAt  time.Time                   `gorm:"column:updated_at"`
+}
+
+func (EventSubscriptionModel) TableName() string { return "event_subscriptions" }
+
+// EventDeliveryModel is the GORM model for the per-subscription delivery log.
+type EventDeliveryModel struct {
+	ID             int64      `gorm:"column:id;primaryKey;autoIncrement"`
+	SubscriptionID int64      `gorm:"column:subscription_id;not null;index:idx_event_deliveries_sub,priority:1"`
+	CloudEventID   string     `gorm:"column:cloud_event_id;not null"`
+	EventType      string     `gorm:"column:event_type;not null"`
+	EventSeq       int64      `gorm:"column:event_seq;not null;default:0"`
+	Payload        string     `gorm:"column:payload;type:text;not null"`
+	Status         string     `gorm:"column:status;not null;index:idx_event_deliveries_due,priority:1"`
+	Attempts       int        `gorm:"column:attempts;not null;default:0"`
+	NextAttemptAt  *time.Time `gorm:"column:next_attempt_at;index:idx_event_deliveries_due,priority:2"`
+	LastError      string     `gorm:"column:last_error;not null;default:''"`
+	ResponseStatus int        `gorm:"column:response_status;not null;default:0"`
+	ReplayOf       *int64     `gorm:"column:replay_of"`
+	DeliveredAt    *time.Time `gorm:"column:delivered_at"`
+	CreatedAt      time.Time  `gorm:"column:created_at;index:idx_event_deliveries_sub,priority:2"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at"`
+}
+
+func (EventDeliveryModel) TableName() string { return "event_deliveries" }
+
+func migrateEventSubscriptionsUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&EventSubscriptionModel{}, &EventDeliveryModel{})
+}
+
+func migrateEventSubscriptionsDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&EventDeliveryModel{}, &EventSubscriptionModel{})
+}
+
+func eventSubscriptionModelFromCore(s *core.EventSubscription) *EventSubscriptionModel {
+	return &EventSubscriptionModel{
+		ID:         s.ID,
+		Name:       s.Name,
+		TargetURL:  s.TargetURL,
+		EventTypes: JSONField[[]core.EventType]{Data: s.EventTypes},
+		ProjectID:  s.ProjectID,
+		Secret:     s.Secret,
+		Enabled:    s.Enabled,
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+}
+
+func (m *EventSubscriptionModel) toCore() *core.EventSubscription {
+	return &core.EventSubscription{
+		ID:         m.ID,
+		Name:       m.Name,
+		TargetURL:  m.TargetURL,
+		EventTypes: m.EventTypes.Data,
+		ProjectID:  m.ProjectID,
+		Secret:     m.Secret,
+		Enabled:    m.Enabled,
+		CreatedAt:  m.CreatedAt,
+		UpdatedAt:  m.UpdatedAt,
+	}
+}
+
+func eventDeliveryModelFromCore(d *core.EventDelivery) *EventDeliveryModel {
+	return &EventDeliveryModel{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		CloudEventID:   d.CloudEventID,
+		EventType:      string(d.EventType),
+		EventSeq:       d.EventSeq,
+		Payload:        d.Payload,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastError:      d.LastError,
+		ResponseStatus: d.ResponseStatus,
+		ReplayOf:       d.ReplayOf,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+}
+
+func (m *EventDeliveryModel) toCore() *core.EventDelivery {
+	return &core.EventDelivery{
+		ID:             m.ID,
+		SubscriptionID: m.SubscriptionID,
+		CloudEventID:   m.CloudEventID,
+		EventType:      core.EventType(m.EventType),
+		EventSeq:       m.EventSeq,
+		Payload:        m.Payload,
+		Status:         core.EventDeliveryStatus(m.Status),
+		Attempts:       m.Attempts,
+		NextAttemptAt:  m.NextAttemptAt,
+		LastError:      m.LastError,
+		ResponseStatus: m.ResponseStatus,
+		ReplayOf:       m.ReplayOf,
+		DeliveredAt:    m.DeliveredAt,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+}
+
+func (s *Store) CreateEventSubscription(ctx context.Context, sub *core.EventSubscription) (int64, error) {
+	now := time.Now().UTC()
+	sub.CreatedAt, sub.UpdatedAt = now, now
+	model := eventSubscriptionModelFromCore(sub)
+	if err := s.orm.WithContext(ctx).Create(model).Error; err != nil {
+		return 0, fmt.Errorf("insert event subscription: %w", err)
+	}
+	sub.ID = model.ID
+	return model.ID, nil
+}
+
+func (s *Store) GetEventSubscription(ctx context.Context, id int64) (*core.EventSubscription, error) {
+	var model EventSubscriptionModel
+	err := s.orm.WithContext(ctx).Where("id = ?", id).First(&model).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, core.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get event subscription %d: %w", id, err)
+	}
+	return model.toCore(), nil
+}
+
+func (s *Store) ListEventSubscriptions(ctx context.Context) ([]*core.EventSubscription, error) {
+	var models []EventSubscriptionModel
+	if err := s.orm.WithContext(ctx).Order("id ASC").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list event subscriptions: %w", err)
+	}
+	out := make([]*core.EventSubscription, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
+
+func (s *Store) UpdateEventSubscription(ctx context.Context, sub *core.EventSubscription) error {
+	sub.UpdatedAt = time.Now().UTC()
+	result := s.orm.WithContext(ctx).Model(&EventSubscriptionModel{}).Where("id = ?", sub.ID).Updates(map[string]any{
+		"name":        sub.Name,
+		"target_url":  sub.TargetURL,
+		"event_types": JSONField[[]core.EventType]{Data: sub.EventTypes},
+		"project_id":  sub.ProjectID,
+		"secret":      sub.Secret,
+		"enabled":     sub.Enabled,
+		"updated_at":  sub.UpdatedAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("update event subscription %d: %w", sub.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) DeleteEventSubscription(ctx context.Context, id int64) error {
+	return s.writeTx(ctx, func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&EventDeliveryModel{}).Error; err != nil {
+			return fmt.Errorf("delete event deliveries: %w", err)
+		}
+		result := tx.Where("id = ?", id).Delete(&EventSubscriptionModel{})
+		if result.Error != nil {
+			return fmt.Errorf("delete event subscription %d: %w", id, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return core.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s *Store) CreateEventDelivery(ctx context.Context, d *core.EventDelivery) (int64, error) {
+	now := time.Now().UTC()
+	d.CreatedAt, d.UpdatedAt = now, now
+	model := eventDeliveryModelFromCore(d)
+	if err := s.orm.WithContext(ctx).Create(model).Error; err != nil {
+		return 0, fmt.Errorf("insert event delivery: %w", err)
+	}
+	d.ID = model.ID
+	return model.ID, nil
+}
+
+func (s *Store) GetEventDelivery(ctx context.Context, id int64) (*core.EventDelivery, error) {
+	var model EventDeliveryModel
+	err := s.orm.WithContext(ctx).Where("id = ?", id).First(&model).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, core.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get event delivery %d: %w", id, err)
+	}
+	return model.toCore(), nil
+}
+
+func (s *Store) UpdateEventDelivery(ctx context.Context, d *core.EventDelivery) error {
+	d.UpdatedAt = time.Now().UTC()
+	result := s.orm.WithContext(ctx).Model(&EventDeliveryModel{}).Where("id = ?", d.ID).Updates(map[string]any{
+		"status":          string(d.Status),
+		"attempts":        d.Attempts,
+		"next_attempt_at": d.NextAttemptAt,
+		"last_error":      d.LastError,
+		"response_status": d.ResponseStatus,
+		"delivered_at":    d.DeliveredAt,
+		"updated_at":      d.UpdatedAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("update event delivery %d: %w", d.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) ListEventDeliveries(ctx context.Context, filter core.EventDeliveryFilter) ([]*core.EventDelivery, error) {
+	query := s.orm.WithContext(ctx).Model(&EventDeliveryModel{})
+	if filter.SubscriptionID != nil {
+		query = query.Where("subscription_id = ?", *filter.SubscriptionID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", string(filter.Status))
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	query = query.Limit(limit)
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	var models []EventDeliveryModel
+	if err := query.Order("id DESC").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list event deliveries: %w", err)
+	}
+	out := make([]*core.EventDelivery, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
+
+func (s *Store) ListDueEventDeliveries(ctx context.Context, now time.Time, limit int) ([]*core.EventDelivery, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var models []EventDeliveryModel
+	err := s.orm.WithContext(ctx).
+		Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", string(core.EventDeliveryPending), now.UTC()).
+		Order("next_attempt_at ASC, id ASC").
+		Limit(limit).
+		Find(&models).Error
+	if err != nil {
+		return nil, fmt.Errorf("list due event deliveries: %w", err)
+	}
+	out := make([]*core.EventDelivery, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKeyModel is the GORM model for stored Idempotency-Key
+// responses. A pending row has status 0 and a short expiry, so a request
+// that never finished does not hold its key for the whole TTL.
+type IdempotencyKeyModel struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Scope       string    `gorm:"column:scope;not null;uniqueIndex:idx_idempotency_keys_scope_key,priority:1"`
+	Key         string    `gorm:"column:idempotency_key;not null;uniqueIndex:idx_idempotency_keys_scope_key,priority:2"`
+	Method      string    `gorm:"column:method;not null;default:''"`
+	Path        string    `gorm:"column:path;not null;default:''"`
+	RequestHash string    `gorm:"column:request_hash;not null;default:''"`
+	Status      int       `gorm:"column:status;not null;default:0"`
+	ContentType string    `gorm:"column:content_type;not null;default:''"`
+	Body        []byte    `gorm:"column:body"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;index:idx_idempotency_keys_expires_at"`
+}
+
+func (IdempotencyKeyModel) TableName() string { return "idempotency_keys" }
+
+func migrateIdempotencyKeysUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&IdempotencyKeyModel{})
+}
+
+func migrateIdempotencyKeysDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&IdempotencyKeyModel{})
+}
+
+func (m *IdempotencyKeyModel) toCore() *core.IdempotencyRecord {
+	return &core.IdempotencyRecord{
+		Scope:       m.Scope,
+		Key:         m.Key,
+		Method:      m.Method,
+		Path:        m.Path,
+		RequestHash: m.RequestHash,
+		Status:      m.Status,
+		ContentType: m.ContentType,
+		Body:        m.Body,
+		CreatedAt:   m.CreatedAt,
+		ExpiresAt:   m.ExpiresAt,
+	}
+}
+
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, rec *core.IdempotencyRecord) (*core.IdempotencyRecord, bool, error) {
+	now := time.Now().UTC()
+	var existing *core.IdempotencyRecord
+	reserved := false
+	err := s.writeTx(ctx, func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at <= ?", now).Delete(&IdempotencyKeyModel{}).Error; err != nil {
+			return fmt.Errorf("prune idempotency keys: %w", err)
+		}
+		model := &IdempotencyKeyModel{
+			Scope:       rec.Scope,
+			Key:         rec.Key,
+			Method:      rec.Method,
+			Path:        rec.Path,
+			RequestHash: rec.RequestHash,
+			CreatedAt:   now,
+			ExpiresAt:   rec.ExpiresAt.UTC(),
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(model)
+		if result.Error != nil {
+			return fmt.Errorf("reserve idempotency key: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			reserved = true
+			return nil
+		}
+		var stored IdempotencyKeyModel
+		if err := tx.Where("scope = ? AND idempotency_key = ?", rec.Scope, rec.Key).First(&stored).Error; err != nil {
+			return fmt.Errorf("load idempotency key: %w", err)
+		}
+		existing = stored.toCore()
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return existing, reserved, nil
+}
+
+func (s *Store) CompleteIdempotencyKey(ctx context.Context, scope, key string, status int, contentType string, body []byte, expiresAt time.Time) error {
+	err := s.orm.WithContext(ctx).Model(&IdempotencyKeyModel{}).
+		Where("scope = ? AND idempotency_key = ?", scope, key).
+		Updates(map[string]any{
+			"status":       status,
+			"content_type": contentType,
+			"body":         body,
+			"expires_at":   expiresAt.UTC(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, scope, key string) error {
+	err := s.orm.WithContext(ctx).
+		Where("scope = ? AND idempotency_key = ? AND status = 0", scope, key).
+		Delete(&IdempotencyKeyModel{}).Error
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -14,13 +14,40 @@ func (s *Store) AppendJournal(ctx context.Context, entry *core.JournalEntry) (in
 		entry.CreatedAt = time.Now().UTC()
 	}
 	model := journalModelFromCore(entry)
-	if err := s.orm.WithContext(ctx).Create(model).Error; err != nil {
+	err := s.withJournalChain(ctx, func(tx *gorm.DB) error {
+		return insertChainedJournal(tx, []*JournalModel{model})
+	})
+	if err != nil {
 		return 0, fmt.Errorf("insert journal entry: %w", err)
 	}
 	entry.ID = model.ID
 	return model.ID, nil
 }
 
+// EnqueueJournal hands entry to the journal queue set by SetJournalQueue, and
+// appends it directly when there is none, when the store is bound to a
+// transaction, or when the queue rejects it (e.g. after it closed).
+func (s *Store) EnqueueJournal(ctx context.Context, entry *core.JournalEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	if s.journalQueue != nil {
+		if err := s.journalQueue.EnqueueJournal(ctx, entry); err == nil {
+			return nil
+		}
+	}
+	_, err := s.AppendJournal(ctx, entry)
+	return err
+}
+
+// SetJournalQueue routes EnqueueJournal through q, typically the event
+// persister's batch writer, so journal entries share its batched
+// transactions instead of extending the hash chain one at a time. Call it
+// before the store is shared.
+func (s *Store) SetJournalQueue(q core.JournalQueue) {
+	s.journalQueue = q
+}
+
 func (s *Store) BatchAppendJournal(ctx context.Context, entries []*core.JournalEntry) error {
 	if len(entries) == 0 {
 		return nil
@@ -33,7 +60,10 @@ func (s *Store) BatchAppendJournal(ctx context.Context, entries []*core.JournalE
 		}
 		models = append(models, journalModelFromCore(e))
 	}
-	if err := s.orm.WithContext(ctx).CreateInBatches(models, 50).Error; err != nil {
+	err := s.withJournalChain(ctx, func(tx *gorm.DB) error {
+		return insertChainedJournal(tx, models)
+	})
+	if err != nil {
 		return fmt.Errorf("batch insert journal entries: %w", err)
 	}
 	for i, m := range models {
@@ -0,0 +1,432 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+)
+
+// activity_journal is hash-chained: each row stores chain_seq, the hash of
+// the previous row and its own hash over journalCanonical. Rows are chained
+// in insertion order under journalMu, so id order and chain order agree.
+// Deleting the journal of a work item records the removed links in an
+// erasure entry; retention only prunes a prefix of the chain.
+
+const journalVerifyBatch = 500
+
+// JournalCheckpointModel is a signed snapshot of the chain head.
+type JournalCheckpointModel struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Seq       int64     `gorm:"column:seq;not null"`
+	Hash      string    `gorm:"column:hash;not null"`
+	Signature string    `gorm:"column:signature;not null"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (JournalCheckpointModel) TableName() string { return "journal_checkpoints" }
+
+func (m *JournalCheckpointModel) toCore() *core.ChainCheckpoint {
+	return &core.ChainCheckpoint{ID: m.ID, Seq: m.Seq, Hash: m.Hash, Signature: m.Signature, CreatedAt: m.CreatedAt}
+}
+
+// migrateJournalChainUp adds the chain columns and chains existing rows in
+// id order.
+func migrateJournalChainUp(tx *gorm.DB) error {
+	for _, field := range []string{"ChainSeq", "PrevHash", "Hash"} {
+		if tx.Migrator().HasColumn(&JournalModel{}, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&JournalModel{}, field); err != nil {
+			return fmt.Errorf("add activity_journal.%s: %w", field, err)
+		}
+	}
+	if err := backfillJournalChain(tx); err != nil {
+		return err
+	}
+	if err := tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_journal_chain_seq ON activity_journal(chain_seq) WHERE chain_seq > 0`).Error; err != nil {
+		return fmt.Errorf("create chain index: %w", err)
+	}
+	return tx.AutoMigrate(&JournalCheckpointModel{})
+}
+
+func migrateJournalChainDown(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable(&JournalCheckpointModel{}); err != nil {
+		return err
+	}
+	if err := tx.Exec(`DROP INDEX IF EXISTS idx_journal_chain_seq`).Error; err != nil {
+		return err
+	}
+	// ALTER TABLE rather than Migrator().DropColumn, which rebuilds the table
+	// under the search triggers (see migrateRowVersionsDown).
+	for _, column := range []string{"chain_seq", "prev_hash", "hash"} {
+		if !tx.Migrator().HasColumn(&JournalModel{}, column) {
+			continue
+		}
+		if err := tx.Exec("ALTER TABLE activity_journal DROP COLUMN " + column).Error; err != nil {
+			return fmt.Errorf("drop activity_journal.%s: %w", column, err)
+		}
+	}
+	return nil
+}
+
+func backfillJournalChain(tx *gorm.DB) error {
+	head, err := journalChainHead(tx)
+	if err != nil {
+		return err
+	}
+	for {
+		var rows []journalChainRow
+		if err := tx.Table("activity_journal").Where("chain_seq = 0").Order("id").Limit(journalVerifyBatch).Find(&rows).Error; err != nil {
+			return fmt.Errorf("load unchained journal rows: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		for i := range rows {
+			row := &rows[i]
+			row.ChainSeq, row.PrevHash = head.Seq+1, head.Hash
+			canonical, err := row.canonical()
+			if err != nil {
+				return fmt.Errorf("chain journal row %d: %w", row.ID, err)
+			}
+			row.Hash = core.ChainHash(row.PrevHash, canonical)
+			if err := tx.Exec(`UPDATE activity_journal SET chain_seq = ?, prev_hash = ?, hash = ? WHERE id = ?`,
+				row.ChainSeq, row.PrevHash, row.Hash, row.ID).Error; err != nil {
+				return fmt.Errorf("chain journal row %d: %w", row.ID, err)
+			}
+			head = core.ChainLink{Seq: row.ChainSeq, PrevHash: row.PrevHash, Hash: row.Hash}
+		}
+	}
+}
+
+// journalChainRow reads a journal row with the payload kept as stored, so
+// that verification hashes exactly the bytes that were written.
+type journalChainRow struct {
+	ID             int64     `gorm:"column:id"`
+	WorkItemID     *int64    `gorm:"column:work_item_id"`
+	ActionID       *int64    `gorm:"column:action_id"`
+	RunID          *int64    `gorm:"column:run_id"`
+	Kind           string    `gorm:"column:kind"`
+	Source         string    `gorm:"column:source"`
+	Summary        string    `gorm:"column:summary"`
+	Payload        *string   `gorm:"column:payload"`
+	Ref            *string   `gorm:"column:ref"`
+	Actor          string    `gorm:"column:actor"`
+	SourceActionID *int64    `gorm:"column:source_action_id"`
+	CreatedAt      time.Time `gorm:"column:created_at"`
+	ChainSeq       int64     `gorm:"column:chain_seq"`
+	PrevHash       string    `gorm:"column:prev_hash"`
+	Hash           string    `gorm:"column:hash"`
+}
+
+// journalCanonical is the hashed form of a journal row. Its id is left out:
+// the chain position is chain_seq.
+type journalCanonical struct {
+	Seq            int64           `json:"seq"`
+	WorkItemID     int64           `json:"work_item_id"`
+	ActionID       int64           `json:"action_id"`
+	RunID          int64           `json:"run_id"`
+	Kind           string          `json:"kind"`
+	Source         string          `json:"source"`
+	Summary        string          `json:"summary"`
+	Payload        json.RawMessage `json:"payload"`
+	Ref            string          `json:"ref"`
+	Actor          string          `json:"actor"`
+	SourceActionID int64           `json:"source_action_id"`
+	CreatedAt      string          `json:"created_at"`
+}
+
+func (r *journalChainRow) canonical() ([]byte, error) {
+	payload := json.RawMessage("null")
+	if r.Payload != nil && *r.Payload != "" {
+		payload = json.RawMessage(*r.Payload)
+	}
+	raw, err := json.Marshal(journalCanonical{
+		Seq:            r.ChainSeq,
+		WorkItemID:     derefInt64(r.WorkItemID),
+		ActionID:       derefInt64(r.ActionID),
+		RunID:          derefInt64(r.RunID),
+		Kind:           r.Kind,
+		Source:         r.Source,
+		Summary:        r.Summary,
+		Payload:        payload,
+		Ref:            derefString(r.Ref),
+		Actor:          r.Actor,
+		SourceActionID: derefInt64(r.SourceActionID),
+		CreatedAt:      r.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return core.CanonicalJSON(raw)
+}
+
+func journalChainRowFromModel(m *JournalModel) (*journalChainRow, error) {
+	row := &journalChainRow{
+		ID:             m.ID,
+		WorkItemID:     m.WorkItemID,
+		ActionID:       m.ActionID,
+		RunID:          m.RunID,
+		Kind:           m.Kind,
+		Source:         m.Source,
+		Summary:        m.Summary,
+		Ref:            m.Ref,
+		Actor:          m.Actor,
+		SourceActionID: m.SourceActionID,
+		CreatedAt:      m.CreatedAt,
+		ChainSeq:       m.ChainSeq,
+		PrevHash:       m.PrevHash,
+	}
+	value, err := m.Payload.Value()
+	if err != nil {
+		return nil, fmt.Errorf("encode journal payload: %w", err)
+	}
+	if text, ok := value.(string); ok {
+		row.Payload = &text
+	}
+	return row, nil
+}
+
+func derefInt64(v *int64) int64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+func derefString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func journalChainHead(tx *gorm.DB) (core.ChainLink, error) {
+	var head core.ChainLink
+	var row JournalModel
+	err := tx.Model(&JournalModel{}).Select("chain_seq", "prev_hash", "hash").
+		Where("chain_seq > 0").Order("chain_seq DESC").Limit(1).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return head, nil
+	}
+	if err != nil {
+		return head, fmt.Errorf("read journal chain head: %w", err)
+	}
+	return core.ChainLink{Seq: row.ChainSeq, PrevHash: row.PrevHash, Hash: row.Hash}, nil
+}
+
+// withJournalChain runs fn in a transaction that may extend the chain. fn
+// reads the head before inserting after it, so the transaction takes the
+// write lock up front: a deferred one would fail with SQLITE_BUSY when
+// another connection commits between the read and the insert. Stores bound
+// to an InTx transaction are already serialized by SQLite's write lock and
+// skip the mutex.
+func (s *Store) withJournalChain(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	if s.journalMu != nil {
+		s.journalMu.Lock()
+		defer s.journalMu.Unlock()
+	}
+	return s.writeTx(ctx, fn)
+}
+
+// insertChainedJournal links models after the current head and inserts them.
+func insertChainedJournal(tx *gorm.DB, models []*JournalModel) error {
+	head, err := journalChainHead(tx)
+	if err != nil {
+		return err
+	}
+	for _, m := range models {
+		m.CreatedAt = m.CreatedAt.UTC()
+		m.ChainSeq, m.PrevHash = head.Seq+1, head.Hash
+		row, err := journalChainRowFromModel(m)
+		if err != nil {
+			return err
+		}
+		canonical, err := row.canonical()
+		if err != nil {
+			return fmt.Errorf("canonical journal entry: %w", err)
+		}
+		m.Hash = core.ChainHash(m.PrevHash, canonical)
+		head = core.ChainLink{Seq: m.ChainSeq, PrevHash: m.PrevHash, Hash: m.Hash}
+	}
+	return tx.CreateInBatches(models, 50).Error
+}
+
+// deleteChainedJournal deletes the rows matched by where and appends an
+// erasure entry listing their links.
+func deleteChainedJournal(tx *gorm.DB, reason string, where string, args ...any) error {
+	var erased []JournalModel
+	if err := tx.Model(&JournalModel{}).Select("chain_seq", "hash").
+		Where(where, args...).Where("chain_seq > 0").Order("chain_seq").Find(&erased).Error; err != nil {
+		return err
+	}
+	if err := tx.Where(where, args...).Delete(&JournalModel{}).Error; err != nil {
+		return err
+	}
+	if len(erased) == 0 {
+		return nil
+	}
+	links := make([]any, 0, len(erased))
+	for _, m := range erased {
+		links = append(links, map[string]any{"seq": m.ChainSeq, "hash": m.Hash})
+	}
+	return insertChainedJournal(tx, []*JournalModel{journalModelFromCore(&core.JournalEntry{
+		Kind:      core.JournalErasure,
+		Source:    core.JournalSourceSystem,
+		Summary:   fmt.Sprintf("%s: %d journal entries erased", reason, len(erased)),
+		Payload:   map[string]any{"reason": reason, "erased": links},
+		CreatedAt: time.Now().UTC(),
+	})})
+}
+
+func (s *Store) CreateJournalCheckpoint(ctx context.Context, key []byte) (*core.ChainCheckpoint, error) {
+	if len(key) == 0 {
+		return nil, fmt.Errorf("journal checkpoint: signing key is required")
+	}
+	var created *JournalCheckpointModel
+	err := s.withJournalChain(ctx, func(tx *gorm.DB) error {
+		head, err := journalChainHead(tx)
+		if err != nil || head.Seq == 0 {
+			return err
+		}
+		var last JournalCheckpointModel
+		err = tx.Order("id DESC").Limit(1).Take(&last).Error
+		if err == nil && last.Seq == head.Seq {
+			return nil
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		created = &JournalCheckpointModel{
+			Seq:       head.Seq,
+			Hash:      head.Hash,
+			Signature: core.SignChainCheckpoint(key, head.Seq, head.Hash),
+			CreatedAt: time.Now().UTC(),
+		}
+		return tx.Create(created).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create journal checkpoint: %w", err)
+	}
+	if created == nil {
+		return nil, nil
+	}
+	return created.toCore(), nil
+}
+
+func (s *Store) ListJournalCheckpoints(ctx context.Context) ([]*core.ChainCheckpoint, error) {
+	var models []JournalCheckpointModel
+	if err := s.orm.WithContext(ctx).Order("id").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list journal checkpoints: %w", err)
+	}
+	out := make([]*core.ChainCheckpoint, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
+
+func (s *Store) VerifyJournalChain(ctx context.Context, key []byte) (*core.ChainReport, error) {
+	report := &core.ChainReport{Source: "activity_journal", SignaturesChecked: len(key) > 0}
+	var verifier core.ChainVerifier
+	if err := s.loadJournalErasures(ctx, &verifier); err != nil {
+		return nil, err
+	}
+	checkpoints, err := s.ListJournalCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Hashes seen at checkpointed positions.
+	pinned := make(map[int64]string, len(checkpoints))
+	for _, cp := range checkpoints {
+		pinned[cp.Seq] = ""
+	}
+
+	for lastID := int64(0); report.Break == nil; {
+		var rows []journalChainRow
+		if err := s.orm.WithContext(ctx).Table("activity_journal").
+			Where("id > ?", lastID).Order("id").Limit(journalVerifyBatch).Find(&rows).Error; err != nil {
+			return nil, fmt.Errorf("read journal chain: %w", err)
+		}
+		for i := range rows {
+			row := &rows[i]
+			lastID = row.ID
+			canonical, err := row.canonical()
+			if err != nil {
+				report.Break = &core.ChainBreak{Seq: row.ChainSeq, Kind: core.ChainBreakEdited, Detail: err.Error()}
+			} else {
+				report.Break = verifier.Check(core.ChainLink{Seq: row.ChainSeq, PrevHash: row.PrevHash, Hash: row.Hash}, canonical)
+			}
+			if report.Break != nil {
+				report.Break.Ref = "id " + strconv.FormatInt(row.ID, 10)
+				break
+			}
+			if _, ok := pinned[row.ChainSeq]; ok {
+				pinned[row.ChainSeq] = row.Hash
+			}
+		}
+		if len(rows) < journalVerifyBatch {
+			break
+		}
+	}
+	report.Entries, report.FirstSeq, report.LastSeq = verifier.Entries, verifier.FirstSeq, verifier.LastSeq
+	if report.Break != nil {
+		return report, nil
+	}
+
+	for _, cp := range checkpoints {
+		ref := "checkpoint " + strconv.FormatInt(cp.ID, 10)
+		if report.SignaturesChecked && !cp.ValidSignature(key) {
+			report.Break = &core.ChainBreak{Seq: cp.Seq, Kind: core.ChainBreakCheckpoint, Ref: ref, Detail: "signature does not match"}
+			return report, nil
+		}
+		switch {
+		case cp.Seq > report.LastSeq:
+			report.Break = &core.ChainBreak{Seq: report.LastSeq + 1, Kind: core.ChainBreakTruncated, Ref: ref,
+				Detail: fmt.Sprintf("checkpoint covers seq %d but the journal ends at seq %d", cp.Seq, report.LastSeq)}
+			return report, nil
+		case cp.Seq < report.FirstSeq || pinned[cp.Seq] == "":
+			// Pruned by retention or erased with its work item.
+			continue
+		case pinned[cp.Seq] != cp.Hash:
+			report.Break = &core.ChainBreak{Seq: cp.Seq, Kind: core.ChainBreakCheckpoint, Ref: ref, Detail: "entry hash differs from the checkpoint"}
+			return report, nil
+		}
+		report.Checkpoints++
+	}
+	return report, nil
+}
+
+// loadJournalErasures declares the links removed by erasure entries.
+func (s *Store) loadJournalErasures(ctx context.Context, verifier *core.ChainVerifier) error {
+	var rows []journalChainRow
+	if err := s.orm.WithContext(ctx).Table("activity_journal").
+		Where("kind = ?", string(core.JournalErasure)).Order("id").Find(&rows).Error; err != nil {
+		return fmt.Errorf("read journal erasures: %w", err)
+	}
+	for _, row := range rows {
+		if row.Payload == nil {
+			continue
+		}
+		var payload struct {
+			Erased []struct {
+				Seq  int64  `json:"seq"`
+				Hash string `json:"hash"`
+			} `json:"erased"`
+		}
+		// A tampered payload fails its own hash during the walk.
+		if json.Unmarshal([]byte(*row.Payload), &payload) != nil {
+			continue
+		}
+		for _, link := range payload.Erased {
+			verifier.AllowErased(link.Seq, link.Hash)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+var testCheckpointKey = []byte("checkpoint-key")
+
+func appendChainTestEntries(t *testing.T, s *Store, workItemID int64, n int) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		_, err := s.AppendJournal(ctx, &core.JournalEntry{
+			WorkItemID: workItemID,
+			Kind:       core.JournalToolCall,
+			Source:     core.JournalSourceAgent,
+			Summary:    "tool call",
+			Payload: map[string]any{
+				"n":      i,
+				"ratio":  0.25,
+				"nested": struct{ B, A int }{B: 2, A: 1},
+			},
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Millisecond),
+		})
+		if err != nil {
+			t.Fatalf("AppendJournal: %v", err)
+		}
+	}
+}
+
+func verifyJournal(t *testing.T, s *Store, key []byte) *core.ChainReport {
+	t.Helper()
+	report, err := s.VerifyJournalChain(context.Background(), key)
+	if err != nil {
+		t.Fatalf("VerifyJournalChain: %v", err)
+	}
+	return report
+}
+
+func TestJournalChainVerifies(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	appendChainTestEntries(t, s, 1, 3)
+	if err := s.BatchAppendJournal(ctx, []*core.JournalEntry{
+		{Kind: core.JournalSystem, Source: core.JournalSourceSystem, Summary: "a"},
+		{Kind: core.JournalSystem, Source: core.JournalSourceSystem, Summary: "b"},
+	}); err != nil {
+		t.Fatalf("BatchAppendJournal: %v", err)
+	}
+	cp, err := s.CreateJournalCheckpoint(ctx, testCheckpointKey)
+	if err != nil || cp == nil || cp.Seq != 5 {
+		t.Fatalf("CreateJournalCheckpoint = %+v, %v", cp, err)
+	}
+	if again, err := s.CreateJournalCheckpoint(ctx, testCheckpointKey); err != nil || again != nil {
+		t.Fatalf("checkpoint of unchanged head = %+v, %v", again, err)
+	}
+
+	report := verifyJournal(t, s, testCheckpointKey)
+	if !report.OK() || report.Entries != 5 || report.FirstSeq != 1 || report.LastSeq != 5 || report.Checkpoints != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// Deleting a work item's journal leaves an erasure entry behind.
+	appendChainTestEntries(t, s, 2, 2)
+	if err := s.DeleteJournalByWorkItem(ctx, 1); err != nil {
+		t.Fatalf("DeleteJournalByWorkItem: %v", err)
+	}
+	report = verifyJournal(t, s, testCheckpointKey)
+	if !report.OK() || report.Entries != 5 || report.LastSeq != 8 {
+		t.Fatalf("report after erasure = %+v (%v)", report, report.Break)
+	}
+}
+
+func TestJournalChainDetectsTampering(t *testing.T) {
+	cases := []struct {
+		name   string
+		tamper func(t *testing.T, s *Store)
+		key    []byte
+		want   core.ChainBreakKind
+		seq    int64
+	}{
+		{
+			name: "edited",
+			tamper: func(t *testing.T, s *Store) {
+				s.orm.Exec(`UPDATE activity_journal SET summary = 'rewritten' WHERE chain_seq = 2`)
+			},
+			want: core.ChainBreakEdited, seq: 2,
+		},
+		{
+			name: "deleted",
+			tamper: func(t *testing.T, s *Store) {
+				s.orm.Exec(`DELETE FROM activity_journal WHERE chain_seq = 2`)
+			},
+			want: core.ChainBreakGap, seq: 2,
+		},
+		{
+			name: "reordered",
+			tamper: func(t *testing.T, s *Store) {
+				s.orm.Exec(`UPDATE activity_journal SET id = 100 WHERE chain_seq = 1`)
+			},
+			want: core.ChainBreakReordered, seq: 1,
+		},
+		{
+			name: "truncated",
+			tamper: func(t *testing.T, s *Store) {
+				s.orm.Exec(`DELETE FROM activity_journal WHERE chain_seq = 3`)
+			},
+			want: core.ChainBreakTruncated, seq: 3,
+		},
+		{
+			name:   "wrong key",
+			tamper: func(t *testing.T, s *Store) {},
+			key:    []byte("other-key"),
+			want:   core.ChainBreakCheckpoint, seq: 3,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestStore(t)
+			appendChainTestEntries(t, s, 1, 3)
+			if _, err := s.CreateJournalCheckpoint(context.Background(), testCheckpointKey); err != nil {
+				t.Fatalf("CreateJournalCheckpoint: %v", err)
+			}
+			tc.tamper(t, s)
+			key := tc.key
+			if key == nil {
+				key = testCheckpointKey
+			}
+			report := verifyJournal(t, s, key)
+			if report.Break == nil || report.Break.Kind != tc.want || report.Break.Seq != tc.seq {
+				t.Fatalf("break = %+v, want %s at seq %d", report.Break, tc.want, tc.seq)
+			}
+		})
+	}
+}
+
+func TestJournalChainBackfill(t *testing.T) {
+	s := newTestStore(t)
+	// Rows written before the chain existed.
+	for _, summary := range []string{"old-1", "old-2"} {
+		model := journalModelFromCore(&core.JournalEntry{
+			Kind: core.JournalSystem, Source: core.JournalSourceSystem, Summary: summary,
+			Payload: map[string]any{"big": int64(1) << 60}, CreatedAt: time.Now().UTC(),
+		})
+		if err := s.orm.Create(model).Error; err != nil {
+			t.Fatalf("insert legacy row: %v", err)
+		}
+	}
+	if err := backfillJournalChain(s.orm); err != nil {
+		t.Fatalf("backfillJournalChain: %v", err)
+	}
+	appendChainTestEntries(t, s, 1, 1)
+	report := verifyJournal(t, s, nil)
+	if !report.OK() || report.Entries != 3 || report.SignaturesChecked {
+		t.Fatalf("report = %+v (%v)", report, report.Break)
+	}
+}
+
+func TestJournalRetentionPrunesChainPrefix(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	old, recent := time.Now().Add(-48*time.Hour), time.Now()
+	for _, at := range []time.Time{old, recent, old} {
+		if _, err := s.AppendJournal(ctx, &core.JournalEntry{Kind: core.JournalSystem, Source: core.JournalSourceSystem, CreatedAt: at}); err != nil {
+			t.Fatalf("AppendJournal: %v", err)
+		}
+	}
+	result, err := s.PruneExpired(ctx, core.RetentionPruneInput{Target: core.RetentionJournal, Before: time.Now().Add(-24 * time.Hour)})
+	if err != nil || result.Pruned != 1 {
+		t.Fatalf("PruneExpired = %+v, %v", result, err)
+	}
+	report := verifyJournal(t, s, nil)
+	if !report.OK() || report.FirstSeq != 2 || report.Entries != 2 {
+		t.Fatalf("report = %+v (%v)", report, report.Break)
+	}
+}
+
+type recordingJournalQueue struct {
+	entries []*core.JournalEntry
+	err     error
+}
+
+func (q *recordingJournalQueue) EnqueueJournal(_ context.Context, entry *core.JournalEntry) error {
+	if q.err != nil {
+		return q.err
+	}
+	q.entries = append(q.entries, entry)
+	return nil
+}
+
+func TestEnqueueJournalUsesQueueAndFallsBack(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	queue := &recordingJournalQueue{}
+	s.SetJournalQueue(queue)
+
+	id, err := s.CreateWorkItem(ctx, &core.WorkItem{Title: "queued", Status: core.WorkItemOpen})
+	if err != nil {
+		t.Fatalf("CreateWorkItem: %v", err)
+	}
+	if len(queue.entries) != 1 || queue.entries[0].WorkItemID != id || queue.entries[0].Kind != core.JournalStateChange {
+		t.Fatalf("queued entries = %+v, want the state change of work item %d", queue.entries, id)
+	}
+	if n, _ := s.CountJournal(ctx, core.JournalFilter{}); n != 0 {
+		t.Fatalf("journal rows = %d, want 0 while the entry is queued", n)
+	}
+
+	// Inside a transaction the entry must commit with it.
+	err = s.InTx(ctx, func(tx core.Store) error {
+		return tx.EnqueueJournal(ctx, &core.JournalEntry{Kind: core.JournalSystem, Source: core.JournalSourceSystem, Summary: "in tx"})
+	})
+	if err != nil {
+		t.Fatalf("InTx: %v", err)
+	}
+	queue.err = errors.New("queue closed")
+	if err := s.EnqueueJournal(ctx, &core.JournalEntry{Kind: core.JournalSystem, Source: core.JournalSourceSystem, Summary: "fallback"}); err != nil {
+		t.Fatalf("EnqueueJournal: %v", err)
+	}
+	if n, _ := s.CountJournal(ctx, core.JournalFilter{}); n != 2 || len(queue.entries) != 1 {
+		t.Fatalf("journal rows = %d, queued = %d; want 2 direct and 1 queued", n, len(queue.entries))
+	}
+}
+
+func TestStateJournalSnapshotsInsideTheUpdate(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	queue := &recordingJournalQueue{}
+	s.SetJournalQueue(queue)
+
+	id, err := s.CreateWorkItem(ctx, &core.WorkItem{Title: "journaled", Status: core.WorkItemOpen})
+	if err != nil {
+		t.Fatalf("CreateWorkItem: %v", err)
+	}
+	if err := s.PrepareWorkItemRun(ctx, id, core.WorkItemQueued); err != nil {
+		t.Fatalf("PrepareWorkItemRun: %v", err)
+	}
+	if len(queue.entries) != 2 {
+		t.Fatalf("queued entries = %d, want create and status change", len(queue.entries))
+	}
+	payload := queue.entries[1].Payload
+	from, _ := payload["from"].(map[string]any)
+	to, _ := payload["state"].(map[string]any)
+	if from["status"] != string(core.WorkItemOpen) || to["status"] != string(core.WorkItemQueued) {
+		t.Fatalf("status change payload = %+v, want open -> queued", payload)
+	}
+
+	// Rejected and missing-row updates journal nothing.
+	if err := s.PrepareWorkItemRun(ctx, id, core.WorkItemQueued); !errors.Is(err, core.ErrInvalidTransition) {
+		t.Fatalf("second PrepareWorkItemRun err = %v, want invalid transition", err)
+	}
+	if err := s.UpdateWorkItemStatus(ctx, id+100, core.WorkItemDone); !errors.Is(err, core.ErrNotFound) {
+		t.Fatalf("UpdateWorkItemStatus(missing) err = %v, want not found", err)
+	}
+	if err := s.UpdateActionStatus(ctx, 999, core.ActionDone); !errors.Is(err, core.ErrNotFound) {
+		t.Fatalf("UpdateActionStatus(missing) err = %v, want not found", err)
+	}
+	if len(queue.entries) != 2 {
+		t.Fatalf("queued entries = %d, want no entries for failed updates", len(queue.entries))
+	}
+}
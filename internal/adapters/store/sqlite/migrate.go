@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer binary.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// migration is one numbered, reversible schema change. up and down each run
+// inside a single transaction together with the schema_migrations update.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *gorm.DB) error
+	down    func(tx *gorm.DB) error
+}
+
+// SchemaMigrationModel records an applied migration.
+type SchemaMigrationModel struct {
+	Version   int       `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name;not null"`
+	AppliedAt time.Time `gorm:"column:applied_at;not null"`
+}
+
+func (SchemaMigrationModel) TableName() string { return "schema_migrations" }
+
+// MigrationStatus describes one known (or unknown, newer) migration.
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// MigrationStep is a migration applied or reverted by MigrateTo.
+type MigrationStep struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Down    bool   `json:"down,omitempty"`
+}
+
+// LatestSchemaVersion is the schema version this binary migrates to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// migrateToLatest brings orm up to LatestSchemaVersion, refusing databases
+// that are already past it.
+func migrateToLatest(ctx context.Context, orm *gorm.DB) error {
+	if orm == nil {
+		return fmt.Errorf("nil orm")
+	}
+	_, err := migrateTo(ctx, orm, LatestSchemaVersion())
+	return err
+}
+
+func ensureMigrationTable(ctx context.Context, orm *gorm.DB) error {
+	if err := orm.WithContext(ctx).AutoMigrate(&SchemaMigrationModel{}); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func appliedMigrations(ctx context.Context, orm *gorm.DB) ([]SchemaMigrationModel, error) {
+	if err := ensureMigrationTable(ctx, orm); err != nil {
+		return nil, err
+	}
+	var rows []SchemaMigrationModel
+	if err := orm.WithContext(ctx).Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("list schema_migrations: %w", err)
+	}
+	return rows, nil
+}
+
+func currentVersion(rows []SchemaMigrationModel) int {
+	if len(rows) == 0 {
+		return 0
+	}
+	return rows[len(rows)-1].Version
+}
+
+func migrateTo(ctx context.Context, orm *gorm.DB, target int) ([]MigrationStep, error) {
+	latest := LatestSchemaVersion()
+	if target < 0 || target > latest {
+		return nil, fmt.Errorf("unknown schema version %d (latest is %d)", target, latest)
+	}
+	rows, err := appliedMigrations(ctx, orm)
+	if err != nil {
+		return nil, err
+	}
+	current := currentVersion(rows)
+	if current > latest {
+		return nil, fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrSchemaTooNew, current, latest)
+	}
+
+	var steps []MigrationStep
+	for _, m := range migrations {
+		if m.version <= current || m.version > target {
+			continue
+		}
+		err := orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigrationModel{Version: m.version, Name: m.name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return steps, fmt.Errorf("migrate up to %d (%s): %w", m.version, m.name, err)
+		}
+		steps = append(steps, MigrationStep{Version: m.version, Name: m.name})
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version > current || m.version <= target {
+			continue
+		}
+		err := orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigrationModel{}, m.version).Error
+		})
+		if err != nil {
+			return steps, fmt.Errorf("migrate down from %d (%s): %w", m.version, m.name, err)
+		}
+		steps = append(steps, MigrationStep{Version: m.version, Name: m.name, Down: true})
+	}
+	return steps, nil
+}
+
+// SchemaVersion returns the highest applied migration version.
+func (s *Store) SchemaVersion(ctx context.Context) (int, error) {
+	rows, err := appliedMigrations(ctx, s.orm)
+	if err != nil {
+		return 0, err
+	}
+	return currentVersion(rows), nil
+}
+
+// MigrationStatus lists every migration known to this binary plus any newer
+// versions recorded in the database.
+func (s *Store) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	rows, err := appliedMigrations(ctx, s.orm)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaMigrationModel, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	out := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Version: m.version, Name: m.name}
+		if row, ok := applied[m.version]; ok {
+			at := row.AppliedAt
+			st.Applied, st.AppliedAt = true, &at
+			delete(applied, m.version)
+		}
+		out = append(out, st)
+	}
+	for _, row := range rows {
+		if _, unknown := applied[row.Version]; unknown {
+			at := row.AppliedAt
+			out = append(out, MigrationStatus{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: &at})
+		}
+	}
+	return out, nil
+}
+
+// MigrateTo applies or reverts migrations until the database is at target.
+func (s *Store) MigrateTo(ctx context.Context, target int) ([]MigrationStep, error) {
+	return migrateTo(ctx, s.orm, target)
+}
+
+// VacuumInto writes a consistent copy of the database to dst, which must not
+// exist yet.
+func (s *Store) VacuumInto(ctx context.Context, dst string) error {
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", dst); err != nil {
+		return fmt.Errorf("snapshot database to %s: %w", dst, err)
+	}
+	return nil
+}
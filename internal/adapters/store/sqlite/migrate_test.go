@@ -0,0 +1,234 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata golden files")
+
+// dumpSchema returns the normalized DDL of every user table and index.
+func dumpSchema(t *testing.T, db *sql.DB) string {
+	t.Helper()
+	rows, err := db.Query(`SELECT sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY type DESC, name`)
+	if err != nil {
+		t.Fatalf("dump schema: %v", err)
+	}
+	defer rows.Close()
+	var b strings.Builder
+	for rows.Next() {
+		var ddl string
+		if err := rows.Scan(&ddl); err != nil {
+			t.Fatalf("scan schema: %v", err)
+		}
+		b.WriteString(ddl)
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+func schemaObjects(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT type || ':' || name FROM sqlite_master WHERE name NOT LIKE 'sqlite_%' ORDER BY 1`)
+	if err != nil {
+		t.Fatalf("list schema objects: %v", err)
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan schema object: %v", err)
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+func loadFixture(t *testing.T, path, fixture string) {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open fixture db: %v", err)
+	}
+	defer db.Close()
+	for _, stmt := range strings.Split(string(raw), ";\n") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("load fixture statement %q: %v", stmt, err)
+		}
+	}
+}
+
+func TestFreshSchemaMatchesGolden(t *testing.T) {
+	s := newTestStore(t)
+	got := dumpSchema(t, s.db)
+	golden := filepath.Join("testdata", "schema_latest.golden.sql")
+	if *updateGolden {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden (run with -update to create): %v", err)
+	}
+	if got != string(want) {
+		t.Fatalf("schema drifted from %s; add a migration and rerun with -update.\n--- got ---\n%s", golden, got)
+	}
+	if v, err := s.SchemaVersion(context.Background()); err != nil || v != LatestSchemaVersion() {
+		t.Fatalf("SchemaVersion = %d, %v; want %d", v, err, LatestSchemaVersion())
+	}
+}
+
+func TestMigrateLegacyFixtureToLatest(t *testing.T) {
+	dir := t.TempDir()
+	legacyPath := filepath.Join(dir, "legacy.db")
+	loadFixture(t, legacyPath, "schema_v0.sql")
+
+	s, err := New(legacyPath)
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+	defer s.Close()
+	fresh := newTestStore(t)
+
+	if got, want := strings.Join(schemaObjects(t, s.db), "\n"), strings.Join(schemaObjects(t, fresh.db), "\n"); got != want {
+		t.Fatalf("migrated schema objects differ from fresh schema:\n%s\n--- want ---\n%s", got, want)
+	}
+
+	ctx := context.Background()
+	item, err := s.GetWorkItem(ctx, 1)
+	if err != nil {
+		t.Fatalf("get legacy work item: %v", err)
+	}
+	if item.ResourceSpaceID == nil || *item.ResourceSpaceID != 3 {
+		t.Fatalf("resource_space_id = %v, want backfilled 3", item.ResourceSpaceID)
+	}
+	totals, err := s.UsageTotals(ctx, core.AnalyticsFilter{})
+	if err != nil || totals.RunCount != 1 || totals.TotalTokens != 15 {
+		t.Fatalf("usage totals = %+v, %v", totals, err)
+	}
+
+	status, err := s.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	for _, st := range status {
+		if !st.Applied || st.AppliedAt == nil {
+			t.Fatalf("migration %d (%s) not applied", st.Version, st.Name)
+		}
+	}
+}
+
+func TestBaselineAddsColumnsMissingFromLegacyTables(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("CREATE TABLE `projects` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`kind` text NOT NULL,`description` text NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+	defer s.Close()
+	if _, err := s.CreateProject(context.Background(), &core.Project{Name: "legacy", Kind: core.ProjectGeneral, Metadata: map[string]string{"k": "v"}}); err != nil {
+		t.Fatalf("create project on adopted table: %v", err)
+	}
+}
+
+func TestVacuumIntoWritesSnapshot(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	if _, err := s.CreateProject(ctx, &core.Project{Name: "kept", Kind: core.ProjectGeneral}); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(t.TempDir(), "snapshot.db")
+	if err := s.VacuumInto(ctx, dst); err != nil {
+		t.Fatalf("VacuumInto: %v", err)
+	}
+	snap, err := New(dst)
+	if err != nil {
+		t.Fatalf("open snapshot: %v", err)
+	}
+	defer snap.Close()
+	if projects, err := snap.ListProjects(ctx, 10, 0); err != nil || len(projects) != 1 {
+		t.Fatalf("snapshot projects = %v, %v", projects, err)
+	}
+}
+
+func TestMigrateDownAndUpRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	want := dumpSchema(t, s.db)
+
+	steps, err := s.MigrateTo(ctx, 0)
+	if err != nil {
+		t.Fatalf("MigrateTo(0): %v", err)
+	}
+	if len(steps) != LatestSchemaVersion() || !steps[0].Down || steps[0].Version != LatestSchemaVersion() {
+		t.Fatalf("down steps = %+v", steps)
+	}
+	if objects := schemaObjects(t, s.db); len(objects) != 1 || objects[0] != "table:schema_migrations" {
+		t.Fatalf("objects after full rollback = %v", objects)
+	}
+
+	if _, err := s.MigrateTo(ctx, LatestSchemaVersion()); err != nil {
+		t.Fatalf("MigrateTo(latest): %v", err)
+	}
+	if got := dumpSchema(t, s.db); got != want {
+		t.Fatalf("schema after round trip differs:\n%s\n--- want ---\n%s", got, want)
+	}
+	if _, err := s.MigrateTo(ctx, LatestSchemaVersion()+1); err == nil {
+		t.Fatal("expected error for unknown target version")
+	}
+}
+
+func TestNewRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "newer.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	future := LatestSchemaVersion() + 1
+	if err := s.orm.Create(&SchemaMigrationModel{Version: future, Name: "from_the_future"}).Error; err != nil {
+		t.Fatalf("record future migration: %v", err)
+	}
+	s.Close()
+
+	if _, err := New(path); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("New() error = %v, want ErrSchemaTooNew", err)
+	}
+
+	raw, err := NewWithOptions(path, OpenOptions{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("open without migrations: %v", err)
+	}
+	defer raw.Close()
+	status, err := raw.MigrationStatus(context.Background())
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if last := status[len(status)-1]; last.Version != future || last.Name != "from_the_future" {
+		t.Fatalf("status tail = %+v, want the unknown future migration", last)
+	}
+}
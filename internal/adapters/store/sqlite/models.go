@@ -77,6 +77,7 @@ type WorkItemModel struct {
 	EscalationPath     JSONField[[]string]       `gorm:"column:escalation_path;type:text"`
 	Metadata           JSONField[map[string]any] `gorm:"column:metadata;type:text"`
 	ArchivedAt         *time.Time                `gorm:"column:archived_at"`
+	Version            int64                     `gorm:"column:version;not null;default:1"`
 	CreatedAt          time.Time                 `gorm:"column:created_at"`
 	UpdatedAt          time.Time                 `gorm:"column:updated_at"`
 }
@@ -100,6 +101,7 @@ type ActionModel struct {
 	Config               JSONField[map[string]any] `gorm:"column:config;type:text"`
 	MaxRetries           int                       `gorm:"column:max_retries"`
 	RetryCount           int                       `gorm:"column:retry_count"`
+	Version              int64                     `gorm:"column:version;not null;default:1"`
 	CreatedAt            time.Time                 `gorm:"column:created_at"`
 	UpdatedAt            time.Time                 `gorm:"column:updated_at"`
 }
@@ -163,6 +165,7 @@ func (AgentContextModel) TableName() string { return "agent_contexts" }
 
 type EventModel struct {
 	ID         int64                     `gorm:"column:id;primaryKey;autoIncrement"`
+	Seq        int64                     `gorm:"column:seq;not null;default:0"`
 	Type       string                    `gorm:"column:type;not null"`
 	Category   string                    `gorm:"column:category;not null;default:domain"`
 	WorkItemID *int64                    `gorm:"column:work_item_id"`
@@ -205,6 +208,7 @@ type DAGTemplateModel struct {
 	Tags        JSONField[[]string]                 `gorm:"column:tags;type:text"`
 	Metadata    JSONField[map[string]string]        `gorm:"column:metadata;type:text"`
 	Actions     JSONField[[]core.DAGTemplateAction] `gorm:"column:actions;type:text"`
+	Version     int64                               `gorm:"column:version;not null;default:1"`
 	CreatedAt   time.Time                           `gorm:"column:created_at"`
 	UpdatedAt   time.Time                           `gorm:"column:updated_at"`
 }
@@ -241,6 +245,7 @@ type ThreadModel struct {
 	OwnerID        string                    `gorm:"column:owner_id;not null"`
 	FocusProjectID int64                     `gorm:"column:focus_project_id"`
 	Metadata       JSONField[map[string]any] `gorm:"column:metadata;type:text"`
+	Version        int64                     `gorm:"column:version;not null;default:1"`
 	CreatedAt      time.Time                 `gorm:"column:created_at"`
 	UpdatedAt      time.Time                 `gorm:"column:updated_at"`
 }
@@ -258,6 +263,7 @@ func threadModelFromCore(t *core.Thread) *ThreadModel {
 		OwnerID:        t.OwnerID,
 		FocusProjectID: t.FocusProjectID,
 		Metadata:       JSONField[map[string]any]{Data: t.Metadata},
+		Version:        t.Version,
 		CreatedAt:      t.CreatedAt,
 		UpdatedAt:      t.UpdatedAt,
 	}
@@ -274,6 +280,7 @@ func (m *ThreadModel) toCore() *core.Thread {
 		OwnerID:        m.OwnerID,
 		FocusProjectID: m.FocusProjectID,
 		Metadata:       m.Metadata.Data,
+		Version:        m.Version,
 		CreatedAt:      m.CreatedAt,
 		UpdatedAt:      m.UpdatedAt,
 	}
@@ -527,6 +534,7 @@ type ThreadProposalModel struct {
 	SourceMessageID *int64                                  `gorm:"column:source_message_id"`
 	InitiativeID    *int64                                  `gorm:"column:initiative_id"`
 	Metadata        JSONField[map[string]any]               `gorm:"column:metadata;type:text"`
+	Version         int64                                   `gorm:"column:version;not null;default:1"`
 	CreatedAt       time.Time                               `gorm:"column:created_at"`
 	UpdatedAt       time.Time                               `gorm:"column:updated_at"`
 }
@@ -552,6 +560,7 @@ func (m *ThreadProposalModel) toCore() *core.ThreadProposal {
 		SourceMessageID: m.SourceMessageID,
 		InitiativeID:    m.InitiativeID,
 		Metadata:        m.Metadata.Data,
+		Version:         m.Version,
 		CreatedAt:       m.CreatedAt,
 		UpdatedAt:       m.UpdatedAt,
 	}
@@ -576,6 +585,7 @@ func threadProposalModelFromCore(proposal *core.ThreadProposal) *ThreadProposalM
 		SourceMessageID: proposal.SourceMessageID,
 		InitiativeID:    proposal.InitiativeID,
 		Metadata:        JSONField[map[string]any]{Data: proposal.Metadata},
+		Version:         proposal.Version,
 		CreatedAt:       proposal.CreatedAt,
 		UpdatedAt:       proposal.UpdatedAt,
 	}
@@ -750,6 +760,10 @@ type JournalModel struct {
 	Actor          string                    `gorm:"column:actor;not null;default:''"`
 	SourceActionID *int64                    `gorm:"column:source_action_id"`
 	CreatedAt      time.Time                 `gorm:"column:created_at"`
+	// Hash chain (see journal_chain.go).
+	ChainSeq int64  `gorm:"column:chain_seq;not null;default:0"`
+	PrevHash string `gorm:"column:prev_hash;not null;default:''"`
+	Hash     string `gorm:"column:hash;not null;default:''"`
 }
 
 func (JournalModel) TableName() string { return "activity_journal" }
@@ -822,6 +836,7 @@ type ActionSignalModel struct {
 	SourceActionID *int64                    `gorm:"column:source_action_id"`
 	Payload        JSONField[map[string]any] `gorm:"column:payload;type:text"`
 	Actor          string                    `gorm:"column:actor;not null"`
+	ActorUserID    int64                     `gorm:"column:actor_user_id;not null;default:0"`
 	CreatedAt      time.Time                 `gorm:"column:created_at"`
 }
 
@@ -843,6 +858,7 @@ func actionSignalModelFromCore(s *core.ActionSignal) *ActionSignalModel {
 		SourceActionID: int64PtrIfNonZero(s.SourceActionID),
 		Payload:        JSONField[map[string]any]{Data: s.Payload},
 		Actor:          s.Actor,
+		ActorUserID:    s.ActorUserID,
 		CreatedAt:      s.CreatedAt,
 	}
 }
@@ -852,16 +868,17 @@ func (m *ActionSignalModel) toCore() *core.ActionSignal {
 		return nil
 	}
 	sig := &core.ActionSignal{
-		ID:         m.ID,
-		ActionID:   m.ActionID,
-		WorkItemID: m.WorkItemID,
-		Type:       core.SignalType(m.Type),
-		Source:     core.SignalSource(m.Source),
-		Summary:    m.Summary,
-		Content:    m.Content,
-		Payload:    m.Payload.Data,
-		Actor:      m.Actor,
-		CreatedAt:  m.CreatedAt,
+		ID:          m.ID,
+		ActionID:    m.ActionID,
+		WorkItemID:  m.WorkItemID,
+		Type:        core.SignalType(m.Type),
+		Source:      core.SignalSource(m.Source),
+		Summary:     m.Summary,
+		Content:     m.Content,
+		Payload:     m.Payload.Data,
+		Actor:       m.Actor,
+		ActorUserID: m.ActorUserID,
+		CreatedAt:   m.CreatedAt,
 	}
 	if m.RunID != nil {
 		sig.RunID = *m.RunID
@@ -927,6 +944,7 @@ func workItemModelFromCore(w *core.WorkItem) *WorkItemModel {
 		EscalationPath:     JSONField[[]string]{Data: w.EscalationPath},
 		Metadata:           JSONField[map[string]any]{Data: w.Metadata},
 		ArchivedAt:         w.ArchivedAt,
+		Version:            w.Version,
 		CreatedAt:          w.CreatedAt,
 		UpdatedAt:          w.UpdatedAt,
 	}
@@ -957,6 +975,7 @@ func (m *WorkItemModel) toCore() *core.WorkItem {
 		EscalationPath:     m.EscalationPath.Data,
 		Metadata:           m.Metadata.Data,
 		ArchivedAt:         m.ArchivedAt,
+		Version:            m.Version,
 		CreatedAt:          m.CreatedAt,
 		UpdatedAt:          m.UpdatedAt,
 	}
@@ -983,6 +1002,7 @@ func actionModelFromCore(action *core.Action) *ActionModel {
 		Config:               JSONField[map[string]any]{Data: action.Config},
 		MaxRetries:           action.MaxRetries,
 		RetryCount:           action.RetryCount,
+		Version:              action.Version,
 		CreatedAt:            action.CreatedAt,
 		UpdatedAt:            action.UpdatedAt,
 	}
@@ -1009,6 +1029,7 @@ func (m *ActionModel) toCore() *core.Action {
 		Config:               m.Config.Data,
 		MaxRetries:           m.MaxRetries,
 		RetryCount:           m.RetryCount,
+		Version:              m.Version,
 		CreatedAt:            m.CreatedAt,
 		UpdatedAt:            m.UpdatedAt,
 	}
@@ -1152,6 +1173,7 @@ func eventModelFromCore(event *core.Event) *EventModel {
 	}
 	return &EventModel{
 		ID:         event.ID,
+		Seq:        event.Seq,
 		Type:       string(event.Type),
 		Category:   category,
 		WorkItemID: int64PtrIfNonZero(event.WorkItemID),
@@ -1168,6 +1190,7 @@ func (m *EventModel) toCore() *core.Event {
 	}
 	event := &core.Event{
 		ID:        m.ID,
+		Seq:       m.Seq,
 		Type:      core.EventType(m.Type),
 		Category:  m.Category,
 		Data:      m.Data.Data,
@@ -1256,6 +1279,7 @@ func dagTemplateModelFromCore(t *core.DAGTemplate) *DAGTemplateModel {
 		Tags:        JSONField[[]string]{Data: t.Tags},
 		Metadata:    JSONField[map[string]string]{Data: t.Metadata},
 		Actions:     JSONField[[]core.DAGTemplateAction]{Data: t.Actions},
+		Version:     t.Version,
 		CreatedAt:   t.CreatedAt,
 		UpdatedAt:   t.UpdatedAt,
 	}
@@ -1273,6 +1297,7 @@ func (m *DAGTemplateModel) toCore() *core.DAGTemplate {
 		Tags:        m.Tags.Data,
 		Metadata:    m.Metadata.Data,
 		Actions:     m.Actions.Data,
+		Version:     m.Version,
 		CreatedAt:   m.CreatedAt,
 		UpdatedAt:   m.UpdatedAt,
 	}
@@ -108,12 +108,17 @@ func (s *Store) UpdateProject(ctx context.Context, p *core.Project) error {
 }
 
 func (s *Store) DeleteProject(ctx context.Context, id int64) error {
-	result := s.orm.WithContext(ctx).Delete(&ProjectModel{}, id)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
-	}
-	return nil
+	return s.writeTx(ctx, func(tx *gorm.DB) error {
+		result := tx.Delete(&ProjectModel{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return core.ErrNotFound
+		}
+		if err := tx.Where("project_id = ?", id).Delete(&ProjectMemberModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("project_id = ?", id).Delete(&VaultSecretModel{}).Error
+	})
 }
@@ -46,6 +46,7 @@ func (s *Store) CreateThreadProposal(ctx context.Context, proposal *core.ThreadP
 	proposal.Summary = model.Summary
 	proposal.Content = model.Content
 	proposal.ProposedBy = model.ProposedBy
+	proposal.Version = 1
 	proposal.CreatedAt = now
 	proposal.UpdatedAt = now
 	return model.ID, nil
@@ -115,30 +116,26 @@ func (s *Store) UpdateThreadProposal(ctx context.Context, proposal *core.ThreadP
 	}
 
 	now := time.Now().UTC()
-	result := s.orm.WithContext(ctx).Model(&ThreadProposalModel{}).
-		Where("id = ?", proposal.ID).
-		Updates(map[string]any{
-			"thread_id":         proposal.ThreadID,
-			"title":             title,
-			"summary":           strings.TrimSpace(proposal.Summary),
-			"content":           strings.TrimSpace(proposal.Content),
-			"proposed_by":       strings.TrimSpace(proposal.ProposedBy),
-			"status":            string(proposal.Status),
-			"reviewed_by":       proposal.ReviewedBy,
-			"reviewed_at":       proposal.ReviewedAt,
-			"review_note":       proposal.ReviewNote,
-			"work_item_drafts":  JSONField[[]core.ProposalWorkItemDraft]{Data: proposal.WorkItemDrafts},
-			"source_message_id": proposal.SourceMessageID,
-			"initiative_id":     proposal.InitiativeID,
-			"metadata":          JSONField[map[string]any]{Data: proposal.Metadata},
-			"updated_at":        now,
-		})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
+	version, err := versionedUpdate(s.orm.WithContext(ctx), &ThreadProposalModel{}, "proposal", proposal.ID, proposal.Version, map[string]any{
+		"thread_id":         proposal.ThreadID,
+		"title":             title,
+		"summary":           strings.TrimSpace(proposal.Summary),
+		"content":           strings.TrimSpace(proposal.Content),
+		"proposed_by":       strings.TrimSpace(proposal.ProposedBy),
+		"status":            string(proposal.Status),
+		"reviewed_by":       proposal.ReviewedBy,
+		"reviewed_at":       proposal.ReviewedAt,
+		"review_note":       proposal.ReviewNote,
+		"work_item_drafts":  JSONField[[]core.ProposalWorkItemDraft]{Data: proposal.WorkItemDrafts},
+		"source_message_id": proposal.SourceMessageID,
+		"initiative_id":     proposal.InitiativeID,
+		"metadata":          JSONField[map[string]any]{Data: proposal.Metadata},
+		"updated_at":        now,
+	})
+	if err != nil {
+		return err
 	}
+	proposal.Version = version
 	proposal.Title = title
 	proposal.UpdatedAt = now
 	return nil
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/yoke233/zhanggui/internal/core"
@@ -48,6 +49,27 @@ func (s *Store) ListResourcesByMessage(ctx context.Context, messageID int64) ([]
 	return s.listResources(ctx, "message_id = ?", messageID)
 }
 
+// ListProvenanceResources returns the provenance envelopes stored for a
+// commit, oldest first. An abbreviated hash matches every commit it prefixes.
+func (s *Store) ListProvenanceResources(ctx context.Context, commitSHA string) ([]*core.Resource, error) {
+	commitSHA = strings.ToLower(strings.TrimSpace(commitSHA))
+	if commitSHA == "" || strings.ContainsAny(commitSHA, "%_") {
+		return nil, fmt.Errorf("commit sha is required")
+	}
+	var models []ResourceModel
+	if err := s.orm.WithContext(ctx).
+		Where("role = ? AND file_name LIKE ?", core.ResourceRoleProvenance, "provenance-"+commitSHA+"%").
+		Order("id ASC").
+		Find(&models).Error; err != nil {
+		return nil, err
+	}
+	out := make([]*core.Resource, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
+
 func (s *Store) DeleteResource(ctx context.Context, id int64) error {
 	result := s.orm.WithContext(ctx).Delete(&ResourceModel{}, id)
 	if result.Error != nil {
@@ -0,0 +1,356 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+)
+
+const defaultRetentionBatchSize = 500
+
+// RetentionRollupModel keeps daily row counts for pruned event_log and
+// activity_journal rows.
+type RetentionRollupModel struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Day       time.Time `gorm:"column:day;not null;uniqueIndex:idx_retention_rollups_key"`
+	Source    string    `gorm:"column:source;not null;uniqueIndex:idx_retention_rollups_key"`
+	Key       string    `gorm:"column:key;not null;uniqueIndex:idx_retention_rollups_key"`
+	Count     int64     `gorm:"column:count;not null"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (RetentionRollupModel) TableName() string { return "retention_daily_rollups" }
+
+// UsageRollupModel keeps daily token totals for pruned usage_records so the
+// usage analytics keep covering the full history.
+type UsageRollupModel struct {
+	ID               int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Day              time.Time `gorm:"column:day;not null;uniqueIndex:idx_usage_rollups_key"`
+	ProjectID        int64     `gorm:"column:project_id;not null;default:0;uniqueIndex:idx_usage_rollups_key"`
+	AgentID          string    `gorm:"column:agent_id;not null;uniqueIndex:idx_usage_rollups_key"`
+	ProfileID        string    `gorm:"column:profile_id;not null;uniqueIndex:idx_usage_rollups_key"`
+	ModelID          string    `gorm:"column:model_id;not null;uniqueIndex:idx_usage_rollups_key"`
+	RunCount         int64     `gorm:"column:run_count;not null"`
+	InputTokens      int64     `gorm:"column:input_tokens;not null"`
+	OutputTokens     int64     `gorm:"column:output_tokens;not null"`
+	CacheReadTokens  int64     `gorm:"column:cache_read_tokens;not null"`
+	CacheWriteTokens int64     `gorm:"column:cache_write_tokens;not null"`
+	ReasoningTokens  int64     `gorm:"column:reasoning_tokens;not null"`
+	TotalTokens      int64     `gorm:"column:total_tokens;not null"`
+	DurationMs       int64     `gorm:"column:duration_ms;not null"`
+	UpdatedAt        time.Time `gorm:"column:updated_at"`
+}
+
+func (UsageRollupModel) TableName() string { return "usage_daily_rollups" }
+
+type retentionRollupKey struct {
+	day time.Time
+	key string
+}
+
+type usageRollupKey struct {
+	day       time.Time
+	projectID int64
+	agentID   string
+	profileID string
+	modelID   string
+}
+
+func rollupDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// CountExpired returns how many rows of target are older than before.
+func (s *Store) CountExpired(ctx context.Context, target core.RetentionTarget, before time.Time) (int64, error) {
+	query, err := s.expiredQuery(ctx, target, before)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	if err := query.Count(&n).Error; err != nil {
+		return 0, fmt.Errorf("count expired %s: %w", target, err)
+	}
+	return n, nil
+}
+
+func (s *Store) expiredQuery(ctx context.Context, target core.RetentionTarget, before time.Time) (*gorm.DB, error) {
+	db := s.orm.WithContext(ctx)
+	switch target {
+	case core.RetentionEventLog:
+		return db.Model(&EventModel{}).Where("timestamp < ?", before), nil
+	case core.RetentionJournal:
+		// Only a prefix of the hash chain may go: stop at the first entry
+		// that is still retained.
+		return db.Model(&JournalModel{}).Where("created_at < ?", before).
+			Where("chain_seq < COALESCE((SELECT MIN(chain_seq) FROM activity_journal WHERE created_at >= ?), 9223372036854775807)", before), nil
+	case core.RetentionUsage:
+		return db.Model(&UsageRecordModel{}).Where("created_at < ?", before), nil
+	case core.RetentionRunOutput:
+		return db.Model(&RunModel{}).
+			Where("output IS NOT NULL AND output != ''").
+			Where("COALESCE(finished_at, created_at) < ?", before), nil
+	default:
+		return nil, fmt.Errorf("unknown retention target %q", target)
+	}
+}
+
+// PruneExpired removes rows older than in.Before in batches. Each batch is
+// handed to in.Archive first (if set), then rolled up into the daily
+// aggregate tables and deleted in one transaction. run_output only clears the
+// output column; the runs themselves are kept.
+func (s *Store) PruneExpired(ctx context.Context, in core.RetentionPruneInput) (core.RetentionPruneResult, error) {
+	var result core.RetentionPruneResult
+	if !in.Target.Valid() {
+		return result, fmt.Errorf("unknown retention target %q", in.Target)
+	}
+	batchSize := in.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRetentionBatchSize
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		n, err := s.pruneBatch(ctx, in, batchSize, &result)
+		if err != nil {
+			return result, fmt.Errorf("prune %s: %w", in.Target, err)
+		}
+		if n < batchSize {
+			return result, nil
+		}
+	}
+}
+
+func (s *Store) pruneBatch(ctx context.Context, in core.RetentionPruneInput, batchSize int, result *core.RetentionPruneResult) (int, error) {
+	query, err := s.expiredQuery(ctx, in.Target, in.Before)
+	if err != nil {
+		return 0, err
+	}
+	query = query.Order("id").Limit(batchSize)
+
+	switch in.Target {
+	case core.RetentionEventLog:
+		var models []EventModel
+		if err := query.Find(&models).Error; err != nil {
+			return 0, err
+		}
+		if len(models) == 0 {
+			return 0, nil
+		}
+		ids := make([]int64, 0, len(models))
+		records := make([]any, 0, len(models))
+		counts := map[retentionRollupKey]int64{}
+		var maxSeq int64
+		for i := range models {
+			ev := models[i].toCore()
+			ids = append(ids, ev.ID)
+			if ev.Seq > maxSeq {
+				maxSeq = ev.Seq
+			}
+			records = append(records, ev)
+			counts[retentionRollupKey{day: rollupDay(ev.Timestamp), key: string(ev.Type)}]++
+			if ev.Type == core.EventRunAudit {
+				if ref, _ := ev.Data["log_ref"].(string); strings.TrimSpace(ref) != "" {
+					result.AuditLogRefs = append(result.AuditLogRefs, strings.TrimSpace(ref))
+				}
+			}
+		}
+		if err := archiveBatch(in.Archive, records); err != nil {
+			return 0, err
+		}
+		err := s.writeTx(ctx, func(tx *gorm.DB) error {
+			if err := upsertRetentionRollups(tx, string(in.Target), counts); err != nil {
+				return err
+			}
+			// Remember how far the log was pruned so cursor replays from
+			// before it resync instead of silently skipping rows.
+			if err := tx.Exec(`UPDATE event_seq_state SET pruned_seq = MAX(pruned_seq, ?) WHERE id = ?`, maxSeq, eventSeqStateID).Error; err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&EventModel{}).Error
+		})
+		if err != nil {
+			return 0, err
+		}
+		result.Pruned += int64(len(ids))
+		result.RolledUp += int64(len(counts))
+		return len(models), nil
+
+	case core.RetentionJournal:
+		var models []JournalModel
+		if err := query.Find(&models).Error; err != nil {
+			return 0, err
+		}
+		if len(models) == 0 {
+			return 0, nil
+		}
+		ids := make([]int64, 0, len(models))
+		records := make([]any, 0, len(models))
+		counts := map[retentionRollupKey]int64{}
+		for i := range models {
+			entry := models[i].toCore()
+			ids = append(ids, entry.ID)
+			records = append(records, entry)
+			counts[retentionRollupKey{day: rollupDay(entry.CreatedAt), key: string(entry.Kind)}]++
+		}
+		if err := archiveBatch(in.Archive, records); err != nil {
+			return 0, err
+		}
+		err := s.writeTx(ctx, func(tx *gorm.DB) error {
+			if err := upsertRetentionRollups(tx, string(in.Target), counts); err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&JournalModel{}).Error
+		})
+		if err != nil {
+			return 0, err
+		}
+		result.Pruned += int64(len(ids))
+		result.RolledUp += int64(len(counts))
+		return len(models), nil
+
+	case core.RetentionUsage:
+		var models []UsageRecordModel
+		if err := query.Find(&models).Error; err != nil {
+			return 0, err
+		}
+		if len(models) == 0 {
+			return 0, nil
+		}
+		ids := make([]int64, 0, len(models))
+		records := make([]any, 0, len(models))
+		sums := map[usageRollupKey]*UsageRollupModel{}
+		for i := range models {
+			rec := models[i].toCore()
+			ids = append(ids, rec.ID)
+			records = append(records, rec)
+			key := usageRollupKey{day: rollupDay(rec.CreatedAt), agentID: rec.AgentID, profileID: rec.ProfileID, modelID: rec.ModelID}
+			if rec.ProjectID != nil {
+				key.projectID = *rec.ProjectID
+			}
+			sum := sums[key]
+			if sum == nil {
+				sum = &UsageRollupModel{Day: key.day, ProjectID: key.projectID, AgentID: key.agentID, ProfileID: key.profileID, ModelID: key.modelID}
+				sums[key] = sum
+			}
+			sum.RunCount++
+			sum.InputTokens += rec.InputTokens
+			sum.OutputTokens += rec.OutputTokens
+			sum.CacheReadTokens += rec.CacheReadTokens
+			sum.CacheWriteTokens += rec.CacheWriteTokens
+			sum.ReasoningTokens += rec.ReasoningTokens
+			sum.TotalTokens += rec.TotalTokens
+			sum.DurationMs += rec.DurationMs
+		}
+		if err := archiveBatch(in.Archive, records); err != nil {
+			return 0, err
+		}
+		err := s.writeTx(ctx, func(tx *gorm.DB) error {
+			if err := upsertUsageRollups(tx, sums); err != nil {
+				return err
+			}
+			return tx.Where("id IN ?", ids).Delete(&UsageRecordModel{}).Error
+		})
+		if err != nil {
+			return 0, err
+		}
+		result.Pruned += int64(len(ids))
+		result.RolledUp += int64(len(sums))
+		return len(models), nil
+
+	case core.RetentionRunOutput:
+		var models []RunModel
+		if err := query.Select("id", "output").Find(&models).Error; err != nil {
+			return 0, err
+		}
+		if len(models) == 0 {
+			return 0, nil
+		}
+		ids := make([]int64, 0, len(models))
+		records := make([]any, 0, len(models))
+		for _, m := range models {
+			ids = append(ids, m.ID)
+			records = append(records, map[string]any{"run_id": m.ID, "output": m.Output.Data})
+		}
+		if err := archiveBatch(in.Archive, records); err != nil {
+			return 0, err
+		}
+		if err := s.orm.WithContext(ctx).Model(&RunModel{}).Where("id IN ?", ids).Update("output", nil).Error; err != nil {
+			return 0, err
+		}
+		result.Pruned += int64(len(ids))
+		return len(models), nil
+	}
+	return 0, nil
+}
+
+func archiveBatch(archive core.RetentionArchiveFunc, records []any) error {
+	if archive == nil {
+		return nil
+	}
+	if err := archive(records); err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	return nil
+}
+
+func upsertRetentionRollups(tx *gorm.DB, source string, counts map[retentionRollupKey]int64) error {
+	now := time.Now().UTC()
+	for key, n := range counts {
+		err := tx.Exec(`INSERT INTO retention_daily_rollups (day, source, key, count, updated_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(day, source, key) DO UPDATE SET count = count + excluded.count, updated_at = excluded.updated_at`,
+			key.day, source, key.key, n, now).Error
+		if err != nil {
+			return fmt.Errorf("upsert %s rollup: %w", source, err)
+		}
+	}
+	return nil
+}
+
+func upsertUsageRollups(tx *gorm.DB, sums map[usageRollupKey]*UsageRollupModel) error {
+	now := time.Now().UTC()
+	for _, sum := range sums {
+		err := tx.Exec(`INSERT INTO usage_daily_rollups
+			(day, project_id, agent_id, profile_id, model_id, run_count, input_tokens, output_tokens,
+			 cache_read_tokens, cache_write_tokens, reasoning_tokens, total_tokens, duration_ms, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(day, project_id, agent_id, profile_id, model_id) DO UPDATE SET
+				run_count = run_count + excluded.run_count,
+				input_tokens = input_tokens + excluded.input_tokens,
+				output_tokens = output_tokens + excluded.output_tokens,
+				cache_read_tokens = cache_read_tokens + excluded.cache_read_tokens,
+				cache_write_tokens = cache_write_tokens + excluded.cache_write_tokens,
+				reasoning_tokens = reasoning_tokens + excluded.reasoning_tokens,
+				total_tokens = total_tokens + excluded.total_tokens,
+				duration_ms = duration_ms + excluded.duration_ms,
+				updated_at = excluded.updated_at`,
+			sum.Day, sum.ProjectID, sum.AgentID, sum.ProfileID, sum.ModelID, sum.RunCount,
+			sum.InputTokens, sum.OutputTokens, sum.CacheReadTokens, sum.CacheWriteTokens,
+			sum.ReasoningTokens, sum.TotalTokens, sum.DurationMs, now).Error
+		if err != nil {
+			return fmt.Errorf("upsert usage rollup: %w", err)
+		}
+	}
+	return nil
+}
+
+// CheckpointWAL folds the write-ahead log back into the main database file.
+func (s *Store) CheckpointWAL(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("wal checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Vacuum rebuilds the database file to reclaim space freed by pruning.
+func (s *Store) Vacuum(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	return nil
+}
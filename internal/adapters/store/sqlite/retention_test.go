@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestPruneExpiredRollsUpAndDeletes(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -40)
+	cutoff := now.AddDate(0, 0, -30)
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.CreateEvent(ctx, &core.Event{Type: core.EventRunStarted, Timestamp: old}); err != nil {
+			t.Fatalf("create old event: %v", err)
+		}
+	}
+	if _, err := s.CreateEvent(ctx, &core.Event{
+		Type: core.EventRunAudit, Timestamp: old,
+		Data: map[string]any{"log_ref": "2025/01/01/run-1-audit.jsonl"},
+	}); err != nil {
+		t.Fatalf("create audit event: %v", err)
+	}
+	if _, err := s.CreateEvent(ctx, &core.Event{Type: core.EventRunStarted, Timestamp: now}); err != nil {
+		t.Fatalf("create fresh event: %v", err)
+	}
+
+	expired, err := s.CountExpired(ctx, core.RetentionEventLog, cutoff)
+	if err != nil || expired != 4 {
+		t.Fatalf("CountExpired = %d, %v; want 4", expired, err)
+	}
+	var archived int
+	result, err := s.PruneExpired(ctx, core.RetentionPruneInput{
+		Target:    core.RetentionEventLog,
+		Before:    cutoff,
+		BatchSize: 2,
+		Archive:   func(records []any) error { archived += len(records); return nil },
+	})
+	if err != nil {
+		t.Fatalf("PruneExpired: %v", err)
+	}
+	if result.Pruned != 4 || archived != 4 {
+		t.Fatalf("pruned = %d, archived = %d; want 4/4", result.Pruned, archived)
+	}
+	if len(result.AuditLogRefs) != 1 || result.AuditLogRefs[0] != "2025/01/01/run-1-audit.jsonl" {
+		t.Fatalf("audit log refs = %v", result.AuditLogRefs)
+	}
+	left, _ := s.ListEvents(ctx, core.EventFilter{})
+	if len(left) != 1 {
+		t.Fatalf("remaining events = %d, want 1", len(left))
+	}
+	var rollup RetentionRollupModel
+	if err := s.orm.Where("source = ? AND key = ?", "event_log", string(core.EventRunStarted)).First(&rollup).Error; err != nil {
+		t.Fatalf("load rollup: %v", err)
+	}
+	if rollup.Count != 3 || !rollup.Day.Equal(rollupDay(old)) {
+		t.Fatalf("rollup = %+v", rollup)
+	}
+}
+
+func TestPruneExpiredUsageKeepsAnalyticsTotals(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -40)
+	projectID := int64(7)
+
+	for i, createdAt := range []time.Time{old, old, now} {
+		if _, err := s.CreateUsageRecord(ctx, &core.UsageRecord{
+			RunID: int64(i + 1), ProjectID: &projectID, AgentID: "worker", ModelID: "m1",
+			InputTokens: 10, OutputTokens: 5, TotalTokens: 15, CreatedAt: createdAt,
+		}); err != nil {
+			t.Fatalf("create usage: %v", err)
+		}
+	}
+	before, err := s.UsageTotals(ctx, core.AnalyticsFilter{ProjectID: &projectID})
+	if err != nil {
+		t.Fatalf("UsageTotals before: %v", err)
+	}
+
+	result, err := s.PruneExpired(ctx, core.RetentionPruneInput{Target: core.RetentionUsage, Before: now.AddDate(0, 0, -30)})
+	if err != nil {
+		t.Fatalf("PruneExpired: %v", err)
+	}
+	if result.Pruned != 2 || result.RolledUp != 1 {
+		t.Fatalf("result = %+v, want 2 pruned into 1 rollup", result)
+	}
+
+	after, err := s.UsageTotals(ctx, core.AnalyticsFilter{ProjectID: &projectID})
+	if err != nil {
+		t.Fatalf("UsageTotals after: %v", err)
+	}
+	if *after != *before || after.RunCount != 3 || after.TotalTokens != 45 {
+		t.Fatalf("totals after prune = %+v, before = %+v", after, before)
+	}
+	byAgent, err := s.UsageByAgent(ctx, core.AnalyticsFilter{})
+	if err != nil || len(byAgent) != 1 || byAgent[0].RunCount != 3 {
+		t.Fatalf("UsageByAgent = %+v, %v", byAgent, err)
+	}
+}
+
+func TestPruneExpiredClearsRunOutput(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	runID, err := s.CreateRun(ctx, &core.Run{ActionID: 1, WorkItemID: 1, Status: core.RunSucceeded, Output: map[string]any{"text": "done"}})
+	if err != nil {
+		t.Fatalf("create run: %v", err)
+	}
+	before := time.Now().UTC().Add(time.Hour)
+	result, err := s.PruneExpired(ctx, core.RetentionPruneInput{Target: core.RetentionRunOutput, Before: before})
+	if err != nil || result.Pruned != 1 {
+		t.Fatalf("PruneExpired = %+v, %v", result, err)
+	}
+	run, err := s.GetRun(ctx, runID)
+	if err != nil {
+		t.Fatalf("get run: %v", err)
+	}
+	if len(run.Output) != 0 {
+		t.Fatalf("run output = %v, want cleared", run.Output)
+	}
+	if n, _ := s.CountExpired(ctx, core.RetentionRunOutput, before); n != 0 {
+		t.Fatalf("expired after prune = %d, want 0", n)
+	}
+}
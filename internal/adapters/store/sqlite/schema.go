@@ -1,85 +1,155 @@
 package sqlite
 
 import (
-	"context"
 	"fmt"
 
 	"gorm.io/gorm"
 )
 
-func autoMigrate(ctx context.Context, orm *gorm.DB) error {
-	if orm == nil {
-		return fmt.Errorf("nil orm")
-	}
-	if err := orm.WithContext(ctx).AutoMigrate(
-		&ProjectModel{},
-		&ResourceSpaceModel{},
-		&ResourceModel{},
-		&WorkItemModel{},
-		&ActionModel{},
-		&RunModel{},
-		&DeliverableModel{},
-		&AgentContextModel{},
-		&EventModel{},
-		&AgentProfileModel{},
-		&DAGTemplateModel{},
-		&UsageRecordModel{},
-		&ThreadModel{},
-		&ThreadMessageModel{},
-		&ThreadMemberModel{},
-		&ThreadWorkItemLinkModel{},
-		&InitiativeModel{},
-		&InitiativeItemModel{},
-		&ThreadInitiativeLinkModel{},
-		&ThreadProposalModel{},
-		&ThreadContextRefModel{},
-		&ThreadAttachmentModel{},
-		&FeatureEntryModel{},
-		&ActionSignalModel{},
-		&ActionIODeclModel{},
-		&InspectionReportModel{},
-		&InspectionFindingModel{},
-		&InspectionInsightModel{},
-		&NotificationModel{},
-		&JournalModel{},
-	); err != nil {
-		return err
+// migrations is the ordered list of schema versions. Append new entries; never
+// renumber or edit a released migration. The baseline is frozen DDL
+// (schema_baseline.go); later migrations that add columns still guard with
+// HasColumn because pre-versioning databases may already carry them.
+var migrations = []migration{
+	{version: 1, name: "baseline", up: migrateBaselineUp, down: migrateBaselineDown},
+	{version: 2, name: "retention_rollups", up: migrateRetentionRollupsUp, down: migrateRetentionRollupsDown},
+	{version: 3, name: "search_index", up: migrateSearchIndexUp, down: migrateSearchIndexDown},
+	{version: 4, name: "event_seq", up: migrateEventSeqUp, down: migrateEventSeqDown},
+	{version: 5, name: "event_subscriptions", up: migrateEventSubscriptionsUp, down: migrateEventSubscriptionsDown},
+	{version: 6, name: "row_versions", up: migrateRowVersionsUp, down: migrateRowVersionsDown},
+	{version: 7, name: "users", up: migrateUsersUp, down: migrateUsersDown},
+	{version: 8, name: "user_identities", up: migrateUserIdentitiesUp, down: migrateUserIdentitiesDown},
+	{version: 9, name: "api_tokens", up: migrateAPITokensUp, down: migrateAPITokensDown},
+	{version: 10, name: "audit_log", up: migrateAuditLogUp, down: migrateAuditLogDown},
+	{version: 11, name: "journal_hash_chain", up: migrateJournalChainUp, down: migrateJournalChainDown},
+	{version: 12, name: "vault_secrets", up: migrateVaultUp, down: migrateVaultDown},
+	{version: 13, name: "idempotency_keys", up: migrateIdempotencyKeysUp, down: migrateIdempotencyKeysDown},
+	{version: 14, name: "event_seq_state", up: migrateEventSeqStateUp, down: migrateEventSeqStateDown},
+}
+
+// migrateBaselineUp creates the frozen pre-versioning schema. It is
+// idempotent so databases created before schema_migrations existed are
+// adopted in place: existing tables only gain the baseline columns they lack.
+func migrateBaselineUp(tx *gorm.DB) error {
+	for _, table := range baselineTables {
+		if tx.Migrator().HasTable(table.name) {
+			if err := addMissingBaselineColumns(tx, table.name, table.columns); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Exec("CREATE TABLE `" + table.name + "` (" + table.columns + ")").Error; err != nil {
+			return fmt.Errorf("create %s: %w", table.name, err)
+		}
 	}
 
 	// Migrate legacy column data (idempotent, safe for fresh DBs).
 	var colCount int64
-	orm.WithContext(ctx).Raw(
+	if err := tx.Raw(
 		`SELECT COUNT(*) FROM pragma_table_info('work_items') WHERE name = 'resource_binding_id'`,
-	).Scan(&colCount)
+	).Scan(&colCount).Error; err != nil {
+		return fmt.Errorf("inspect work_items: %w", err)
+	}
 	if colCount > 0 {
-		orm.WithContext(ctx).Exec(
+		if err := tx.Exec(
 			`UPDATE work_items SET resource_space_id = resource_binding_id WHERE resource_space_id IS NULL AND resource_binding_id IS NOT NULL`,
-		)
+		).Error; err != nil {
+			return fmt.Errorf("backfill work_items.resource_space_id: %w", err)
+		}
 	}
 
-	// Create partial indexes for activity_journal (GORM AutoMigrate does not support SQLite partial indexes).
-	for _, ddl := range []string{
-		`CREATE INDEX IF NOT EXISTS idx_actions_work_item_position_id ON actions(work_item_id, position, id)`,
-		`CREATE INDEX IF NOT EXISTS idx_runs_action_attempt ON runs(action_id, attempt)`,
-		`CREATE INDEX IF NOT EXISTS idx_runs_status_id ON runs(status, id)`,
-		`CREATE INDEX IF NOT EXISTS idx_runs_action_result ON runs(action_id, id DESC) WHERE result_markdown IS NOT NULL AND result_markdown != ''`,
-		`CREATE INDEX IF NOT EXISTS idx_deliverables_work_item_created_at ON deliverables(work_item_id, created_at DESC) WHERE work_item_id IS NOT NULL`,
-		`CREATE INDEX IF NOT EXISTS idx_deliverables_thread_created_at ON deliverables(thread_id, created_at DESC) WHERE thread_id IS NOT NULL`,
-		`CREATE INDEX IF NOT EXISTS idx_deliverables_producer ON deliverables(producer_type, producer_id, created_at DESC)`,
-		`CREATE INDEX IF NOT EXISTS idx_action_signals_action_id ON action_signals(action_id, id)`,
-		`CREATE INDEX IF NOT EXISTS idx_thread_messages_thread_id ON thread_messages(thread_id, id)`,
-		`CREATE INDEX IF NOT EXISTS idx_thread_members_thread_profile_status ON thread_members(thread_id, agent_profile_id, status)`,
-		`CREATE INDEX IF NOT EXISTS idx_usage_records_run_id ON usage_records(run_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_usage_records_created_at ON usage_records(created_at)`,
-		`CREATE INDEX IF NOT EXISTS idx_usage_records_project_created_at ON usage_records(project_id, created_at)`,
-		`CREATE INDEX IF NOT EXISTS idx_journal_run ON activity_journal(run_id, created_at) WHERE run_id IS NOT NULL`,
-		`CREATE INDEX IF NOT EXISTS idx_journal_action ON activity_journal(action_id, created_at) WHERE action_id IS NOT NULL`,
-		`CREATE INDEX IF NOT EXISTS idx_journal_work_item ON activity_journal(work_item_id, created_at) WHERE work_item_id IS NOT NULL`,
-		`CREATE INDEX IF NOT EXISTS idx_journal_kind ON activity_journal(kind, created_at)`,
-	} {
-		if err := orm.WithContext(ctx).Exec(ddl).Error; err != nil {
+	for _, ddl := range baselineIndexes {
+		if err := tx.Exec(ddl).Error; err != nil {
 			return fmt.Errorf("create sqlite index: %w", err)
 		}
 	}
 	return nil
 }
+
+// addMissingBaselineColumns adds the baseline columns a legacy table lacks.
+// The definitions are read back from a temporary probe table so SQLite, not
+// a hand-written parser, splits the column list.
+func addMissingBaselineColumns(tx *gorm.DB, table, columns string) error {
+	type columnInfo struct {
+		Name    string
+		Type    string
+		NotNull bool    `gorm:"column:notnull"`
+		Default *string `gorm:"column:dflt_value"`
+	}
+	if err := tx.Exec("CREATE TEMP TABLE baseline_probe (" + columns + ")").Error; err != nil {
+		return fmt.Errorf("probe %s: %w", table, err)
+	}
+	defer tx.Exec("DROP TABLE IF EXISTS temp.baseline_probe")
+
+	var want, have []columnInfo
+	if err := tx.Raw(`SELECT name, type, "notnull", dflt_value FROM pragma_table_info('baseline_probe', 'temp')`).Scan(&want).Error; err != nil {
+		return fmt.Errorf("inspect baseline %s: %w", table, err)
+	}
+	if err := tx.Raw(`SELECT name, type, "notnull", dflt_value FROM pragma_table_info(?)`, table).Scan(&have).Error; err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	existing := make(map[string]bool, len(have))
+	for _, col := range have {
+		existing[col.Name] = true
+	}
+	for _, col := range want {
+		if existing[col.Name] {
+			continue
+		}
+		ddl := "ALTER TABLE `" + table + "` ADD COLUMN `" + col.Name + "` " + col.Type
+		if col.Default != nil {
+			if col.NotNull {
+				ddl += " NOT NULL"
+			}
+			ddl += " DEFAULT " + *col.Default
+		}
+		if err := tx.Exec(ddl).Error; err != nil {
+			return fmt.Errorf("add %s.%s: %w", table, col.Name, err)
+		}
+	}
+	return nil
+}
+
+// migrateBaselineDown drops every baseline table, returning to an empty
+// database. `ai-flow db migrate` only runs it with --force, after a snapshot.
+func migrateBaselineDown(tx *gorm.DB) error {
+	for i := len(baselineTables) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropTable(baselineTables[i].name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateRetentionRollupsUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&RetentionRollupModel{}, &UsageRollupModel{})
+}
+
+func migrateRetentionRollupsDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&UsageRollupModel{}, &RetentionRollupModel{})
+}
+
+// migrateEventSeqUp adds the bus sequence number to event_log. Rows written
+// before sequencing get seq = id, which preserves their order; the bus is
+// seeded from MAX(seq) on startup so new events sort after them.
+func migrateEventSeqUp(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&EventModel{}, "Seq") {
+		if err := tx.Migrator().AddColumn(&EventModel{}, "Seq"); err != nil {
+			return fmt.Errorf("add event_log.seq: %w", err)
+		}
+	}
+	if err := tx.Exec(`UPDATE event_log SET seq = id WHERE seq = 0`).Error; err != nil {
+		return fmt.Errorf("backfill event_log.seq: %w", err)
+	}
+	return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_event_log_seq ON event_log(seq)`).Error
+}
+
+func migrateEventSeqDown(tx *gorm.DB) error {
+	if err := tx.Exec(`DROP INDEX IF EXISTS idx_event_log_seq`).Error; err != nil {
+		return err
+	}
+	if tx.Migrator().HasColumn(&EventModel{}, "Seq") {
+		return tx.Migrator().DropColumn(&EventModel{}, "Seq")
+	}
+	return nil
+}
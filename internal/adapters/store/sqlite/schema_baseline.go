@@ -0,0 +1,74 @@
+package sqlite
+
+// baselineTables is the schema as it stood when versioned migrations were
+// introduced, one entry per table with its column definitions. It is frozen:
+// schema changes go into new migrations, never here.
+var baselineTables = []struct{ name, columns string }{
+	{"projects", "`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`kind` text NOT NULL,`description` text NOT NULL,`metadata` text,`created_at` datetime,`updated_at` datetime"},
+	{"resource_spaces", "`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer NOT NULL,`kind` text NOT NULL,`root_uri` text NOT NULL,`role` text NOT NULL DEFAULT \"\",`label` text NOT NULL DEFAULT \"\",`config` text,`created_at` datetime,`updated_at` datetime"},
+	{"resources", "`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer NOT NULL,`work_item_id` integer,`run_id` integer,`message_id` integer,`storage_kind` text NOT NULL DEFAULT \"local\",`uri` text NOT NULL,`role` text NOT NULL DEFAULT \"\",`file_name` text NOT NULL DEFAULT \"\",`mime_type` text NOT NULL DEFAULT \"\",`size_bytes` integer NOT NULL DEFAULT 0,`checksum` text NOT NULL DEFAULT \"\",`metadata` text,`created_at` datetime"},
+	{"work_items", "`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer,`resource_space_id` integer,`parent_work_item_id` integer,`root_work_item_id` integer,`final_deliverable_id` integer,`title` text NOT NULL,`body` text NOT NULL,`status` text NOT NULL,`priority` text NOT NULL,`executor_profile_id` text NOT NULL DEFAULT \"\",`reviewer_profile_id` text NOT NULL DEFAULT \"\",`active_profile_id` text NOT NULL DEFAULT \"\",`sponsor_profile_id` text NOT NULL DEFAULT \"\",`created_by_profile_id` text NOT NULL DEFAULT \"\",`labels` text,`depends_on` text,`escalation_path` text,`metadata` text,`archived_at` datetime,`created_at` datetime,`updated_at` datetime"},
+	{"actions", "`id` integer PRIMARY KEY AUTOINCREMENT,`work_item_id` integer NOT NULL,`name` text NOT NULL,`description` text NOT NULL,`type` text NOT NULL,`status` text NOT NULL,`position` integer NOT NULL,`depends_on` text,`input` text,`agent_role` text,`required_capabilities` text,`acceptance_criteria` text,`timeout_ms` integer,`config` text,`max_retries` integer,`retry_count` integer,`created_at` datetime,`updated_at` datetime"},
+	{"runs", "`id` integer PRIMARY KEY AUTOINCREMENT,`action_id` integer NOT NULL,`work_item_id` integer NOT NULL,`status` text NOT NULL,`agent_id` text,`agent_context_id` integer,`briefing_snapshot` text,`input` text,`output` text,`error_message` text,`error_kind` text,`attempt` integer,`started_at` datetime,`finished_at` datetime,`created_at` datetime,`result_markdown` text,`result_metadata` text,`result_assets` text"},
+	{"deliverables", "`id` integer PRIMARY KEY AUTOINCREMENT,`work_item_id` integer,`thread_id` integer,`kind` text NOT NULL,`title` text NOT NULL DEFAULT \"\",`summary` text NOT NULL DEFAULT \"\",`payload` text,`producer_type` text NOT NULL,`producer_id` integer NOT NULL,`status` text NOT NULL,`created_at` datetime"},
+	{"agent_contexts", "`id` integer PRIMARY KEY AUTOINCREMENT,`agent_id` text NOT NULL,`work_item_id` integer NOT NULL,`system_prompt` text,`session_id` text,`summary` text,`turn_count` integer,`worker_id` text NOT NULL,`worker_last_seen_at` datetime,`created_at` datetime,`updated_at` datetime"},
+	{"event_log", "`id` integer PRIMARY KEY AUTOINCREMENT,`type` text NOT NULL,`category` text NOT NULL DEFAULT \"domain\",`work_item_id` integer,`action_id` integer,`run_id` integer,`data` text,`timestamp` datetime"},
+	{"agent_profiles", "`id` text,`name` text NOT NULL,`manager_profile_id` text NOT NULL DEFAULT \"\",`driver_id` text NOT NULL DEFAULT \"\",`llm_config_id` text NOT NULL DEFAULT \"\",`driver_config` text,`role` text NOT NULL,`capabilities` text,`actions_allowed` text,`prompt_template` text NOT NULL,`skills` text,`session_reuse` numeric NOT NULL,`session_max_turns` integer NOT NULL,`session_idle_ttl_ms` integer NOT NULL,`mcp_enabled` numeric NOT NULL,`mcp_tools` text,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`)"},
+	{"dag_templates", "`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`description` text NOT NULL,`project_id` integer,`tags` text,`metadata` text,`actions` text,`created_at` datetime,`updated_at` datetime"},
+	{"usage_records", "`id` integer PRIMARY KEY AUTOINCREMENT,`run_id` integer NOT NULL,`work_item_id` integer NOT NULL,`action_id` integer NOT NULL,`project_id` integer,`agent_id` text NOT NULL,`profile_id` text NOT NULL,`model_id` text NOT NULL,`input_tokens` integer NOT NULL,`output_tokens` integer NOT NULL,`cache_read_tokens` integer NOT NULL,`cache_write_tokens` integer NOT NULL,`reasoning_tokens` integer NOT NULL,`total_tokens` integer NOT NULL,`duration_ms` integer NOT NULL,`created_at` datetime"},
+	{"threads", "`id` integer PRIMARY KEY AUTOINCREMENT,`title` text NOT NULL,`status` text NOT NULL,`owner_id` text NOT NULL,`focus_project_id` integer,`metadata` text,`created_at` datetime,`updated_at` datetime"},
+	{"thread_messages", "`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`sender_id` text NOT NULL,`role` text NOT NULL,`content` text NOT NULL,`reply_to_msg_id` integer,`metadata` text,`created_at` datetime"},
+	{"thread_members", "`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`kind` text NOT NULL,`user_id` text NOT NULL DEFAULT \"\",`agent_profile_id` text NOT NULL DEFAULT \"\",`role` text NOT NULL DEFAULT \"member\",`status` text NOT NULL DEFAULT \"\",`agent_data` text,`joined_at` datetime,`last_active_at` datetime"},
+	{"thread_work_item_links", "`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`work_item_id` integer NOT NULL,`relation_type` text NOT NULL DEFAULT \"related\",`is_primary` numeric NOT NULL DEFAULT false,`created_at` datetime"},
+	{"initiatives", "`id` integer PRIMARY KEY AUTOINCREMENT,`title` text NOT NULL,`description` text NOT NULL,`status` text NOT NULL,`created_by` text NOT NULL,`approved_by` text,`approved_at` datetime,`review_note` text NOT NULL DEFAULT \"\",`metadata` text,`created_at` datetime,`updated_at` datetime"},
+	{"initiative_items", "`id` integer PRIMARY KEY AUTOINCREMENT,`initiative_id` integer NOT NULL,`work_item_id` integer NOT NULL,`role` text NOT NULL DEFAULT \"\",`created_at` datetime"},
+	{"thread_initiative_links", "`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`initiative_id` integer NOT NULL,`relation_type` text NOT NULL DEFAULT \"source\",`created_at` datetime"},
+	{"thread_proposals", "`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`title` text NOT NULL,`summary` text NOT NULL DEFAULT \"\",`content` text NOT NULL DEFAULT \"\",`proposed_by` text NOT NULL DEFAULT \"\",`status` text NOT NULL,`reviewed_by` text,`reviewed_at` datetime,`review_note` text NOT NULL DEFAULT \"\",`work_item_drafts` text,`source_message_id` integer,`initiative_id` integer,`metadata` text,`created_at` datetime,`updated_at` datetime"},
+	{"thread_context_refs", "`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`project_id` integer NOT NULL,`access` text NOT NULL DEFAULT \"read\",`note` text NOT NULL DEFAULT \"\",`granted_by` text NOT NULL DEFAULT \"\",`created_at` datetime,`expires_at` datetime"},
+	{"thread_attachments", "`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`message_id` integer,`file_name` text NOT NULL,`file_path` text NOT NULL,`file_size` integer NOT NULL DEFAULT 0,`content_type` text NOT NULL DEFAULT \"\",`is_directory` numeric NOT NULL DEFAULT false,`uploaded_by` text NOT NULL DEFAULT \"\",`note` text NOT NULL DEFAULT \"\",`created_at` datetime"},
+	{"feature_entries", "`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer NOT NULL,`key` text NOT NULL,`description` text NOT NULL,`status` text NOT NULL,`work_item_id` integer,`action_id` integer,`tags` text,`metadata` text,`created_at` datetime,`updated_at` datetime"},
+	{"action_signals", "`id` integer PRIMARY KEY AUTOINCREMENT,`action_id` integer NOT NULL,`work_item_id` integer NOT NULL,`run_id` integer,`type` text NOT NULL,`source` text NOT NULL,`summary` text NOT NULL,`content` text NOT NULL,`source_action_id` integer,`payload` text,`actor` text NOT NULL,`created_at` datetime"},
+	{"action_io_decls", "`id` integer PRIMARY KEY AUTOINCREMENT,`action_id` integer NOT NULL,`direction` text NOT NULL,`space_id` integer,`resource_id` integer,`path` text NOT NULL DEFAULT \"\",`media_type` text NOT NULL DEFAULT \"\",`description` text NOT NULL DEFAULT \"\",`required` numeric NOT NULL DEFAULT false,`created_at` datetime"},
+	{"inspection_reports", "`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer,`status` text NOT NULL,`trigger_source` text NOT NULL,`period_start` datetime NOT NULL,`period_end` datetime NOT NULL,`snapshot` text,`summary` text NOT NULL DEFAULT \"\",`suggested_skills` text,`error_message` text NOT NULL DEFAULT \"\",`created_at` datetime,`finished_at` datetime"},
+	{"inspection_findings", "`id` integer PRIMARY KEY AUTOINCREMENT,`inspection_id` integer NOT NULL,`category` text NOT NULL,`severity` text NOT NULL,`title` text NOT NULL,`description` text NOT NULL DEFAULT \"\",`evidence` text NOT NULL DEFAULT \"\",`work_item_id` integer,`action_id` integer,`run_id` integer,`project_id` integer,`recommendation` text NOT NULL DEFAULT \"\",`recurring` numeric NOT NULL DEFAULT false,`occurrence_count` integer NOT NULL DEFAULT 1,`created_at` datetime"},
+	{"inspection_insights", "`id` integer PRIMARY KEY AUTOINCREMENT,`inspection_id` integer NOT NULL,`type` text NOT NULL,`title` text NOT NULL,`description` text NOT NULL DEFAULT \"\",`trend` text NOT NULL DEFAULT \"\",`action_items` text,`created_at` datetime"},
+	{"notifications", "`id` integer PRIMARY KEY AUTOINCREMENT,`level` text NOT NULL,`title` text NOT NULL,`body` text NOT NULL DEFAULT \"\",`category` text NOT NULL DEFAULT \"\",`action_url` text NOT NULL DEFAULT \"\",`project_id` integer,`work_item_id` integer,`run_id` integer,`channels` text,`read` numeric NOT NULL DEFAULT false,`read_at` datetime,`created_at` datetime"},
+	{"activity_journal", "`id` integer PRIMARY KEY AUTOINCREMENT,`work_item_id` integer,`action_id` integer,`run_id` integer,`kind` text NOT NULL,`source` text NOT NULL DEFAULT \"system\",`summary` text NOT NULL DEFAULT \"\",`payload` text,`ref` text,`actor` text NOT NULL DEFAULT \"\",`source_action_id` integer,`created_at` datetime"},
+}
+
+// baselineIndexes are the indexes of the frozen baseline schema.
+var baselineIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS `idx_resource_spaces_project` ON `resource_spaces`(`project_id`)",
+	"CREATE INDEX IF NOT EXISTS `idx_resources_message` ON `resources`(`message_id`) WHERE message_id IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS `idx_resources_run` ON `resources`(`run_id`) WHERE run_id IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS `idx_resources_work_item` ON `resources`(`work_item_id`) WHERE work_item_id IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS `idx_resources_project` ON `resources`(`project_id`)",
+	"CREATE INDEX IF NOT EXISTS `idx_deliverables_producer` ON `deliverables`(`producer_type`,`producer_id`)",
+	"CREATE INDEX IF NOT EXISTS `idx_deliverables_thread_id` ON `deliverables`(`thread_id`)",
+	"CREATE INDEX IF NOT EXISTS `idx_deliverables_work_item_id` ON `deliverables`(`work_item_id`)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS `idx_thread_work_item_links_unique` ON `thread_work_item_links`(`thread_id`,`work_item_id`)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS `idx_initiative_items_unique` ON `initiative_items`(`initiative_id`,`work_item_id`)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS `idx_thread_initiative_links_unique` ON `thread_initiative_links`(`thread_id`,`initiative_id`)",
+	"CREATE INDEX IF NOT EXISTS `idx_thread_proposals_status` ON `thread_proposals`(`status`)",
+	"CREATE INDEX IF NOT EXISTS `idx_thread_proposals_thread_id` ON `thread_proposals`(`thread_id`)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS `idx_thread_context_refs_thread_project` ON `thread_context_refs`(`thread_id`,`project_id`)",
+	"CREATE INDEX IF NOT EXISTS `idx_thread_attachments_thread_id` ON `thread_attachments`(`thread_id`)",
+	"CREATE UNIQUE INDEX IF NOT EXISTS `idx_feature_entries_project_key` ON `feature_entries`(`project_id`,`key`)",
+	"CREATE INDEX IF NOT EXISTS `idx_action_io_decls_action` ON `action_io_decls`(`action_id`,`direction`)",
+	"CREATE INDEX IF NOT EXISTS idx_actions_work_item_position_id ON actions(work_item_id, position, id)",
+	"CREATE INDEX IF NOT EXISTS idx_runs_action_attempt ON runs(action_id, attempt)",
+	"CREATE INDEX IF NOT EXISTS idx_runs_status_id ON runs(status, id)",
+	"CREATE INDEX IF NOT EXISTS idx_runs_action_result ON runs(action_id, id DESC) WHERE result_markdown IS NOT NULL AND result_markdown != ''",
+	"CREATE INDEX IF NOT EXISTS idx_deliverables_work_item_created_at ON deliverables(work_item_id, created_at DESC) WHERE work_item_id IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS idx_deliverables_thread_created_at ON deliverables(thread_id, created_at DESC) WHERE thread_id IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS idx_action_signals_action_id ON action_signals(action_id, id)",
+	"CREATE INDEX IF NOT EXISTS idx_thread_messages_thread_id ON thread_messages(thread_id, id)",
+	"CREATE INDEX IF NOT EXISTS idx_thread_members_thread_profile_status ON thread_members(thread_id, agent_profile_id, status)",
+	"CREATE INDEX IF NOT EXISTS idx_usage_records_run_id ON usage_records(run_id)",
+	"CREATE INDEX IF NOT EXISTS idx_usage_records_created_at ON usage_records(created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_usage_records_project_created_at ON usage_records(project_id, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_journal_run ON activity_journal(run_id, created_at) WHERE run_id IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS idx_journal_action ON activity_journal(action_id, created_at) WHERE action_id IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS idx_journal_work_item ON activity_journal(work_item_id, created_at) WHERE work_item_id IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS idx_journal_kind ON activity_journal(kind, created_at)",
+}
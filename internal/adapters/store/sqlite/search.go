@@ -0,0 +1,315 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// trigramMin is the shortest term the trigram tokenizer can match; shorter
+	// terms (common for CJK words) fall back to a LIKE filter.
+	trigramMin     = 3
+	snippetContext = 40
+)
+
+// searchSource describes how one table feeds the search_index FTS5 table.
+// Expressions use {row} for the row alias: NEW inside triggers and the source
+// table during backfill. Index rowids are id*8+code so every source row owns
+// exactly one index row.
+type searchSource struct {
+	kind    core.SearchKind
+	table   string
+	code    int
+	watched string // columns whose update re-indexes the row
+	when    string // rows that are indexed at all
+	project string
+	work    string
+	thread  string
+	title   string
+	body    string
+}
+
+var searchSources = []searchSource{
+	{
+		kind: core.SearchKindWorkItem, table: "work_items", code: 1,
+		watched: "title, body, project_id",
+		when:    "1",
+		project: "{row}.project_id",
+		work:    "{row}.id",
+		thread:  "NULL",
+		title:   "{row}.title",
+		body:    "{row}.body",
+	},
+	{
+		kind: core.SearchKindThreadMessage, table: "thread_messages", code: 2,
+		watched: "content, thread_id",
+		when:    "COALESCE({row}.content, '') != ''",
+		project: "(SELECT focus_project_id FROM threads WHERE id = {row}.thread_id)",
+		work:    "NULL",
+		thread:  "{row}.thread_id",
+		title:   "COALESCE((SELECT title FROM threads WHERE id = {row}.thread_id), '')",
+		body:    "{row}.content",
+	},
+	{
+		kind: core.SearchKindJournal, table: "activity_journal", code: 3,
+		watched: "summary",
+		when:    "COALESCE({row}.summary, '') != ''",
+		project: "(SELECT project_id FROM work_items WHERE id = {row}.work_item_id)",
+		work:    "{row}.work_item_id",
+		thread:  "NULL",
+		title:   "{row}.kind",
+		body:    "{row}.summary",
+	},
+	{
+		kind: core.SearchKindRun, table: "runs", code: 4,
+		watched: "result_markdown",
+		when:    "COALESCE({row}.result_markdown, '') != ''",
+		project: "(SELECT project_id FROM work_items WHERE id = {row}.work_item_id)",
+		work:    "{row}.work_item_id",
+		thread:  "NULL",
+		title:   "COALESCE((SELECT name FROM actions WHERE id = {row}.action_id), '')",
+		body:    "{row}.result_markdown",
+	},
+	{
+		kind: core.SearchKindDeliverable, table: "deliverables", code: 5,
+		watched: "title, summary, payload",
+		when:    "1",
+		project: "COALESCE((SELECT project_id FROM work_items WHERE id = {row}.work_item_id), (SELECT focus_project_id FROM threads WHERE id = {row}.thread_id))",
+		work:    "{row}.work_item_id",
+		thread:  "{row}.thread_id",
+		title:   "{row}.title",
+		body:    "{row}.summary || char(10) || COALESCE({row}.payload, '')",
+	},
+}
+
+func (src searchSource) rowid(alias string) string {
+	return fmt.Sprintf("%s.id * 8 + %d", alias, src.code)
+}
+
+// insertSQL indexes rows of alias; from is empty inside triggers.
+func (src searchSource) insertSQL(alias, from string) string {
+	expr := func(s string) string { return strings.ReplaceAll(s, "{row}", alias) }
+	return fmt.Sprintf(
+		`INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT %s, '%s', %s.id, %s, %s, %s, %s.created_at, %s, %s%s WHERE %s`,
+		src.rowid(alias), src.kind, alias, expr(src.project), expr(src.work), expr(src.thread), alias, expr(src.title), expr(src.body), from, expr(src.when),
+	)
+}
+
+func (src searchSource) triggerNames() []string {
+	return []string{"trg_search_" + src.table + "_ai", "trg_search_" + src.table + "_au", "trg_search_" + src.table + "_ad"}
+}
+
+func (src searchSource) triggerDDL() []string {
+	names := src.triggerNames()
+	del := fmt.Sprintf("DELETE FROM search_index WHERE rowid = %s;", src.rowid("old"))
+	return []string{
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER INSERT ON %s BEGIN %s; END", names[0], src.table, src.insertSQL("new", "")),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER UPDATE OF %s ON %s BEGIN %s %s; END", names[1], src.watched, src.table, del, src.insertSQL("new", "")),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s AFTER DELETE ON %s BEGIN %s END", names[2], src.table, del),
+	}
+}
+
+// migrateSearchIndexUp creates the FTS5 index, the triggers that keep it in
+// step with every store write, and indexes existing rows. The trigram
+// tokenizer is used so CJK text without word boundaries stays searchable.
+func migrateSearchIndexUp(tx *gorm.DB) error {
+	if err := tx.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+		kind UNINDEXED, ref_id UNINDEXED, project_id UNINDEXED, work_item_id UNINDEXED,
+		thread_id UNINDEXED, created_at UNINDEXED, title, body, tokenize = 'trigram')`).Error; err != nil {
+		return fmt.Errorf("create search_index: %w", err)
+	}
+	if err := tx.Exec(`DELETE FROM search_index`).Error; err != nil {
+		return fmt.Errorf("reset search_index: %w", err)
+	}
+	for _, src := range searchSources {
+		for _, ddl := range src.triggerDDL() {
+			if err := tx.Exec(ddl).Error; err != nil {
+				return fmt.Errorf("create search trigger on %s: %w", src.table, err)
+			}
+		}
+		if err := tx.Exec(src.insertSQL("src", " FROM "+src.table+" src")).Error; err != nil {
+			return fmt.Errorf("backfill search_index from %s: %w", src.table, err)
+		}
+	}
+	return nil
+}
+
+func migrateSearchIndexDown(tx *gorm.DB) error {
+	for _, src := range searchSources {
+		for _, name := range src.triggerNames() {
+			if err := tx.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Exec(`DROP TABLE IF EXISTS search_index`).Error
+}
+
+type searchRow struct {
+	Kind       string
+	RefID      int64
+	ProjectID  *int64
+	WorkItemID *int64
+	ThreadID   *int64
+	CreatedAt  *string
+	Title      string
+	Body       string
+	Snippet    string
+	Score      float64
+}
+
+// Search runs a ranked full-text query. Terms of three or more characters go
+// through FTS5 MATCH (ranked by bm25); shorter terms are applied as substring
+// filters.
+func (s *Store) Search(ctx context.Context, q core.SearchQuery) ([]core.SearchHit, error) {
+	matchTerms, shortTerms := splitSearchTerms(q.Query)
+	if len(matchTerms) == 0 && len(shortTerms) == 0 {
+		return nil, core.ErrEmptySearchQuery
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	if len(matchTerms) > 0 {
+		quoted := make([]string, len(matchTerms))
+		for i, t := range matchTerms {
+			quoted[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+		}
+		where = append(where, "search_index MATCH ?")
+		args = append(args, strings.Join(quoted, " "))
+	}
+	for _, t := range shortTerms {
+		pattern := "%" + escapeLike(t) + "%"
+		where = append(where, `(title LIKE ? ESCAPE '\' OR body LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	if len(q.Kinds) > 0 {
+		placeholders := make([]string, len(q.Kinds))
+		for i, k := range q.Kinds {
+			placeholders[i] = "?"
+			args = append(args, string(k))
+		}
+		where = append(where, "kind IN ("+strings.Join(placeholders, ",")+")")
+	}
+	if q.ProjectID != nil {
+		where = append(where, "project_id = ?")
+		args = append(args, *q.ProjectID)
+	}
+
+	cols := "kind, ref_id, project_id, work_item_id, thread_id, created_at, title"
+	var query string
+	if len(matchTerms) > 0 {
+		query = fmt.Sprintf(`SELECT %s, '' AS body,
+			snippet(search_index, -1, '<mark>', '</mark>', '…', 64) AS snippet,
+			bm25(search_index, 0, 0, 0, 0, 0, 0, 2.0, 1.0) AS score
+			FROM search_index WHERE %s ORDER BY score LIMIT ? OFFSET ?`, cols, strings.Join(where, " AND "))
+	} else {
+		query = fmt.Sprintf(`SELECT %s, body, '' AS snippet, 0 AS score
+			FROM search_index WHERE %s ORDER BY rowid DESC LIMIT ? OFFSET ?`, cols, strings.Join(where, " AND "))
+	}
+	args = append(args, limit, max(q.Offset, 0))
+
+	var rows []searchRow
+	if err := s.orm.WithContext(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	hits := make([]core.SearchHit, 0, len(rows))
+	for _, row := range rows {
+		hit := core.SearchHit{
+			Kind:       core.SearchKind(row.Kind),
+			RefID:      row.RefID,
+			ProjectID:  row.ProjectID,
+			WorkItemID: row.WorkItemID,
+			ThreadID:   row.ThreadID,
+			Title:      row.Title,
+			Snippet:    row.Snippet,
+			// bm25 is lower-is-better; expose higher-is-better.
+			Score:     -row.Score,
+			CreatedAt: parseIndexedTime(row.CreatedAt),
+		}
+		if len(matchTerms) == 0 {
+			hit.Snippet = likeSnippet(row.Body, shortTerms[0])
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+func splitSearchTerms(query string) (match, short []string) {
+	for _, t := range strings.Fields(query) {
+		if utf8.RuneCountInString(t) >= trigramMin {
+			match = append(match, t)
+		} else {
+			short = append(short, t)
+		}
+	}
+	return match, short
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// likeSnippet mirrors FTS5 snippet() for substring-only matches.
+func likeSnippet(body, term string) string {
+	runes := []rune(body)
+	idx := strings.Index(strings.ToLower(body), strings.ToLower(term))
+	if idx < 0 {
+		if len(runes) > 2*snippetContext {
+			return string(runes[:2*snippetContext]) + "…"
+		}
+		return body
+	}
+	start := utf8.RuneCountInString(body[:idx])
+	end := start + utf8.RuneCountInString(term)
+	from, to := max(start-snippetContext, 0), min(end+snippetContext, len(runes))
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	b.WriteString(string(runes[from:start]))
+	b.WriteString("<mark>")
+	b.WriteString(string(runes[start:end]))
+	b.WriteString("</mark>")
+	b.WriteString(string(runes[end:to]))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// parseIndexedTime reads created_at copied from the source rows, which FTS5
+// stores as text.
+func parseIndexedTime(raw *string) *time.Time {
+	if raw == nil || *raw == "" {
+		return nil
+	}
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999-07:00",
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05",
+	} {
+		if t, err := time.Parse(layout, *raw); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
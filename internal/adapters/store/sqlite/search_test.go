@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestSearchIndexFollowsStoreWrites(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	projectID, err := s.CreateProject(ctx, &core.Project{Name: "search"})
+	if err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	wiID, err := s.CreateWorkItem(ctx, &core.WorkItem{
+		ProjectID: &projectID,
+		Title:     "Fix flaky authentication test",
+		Body:      "The login suite times out on CI.",
+		Status:    core.WorkItemOpen,
+	})
+	if err != nil {
+		t.Fatalf("create work item: %v", err)
+	}
+	threadID, err := s.CreateThread(ctx, &core.Thread{Title: "认证讨论", Status: core.ThreadActive, FocusProjectID: projectID})
+	if err != nil {
+		t.Fatalf("create thread: %v", err)
+	}
+	if _, err := s.CreateThreadMessage(ctx, &core.ThreadMessage{ThreadID: threadID, SenderID: "u", Role: "human", Content: "登录认证偶尔超时，需要排查"}); err != nil {
+		t.Fatalf("create message: %v", err)
+	}
+	if _, err := s.AppendJournal(ctx, &core.JournalEntry{WorkItemID: wiID, Kind: core.JournalAgentOutput, Source: core.JournalSourceAgent, Summary: "retried authentication flow"}); err != nil {
+		t.Fatalf("append journal: %v", err)
+	}
+
+	hits, err := s.Search(ctx, core.SearchQuery{Query: "authentication"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("hits = %+v, want work item and journal", hits)
+	}
+	if hits[0].Kind != core.SearchKindWorkItem || hits[0].RefID != wiID {
+		t.Fatalf("top hit = %+v, want work item (title match ranks first)", hits[0])
+	}
+	if !strings.Contains(hits[0].Snippet, "<mark>") || hits[0].ProjectID == nil || *hits[0].ProjectID != projectID {
+		t.Fatalf("work item hit = %+v", hits[0])
+	}
+	if hits[1].Kind != core.SearchKindJournal || hits[1].WorkItemID == nil || *hits[1].WorkItemID != wiID {
+		t.Fatalf("journal hit = %+v", hits[1])
+	}
+
+	// Kind filter and two-character CJK terms (substring fallback).
+	hits, err = s.Search(ctx, core.SearchQuery{Query: "认证", Kinds: []core.SearchKind{core.SearchKindThreadMessage}, ProjectID: &projectID})
+	if err != nil {
+		t.Fatalf("Search CJK: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ThreadID == nil || *hits[0].ThreadID != threadID || !strings.Contains(hits[0].Snippet, "<mark>认证</mark>") {
+		t.Fatalf("CJK hits = %+v", hits)
+	}
+
+	// Updates re-index, deletes drop the row.
+	wi, err := s.GetWorkItem(ctx, wiID)
+	if err != nil {
+		t.Fatalf("get work item: %v", err)
+	}
+	wi.Title = "Stabilise payment retries"
+	if err := s.UpdateWorkItem(ctx, wi); err != nil {
+		t.Fatalf("update work item: %v", err)
+	}
+	if hits, _ := s.Search(ctx, core.SearchQuery{Query: "payment", Kinds: []core.SearchKind{core.SearchKindWorkItem}}); len(hits) != 1 {
+		t.Fatalf("hits after update = %+v", hits)
+	}
+	if hits, _ := s.Search(ctx, core.SearchQuery{Query: "flaky"}); len(hits) != 0 {
+		t.Fatalf("stale hits after update = %+v", hits)
+	}
+
+	if _, err := s.Search(ctx, core.SearchQuery{Query: "   "}); err != core.ErrEmptySearchQuery {
+		t.Fatalf("empty query err = %v", err)
+	}
+}
+
+func TestSearchIndexBackfillsExistingRows(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	wiID, _ := s.CreateWorkItem(ctx, &core.WorkItem{Title: "legacy migration plan", Status: core.WorkItemOpen})
+	if _, err := s.MigrateTo(ctx, 2); err != nil {
+		t.Fatalf("migrate down: %v", err)
+	}
+	if _, err := s.MigrateTo(ctx, LatestSchemaVersion()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	hits, err := s.Search(ctx, core.SearchQuery{Query: "migration"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(hits) != 1 || hits[0].RefID != wiID {
+		t.Fatalf("hits = %+v", hits)
+	}
+}
+
+func TestConcurrentWritesToIndexedTablesDoNotFailBusy(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				item := &core.WorkItem{Title: fmt.Sprintf("item %d-%d", g, i), Body: "indexed body", Status: core.WorkItemOpen, Priority: core.PriorityMedium}
+				if _, err := s.CreateWorkItem(ctx, item); err != nil {
+					errs <- err
+					return
+				}
+				item.Body = "indexed body, updated"
+				if err := s.UpdateWorkItem(ctx, item); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent write: %v", err)
+	}
+}
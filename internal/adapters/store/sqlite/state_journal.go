@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+)
+
+// State transitions are dual-written to activity_journal as state_change
+// entries so the work item timeline can be replayed. Like signal journaling
+// this is best-effort: a failed journal write never fails the update. The
+// before state is read in the update's own transaction, and entries go
+// through EnqueueJournal so they ride the batch writer instead of taking a
+// separate hash-chain transaction per update.
+
+func (s *Store) journalWorkItemState(ctx context.Context, before, after *core.WorkItem) {
+	var from, to any
+	id := int64(0)
+	if before != nil {
+		from, id = core.WorkItemStateOf(before), before.ID
+	}
+	if after != nil {
+		to, id = core.WorkItemStateOf(after), after.ID
+	}
+	if entry := core.StateChangeJournalEntry(core.StateEntityWorkItem, id, 0, from, to); entry != nil {
+		_ = s.EnqueueJournal(ctx, entry)
+	}
+}
+
+func (s *Store) journalActionState(ctx context.Context, before, after *core.Action) {
+	var from, to any
+	var workItemID, actionID int64
+	if before != nil {
+		from, workItemID, actionID = core.ActionStateOf(before), before.WorkItemID, before.ID
+	}
+	if after != nil {
+		to, workItemID, actionID = core.ActionStateOf(after), after.WorkItemID, after.ID
+	}
+	if entry := core.StateChangeJournalEntry(core.StateEntityAction, workItemID, actionID, from, to); entry != nil {
+		_ = s.EnqueueJournal(ctx, entry)
+	}
+}
+
+// withWorkItemSnapshot runs update in one write transaction right after
+// reading the row it changes, so the journaled before state is exactly what
+// the update replaced. before is nil when the row does not exist.
+func (s *Store) withWorkItemSnapshot(ctx context.Context, id int64, update func(tx *gorm.DB, before *core.WorkItem) error) error {
+	return s.writeTx(ctx, func(tx *gorm.DB) error {
+		var models []WorkItemModel
+		if err := tx.Where("id = ?", id).Limit(1).Find(&models).Error; err != nil {
+			return err
+		}
+		var before *core.WorkItem
+		if len(models) > 0 {
+			before = models[0].toCore()
+		}
+		return update(tx, before)
+	})
+}
+
+func (s *Store) withActionSnapshot(ctx context.Context, id int64, update func(tx *gorm.DB, before *core.Action) error) error {
+	return s.writeTx(ctx, func(tx *gorm.DB) error {
+		var models []ActionModel
+		if err := tx.Where("id = ?", id).Limit(1).Find(&models).Error; err != nil {
+			return err
+		}
+		var before *core.Action
+		if len(models) > 0 {
+			before = models[0].toCore()
+		}
+		return update(tx, before)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	gormsqlite "github.com/glebarez/sqlite"
@@ -19,13 +20,41 @@ import (
 type Store struct {
 	db  *sql.DB
 	orm *gorm.DB
+	// writeDB is a second pool over the same file whose transactions BEGIN
+	// IMMEDIATE. Writes to tables indexed by the FTS5 search triggers fail
+	// with SQLITE_BUSY, bypassing busy_timeout, when their deferred
+	// transaction has to upgrade under contention; taking the write lock up
+	// front avoids that. gorm's implicit write transactions are routed here
+	// by routeWrites and explicit ones go through writeTx. Reads stay on db.
+	// Nil for in-memory databases and transaction-bound stores.
+	writeDB  *sql.DB
+	writeORM *gorm.DB
+	// journalMu serializes appends to the journal hash chain; nil in stores
+	// bound to a transaction.
+	journalMu *sync.Mutex
+	// journalQueue batches EnqueueJournal writes; nil in stores bound to a
+	// transaction, whose entries must commit or roll back with it.
+	journalQueue core.JournalQueue
 }
 
 const startupDBTimeout = 6 * time.Second
 
-// New opens (or creates) a SQLite database at path and ensures schema exists via GORM AutoMigrate.
+// OpenOptions tunes how a database is opened.
+type OpenOptions struct {
+	// SkipMigrations opens the database as-is. Used by `ai-flow db migrate`
+	// to inspect or roll back the schema.
+	SkipMigrations bool
+}
+
+// New opens (or creates) a SQLite database at path and migrates it to the
+// latest schema version. It refuses databases migrated by a newer binary.
 func New(path string) (*Store, error) {
-	db, err := sql.Open("sqlite", path+"?_busy_timeout=5000")
+	return NewWithOptions(path, OpenOptions{})
+}
+
+// NewWithOptions is New with explicit open options.
+func NewWithOptions(path string, opts OpenOptions) (*Store, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, fmt.Errorf("open sqlite %s: %w", path, err)
 	}
@@ -49,22 +78,79 @@ func New(path string) (*Store, error) {
 			return nil, startupDBError(path, fmt.Sprintf("exec %s", pragma), err)
 		}
 	}
-	orm, err := gorm.Open(gormsqlite.Dialector{
+	orm, err := openORM(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open gorm sqlite %s: %w", path, err)
+	}
+	if !opts.SkipMigrations {
+		if err := migrateToLatest(ctx, orm); err != nil {
+			db.Close()
+			return nil, startupDBError(path, "migrate schema", err)
+		}
+	}
+
+	store := &Store{db: db, orm: orm, journalMu: &sync.Mutex{}}
+	if maxOpenConns > 1 {
+		writeDB, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_txlock=immediate")
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("open sqlite write pool %s: %w", path, err)
+		}
+		writeDB.SetMaxOpenConns(maxOpenConns)
+		writeDB.SetMaxIdleConns(maxOpenConns)
+		writeORM, err := openORM(writeDB)
+		if err == nil {
+			err = routeWrites(orm, db, writeDB)
+		}
+		if err != nil {
+			writeDB.Close()
+			db.Close()
+			return nil, fmt.Errorf("open gorm sqlite write pool %s: %w", path, err)
+		}
+		store.writeDB, store.writeORM = writeDB, writeORM
+	}
+	return store, nil
+}
+
+func openORM(db *sql.DB) (*gorm.DB, error) {
+	return gorm.Open(gormsqlite.Dialector{
 		DriverName: "sqlite",
 		Conn:       db,
 	}, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("open gorm sqlite %s: %w", path, err)
+}
+
+// routeWrites points statements that write outside an explicit transaction
+// at writeDB, so the transaction gorm wraps them in takes the write lock up
+// front. Statements already bound to a transaction keep their connection.
+func routeWrites(orm *gorm.DB, readDB, writeDB *sql.DB) error {
+	route := func(db *gorm.DB) {
+		if db.Statement.ConnPool == readDB {
+			db.Statement.ConnPool = writeDB
+		}
 	}
-	if err := autoMigrate(ctx, orm); err != nil {
-		db.Close()
-		return nil, startupDBError(path, "auto migrate", err)
+	cb := orm.Callback()
+	if err := cb.Create().Before("gorm:begin_transaction").Register("sqlite:route_write", route); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:begin_transaction").Register("sqlite:route_write", route); err != nil {
+		return err
 	}
+	if err := cb.Delete().Before("gorm:begin_transaction").Register("sqlite:route_write", route); err != nil {
+		return err
+	}
+	return cb.Raw().Before("gorm:raw").Register("sqlite:route_write", route)
+}
 
-	return &Store{db: db, orm: orm}, nil
+// writeTx runs fn in a transaction that takes the write lock up front. Use
+// it instead of orm.Transaction for every transaction that writes.
+func (s *Store) writeTx(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	if s.writeORM == nil {
+		return s.orm.WithContext(ctx).Transaction(fn)
+	}
+	return s.writeORM.WithContext(ctx).Transaction(fn)
 }
 
 func sqliteMaxOpenConns(path string) int {
@@ -93,7 +179,7 @@ func startupDBError(path string, op string, err error) error {
 	if errors.Is(err, context.DeadlineExceeded) || strings.Contains(strings.ToLower(err.Error()), "database is locked") {
 		return fmt.Errorf("%s; database may be locked by another ai-flow process, stop old processes and remove %s-shm/%s-wal if needed", msg, path, path)
 	}
-	return fmt.Errorf("%s", msg)
+	return fmt.Errorf("%s %s: %w", op, path, err)
 }
 
 func (s *Store) cloneWithORM(orm *gorm.DB) *Store {
@@ -112,12 +198,15 @@ func (s *Store) InTx(ctx context.Context, fn func(store core.Store) error) error
 	if s == nil || s.orm == nil {
 		return fmt.Errorf("store is not initialized")
 	}
-	return s.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	return s.writeTx(ctx, func(tx *gorm.DB) error {
 		return fn(s.cloneWithORM(tx))
 	})
 }
 
-// Close closes the underlying database connection.
+// Close closes the underlying database connections.
 func (s *Store) Close() error {
+	if s.writeDB != nil {
+		_ = s.writeDB.Close()
+	}
 	return s.db.Close()
 }
@@ -705,6 +705,30 @@ func TestEventListFiltersByThreadID(t *testing.T) {
 	}
 }
 
+func TestMaxEventSeqCoversReservedAndPrunedSeq(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	if err := s.BatchCreateEvents(ctx, []*core.Event{{Type: core.EventWorkItemStarted, Seq: 5}}); err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+	if err := s.ReserveEventSeq(ctx, 1000); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if err := s.ReserveEventSeq(ctx, 10); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if got, err := s.MaxEventSeq(ctx); err != nil || got != 1000 {
+		t.Fatalf("MaxEventSeq = %d, %v; want the reserved ceiling 1000", got, err)
+	}
+
+	if _, err := s.PruneExpired(ctx, core.RetentionPruneInput{Target: core.RetentionEventLog, Before: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if got, err := s.PrunedEventSeq(ctx); err != nil || got != 5 {
+		t.Fatalf("PrunedEventSeq = %d, %v; want 5", got, err)
+	}
+}
+
 func TestProjectCRUD_NewFields(t *testing.T) {
 	s := newTestStore(t)
 	ctx := context.Background()
@@ -0,0 +1,121 @@
+CREATE TRIGGER trg_search_activity_journal_ad AFTER DELETE ON activity_journal BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 3; END;
+CREATE TRIGGER trg_search_activity_journal_ai AFTER INSERT ON activity_journal BEGIN INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 3, 'journal', new.id, (SELECT project_id FROM work_items WHERE id = new.work_item_id), new.work_item_id, NULL, new.created_at, new.kind, new.summary WHERE COALESCE(new.summary, '') != ''; END;
+CREATE TRIGGER trg_search_activity_journal_au AFTER UPDATE OF summary ON activity_journal BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 3; INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 3, 'journal', new.id, (SELECT project_id FROM work_items WHERE id = new.work_item_id), new.work_item_id, NULL, new.created_at, new.kind, new.summary WHERE COALESCE(new.summary, '') != ''; END;
+CREATE TRIGGER trg_search_deliverables_ad AFTER DELETE ON deliverables BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 5; END;
+CREATE TRIGGER trg_search_deliverables_ai AFTER INSERT ON deliverables BEGIN INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 5, 'deliverable', new.id, COALESCE((SELECT project_id FROM work_items WHERE id = new.work_item_id), (SELECT focus_project_id FROM threads WHERE id = new.thread_id)), new.work_item_id, new.thread_id, new.created_at, new.title, new.summary || char(10) || COALESCE(new.payload, '') WHERE 1; END;
+CREATE TRIGGER trg_search_deliverables_au AFTER UPDATE OF title, summary, payload ON deliverables BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 5; INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 5, 'deliverable', new.id, COALESCE((SELECT project_id FROM work_items WHERE id = new.work_item_id), (SELECT focus_project_id FROM threads WHERE id = new.thread_id)), new.work_item_id, new.thread_id, new.created_at, new.title, new.summary || char(10) || COALESCE(new.payload, '') WHERE 1; END;
+CREATE TRIGGER trg_search_runs_ad AFTER DELETE ON runs BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 4; END;
+CREATE TRIGGER trg_search_runs_ai AFTER INSERT ON runs BEGIN INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 4, 'run', new.id, (SELECT project_id FROM work_items WHERE id = new.work_item_id), new.work_item_id, NULL, new.created_at, COALESCE((SELECT name FROM actions WHERE id = new.action_id), ''), new.result_markdown WHERE COALESCE(new.result_markdown, '') != ''; END;
+CREATE TRIGGER trg_search_runs_au AFTER UPDATE OF result_markdown ON runs BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 4; INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 4, 'run', new.id, (SELECT project_id FROM work_items WHERE id = new.work_item_id), new.work_item_id, NULL, new.created_at, COALESCE((SELECT name FROM actions WHERE id = new.action_id), ''), new.result_markdown WHERE COALESCE(new.result_markdown, '') != ''; END;
+CREATE TRIGGER trg_search_thread_messages_ad AFTER DELETE ON thread_messages BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 2; END;
+CREATE TRIGGER trg_search_thread_messages_ai AFTER INSERT ON thread_messages BEGIN INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 2, 'thread_message', new.id, (SELECT focus_project_id FROM threads WHERE id = new.thread_id), NULL, new.thread_id, new.created_at, COALESCE((SELECT title FROM threads WHERE id = new.thread_id), ''), new.content WHERE COALESCE(new.content, '') != ''; END;
+CREATE TRIGGER trg_search_thread_messages_au AFTER UPDATE OF content, thread_id ON thread_messages BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 2; INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 2, 'thread_message', new.id, (SELECT focus_project_id FROM threads WHERE id = new.thread_id), NULL, new.thread_id, new.created_at, COALESCE((SELECT title FROM threads WHERE id = new.thread_id), ''), new.content WHERE COALESCE(new.content, '') != ''; END;
+CREATE TRIGGER trg_search_work_items_ad AFTER DELETE ON work_items BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 1; END;
+CREATE TRIGGER trg_search_work_items_ai AFTER INSERT ON work_items BEGIN INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 1, 'work_item', new.id, new.project_id, new.id, NULL, new.created_at, new.title, new.body WHERE 1; END;
+CREATE TRIGGER trg_search_work_items_au AFTER UPDATE OF title, body, project_id ON work_items BEGIN DELETE FROM search_index WHERE rowid = old.id * 8 + 1; INSERT INTO search_index(rowid, kind, ref_id, project_id, work_item_id, thread_id, created_at, title, body) SELECT new.id * 8 + 1, 'work_item', new.id, new.project_id, new.id, NULL, new.created_at, new.title, new.body WHERE 1; END;
+CREATE TABLE `action_io_decls` (`id` integer PRIMARY KEY AUTOINCREMENT,`action_id` integer NOT NULL,`direction` text NOT NULL,`space_id` integer,`resource_id` integer,`path` text NOT NULL DEFAULT "",`media_type` text NOT NULL DEFAULT "",`description` text NOT NULL DEFAULT "",`required` numeric NOT NULL DEFAULT false,`created_at` datetime);
+CREATE TABLE `action_signals` (`id` integer PRIMARY KEY AUTOINCREMENT,`action_id` integer NOT NULL,`work_item_id` integer NOT NULL,`run_id` integer,`type` text NOT NULL,`source` text NOT NULL,`summary` text NOT NULL,`content` text NOT NULL,`source_action_id` integer,`payload` text,`actor` text NOT NULL,`created_at` datetime, `actor_user_id` integer NOT NULL DEFAULT 0);
+CREATE TABLE `actions` (`id` integer PRIMARY KEY AUTOINCREMENT,`work_item_id` integer NOT NULL,`name` text NOT NULL,`description` text NOT NULL,`type` text NOT NULL,`status` text NOT NULL,`position` integer NOT NULL,`depends_on` text,`input` text,`agent_role` text,`required_capabilities` text,`acceptance_criteria` text,`timeout_ms` integer,`config` text,`max_retries` integer,`retry_count` integer,`created_at` datetime,`updated_at` datetime, `version` integer NOT NULL DEFAULT 1);
+CREATE TABLE `activity_journal` (`id` integer PRIMARY KEY AUTOINCREMENT,`work_item_id` integer,`action_id` integer,`run_id` integer,`kind` text NOT NULL,`source` text NOT NULL DEFAULT "system",`summary` text NOT NULL DEFAULT "",`payload` text,`ref` text,`actor` text NOT NULL DEFAULT "",`source_action_id` integer,`created_at` datetime, `chain_seq` integer NOT NULL DEFAULT 0, `prev_hash` text NOT NULL DEFAULT "", `hash` text NOT NULL DEFAULT "");
+CREATE TABLE `agent_contexts` (`id` integer PRIMARY KEY AUTOINCREMENT,`agent_id` text NOT NULL,`work_item_id` integer NOT NULL,`system_prompt` text,`session_id` text,`summary` text,`turn_count` integer,`worker_id` text NOT NULL,`worker_last_seen_at` datetime,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `agent_profiles` (`id` text,`name` text NOT NULL,`manager_profile_id` text NOT NULL DEFAULT "",`driver_id` text NOT NULL DEFAULT "",`llm_config_id` text NOT NULL DEFAULT "",`driver_config` text,`role` text NOT NULL,`capabilities` text,`actions_allowed` text,`prompt_template` text NOT NULL,`skills` text,`session_reuse` numeric NOT NULL,`session_max_turns` integer NOT NULL,`session_idle_ttl_ms` integer NOT NULL,`mcp_enabled` numeric NOT NULL,`mcp_tools` text,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`id`));
+CREATE TABLE `api_tokens` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`token_hash` text NOT NULL,`prefix` text NOT NULL DEFAULT "",`owner` text NOT NULL DEFAULT "",`created_by` text NOT NULL DEFAULT "",`scopes` text,`projects` text,`expires_at` datetime,`revoked_at` datetime,`last_used_at` datetime,`last_used_ip` text NOT NULL DEFAULT "",`created_at` datetime);
+CREATE TABLE `audit_log` (`id` integer PRIMARY KEY AUTOINCREMENT,`actor` text NOT NULL DEFAULT "",`user_id` integer NOT NULL DEFAULT 0,`role` text NOT NULL DEFAULT "",`method` text NOT NULL,`route` text NOT NULL DEFAULT "",`path` text NOT NULL DEFAULT "",`resource_ids` text,`changes` text,`status` integer NOT NULL,`outcome` text NOT NULL,`error` text NOT NULL DEFAULT "",`remote_ip` text NOT NULL DEFAULT "",`user_agent` text NOT NULL DEFAULT "",`duration_ms` integer NOT NULL DEFAULT 0,`created_at` datetime);
+CREATE TABLE `dag_templates` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`description` text NOT NULL,`project_id` integer,`tags` text,`metadata` text,`actions` text,`created_at` datetime,`updated_at` datetime, `version` integer NOT NULL DEFAULT 1);
+CREATE TABLE `deliverables` (`id` integer PRIMARY KEY AUTOINCREMENT,`work_item_id` integer,`thread_id` integer,`kind` text NOT NULL,`title` text NOT NULL DEFAULT "",`summary` text NOT NULL DEFAULT "",`payload` text,`producer_type` text NOT NULL,`producer_id` integer NOT NULL,`status` text NOT NULL,`created_at` datetime);
+CREATE TABLE `event_deliveries` (`id` integer PRIMARY KEY AUTOINCREMENT,`subscription_id` integer NOT NULL,`cloud_event_id` text NOT NULL,`event_type` text NOT NULL,`event_seq` integer NOT NULL DEFAULT 0,`payload` text NOT NULL,`status` text NOT NULL,`attempts` integer NOT NULL DEFAULT 0,`next_attempt_at` datetime,`last_error` text NOT NULL DEFAULT "",`response_status` integer NOT NULL DEFAULT 0,`replay_of` integer,`delivered_at` datetime,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `event_log` (`id` integer PRIMARY KEY AUTOINCREMENT,`type` text NOT NULL,`category` text NOT NULL DEFAULT "domain",`work_item_id` integer,`action_id` integer,`run_id` integer,`data` text,`timestamp` datetime, `seq` integer NOT NULL DEFAULT 0);
+CREATE TABLE `event_seq_state` (`id` integer PRIMARY KEY AUTOINCREMENT,`reserved_seq` integer NOT NULL DEFAULT 0,`pruned_seq` integer NOT NULL DEFAULT 0);
+CREATE TABLE `event_subscriptions` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL DEFAULT "",`target_url` text NOT NULL,`event_types` text,`project_id` integer,`secret` text NOT NULL DEFAULT "",`enabled` numeric NOT NULL DEFAULT false,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `feature_entries` (`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer NOT NULL,`key` text NOT NULL,`description` text NOT NULL,`status` text NOT NULL,`work_item_id` integer,`action_id` integer,`tags` text,`metadata` text,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `idempotency_keys` (`id` integer PRIMARY KEY AUTOINCREMENT,`scope` text NOT NULL,`idempotency_key` text NOT NULL,`method` text NOT NULL DEFAULT "",`path` text NOT NULL DEFAULT "",`request_hash` text NOT NULL DEFAULT "",`status` integer NOT NULL DEFAULT 0,`content_type` text NOT NULL DEFAULT "",`body` blob,`created_at` datetime,`expires_at` datetime);
+CREATE TABLE `initiative_items` (`id` integer PRIMARY KEY AUTOINCREMENT,`initiative_id` integer NOT NULL,`work_item_id` integer NOT NULL,`role` text NOT NULL DEFAULT "",`created_at` datetime);
+CREATE TABLE `initiatives` (`id` integer PRIMARY KEY AUTOINCREMENT,`title` text NOT NULL,`description` text NOT NULL,`status` text NOT NULL,`created_by` text NOT NULL,`approved_by` text,`approved_at` datetime,`review_note` text NOT NULL DEFAULT "",`metadata` text,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `inspection_findings` (`id` integer PRIMARY KEY AUTOINCREMENT,`inspection_id` integer NOT NULL,`category` text NOT NULL,`severity` text NOT NULL,`title` text NOT NULL,`description` text NOT NULL DEFAULT "",`evidence` text NOT NULL DEFAULT "",`work_item_id` integer,`action_id` integer,`run_id` integer,`project_id` integer,`recommendation` text NOT NULL DEFAULT "",`recurring` numeric NOT NULL DEFAULT false,`occurrence_count` integer NOT NULL DEFAULT 1,`created_at` datetime);
+CREATE TABLE `inspection_insights` (`id` integer PRIMARY KEY AUTOINCREMENT,`inspection_id` integer NOT NULL,`type` text NOT NULL,`title` text NOT NULL,`description` text NOT NULL DEFAULT "",`trend` text NOT NULL DEFAULT "",`action_items` text,`created_at` datetime);
+CREATE TABLE `inspection_reports` (`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer,`status` text NOT NULL,`trigger_source` text NOT NULL,`period_start` datetime NOT NULL,`period_end` datetime NOT NULL,`snapshot` text,`summary` text NOT NULL DEFAULT "",`suggested_skills` text,`error_message` text NOT NULL DEFAULT "",`created_at` datetime,`finished_at` datetime);
+CREATE TABLE `journal_checkpoints` (`id` integer PRIMARY KEY AUTOINCREMENT,`seq` integer NOT NULL,`hash` text NOT NULL,`signature` text NOT NULL,`created_at` datetime);
+CREATE TABLE `notifications` (`id` integer PRIMARY KEY AUTOINCREMENT,`level` text NOT NULL,`title` text NOT NULL,`body` text NOT NULL DEFAULT "",`category` text NOT NULL DEFAULT "",`action_url` text NOT NULL DEFAULT "",`project_id` integer,`work_item_id` integer,`run_id` integer,`channels` text,`read` numeric NOT NULL DEFAULT false,`read_at` datetime,`created_at` datetime);
+CREATE TABLE `project_members` (`project_id` integer,`user_id` integer,`role` text NOT NULL,`created_at` datetime,`updated_at` datetime,PRIMARY KEY (`project_id`,`user_id`));
+CREATE TABLE `projects` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`kind` text NOT NULL,`description` text NOT NULL,`metadata` text,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `resource_spaces` (`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer NOT NULL,`kind` text NOT NULL,`root_uri` text NOT NULL,`role` text NOT NULL DEFAULT "",`label` text NOT NULL DEFAULT "",`config` text,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `resources` (`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer NOT NULL,`work_item_id` integer,`run_id` integer,`message_id` integer,`storage_kind` text NOT NULL DEFAULT "local",`uri` text NOT NULL,`role` text NOT NULL DEFAULT "",`file_name` text NOT NULL DEFAULT "",`mime_type` text NOT NULL DEFAULT "",`size_bytes` integer NOT NULL DEFAULT 0,`checksum` text NOT NULL DEFAULT "",`metadata` text,`created_at` datetime);
+CREATE TABLE `retention_daily_rollups` (`id` integer PRIMARY KEY AUTOINCREMENT,`day` datetime NOT NULL,`source` text NOT NULL,`key` text NOT NULL,`count` integer NOT NULL,`updated_at` datetime);
+CREATE TABLE `runs` (`id` integer PRIMARY KEY AUTOINCREMENT,`action_id` integer NOT NULL,`work_item_id` integer NOT NULL,`status` text NOT NULL,`agent_id` text,`agent_context_id` integer,`briefing_snapshot` text,`input` text,`output` text,`error_message` text,`error_kind` text,`attempt` integer,`started_at` datetime,`finished_at` datetime,`created_at` datetime,`result_markdown` text,`result_metadata` text,`result_assets` text);
+CREATE TABLE `schema_migrations` (`version` integer,`name` text NOT NULL,`applied_at` datetime NOT NULL,PRIMARY KEY (`version`));
+CREATE VIRTUAL TABLE search_index USING fts5(
+		kind UNINDEXED, ref_id UNINDEXED, project_id UNINDEXED, work_item_id UNINDEXED,
+		thread_id UNINDEXED, created_at UNINDEXED, title, body, tokenize = 'trigram');
+CREATE TABLE 'search_index_config'(k PRIMARY KEY, v) WITHOUT ROWID;
+CREATE TABLE 'search_index_content'(id INTEGER PRIMARY KEY, c0, c1, c2, c3, c4, c5, c6, c7);
+CREATE TABLE 'search_index_data'(id INTEGER PRIMARY KEY, block BLOB);
+CREATE TABLE 'search_index_docsize'(id INTEGER PRIMARY KEY, sz BLOB);
+CREATE TABLE 'search_index_idx'(segid, term, pgno, PRIMARY KEY(segid, term)) WITHOUT ROWID;
+CREATE TABLE `thread_attachments` (`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`message_id` integer,`file_name` text NOT NULL,`file_path` text NOT NULL,`file_size` integer NOT NULL DEFAULT 0,`content_type` text NOT NULL DEFAULT "",`is_directory` numeric NOT NULL DEFAULT false,`uploaded_by` text NOT NULL DEFAULT "",`note` text NOT NULL DEFAULT "",`created_at` datetime);
+CREATE TABLE `thread_context_refs` (`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`project_id` integer NOT NULL,`access` text NOT NULL DEFAULT "read",`note` text NOT NULL DEFAULT "",`granted_by` text NOT NULL DEFAULT "",`created_at` datetime,`expires_at` datetime);
+CREATE TABLE `thread_initiative_links` (`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`initiative_id` integer NOT NULL,`relation_type` text NOT NULL DEFAULT "source",`created_at` datetime);
+CREATE TABLE `thread_members` (`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`kind` text NOT NULL,`user_id` text NOT NULL DEFAULT "",`agent_profile_id` text NOT NULL DEFAULT "",`role` text NOT NULL DEFAULT "member",`status` text NOT NULL DEFAULT "",`agent_data` text,`joined_at` datetime,`last_active_at` datetime);
+CREATE TABLE `thread_messages` (`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`sender_id` text NOT NULL,`role` text NOT NULL,`content` text NOT NULL,`reply_to_msg_id` integer,`metadata` text,`created_at` datetime);
+CREATE TABLE `thread_proposals` (`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`title` text NOT NULL,`summary` text NOT NULL DEFAULT "",`content` text NOT NULL DEFAULT "",`proposed_by` text NOT NULL DEFAULT "",`status` text NOT NULL,`reviewed_by` text,`reviewed_at` datetime,`review_note` text NOT NULL DEFAULT "",`work_item_drafts` text,`source_message_id` integer,`initiative_id` integer,`metadata` text,`created_at` datetime,`updated_at` datetime, `version` integer NOT NULL DEFAULT 1);
+CREATE TABLE `thread_work_item_links` (`id` integer PRIMARY KEY AUTOINCREMENT,`thread_id` integer NOT NULL,`work_item_id` integer NOT NULL,`relation_type` text NOT NULL DEFAULT "related",`is_primary` numeric NOT NULL DEFAULT false,`created_at` datetime);
+CREATE TABLE `threads` (`id` integer PRIMARY KEY AUTOINCREMENT,`title` text NOT NULL,`status` text NOT NULL,`owner_id` text NOT NULL,`focus_project_id` integer,`metadata` text,`created_at` datetime,`updated_at` datetime, `version` integer NOT NULL DEFAULT 1);
+CREATE TABLE `token_revocations` (`token_hash` text,`expires_at` datetime NOT NULL,`created_at` datetime,PRIMARY KEY (`token_hash`));
+CREATE TABLE `usage_daily_rollups` (`id` integer PRIMARY KEY AUTOINCREMENT,`day` datetime NOT NULL,`project_id` integer NOT NULL DEFAULT 0,`agent_id` text NOT NULL,`profile_id` text NOT NULL,`model_id` text NOT NULL,`run_count` integer NOT NULL,`input_tokens` integer NOT NULL,`output_tokens` integer NOT NULL,`cache_read_tokens` integer NOT NULL,`cache_write_tokens` integer NOT NULL,`reasoning_tokens` integer NOT NULL,`total_tokens` integer NOT NULL,`duration_ms` integer NOT NULL,`updated_at` datetime);
+CREATE TABLE `usage_records` (`id` integer PRIMARY KEY AUTOINCREMENT,`run_id` integer NOT NULL,`work_item_id` integer NOT NULL,`action_id` integer NOT NULL,`project_id` integer,`agent_id` text NOT NULL,`profile_id` text NOT NULL,`model_id` text NOT NULL,`input_tokens` integer NOT NULL,`output_tokens` integer NOT NULL,`cache_read_tokens` integer NOT NULL,`cache_write_tokens` integer NOT NULL,`reasoning_tokens` integer NOT NULL,`total_tokens` integer NOT NULL,`duration_ms` integer NOT NULL,`created_at` datetime);
+CREATE TABLE `user_identities` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`provider` text NOT NULL,`subject` text NOT NULL,`email` text NOT NULL DEFAULT "",`last_login_at` datetime,`created_at` datetime);
+CREATE TABLE `user_sessions` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`token_hash` text NOT NULL,`user_agent` text NOT NULL DEFAULT "",`remote_addr` text NOT NULL DEFAULT "",`expires_at` datetime NOT NULL,`created_at` datetime);
+CREATE TABLE `users` (`id` integer PRIMARY KEY AUTOINCREMENT,`username` text NOT NULL,`display_name` text NOT NULL DEFAULT "",`password_hash` text NOT NULL DEFAULT "",`admin` numeric NOT NULL DEFAULT false,`disabled` numeric NOT NULL DEFAULT false,`last_login_at` datetime,`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `vault_secrets` (`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer NOT NULL DEFAULT 0,`profile_id` text NOT NULL DEFAULT "",`name` text NOT NULL,`description` text NOT NULL DEFAULT "",`ciphertext` blob NOT NULL,`key_id` text NOT NULL DEFAULT "",`created_by` text NOT NULL DEFAULT "",`created_at` datetime,`updated_at` datetime);
+CREATE TABLE `work_items` (`id` integer PRIMARY KEY AUTOINCREMENT,`project_id` integer,`resource_space_id` integer,`parent_work_item_id` integer,`root_work_item_id` integer,`final_deliverable_id` integer,`title` text NOT NULL,`body` text NOT NULL,`status` text NOT NULL,`priority` text NOT NULL,`executor_profile_id` text NOT NULL DEFAULT "",`reviewer_profile_id` text NOT NULL DEFAULT "",`active_profile_id` text NOT NULL DEFAULT "",`sponsor_profile_id` text NOT NULL DEFAULT "",`created_by_profile_id` text NOT NULL DEFAULT "",`labels` text,`depends_on` text,`escalation_path` text,`metadata` text,`archived_at` datetime,`created_at` datetime,`updated_at` datetime, `version` integer NOT NULL DEFAULT 1);
+CREATE INDEX `idx_action_io_decls_action` ON `action_io_decls`(`action_id`,`direction`);
+CREATE INDEX idx_action_signals_action_id ON action_signals(action_id, id);
+CREATE INDEX idx_actions_work_item_position_id ON actions(work_item_id, position, id);
+CREATE UNIQUE INDEX `idx_api_tokens_hash` ON `api_tokens`(`token_hash`);
+CREATE INDEX `idx_audit_log_actor` ON `audit_log`(`actor`);
+CREATE INDEX `idx_audit_log_created` ON `audit_log`(`created_at`);
+CREATE INDEX `idx_deliverables_producer` ON `deliverables`(`producer_type`,`producer_id`);
+CREATE INDEX idx_deliverables_thread_created_at ON deliverables(thread_id, created_at DESC) WHERE thread_id IS NOT NULL;
+CREATE INDEX `idx_deliverables_thread_id` ON `deliverables`(`thread_id`);
+CREATE INDEX idx_deliverables_work_item_created_at ON deliverables(work_item_id, created_at DESC) WHERE work_item_id IS NOT NULL;
+CREATE INDEX `idx_deliverables_work_item_id` ON `deliverables`(`work_item_id`);
+CREATE INDEX `idx_event_deliveries_due` ON `event_deliveries`(`status`,`next_attempt_at`);
+CREATE INDEX `idx_event_deliveries_sub` ON `event_deliveries`(`subscription_id`,`created_at`);
+CREATE INDEX idx_event_log_seq ON event_log(seq);
+CREATE UNIQUE INDEX `idx_feature_entries_project_key` ON `feature_entries`(`project_id`,`key`);
+CREATE INDEX `idx_idempotency_keys_expires_at` ON `idempotency_keys`(`expires_at`);
+CREATE UNIQUE INDEX `idx_idempotency_keys_scope_key` ON `idempotency_keys`(`scope`,`idempotency_key`);
+CREATE UNIQUE INDEX `idx_initiative_items_unique` ON `initiative_items`(`initiative_id`,`work_item_id`);
+CREATE INDEX idx_journal_action ON activity_journal(action_id, created_at) WHERE action_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_journal_chain_seq ON activity_journal(chain_seq) WHERE chain_seq > 0;
+CREATE INDEX idx_journal_kind ON activity_journal(kind, created_at);
+CREATE INDEX idx_journal_run ON activity_journal(run_id, created_at) WHERE run_id IS NOT NULL;
+CREATE INDEX idx_journal_work_item ON activity_journal(work_item_id, created_at) WHERE work_item_id IS NOT NULL;
+CREATE INDEX `idx_project_members_user` ON `project_members`(`user_id`);
+CREATE INDEX `idx_resource_spaces_project` ON `resource_spaces`(`project_id`);
+CREATE INDEX `idx_resources_message` ON `resources`(`message_id`) WHERE message_id IS NOT NULL;
+CREATE INDEX `idx_resources_project` ON `resources`(`project_id`);
+CREATE INDEX `idx_resources_run` ON `resources`(`run_id`) WHERE run_id IS NOT NULL;
+CREATE INDEX `idx_resources_work_item` ON `resources`(`work_item_id`) WHERE work_item_id IS NOT NULL;
+CREATE UNIQUE INDEX `idx_retention_rollups_key` ON `retention_daily_rollups`(`day`,`source`,`key`);
+CREATE INDEX idx_runs_action_attempt ON runs(action_id, attempt);
+CREATE INDEX idx_runs_action_result ON runs(action_id, id DESC) WHERE result_markdown IS NOT NULL AND result_markdown != '';
+CREATE INDEX idx_runs_status_id ON runs(status, id);
+CREATE INDEX `idx_thread_attachments_thread_id` ON `thread_attachments`(`thread_id`);
+CREATE UNIQUE INDEX `idx_thread_context_refs_thread_project` ON `thread_context_refs`(`thread_id`,`project_id`);
+CREATE UNIQUE INDEX `idx_thread_initiative_links_unique` ON `thread_initiative_links`(`thread_id`,`initiative_id`);
+CREATE INDEX idx_thread_members_thread_profile_status ON thread_members(thread_id, agent_profile_id, status);
+CREATE INDEX idx_thread_messages_thread_id ON thread_messages(thread_id, id);
+CREATE INDEX `idx_thread_proposals_status` ON `thread_proposals`(`status`);
+CREATE INDEX `idx_thread_proposals_thread_id` ON `thread_proposals`(`thread_id`);
+CREATE UNIQUE INDEX `idx_thread_work_item_links_unique` ON `thread_work_item_links`(`thread_id`,`work_item_id`);
+CREATE INDEX `idx_token_revocations_expires` ON `token_revocations`(`expires_at`);
+CREATE INDEX idx_usage_records_created_at ON usage_records(created_at);
+CREATE INDEX idx_usage_records_project_created_at ON usage_records(project_id, created_at);
+CREATE INDEX idx_usage_records_run_id ON usage_records(run_id);
+CREATE UNIQUE INDEX `idx_usage_rollups_key` ON `usage_daily_rollups`(`day`,`project_id`,`agent_id`,`profile_id`,`model_id`);
+CREATE UNIQUE INDEX `idx_user_identities_subject` ON `user_identities`(`provider`,`subject`);
+CREATE INDEX `idx_user_identities_user` ON `user_identities`(`user_id`);
+CREATE UNIQUE INDEX `idx_user_sessions_token` ON `user_sessions`(`token_hash`);
+CREATE INDEX `idx_user_sessions_user` ON `user_sessions`(`user_id`);
+CREATE UNIQUE INDEX `idx_users_username` ON `users`(`username`);
+CREATE UNIQUE INDEX `idx_vault_secrets_scope` ON `vault_secrets`(`project_id`,`profile_id`,`name`);
@@ -42,6 +42,7 @@ func (s *Store) CreateThread(ctx context.Context, thread *core.Thread) (int64, e
 	}
 	thread.ID = model.ID
 	thread.Title = title
+	thread.Version = 1
 	thread.CreatedAt = now
 	thread.UpdatedAt = now
 	return model.ID, nil
@@ -113,24 +114,19 @@ func (s *Store) UpdateThread(ctx context.Context, thread *core.Thread) error {
 	model := threadModelFromCore(thread)
 	model.UpdatedAt = now
 
-	result := s.orm.WithContext(ctx).Model(&ThreadModel{}).
-		Where("id = ?", thread.ID).
-		Updates(map[string]any{
-			"title":            model.Title,
-			"status":           model.Status,
-			"owner_id":         model.OwnerID,
-			"focus_project_id": model.FocusProjectID,
-			"metadata":         model.Metadata,
-			"updated_at":       model.UpdatedAt,
-		})
-
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
+	version, err := versionedUpdate(s.orm.WithContext(ctx), &ThreadModel{}, "thread", thread.ID, thread.Version, map[string]any{
+		"title":            model.Title,
+		"status":           model.Status,
+		"owner_id":         model.OwnerID,
+		"focus_project_id": model.FocusProjectID,
+		"metadata":         model.Metadata,
+		"updated_at":       model.UpdatedAt,
+	})
+	if err != nil {
+		return err
 	}
 
+	thread.Version = version
 	thread.UpdatedAt = now
 	return nil
 }
@@ -175,7 +171,7 @@ func (s *Store) CreateThreadWithParticipants(ctx context.Context, thread *core.T
 	model.CreatedAt = now
 	model.UpdatedAt = now
 
-	err := s.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err := s.writeTx(ctx, func(tx *gorm.DB) error {
 		if err := tx.Create(model).Error; err != nil {
 			return err
 		}
@@ -226,6 +222,7 @@ func (s *Store) CreateThreadWithParticipants(ctx context.Context, thread *core.T
 
 	thread.ID = model.ID
 	thread.Title = title
+	thread.Version = 1
 	thread.CreatedAt = now
 	thread.UpdatedAt = now
 	return nil
@@ -506,7 +503,7 @@ func (s *Store) CreateThreadWorkItemLink(ctx context.Context, link *core.ThreadW
 		CreatedAt:    now,
 	}
 
-	err := s.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err := s.writeTx(ctx, func(tx *gorm.DB) error {
 		if link.IsPrimary {
 			if err := tx.Model(&ThreadWorkItemLinkModel{}).
 				Where("thread_id = ? AND is_primary = ?", link.ThreadID, true).
@@ -19,7 +19,7 @@ func (s *Store) CreateUsageRecord(ctx context.Context, r *core.UsageRecord) (int
 
 	// Dual-write to activity_journal.
 	if entry := core.UsageRecordToJournalEntry(r); entry != nil {
-		_, _ = s.AppendJournal(ctx, entry)
+		_ = s.EnqueueJournal(ctx, entry)
 	}
 	return model.ID, nil
 }
@@ -48,19 +48,33 @@ func (s *Store) GetUsageByRun(ctx context.Context, runID int64) (*core.UsageReco
 	return model.toCore(), nil
 }
 
+// usageAnalyticsSource merges live usage records with the daily rollups of
+// records removed by the retention service.
+const usageAnalyticsSource = `(
+		SELECT project_id, agent_id, profile_id, 1 AS run_count,
+			input_tokens, output_tokens, cache_read_tokens, cache_write_tokens,
+			reasoning_tokens, total_tokens, created_at
+		FROM usage_records
+		UNION ALL
+		SELECT NULLIF(project_id, 0), agent_id, profile_id, run_count,
+			input_tokens, output_tokens, cache_read_tokens, cache_write_tokens,
+			reasoning_tokens, total_tokens, day AS created_at
+		FROM usage_daily_rollups
+	)`
+
 func (s *Store) UsageByProject(ctx context.Context, filter core.AnalyticsFilter) ([]core.ProjectUsageSummary, error) {
 	query := `
 		SELECT
 			COALESCE(u.project_id, 0),
 			COALESCE(p.name, '(no project)'),
-			COUNT(*) AS exec_count,
+			SUM(u.run_count) AS exec_count,
 			SUM(u.input_tokens),
 			SUM(u.output_tokens),
 			SUM(u.cache_read_tokens),
 			SUM(u.cache_write_tokens),
 			SUM(u.reasoning_tokens),
 			SUM(u.total_tokens)
-		FROM usage_records u
+		FROM ` + usageAnalyticsSource + ` u
 		LEFT JOIN projects p ON p.id = u.project_id`
 
 	conditions, args := usageFilterConditions(filter)
@@ -97,14 +111,14 @@ func (s *Store) UsageByAgent(ctx context.Context, filter core.AnalyticsFilter) (
 			u.agent_id,
 			u.project_id,
 			COALESCE(p.name, ''),
-			COUNT(*) AS exec_count,
+			SUM(u.run_count) AS exec_count,
 			SUM(u.input_tokens),
 			SUM(u.output_tokens),
 			SUM(u.cache_read_tokens),
 			SUM(u.cache_write_tokens),
 			SUM(u.reasoning_tokens),
 			SUM(u.total_tokens)
-		FROM usage_records u
+		FROM ` + usageAnalyticsSource + ` u
 		LEFT JOIN projects p ON p.id = u.project_id`
 
 	conditions, args := usageFilterConditions(filter)
@@ -142,14 +156,14 @@ func (s *Store) UsageByProfile(ctx context.Context, filter core.AnalyticsFilter)
 			u.agent_id,
 			u.project_id,
 			COALESCE(p.name, ''),
-			COUNT(*) AS exec_count,
+			SUM(u.run_count) AS exec_count,
 			SUM(u.input_tokens),
 			SUM(u.output_tokens),
 			SUM(u.cache_read_tokens),
 			SUM(u.cache_write_tokens),
 			SUM(u.reasoning_tokens),
 			SUM(u.total_tokens)
-		FROM usage_records u
+		FROM ` + usageAnalyticsSource + ` u
 		LEFT JOIN projects p ON p.id = u.project_id`
 
 	conditions, args := usageFilterConditions(filter)
@@ -183,14 +197,14 @@ func (s *Store) UsageByProfile(ctx context.Context, filter core.AnalyticsFilter)
 func (s *Store) UsageTotals(ctx context.Context, filter core.AnalyticsFilter) (*core.UsageTotalSummary, error) {
 	query := `
 		SELECT
-			COUNT(*),
+			COALESCE(SUM(run_count), 0),
 			COALESCE(SUM(input_tokens), 0),
 			COALESCE(SUM(output_tokens), 0),
 			COALESCE(SUM(cache_read_tokens), 0),
 			COALESCE(SUM(cache_write_tokens), 0),
 			COALESCE(SUM(reasoning_tokens), 0),
 			COALESCE(SUM(total_tokens), 0)
-		FROM usage_records u`
+		FROM ` + usageAnalyticsSource + ` u`
 
 	conditions, args := usageFilterConditions(filter)
 	if len(conditions) > 0 {
@@ -0,0 +1,387 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserModel is the GORM model for console user accounts.
+type UserModel struct {
+	ID           int64      `gorm:"column:id;primaryKey;autoIncrement"`
+	Username     string     `gorm:"column:username;not null;uniqueIndex:idx_users_username"`
+	DisplayName  string     `gorm:"column:display_name;not null;default:''"`
+	PasswordHash string     `gorm:"column:password_hash;not null;default:''"`
+	Admin        bool       `gorm:"column:admin;not null;default:false"`
+	Disabled     bool       `gorm:"column:disabled;not null;default:false"`
+	LastLoginAt  *time.Time `gorm:"column:last_login_at"`
+	CreatedAt    time.Time  `gorm:"column:created_at"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at"`
+}
+
+func (UserModel) TableName() string { return "users" }
+
+// UserSessionModel is the GORM model for console login sessions.
+type UserSessionModel struct {
+	ID         int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID     int64     `gorm:"column:user_id;not null;index:idx_user_sessions_user"`
+	TokenHash  string    `gorm:"column:token_hash;not null;uniqueIndex:idx_user_sessions_token"`
+	UserAgent  string    `gorm:"column:user_agent;not null;default:''"`
+	RemoteAddr string    `gorm:"column:remote_addr;not null;default:''"`
+	ExpiresAt  time.Time `gorm:"column:expires_at;not null"`
+	CreatedAt  time.Time `gorm:"column:created_at"`
+}
+
+func (UserSessionModel) TableName() string { return "user_sessions" }
+
+// ProjectMemberModel is the GORM model for per-project user roles.
+type ProjectMemberModel struct {
+	ProjectID int64     `gorm:"column:project_id;primaryKey"`
+	UserID    int64     `gorm:"column:user_id;primaryKey;index:idx_project_members_user"`
+	Role      string    `gorm:"column:role;not null"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+func (ProjectMemberModel) TableName() string { return "project_members" }
+
+// UserIdentityModel is the GORM model linking users to external identity
+// provider accounts.
+type UserIdentityModel struct {
+	ID          int64      `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID      int64      `gorm:"column:user_id;not null;index:idx_user_identities_user"`
+	Provider    string     `gorm:"column:provider;not null;uniqueIndex:idx_user_identities_subject,priority:1"`
+	Subject     string     `gorm:"column:subject;not null;uniqueIndex:idx_user_identities_subject,priority:2"`
+	Email       string     `gorm:"column:email;not null;default:''"`
+	LastLoginAt *time.Time `gorm:"column:last_login_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at"`
+}
+
+func (UserIdentityModel) TableName() string { return "user_identities" }
+
+func migrateUsersUp(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&UserModel{}, &UserSessionModel{}, &ProjectMemberModel{}); err != nil {
+		return err
+	}
+	if !tx.Migrator().HasColumn(&ActionSignalModel{}, "ActorUserID") {
+		if err := tx.Migrator().AddColumn(&ActionSignalModel{}, "ActorUserID"); err != nil {
+			return fmt.Errorf("add action_signals.actor_user_id: %w", err)
+		}
+	}
+	return nil
+}
+
+func migrateUsersDown(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&ActionSignalModel{}, "ActorUserID") {
+		if err := tx.Exec(`ALTER TABLE action_signals DROP COLUMN actor_user_id`).Error; err != nil {
+			return fmt.Errorf("drop action_signals.actor_user_id: %w", err)
+		}
+	}
+	return tx.Migrator().DropTable(&ProjectMemberModel{}, &UserSessionModel{}, &UserModel{})
+}
+
+func migrateUserIdentitiesUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&UserIdentityModel{})
+}
+
+func migrateUserIdentitiesDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&UserIdentityModel{})
+}
+
+func userModelFromCore(u *core.User) *UserModel {
+	return &UserModel{
+		ID:           u.ID,
+		Username:     u.Username,
+		DisplayName:  u.DisplayName,
+		PasswordHash: u.PasswordHash,
+		Admin:        u.Admin,
+		Disabled:     u.Disabled,
+		LastLoginAt:  u.LastLoginAt,
+		CreatedAt:    u.CreatedAt,
+		UpdatedAt:    u.UpdatedAt,
+	}
+}
+
+func (m *UserModel) toCore() *core.User {
+	return &core.User{
+		ID:           m.ID,
+		Username:     m.Username,
+		DisplayName:  m.DisplayName,
+		PasswordHash: m.PasswordHash,
+		Admin:        m.Admin,
+		Disabled:     m.Disabled,
+		LastLoginAt:  m.LastLoginAt,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+	}
+}
+
+func (m *UserSessionModel) toCore() *core.UserSession {
+	return &core.UserSession{
+		ID:         m.ID,
+		UserID:     m.UserID,
+		TokenHash:  m.TokenHash,
+		UserAgent:  m.UserAgent,
+		RemoteAddr: m.RemoteAddr,
+		ExpiresAt:  m.ExpiresAt,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+func (m *UserIdentityModel) toCore() *core.UserIdentity {
+	return &core.UserIdentity{
+		ID:          m.ID,
+		UserID:      m.UserID,
+		Provider:    m.Provider,
+		Subject:     m.Subject,
+		Email:       m.Email,
+		LastLoginAt: m.LastLoginAt,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+func (m *ProjectMemberModel) toCore() *core.ProjectMember {
+	return &core.ProjectMember{
+		ProjectID: m.ProjectID,
+		UserID:    m.UserID,
+		Role:      core.ProjectRole(m.Role),
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func (s *Store) CreateUser(ctx context.Context, u *core.User) (int64, error) {
+	now := time.Now().UTC()
+	u.CreatedAt, u.UpdatedAt = now, now
+	model := userModelFromCore(u)
+	if err := s.orm.WithContext(ctx).Create(model).Error; err != nil {
+		return 0, fmt.Errorf("insert user: %w", err)
+	}
+	u.ID = model.ID
+	return model.ID, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, id int64) (*core.User, error) {
+	var model UserModel
+	err := s.orm.WithContext(ctx).Where("id = ?", id).First(&model).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, core.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user %d: %w", id, err)
+	}
+	return model.toCore(), nil
+}
+
+// GetUserByUsername matches usernames case-insensitively.
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*core.User, error) {
+	var model UserModel
+	err := s.orm.WithContext(ctx).Where("LOWER(username) = ?", strings.ToLower(strings.TrimSpace(username))).First(&model).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, core.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user %q: %w", username, err)
+	}
+	return model.toCore(), nil
+}
+
+func (s *Store) ListUsers(ctx context.Context) ([]*core.User, error) {
+	var models []UserModel
+	if err := s.orm.WithContext(ctx).Order("id ASC").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	out := make([]*core.User, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, u *core.User) error {
+	u.UpdatedAt = time.Now().UTC()
+	result := s.orm.WithContext(ctx).Model(&UserModel{}).Where("id = ?", u.ID).Updates(map[string]any{
+		"display_name":  u.DisplayName,
+		"password_hash": u.PasswordHash,
+		"admin":         u.Admin,
+		"disabled":      u.Disabled,
+		"last_login_at": u.LastLoginAt,
+		"updated_at":    u.UpdatedAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("update user %d: %w", u.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) CreateUserSession(ctx context.Context, sess *core.UserSession) (int64, error) {
+	sess.CreatedAt = time.Now().UTC()
+	model := &UserSessionModel{
+		UserID:     sess.UserID,
+		TokenHash:  sess.TokenHash,
+		UserAgent:  sess.UserAgent,
+		RemoteAddr: sess.RemoteAddr,
+		ExpiresAt:  sess.ExpiresAt,
+		CreatedAt:  sess.CreatedAt,
+	}
+	if err := s.orm.WithContext(ctx).Create(model).Error; err != nil {
+		return 0, fmt.Errorf("insert user session: %w", err)
+	}
+	sess.ID = model.ID
+	return model.ID, nil
+}
+
+func (s *Store) GetUserSessionByTokenHash(ctx context.Context, tokenHash string) (*core.UserSession, error) {
+	var model UserSessionModel
+	err := s.orm.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&model).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, core.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user session: %w", err)
+	}
+	return model.toCore(), nil
+}
+
+func (s *Store) DeleteUserSession(ctx context.Context, id int64) error {
+	if err := s.orm.WithContext(ctx).Where("id = ?", id).Delete(&UserSessionModel{}).Error; err != nil {
+		return fmt.Errorf("delete user session %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteUserSessions(ctx context.Context, userID int64) error {
+	if err := s.orm.WithContext(ctx).Where("user_id = ?", userID).Delete(&UserSessionModel{}).Error; err != nil {
+		return fmt.Errorf("delete sessions of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+func (s *Store) ListUserSessions(ctx context.Context, userID int64) ([]*core.UserSession, error) {
+	var models []UserSessionModel
+	if err := s.orm.WithContext(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list sessions of user %d: %w", userID, err)
+	}
+	out := make([]*core.UserSession, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
+
+func (s *Store) CreateUserIdentity(ctx context.Context, id *core.UserIdentity) (int64, error) {
+	id.CreatedAt = time.Now().UTC()
+	model := &UserIdentityModel{
+		UserID:      id.UserID,
+		Provider:    id.Provider,
+		Subject:     id.Subject,
+		Email:       id.Email,
+		LastLoginAt: id.LastLoginAt,
+		CreatedAt:   id.CreatedAt,
+	}
+	if err := s.orm.WithContext(ctx).Create(model).Error; err != nil {
+		return 0, fmt.Errorf("insert user identity: %w", err)
+	}
+	id.ID = model.ID
+	return model.ID, nil
+}
+
+func (s *Store) GetUserIdentity(ctx context.Context, provider, subject string) (*core.UserIdentity, error) {
+	var model UserIdentityModel
+	err := s.orm.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&model).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, core.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user identity: %w", err)
+	}
+	return model.toCore(), nil
+}
+
+func (s *Store) UpdateUserIdentity(ctx context.Context, id *core.UserIdentity) error {
+	result := s.orm.WithContext(ctx).Model(&UserIdentityModel{}).Where("id = ?", id.ID).Updates(map[string]any{
+		"email":         id.Email,
+		"last_login_at": id.LastLoginAt,
+	})
+	if result.Error != nil {
+		return fmt.Errorf("update user identity %d: %w", id.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) ListUserIdentities(ctx context.Context, userID int64) ([]*core.UserIdentity, error) {
+	var models []UserIdentityModel
+	if err := s.orm.WithContext(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list identities of user %d: %w", userID, err)
+	}
+	out := make([]*core.UserIdentity, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
+
+func (s *Store) UpsertProjectMember(ctx context.Context, m *core.ProjectMember) error {
+	now := time.Now().UTC()
+	m.UpdatedAt = now
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = now
+	}
+	model := &ProjectMemberModel{
+		ProjectID: m.ProjectID,
+		UserID:    m.UserID,
+		Role:      string(m.Role),
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+	err := s.orm.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(model).Error
+	if err != nil {
+		return fmt.Errorf("upsert project member: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteProjectMember(ctx context.Context, projectID, userID int64) error {
+	result := s.orm.WithContext(ctx).Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&ProjectMemberModel{})
+	if result.Error != nil {
+		return fmt.Errorf("delete project member: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) ListProjectMembers(ctx context.Context, projectID int64) ([]*core.ProjectMember, error) {
+	return s.listProjectMembers(ctx, "project_id = ?", projectID)
+}
+
+func (s *Store) ListUserMemberships(ctx context.Context, userID int64) ([]*core.ProjectMember, error) {
+	return s.listProjectMembers(ctx, "user_id = ?", userID)
+}
+
+func (s *Store) listProjectMembers(ctx context.Context, where string, arg int64) ([]*core.ProjectMember, error) {
+	var models []ProjectMemberModel
+	if err := s.orm.WithContext(ctx).Where(where, arg).Order("project_id ASC, user_id ASC").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list project members: %w", err)
+	}
+	out := make([]*core.ProjectMember, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VaultSecretModel is the GORM model for encrypted vault secrets. ProjectID
+// 0 and an empty ProfileID mean "any", so the scope columns stay NOT NULL
+// and the unique index covers global secrets too.
+type VaultSecretModel struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	ProjectID   int64     `gorm:"column:project_id;not null;default:0;uniqueIndex:idx_vault_secrets_scope,priority:1"`
+	ProfileID   string    `gorm:"column:profile_id;not null;default:'';uniqueIndex:idx_vault_secrets_scope,priority:2"`
+	Name        string    `gorm:"column:name;not null;uniqueIndex:idx_vault_secrets_scope,priority:3"`
+	Description string    `gorm:"column:description;not null;default:''"`
+	Ciphertext  []byte    `gorm:"column:ciphertext;not null"`
+	KeyID       string    `gorm:"column:key_id;not null;default:''"`
+	CreatedBy   string    `gorm:"column:created_by;not null;default:''"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
+}
+
+func (VaultSecretModel) TableName() string { return "vault_secrets" }
+
+func migrateVaultUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&VaultSecretModel{})
+}
+
+func migrateVaultDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&VaultSecretModel{})
+}
+
+func (m *VaultSecretModel) toCore() *core.VaultSecret {
+	return &core.VaultSecret{
+		ID:          m.ID,
+		ProjectID:   m.ProjectID,
+		ProfileID:   m.ProfileID,
+		Name:        m.Name,
+		Description: m.Description,
+		Ciphertext:  m.Ciphertext,
+		KeyID:       m.KeyID,
+		CreatedBy:   m.CreatedBy,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+func (s *Store) UpsertVaultSecret(ctx context.Context, secret *core.VaultSecret) (int64, error) {
+	now := time.Now().UTC()
+	model := &VaultSecretModel{
+		ProjectID:   secret.ProjectID,
+		ProfileID:   secret.ProfileID,
+		Name:        secret.Name,
+		Description: secret.Description,
+		Ciphertext:  secret.Ciphertext,
+		KeyID:       secret.KeyID,
+		CreatedBy:   secret.CreatedBy,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	err := s.orm.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "profile_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description", "ciphertext", "key_id", "updated_at"}),
+	}).Create(model).Error
+	if err != nil {
+		return 0, fmt.Errorf("upsert vault secret: %w", err)
+	}
+	// On conflict SQLite does not report the existing row's ID.
+	var stored VaultSecretModel
+	if err := s.orm.WithContext(ctx).
+		Where("project_id = ? AND profile_id = ? AND name = ?", secret.ProjectID, secret.ProfileID, secret.Name).
+		First(&stored).Error; err != nil {
+		return 0, fmt.Errorf("reload vault secret: %w", err)
+	}
+	secret.ID = stored.ID
+	secret.CreatedBy = stored.CreatedBy
+	secret.CreatedAt = stored.CreatedAt
+	secret.UpdatedAt = stored.UpdatedAt
+	return stored.ID, nil
+}
+
+func (s *Store) GetVaultSecret(ctx context.Context, id int64) (*core.VaultSecret, error) {
+	var model VaultSecretModel
+	err := s.orm.WithContext(ctx).Where("id = ?", id).First(&model).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, core.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get vault secret: %w", err)
+	}
+	return model.toCore(), nil
+}
+
+func (s *Store) ListVaultSecrets(ctx context.Context, filter core.VaultSecretFilter) ([]*core.VaultSecret, error) {
+	q := s.orm.WithContext(ctx).Model(&VaultSecretModel{})
+	if filter.ProjectID != nil {
+		q = q.Where("project_id = ?", *filter.ProjectID)
+	}
+	if filter.ProfileID != nil {
+		q = q.Where("profile_id = ?", *filter.ProfileID)
+	}
+	if filter.Name != "" {
+		q = q.Where("name = ?", filter.Name)
+	}
+	return findVaultSecrets(q)
+}
+
+func (s *Store) ListVaultSecretsInScope(ctx context.Context, projectID int64, profileID string) ([]*core.VaultSecret, error) {
+	q := s.orm.WithContext(ctx).Model(&VaultSecretModel{}).
+		Where("project_id IN (0, ?) AND profile_id IN ('', ?)", projectID, profileID)
+	return findVaultSecrets(q)
+}
+
+func findVaultSecrets(q *gorm.DB) ([]*core.VaultSecret, error) {
+	var models []VaultSecretModel
+	if err := q.Order("name ASC, project_id ASC, profile_id ASC").Find(&models).Error; err != nil {
+		return nil, fmt.Errorf("list vault secrets: %w", err)
+	}
+	out := make([]*core.VaultSecret, 0, len(models))
+	for i := range models {
+		out = append(out, models[i].toCore())
+	}
+	return out, nil
+}
+
+func (s *Store) DeleteVaultSecret(ctx context.Context, id int64) error {
+	result := s.orm.WithContext(ctx).Where("id = ?", id).Delete(&VaultSecretModel{})
+	if result.Error != nil {
+		return fmt.Errorf("delete vault secret %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return core.ErrNotFound
+	}
+	return nil
+}
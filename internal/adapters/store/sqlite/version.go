@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// versionedModels are the aggregates that carry an optimistic-concurrency
+// version column.
+var versionedModels = []any{
+	&WorkItemModel{},
+	&ActionModel{},
+	&DAGTemplateModel{},
+	&ThreadModel{},
+	&ThreadProposalModel{},
+}
+
+func migrateRowVersionsUp(tx *gorm.DB) error {
+	for _, model := range versionedModels {
+		if tx.Migrator().HasColumn(model, "Version") {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(model, "Version"); err != nil {
+			return fmt.Errorf("add version column: %w", err)
+		}
+	}
+	return nil
+}
+
+func migrateRowVersionsDown(tx *gorm.DB) error {
+	for _, model := range versionedModels {
+		if !tx.Migrator().HasColumn(model, "Version") {
+			continue
+		}
+		// Migrator().DropColumn rebuilds the table on SQLite, which trips the
+		// search triggers that reference work_items; ALTER TABLE does not.
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE ? DROP COLUMN version", clause.Table{Name: stmt.Schema.Table}).Error; err != nil {
+			return fmt.Errorf("drop %s.version: %w", stmt.Schema.Table, err)
+		}
+	}
+	return nil
+}
+
+// bumpVersion is merged into every write to a versioned row so that partial
+// updates (status, metadata, archive) also invalidate outstanding ETags.
+func bumpVersion(updates map[string]any) map[string]any {
+	updates["version"] = gorm.Expr("version + 1")
+	return updates
+}
+
+// versionedUpdate applies updates to the row id of model and returns its new
+// version. When expected is non-zero the write only lands if the row is still
+// at that version; otherwise it fails with *core.VersionConflictError. A zero
+// expected version is an unconditional write.
+func versionedUpdate(db *gorm.DB, model any, resource string, id, expected int64, updates map[string]any) (int64, error) {
+	query := db.Model(model).Where("id = ?", id)
+	if expected > 0 {
+		query = query.Where("version = ?", expected)
+	}
+	result := query.Updates(bumpVersion(updates))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 && expected > 0 {
+		return expected + 1, nil
+	}
+
+	var versions []int64
+	if err := db.Model(model).Where("id = ?", id).Pluck("version", &versions).Error; err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, core.ErrNotFound
+	}
+	if result.RowsAffected == 0 {
+		return 0, &core.VersionConflictError{Resource: resource, ID: id, Expected: expected, Actual: versions[0]}
+	}
+	return versions[0], nil
+}
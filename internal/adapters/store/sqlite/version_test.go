@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestUpdateActionRejectsStaleVersion(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	workItemID, err := s.CreateWorkItem(ctx, &core.WorkItem{Title: "versioned"})
+	if err != nil {
+		t.Fatalf("create work item: %v", err)
+	}
+	action := &core.Action{WorkItemID: workItemID, Name: "impl", Type: core.ActionExec, Status: core.ActionPending}
+	if _, err := s.CreateAction(ctx, action); err != nil {
+		t.Fatalf("create action: %v", err)
+	}
+	if action.Version != 1 {
+		t.Fatalf("expected version 1 after create, got %d", action.Version)
+	}
+
+	human, _ := s.GetAction(ctx, action.ID)
+	engine, _ := s.GetAction(ctx, action.ID)
+
+	human.Description = "edited in the UI"
+	if err := s.UpdateAction(ctx, human); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	if human.Version != 2 {
+		t.Fatalf("expected version 2 after update, got %d", human.Version)
+	}
+
+	engine.RetryCount++
+	err = s.UpdateAction(ctx, engine)
+	if !errors.Is(err, core.ErrVersionConflict) {
+		t.Fatalf("expected version conflict, got %v", err)
+	}
+	var conflict *core.VersionConflictError
+	if !errors.As(err, &conflict) || conflict.Expected != 1 || conflict.Actual != 2 {
+		t.Fatalf("unexpected conflict detail: %+v", conflict)
+	}
+
+	got, _ := s.GetAction(ctx, action.ID)
+	if got.Description != "edited in the UI" || got.RetryCount != 0 {
+		t.Fatalf("stale write leaked into row: %+v", got)
+	}
+}
+
+func TestPartialUpdatesBumpVersion(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	item := &core.WorkItem{Title: "versioned"}
+	id, err := s.CreateWorkItem(ctx, item)
+	if err != nil {
+		t.Fatalf("create work item: %v", err)
+	}
+	if err := s.UpdateWorkItemStatus(ctx, id, core.WorkItemAccepted); err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+	if err := s.UpdateWorkItemMetadata(ctx, id, map[string]any{"k": "v"}); err != nil {
+		t.Fatalf("update metadata: %v", err)
+	}
+	got, _ := s.GetWorkItem(ctx, id)
+	if got.Version != 3 {
+		t.Fatalf("expected version 3, got %d", got.Version)
+	}
+
+	item.Title = "stale"
+	if err := s.UpdateWorkItem(ctx, item); !errors.Is(err, core.ErrVersionConflict) {
+		t.Fatalf("expected version conflict for stale work item, got %v", err)
+	}
+	got.Title = "fresh"
+	if err := s.UpdateWorkItem(ctx, got); err != nil {
+		t.Fatalf("fresh update: %v", err)
+	}
+	if got.Version != 4 {
+		t.Fatalf("expected version 4, got %d", got.Version)
+	}
+}
+
+func TestUpdateWithZeroVersionIsUnconditional(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	thread := &core.Thread{Title: "versioned", OwnerID: "u"}
+	if _, err := s.CreateThread(ctx, thread); err != nil {
+		t.Fatalf("create thread: %v", err)
+	}
+	blind := &core.Thread{ID: thread.ID, Title: "blind", OwnerID: "u"}
+	if err := s.UpdateThread(ctx, blind); err != nil {
+		t.Fatalf("blind update: %v", err)
+	}
+	if blind.Version != 2 {
+		t.Fatalf("expected version 2, got %d", blind.Version)
+	}
+	if err := s.UpdateThread(ctx, &core.Thread{ID: thread.ID + 100, Title: "x", OwnerID: "u"}); !errors.Is(err, core.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
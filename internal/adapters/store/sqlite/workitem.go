@@ -41,8 +41,10 @@ func (s *Store) CreateWorkItem(ctx context.Context, workItem *core.WorkItem) (in
 	}
 	workItem.ID = model.ID
 	workItem.Title = title
+	workItem.Version = 1
 	workItem.CreatedAt = now
 	workItem.UpdatedAt = now
+	s.journalWorkItemState(ctx, nil, workItem)
 	return model.ID, nil
 }
 
@@ -117,37 +119,44 @@ func (s *Store) UpdateWorkItem(ctx context.Context, workItem *core.WorkItem) err
 	now := time.Now().UTC()
 	model := workItemModelFromCore(workItem)
 	model.UpdatedAt = now
-
-	result := s.orm.WithContext(ctx).Model(&WorkItemModel{}).
-		Where("id = ?", workItem.ID).
-		Updates(map[string]any{
-			"project_id":            model.ProjectID,
-			"resource_space_id":     model.ResourceSpaceID,
-			"parent_work_item_id":   model.ParentWorkItemID,
-			"root_work_item_id":     model.RootWorkItemID,
-			"final_deliverable_id":  model.FinalDeliverableID,
-			"title":                 model.Title,
-			"body":                  model.Body,
-			"status":                model.Status,
-			"priority":              model.Priority,
-			"executor_profile_id":   model.ExecutorProfileID,
-			"reviewer_profile_id":   model.ReviewerProfileID,
-			"active_profile_id":     model.ActiveProfileID,
-			"sponsor_profile_id":    model.SponsorProfileID,
-			"created_by_profile_id": model.CreatedByProfileID,
-			"labels":                model.Labels,
-			"depends_on":            model.DependsOn,
-			"escalation_path":       model.EscalationPath,
-			"metadata":              model.Metadata,
-			"updated_at":            model.UpdatedAt,
-		})
-	if result.Error != nil {
-		return result.Error
+	updates := map[string]any{
+		"project_id":            model.ProjectID,
+		"resource_space_id":     model.ResourceSpaceID,
+		"parent_work_item_id":   model.ParentWorkItemID,
+		"root_work_item_id":     model.RootWorkItemID,
+		"final_deliverable_id":  model.FinalDeliverableID,
+		"title":                 model.Title,
+		"body":                  model.Body,
+		"status":                model.Status,
+		"priority":              model.Priority,
+		"executor_profile_id":   model.ExecutorProfileID,
+		"reviewer_profile_id":   model.ReviewerProfileID,
+		"active_profile_id":     model.ActiveProfileID,
+		"sponsor_profile_id":    model.SponsorProfileID,
+		"created_by_profile_id": model.CreatedByProfileID,
+		"labels":                model.Labels,
+		"depends_on":            model.DependsOn,
+		"escalation_path":       model.EscalationPath,
+		"metadata":              model.Metadata,
+		"updated_at":            model.UpdatedAt,
 	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
+
+	var before *core.WorkItem
+	var version int64
+	err := s.withWorkItemSnapshot(ctx, workItem.ID, func(tx *gorm.DB, snapshot *core.WorkItem) error {
+		before = snapshot
+		var err error
+		version, err = versionedUpdate(tx, &WorkItemModel{}, "work item", workItem.ID, workItem.Version, updates)
+		return err
+	})
+	if err != nil {
+		return err
 	}
+	workItem.Version = version
 	workItem.UpdatedAt = now
+	if before != nil {
+		s.journalWorkItemState(ctx, before, workItem)
+	}
 	return nil
 }
 
@@ -156,18 +165,25 @@ func (s *Store) UpdateWorkItemStatus(ctx context.Context, id int64, status core.
 		return fmt.Errorf("store is not initialized")
 	}
 
-	result := s.orm.WithContext(ctx).Model(&WorkItemModel{}).
-		Where("id = ?", id).
-		Updates(map[string]any{
-			"status":     string(status),
-			"updated_at": time.Now().UTC(),
-		})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected == 0 {
-		return core.ErrNotFound
+	var before *core.WorkItem
+	err := s.withWorkItemSnapshot(ctx, id, func(tx *gorm.DB, snapshot *core.WorkItem) error {
+		if snapshot == nil {
+			return core.ErrNotFound
+		}
+		before = snapshot
+		return tx.Model(&WorkItemModel{}).
+			Where("id = ?", id).
+			Updates(bumpVersion(map[string]any{
+				"status":     string(status),
+				"updated_at": time.Now().UTC(),
+			})).Error
+	})
+	if err != nil {
+		return err
 	}
+	after := *before
+	after.Status = status
+	s.journalWorkItemState(ctx, before, &after)
 	return nil
 }
 
@@ -178,10 +194,10 @@ func (s *Store) UpdateWorkItemMetadata(ctx context.Context, id int64, metadata m
 
 	result := s.orm.WithContext(ctx).Model(&WorkItemModel{}).
 		Where("id = ?", id).
-		Updates(map[string]any{
+		Updates(bumpVersion(map[string]any{
 			"metadata":   JSONField[map[string]any]{Data: metadata},
 			"updated_at": time.Now().UTC(),
-		})
+		}))
 	if result.Error != nil {
 		return fmt.Errorf("update work item metadata: %w", result.Error)
 	}
@@ -206,41 +222,37 @@ func (s *Store) PrepareWorkItemRun(ctx context.Context, id int64, queuedStatus c
 		string(core.WorkItemNeedsRework),
 		string(core.WorkItemEscalated),
 	}
-	result := s.orm.WithContext(ctx).Model(&WorkItemModel{}).
-		Where("id = ? AND status IN ? AND archived_at IS NULL", id, allowedStatuses).
-		Updates(map[string]any{
-			"status":     string(queuedStatus),
-			"updated_at": time.Now().UTC(),
-		})
-	if result.Error != nil {
-		return fmt.Errorf("prepare work item run: %w", result.Error)
-	}
-	if result.RowsAffected > 0 {
+	var before *core.WorkItem
+	err := s.withWorkItemSnapshot(ctx, id, func(tx *gorm.DB, snapshot *core.WorkItem) error {
+		if snapshot == nil {
+			return core.ErrNotFound
+		}
+		before = snapshot
+		result := tx.Model(&WorkItemModel{}).
+			Where("id = ? AND status IN ? AND archived_at IS NULL", id, allowedStatuses).
+			Updates(bumpVersion(map[string]any{
+				"status":     string(queuedStatus),
+				"updated_at": time.Now().UTC(),
+			}))
+		if result.Error != nil {
+			return fmt.Errorf("prepare work item run: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return core.ErrInvalidTransition
+		}
 		return nil
-	}
-
-	if _, err := s.GetWorkItem(ctx, id); err != nil {
+	})
+	if err != nil {
 		return err
 	}
-	return core.ErrInvalidTransition
+	after := *before
+	after.Status = queuedStatus
+	s.journalWorkItemState(ctx, before, &after)
+	return nil
 }
 
 func (s *Store) SetWorkItemArchived(ctx context.Context, id int64, archived bool) error {
 	now := time.Now().UTC()
-	query := s.orm.WithContext(ctx).Model(&WorkItemModel{}).Where("id = ?", id)
-	if archived {
-		query = query.Where("archived_at IS NULL").Where("status NOT IN ?", []string{
-			string(core.WorkItemQueued),
-			string(core.WorkItemRunning),
-			string(core.WorkItemBlocked),
-			string(core.WorkItemInExecution),
-			string(core.WorkItemPendingReview),
-			string(core.WorkItemEscalated),
-		})
-	} else {
-		query = query.Where("archived_at IS NOT NULL")
-	}
-
 	var updates map[string]any
 	if archived {
 		updates = map[string]any{"archived_at": now, "updated_at": now}
@@ -248,18 +260,44 @@ func (s *Store) SetWorkItemArchived(ctx context.Context, id int64, archived bool
 		updates = map[string]any{"archived_at": nil, "updated_at": now}
 	}
 
-	result := query.Updates(updates)
-	if result.Error != nil {
-		return fmt.Errorf("set work item archived: %w", result.Error)
-	}
-	if result.RowsAffected > 0 {
+	var before *core.WorkItem
+	err := s.withWorkItemSnapshot(ctx, id, func(tx *gorm.DB, snapshot *core.WorkItem) error {
+		if snapshot == nil {
+			return core.ErrNotFound
+		}
+		before = snapshot
+		query := tx.Model(&WorkItemModel{}).Where("id = ?", id)
+		if archived {
+			query = query.Where("archived_at IS NULL").Where("status NOT IN ?", []string{
+				string(core.WorkItemQueued),
+				string(core.WorkItemRunning),
+				string(core.WorkItemBlocked),
+				string(core.WorkItemInExecution),
+				string(core.WorkItemPendingReview),
+				string(core.WorkItemEscalated),
+			})
+		} else {
+			query = query.Where("archived_at IS NOT NULL")
+		}
+		result := query.Updates(bumpVersion(updates))
+		if result.Error != nil {
+			return fmt.Errorf("set work item archived: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return core.ErrInvalidTransition
+		}
 		return nil
-	}
-
-	if _, err := s.GetWorkItem(ctx, id); err != nil {
+	})
+	if err != nil {
 		return err
 	}
-	return core.ErrInvalidTransition
+	after := *before
+	after.ArchivedAt = nil
+	if archived {
+		after.ArchivedAt = &now
+	}
+	s.journalWorkItemState(ctx, before, &after)
+	return nil
 }
 
 func (s *Store) DeleteWorkItem(ctx context.Context, id int64) error {
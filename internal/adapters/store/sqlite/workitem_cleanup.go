@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 func (s *Store) DeleteActionIODeclsByWorkItem(ctx context.Context, workItemID int64) error {
@@ -85,9 +87,10 @@ func (s *Store) DeleteJournalByWorkItem(ctx context.Context, workItemID int64) e
 	if s == nil || s.orm == nil {
 		return fmt.Errorf("store is not initialized")
 	}
-	return s.orm.WithContext(ctx).
-		Where("work_item_id = ?", workItemID).
-		Delete(&JournalModel{}).Error
+	// The removed links are kept in an erasure entry so the chain verifies.
+	return s.withJournalChain(ctx, func(tx *gorm.DB) error {
+		return deleteChainedJournal(tx, fmt.Sprintf("work item %d deleted", workItemID), "work_item_id = ?", workItemID)
+	})
 }
 
 func (s *Store) DeleteActionsByWorkItem(ctx context.Context, workItemID int64) error {
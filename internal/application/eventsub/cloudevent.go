@@ -0,0 +1,115 @@
+package eventsub
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// CloudEventContentType is the structured-mode CloudEvents media type.
+const CloudEventContentType = "application/cloudevents+json; charset=utf-8"
+
+// Signature headers, following the Standard Webhooks layout.
+const (
+	HeaderWebhookID        = "webhook-id"
+	HeaderWebhookTimestamp = "webhook-timestamp"
+	HeaderWebhookSignature = "webhook-signature"
+)
+
+// CloudEvent is a CloudEvents 1.0 envelope in structured JSON mode.
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            EventData `json:"data"`
+}
+
+// EventData is the CloudEvent payload: the domain event without its
+// transport fields.
+type EventData struct {
+	Seq        int64          `json:"seq,omitempty"`
+	Category   string         `json:"category,omitempty"`
+	WorkItemID int64          `json:"work_item_id,omitempty"`
+	ActionID   int64          `json:"action_id,omitempty"`
+	RunID      int64          `json:"run_id,omitempty"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// NewCloudEvent wraps ev. The id is the bus sequence number, which is unique
+// per source; events without one get a random id.
+func NewCloudEvent(source string, ev core.Event) CloudEvent {
+	id := strconv.FormatInt(ev.Seq, 10)
+	if ev.Seq == 0 {
+		b := make([]byte, 12)
+		_, _ = rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	ts := ev.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	return CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Source:          source,
+		Type:            string(ev.Type),
+		Subject:         eventSubject(ev),
+		Time:            ts.UTC(),
+		DataContentType: "application/json",
+		Data: EventData{
+			Seq:        ev.Seq,
+			Category:   ev.Category,
+			WorkItemID: ev.WorkItemID,
+			ActionID:   ev.ActionID,
+			RunID:      ev.RunID,
+			Data:       ev.Data,
+		},
+	}
+}
+
+// eventSubject names the most specific resource the event is about.
+func eventSubject(ev core.Event) string {
+	switch {
+	case ev.RunID > 0:
+		return fmt.Sprintf("runs/%d", ev.RunID)
+	case ev.ActionID > 0:
+		return fmt.Sprintf("actions/%d", ev.ActionID)
+	case ev.WorkItemID > 0:
+		return fmt.Sprintf("work_items/%d", ev.WorkItemID)
+	}
+	if tid, ok := ev.Data["thread_id"].(int64); ok && tid > 0 {
+		return fmt.Sprintf("threads/%d", tid)
+	}
+	return ""
+}
+
+// Sign returns the webhook-signature value for body: "v1," followed by the
+// base64 HMAC-SHA256 of "<id>.<timestamp>.<body>" keyed with secret.
+func Sign(secret, id string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.%d.", id, timestamp)
+	mac.Write(body)
+	return "v1," + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a webhook-signature header produced by Sign.
+func Verify(secret, id string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, id, timestamp, body)), []byte(signature))
+}
+
+func newSecret() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,700 @@
+// Package eventsub pushes domain events from the event bus to external
+// subscribers as signed CloudEvents, with a persisted delivery log,
+// exponential retry and a dead-letter state.
+package eventsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+// ErrInvalidSubscription is returned for subscriptions that fail validation.
+var ErrInvalidSubscription = errors.New("invalid event subscription")
+
+const (
+	defaultMaxAttempts    = 8
+	defaultInitialBackoff = 10 * time.Second
+	defaultMaxBackoff     = time.Hour
+	defaultTimeout        = 10 * time.Second
+	defaultPollInterval   = 5 * time.Second
+	defaultWorkers        = 4
+	defaultSource         = "/ai-flow"
+	dueBatchSize          = 100
+	maxReplayEvents       = 1000
+	maxErrorBody          = 256
+	// backfillSyncTimeout bounds how long a back-fill waits for the missed
+	// events to reach the event log.
+	backfillSyncTimeout = 10 * time.Second
+)
+
+// Store is the persistence the service needs.
+type Store interface {
+	core.EventSubscriptionStore
+	GetWorkItem(ctx context.Context, id int64) (*core.WorkItem, error)
+	GetThread(ctx context.Context, id int64) (*core.Thread, error)
+	ListEvents(ctx context.Context, filter core.EventFilter) ([]*core.Event, error)
+}
+
+// EventBus is the subset of core.EventBus the service subscribes to.
+type EventBus interface {
+	Subscribe(opts core.SubscribeOpts) *core.Subscription
+}
+
+// EventLogSyncer waits until events up to a bus sequence number are readable
+// from the event log. Implemented by *flow.EventPersister.
+type EventLogSyncer interface {
+	Sync(ctx context.Context, seq int64) error
+}
+
+// Config tunes delivery. Zero values use the defaults.
+type Config struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+	PollInterval   time.Duration
+	Workers        int
+	// Source is the CloudEvents source attribute.
+	Source string
+}
+
+// ConfigFromRuntime maps the [runtime.event_subscriptions] section.
+func ConfigFromRuntime(cfg config.RuntimeEventSubscriptionsConfig) Config {
+	return Config{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: cfg.InitialBackoff.Duration,
+		MaxBackoff:     cfg.MaxBackoff.Duration,
+		Timeout:        cfg.Timeout.Duration,
+		PollInterval:   cfg.PollInterval.Duration,
+		Source:         cfg.Source,
+	}
+}
+
+// Service manages subscriptions and delivers matching events to them.
+// Deliveries are written to the log before the first attempt, so every event
+// is delivered at least once across restarts.
+type Service struct {
+	store  Store
+	bus    EventBus
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	subs      []*core.EventSubscription
+	subsValid bool
+	projects  map[string]int64 // "w<id>"/"t<id>" → project ID
+
+	wake chan struct{}
+	sub  *core.Subscription
+	wg   sync.WaitGroup
+
+	// gaps are bus sequence ranges intake missed on overflow; backfillLoop
+	// queues their deliveries from the event log.
+	gapMu   sync.Mutex
+	gaps    []seqGap
+	gapWake chan struct{}
+	logSync EventLogSyncer
+}
+
+// seqGap is an inclusive range of bus sequence numbers.
+type seqGap struct {
+	from, to int64
+}
+
+// New creates a service. Call Start to begin dispatching.
+func New(store Store, bus EventBus, cfg Config) *Service {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = defaultInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if strings.TrimSpace(cfg.Source) == "" {
+		cfg.Source = defaultSource
+	}
+	return &Service{
+		store:    store,
+		bus:      bus,
+		cfg:      cfg,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		now:      time.Now,
+		projects: make(map[string]int64),
+		wake:     make(chan struct{}, 1),
+		gapWake:  make(chan struct{}, 1),
+	}
+}
+
+// SetEventLogSyncer makes overflow back-fills wait for the missed events to
+// be persisted before reading them. Call before Start.
+func (s *Service) SetEventLogSyncer(syncer EventLogSyncer) {
+	s.logSync = syncer
+}
+
+// Start subscribes to the bus and runs the delivery loop until ctx is done.
+func (s *Service) Start(ctx context.Context) {
+	s.sub = s.bus.Subscribe(core.SubscribeOpts{BufferSize: 1024, NotifyOverflow: true})
+	s.wg.Add(3)
+	go func() {
+		defer s.wg.Done()
+		s.intake(ctx)
+	}()
+	go func() {
+		defer s.wg.Done()
+		s.deliveryLoop(ctx)
+	}()
+	go func() {
+		defer s.wg.Done()
+		s.backfillLoop(ctx)
+	}()
+}
+
+// Stop cancels the bus subscription and waits for the loops to exit. The
+// caller must also cancel the context passed to Start.
+func (s *Service) Stop() {
+	if s.sub != nil {
+		s.sub.Cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Service) intake(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-s.sub.C:
+			if !ok {
+				return
+			}
+			if ev.Type == core.EventStreamOverflow {
+				from, to := seqValue(ev.Data["from_seq"]), seqValue(ev.Data["to_seq"])
+				slog.Warn("event subscriptions: fell behind the event bus; back-filling from the event log",
+					"from_seq", from, "to_seq", to)
+				s.recordGap(from, to)
+				continue
+			}
+			if core.IsTransientAgentEvent(ev) {
+				continue
+			}
+			if n := s.enqueue(ctx, ev, nil); n > 0 {
+				s.signal()
+			}
+		}
+	}
+}
+
+// recordGap queues the missed range [from, to] for backfillLoop.
+func (s *Service) recordGap(from, to int64) {
+	if from <= 0 || to < from {
+		return
+	}
+	s.gapMu.Lock()
+	if n := len(s.gaps); n > 0 && s.gaps[n-1].to+1 >= from {
+		s.gaps[n-1].to = max(s.gaps[n-1].to, to)
+	} else {
+		s.gaps = append(s.gaps, seqGap{from: from, to: to})
+	}
+	s.gapMu.Unlock()
+	select {
+	case s.gapWake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Service) nextGap() (seqGap, bool) {
+	s.gapMu.Lock()
+	defer s.gapMu.Unlock()
+	if len(s.gaps) == 0 {
+		return seqGap{}, false
+	}
+	gap := s.gaps[0]
+	s.gaps = s.gaps[1:]
+	return gap, true
+}
+
+// backfillLoop queues deliveries for events intake missed, reading them back
+// from the event log, so an overflow delays deliveries instead of losing them.
+func (s *Service) backfillLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.gapWake:
+		}
+		for {
+			gap, ok := s.nextGap()
+			if !ok {
+				break
+			}
+			s.backfill(ctx, gap)
+		}
+	}
+}
+
+func (s *Service) backfill(ctx context.Context, gap seqGap) {
+	if s.logSync != nil {
+		syncCtx, cancel := context.WithTimeout(ctx, backfillSyncTimeout)
+		err := s.logSync.Sync(syncCtx, gap.to)
+		cancel()
+		if err != nil {
+			slog.Warn("event subscriptions: event log sync before back-fill incomplete", "to_seq", gap.to, "error", err)
+		}
+	}
+	queued, err := s.replayLog(ctx, nil, nil, gap.from-1, gap.to, 0)
+	if err != nil {
+		slog.Warn("event subscriptions: back-fill failed; use replay to resend",
+			"from_seq", gap.from, "to_seq", gap.to, "error", err)
+	} else {
+		slog.Info("event subscriptions: back-filled missed events", "from_seq", gap.from, "to_seq", gap.to, "deliveries", queued)
+	}
+	if queued > 0 {
+		s.signal()
+	}
+}
+
+// seqValue reads a sequence number from overflow marker data, which holds
+// int64 in process and float64 after a JSON round trip.
+func seqValue(v any) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	case float64:
+		return int64(n)
+	}
+	return 0
+}
+
+func (s *Service) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Service) deliveryLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		s.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// enqueue records a pending delivery of ev for every matching subscription,
+// or only for only when set. It returns the number of deliveries created.
+func (s *Service) enqueue(ctx context.Context, ev core.Event, only *core.EventSubscription) int {
+	var targets []*core.EventSubscription
+	if only != nil {
+		targets = []*core.EventSubscription{only}
+	} else {
+		subs, err := s.subscriptions(ctx)
+		if err != nil {
+			slog.Warn("event subscriptions: load failed", "error", err)
+			return 0
+		}
+		targets = subs
+	}
+	var payload []byte
+	var ce CloudEvent
+	created := 0
+	for _, sub := range targets {
+		if !sub.Matches(ev) || !s.inProject(ctx, sub, ev) {
+			continue
+		}
+		if payload == nil {
+			ce = NewCloudEvent(s.cfg.Source, ev)
+			var err error
+			if payload, err = json.Marshal(ce); err != nil {
+				slog.Warn("event subscriptions: encode event failed", "type", ev.Type, "error", err)
+				return created
+			}
+		}
+		now := s.now().UTC()
+		d := &core.EventDelivery{
+			SubscriptionID: sub.ID,
+			CloudEventID:   ce.ID,
+			EventType:      ev.Type,
+			EventSeq:       ev.Seq,
+			Payload:        string(payload),
+			Status:         core.EventDeliveryPending,
+			NextAttemptAt:  &now,
+		}
+		if _, err := s.store.CreateEventDelivery(ctx, d); err != nil {
+			slog.Warn("event subscriptions: record delivery failed", "subscription_id", sub.ID, "type", ev.Type, "error", err)
+			continue
+		}
+		created++
+	}
+	return created
+}
+
+// deliverDue attempts every due delivery, Workers at a time.
+func (s *Service) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := s.store.ListDueEventDeliveries(ctx, s.now(), dueBatchSize)
+		if err != nil {
+			slog.Warn("event subscriptions: list due deliveries failed", "error", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+		sem := make(chan struct{}, s.cfg.Workers)
+		var wg sync.WaitGroup
+		for _, d := range due {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(d *core.EventDelivery) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				s.attempt(ctx, d)
+			}(d)
+		}
+		wg.Wait()
+		if len(due) < dueBatchSize {
+			return
+		}
+	}
+}
+
+// attempt sends d once and records the outcome.
+func (s *Service) attempt(ctx context.Context, d *core.EventDelivery) {
+	sub, err := s.store.GetEventSubscription(ctx, d.SubscriptionID)
+	switch {
+	case errors.Is(err, core.ErrNotFound):
+		d.Status = core.EventDeliveryDead
+		d.LastError = "subscription deleted"
+		d.NextAttemptAt = nil
+		s.save(ctx, d)
+		return
+	case err != nil:
+		slog.Warn("event subscriptions: load subscription failed", "subscription_id", d.SubscriptionID, "error", err)
+		return
+	case !sub.Enabled:
+		// Park until the subscription is re-enabled or the delivery replayed.
+		next := s.now().UTC().Add(s.cfg.MaxBackoff)
+		d.NextAttemptAt = &next
+		d.LastError = "subscription disabled"
+		s.save(ctx, d)
+		return
+	}
+
+	d.Attempts++
+	status, sendErr := s.send(ctx, sub, d)
+	d.ResponseStatus = status
+	now := s.now().UTC()
+	if sendErr == nil {
+		d.Status = core.EventDeliverySucceeded
+		d.LastError = ""
+		d.NextAttemptAt = nil
+		d.DeliveredAt = &now
+	} else {
+		d.LastError = sendErr.Error()
+		if d.Attempts >= s.cfg.MaxAttempts {
+			d.Status = core.EventDeliveryDead
+			d.NextAttemptAt = nil
+			slog.Warn("event subscriptions: delivery dead-lettered",
+				"delivery_id", d.ID, "subscription_id", sub.ID, "attempts", d.Attempts, "error", sendErr)
+		} else {
+			next := now.Add(s.backoff(d.Attempts))
+			d.NextAttemptAt = &next
+		}
+	}
+	s.save(ctx, d)
+}
+
+func (s *Service) save(ctx context.Context, d *core.EventDelivery) {
+	if err := s.store.UpdateEventDelivery(ctx, d); err != nil {
+		slog.Warn("event subscriptions: update delivery failed", "delivery_id", d.ID, "error", err)
+	}
+}
+
+// backoff is InitialBackoff doubled per failed attempt, capped at MaxBackoff.
+func (s *Service) backoff(attempts int) time.Duration {
+	d := s.cfg.InitialBackoff
+	for i := 1; i < attempts && d < s.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, s.cfg.MaxBackoff)
+}
+
+func (s *Service) send(ctx context.Context, sub *core.EventSubscription, d *core.EventDelivery) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.TargetURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := s.now().Unix()
+	req.Header.Set("Content-Type", CloudEventContentType)
+	req.Header.Set(HeaderWebhookID, d.CloudEventID)
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(ts, 10))
+	if sub.Secret != "" {
+		req.Header.Set(HeaderWebhookSignature, Sign(sub.Secret, d.CloudEventID, ts, body))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+}
+
+func (s *Service) subscriptions(ctx context.Context) ([]*core.EventSubscription, error) {
+	s.mu.RLock()
+	if s.subsValid {
+		subs := s.subs
+		s.mu.RUnlock()
+		return subs, nil
+	}
+	s.mu.RUnlock()
+	subs, err := s.store.ListEventSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.subs, s.subsValid = subs, true
+	s.mu.Unlock()
+	return subs, nil
+}
+
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	s.subsValid = false
+	s.mu.Unlock()
+}
+
+// inProject reports whether ev belongs to the subscription's project scope.
+func (s *Service) inProject(ctx context.Context, sub *core.EventSubscription, ev core.Event) bool {
+	if sub.ProjectID == nil {
+		return true
+	}
+	projectID, ok := s.projectOf(ctx, ev)
+	return ok && projectID == *sub.ProjectID
+}
+
+func (s *Service) projectOf(ctx context.Context, ev core.Event) (int64, bool) {
+	var key string
+	var lookup func() int64
+	if ev.WorkItemID > 0 {
+		key = "w" + strconv.FormatInt(ev.WorkItemID, 10)
+		lookup = func() int64 {
+			if wi, err := s.store.GetWorkItem(ctx, ev.WorkItemID); err == nil && wi.ProjectID != nil {
+				return *wi.ProjectID
+			}
+			return 0
+		}
+	} else if tid, ok := ev.Data["thread_id"].(int64); ok && tid > 0 {
+		key = "t" + strconv.FormatInt(tid, 10)
+		lookup = func() int64 {
+			if thread, err := s.store.GetThread(ctx, tid); err == nil {
+				return thread.FocusProjectID
+			}
+			return 0
+		}
+	} else {
+		return 0, false
+	}
+	s.mu.RLock()
+	projectID, cached := s.projects[key]
+	s.mu.RUnlock()
+	if !cached {
+		projectID = lookup()
+		s.mu.Lock()
+		if len(s.projects) > 10000 {
+			clear(s.projects)
+		}
+		s.projects[key] = projectID
+		s.mu.Unlock()
+	}
+	return projectID, projectID > 0
+}
+
+// CreateSubscription validates and stores sub. A secret is generated when
+// none is given; the returned subscription carries it so it can be shown once.
+func (s *Service) CreateSubscription(ctx context.Context, sub *core.EventSubscription) (*core.EventSubscription, error) {
+	if err := validate(sub); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(sub.Secret) == "" {
+		sub.Secret = newSecret()
+	}
+	if _, err := s.store.CreateEventSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return sub, nil
+}
+
+// GetSubscription returns one subscription.
+func (s *Service) GetSubscription(ctx context.Context, id int64) (*core.EventSubscription, error) {
+	return s.store.GetEventSubscription(ctx, id)
+}
+
+// ListSubscriptions returns all subscriptions.
+func (s *Service) ListSubscriptions(ctx context.Context) ([]*core.EventSubscription, error) {
+	return s.store.ListEventSubscriptions(ctx)
+}
+
+// UpdateSubscription validates and saves sub.
+func (s *Service) UpdateSubscription(ctx context.Context, sub *core.EventSubscription) error {
+	if err := validate(sub); err != nil {
+		return err
+	}
+	if err := s.store.UpdateEventSubscription(ctx, sub); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// RotateSecret replaces the signing secret and returns the new one.
+func (s *Service) RotateSecret(ctx context.Context, sub *core.EventSubscription) (string, error) {
+	sub.Secret = newSecret()
+	if err := s.UpdateSubscription(ctx, sub); err != nil {
+		return "", err
+	}
+	return sub.Secret, nil
+}
+
+// DeleteSubscription removes a subscription and its delivery log.
+func (s *Service) DeleteSubscription(ctx context.Context, id int64) error {
+	if err := s.store.DeleteEventSubscription(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// ListDeliveries returns the delivery log, newest first.
+func (s *Service) ListDeliveries(ctx context.Context, filter core.EventDeliveryFilter) ([]*core.EventDelivery, error) {
+	return s.store.ListEventDeliveries(ctx, filter)
+}
+
+// Replay queues a fresh copy of a logged delivery, typically a dead one. The
+// copy keeps the CloudEvent id so receivers can deduplicate. Deliveries that
+// belong to another subscription are reported as core.ErrNotFound.
+func (s *Service) Replay(ctx context.Context, subscriptionID, deliveryID int64) (*core.EventDelivery, error) {
+	orig, err := s.store.GetEventDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if orig.SubscriptionID != subscriptionID {
+		return nil, core.ErrNotFound
+	}
+	now := s.now().UTC()
+	d := &core.EventDelivery{
+		SubscriptionID: orig.SubscriptionID,
+		CloudEventID:   orig.CloudEventID,
+		EventType:      orig.EventType,
+		EventSeq:       orig.EventSeq,
+		Payload:        orig.Payload,
+		Status:         core.EventDeliveryPending,
+		NextAttemptAt:  &now,
+		ReplayOf:       &orig.ID,
+	}
+	if _, err := s.store.CreateEventDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+	s.signal()
+	return d, nil
+}
+
+// ReplaySince queues deliveries to one subscription for persisted events after
+// sinceSeq, up to 1000 events. It returns the number of deliveries queued.
+func (s *Service) ReplaySince(ctx context.Context, subscriptionID, sinceSeq int64) (int, error) {
+	sub, err := s.store.GetEventSubscription(ctx, subscriptionID)
+	if err != nil {
+		return 0, err
+	}
+	queued, err := s.replayLog(ctx, sub, sub.EventTypes, sinceSeq, 0, maxReplayEvents)
+	if queued > 0 {
+		s.signal()
+	}
+	return queued, err
+}
+
+// replayLog queues deliveries for persisted events with since < seq <= until
+// (no upper bound when until is 0), reading at most limit events (all when
+// limit is 0). only restricts the targets as in enqueue.
+func (s *Service) replayLog(ctx context.Context, only *core.EventSubscription, types []core.EventType, since, until int64, limit int) (int, error) {
+	queued, read := 0, 0
+	for {
+		page := maxReplayEvents
+		if limit > 0 {
+			page = min(page, limit-read)
+		}
+		events, err := s.store.ListEvents(ctx, core.EventFilter{SinceSeq: &since, Types: types, Limit: page})
+		if err != nil {
+			return queued, err
+		}
+		for _, ev := range events {
+			if until > 0 && ev.Seq > until {
+				return queued, nil
+			}
+			since = ev.Seq
+			if !core.IsTransientAgentEvent(*ev) {
+				queued += s.enqueue(ctx, *ev, only)
+			}
+		}
+		read += len(events)
+		if len(events) < page || (limit > 0 && read >= limit) {
+			return queued, nil
+		}
+	}
+}
+
+func validate(sub *core.EventSubscription) error {
+	if sub == nil {
+		return fmt.Errorf("%w: missing subscription", ErrInvalidSubscription)
+	}
+	u, err := url.Parse(strings.TrimSpace(sub.TargetURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: target_url must be an absolute http(s) URL", ErrInvalidSubscription)
+	}
+	sub.TargetURL = u.String()
+	for _, t := range sub.EventTypes {
+		if strings.TrimSpace(string(t)) == "" {
+			return fmt.Errorf("%w: event_types must not contain empty values", ErrInvalidSubscription)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package eventsub
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	membus "github.com/yoke233/zhanggui/internal/adapters/events/memory"
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+type testReceiver struct {
+	mu       sync.Mutex
+	requests []capturedRequest
+	status   int
+}
+
+func (rcv *testReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	rcv.requests = append(rcv.requests, capturedRequest{header: r.Header.Clone(), body: body})
+	status := rcv.status
+	rcv.mu.Unlock()
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte("nope"))
+}
+
+func (rcv *testReceiver) count() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return len(rcv.requests)
+}
+
+func newTestService(t *testing.T, cfg Config) (*Service, *sqlite.Store, *time.Time) {
+	t.Helper()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "eventsub.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	svc := New(store, membus.NewBus(), cfg)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	return svc, store, &now
+}
+
+func TestService_DeliversSignedCloudEvent(t *testing.T) {
+	rcv := &testReceiver{status: http.StatusNoContent}
+	target := httptest.NewServer(rcv)
+	t.Cleanup(target.Close)
+	svc, store, _ := newTestService(t, Config{})
+	ctx := context.Background()
+
+	projectID, _ := store.CreateProject(ctx, &core.Project{Name: "alpha"})
+	otherID, _ := store.CreateProject(ctx, &core.Project{Name: "beta"})
+	inScope, _ := store.CreateWorkItem(ctx, &core.WorkItem{ProjectID: &projectID, Title: "a", Status: core.WorkItemOpen})
+	outOfScope, _ := store.CreateWorkItem(ctx, &core.WorkItem{ProjectID: &otherID, Title: "b", Status: core.WorkItemOpen})
+
+	sub, err := svc.CreateSubscription(ctx, &core.EventSubscription{
+		TargetURL:  target.URL,
+		EventTypes: []core.EventType{core.EventWorkItemCompleted},
+		ProjectID:  &projectID,
+		Enabled:    true,
+	})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	if sub.Secret == "" {
+		t.Fatal("expected a generated secret")
+	}
+
+	svc.enqueue(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: inScope, Seq: 1}, nil)
+	svc.enqueue(ctx, core.Event{Type: core.EventWorkItemCompleted, WorkItemID: outOfScope, Seq: 2}, nil)
+	svc.enqueue(ctx, core.Event{Type: core.EventWorkItemCompleted, WorkItemID: inScope, Seq: 3}, nil)
+	svc.deliverDue(ctx)
+
+	if rcv.count() != 1 {
+		t.Fatalf("deliveries = %d, want 1", rcv.count())
+	}
+	req := rcv.requests[0]
+	if ct := req.header.Get("Content-Type"); ct != CloudEventContentType {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	ts, _ := strconv.ParseInt(req.header.Get(HeaderWebhookTimestamp), 10, 64)
+	if !Verify(sub.Secret, req.header.Get(HeaderWebhookID), ts, req.body, req.header.Get(HeaderWebhookSignature)) {
+		t.Fatal("signature does not verify")
+	}
+	var ce CloudEvent
+	if err := json.Unmarshal(req.body, &ce); err != nil {
+		t.Fatalf("decode cloudevent: %v", err)
+	}
+	if ce.SpecVersion != "1.0" || ce.ID != "3" || ce.Type != string(core.EventWorkItemCompleted) ||
+		ce.Source != defaultSource || ce.Subject != "work_items/"+strconv.FormatInt(inScope, 10) {
+		t.Fatalf("unexpected cloudevent: %+v", ce)
+	}
+
+	log, err := svc.ListDeliveries(ctx, core.EventDeliveryFilter{SubscriptionID: &sub.ID})
+	if err != nil || len(log) != 1 {
+		t.Fatalf("delivery log = %v, %v", log, err)
+	}
+	if log[0].Status != core.EventDeliverySucceeded || log[0].Attempts != 1 || log[0].ResponseStatus != http.StatusNoContent {
+		t.Fatalf("unexpected delivery: %+v", log[0])
+	}
+}
+
+func TestService_RetriesToDeadLetterAndReplays(t *testing.T) {
+	rcv := &testReceiver{status: http.StatusInternalServerError}
+	target := httptest.NewServer(rcv)
+	t.Cleanup(target.Close)
+	svc, store, now := newTestService(t, Config{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Minute})
+	ctx := context.Background()
+
+	sub, err := svc.CreateSubscription(ctx, &core.EventSubscription{TargetURL: target.URL, Enabled: true})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	svc.enqueue(ctx, core.Event{Type: core.EventRunFailed, RunID: 9, Seq: 5}, nil)
+
+	// Attempts at t=0, +1s, +2s; the third failure dead-letters.
+	for i, advance := range []time.Duration{0, time.Second, 2 * time.Second} {
+		*now = now.Add(advance)
+		svc.deliverDue(ctx)
+		if rcv.count() != i+1 {
+			t.Fatalf("after attempt %d: requests = %d", i+1, rcv.count())
+		}
+		svc.deliverDue(ctx) // not due yet
+		if rcv.count() != i+1 {
+			t.Fatalf("delivery retried before its backoff elapsed")
+		}
+	}
+	log, _ := store.ListEventDeliveries(ctx, core.EventDeliveryFilter{SubscriptionID: &sub.ID})
+	dead := log[0]
+	if dead.Status != core.EventDeliveryDead || dead.Attempts != 3 || dead.ResponseStatus != 500 || dead.LastError != "HTTP 500: nope" {
+		t.Fatalf("unexpected dead delivery: %+v", dead)
+	}
+
+	if _, err := svc.Replay(ctx, sub.ID+1, dead.ID); err != core.ErrNotFound {
+		t.Fatalf("replay under another subscription: err = %v, want ErrNotFound", err)
+	}
+	rcv.mu.Lock()
+	rcv.status = http.StatusOK
+	rcv.mu.Unlock()
+	replay, err := svc.Replay(ctx, sub.ID, dead.ID)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	svc.deliverDue(ctx)
+	got, _ := store.GetEventDelivery(ctx, replay.ID)
+	if got.Status != core.EventDeliverySucceeded || got.CloudEventID != dead.CloudEventID || got.ReplayOf == nil || *got.ReplayOf != dead.ID {
+		t.Fatalf("unexpected replay delivery: %+v", got)
+	}
+	if id := rcv.requests[3].header.Get(HeaderWebhookID); id != "5" {
+		t.Fatalf("replayed webhook-id = %q, want original event id", id)
+	}
+}
+
+func TestService_ReplaySinceReadsEventLog(t *testing.T) {
+	svc, store, _ := newTestService(t, Config{})
+	ctx := context.Background()
+	for i, typ := range []core.EventType{core.EventWorkItemCompleted, core.EventWorkItemStarted, core.EventWorkItemCompleted} {
+		if _, err := store.CreateEvent(ctx, &core.Event{Type: typ, Seq: int64(i + 1), Timestamp: time.Now()}); err != nil {
+			t.Fatalf("create event: %v", err)
+		}
+	}
+	sub, err := svc.CreateSubscription(ctx, &core.EventSubscription{
+		TargetURL:  "https://example.test/hook",
+		EventTypes: []core.EventType{core.EventWorkItemCompleted},
+		Enabled:    true,
+	})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	queued, err := svc.ReplaySince(ctx, sub.ID, 1)
+	if err != nil || queued != 1 {
+		t.Fatalf("ReplaySince = %d, %v; want 1", queued, err)
+	}
+	log, _ := svc.ListDeliveries(ctx, core.EventDeliveryFilter{SubscriptionID: &sub.ID})
+	if len(log) != 1 || log[0].EventSeq != 3 || log[0].Status != core.EventDeliveryPending {
+		t.Fatalf("unexpected replay log: %+v", log)
+	}
+
+	if _, err := svc.CreateSubscription(ctx, &core.EventSubscription{TargetURL: "ftp://example.test"}); err == nil {
+		t.Fatal("expected non-http target to be rejected")
+	}
+}
+
+type channelBus struct {
+	ch chan core.Event
+}
+
+func (b *channelBus) Subscribe(core.SubscribeOpts) *core.Subscription {
+	return &core.Subscription{C: b.ch, Cancel: func() {}}
+}
+
+type recordingSyncer struct {
+	mu   sync.Mutex
+	seqs []int64
+}
+
+func (r *recordingSyncer) Sync(_ context.Context, seq int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seqs = append(r.seqs, seq)
+	return nil
+}
+
+func TestService_BackfillsOverflowFromEventLog(t *testing.T) {
+	rcv := &testReceiver{status: http.StatusNoContent}
+	target := httptest.NewServer(rcv)
+	t.Cleanup(target.Close)
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "eventsub.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	ctx, cancel := context.WithCancel(context.Background())
+
+	for seq := int64(1); seq <= 5; seq++ {
+		if _, err := store.CreateEvent(ctx, &core.Event{Type: core.EventWorkItemCompleted, Seq: seq, Timestamp: time.Now()}); err != nil {
+			t.Fatalf("create event: %v", err)
+		}
+	}
+	bus := &channelBus{ch: make(chan core.Event, 4)}
+	syncer := &recordingSyncer{}
+	svc := New(store, bus, Config{})
+	svc.SetEventLogSyncer(syncer)
+	sub, err := svc.CreateSubscription(ctx, &core.EventSubscription{TargetURL: target.URL, Enabled: true})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	svc.Start(ctx)
+	defer func() {
+		cancel()
+		svc.Stop()
+	}()
+
+	bus.ch <- core.Event{Type: core.EventStreamOverflow, Data: map[string]any{"from_seq": int64(2), "to_seq": int64(4), "dropped": int64(3)}}
+	bus.ch <- core.Event{Type: core.EventWorkItemCompleted, Seq: 6}
+
+	want := map[int64]bool{2: true, 3: true, 4: true, 6: true}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		log, _ := svc.ListDeliveries(ctx, core.EventDeliveryFilter{SubscriptionID: &sub.ID})
+		got := map[int64]bool{}
+		for _, d := range log {
+			got[d.EventSeq] = true
+		}
+		if len(got) == len(want) && got[2] && got[3] && got[4] && got[6] {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("delivered seqs = %v, want %v", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	syncer.mu.Lock()
+	defer syncer.mu.Unlock()
+	if len(syncer.seqs) != 1 || syncer.seqs[0] != 4 {
+		t.Fatalf("synced seqs = %v, want [4]", syncer.seqs)
+	}
+}
@@ -0,0 +1,374 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// ErrBatchWriterClosed is returned when records are enqueued after Close.
+var ErrBatchWriterClosed = errors.New("batch writer is closed")
+
+const (
+	defaultBatchSize     = 256
+	defaultFlushInterval = 50 * time.Millisecond
+	defaultQueueSize     = 4096
+	// maxFlushAttempts bounds retries of a failing batch before it is dropped.
+	maxFlushAttempts  = 3
+	closeFlushTimeout = 10 * time.Second
+)
+
+// JournalBatchStore is implemented by stores that can batch journal writes.
+type JournalBatchStore interface {
+	BatchAppendJournal(ctx context.Context, entries []*core.JournalEntry) error
+}
+
+// BatchWriterConfig tunes batching. Zero values use the defaults.
+type BatchWriterConfig struct {
+	BatchSize     int
+	FlushInterval time.Duration
+	QueueSize     int
+}
+
+// BatchWriterStats is a snapshot of writer counters.
+type BatchWriterStats struct {
+	Enqueued       uint64  `json:"enqueued"`
+	Coalesced      uint64  `json:"coalesced"`
+	EventsWritten  uint64  `json:"events_written"`
+	JournalWritten uint64  `json:"journal_written"`
+	Batches        uint64  `json:"batches"`
+	FlushErrors    uint64  `json:"flush_errors"`
+	Dropped        uint64  `json:"dropped"`
+	Blocked        uint64  `json:"blocked"`
+	Queued         int     `json:"queued"`
+	Pending        int     `json:"pending"`
+	LastBatchSize  int     `json:"last_batch_size"`
+	LastFlushMs    float64 `json:"last_flush_ms"`
+}
+
+type writeItem struct {
+	event   *core.Event
+	journal *core.JournalEntry
+}
+
+// BatchWriter is a write-behind pipeline for high-volume events and journal
+// entries. Records are buffered per run, consecutive streaming updates are
+// coalesced, and buffers are flushed in one transaction when BatchSize is
+// reached or FlushInterval elapses. Enqueue blocks while the bounded queue is
+// full, pushing back on producers instead of growing without limit.
+type BatchWriter struct {
+	store   EventStore
+	journal JournalBatchStore
+	cfg     BatchWriterConfig
+
+	queue   chan writeItem
+	flushCh chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	closeOnce sync.Once
+	mu        sync.RWMutex // guards closed against concurrent sends
+	closed    bool
+
+	// Owned by the run loop.
+	events    []*core.Event
+	entries   []*core.JournalEntry
+	lastByRun map[int64]int
+	attempts  int
+
+	enqueued       atomic.Uint64
+	coalesced      atomic.Uint64
+	eventsWritten  atomic.Uint64
+	journalWritten atomic.Uint64
+	batches        atomic.Uint64
+	flushErrors    atomic.Uint64
+	dropped        atomic.Uint64
+	lostSeq        atomic.Int64
+	blocked        atomic.Uint64
+	pending        atomic.Int64
+	lastBatchSize  atomic.Int64
+	lastFlushNanos atomic.Int64
+}
+
+// NewBatchWriter starts a writer over store. Journal entries are accepted
+// when store also implements JournalBatchStore.
+func NewBatchWriter(store EventStore, cfg BatchWriterConfig) *BatchWriter {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultFlushInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	w := &BatchWriter{
+		store:     store,
+		cfg:       cfg,
+		queue:     make(chan writeItem, cfg.QueueSize),
+		flushCh:   make(chan chan struct{}),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		lastByRun: make(map[int64]int),
+	}
+	w.journal, _ = store.(JournalBatchStore)
+	go w.run()
+	return w
+}
+
+// EnqueueEvent buffers ev for persistence. It blocks while the queue is full.
+func (w *BatchWriter) EnqueueEvent(ctx context.Context, ev *core.Event) error {
+	if ev == nil {
+		return nil
+	}
+	return w.enqueue(ctx, writeItem{event: ev})
+}
+
+// EnqueueJournal buffers entry for BatchAppendJournal. It blocks while the
+// queue is full.
+func (w *BatchWriter) EnqueueJournal(ctx context.Context, entry *core.JournalEntry) error {
+	if entry == nil {
+		return nil
+	}
+	if w.journal == nil {
+		return errors.New("batch writer: store does not support batched journal writes")
+	}
+	return w.enqueue(ctx, writeItem{journal: entry})
+}
+
+func (w *BatchWriter) enqueue(ctx context.Context, item writeItem) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrBatchWriterClosed
+	}
+	select {
+	case w.queue <- item:
+		w.enqueued.Add(1)
+		return nil
+	default:
+	}
+	w.blocked.Add(1)
+	select {
+	case w.queue <- item:
+		w.enqueued.Add(1)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush writes everything enqueued so far and waits for it to be persisted.
+func (w *BatchWriter) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case w.flushCh <- ack:
+	case <-w.done:
+		return ErrBatchWriterClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops intake, drains the queue, flushes the remaining buffers and
+// waits for the writer to exit. It is safe to call more than once.
+func (w *BatchWriter) Close() {
+	w.closeOnce.Do(func() {
+		w.mu.Lock()
+		w.closed = true
+		w.mu.Unlock()
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// LostSeq returns the highest bus sequence number among events the writer
+// dropped, or 0 if none were dropped. Replays from before it have gaps.
+func (w *BatchWriter) LostSeq() int64 {
+	return w.lostSeq.Load()
+}
+
+// markLost records that events were dropped without being persisted.
+func (w *BatchWriter) markLost(events []*core.Event) {
+	for _, ev := range events {
+		for {
+			cur := w.lostSeq.Load()
+			if ev.Seq <= cur || w.lostSeq.CompareAndSwap(cur, ev.Seq) {
+				break
+			}
+		}
+	}
+}
+
+// Stats returns a snapshot of the writer counters.
+func (w *BatchWriter) Stats() BatchWriterStats {
+	return BatchWriterStats{
+		Enqueued:       w.enqueued.Load(),
+		Coalesced:      w.coalesced.Load(),
+		EventsWritten:  w.eventsWritten.Load(),
+		JournalWritten: w.journalWritten.Load(),
+		Batches:        w.batches.Load(),
+		FlushErrors:    w.flushErrors.Load(),
+		Dropped:        w.dropped.Load(),
+		Blocked:        w.blocked.Load(),
+		Queued:         len(w.queue),
+		Pending:        int(w.pending.Load()),
+		LastBatchSize:  int(w.lastBatchSize.Load()),
+		LastFlushMs:    float64(w.lastFlushNanos.Load()) / float64(time.Millisecond),
+	}
+}
+
+func (w *BatchWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case item := <-w.queue:
+			w.add(item)
+			if w.size() >= w.cfg.BatchSize {
+				w.flush(context.Background())
+			}
+		case <-ticker.C:
+			w.flush(context.Background())
+		case ack := <-w.flushCh:
+			w.drain()
+			w.flush(context.Background())
+			close(ack)
+		case <-w.stop:
+			w.drain()
+			ctx, cancel := context.WithTimeout(context.Background(), closeFlushTimeout)
+			for w.size() > 0 && ctx.Err() == nil {
+				w.flush(ctx)
+			}
+			cancel()
+			if n := w.size(); n > 0 {
+				w.dropped.Add(uint64(n))
+				w.markLost(w.events)
+				slog.Error("batch writer: records lost on shutdown", "count", n)
+			}
+			return
+		}
+	}
+}
+
+// drain moves everything currently queued into the buffers.
+func (w *BatchWriter) drain() {
+	for {
+		select {
+		case item := <-w.queue:
+			w.add(item)
+		default:
+			return
+		}
+	}
+}
+
+func (w *BatchWriter) size() int { return len(w.events) + len(w.entries) }
+
+func (w *BatchWriter) add(item writeItem) {
+	defer func() { w.pending.Store(int64(w.size())) }()
+	if item.journal != nil {
+		w.entries = append(w.entries, item.journal)
+		return
+	}
+	ev := item.event
+	if ev.RunID != 0 {
+		if idx, ok := w.lastByRun[ev.RunID]; ok && coalesceEvent(w.events[idx], ev) {
+			w.coalesced.Add(1)
+			return
+		}
+		w.lastByRun[ev.RunID] = len(w.events)
+	}
+	w.events = append(w.events, ev)
+}
+
+// flush writes the buffers in one batch per kind. A failing batch is kept
+// for the next flush and dropped after maxFlushAttempts.
+func (w *BatchWriter) flush(ctx context.Context) {
+	if w.size() == 0 {
+		return
+	}
+	start := time.Now()
+	batchSize := w.size()
+	var err error
+	if len(w.events) > 0 {
+		if err = w.store.BatchCreateEvents(ctx, w.events); err == nil {
+			w.eventsWritten.Add(uint64(len(w.events)))
+			w.events = w.events[:0]
+			clear(w.lastByRun)
+		}
+	}
+	if err == nil && len(w.entries) > 0 {
+		if err = w.journal.BatchAppendJournal(ctx, w.entries); err == nil {
+			w.journalWritten.Add(uint64(len(w.entries)))
+			w.entries = w.entries[:0]
+		}
+	}
+	defer func() { w.pending.Store(int64(w.size())) }()
+	if err != nil {
+		w.flushErrors.Add(1)
+		w.attempts++
+		slog.Warn("batch writer: flush failed", "attempt", w.attempts, "events", len(w.events), "journal", len(w.entries), "error", err)
+		if w.attempts < maxFlushAttempts {
+			return
+		}
+		w.dropped.Add(uint64(w.size()))
+		w.markLost(w.events)
+		slog.Error("batch writer: dropping batch after repeated failures", "events", len(w.events), "journal", len(w.entries))
+		w.events, w.entries = w.events[:0], w.entries[:0]
+		clear(w.lastByRun)
+	}
+	w.attempts = 0
+	w.batches.Add(1)
+	w.lastBatchSize.Store(int64(batchSize))
+	w.lastFlushNanos.Store(int64(time.Since(start)))
+}
+
+// coalesceEvent folds next into prev when both are successive updates of the
+// same streaming item: tool-call updates for the same call and usage updates
+// keep the latest data. Content chunks never get here; the event bridge
+// already aggregates them and the persister skips them.
+func coalesceEvent(prev, next *core.Event) bool {
+	if prev.Type != next.Type || prev.RunID != next.RunID || prev.Category != next.Category {
+		return false
+	}
+	prevType, _ := prev.Data["type"].(string)
+	nextType, _ := next.Data["type"].(string)
+	if prevType == "" || prevType != nextType || prev.Data["session_id"] != next.Data["session_id"] {
+		return false
+	}
+	// Bus events share their Data map with other subscribers; copy before folding.
+	data := make(map[string]any, len(prev.Data)+len(next.Data))
+	for k, v := range prev.Data {
+		data[k] = v
+	}
+	switch prevType {
+	case "tool_call":
+		if prev.Data["tool_call_id"] == nil || prev.Data["tool_call_id"] != next.Data["tool_call_id"] {
+			return false
+		}
+		for k, v := range next.Data {
+			data[k] = v
+		}
+		prev.Timestamp = next.Timestamp
+	case "usage_update":
+		data = next.Data
+		prev.Timestamp = next.Timestamp
+	default:
+		return false
+	}
+	prev.Data = data
+	return true
+}
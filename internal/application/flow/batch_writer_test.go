@@ -0,0 +1,209 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestBatchWriter_CoalescesRunUpdates(t *testing.T) {
+	store := newTestStore(t)
+	w := NewBatchWriter(store, BatchWriterConfig{FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	shared := map[string]any{"type": "tool_call", "tool_call_id": "t1", "content": "read"}
+	for _, ev := range []*core.Event{
+		{Type: core.EventRunAgentOutput, RunID: 7, Data: shared},
+		{Type: core.EventRunAgentOutput, RunID: 7, Data: map[string]any{"type": "tool_call", "tool_call_id": "t1", "content": "read main.go"}},
+		{Type: core.EventRunAgentOutput, RunID: 7, Data: map[string]any{"type": "usage_update", "content": "10 tokens"}},
+		{Type: core.EventRunAgentOutput, RunID: 7, Data: map[string]any{"type": "usage_update", "content": "25 tokens"}},
+		{Type: core.EventRunAgentOutput, RunID: 8, Data: map[string]any{"type": "tool_call", "tool_call_id": "t1", "content": "other run"}},
+		{Type: core.EventRunAgentOutput, RunID: 7, Data: map[string]any{"type": "tool_call", "tool_call_id": "t2", "content": "write"}},
+	} {
+		if err := w.EnqueueEvent(ctx, ev); err != nil {
+			t.Fatalf("EnqueueEvent: %v", err)
+		}
+	}
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	w.Close()
+
+	events, err := store.ListEvents(ctx, core.EventFilter{})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	var got []string
+	for _, ev := range events {
+		got = append(got, ev.Data["content"].(string))
+	}
+	want := []string{"read main.go", "25 tokens", "other run", "write"}
+	if len(got) != len(want) {
+		t.Fatalf("persisted contents = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("persisted contents = %v, want %v", got, want)
+		}
+	}
+	if shared["content"] != "read" {
+		t.Fatalf("coalescing mutated the published event data: %v", shared)
+	}
+	stats := w.Stats()
+	if stats.Coalesced != 2 || stats.EventsWritten != 4 || stats.Batches != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestBatchWriter_FlushesOnBatchSizeAndClose(t *testing.T) {
+	store := newTestStore(t)
+	w := NewBatchWriter(store, BatchWriterConfig{BatchSize: 2, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := w.EnqueueEvent(ctx, &core.Event{Type: core.EventWorkItemStarted, WorkItemID: int64(i + 1)}); err != nil {
+			t.Fatalf("EnqueueEvent: %v", err)
+		}
+	}
+	if err := w.EnqueueJournal(ctx, &core.JournalEntry{WorkItemID: 1, Kind: core.JournalStateChange, Source: core.JournalSourceSystem, Summary: "started"}); err != nil {
+		t.Fatalf("EnqueueJournal: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for w.Stats().EventsWritten < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := w.Stats().EventsWritten; got < 2 {
+		t.Fatalf("size-triggered flush wrote %d events, want >= 2", got)
+	}
+
+	w.Close()
+	events, _ := store.ListEvents(ctx, core.EventFilter{})
+	entries, _ := store.ListJournal(ctx, core.JournalFilter{})
+	if len(events) != 3 || len(entries) != 1 {
+		t.Fatalf("after close: %d events, %d journal entries; want 3 and 1", len(events), len(entries))
+	}
+	if err := w.EnqueueEvent(ctx, &core.Event{Type: core.EventWorkItemStarted}); !errors.Is(err, ErrBatchWriterClosed) {
+		t.Fatalf("enqueue after close err = %v", err)
+	}
+}
+
+// blockingEventStore holds every batch until release is closed.
+type blockingEventStore struct {
+	EventStore
+	release chan struct{}
+}
+
+func (s *blockingEventStore) BatchCreateEvents(ctx context.Context, events []*core.Event) error {
+	<-s.release
+	return s.EventStore.BatchCreateEvents(ctx, events)
+}
+
+func TestBatchWriter_BackpressureBlocksProducers(t *testing.T) {
+	store := &blockingEventStore{EventStore: newTestStore(t), release: make(chan struct{})}
+	w := NewBatchWriter(store, BatchWriterConfig{BatchSize: 1, QueueSize: 1, FlushInterval: time.Hour})
+
+	ctx := context.Background()
+	// First event is picked up and stuck in the flush; second fills the queue.
+	_ = w.EnqueueEvent(ctx, &core.Event{Type: core.EventWorkItemStarted})
+	time.Sleep(20 * time.Millisecond)
+	_ = w.EnqueueEvent(ctx, &core.Event{Type: core.EventWorkItemStarted})
+
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := w.EnqueueEvent(timeout, &core.Event{Type: core.EventWorkItemStarted}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("enqueue on full queue err = %v, want deadline exceeded", err)
+	}
+	if w.Stats().Blocked == 0 {
+		t.Fatal("expected blocked counter to increase")
+	}
+
+	close(store.release)
+	w.Close()
+	if got := w.Stats().EventsWritten; got != 2 {
+		t.Fatalf("events written = %d, want 2", got)
+	}
+}
+
+type failingEventStore struct {
+	EventStore
+}
+
+func (failingEventStore) BatchCreateEvents(context.Context, []*core.Event) error {
+	return errors.New("disk full")
+}
+
+func TestBatchWriter_RecordsLostSeqOfDroppedBatch(t *testing.T) {
+	w := NewBatchWriter(failingEventStore{EventStore: newTestStore(t)}, BatchWriterConfig{FlushInterval: time.Hour})
+	ctx := context.Background()
+	for _, seq := range []int64{5, 9, 7} {
+		if err := w.EnqueueEvent(ctx, &core.Event{Type: core.EventWorkItemStarted, Seq: seq}); err != nil {
+			t.Fatalf("EnqueueEvent: %v", err)
+		}
+	}
+	for i := 0; i < maxFlushAttempts; i++ {
+		if err := w.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+	w.Close()
+	if got := w.Stats().Dropped; got != 3 {
+		t.Fatalf("dropped = %d, want 3", got)
+	}
+	if got := w.LostSeq(); got != 9 {
+		t.Fatalf("LostSeq = %d, want 9", got)
+	}
+}
+
+// Insert throughput before and after batching, against an on-disk database:
+//
+//	go test ./internal/application/flow -run '^$' -bench EventInsert
+func BenchmarkEventInsert_Individual(b *testing.B) {
+	store := newBenchStore(b)
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := store.CreateEvent(ctx, benchEvent(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEventInsert_Batched(b *testing.B) {
+	store := newBenchStore(b)
+	ctx := context.Background()
+	w := NewBatchWriter(store, BatchWriterConfig{})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := w.EnqueueEvent(ctx, benchEvent(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	w.Close()
+}
+
+func newBenchStore(b *testing.B) *sqlite.Store {
+	b.Helper()
+	s, err := sqlite.New(filepath.Join(b.TempDir(), "bench.db"))
+	if err != nil {
+		b.Fatalf("new store: %v", err)
+	}
+	b.Cleanup(func() { s.Close() })
+	return s
+}
+
+func benchEvent(i int) *core.Event {
+	return &core.Event{
+		Type:       core.EventRunAgentOutput,
+		WorkItemID: 1,
+		ActionID:   1,
+		RunID:      int64(i%8 + 1),
+		Data:       map[string]any{"type": "agent_message", "content": "streamed output line"},
+		Timestamp:  time.Now().UTC(),
+	}
+}
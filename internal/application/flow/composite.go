@@ -39,11 +39,13 @@ func (e *WorkItemEngine) ExpandComposite(ctx context.Context, action *core.Actio
 	}
 
 	// Store the child work item ID in the action's Config for tracking.
-	if action.Config == nil {
-		action.Config = map[string]any{}
-	}
-	action.Config["child_work_item_id"] = childWorkItemID
-	if err := e.workflow.store.UpdateAction(ctx, action); err != nil {
+	err = e.updateAction(ctx, action, func(a *core.Action) {
+		if a.Config == nil {
+			a.Config = map[string]any{}
+		}
+		a.Config["child_work_item_id"] = childWorkItemID
+	})
+	if err != nil {
 		return 0, fmt.Errorf("persist child work item link for action %d: %w", action.ID, err)
 	}
 
@@ -114,10 +116,11 @@ func (e *WorkItemEngine) executeComposite(ctx context.Context, action *core.Acti
 	if err := e.Run(ctx, childWIID); err != nil {
 		// Child work item failed — check retry budget on the composite action.
 		if action.RetryCount < action.MaxRetries {
-			action.RetryCount++
-			action.Status = core.ActionPending
-			delete(action.Config, "child_work_item_id") // clear link so next attempt creates fresh child work item
-			return e.workflow.store.UpdateAction(ctx, action)
+			return e.updateAction(ctx, action, func(a *core.Action) {
+				a.RetryCount++
+				a.Status = core.ActionPending
+				delete(a.Config, "child_work_item_id") // clear link so next attempt creates fresh child work item
+			})
 		}
 
 		_ = e.transitionAction(ctx, action, core.ActionFailed)
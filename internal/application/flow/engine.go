@@ -19,6 +19,7 @@ type workflowRuntime struct {
 	bus      EventPublisher
 	sem      *Semaphore
 	executor ActionExecutor
+	results  ResultScanner
 }
 
 type preparationService struct {
@@ -34,6 +35,7 @@ type gateService struct {
 	prPrompts      PRFlowPromptsProvider
 	crFactory      ChangeRequestProviderFactory
 	gateEvaluators []GateEvaluator
+	provenance     core.ProvenanceRecorder
 }
 
 // WorkItemEngine orchestrates WorkItem execution: sequential action scheduling, state transitions, events.
@@ -102,6 +104,17 @@ func WithGateEvaluators(evaluators ...GateEvaluator) Option {
 	return func(e *WorkItemEngine) { e.gates.gateEvaluators = evaluators }
 }
 
+// WithProvenanceRecorder signs a provenance statement for every change request
+// a gate merges.
+func WithProvenanceRecorder(r core.ProvenanceRecorder) Option {
+	return func(e *WorkItemEngine) { e.gates.provenance = r }
+}
+
+// WithResultScanner redacts credentials from run results before they are persisted.
+func WithResultScanner(s ResultScanner) Option {
+	return func(e *WorkItemEngine) { e.workflow.results = s }
+}
+
 // WithResourceResolver sets the external resource resolver for input fetch / output deposit.
 func WithResourceResolver(rr *ResourceResolver) Option {
 	return func(e *WorkItemEngine) { e.preparation.resources = rr }
@@ -581,18 +594,38 @@ func (e *WorkItemEngine) setTerminalFailureState(ctx context.Context, workItemID
 }
 
 func (e *WorkItemEngine) setWorkItemStatus(ctx context.Context, workItemID int64, status core.WorkItemStatus, activeProfileID string) error {
-	workItem, err := e.workflow.store.GetWorkItem(ctx, workItemID)
-	if err != nil {
-		return err
-	}
-	if workItem.Status == status && (strings.TrimSpace(activeProfileID) == "" || workItem.ActiveProfileID == strings.TrimSpace(activeProfileID)) {
+	return core.RetryOnConflict(func() error {
+		workItem, err := e.workflow.store.GetWorkItem(ctx, workItemID)
+		if err != nil {
+			return err
+		}
+		if workItem.Status == status && (strings.TrimSpace(activeProfileID) == "" || workItem.ActiveProfileID == strings.TrimSpace(activeProfileID)) {
+			return nil
+		}
+		workItem.Status = status
+		if profileID := strings.TrimSpace(activeProfileID); profileID != "" {
+			workItem.ActiveProfileID = profileID
+		}
+		return e.workflow.store.UpdateWorkItem(ctx, workItem)
+	})
+}
+
+// updateAction re-reads the action, applies mutate and writes it back,
+// retrying when another writer (such as a human edit) bumped the version in
+// between. On success *action mirrors the stored row.
+func (e *WorkItemEngine) updateAction(ctx context.Context, action *core.Action, mutate func(*core.Action)) error {
+	return core.RetryOnConflict(func() error {
+		latest, err := e.workflow.store.GetAction(ctx, action.ID)
+		if err != nil {
+			return err
+		}
+		mutate(latest)
+		if err := e.workflow.store.UpdateAction(ctx, latest); err != nil {
+			return err
+		}
+		*action = *latest
 		return nil
-	}
-	workItem.Status = status
-	if profileID := strings.TrimSpace(activeProfileID); profileID != "" {
-		workItem.ActiveProfileID = profileID
-	}
-	return e.workflow.store.UpdateWorkItem(ctx, workItem)
+	})
 }
 
 func nextEscalationProfile(workItem *core.WorkItem) string {
@@ -102,9 +102,11 @@ func (e *WorkItemEngine) ProcessGate(ctx context.Context, action *core.Action, r
 			return core.ErrMaxRetriesExceeded
 		}
 		e.recordGateRework(ctx, up, action.ID, result.Reason, result.Metadata)
-		up.RetryCount++
-		up.Status = core.ActionPending
-		if err := e.workflow.store.UpdateAction(ctx, up); err != nil {
+		err = e.updateAction(ctx, up, func(a *core.Action) {
+			a.RetryCount++
+			a.Status = core.ActionPending
+		})
+		if err != nil {
 			return fmt.Errorf("reset action %d: %w", upID, err)
 		}
 	}
@@ -31,7 +31,7 @@ func (e *WorkItemEngine) mergePRIfConfigured(ctx context.Context, action *core.A
 		return nil
 	}
 
-	prNumber, err := e.resolvePRNumber(ctx, action)
+	prNumber, prResult, err := e.resolvePRResult(ctx, action)
 	if err != nil {
 		return err
 	}
@@ -94,21 +94,55 @@ func (e *WorkItemEngine) mergePRIfConfigured(ctx context.Context, action *core.A
 		}
 	}
 
-	return provider.Merge(ctx, repo, prNumber, MergeInput{
+	if err := provider.Merge(ctx, repo, prNumber, MergeInput{
 		Method:        mergeMethod,
 		CommitTitle:   fmt.Sprintf("merge: work item %d", action.WorkItemID),
 		CommitMessage: fmt.Sprintf("merged by ai-workflow gate action %d", action.ID),
 		Extra:         extra,
-	})
+	}); err != nil {
+		return err
+	}
+	e.recordMergeProvenance(ctx, action, repo, prNumber, prResult)
+	return nil
+}
+
+// recordMergeProvenance signs a provenance statement for the head commit of a
+// merged change request. Failures are logged: the merge already happened.
+func (e *WorkItemEngine) recordMergeProvenance(ctx context.Context, action *core.Action, repo ChangeRequestRepo, prNumber int, prResult map[string]any) {
+	if e.gates.provenance == nil {
+		return
+	}
+	headSHA, _ := prResult["head_sha"].(string)
+	if strings.TrimSpace(headSHA) == "" {
+		slog.Warn("merge provenance skipped: head_sha unknown", "action_id", action.ID, "pr_number", prNumber)
+		return
+	}
+	subject := core.ProvenanceSubject{
+		Kind:                core.ProvenanceChangeRequest,
+		WorkItemID:          action.WorkItemID,
+		ActionID:            action.ID,
+		CommitSHA:           headSHA,
+		Repo:                strings.Trim(strings.Join([]string{repo.Host, repo.Namespace, repo.Name}, "/"), "/"),
+		ChangeRequestNumber: prNumber,
+	}
+	subject.Ref, _ = prResult["base_branch"].(string)
+	subject.ChangeRequestURL, _ = prResult["pr_url"].(string)
+	if runs, err := e.workflow.store.ListRunsByAction(ctx, action.ID); err == nil && len(runs) > 0 {
+		subject.RunID = runs[len(runs)-1].ID
+	}
+	if _, err := e.gates.provenance.RecordProvenance(ctx, subject); err != nil {
+		slog.Warn("record merge provenance failed", "action_id", action.ID, "pr_number", prNumber, "error", err)
+	}
 }
 
-// resolvePRNumber finds the PR number from gate run result or predecessor run results.
-func (e *WorkItemEngine) resolvePRNumber(ctx context.Context, action *core.Action) (int, error) {
+// resolvePRResult finds the PR number, and the result metadata it was read
+// from, in the gate run result or predecessor run results.
+func (e *WorkItemEngine) resolvePRResult(ctx context.Context, action *core.Action) (int, map[string]any, error) {
 	// Prefer gate run result metadata.
 	run, err := e.workflow.store.GetLatestRunWithResult(ctx, action.ID)
 	if err == nil && run != nil && run.ResultMetadata != nil {
 		if n, ok := toInt64(run.ResultMetadata["pr_number"]); ok && n > 0 {
-			return int(n), nil
+			return int(n), run.ResultMetadata, nil
 		}
 	}
 
@@ -120,10 +154,10 @@ func (e *WorkItemEngine) resolvePRNumber(ctx context.Context, action *core.Actio
 			continue
 		}
 		if n, ok := toInt64(r.ResultMetadata["pr_number"]); ok && n > 0 {
-			return int(n), nil
+			return int(n), r.ResultMetadata, nil
 		}
 	}
-	return 0, fmt.Errorf("pr_number not found for merge")
+	return 0, nil, fmt.Errorf("pr_number not found for merge")
 }
 
 // handleMergeConflictBlock detects merge conflicts (dirty) and immediately blocks
@@ -0,0 +1,53 @@
+package flow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type recordingProvenance struct {
+	subjects []core.ProvenanceSubject
+}
+
+func (r *recordingProvenance) RecordProvenance(_ context.Context, subject core.ProvenanceSubject) (*core.Resource, error) {
+	r.subjects = append(r.subjects, subject)
+	return &core.Resource{ID: int64(len(r.subjects))}, nil
+}
+
+// TestRecordMergeProvenance: a merged PR is attested at its head commit by the gate's latest run.
+func TestRecordMergeProvenance(t *testing.T) {
+	store, bus := setup(t)
+	ctx := context.Background()
+	recorder := &recordingProvenance{}
+	eng := New(store, bus, nil, WithProvenanceRecorder(recorder))
+
+	workItemID, _ := store.CreateWorkItem(ctx, &core.WorkItem{Title: "provenance", Status: core.WorkItemRunning})
+	actionID, _ := store.CreateAction(ctx, &core.Action{WorkItemID: workItemID, Name: "gate", Type: core.ActionGate, Status: core.ActionRunning})
+	action, _ := store.GetAction(ctx, actionID)
+	store.CreateRun(ctx, &core.Run{ActionID: actionID, WorkItemID: workItemID, Status: core.RunFailed, Attempt: 1})
+	latest, _ := store.CreateRun(ctx, &core.Run{ActionID: actionID, WorkItemID: workItemID, Status: core.RunSucceeded, Attempt: 2})
+
+	repo := ChangeRequestRepo{Kind: "github", Host: "github.com", Namespace: "acme", Name: "backend"}
+	eng.recordMergeProvenance(ctx, action, repo, 42, map[string]any{
+		"pr_number":   42,
+		"pr_url":      "https://github.com/acme/backend/pull/42",
+		"base_branch": "main",
+		"head_sha":    "abc123",
+	})
+	if len(recorder.subjects) != 1 {
+		t.Fatalf("expected 1 recorded subject, got %d", len(recorder.subjects))
+	}
+	got := recorder.subjects[0]
+	if got.Kind != core.ProvenanceChangeRequest || got.CommitSHA != "abc123" || got.RunID != latest ||
+		got.Repo != "github.com/acme/backend" || got.Ref != "main" || got.ChangeRequestNumber != 42 {
+		t.Fatalf("unexpected subject %+v", got)
+	}
+
+	// Without a head SHA there is nothing to attest.
+	eng.recordMergeProvenance(ctx, action, repo, 43, map[string]any{"pr_number": 43})
+	if len(recorder.subjects) != 1 {
+		t.Fatalf("expected no subject without head_sha, got %d", len(recorder.subjects))
+	}
+}
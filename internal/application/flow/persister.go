@@ -3,6 +3,8 @@ package flow
 import (
 	"context"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 
 	"github.com/yoke233/zhanggui/internal/core"
 )
@@ -10,49 +12,159 @@ import (
 // EventPersister subscribes to all events on the EventBus and writes them to EventStore.
 // Transient streaming events (individual chunks) are skipped — only aggregated
 // events (agent_message, agent_thought, tool_call, done) are persisted.
+// Writes go through a BatchWriter so bursts from concurrent agents land in
+// batched transactions instead of one insert per event. The subscription
+// uses bus backpressure: when the writer pushes back, publishers wait for
+// the persister rather than events piling up in memory. Events the bus
+// still had to drop are recorded in LostSeq so replaying clients resync.
 type EventPersister struct {
-	store EventStore
-	bus   EventBus
-	sub   *core.Subscription
-	done  chan struct{}
+	store  EventStore
+	bus    EventBus
+	cfg    BatchWriterConfig
+	writer *BatchWriter
+	sub    *core.Subscription
+	done   chan struct{}
+
+	// seenMu guards seen, the highest bus sequence number handled so far, and
+	// seenCh, which is closed and replaced whenever seen advances.
+	seenMu sync.Mutex
+	seen   int64
+	seenCh chan struct{}
+
+	// lostSeq is the highest sequence number of an event that could not be
+	// handed to the writer.
+	lostSeq atomic.Int64
+}
+
+// EventPersisterOption configures an EventPersister.
+type EventPersisterOption func(*EventPersister)
+
+// WithPersisterBatching overrides the batch writer settings.
+func WithPersisterBatching(cfg BatchWriterConfig) EventPersisterOption {
+	return func(p *EventPersister) { p.cfg = cfg }
 }
 
 // NewEventPersister creates an EventPersister.
-func NewEventPersister(store EventStore, bus EventBus) *EventPersister {
-	return &EventPersister{store: store, bus: bus}
+func NewEventPersister(store EventStore, bus EventBus, opts ...EventPersisterOption) *EventPersister {
+	p := &EventPersister{store: store, bus: bus, seenCh: make(chan struct{})}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // Start subscribes to all events and begins persisting in a background goroutine.
 func (p *EventPersister) Start(ctx context.Context) error {
-	p.sub = p.bus.Subscribe(core.SubscribeOpts{BufferSize: 256})
+	p.writer = NewBatchWriter(p.store, p.cfg)
+	p.sub = p.bus.Subscribe(core.SubscribeOpts{BufferSize: 1024, Backpressure: true})
 	p.done = make(chan struct{})
 	go p.loop(ctx)
 	return nil
 }
 
-func (p *EventPersister) loop(ctx context.Context) {
-	defer close(p.done)
+// Writer returns the batch writer, or nil before Start. Stores route their
+// journal entries through it (see sqlite.Store.SetJournalQueue).
+func (p *EventPersister) Writer() *BatchWriter {
+	return p.writer
+}
+
+// Flush waits until every event received so far has been written.
+func (p *EventPersister) Flush(ctx context.Context) error {
+	if p.writer == nil {
+		return nil
+	}
+	return p.writer.Flush(ctx)
+}
+
+// Sync waits until every event up to bus sequence seq has been handed to the
+// writer and then flushes it, so event_log reads see those events.
+func (p *EventPersister) Sync(ctx context.Context, seq int64) error {
+	if p.writer == nil {
+		return nil
+	}
 	for {
+		p.seenMu.Lock()
+		seen, ch := p.seen, p.seenCh
+		p.seenMu.Unlock()
+		if seen >= seq {
+			break
+		}
 		select {
+		case <-ch:
+		case <-p.done:
+			return ErrBatchWriterClosed
 		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return p.writer.Flush(ctx)
+}
+
+// LostSeq returns the highest bus sequence number of an event that was never
+// written to the event log, or 0. A since_seq replay from below it would
+// silently miss that event.
+func (p *EventPersister) LostSeq() int64 {
+	lost := p.lostSeq.Load()
+	if p.writer != nil {
+		lost = max(lost, p.writer.LostSeq())
+	}
+	return lost
+}
+
+func (p *EventPersister) markSeen(seq int64) {
+	p.seenMu.Lock()
+	defer p.seenMu.Unlock()
+	if seq <= p.seen {
+		return
+	}
+	p.seen = seq
+	close(p.seenCh)
+	p.seenCh = make(chan struct{})
+}
+
+// loop persists events until Stop cancels the subscription and the buffered
+// events are drained. Cancelling ctx does not stop it: events
+// published during shutdown are still written.
+func (p *EventPersister) loop(ctx context.Context) {
+	defer close(p.done)
+	ctx = context.WithoutCancel(ctx)
+	for ev := range p.sub.C {
+		p.persist(ctx, ev)
+	}
+}
+
+func (p *EventPersister) persist(ctx context.Context, ev core.Event) {
+	if ev.Type == core.EventStreamOverflow {
+		// The bus gave up waiting on us and dropped these events.
+		toSeq, _ := ev.Data["to_seq"].(int64)
+		slog.Warn("runtime event persister: bus dropped events",
+			"from_seq", ev.Data["from_seq"], "to_seq", toSeq, "dropped", ev.Data["dropped"])
+		p.markLost(toSeq)
+		p.markSeen(toSeq)
+		return
+	}
+	// Skip transient chunk events — only persist aggregated content.
+	if !core.IsTransientAgentEvent(ev) {
+		if err := p.writer.EnqueueEvent(ctx, &ev); err != nil {
+			slog.Warn("runtime event persister: enqueue event failed",
+				"type", ev.Type, "work_item_id", ev.WorkItemID, "error", err)
+			p.markLost(ev.Seq)
+		}
+	}
+	p.markSeen(ev.Seq)
+}
+
+func (p *EventPersister) markLost(seq int64) {
+	for {
+		lost := p.lostSeq.Load()
+		if seq <= lost || p.lostSeq.CompareAndSwap(lost, seq) {
 			return
-		case ev, ok := <-p.sub.C:
-			if !ok {
-				return
-			}
-			// Skip transient chunk events — only persist aggregated content.
-			if core.IsTransientAgentEvent(ev) {
-				continue
-			}
-			if _, err := p.store.CreateEvent(ctx, &ev); err != nil {
-				slog.Warn("runtime event persister: store event failed",
-					"type", ev.Type, "work_item_id", ev.WorkItemID, "error", err)
-			}
 		}
 	}
 }
 
-// Stop cancels the subscription and stops the background goroutine.
+// Stop cancels the subscription, waits for the background goroutine to
+// persist what is still buffered and flushes the writer.
 func (p *EventPersister) Stop() {
 	if p.sub != nil {
 		p.sub.Cancel()
@@ -60,4 +172,7 @@ func (p *EventPersister) Stop() {
 	if p.done != nil {
 		<-p.done
 	}
+	if p.writer != nil {
+		p.writer.Close()
+	}
 }
@@ -199,6 +199,35 @@ func TestEventPersister_SkipsTransientChunks(t *testing.T) {
 	}
 }
 
+func TestEventPersister_SyncMakesEventsReadable(t *testing.T) {
+	store := newTestStore(t)
+	bus := NewMemBus()
+
+	persister := NewEventPersister(store, bus, WithPersisterBatching(BatchWriterConfig{FlushInterval: time.Hour}))
+	ctx := context.Background()
+	if err := persister.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer persister.Stop()
+
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: 1})
+	bus.Publish(ctx, core.Event{Type: core.EventWorkItemCompleted, WorkItemID: 1})
+
+	syncCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := persister.Sync(syncCtx, bus.LastSeq()); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	since := int64(1)
+	events, err := store.ListEvents(ctx, core.EventFilter{SinceSeq: &since})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Seq != 2 || events[0].Type != core.EventWorkItemCompleted {
+		t.Fatalf("events since seq 1 = %+v", events)
+	}
+}
+
 func newTestStore(t *testing.T) *sqlite.Store {
 	t.Helper()
 	s, err := sqlite.New(":memory:")
@@ -208,3 +237,60 @@ func newTestStore(t *testing.T) *sqlite.Store {
 	t.Cleanup(func() { s.Close() })
 	return s
 }
+
+func TestEventPersister_DoesNotDropUnderBackpressure(t *testing.T) {
+	store := newTestStore(t)
+	bus := NewMemBus()
+
+	// A one-slot writer queue makes the persister fall far behind the bus.
+	persister := NewEventPersister(store, bus, WithPersisterBatching(BatchWriterConfig{BatchSize: 1, QueueSize: 1}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := persister.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	const total = 3000
+	for i := 0; i < total; i++ {
+		bus.Publish(ctx, core.Event{Type: core.EventWorkItemStarted, WorkItemID: 1, Timestamp: time.Now().UTC()})
+	}
+	persister.Stop()
+
+	events, err := store.ListEvents(context.Background(), core.EventFilter{Limit: total + 1})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) != total {
+		t.Fatalf("persisted %d events, want %d", len(events), total)
+	}
+}
+
+func TestEventPersister_RecordsEventsTheBusDropped(t *testing.T) {
+	store := newTestStore(t)
+	persister := NewEventPersister(store, NewMemBus())
+	ctx := context.Background()
+	if err := persister.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer persister.Stop()
+
+	persister.persist(ctx, core.Event{Type: core.EventStreamOverflow, Data: map[string]any{
+		"from_seq": int64(3), "to_seq": int64(7), "dropped": int64(5),
+	}})
+	if got := persister.LostSeq(); got != 7 {
+		t.Fatalf("LostSeq = %d, want 7", got)
+	}
+	// Sync must not wait for events that will never arrive.
+	syncCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := persister.Sync(syncCtx, 7); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	events, err := store.ListEvents(ctx, core.EventFilter{})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("overflow marker was persisted: %+v", events)
+	}
+}
@@ -25,6 +25,12 @@ type CompositeExpander interface {
 	Expand(ctx context.Context, action *core.Action) ([]*core.Action, error)
 }
 
+// ResultScanner finds credentials in text a run produced and returns the text
+// with them redacted.
+type ResultScanner interface {
+	ScrubText(ctx context.Context, workItemID int64, name, text string) (string, []core.LeakFinding)
+}
+
 // ExpanderFunc adapts a plain function into a CompositeExpander.
 type ExpanderFunc func(ctx context.Context, action *core.Action) ([]*core.Action, error)
 
@@ -78,6 +84,7 @@ func (e *WorkItemEngine) prepare(ctx context.Context, action *core.Action) (agen
 func (e *WorkItemEngine) finalize(ctx context.Context, action *core.Action, run *core.Run, runErr error) error {
 	finished := time.Now().UTC()
 	run.FinishedAt = &finished
+	e.scrubRunResult(ctx, action, run)
 
 	if runErr != nil {
 		return e.handleFailure(ctx, action, run, runErr)
@@ -85,6 +92,27 @@ func (e *WorkItemEngine) finalize(ctx context.Context, action *core.Action, run
 	return e.handleSuccess(ctx, action, run)
 }
 
+// scrubRunResult redacts credentials from the run result before it is
+// persisted and records what was found in the result metadata.
+func (e *WorkItemEngine) scrubRunResult(ctx context.Context, action *core.Action, run *core.Run) {
+	if e.workflow.results == nil || strings.TrimSpace(run.ResultMarkdown) == "" {
+		return
+	}
+	original := run.ResultMarkdown
+	scrubbed, findings := e.workflow.results.ScrubText(ctx, action.WorkItemID, "result_markdown", original)
+	if len(findings) == 0 {
+		return
+	}
+	run.ResultMarkdown = scrubbed
+	if text, ok := run.Output["text"].(string); ok && text == original {
+		run.Output["text"] = scrubbed
+	}
+	if run.ResultMetadata == nil {
+		run.ResultMetadata = map[string]any{}
+	}
+	run.ResultMetadata["leak_findings"] = findings
+}
+
 // handleFailure classifies the error and decides: retry, block, or fail.
 func (e *WorkItemEngine) handleFailure(ctx context.Context, action *core.Action, run *core.Run, runErr error) error {
 	run.Status = core.RunFailed
@@ -120,9 +148,11 @@ func (e *WorkItemEngine) handleFailure(ctx context.Context, action *core.Action,
 
 	// Transient or unclassified → retry if budget remains.
 	if action.RetryCount < action.MaxRetries {
-		action.RetryCount++
-		action.Status = core.ActionPending
-		if err := e.workflow.store.UpdateAction(ctx, action); err != nil {
+		err := e.updateAction(ctx, action, func(a *core.Action) {
+			a.RetryCount++
+			a.Status = core.ActionPending
+		})
+		if err != nil {
 			return fmt.Errorf("retry action %d: %w", action.ID, err)
 		}
 		return nil
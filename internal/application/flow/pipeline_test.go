@@ -1,8 +1,11 @@
 package flow
 
 import (
+	"context"
 	"strings"
 	"testing"
+
+	"github.com/yoke233/zhanggui/internal/core"
 )
 
 func TestRenderInputSnapshot_IncludesContextRefs(t *testing.T) {
@@ -62,3 +65,34 @@ func TestRenderInputSnapshot_RespectsTotalBudget(t *testing.T) {
 		t.Fatalf("expected truncated marker in snapshot, got: %q", got)
 	}
 }
+
+type stubResultScanner struct{ secret string }
+
+func (s stubResultScanner) ScrubText(_ context.Context, _ int64, name, text string) (string, []core.LeakFinding) {
+	if !strings.Contains(text, s.secret) {
+		return text, nil
+	}
+	return strings.ReplaceAll(text, s.secret, "[REDACTED]"), []core.LeakFinding{{Rule: "test", File: name}}
+}
+
+func TestScrubRunResult(t *testing.T) {
+	e := New(nil, nil, nil, WithResultScanner(stubResultScanner{secret: "tok-123"}))
+	run := &core.Run{
+		ResultMarkdown: "deployed with tok-123",
+		Output:         map[string]any{"text": "deployed with tok-123"},
+	}
+	e.scrubRunResult(context.Background(), &core.Action{WorkItemID: 1}, run)
+	if run.ResultMarkdown != "deployed with [REDACTED]" || run.Output["text"] != "deployed with [REDACTED]" {
+		t.Fatalf("run = %+v", run)
+	}
+	findings, _ := run.ResultMetadata["leak_findings"].([]core.LeakFinding)
+	if len(findings) != 1 || findings[0].File != "result_markdown" {
+		t.Fatalf("metadata = %v", run.ResultMetadata)
+	}
+
+	clean := &core.Run{ResultMarkdown: "all good"}
+	e.scrubRunResult(context.Background(), &core.Action{}, clean)
+	if clean.ResultMetadata != nil {
+		t.Fatalf("clean run got metadata %v", clean.ResultMetadata)
+	}
+}
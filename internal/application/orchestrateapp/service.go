@@ -236,7 +236,7 @@ func (s *Service) AdoptDeliverable(ctx context.Context, input AdoptDeliverableIn
 	if err != nil {
 		return nil, err
 	}
-	if _, err := s.store.AppendJournal(ctx, &core.JournalEntry{
+	if err := s.store.EnqueueJournal(ctx, &core.JournalEntry{
 		WorkItemID: workItem.ID,
 		Kind:       core.JournalSystem,
 		Source:     journalSourceForActor(input.ActorProfile),
@@ -276,12 +276,23 @@ func (s *Service) ReassignTask(ctx context.Context, input ReassignTaskInput) (*R
 	if err := s.propagatePreferredProfile(ctx, workItem.ID, newProfile); err != nil {
 		return nil, err
 	}
-	workItem.ExecutorProfileID = newProfile
-	workItem.ActiveProfileID = newProfile
-	if err := s.refreshResponsibilityFields(ctx, workItem); err != nil {
-		return nil, err
-	}
-	if err := s.store.UpdateWorkItem(ctx, workItem); err != nil {
+	err = core.RetryOnConflict(func() error {
+		latest, err := s.store.GetWorkItem(ctx, input.WorkItemID)
+		if err != nil {
+			return err
+		}
+		latest.ExecutorProfileID = newProfile
+		latest.ActiveProfileID = newProfile
+		if err := s.refreshResponsibilityFields(ctx, latest); err != nil {
+			return err
+		}
+		if err := s.store.UpdateWorkItem(ctx, latest); err != nil {
+			return err
+		}
+		workItem = latest
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 	entry := map[string]any{
@@ -293,7 +304,7 @@ func (s *Service) ReassignTask(ctx context.Context, input ReassignTaskInput) (*R
 		"sponsor_profile_id":     workItem.SponsorProfileID,
 		"escalation_path":        cloneStrings(workItem.EscalationPath),
 	}
-	if _, err := s.store.AppendJournal(ctx, &core.JournalEntry{
+	if err := s.store.EnqueueJournal(ctx, &core.JournalEntry{
 		WorkItemID: workItem.ID,
 		Kind:       core.JournalAssignment,
 		Source:     journalSourceForActor(input.ActorProfile),
@@ -357,11 +368,8 @@ func (s *Service) DecomposeTask(ctx context.Context, input DecomposeTaskInput) (
 			if action == nil || !isExecutableAction(action) {
 				continue
 			}
-			if action.Config == nil {
-				action.Config = map[string]any{}
-			}
-			action.Config["preferred_profile_id"] = preferredProfile
-			if err := s.store.UpdateAction(ctx, action); err != nil {
+			// MaterializeDAG bumped the version when it wired depends_on.
+			if err := s.setPreferredProfile(ctx, action.ID, preferredProfile); err != nil {
 				return nil, err
 			}
 		}
@@ -476,7 +484,7 @@ func (s *Service) appendEscalationJournal(ctx context.Context, workItem *core.Wo
 		"invite_profiles":        cloneStrings(input.InviteProfiles),
 		"invite_humans":          cloneStrings(input.InviteHumans),
 	}
-	_, err := s.store.AppendJournal(ctx, &core.JournalEntry{
+	return s.store.EnqueueJournal(ctx, &core.JournalEntry{
 		WorkItemID: workItem.ID,
 		Kind:       core.JournalSystem,
 		Source:     journalSourceForActor(input.ActorProfile),
@@ -485,7 +493,6 @@ func (s *Service) appendEscalationJournal(ctx context.Context, workItem *core.Wo
 		Actor:      strings.TrimSpace(input.ActorProfile),
 		CreatedAt:  s.now().UTC(),
 	})
-	return err
 }
 
 func metadataValue(metadata map[string]any, path ...string) string {
@@ -695,17 +702,27 @@ func (s *Service) propagatePreferredProfile(ctx context.Context, workItemID int6
 		if !shouldPropagatePreferredProfile(action) {
 			continue
 		}
-		if action.Config == nil {
-			action.Config = map[string]any{}
-		}
-		action.Config["preferred_profile_id"] = profile
-		if err := s.store.UpdateAction(ctx, action); err != nil {
+		if err := s.setPreferredProfile(ctx, action.ID, profile); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (s *Service) setPreferredProfile(ctx context.Context, actionID int64, profile string) error {
+	return core.RetryOnConflict(func() error {
+		action, err := s.store.GetAction(ctx, actionID)
+		if err != nil {
+			return err
+		}
+		if action.Config == nil {
+			action.Config = map[string]any{}
+		}
+		action.Config["preferred_profile_id"] = profile
+		return s.store.UpdateAction(ctx, action)
+	})
+}
+
 func cloneMetadata(metadata map[string]any) map[string]any {
 	if metadata == nil {
 		return nil
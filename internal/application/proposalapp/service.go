@@ -39,6 +39,8 @@ type UpdateProposalInput struct {
 	WorkItemDrafts  *[]core.ProposalWorkItemDraft
 	Metadata        *map[string]any
 	SourceMessageID *int64
+	// ExpectedVersion, when non-zero, must match the stored version.
+	ExpectedVersion int64
 }
 
 type ReviewInput struct {
@@ -135,10 +137,17 @@ func (s *Service) GetProposal(ctx context.Context, proposalID int64) (*core.Thre
 }
 
 func (s *Service) UpdateProposal(ctx context.Context, input UpdateProposalInput) (*core.ThreadProposal, error) {
+	return retryOnConflict(func() (*core.ThreadProposal, error) { return s.updateProposal(ctx, input) })
+}
+
+func (s *Service) updateProposal(ctx context.Context, input UpdateProposalInput) (*core.ThreadProposal, error) {
 	proposal, err := s.GetProposal(ctx, input.ID)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkProposalVersion(proposal, input.ExpectedVersion); err != nil {
+		return nil, err
+	}
 	if proposal.Status != core.ProposalDraft && proposal.Status != core.ProposalRevised {
 		return nil, core.ErrInvalidTransition
 	}
@@ -187,11 +196,18 @@ func (s *Service) UpdateProposal(ctx context.Context, input UpdateProposalInput)
 	return proposal, nil
 }
 
-func (s *Service) ReplaceDrafts(ctx context.Context, proposalID int64, drafts []core.ProposalWorkItemDraft) (*core.ThreadProposal, error) {
+func (s *Service) ReplaceDrafts(ctx context.Context, proposalID int64, expectedVersion int64, drafts []core.ProposalWorkItemDraft) (*core.ThreadProposal, error) {
+	return retryOnConflict(func() (*core.ThreadProposal, error) { return s.replaceDrafts(ctx, proposalID, expectedVersion, drafts) })
+}
+
+func (s *Service) replaceDrafts(ctx context.Context, proposalID int64, expectedVersion int64, drafts []core.ProposalWorkItemDraft) (*core.ThreadProposal, error) {
 	proposal, err := s.GetProposal(ctx, proposalID)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkProposalVersion(proposal, expectedVersion); err != nil {
+		return nil, err
+	}
 	if proposal.Status != core.ProposalDraft && proposal.Status != core.ProposalRevised {
 		return nil, core.ErrInvalidTransition
 	}
@@ -209,6 +225,13 @@ func (s *Service) ReplaceDrafts(ctx context.Context, proposalID int64, drafts []
 	return proposal, nil
 }
 
+func checkProposalVersion(proposal *core.ThreadProposal, expected int64) error {
+	if expected > 0 && expected != proposal.Version {
+		return &core.VersionConflictError{Resource: "proposal", ID: proposal.ID, Expected: expected, Actual: proposal.Version}
+	}
+	return nil
+}
+
 func (s *Service) DeleteProposal(ctx context.Context, proposalID int64) error {
 	proposal, err := s.GetProposal(ctx, proposalID)
 	if err != nil {
@@ -221,6 +244,10 @@ func (s *Service) DeleteProposal(ctx context.Context, proposalID int64) error {
 }
 
 func (s *Service) Submit(ctx context.Context, proposalID int64) (*core.ThreadProposal, error) {
+	return retryOnConflict(func() (*core.ThreadProposal, error) { return s.submit(ctx, proposalID) })
+}
+
+func (s *Service) submit(ctx context.Context, proposalID int64) (*core.ThreadProposal, error) {
 	proposal, err := s.GetProposal(ctx, proposalID)
 	if err != nil {
 		return nil, err
@@ -262,6 +289,15 @@ func (s *Service) Submit(ctx context.Context, proposalID int64) (*core.ThreadPro
 }
 
 func (s *Service) Approve(ctx context.Context, proposalID int64, input ReviewInput) (*core.ThreadProposal, error) {
+	if s == nil || s.tx == nil {
+		// Without a transaction a failed attempt keeps the initiative it
+		// materialized, so retrying would create a second one.
+		return s.approve(ctx, proposalID, input)
+	}
+	return retryOnConflict(func() (*core.ThreadProposal, error) { return s.approve(ctx, proposalID, input) })
+}
+
+func (s *Service) approve(ctx context.Context, proposalID int64, input ReviewInput) (*core.ThreadProposal, error) {
 	proposal, err := s.GetProposal(ctx, proposalID)
 	if err != nil {
 		return nil, err
@@ -322,6 +358,10 @@ func (s *Service) Approve(ctx context.Context, proposalID int64, input ReviewInp
 }
 
 func (s *Service) Reject(ctx context.Context, proposalID int64, input ReviewInput) (*core.ThreadProposal, error) {
+	return retryOnConflict(func() (*core.ThreadProposal, error) { return s.reject(ctx, proposalID, input) })
+}
+
+func (s *Service) reject(ctx context.Context, proposalID int64, input ReviewInput) (*core.ThreadProposal, error) {
 	proposal, err := s.GetProposal(ctx, proposalID)
 	if err != nil {
 		return nil, err
@@ -358,6 +398,10 @@ func (s *Service) Reject(ctx context.Context, proposalID int64, input ReviewInpu
 }
 
 func (s *Service) Revise(ctx context.Context, proposalID int64, input ReviseInput) (*core.ThreadProposal, error) {
+	return retryOnConflict(func() (*core.ThreadProposal, error) { return s.revise(ctx, proposalID, input) })
+}
+
+func (s *Service) revise(ctx context.Context, proposalID int64, input ReviseInput) (*core.ThreadProposal, error) {
 	proposal, err := s.GetProposal(ctx, proposalID)
 	if err != nil {
 		return nil, err
@@ -392,6 +436,20 @@ func (s *Service) Revise(ctx context.Context, proposalID int64, input ReviseInpu
 	return proposal, nil
 }
 
+// retryOnConflict re-runs fn, which reads the proposal afresh, when a
+// concurrent write bumped a version between its read and its write. A
+// mismatch with a caller-supplied expected version fails again on every
+// attempt and is returned as is.
+func retryOnConflict(fn func() (*core.ThreadProposal, error)) (*core.ThreadProposal, error) {
+	var proposal *core.ThreadProposal
+	err := core.RetryOnConflict(func() error {
+		var err error
+		proposal, err = fn()
+		return err
+	})
+	return proposal, err
+}
+
 func (s *Service) withTx(ctx context.Context, fn func(ctx context.Context, store Store) error) error {
 	if s.tx != nil {
 		return s.tx.InTx(ctx, fn)
@@ -396,3 +396,54 @@ func TestServiceApproveRevalidatesSourceMessageThreadOwnership(t *testing.T) {
 		t.Fatal("expected Approve to reject foreign source_message_id")
 	}
 }
+
+// racingProposalStore lets another writer update the proposal right before
+// the first write, so that write hits a version conflict.
+type racingProposalStore struct {
+	*sqlite.Store
+	raced bool
+}
+
+func (s *racingProposalStore) UpdateThreadProposal(ctx context.Context, proposal *core.ThreadProposal) error {
+	if !s.raced {
+		s.raced = true
+		other, err := s.Store.GetThreadProposal(ctx, proposal.ID)
+		if err != nil {
+			return err
+		}
+		other.Summary = "edited concurrently"
+		if err := s.Store.UpdateThreadProposal(ctx, other); err != nil {
+			return err
+		}
+	}
+	return s.Store.UpdateThreadProposal(ctx, proposal)
+}
+
+func TestServiceSubmitRetriesVersionConflict(t *testing.T) {
+	base := newProposalServiceTestStore(t)
+	store := &racingProposalStore{Store: base, raced: true}
+	svc := New(Config{Store: store, Bus: &recordingBus{}})
+	ctx := context.Background()
+
+	threadID, err := base.CreateThread(ctx, &core.Thread{Title: "racing", Status: core.ThreadActive, OwnerID: "user-1"})
+	if err != nil {
+		t.Fatalf("CreateThread: %v", err)
+	}
+	proposal, err := svc.CreateProposal(ctx, CreateProposalInput{
+		ThreadID:       threadID,
+		Title:          "racing proposal",
+		WorkItemDrafts: []core.ProposalWorkItemDraft{{TempID: "draft-a", Title: "task A"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateProposal: %v", err)
+	}
+
+	store.raced = false
+	proposal, err = svc.Submit(ctx, proposal.ID)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if proposal.Status != core.ProposalOpen || proposal.Summary != "edited concurrently" {
+		t.Fatalf("proposal = %+v, want open with the concurrent edit kept", proposal)
+	}
+}
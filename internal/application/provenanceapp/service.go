@@ -0,0 +1,439 @@
+// Package provenanceapp signs in-toto provenance statements for the commits
+// agents publish: which work item, actions and runs produced a commit, with
+// which profile, driver and model, the digest of each briefing, the audit
+// digests of every tool call and the verdicts of the gates that let it land.
+//
+// Statements are wrapped in DSSE envelopes signed with a local ed25519 key,
+// stored as provenance Resources and optionally attached to the commit as a
+// git note.
+package provenanceapp
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// DefaultNotesRef is the git notes ref envelopes are attached under.
+const DefaultNotesRef = "refs/notes/provenance"
+
+// Store is the persistence the service needs.
+type Store interface {
+	core.ProvenanceStore
+	GetWorkItem(ctx context.Context, id int64) (*core.WorkItem, error)
+	ListActionsByWorkItem(ctx context.Context, workItemID int64) ([]*core.Action, error)
+	ListRunsByAction(ctx context.Context, actionID int64) ([]*core.Run, error)
+	GetUsageByRun(ctx context.Context, runID int64) (*core.UsageRecord, error)
+	ListToolCallAuditsByRun(ctx context.Context, runID int64) ([]*core.ToolCallAudit, error)
+	ListActionSignalsByType(ctx context.Context, actionID int64, types ...core.SignalType) ([]*core.ActionSignal, error)
+	CreateResource(ctx context.Context, r *core.Resource) (int64, error)
+}
+
+// ProfileLookup resolves the driver of an agent profile.
+type ProfileLookup interface {
+	GetProfile(ctx context.Context, id string) (*core.AgentProfile, error)
+}
+
+// Config configures the service.
+type Config struct {
+	Store Store
+	Files core.FileStore
+	// Profiles is optional; without it steps carry no driver ID.
+	Profiles ProfileLookup
+	// KeyFile is the path of the ed25519 signing key, created with mode 0600
+	// when missing.
+	KeyFile string
+	// GitNotes attaches each envelope to its commit under NotesRef.
+	GitNotes bool
+	NotesRef string
+}
+
+// Service records and verifies provenance statements.
+type Service struct {
+	store    Store
+	files    core.FileStore
+	profiles ProfileLookup
+	keyFile  string
+	gitNotes bool
+	notesRef string
+
+	mu  sync.Mutex
+	key ed25519.PrivateKey
+}
+
+// New creates a provenance service. The key file is not read until the first
+// statement is signed.
+func New(cfg Config) *Service {
+	notesRef := strings.TrimSpace(cfg.NotesRef)
+	if notesRef == "" {
+		notesRef = DefaultNotesRef
+	}
+	return &Service{
+		store:    cfg.Store,
+		files:    cfg.Files,
+		profiles: cfg.Profiles,
+		keyFile:  strings.TrimSpace(cfg.KeyFile),
+		gitNotes: cfg.GitNotes,
+		notesRef: notesRef,
+	}
+}
+
+// RecordProvenance builds, signs and stores the statement for subject. It
+// implements core.ProvenanceRecorder. When git notes are enabled and the
+// subject has a worktree, the returned resource's "git_note_ref" metadata
+// names the ref the note was added under, for the caller to push.
+func (s *Service) RecordProvenance(ctx context.Context, subject core.ProvenanceSubject) (*core.Resource, error) {
+	sha := strings.ToLower(strings.TrimSpace(subject.CommitSHA))
+	if sha == "" {
+		return nil, fmt.Errorf("provenance: commit sha is required")
+	}
+	subject.CommitSHA = sha
+	workItem, err := s.store.GetWorkItem(ctx, subject.WorkItemID)
+	if err != nil {
+		return nil, fmt.Errorf("provenance: load work item %d: %w", subject.WorkItemID, err)
+	}
+	statement, err := s.BuildStatement(ctx, workItem, subject)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.signingKey()
+	if err != nil {
+		return nil, err
+	}
+	env, err := Sign(key, statement)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := "provenance-" + sha + ".intoto.json"
+	uri, size, err := s.files.Save(ctx, fileName, bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("provenance: save envelope: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	metadata := map[string]any{
+		"commit": sha,
+		"kind":   string(subject.Kind),
+		"key_id": env.Signatures[0].KeyID,
+	}
+	if subject.ChangeRequestNumber > 0 {
+		metadata["pr_number"] = subject.ChangeRequestNumber
+	}
+	if s.gitNotes && strings.TrimSpace(subject.WorkDir) != "" {
+		if err := addGitNote(ctx, subject.WorkDir, s.notesRef, sha, raw); err != nil {
+			metadata["git_note_error"] = err.Error()
+		} else {
+			metadata["git_note_ref"] = s.notesRef
+		}
+	}
+	resource := &core.Resource{
+		WorkItemID:  &subject.WorkItemID,
+		StorageKind: "local",
+		URI:         uri,
+		Role:        core.ResourceRoleProvenance,
+		FileName:    fileName,
+		MimeType:    "application/json",
+		SizeBytes:   size,
+		Checksum:    hex.EncodeToString(sum[:]),
+		Metadata:    metadata,
+	}
+	if workItem.ProjectID != nil {
+		resource.ProjectID = *workItem.ProjectID
+	}
+	if _, err := s.store.CreateResource(ctx, resource); err != nil {
+		_ = s.files.Delete(ctx, uri)
+		return nil, err
+	}
+	return resource, nil
+}
+
+// BuildStatement assembles the unsigned statement for subject from the work
+// item's actions and their latest runs.
+func (s *Service) BuildStatement(ctx context.Context, workItem *core.WorkItem, subject core.ProvenanceSubject) (*Statement, error) {
+	actions, err := s.store.ListActionsByWorkItem(ctx, workItem.ID)
+	if err != nil {
+		return nil, fmt.Errorf("provenance: list actions: %w", err)
+	}
+	params := ExternalParameters{
+		WorkItemID: workItem.ID,
+		Repository: strings.TrimSpace(subject.Repo),
+		Ref:        strings.TrimSpace(subject.Ref),
+	}
+	if workItem.ProjectID != nil {
+		params.ProjectID = *workItem.ProjectID
+	}
+	if subject.ChangeRequestNumber > 0 {
+		params.ChangeRequest = &ChangeRequest{Number: subject.ChangeRequestNumber, URL: strings.TrimSpace(subject.ChangeRequestURL)}
+	}
+
+	statement := &Statement{
+		Type:          StatementType,
+		Subject:       []Subject{{Name: subjectName(subject), Digest: map[string]string{"gitCommit": subject.CommitSHA}}},
+		PredicateType: PredicateType,
+		Predicate: Predicate{
+			BuildDefinition: BuildDefinition{
+				BuildType:          buildTypePrefix + string(subject.Kind) + "/v1",
+				ExternalParameters: params,
+				InternalParameters: InternalParameters{Steps: []Step{}},
+			},
+			RunDetails: RunDetails{
+				Builder:  Builder{ID: BuilderID},
+				Metadata: BuildMetadata{InvocationID: fmt.Sprintf("work-item/%d/action/%d/run/%d", workItem.ID, subject.ActionID, subject.RunID)},
+			},
+		},
+	}
+	for _, action := range actions {
+		runs, err := s.store.ListRunsByAction(ctx, action.ID)
+		if err != nil {
+			return nil, fmt.Errorf("provenance: list runs of action %d: %w", action.ID, err)
+		}
+		if len(runs) == 0 {
+			continue
+		}
+		// Runs are ordered by attempt; the latest one is what the commit saw.
+		run := runs[len(runs)-1]
+		step, err := s.buildStep(ctx, action, run)
+		if err != nil {
+			return nil, err
+		}
+		statement.Predicate.BuildDefinition.InternalParameters.Steps = append(statement.Predicate.BuildDefinition.InternalParameters.Steps, step)
+		if run.ID == subject.RunID {
+			statement.Predicate.RunDetails.Metadata.StartedOn = run.StartedAt
+			statement.Predicate.RunDetails.Metadata.FinishedOn = run.FinishedAt
+		}
+	}
+	return statement, nil
+}
+
+func (s *Service) buildStep(ctx context.Context, action *core.Action, run *core.Run) (Step, error) {
+	step := Step{
+		ActionID:  action.ID,
+		Name:      action.Name,
+		Type:      string(action.Type),
+		RunID:     run.ID,
+		Attempt:   run.Attempt,
+		Status:    string(run.Status),
+		AgentID:   run.AgentID,
+		ProfileID: run.AgentID,
+	}
+	if usage, err := s.store.GetUsageByRun(ctx, run.ID); err == nil && usage != nil {
+		if usage.ProfileID != "" {
+			step.ProfileID = usage.ProfileID
+		}
+		step.ModelID = usage.ModelID
+	}
+	if s.profiles != nil && step.ProfileID != "" {
+		if profile, err := s.profiles.GetProfile(ctx, step.ProfileID); err == nil && profile != nil {
+			step.DriverID = profile.DriverID
+			if step.DriverID == "" {
+				step.DriverID = profile.Driver.ID
+			}
+		}
+	}
+	if run.BriefingSnapshot != "" {
+		sum := sha256.Sum256([]byte(run.BriefingSnapshot))
+		step.BriefingDigest = map[string]string{"sha256": hex.EncodeToString(sum[:])}
+	}
+
+	audits, err := s.store.ListToolCallAuditsByRun(ctx, run.ID)
+	if err != nil {
+		return Step{}, fmt.Errorf("provenance: list tool calls of run %d: %w", run.ID, err)
+	}
+	for _, a := range audits {
+		step.ToolCalls = append(step.ToolCalls, ToolCall{
+			ID:           a.ToolCallID,
+			Name:         a.ToolName,
+			Status:       a.Status,
+			InputDigest:  a.InputDigest,
+			OutputDigest: a.OutputDigest,
+			StdoutDigest: a.StdoutDigest,
+			StderrDigest: a.StderrDigest,
+		})
+	}
+
+	if action.Type == core.ActionGate {
+		if verdict, ok := run.ResultMetadata["verdict"].(string); ok {
+			step.Verdict = verdict
+		}
+	}
+	signals, err := s.store.ListActionSignalsByType(ctx, action.ID, core.SignalApprove, core.SignalReject)
+	if err != nil {
+		return Step{}, fmt.Errorf("provenance: list verdicts of action %d: %w", action.ID, err)
+	}
+	for _, sig := range signals {
+		step.Signals = append(step.Signals, Signal{
+			Type:        string(sig.Type),
+			Source:      string(sig.Source),
+			Actor:       sig.Actor,
+			ActorUserID: sig.ActorUserID,
+			Summary:     sig.Summary,
+			CreatedAt:   sig.CreatedAt,
+		})
+	}
+	return step, nil
+}
+
+func subjectName(subject core.ProvenanceSubject) string {
+	name := "git+" + strings.TrimSpace(subject.Repo)
+	if strings.TrimSpace(subject.Repo) == "" {
+		name = "git"
+	}
+	if subject.ChangeRequestNumber > 0 {
+		return name + "#" + strconv.Itoa(subject.ChangeRequestNumber) + "@" + subject.CommitSHA
+	}
+	return name + "@" + subject.CommitSHA
+}
+
+func (s *Service) signingKey() (ed25519.PrivateKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil {
+		return s.key, nil
+	}
+	if s.keyFile == "" {
+		return nil, fmt.Errorf("provenance key file is not configured")
+	}
+	key, err := LoadOrCreateKey(s.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	s.key = key
+	return key, nil
+}
+
+// Verification is the outcome of checking one stored envelope.
+type Verification struct {
+	// Source is "resource" or "git-note".
+	Source     string     `json:"source"`
+	ResourceID int64      `json:"resource_id,omitempty"`
+	Statement  *Statement `json:"statement,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Valid reports whether the envelope verified.
+func (v Verification) Valid() bool { return v.Error == "" && v.Statement != nil }
+
+// Verify checks every stored envelope for a commit against the local key.
+// When workDir is set the git note on the commit is checked too. It returns
+// an error only when nothing could be checked.
+func (s *Service) Verify(ctx context.Context, commitSHA, workDir string) ([]Verification, error) {
+	commitSHA = strings.ToLower(strings.TrimSpace(commitSHA))
+	if commitSHA == "" {
+		return nil, fmt.Errorf("commit sha is required")
+	}
+	if s.keyFile == "" {
+		return nil, fmt.Errorf("provenance key file is not configured")
+	}
+	key, err := LoadKey(s.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pub := key.Public().(ed25519.PublicKey)
+
+	var out []Verification
+	resources, err := s.store.ListProvenanceResources(ctx, commitSHA)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resources {
+		v := Verification{Source: "resource", ResourceID: r.ID}
+		raw, err := s.readResource(ctx, r)
+		if err == nil {
+			v.Statement, err = verifyEnvelope(pub, raw, commitSHA)
+		}
+		if err != nil {
+			v.Error = err.Error()
+		}
+		out = append(out, v)
+	}
+	if strings.TrimSpace(workDir) != "" {
+		if raw, err := gitOutput(ctx, workDir, nil, "notes", "--ref", s.notesRef, "show", commitSHA); err == nil {
+			v := Verification{Source: "git-note"}
+			if v.Statement, err = verifyEnvelope(pub, raw, commitSHA); err != nil {
+				v.Error = err.Error()
+			}
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no provenance recorded for commit %s", commitSHA)
+	}
+	return out, nil
+}
+
+func (s *Service) readResource(ctx context.Context, r *core.Resource) ([]byte, error) {
+	rc, err := s.files.Open(ctx, r.URI)
+	if err != nil {
+		return nil, fmt.Errorf("open envelope: %w", err)
+	}
+	defer rc.Close()
+	raw, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("read envelope: %w", err)
+	}
+	if r.Checksum != "" {
+		sum := sha256.Sum256(raw)
+		if hex.EncodeToString(sum[:]) != r.Checksum {
+			return nil, errors.New("envelope file does not match its recorded checksum")
+		}
+	}
+	return raw, nil
+}
+
+func verifyEnvelope(pub ed25519.PublicKey, raw []byte, commitSHA string) (*Statement, error) {
+	var env Envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, fmt.Errorf("decode envelope: %w", err)
+	}
+	statement, err := Open(pub, &env)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(statement.CommitSHA(), commitSHA) {
+		return nil, fmt.Errorf("statement attests commit %s, not %s", statement.CommitSHA(), commitSHA)
+	}
+	return statement, nil
+}
+
+func addGitNote(ctx context.Context, dir, ref, sha string, envelope []byte) error {
+	_, err := gitOutput(ctx, dir, envelope,
+		"-c", "user.name=ai-flow",
+		"-c", "user.email=ai-flow@local",
+		"notes", "--ref", ref, "add", "-f", "-F", "-", sha,
+	)
+	return err
+}
+
+func gitOutput(ctx context.Context, dir string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,188 @@
+package provenanceapp
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/resource/filestore"
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type stubProfiles map[string]string
+
+func (s stubProfiles) GetProfile(_ context.Context, id string) (*core.AgentProfile, error) {
+	return &core.AgentProfile{ID: id, DriverID: s[id]}, nil
+}
+
+func TestRecordAndVerify(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := sqlite.New(filepath.Join(dir, "provenance.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	projectID, err := store.CreateProject(ctx, &core.Project{Name: "backend", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	workItemID, err := store.CreateWorkItem(ctx, &core.WorkItem{Title: "fix login", ProjectID: &projectID, Status: core.WorkItemRunning})
+	if err != nil {
+		t.Fatalf("CreateWorkItem: %v", err)
+	}
+	implID, err := store.CreateAction(ctx, &core.Action{WorkItemID: workItemID, Name: "implement", Type: core.ActionExec, Status: core.ActionDone})
+	if err != nil {
+		t.Fatalf("CreateAction: %v", err)
+	}
+	gateID, err := store.CreateAction(ctx, &core.Action{WorkItemID: workItemID, Name: "review", Type: core.ActionGate, Status: core.ActionDone, Position: 1})
+	if err != nil {
+		t.Fatalf("CreateAction: %v", err)
+	}
+	if _, err := store.CreateAction(ctx, &core.Action{WorkItemID: workItemID, Name: "deploy", Type: core.ActionExec, Status: core.ActionPending, Position: 2}); err != nil {
+		t.Fatalf("CreateAction: %v", err)
+	}
+	implRun, err := store.CreateRun(ctx, &core.Run{ActionID: implID, WorkItemID: workItemID, Status: core.RunSucceeded, AgentID: "worker", BriefingSnapshot: "fix the login bug", Attempt: 1})
+	if err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	if _, err := store.CreateUsageRecord(ctx, &core.UsageRecord{RunID: implRun, WorkItemID: workItemID, ActionID: implID, AgentID: "worker", ProfileID: "worker", ModelID: "gpt-5"}); err != nil {
+		t.Fatalf("CreateUsageRecord: %v", err)
+	}
+	if _, err := store.CreateToolCallAudit(ctx, &core.ToolCallAudit{WorkItemID: workItemID, ActionID: implID, RunID: implRun, ToolCallID: "call-1", ToolName: "edit", Status: "completed", InputDigest: "sha256:in", OutputDigest: "sha256:out"}); err != nil {
+		t.Fatalf("CreateToolCallAudit: %v", err)
+	}
+	gateRun, err := store.CreateRun(ctx, &core.Run{ActionID: gateID, WorkItemID: workItemID, Status: core.RunSucceeded, AgentID: "reviewer", Attempt: 1, ResultMetadata: map[string]any{"verdict": "pass"}})
+	if err != nil {
+		t.Fatalf("CreateRun: %v", err)
+	}
+	if _, err := store.CreateActionSignal(ctx, &core.ActionSignal{ActionID: gateID, WorkItemID: workItemID, Type: core.SignalApprove, Source: core.SignalSourceHuman, Actor: "alice", ActorUserID: 7, Summary: "looks good", CreatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("CreateActionSignal: %v", err)
+	}
+
+	repo := filepath.Join(dir, "repo")
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if err := os.MkdirAll(repo, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q")
+	if err := os.WriteFile(filepath.Join(repo, "login.go"), []byte("package login\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "-A")
+	git("-c", "user.name=t", "-c", "user.email=t@local", "commit", "-q", "-m", "fix login")
+	sha := git("rev-parse", "HEAD")
+
+	keyFile := filepath.Join(dir, DefaultKeyFile)
+	svc := New(Config{
+		Store:    store,
+		Files:    filestore.NewLocal(filepath.Join(dir, "files")),
+		Profiles: stubProfiles{"worker": "codex"},
+		KeyFile:  keyFile,
+		GitNotes: true,
+	})
+	resource, err := svc.RecordProvenance(ctx, core.ProvenanceSubject{
+		Kind:                core.ProvenanceChangeRequest,
+		WorkItemID:          workItemID,
+		ActionID:            gateID,
+		RunID:               gateRun,
+		CommitSHA:           sha,
+		Repo:                "github.com/acme/backend",
+		ChangeRequestNumber: 42,
+		WorkDir:             repo,
+	})
+	if err != nil {
+		t.Fatalf("RecordProvenance: %v", err)
+	}
+	if resource.ProjectID != projectID || resource.Role != core.ResourceRoleProvenance || resource.Metadata["git_note_ref"] != DefaultNotesRef {
+		t.Fatalf("resource = %+v", resource)
+	}
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("key file: %v, %v", info, err)
+	}
+
+	results, err := svc.Verify(ctx, sha[:12], repo)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if len(results) != 2 || results[0].Source != "resource" || results[1].Source != "git-note" {
+		t.Fatalf("results = %+v", results)
+	}
+	for _, r := range results {
+		if !r.Valid() {
+			t.Fatalf("%s did not verify: %s", r.Source, r.Error)
+		}
+	}
+	statement := results[0].Statement
+	if statement.CommitSHA() != sha || statement.Subject[0].Name != "git+github.com/acme/backend#42@"+sha {
+		t.Fatalf("subject = %+v", statement.Subject)
+	}
+	steps := statement.Predicate.BuildDefinition.InternalParameters.Steps
+	if len(steps) != 2 {
+		t.Fatalf("steps = %+v", steps)
+	}
+	impl, gate := steps[0], steps[1]
+	if impl.ProfileID != "worker" || impl.DriverID != "codex" || impl.ModelID != "gpt-5" ||
+		impl.BriefingDigest["sha256"] == "" || len(impl.ToolCalls) != 1 || impl.ToolCalls[0].InputDigest != "sha256:in" {
+		t.Fatalf("implement step = %+v", impl)
+	}
+	if gate.Verdict != "pass" || len(gate.Signals) != 1 || gate.Signals[0].Actor != "alice" {
+		t.Fatalf("gate step = %+v", gate)
+	}
+
+	// A key other than the signer's rejects the envelope.
+	if err := os.Rename(keyFile, keyFile+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateKey(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	results, err = New(Config{Store: store, Files: filestore.NewLocal(filepath.Join(dir, "files")), KeyFile: keyFile}).Verify(ctx, sha, "")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if len(results) != 1 || results[0].Valid() || !strings.Contains(results[0].Error, ErrInvalidSignature.Error()) {
+		t.Fatalf("results with foreign key = %+v", results)
+	}
+
+	if _, err := svc.Verify(ctx, "deadbeef", ""); err == nil {
+		t.Fatalf("expected an error for a commit without provenance")
+	}
+}
+
+func TestOpenRejectsTamperedPayload(t *testing.T) {
+	key, err := LoadOrCreateKey(filepath.Join(t.TempDir(), DefaultKeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := Sign(key, &Statement{Type: StatementType, PredicateType: PredicateType, Subject: []Subject{{Name: "git@abc", Digest: map[string]string{"gitCommit": "abc"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	if _, err := Open(pub, env); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	env.Payload = env.Payload[:len(env.Payload)-4] + "AAAA"
+	if _, err := Open(pub, env); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("Open(tampered) = %v, want ErrInvalidSignature", err)
+	}
+}
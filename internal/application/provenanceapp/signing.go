@@ -0,0 +1,153 @@
+package provenanceapp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/platform/keyfile"
+)
+
+// ErrInvalidSignature is returned for an envelope that was not signed by the
+// local key, or whose payload was changed after signing.
+var ErrInvalidSignature = errors.New("provenance signature is invalid")
+
+// DefaultKeyFile is the signing key file name inside the data directory.
+const DefaultKeyFile = "provenance.key"
+
+// ResolveKeyFile returns the signing key path for a configured value, which
+// is relative to dataDir unless absolute.
+func ResolveKeyFile(dataDir, keyFile string) string {
+	return keyfile.Resolve(dataDir, keyFile, DefaultKeyFile)
+}
+
+// LoadOrCreateKey reads a PEM-encoded PKCS#8 ed25519 private key from path,
+// generating one when the file does not exist.
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	raw, err := keyfile.LoadOrCreate(path, func() ([]byte, error) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("provenance key: %w", err)
+	}
+	return parseKey(path, raw)
+}
+
+// LoadKey reads an existing signing key without creating one.
+func LoadKey(path string) (ed25519.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read provenance key: %w", err)
+	}
+	return parseKey(path, raw)
+}
+
+func parseKey(path string, raw []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("provenance key file %s is not PEM encoded", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse provenance key %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("provenance key file %s must hold an ed25519 key", path)
+	}
+	return key, nil
+}
+
+// KeyID fingerprints a public key as the hex SHA-256 of its PKIX encoding.
+func KeyID(pub ed25519.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// pae is the DSSE v1 pre-authentication encoding of a payload.
+func pae(payloadType string, payload []byte) []byte {
+	var b strings.Builder
+	b.WriteString("DSSEv1 ")
+	b.WriteString(strconv.Itoa(len(payloadType)))
+	b.WriteString(" ")
+	b.WriteString(payloadType)
+	b.WriteString(" ")
+	b.WriteString(strconv.Itoa(len(payload)))
+	b.WriteString(" ")
+	b.Write(payload)
+	return []byte(b.String())
+}
+
+// Sign wraps a statement in a DSSE envelope signed with key.
+func Sign(key ed25519.PrivateKey, statement *Statement) (*Envelope, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(key, pae(PayloadType, payload))
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{{
+			KeyID: KeyID(key.Public().(ed25519.PublicKey)),
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		}},
+	}, nil
+}
+
+// Open checks that env carries a valid signature by pub and returns the
+// statement inside it.
+func Open(pub ed25519.PublicKey, env *Envelope) (*Statement, error) {
+	if env == nil || env.PayloadType != PayloadType {
+		return nil, fmt.Errorf("%w: unexpected payload type", ErrInvalidSignature)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload is not base64", ErrInvalidSignature)
+	}
+	keyID := KeyID(pub)
+	verified := false
+	for _, s := range env.Signatures {
+		if s.KeyID != "" && s.KeyID != keyID {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err == nil && ed25519.Verify(pub, pae(env.PayloadType, payload), sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: no signature by key %s", ErrInvalidSignature, keyID)
+	}
+	var statement Statement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return nil, fmt.Errorf("decode statement: %w", err)
+	}
+	if statement.Type != StatementType || statement.PredicateType != PredicateType {
+		return nil, fmt.Errorf("unexpected statement type %q / %q", statement.Type, statement.PredicateType)
+	}
+	return &statement, nil
+}
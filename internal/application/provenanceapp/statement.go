@@ -0,0 +1,145 @@
+package provenanceapp
+
+import "time"
+
+const (
+	// StatementType is the in-toto Statement v1 type.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PredicateType is the SLSA provenance v1 predicate type.
+	PredicateType = "https://slsa.dev/provenance/v1"
+	// PayloadType is the DSSE payload type of in-toto statements.
+	PayloadType = "application/vnd.in-toto+json"
+	// BuilderID identifies ai-flow as the builder in runDetails.
+	BuilderID = "https://github.com/yoke233/zhanggui/ai-flow"
+
+	buildTypePrefix = "https://github.com/yoke233/zhanggui/provenance/"
+)
+
+// Statement is an in-toto v1 statement carrying a SLSA-style provenance
+// predicate.
+type Statement struct {
+	Type          string    `json:"_type"`
+	Subject       []Subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     Predicate `json:"predicate"`
+}
+
+// Subject is an attested artifact; for ai-flow always a git commit.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Predicate follows the SLSA provenance v1 layout.
+type Predicate struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition records what was asked for (the work item and target) and
+// how ai-flow carried it out (the action steps).
+type BuildDefinition struct {
+	BuildType          string             `json:"buildType"`
+	ExternalParameters ExternalParameters `json:"externalParameters"`
+	InternalParameters InternalParameters `json:"internalParameters"`
+}
+
+// ExternalParameters are the inputs a user controls.
+type ExternalParameters struct {
+	WorkItemID    int64          `json:"workItemId"`
+	ProjectID     int64          `json:"projectId,omitempty"`
+	Repository    string         `json:"repository,omitempty"`
+	Ref           string         `json:"ref,omitempty"`
+	ChangeRequest *ChangeRequest `json:"changeRequest,omitempty"`
+}
+
+// ChangeRequest identifies a merged pull or merge request.
+type ChangeRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"url,omitempty"`
+}
+
+// InternalParameters lists every action of the work item that ran, with its
+// latest run.
+type InternalParameters struct {
+	Steps []Step `json:"steps"`
+}
+
+// Step is one action run: who ran it, with which briefing, which tools it
+// called and how gates judged it.
+type Step struct {
+	ActionID       int64             `json:"actionId"`
+	Name           string            `json:"name"`
+	Type           string            `json:"type"`
+	RunID          int64             `json:"runId"`
+	Attempt        int               `json:"attempt"`
+	Status         string            `json:"status"`
+	AgentID        string            `json:"agentId,omitempty"`
+	ProfileID      string            `json:"profileId,omitempty"`
+	DriverID       string            `json:"driverId,omitempty"`
+	ModelID        string            `json:"modelId,omitempty"`
+	BriefingDigest map[string]string `json:"briefingDigest,omitempty"`
+	ToolCalls      []ToolCall        `json:"toolCalls,omitempty"`
+	Verdict        string            `json:"verdict,omitempty"`
+	Signals        []Signal          `json:"signals,omitempty"`
+}
+
+// ToolCall carries the audit digests of one tool call, never its content.
+type ToolCall struct {
+	ID           string `json:"id"`
+	Name         string `json:"name,omitempty"`
+	Status       string `json:"status,omitempty"`
+	InputDigest  string `json:"inputDigest,omitempty"`
+	OutputDigest string `json:"outputDigest,omitempty"`
+	StdoutDigest string `json:"stdoutDigest,omitempty"`
+	StderrDigest string `json:"stderrDigest,omitempty"`
+}
+
+// Signal is an approve or reject verdict recorded on a gate.
+type Signal struct {
+	Type        string    `json:"type"`
+	Source      string    `json:"source"`
+	Actor       string    `json:"actor,omitempty"`
+	ActorUserID int64     `json:"actorUserId,omitempty"`
+	Summary     string    `json:"summary,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// RunDetails describes the ai-flow invocation that produced the subject.
+type RunDetails struct {
+	Builder  Builder       `json:"builder"`
+	Metadata BuildMetadata `json:"metadata"`
+}
+
+// Builder identifies the builder.
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// BuildMetadata identifies the run that published the subject.
+type BuildMetadata struct {
+	InvocationID string     `json:"invocationId"`
+	StartedOn    *time.Time `json:"startedOn,omitempty"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+// CommitSHA returns the git commit digest of the first subject.
+func (s *Statement) CommitSHA() string {
+	if s == nil || len(s.Subject) == 0 {
+		return ""
+	}
+	return s.Subject[0].Digest["gitCommit"]
+}
+
+// Envelope is a DSSE envelope around a signed statement.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a DSSE signature.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
@@ -95,8 +95,21 @@ func (s *Service) CreateThread(ctx context.Context, input CreateThreadInput) (*C
 	thread := createResult.Thread
 	if thread != nil && thread.Metadata != nil {
 		if _, exists := thread.Metadata["skip_default_context_refs"]; exists {
-			delete(thread.Metadata, "skip_default_context_refs")
-			if err := s.store.UpdateThread(ctx, thread); err != nil {
+			err := core.RetryOnConflict(func() error {
+				latest, err := s.store.GetThread(ctx, thread.ID)
+				if err != nil {
+					return err
+				}
+				delete(latest.Metadata, "skip_default_context_refs")
+				if err := s.store.UpdateThread(ctx, latest); err != nil {
+					return err
+				}
+				delete(thread.Metadata, "skip_default_context_refs")
+				thread.Version = latest.Version
+				thread.UpdatedAt = latest.UpdatedAt
+				return nil
+			})
+			if err != nil {
 				return nil, err
 			}
 		}
@@ -0,0 +1,62 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Scheduler periodically runs the retention service.
+type Scheduler struct {
+	svc      *Service
+	interval time.Duration
+}
+
+// NewScheduler creates a scheduler; interval defaults to 24h.
+func NewScheduler(svc *Service, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return &Scheduler{svc: svc, interval: interval}
+}
+
+// Start runs retention immediately and then on every interval. Blocks until
+// ctx is cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	slog.Info("retention scheduler started", "interval", s.interval)
+	s.runOnce(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("retention scheduler stopped")
+			return
+		case <-ticker.C:
+			s.runOnce(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context) {
+	report, err := s.svc.Run(ctx, RunOptions{})
+	if err != nil {
+		slog.Error("retention scheduler: run failed", "error", err)
+		return
+	}
+	var pruned, rolledUp int64
+	for _, t := range report.Targets {
+		pruned += t.Pruned
+		rolledUp += t.RolledUp
+		if t.Error != "" {
+			slog.Warn("retention scheduler: target failed", "target", t.Target, "error", t.Error)
+		}
+	}
+	slog.Info("retention scheduler: run completed",
+		"pruned", pruned,
+		"rolled_up", rolledUp,
+		"audit_files_deleted", report.AuditFiles.Deleted,
+		"vacuumed", report.Vacuumed,
+	)
+}
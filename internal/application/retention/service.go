@@ -0,0 +1,290 @@
+package retention
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/audit"
+	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+// Policy is the retention policy for one target.
+type Policy struct {
+	Days int
+	Mode core.RetentionMode
+}
+
+// Config configures the retention service.
+type Config struct {
+	// DefaultDays applies to targets without their own Days. Zero keeps data forever.
+	DefaultDays    int
+	Policies       map[core.RetentionTarget]Policy
+	BatchSize      int
+	VacuumInterval time.Duration // zero disables VACUUM
+	AuditRoot      string        // tool-call audit JSONL root; empty skips file pruning
+	ArchiveDir     string        // destination for archive-mode targets
+}
+
+// Service enforces retention policies against the store and the audit root.
+type Service struct {
+	store core.RetentionStore
+	cfg   Config
+	now   func() time.Time
+
+	mu         sync.Mutex
+	lastVacuum time.Time
+}
+
+// New creates a retention service.
+func New(store core.RetentionStore, cfg Config) *Service {
+	return &Service{store: store, cfg: cfg, now: time.Now}
+}
+
+// RunOptions tunes a single retention run.
+type RunOptions struct {
+	DryRun bool
+	Vacuum bool // force VACUUM regardless of VacuumInterval
+}
+
+// Run prunes every target according to its policy. With DryRun set it only
+// reports how much data has expired.
+func (s *Service) Run(ctx context.Context, opts RunOptions) (*core.RetentionReport, error) {
+	if s == nil || s.store == nil {
+		return nil, errors.New("retention service is not configured")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now().UTC()
+	report := &core.RetentionReport{DryRun: opts.DryRun, StartedAt: now}
+	var logRefs []string
+	for _, target := range core.RetentionTargets() {
+		tr, refs := s.runTarget(ctx, target, now, opts.DryRun)
+		report.Targets = append(report.Targets, tr)
+		logRefs = append(logRefs, refs...)
+	}
+	report.AuditFiles = s.pruneAuditFiles(now, logRefs, opts.DryRun)
+
+	if !opts.DryRun {
+		if err := s.store.CheckpointWAL(ctx); err != nil {
+			slog.Warn("retention: wal checkpoint failed", "error", err)
+		} else {
+			report.Checkpointed = true
+		}
+		if opts.Vacuum || (s.cfg.VacuumInterval > 0 && now.Sub(s.lastVacuum) >= s.cfg.VacuumInterval) {
+			if err := s.store.Vacuum(ctx); err != nil {
+				slog.Warn("retention: vacuum failed", "error", err)
+			} else {
+				report.Vacuumed = true
+				s.lastVacuum = now
+			}
+		}
+	}
+	report.FinishedAt = s.now().UTC()
+	return report, nil
+}
+
+func (s *Service) policy(target core.RetentionTarget) Policy {
+	p := s.cfg.Policies[target]
+	if p.Days == 0 {
+		p.Days = s.cfg.DefaultDays
+	}
+	if p.Mode == "" {
+		p.Mode = core.RetentionModeDelete
+	}
+	if p.Days <= 0 {
+		p.Mode = core.RetentionModeKeep
+	}
+	return p
+}
+
+func (s *Service) runTarget(ctx context.Context, target core.RetentionTarget, now time.Time, dryRun bool) (core.RetentionTargetReport, []string) {
+	p := s.policy(target)
+	tr := core.RetentionTargetReport{Target: target, Mode: p.Mode, Days: p.Days}
+	if p.Mode == core.RetentionModeKeep {
+		return tr, nil
+	}
+	before := now.AddDate(0, 0, -p.Days)
+	tr.Before = &before
+
+	expired, err := s.store.CountExpired(ctx, target, before)
+	if err != nil {
+		tr.Error = err.Error()
+		return tr, nil
+	}
+	tr.Expired = expired
+	if dryRun || expired == 0 {
+		return tr, nil
+	}
+
+	in := core.RetentionPruneInput{Target: target, Before: before, BatchSize: s.cfg.BatchSize}
+	var archive *archiveWriter
+	if p.Mode == core.RetentionModeArchive {
+		archive, err = newArchiveWriter(s.cfg.ArchiveDir, target, now)
+		if err != nil {
+			tr.Error = err.Error()
+			return tr, nil
+		}
+		tr.ArchivePath = archive.path
+		in.Archive = archive.write
+	}
+	result, err := s.store.PruneExpired(ctx, in)
+	if archive != nil {
+		if cerr := archive.close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	tr.Pruned = result.Pruned
+	tr.RolledUp = result.RolledUp
+	if err != nil {
+		tr.Error = err.Error()
+	}
+	return tr, result.AuditLogRefs
+}
+
+// pruneAuditFiles removes audit JSONL files older than the event_log cutoff
+// together with files referenced by pruned run.audit events.
+func (s *Service) pruneAuditFiles(now time.Time, logRefs []string, dryRun bool) core.RetentionFileReport {
+	root := strings.TrimSpace(s.cfg.AuditRoot)
+	report := core.RetentionFileReport{Root: root}
+	p := s.policy(core.RetentionEventLog)
+	if root == "" || p.Mode == core.RetentionModeKeep {
+		return report
+	}
+	before := now.AddDate(0, 0, -p.Days)
+
+	candidates := map[string]int64{}
+	for _, ref := range logRefs {
+		path, err := audit.ResolveLogPath(root, ref)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			candidates[path] = info.Size()
+		}
+	}
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), ".jsonl") {
+			return nil
+		}
+		info, err := d.Info()
+		if err == nil && info.ModTime().Before(before) {
+			candidates[path] = info.Size()
+		}
+		return nil
+	})
+
+	report.Expired = len(candidates)
+	if dryRun {
+		for _, size := range candidates {
+			report.Bytes += size
+		}
+		return report
+	}
+	for path, size := range candidates {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("retention: remove audit file failed", "path", path, "error", err)
+			continue
+		}
+		report.Deleted++
+		report.Bytes += size
+	}
+	removeEmptyDirs(root)
+	return report
+}
+
+// removeEmptyDirs deletes empty directories below root, deepest first.
+func removeEmptyDirs(root string) {
+	var dirs []string
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != root {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) > len(dirs[j]) })
+	for _, dir := range dirs {
+		_ = os.Remove(dir) // fails harmlessly when not empty
+	}
+}
+
+// archiveWriter streams archived rows as gzip-compressed JSONL.
+type archiveWriter struct {
+	path string
+	file *os.File
+	gz   *gzip.Writer
+	enc  *json.Encoder
+}
+
+func newArchiveWriter(dir string, target core.RetentionTarget, now time.Time) (*archiveWriter, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, errors.New("retention archive dir is not configured")
+	}
+	targetDir := filepath.Join(dir, string(target))
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create archive dir: %w", err)
+	}
+	path := filepath.Join(targetDir, now.Format("20060102T150405Z")+".jsonl.gz")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	gz := gzip.NewWriter(f)
+	return &archiveWriter{path: path, file: f, gz: gz, enc: json.NewEncoder(gz)}, nil
+}
+
+func (a *archiveWriter) write(records []any) error {
+	for _, rec := range records {
+		if err := a.enc.Encode(rec); err != nil {
+			return fmt.Errorf("write archive: %w", err)
+		}
+	}
+	return a.gz.Flush()
+}
+
+func (a *archiveWriter) close() error {
+	gzErr := a.gz.Close()
+	fileErr := a.file.Close()
+	if gzErr != nil {
+		return gzErr
+	}
+	return fileErr
+}
+
+// ConfigFromAudit builds the service config from the [audit] section.
+func ConfigFromAudit(cfg config.AuditConfig, dataDir string) Config {
+	out := Config{
+		DefaultDays:    cfg.RetentionDays,
+		Policies:       make(map[core.RetentionTarget]Policy, len(cfg.Retention.Tables)),
+		BatchSize:      cfg.Retention.BatchSize,
+		VacuumInterval: cfg.Retention.VacuumInterval.Duration,
+		AuditRoot:      audit.ResolveRootDir(dataDir, cfg.FallbackDir),
+	}
+	for name, table := range cfg.Retention.Tables {
+		out.Policies[core.RetentionTarget(name)] = Policy{
+			Days: table.Days,
+			Mode: core.RetentionMode(strings.TrimSpace(table.Mode)),
+		}
+	}
+	archiveDir := strings.TrimSpace(cfg.Retention.ArchiveDir)
+	if archiveDir == "" {
+		archiveDir = "archive"
+	}
+	if !filepath.IsAbs(archiveDir) && dataDir != "" {
+		archiveDir = filepath.Join(dataDir, archiveDir)
+	}
+	out.ArchiveDir = archiveDir
+	return out
+}
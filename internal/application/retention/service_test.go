@@ -0,0 +1,163 @@
+package retention
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type fakeRetentionStore struct {
+	expired     map[core.RetentionTarget]int64
+	pruned      []core.RetentionTarget
+	logRefs     []string
+	checkpoints int
+	vacuums     int
+}
+
+func (f *fakeRetentionStore) CountExpired(_ context.Context, target core.RetentionTarget, _ time.Time) (int64, error) {
+	return f.expired[target], nil
+}
+
+func (f *fakeRetentionStore) PruneExpired(_ context.Context, in core.RetentionPruneInput) (core.RetentionPruneResult, error) {
+	f.pruned = append(f.pruned, in.Target)
+	n := f.expired[in.Target]
+	if in.Archive != nil {
+		if err := in.Archive([]any{map[string]any{"target": in.Target}}); err != nil {
+			return core.RetentionPruneResult{}, err
+		}
+	}
+	result := core.RetentionPruneResult{Pruned: n, RolledUp: 1}
+	if in.Target == core.RetentionEventLog {
+		result.AuditLogRefs = f.logRefs
+	}
+	return result, nil
+}
+
+func (f *fakeRetentionStore) CheckpointWAL(context.Context) error { f.checkpoints++; return nil }
+
+func (f *fakeRetentionStore) Vacuum(context.Context) error { f.vacuums++; return nil }
+
+func writeAuditFile(t *testing.T, root, ref string, modTime time.Time) string {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(ref))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestServiceRunPrunesTargetsAndAuditFiles(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	oldFile := writeAuditFile(t, root, "2025/01/01/run-1-audit.jsonl", now.AddDate(0, 0, -60))
+	referenced := writeAuditFile(t, root, "2025/02/01/run-2-audit.jsonl", now)
+	freshFile := writeAuditFile(t, root, "2025/03/01/run-3-audit.jsonl", now)
+
+	store := &fakeRetentionStore{
+		expired: map[core.RetentionTarget]int64{core.RetentionEventLog: 5, core.RetentionJournal: 2},
+		logRefs: []string{"2025/02/01/run-2-audit.jsonl", "../escape.jsonl"},
+	}
+	svc := New(store, Config{
+		DefaultDays:    30,
+		Policies:       map[core.RetentionTarget]Policy{core.RetentionUsage: {Mode: core.RetentionModeKeep}},
+		VacuumInterval: time.Hour,
+		AuditRoot:      root,
+	})
+
+	dry, err := svc.Run(context.Background(), RunOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(store.pruned) != 0 || store.checkpoints != 0 || dry.AuditFiles.Expired != 1 {
+		t.Fatalf("dry run touched data: pruned=%v checkpoints=%d files=%+v", store.pruned, store.checkpoints, dry.AuditFiles)
+	}
+	if _, err := os.Stat(oldFile); err != nil {
+		t.Fatalf("dry run removed audit file: %v", err)
+	}
+
+	report, err := svc.Run(context.Background(), RunOptions{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if strings.Join(targetNames(store.pruned), ",") != "event_log,activity_journal" {
+		t.Fatalf("pruned targets = %v", store.pruned)
+	}
+	if report.Targets[3].Mode != core.RetentionModeKeep || report.Targets[0].Pruned != 5 {
+		t.Fatalf("targets = %+v", report.Targets)
+	}
+	if report.AuditFiles.Deleted != 2 {
+		t.Fatalf("audit files = %+v, want 2 deleted", report.AuditFiles)
+	}
+	for _, path := range []string{oldFile, referenced} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed, err = %v", path, err)
+		}
+	}
+	if _, err := os.Stat(freshFile); err != nil {
+		t.Fatalf("fresh audit file removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "2025", "01")); !os.IsNotExist(err) {
+		t.Fatalf("empty audit dirs should be removed, err = %v", err)
+	}
+	if !report.Checkpointed || !report.Vacuumed || store.vacuums != 1 {
+		t.Fatalf("maintenance: checkpointed=%v vacuumed=%v vacuums=%d", report.Checkpointed, report.Vacuumed, store.vacuums)
+	}
+
+	// Within the vacuum interval only the WAL is checkpointed.
+	if report, _ = svc.Run(context.Background(), RunOptions{}); report.Vacuumed || store.checkpoints != 2 {
+		t.Fatalf("second run vacuumed=%v checkpoints=%d", report.Vacuumed, store.checkpoints)
+	}
+}
+
+func TestServiceArchiveModeWritesGzipJSONL(t *testing.T) {
+	archiveDir := t.TempDir()
+	store := &fakeRetentionStore{expired: map[core.RetentionTarget]int64{core.RetentionJournal: 3}}
+	svc := New(store, Config{
+		DefaultDays: 7,
+		Policies:    map[core.RetentionTarget]Policy{core.RetentionJournal: {Days: 90, Mode: core.RetentionModeArchive}},
+		ArchiveDir:  archiveDir,
+	})
+
+	report, err := svc.Run(context.Background(), RunOptions{})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	journal := report.Targets[1]
+	if journal.Days != 90 || journal.ArchivePath == "" || journal.Error != "" {
+		t.Fatalf("journal report = %+v", journal)
+	}
+	f, err := os.Open(journal.ArchivePath)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	body := make([]byte, 256)
+	n, _ := gz.Read(body)
+	if !strings.Contains(string(body[:n]), `"target":"activity_journal"`) {
+		t.Fatalf("archive body = %q", body[:n])
+	}
+}
+
+func targetNames(targets []core.RetentionTarget) []string {
+	out := make([]string, len(targets))
+	for i, t := range targets {
+		out[i] = string(t)
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package runtimeapp
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrExecutorNotFound is returned when an executor ID is not registered.
+	ErrExecutorNotFound = errors.New("executor not found")
+	// ErrNoEligibleExecutor is returned when no live executor satisfies run requirements.
+	ErrNoEligibleExecutor = errors.New("no eligible executor")
+)
+
+// Well-known requirement keys matched against executor registrations.
+// Any other key is matched against ExecutorInfo.Labels.
+const (
+	ExecutorRequirementDriver  = "driver"
+	ExecutorRequirementSandbox = "sandbox"
+)
+
+// ExecutorHealth is derived from the time since the last heartbeat.
+type ExecutorHealth string
+
+const (
+	ExecutorHealthy ExecutorHealth = "healthy"
+	ExecutorStale   ExecutorHealth = "stale"
+	ExecutorOffline ExecutorHealth = "offline"
+)
+
+// ExecutorControlAction changes whether an executor accepts new work.
+type ExecutorControlAction string
+
+const (
+	// ExecutorCordon stops routing new invocations to the executor.
+	ExecutorCordon ExecutorControlAction = "cordon"
+	// ExecutorUncordon resumes routing to a cordoned or draining executor.
+	ExecutorUncordon ExecutorControlAction = "uncordon"
+	// ExecutorDrain cordons the executor and lets it exit once active runs finish.
+	ExecutorDrain ExecutorControlAction = "drain"
+)
+
+// Valid reports whether a is a known control action.
+func (a ExecutorControlAction) Valid() bool {
+	switch a {
+	case ExecutorCordon, ExecutorUncordon, ExecutorDrain:
+		return true
+	default:
+		return false
+	}
+}
+
+// ExecutorInfo is the server's view of a registered remote executor.
+type ExecutorInfo struct {
+	ID              string            `json:"id"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Drivers         []string          `json:"drivers,omitempty"`
+	SandboxKinds    []string          `json:"sandbox_kinds,omitempty"`
+	Capacity        int               `json:"capacity"`
+	ActiveRuns      []int64           `json:"active_runs"`
+	Health          ExecutorHealth    `json:"health"`
+	Cordoned        bool              `json:"cordoned"`
+	Draining        bool              `json:"draining"`
+	StartedAt       time.Time         `json:"started_at"`
+	LastHeartbeatAt time.Time         `json:"last_heartbeat_at"`
+}
+
+// ExecutorFleet exposes the executors known to a distributed session manager.
+type ExecutorFleet interface {
+	ListExecutors(ctx context.Context) []ExecutorInfo
+	GetExecutor(ctx context.Context, id string) (*ExecutorInfo, error)
+	ControlExecutor(ctx context.Context, id string, action ExecutorControlAction) (*ExecutorInfo, error)
+}
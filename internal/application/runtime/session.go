@@ -85,6 +85,10 @@ type SessionAcquireInput struct {
 	// These are linked directly into the agent's skills dir, bypassing the
 	// global skillsRoot. Used for per-run materials (e.g. action-context).
 	EphemeralSkills map[string]string
+
+	// Requirements constrain which remote executor may run the invocation
+	// (e.g. {"sandbox": "docker", "os": "linux"}). Ignored in local mode.
+	Requirements map[string]string
 }
 
 // SessionHandle is an opaque reference to an acquired session.
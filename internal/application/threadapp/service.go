@@ -647,45 +647,51 @@ func cloneMetadata(in map[string]any) map[string]any {
 }
 
 func (s *Service) setThreadFocus(ctx context.Context, threadID int64, projectID int64) error {
-	thread, err := s.store.GetThread(ctx, threadID)
-	if err != nil {
-		return err
-	}
-	core.SetThreadFocusProjectID(thread, projectID)
-	return s.store.UpdateThread(ctx, thread)
+	return core.RetryOnConflict(func() error {
+		thread, err := s.store.GetThread(ctx, threadID)
+		if err != nil {
+			return err
+		}
+		core.SetThreadFocusProjectID(thread, projectID)
+		return s.store.UpdateThread(ctx, thread)
+	})
 }
 
 func (s *Service) ensureThreadFocus(ctx context.Context, threadID int64, projectID int64) error {
-	thread, err := s.store.GetThread(ctx, threadID)
-	if err != nil {
-		return err
-	}
-	if _, ok := core.ReadThreadFocusProjectID(thread); ok {
-		return nil
-	}
-	core.SetThreadFocusProjectID(thread, projectID)
-	return s.store.UpdateThread(ctx, thread)
+	return core.RetryOnConflict(func() error {
+		thread, err := s.store.GetThread(ctx, threadID)
+		if err != nil {
+			return err
+		}
+		if _, ok := core.ReadThreadFocusProjectID(thread); ok {
+			return nil
+		}
+		core.SetThreadFocusProjectID(thread, projectID)
+		return s.store.UpdateThread(ctx, thread)
+	})
 }
 
 func (s *Service) reconcileThreadFocusAfterRefDelete(ctx context.Context, threadID int64, deletedProjectID int64) error {
-	thread, err := s.store.GetThread(ctx, threadID)
-	if err != nil {
-		return err
-	}
-	focusProjectID, ok := core.ReadThreadFocusProjectID(thread)
-	if !ok || focusProjectID != deletedProjectID {
-		return nil
-	}
-	refs, err := s.store.ListThreadContextRefs(ctx, threadID)
-	if err != nil {
-		return err
-	}
-	if len(refs) == 0 {
-		core.ClearThreadFocus(thread)
+	return core.RetryOnConflict(func() error {
+		thread, err := s.store.GetThread(ctx, threadID)
+		if err != nil {
+			return err
+		}
+		focusProjectID, ok := core.ReadThreadFocusProjectID(thread)
+		if !ok || focusProjectID != deletedProjectID {
+			return nil
+		}
+		refs, err := s.store.ListThreadContextRefs(ctx, threadID)
+		if err != nil {
+			return err
+		}
+		if len(refs) == 0 {
+			core.ClearThreadFocus(thread)
+			return s.store.UpdateThread(ctx, thread)
+		}
+		core.SetThreadFocusProjectID(thread, refs[0].ProjectID)
 		return s.store.UpdateThread(ctx, thread)
-	}
-	core.SetThreadFocusProjectID(thread, refs[0].ProjectID)
-	return s.store.UpdateThread(ctx, thread)
+	})
 }
 
 func (s *Service) syncThreadWorkspace(ctx context.Context, threadID int64) error {
@@ -0,0 +1,112 @@
+package tokenapp
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+)
+
+func newTestService(t *testing.T) (*Service, *sqlite.Store, *time.Time) {
+	t.Helper()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "tokens.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	svc := New(Config{Store: store})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	return svc, store, &now
+}
+
+func TestService_CreateResolveRevoke(t *testing.T) {
+	svc, store, now := newTestService(t)
+	ctx := context.Background()
+
+	if _, _, err := svc.Create(ctx, CreateInput{Name: "ci"}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("create without scopes err = %v, want ErrInvalidInput", err)
+	}
+	token, secret, err := svc.Create(ctx, CreateInput{
+		Name:      "ci",
+		CreatedBy: "admin",
+		Scopes:    []string{" issues:write ", ""},
+		Projects:  []string{"backend"},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !strings.HasPrefix(secret, TokenPrefix) || !strings.HasPrefix(secret, token.Prefix) || token.TokenHash == secret {
+		t.Fatalf("token must be returned once and stored hashed: prefix %q", token.Prefix)
+	}
+	if token.Owner != "admin" || len(token.Scopes) != 1 || token.Scopes[0] != "issues:write" {
+		t.Fatalf("token = %+v", token)
+	}
+	if token.ExpiresAt == nil || !token.ExpiresAt.Equal(now.Add(DefaultTTL)) {
+		t.Fatalf("ExpiresAt = %v, want default TTL", token.ExpiresAt)
+	}
+
+	resolved, err := svc.Resolve(ctx, secret, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if resolved.ID != token.ID || resolved.Projects[0] != "backend" {
+		t.Fatalf("resolved = %+v", resolved)
+	}
+	stored, err := store.GetAPIToken(ctx, token.ID)
+	if err != nil || stored.LastUsedAt == nil || stored.LastUsedIP != "10.0.0.1" {
+		t.Fatalf("last use not recorded: %+v, %v", stored, err)
+	}
+	if _, err := svc.Resolve(ctx, secret+"x", ""); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("wrong secret err = %v, want ErrInvalidToken", err)
+	}
+
+	if _, err := svc.Revoke(ctx, token.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := svc.Revoke(ctx, token.ID); err != nil {
+		t.Fatalf("Revoke twice: %v", err)
+	}
+	if _, err := svc.Resolve(ctx, secret, "10.0.0.1"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("revoked token err = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestService_CustomExpiry(t *testing.T) {
+	svc, _, now := newTestService(t)
+	ctx := context.Background()
+
+	past := now.Add(-time.Minute)
+	if _, _, err := svc.Create(ctx, CreateInput{Name: "old", Scopes: []string{"*"}, ExpiresAt: &past}); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("past expiry err = %v, want ErrInvalidInput", err)
+	}
+
+	soon := now.Add(time.Hour)
+	_, secret, err := svc.Create(ctx, CreateInput{Name: "short", Scopes: []string{"*"}, ExpiresAt: &soon})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	forever, foreverSecret, err := svc.Create(ctx, CreateInput{Name: "bot", Scopes: []string{"*"}, NoExpiry: true})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if forever.ExpiresAt != nil {
+		t.Fatalf("NoExpiry token expires at %v", forever.ExpiresAt)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if _, err := svc.Resolve(ctx, secret, ""); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expired token err = %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.Resolve(ctx, foreverSecret, ""); err != nil {
+		t.Fatalf("Resolve non-expiring token: %v", err)
+	}
+	tokens, err := svc.List(ctx)
+	if err != nil || len(tokens) != 2 {
+		t.Fatalf("List = %d tokens, %v", len(tokens), err)
+	}
+}
@@ -0,0 +1,225 @@
+package userapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// ExternalLoginInput is a verified sign-in at an external identity provider.
+type ExternalLoginInput struct {
+	// Provider identifies the identity provider, e.g. the OIDC issuer URL.
+	Provider string
+	// Subject is the provider's stable user ID.
+	Subject string
+	// Username is the preferred username; it is sanitised and made unique
+	// when the user is first provisioned.
+	Username    string
+	DisplayName string
+	Email       string
+	Groups      []string
+	UserAgent   string
+	RemoteAddr  string
+}
+
+// GroupMapping turns identity provider groups into console permissions. It
+// is authoritative for what it names: AdminGroups, when set, decides the
+// admin flag on every login, and each project referenced by Roles gets the
+// highest role of the user's groups, or loses the membership when none
+// match. Other projects keep their manually managed memberships.
+type GroupMapping struct {
+	AdminGroups []string
+	Roles       []GroupRole
+}
+
+// GroupRole grants Role in Project to members of Group. Project is a
+// project ID or name.
+type GroupRole struct {
+	Group   string
+	Project string
+	Role    core.ProjectRole
+}
+
+// LoginExternal signs in a user authenticated by an identity provider,
+// provisioning the account on first login and applying mapping.
+func (s *Service) LoginExternal(ctx context.Context, in ExternalLoginInput, mapping GroupMapping) (*LoginResult, error) {
+	if strings.TrimSpace(in.Provider) == "" || strings.TrimSpace(in.Subject) == "" {
+		return nil, fmt.Errorf("%w: external identity needs a provider and subject", ErrInvalidUser)
+	}
+	now := s.now()
+
+	var user *core.User
+	identity, err := s.store.GetUserIdentity(ctx, in.Provider, in.Subject)
+	switch {
+	case err == nil:
+		if user, err = s.store.GetUser(ctx, identity.UserID); err != nil {
+			return nil, err
+		}
+		if user.Disabled {
+			return nil, ErrInvalidCredentials
+		}
+		if name := strings.TrimSpace(in.DisplayName); name != "" {
+			user.DisplayName = name
+		}
+		identity.Email = in.Email
+		identity.LastLoginAt = &now
+		if err := s.store.UpdateUserIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, core.ErrNotFound):
+		if user, err = s.provisionExternalUser(ctx, in); err != nil {
+			return nil, err
+		}
+		identity = &core.UserIdentity{
+			UserID:      user.ID,
+			Provider:    in.Provider,
+			Subject:     in.Subject,
+			Email:       in.Email,
+			LastLoginAt: &now,
+		}
+		if _, err := s.store.CreateUserIdentity(ctx, identity); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if len(mapping.AdminGroups) > 0 {
+		user.Admin = slices.ContainsFunc(in.Groups, func(g string) bool { return slices.Contains(mapping.AdminGroups, g) })
+	}
+	if err := s.syncGroupRoles(ctx, user.ID, in.Groups, mapping.Roles); err != nil {
+		return nil, err
+	}
+	return s.startSession(ctx, user, in.UserAgent, in.RemoteAddr)
+}
+
+// provisionExternalUser creates a password-less account. The username is
+// never matched against existing local accounts, which would let the
+// identity provider take them over; a taken name gets a numeric suffix.
+func (s *Service) provisionExternalUser(ctx context.Context, in ExternalLoginInput) (*core.User, error) {
+	base := sanitizeUsername(in.Username)
+	if base == "" {
+		local, _, _ := strings.Cut(in.Email, "@")
+		base = sanitizeUsername(local)
+	}
+	if base == "" {
+		base = "user"
+	}
+	for i := 1; i <= 100; i++ {
+		username := base
+		if i > 1 {
+			suffix := "-" + strconv.Itoa(i)
+			username = truncate(base, 64-len(suffix)) + suffix
+		}
+		_, err := s.store.GetUserByUsername(ctx, username)
+		if errors.Is(err, core.ErrNotFound) {
+			user := &core.User{Username: username, DisplayName: strings.TrimSpace(in.DisplayName)}
+			if _, err := s.store.CreateUser(ctx, user); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: no free username for %q", ErrUsernameTaken, base)
+}
+
+// sanitizeUsername maps a provider username onto usernamePattern.
+func sanitizeUsername(raw string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '.', r == '_', r == '-':
+			if b.Len() > 0 {
+				b.WriteRune(r)
+			}
+		default:
+			if b.Len() > 0 {
+				b.WriteRune('-')
+			}
+		}
+	}
+	return truncate(b.String(), 64)
+}
+
+func (s *Service) syncGroupRoles(ctx context.Context, userID int64, groups []string, roles []GroupRole) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	desired := make(map[int64]core.ProjectRole)
+	managed := make(map[int64]bool)
+	for _, gr := range roles {
+		project, err := s.resolveProject(ctx, gr.Project)
+		if errors.Is(err, core.ErrNotFound) {
+			slog.Warn("user sso: group mapping names an unknown project", "group", gr.Group, "project", gr.Project)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		managed[project.ID] = true
+		if !slices.Contains(groups, gr.Group) {
+			continue
+		}
+		if current, ok := desired[project.ID]; !ok || gr.Role.AtLeast(current) {
+			desired[project.ID] = gr.Role
+		}
+	}
+
+	memberships, err := s.store.ListUserMemberships(ctx, userID)
+	if err != nil {
+		return err
+	}
+	current := make(map[int64]core.ProjectRole, len(memberships))
+	for _, m := range memberships {
+		current[m.ProjectID] = m.Role
+	}
+	for projectID := range managed {
+		role, want := desired[projectID]
+		have, has := current[projectID]
+		switch {
+		case want && (!has || have != role):
+			if err := s.store.UpsertProjectMember(ctx, &core.ProjectMember{ProjectID: projectID, UserID: userID, Role: role}); err != nil {
+				return err
+			}
+		case !want && has:
+			if err := s.store.DeleteProjectMember(ctx, projectID, userID); err != nil && !errors.Is(err, core.ErrNotFound) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveProject finds a project by numeric ID or by name.
+func (s *Service) resolveProject(ctx context.Context, ref string) (*core.Project, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return s.store.GetProject(ctx, id)
+	}
+	const pageSize = 200
+	for offset := 0; ; offset += pageSize {
+		projects, err := s.store.ListProjects(ctx, pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			if p.Name == ref {
+				return p, nil
+			}
+		}
+		if len(projects) < pageSize {
+			return nil, core.ErrNotFound
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package userapp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestService_LoginExternalProvisionsOnce(t *testing.T) {
+	svc, store, _ := newTestService(t)
+	ctx := context.Background()
+
+	// A local account with the same name must not be taken over.
+	local, err := svc.CreateUser(ctx, CreateUserInput{Username: "alice", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	in := ExternalLoginInput{
+		Provider:    "https://idp.test",
+		Subject:     "sub-1",
+		Username:    "alice",
+		DisplayName: "Alice A.",
+		Email:       "alice@example.com",
+	}
+	first, err := svc.LoginExternal(ctx, in, GroupMapping{})
+	if err != nil {
+		t.Fatalf("LoginExternal: %v", err)
+	}
+	if first.User.ID == local.ID || first.User.Username != "alice-2" {
+		t.Fatalf("provisioned user = %+v, want new account alice-2", first.User)
+	}
+	if first.User.PasswordHash != "" {
+		t.Fatalf("SSO users must not get a password")
+	}
+	if _, err := svc.Login(ctx, LoginInput{Username: "alice-2", Password: ""}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("password login of SSO user err = %v, want ErrInvalidCredentials", err)
+	}
+
+	second, err := svc.LoginExternal(ctx, in, GroupMapping{})
+	if err != nil {
+		t.Fatalf("LoginExternal again: %v", err)
+	}
+	if second.User.ID != first.User.ID {
+		t.Fatalf("second login provisioned user %d, want %d", second.User.ID, first.User.ID)
+	}
+	identities, err := store.ListUserIdentities(ctx, first.User.ID)
+	if err != nil || len(identities) != 1 || identities[0].Subject != "sub-1" {
+		t.Fatalf("identities = %v, %v", identities, err)
+	}
+
+	disabled := true
+	if _, err := svc.UpdateUser(ctx, first.User.ID, UpdateUserInput{Disabled: &disabled}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if _, err := svc.LoginExternal(ctx, in, GroupMapping{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("disabled SSO login err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestService_LoginExternalSyncsGroupRoles(t *testing.T) {
+	svc, store, _ := newTestService(t)
+	ctx := context.Background()
+
+	backend, err := store.CreateProject(ctx, &core.Project{Name: "backend", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	manual, err := store.CreateProject(ctx, &core.Project{Name: "manual", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	mapping := GroupMapping{
+		AdminGroups: []string{"root"},
+		Roles: []GroupRole{
+			{Group: "eng", Project: "backend", Role: core.ProjectRoleOperator},
+			{Group: "leads", Project: "backend", Role: core.ProjectRoleApprover},
+			{Group: "eng", Project: "missing", Role: core.ProjectRoleViewer},
+		},
+	}
+	in := ExternalLoginInput{Provider: "https://idp.test", Subject: "sub-2", Username: "bob", Groups: []string{"eng", "leads"}}
+
+	result, err := svc.LoginExternal(ctx, in, mapping)
+	if err != nil {
+		t.Fatalf("LoginExternal: %v", err)
+	}
+	userID := result.User.ID
+	if result.User.Admin {
+		t.Fatalf("user without admin group became admin")
+	}
+	if _, err := svc.SetProjectMember(ctx, manual, userID, core.ProjectRoleViewer); err != nil {
+		t.Fatalf("SetProjectMember: %v", err)
+	}
+	roles := func() map[int64]core.ProjectRole {
+		t.Helper()
+		memberships, err := store.ListUserMemberships(ctx, userID)
+		if err != nil {
+			t.Fatalf("ListUserMemberships: %v", err)
+		}
+		out := make(map[int64]core.ProjectRole)
+		for _, m := range memberships {
+			out[m.ProjectID] = m.Role
+		}
+		return out
+	}
+	if got := roles(); got[backend] != core.ProjectRoleApprover {
+		t.Fatalf("roles = %v, want approver in backend (highest group role)", got)
+	}
+
+	// Leaving every mapped group removes the mapped membership only.
+	in.Groups = []string{"root"}
+	result, err = svc.LoginExternal(ctx, in, mapping)
+	if err != nil {
+		t.Fatalf("LoginExternal: %v", err)
+	}
+	if !result.User.Admin {
+		t.Fatalf("admin group did not grant admin")
+	}
+	got := roles()
+	if _, ok := got[backend]; ok {
+		t.Fatalf("roles = %v, want backend membership removed", got)
+	}
+	if got[manual] != core.ProjectRoleViewer {
+		t.Fatalf("roles = %v, want manual membership kept", got)
+	}
+}
@@ -0,0 +1,370 @@
+// Package userapp manages console user accounts: local password login,
+// cookie sessions and per-project role memberships.
+package userapp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown user, a wrong
+	// password or a disabled account; callers must not tell them apart.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrInvalidSession is returned for unknown or expired session tokens.
+	ErrInvalidSession = errors.New("invalid or expired session")
+	// ErrUsernameTaken is returned when creating a duplicate username.
+	ErrUsernameTaken = errors.New("username already exists")
+	// ErrInvalidUser is returned for user input that fails validation.
+	ErrInvalidUser = errors.New("invalid user")
+)
+
+const (
+	// DefaultSessionTTL is how long a console login stays valid.
+	DefaultSessionTTL = 7 * 24 * time.Hour
+	minPasswordLength = 8
+	sessionTokenBytes = 32
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Store is the persistence the service needs.
+type Store interface {
+	core.UserStore
+	GetProject(ctx context.Context, id int64) (*core.Project, error)
+	ListProjects(ctx context.Context, limit, offset int) ([]*core.Project, error)
+}
+
+// Config configures the service. A zero SessionTTL uses DefaultSessionTTL.
+type Config struct {
+	Store      Store
+	SessionTTL time.Duration
+}
+
+// Service implements account, session and membership use cases.
+type Service struct {
+	store      Store
+	sessionTTL time.Duration
+	now        func() time.Time
+}
+
+// New creates a user service.
+func New(cfg Config) *Service {
+	ttl := cfg.SessionTTL
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &Service{store: cfg.Store, sessionTTL: ttl, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// CreateUserInput describes a new account.
+type CreateUserInput struct {
+	Username    string
+	DisplayName string
+	Password    string
+	Admin       bool
+}
+
+// CreateUser validates the input and stores the account with a bcrypt hash.
+func (s *Service) CreateUser(ctx context.Context, in CreateUserInput) (*core.User, error) {
+	username := strings.TrimSpace(in.Username)
+	if !usernamePattern.MatchString(username) {
+		return nil, fmt.Errorf("%w: username must be 1-64 letters, digits, '.', '_' or '-'", ErrInvalidUser)
+	}
+	hash, err := hashPassword(in.Password)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetUserByUsername(ctx, username); err == nil {
+		return nil, ErrUsernameTaken
+	} else if !errors.Is(err, core.ErrNotFound) {
+		return nil, err
+	}
+	user := &core.User{
+		Username:     username,
+		DisplayName:  strings.TrimSpace(in.DisplayName),
+		PasswordHash: hash,
+		Admin:        in.Admin,
+	}
+	if _, err := s.store.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateUserInput changes the non-nil fields of an account.
+type UpdateUserInput struct {
+	DisplayName *string
+	Password    *string
+	Admin       *bool
+	Disabled    *bool
+}
+
+// UpdateUser applies in. Changing the password or disabling the account
+// signs the user out everywhere.
+func (s *Service) UpdateUser(ctx context.Context, id int64, in UpdateUserInput) (*core.User, error) {
+	user, err := s.store.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	endSessions := false
+	if in.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*in.DisplayName)
+	}
+	if in.Password != nil {
+		hash, err := hashPassword(*in.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = hash
+		endSessions = true
+	}
+	if in.Admin != nil {
+		user.Admin = *in.Admin
+	}
+	if in.Disabled != nil {
+		endSessions = endSessions || (*in.Disabled && !user.Disabled)
+		user.Disabled = *in.Disabled
+	}
+	if err := s.store.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	if endSessions {
+		if err := s.store.DeleteUserSessions(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// LoginInput carries credentials and client details recorded on the session.
+type LoginInput struct {
+	Username   string
+	Password   string
+	UserAgent  string
+	RemoteAddr string
+}
+
+// LoginResult is a new session. Token is the cookie value and is only
+// available here; the store keeps its hash.
+type LoginResult struct {
+	User    *core.User
+	Session *core.UserSession
+	Token   string
+}
+
+// dummyHash keeps the cost of a login for an unknown user the same as for a
+// wrong password.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("zhanggui-dummy-password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// Login checks the password and opens a session.
+func (s *Service) Login(ctx context.Context, in LoginInput) (*LoginResult, error) {
+	user, err := s.store.GetUserByUsername(ctx, in.Username)
+	if errors.Is(err, core.ErrNotFound) {
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(in.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Users provisioned by single sign-on have no password and cannot log in
+	// here; CompareHashAndPassword rejects the empty hash.
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(in.Password)) != nil || user.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+	return s.startSession(ctx, user, in.UserAgent, in.RemoteAddr)
+}
+
+func (s *Service) startSession(ctx context.Context, user *core.User, userAgent, remoteAddr string) (*LoginResult, error) {
+	token, err := newSessionToken()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	session := &core.UserSession{
+		UserID:     user.ID,
+		TokenHash:  hashSessionToken(token),
+		UserAgent:  truncate(userAgent, 256),
+		RemoteAddr: truncate(remoteAddr, 64),
+		ExpiresAt:  now.Add(s.sessionTTL),
+	}
+	if _, err := s.store.CreateUserSession(ctx, session); err != nil {
+		return nil, err
+	}
+	user.LastLoginAt = &now
+	if err := s.store.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return &LoginResult{User: user, Session: session, Token: token}, nil
+}
+
+// RevokeSession ends one session of userID.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	sessions, err := s.store.ListUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, sess := range sessions {
+		if sess.ID == sessionID {
+			return s.store.DeleteUserSession(ctx, sessionID)
+		}
+	}
+	return core.ErrNotFound
+}
+
+// Logout ends the session identified by token. Unknown tokens are ignored.
+func (s *Service) Logout(ctx context.Context, token string) error {
+	session, err := s.store.GetUserSessionByTokenHash(ctx, hashSessionToken(token))
+	if errors.Is(err, core.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteUserSession(ctx, session.ID)
+}
+
+// ResolveSession returns the enabled user behind a session token. Expired
+// sessions are deleted on sight.
+func (s *Service) ResolveSession(ctx context.Context, token string) (*core.User, *core.UserSession, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, nil, ErrInvalidSession
+	}
+	session, err := s.store.GetUserSessionByTokenHash(ctx, hashSessionToken(token))
+	if errors.Is(err, core.ErrNotFound) {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !s.now().Before(session.ExpiresAt) {
+		_ = s.store.DeleteUserSession(ctx, session.ID)
+		return nil, nil, ErrInvalidSession
+	}
+	user, err := s.store.GetUser(ctx, session.UserID)
+	if errors.Is(err, core.ErrNotFound) {
+		return nil, nil, ErrInvalidSession
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Disabled {
+		return nil, nil, ErrInvalidSession
+	}
+	return user, session, nil
+}
+
+// SetProjectMember grants userID role in projectID, replacing any previous
+// role.
+func (s *Service) SetProjectMember(ctx context.Context, projectID, userID int64, role core.ProjectRole) (*core.ProjectMember, error) {
+	if _, err := s.store.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	member := &core.ProjectMember{ProjectID: projectID, UserID: userID, Role: role}
+	if err := s.store.UpsertProjectMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// Authorize reports whether userID may use scope. With a project the user's
+// role in that project decides; without one (unscoped resources, lists) any
+// membership granting the scope is enough. Admin users are always allowed.
+func (s *Service) Authorize(ctx context.Context, userID int64, projectID *int64, scope string) (bool, error) {
+	user, err := s.store.GetUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if user.Admin {
+		return true, nil
+	}
+	memberships, err := s.store.ListUserMemberships(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range memberships {
+		if projectID != nil && m.ProjectID != *projectID {
+			continue
+		}
+		if m.Role.Allows(scope) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ProjectsWithScope lists the projects in which userID holds scope. all is
+// true for admin users, who are not limited to memberships.
+func (s *Service) ProjectsWithScope(ctx context.Context, userID int64, scope string) (ids map[int64]bool, all bool, err error) {
+	user, err := s.store.GetUser(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if user.Admin {
+		return nil, true, nil
+	}
+	memberships, err := s.store.ListUserMemberships(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	ids = make(map[int64]bool, len(memberships))
+	for _, m := range memberships {
+		if m.Role.Allows(scope) {
+			ids[m.ProjectID] = true
+		}
+	}
+	return ids, false, nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("%w: password must be at least %d characters", ErrInvalidUser, minPasswordLength)
+	}
+	// bcrypt ignores input beyond 72 bytes and GenerateFromPassword rejects it.
+	if len(password) > 72 {
+		return "", fmt.Errorf("%w: password must be at most 72 bytes", ErrInvalidUser)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func newSessionToken() (string, error) {
+	b := make([]byte, sessionTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate session token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
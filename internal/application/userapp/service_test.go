@@ -0,0 +1,169 @@
+package userapp
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func newTestService(t *testing.T) (*Service, *sqlite.Store, *time.Time) {
+	t.Helper()
+	store, err := sqlite.New(filepath.Join(t.TempDir(), "users.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	svc := New(Config{Store: store, SessionTTL: time.Hour})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	return svc, store, &now
+}
+
+func TestService_LoginResolveLogout(t *testing.T) {
+	svc, _, now := newTestService(t)
+	ctx := context.Background()
+
+	user, err := svc.CreateUser(ctx, CreateUserInput{Username: "alice", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := svc.CreateUser(ctx, CreateUserInput{Username: "ALICE", Password: "correct horse"}); !errors.Is(err, ErrUsernameTaken) {
+		t.Fatalf("duplicate username err = %v, want ErrUsernameTaken", err)
+	}
+	if _, err := svc.CreateUser(ctx, CreateUserInput{Username: "bob", Password: "short"}); !errors.Is(err, ErrInvalidUser) {
+		t.Fatalf("short password err = %v, want ErrInvalidUser", err)
+	}
+
+	if _, err := svc.Login(ctx, LoginInput{Username: "alice", Password: "wrong password"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password err = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := svc.Login(ctx, LoginInput{Username: "nobody", Password: "correct horse"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("unknown user err = %v, want ErrInvalidCredentials", err)
+	}
+
+	result, err := svc.Login(ctx, LoginInput{Username: "alice", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if result.Token == "" || result.Session.TokenHash == result.Token {
+		t.Fatalf("session token must be returned once and stored hashed")
+	}
+	if result.User.LastLoginAt == nil {
+		t.Fatalf("LastLoginAt not set")
+	}
+
+	resolved, _, err := svc.ResolveSession(ctx, result.Token)
+	if err != nil || resolved.ID != user.ID {
+		t.Fatalf("ResolveSession = %v, %v; want user %d", resolved, err, user.ID)
+	}
+
+	if err := svc.Logout(ctx, result.Token); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, _, err := svc.ResolveSession(ctx, result.Token); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("after logout err = %v, want ErrInvalidSession", err)
+	}
+
+	result, err = svc.Login(ctx, LoginInput{Username: "alice", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	*now = now.Add(2 * time.Hour)
+	if _, _, err := svc.ResolveSession(ctx, result.Token); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("expired session err = %v, want ErrInvalidSession", err)
+	}
+}
+
+func TestService_DisablingUserEndsSessions(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	ctx := context.Background()
+
+	user, err := svc.CreateUser(ctx, CreateUserInput{Username: "carol", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	result, err := svc.Login(ctx, LoginInput{Username: "carol", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	disabled := true
+	if _, err := svc.UpdateUser(ctx, user.ID, UpdateUserInput{Disabled: &disabled}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if _, _, err := svc.ResolveSession(ctx, result.Token); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("disabled session err = %v, want ErrInvalidSession", err)
+	}
+	if _, err := svc.Login(ctx, LoginInput{Username: "carol", Password: "correct horse"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("disabled login err = %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestService_AuthorizeByProjectRole(t *testing.T) {
+	svc, store, _ := newTestService(t)
+	ctx := context.Background()
+
+	projectA, err := store.CreateProject(ctx, &core.Project{Name: "a", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	projectB, err := store.CreateProject(ctx, &core.Project{Name: "b", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	user, err := svc.CreateUser(ctx, CreateUserInput{Username: "dave", Password: "correct horse"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	admin, err := svc.CreateUser(ctx, CreateUserInput{Username: "root", Password: "correct horse", Admin: true})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := svc.SetProjectMember(ctx, projectA, user.ID, core.ProjectRoleOperator); err != nil {
+		t.Fatalf("SetProjectMember: %v", err)
+	}
+	if _, err := svc.SetProjectMember(ctx, projectB, user.ID, core.ProjectRoleViewer); err != nil {
+		t.Fatalf("SetProjectMember: %v", err)
+	}
+	if _, err := svc.SetProjectMember(ctx, 9999, user.ID, core.ProjectRoleViewer); !errors.Is(err, core.ErrNotFound) {
+		t.Fatalf("unknown project err = %v, want ErrNotFound", err)
+	}
+
+	cases := []struct {
+		name    string
+		userID  int64
+		project *int64
+		scope   string
+		want    bool
+	}{
+		{"operator writes issues", user.ID, &projectA, "issues:write", true},
+		{"operator cannot approve", user.ID, &projectA, core.ScopeGatesApprove, false},
+		{"viewer reads issues", user.ID, &projectB, "issues:read", true},
+		{"viewer cannot write issues", user.ID, &projectB, "issues:write", false},
+		{"unscoped uses any membership", user.ID, nil, "issues:write", true},
+		{"unscoped needs some membership", user.ID, nil, core.ScopeMembersWrite, false},
+		{"admin user bypasses roles", admin.ID, &projectB, core.ScopeMembersWrite, true},
+	}
+	for _, tc := range cases {
+		got, err := svc.Authorize(ctx, tc.userID, tc.project, tc.scope)
+		if err != nil {
+			t.Fatalf("%s: Authorize: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: Authorize = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	ids, all, err := svc.ProjectsWithScope(ctx, user.ID, "issues:write")
+	if err != nil || all {
+		t.Fatalf("ProjectsWithScope = %v, %v, %v", ids, all, err)
+	}
+	if !ids[projectA] || ids[projectB] {
+		t.Fatalf("ProjectsWithScope(issues:write) = %v, want only project %d", ids, projectA)
+	}
+}
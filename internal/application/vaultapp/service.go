@@ -0,0 +1,343 @@
+// Package vaultapp keeps per-project secrets encrypted at rest and resolves
+// the ones an agent may use when it is launched.
+//
+// Secrets are sealed with AES-256-GCM under a master key read from a key
+// file, which is created on first use. The secret's scope and name are bound
+// into each ciphertext, so a row copied to another scope no longer opens.
+package vaultapp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/audit"
+	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/keyfile"
+)
+
+var (
+	// ErrInvalidInput is returned for a secret that fails validation.
+	ErrInvalidInput = errors.New("invalid secret")
+	// ErrKeyMismatch is returned for a secret sealed with another master key.
+	ErrKeyMismatch = errors.New("secret was sealed with a different master key")
+)
+
+const (
+	// DefaultKeyFile is the master key file name inside the data directory.
+	DefaultKeyFile = "vault.key"
+	// RedactionRefreshInterval is how often long-running processes re-read
+	// the vault for WatchValues.
+	RedactionRefreshInterval = 30 * time.Second
+
+	keyBytes          = 32
+	maxValueLength    = 64 << 10
+	maxDescriptionLen = 500
+)
+
+// Store is the persistence the vault needs.
+type Store interface {
+	core.VaultStore
+	GetProject(ctx context.Context, id int64) (*core.Project, error)
+	GetWorkItem(ctx context.Context, id int64) (*core.WorkItem, error)
+}
+
+// Config configures the service.
+type Config struct {
+	Store Store
+	// KeyFile is the path of the master key, created with mode 0600 when
+	// missing.
+	KeyFile string
+}
+
+// Service implements the vault use cases.
+type Service struct {
+	store   Store
+	keyFile string
+
+	mu    sync.Mutex
+	key   []byte
+	keyID string
+}
+
+// New creates a vault service. The key file is not read until a secret is
+// stored or opened.
+func New(cfg Config) *Service {
+	return &Service{store: cfg.Store, keyFile: strings.TrimSpace(cfg.KeyFile)}
+}
+
+// ResolveKeyFile returns the master key path for a configured value, which
+// is relative to dataDir unless absolute.
+func ResolveKeyFile(dataDir, keyFile string) string {
+	return keyfile.Resolve(dataDir, keyFile, DefaultKeyFile)
+}
+
+// SetInput describes a secret to store. ProjectID 0 and an empty ProfileID
+// make the secret visible to every project and profile.
+type SetInput struct {
+	ProjectID   int64
+	ProfileID   string
+	Name        string
+	Value       string
+	Description string
+	CreatedBy   string
+}
+
+// Set encrypts and stores a secret, replacing an existing one with the same
+// scope and name.
+func (s *Service) Set(ctx context.Context, in SetInput) (*core.VaultSecret, error) {
+	name := strings.TrimSpace(in.Name)
+	if !core.ValidVaultSecretName(name) {
+		return nil, fmt.Errorf("%w: name must be a valid environment variable name", ErrInvalidInput)
+	}
+	if in.Value == "" || len(in.Value) > maxValueLength {
+		return nil, fmt.Errorf("%w: value must be 1-%d bytes", ErrInvalidInput, maxValueLength)
+	}
+	description := strings.TrimSpace(in.Description)
+	if len(description) > maxDescriptionLen {
+		return nil, fmt.Errorf("%w: description must be at most %d characters", ErrInvalidInput, maxDescriptionLen)
+	}
+	if in.ProjectID < 0 {
+		return nil, fmt.Errorf("%w: invalid project ID", ErrInvalidInput)
+	}
+	if in.ProjectID != 0 {
+		if _, err := s.store.GetProject(ctx, in.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	secret := &core.VaultSecret{
+		ProjectID:   in.ProjectID,
+		ProfileID:   strings.TrimSpace(in.ProfileID),
+		Name:        name,
+		Description: description,
+		CreatedBy:   strings.TrimSpace(in.CreatedBy),
+	}
+	key, keyID, err := s.masterKey()
+	if err != nil {
+		return nil, err
+	}
+	secret.Ciphertext, err = seal(key, secretAAD(secret), []byte(in.Value))
+	if err != nil {
+		return nil, err
+	}
+	secret.KeyID = keyID
+	if _, err := s.store.UpsertVaultSecret(ctx, secret); err != nil {
+		return nil, err
+	}
+	audit.RegisterSecretValues(in.Value)
+	return secret, nil
+}
+
+// Get returns one secret without its value.
+func (s *Service) Get(ctx context.Context, id int64) (*core.VaultSecret, error) {
+	return s.store.GetVaultSecret(ctx, id)
+}
+
+// List returns secrets without their values.
+func (s *Service) List(ctx context.Context, filter core.VaultSecretFilter) ([]*core.VaultSecret, error) {
+	return s.store.ListVaultSecrets(ctx, filter)
+}
+
+// Delete removes a secret.
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	return s.store.DeleteVaultSecret(ctx, id)
+}
+
+// Resolve decrypts the secrets visible to profileID in projectID. When a
+// name is defined at several scopes the most specific one wins. Every value
+// returned is registered with the audit redactor.
+func (s *Service) Resolve(ctx context.Context, projectID int64, profileID string) (map[string]string, error) {
+	secrets, err := s.store.ListVaultSecretsInScope(ctx, projectID, strings.TrimSpace(profileID))
+	if err != nil {
+		return nil, err
+	}
+	if len(secrets) == 0 {
+		return map[string]string{}, nil
+	}
+	chosen := make(map[string]*core.VaultSecret, len(secrets))
+	for _, secret := range secrets {
+		if cur, ok := chosen[secret.Name]; !ok || secret.Specificity() > cur.Specificity() {
+			chosen[secret.Name] = secret
+		}
+	}
+	key, keyID, err := s.masterKey()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(chosen))
+	values := make([]string, 0, len(chosen))
+	for name, secret := range chosen {
+		if secret.KeyID != "" && secret.KeyID != keyID {
+			return nil, fmt.Errorf("open secret %q: %w", name, ErrKeyMismatch)
+		}
+		plain, err := open(key, secretAAD(secret), secret.Ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("open secret %q: %w", name, err)
+		}
+		out[name] = string(plain)
+		values = append(values, string(plain))
+	}
+	audit.RegisterSecretValues(values...)
+	return out, nil
+}
+
+// RegisterValues decrypts every stored secret and registers it with the audit
+// redactor, so values set before this process started, or by another
+// process, are scrubbed before anything resolves them. Secrets that fail to
+// open are reported after the rest are registered.
+func (s *Service) RegisterValues(ctx context.Context) error {
+	secrets, err := s.store.ListVaultSecrets(ctx, core.VaultSecretFilter{})
+	if err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+	key, keyID, err := s.masterKey()
+	if err != nil {
+		return err
+	}
+	values := make([]string, 0, len(secrets))
+	var errs []error
+	for _, secret := range secrets {
+		if secret.KeyID != "" && secret.KeyID != keyID {
+			errs = append(errs, fmt.Errorf("open secret %q: %w", secret.Name, ErrKeyMismatch))
+			continue
+		}
+		plain, err := open(key, secretAAD(secret), secret.Ciphertext)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("open secret %q: %w", secret.Name, err))
+			continue
+		}
+		values = append(values, string(plain))
+	}
+	audit.RegisterSecretValues(values...)
+	return errors.Join(errs...)
+}
+
+// WatchValues calls RegisterValues every interval until ctx is done, picking
+// up secrets set by other processes, such as the CLI or the server when this
+// is an executor.
+func (s *Service) WatchValues(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.RegisterValues(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("vault: failed to register secrets for redaction", "error", err)
+		}
+	}
+}
+
+// ResolveSecrets implements core.SecretResolver using the project of the
+// work item; work item 0 or one without a project sees global secrets only.
+func (s *Service) ResolveSecrets(ctx context.Context, workItemID int64, profileID string) (map[string]string, error) {
+	var projectID int64
+	if workItemID > 0 {
+		workItem, err := s.store.GetWorkItem(ctx, workItemID)
+		if err != nil {
+			return nil, fmt.Errorf("load work item %d: %w", workItemID, err)
+		}
+		if workItem.ProjectID != nil {
+			projectID = *workItem.ProjectID
+		}
+	}
+	return s.Resolve(ctx, projectID, profileID)
+}
+
+// masterKey loads the key file, creating it on first use.
+func (s *Service) masterKey() ([]byte, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil {
+		return s.key, s.keyID, nil
+	}
+	if s.keyFile == "" {
+		return nil, "", fmt.Errorf("vault key file is not configured")
+	}
+	key, err := LoadOrCreateKey(s.keyFile)
+	if err != nil {
+		return nil, "", err
+	}
+	s.key, s.keyID = key, KeyID(key)
+	return s.key, s.keyID, nil
+}
+
+// LoadOrCreateKey reads a hex-encoded 256-bit key from path, generating one
+// when the file does not exist.
+func LoadOrCreateKey(path string) ([]byte, error) {
+	raw, err := keyfile.LoadOrCreate(path, func() ([]byte, error) {
+		key := make([]byte, keyBytes)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		return []byte(hex.EncodeToString(key) + "\n"), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("vault key: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil || len(key) != keyBytes {
+		return nil, fmt.Errorf("vault key file %s must hold %d hex-encoded bytes", path, keyBytes)
+	}
+	return key, nil
+}
+
+// KeyID fingerprints a master key without revealing it.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("vault-key-id\n"), key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+func secretAAD(s *core.VaultSecret) []byte {
+	return []byte(strconv.FormatInt(s.ProjectID, 10) + "\n" + s.ProfileID + "\n" + s.Name)
+}
+
+func seal(key, aad, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, aad), nil
+}
+
+func open(key, aad, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is truncated")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], aad)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,170 @@
+package vaultapp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/audit"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func newTestService(t *testing.T) (*Service, *sqlite.Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := sqlite.New(filepath.Join(dir, "vault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	keyFile := filepath.Join(dir, DefaultKeyFile)
+	return New(Config{Store: store, KeyFile: keyFile}), store, keyFile
+}
+
+func TestService_SetResolveScopes(t *testing.T) {
+	svc, store, keyFile := newTestService(t)
+	ctx := context.Background()
+	projectID, err := store.CreateProject(ctx, &core.Project{Name: "backend", Kind: core.ProjectDev})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	workItemID, err := store.CreateWorkItem(ctx, &core.WorkItem{Title: "release", ProjectID: &projectID, Status: core.WorkItemOpen})
+	if err != nil {
+		t.Fatalf("CreateWorkItem: %v", err)
+	}
+
+	for _, in := range []SetInput{
+		{Name: "NPM_TOKEN", Value: "global-npm"},
+		{Name: "NPM_TOKEN", Value: "project-npm", ProjectID: projectID},
+		{Name: "NPM_TOKEN", Value: "worker-npm", ProjectID: projectID, ProfileID: "worker"},
+		{Name: "DATABASE_URL", Value: "postgres://staging", ProfileID: "worker"},
+	} {
+		if _, err := svc.Set(ctx, in); err != nil {
+			t.Fatalf("Set(%s): %v", in.Name, err)
+		}
+	}
+	// Replacing keeps the row.
+	replaced, err := svc.Set(ctx, SetInput{Name: "NPM_TOKEN", Value: "global-npm-2", Description: "rotated"})
+	if err != nil {
+		t.Fatalf("Set(replace): %v", err)
+	}
+	if all, _ := svc.List(ctx, core.VaultSecretFilter{}); len(all) != 4 {
+		t.Fatalf("List() = %d secrets, want 4", len(all))
+	}
+
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("key file: %v, %v", info, err)
+	}
+	stored, err := store.GetVaultSecret(ctx, replaced.ID)
+	if err != nil || bytes.Contains(stored.Ciphertext, []byte("global-npm-2")) || stored.Description != "rotated" {
+		t.Fatalf("stored secret = %+v, %v", stored, err)
+	}
+
+	cases := []struct {
+		workItemID int64
+		profile    string
+		npm        string
+		db         string
+	}{
+		{workItemID, "worker", "worker-npm", "postgres://staging"},
+		{workItemID, "reviewer", "project-npm", ""},
+		{0, "worker", "global-npm-2", "postgres://staging"},
+	}
+	for _, tc := range cases {
+		got, err := svc.ResolveSecrets(ctx, tc.workItemID, tc.profile)
+		if err != nil {
+			t.Fatalf("ResolveSecrets(%d, %s): %v", tc.workItemID, tc.profile, err)
+		}
+		if got["NPM_TOKEN"] != tc.npm || got["DATABASE_URL"] != tc.db {
+			t.Fatalf("ResolveSecrets(%d, %s) = %v", tc.workItemID, tc.profile, got)
+		}
+	}
+	if got := audit.NewRedactor("").Redact("token is worker-npm"); got != "token is [REDACTED]" {
+		t.Fatalf("redactor did not scrub resolved secret: %q", got)
+	}
+}
+
+func TestService_Validation(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	ctx := context.Background()
+	for _, in := range []SetInput{
+		{Name: "bad-name", Value: "x"},
+		{Name: "EMPTY"},
+		{Name: "NEG", Value: "x", ProjectID: -1},
+	} {
+		if _, err := svc.Set(ctx, in); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("Set(%+v) err = %v, want ErrInvalidInput", in, err)
+		}
+	}
+	if _, err := svc.Set(ctx, SetInput{Name: "X", Value: "x", ProjectID: 99}); !errors.Is(err, core.ErrNotFound) {
+		t.Fatalf("Set(unknown project) err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestService_KeyMismatchAndTamper(t *testing.T) {
+	svc, store, keyFile := newTestService(t)
+	ctx := context.Background()
+	secret, err := svc.Set(ctx, SetInput{Name: "API_KEY", Value: "staging-key"})
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// A row moved to another scope no longer decrypts.
+	moved := *secret
+	moved.ProfileID = "other"
+	if _, err := store.UpsertVaultSecret(ctx, &moved); err != nil {
+		t.Fatalf("UpsertVaultSecret: %v", err)
+	}
+	if _, err := svc.Resolve(ctx, 0, "other"); err == nil {
+		t.Fatalf("Resolve of a moved ciphertext should fail")
+	}
+	if err := store.DeleteVaultSecret(ctx, moved.ID); err != nil {
+		t.Fatalf("DeleteVaultSecret: %v", err)
+	}
+
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatalf("remove key: %v", err)
+	}
+	other := New(Config{Store: store, KeyFile: keyFile})
+	if _, err := other.Resolve(ctx, 0, ""); !errors.Is(err, ErrKeyMismatch) {
+		t.Fatalf("Resolve with a new key err = %v, want ErrKeyMismatch", err)
+	}
+}
+
+func TestService_RegistersValuesOnSetAndAtStartup(t *testing.T) {
+	svc, store, keyFile := newTestService(t)
+	ctx := context.Background()
+	redactor := audit.NewRedactor("")
+	if _, err := svc.Set(ctx, SetInput{Name: "SET_TOKEN", Value: "set-time-value"}); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := redactor.Redact("token is set-time-value"); got != "token is [REDACTED]" {
+		t.Fatalf("Redact after Set = %q, want the value scrubbed before any Resolve", got)
+	}
+
+	// A secret stored by another process is unknown here until registered.
+	key, err := LoadOrCreateKey(keyFile)
+	if err != nil {
+		t.Fatalf("LoadOrCreateKey: %v", err)
+	}
+	stored := &core.VaultSecret{Name: "STORED_TOKEN", KeyID: KeyID(key)}
+	if stored.Ciphertext, err = seal(key, secretAAD(stored), []byte("stored-elsewhere")); err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if _, err := store.UpsertVaultSecret(ctx, stored); err != nil {
+		t.Fatalf("UpsertVaultSecret: %v", err)
+	}
+	if got := redactor.Redact("stored-elsewhere"); got != "stored-elsewhere" {
+		t.Fatalf("Redact before RegisterValues = %q", got)
+	}
+	if err := New(Config{Store: store, KeyFile: keyFile}).RegisterValues(ctx); err != nil {
+		t.Fatalf("RegisterValues: %v", err)
+	}
+	if got := redactor.Redact("token is stored-elsewhere"); got != "token is [REDACTED]" {
+		t.Fatalf("Redact after RegisterValues = %q", got)
+	}
+}
@@ -28,6 +28,7 @@ type WorkItemWriter interface {
 }
 
 type ActionReader interface {
+	GetAction(ctx context.Context, id int64) (*core.Action, error)
 	ListActionsByWorkItem(ctx context.Context, workItemID int64) ([]*core.Action, error)
 }
 
@@ -130,6 +131,8 @@ type UpdateWorkItemInput struct {
 	DependsOn          *[]int64
 	EscalationPath     *[]string
 	Metadata           map[string]any
+	// ExpectedVersion, when non-zero, must match the stored version.
+	ExpectedVersion int64
 }
 
 type RunWorkItemResult struct {
@@ -9,21 +9,24 @@ import (
 )
 
 func (s *Service) AdoptDeliverable(ctx context.Context, workItemID, deliverableID int64) (*core.WorkItem, error) {
-	store := s.store
 	var (
 		workItem        *core.WorkItem
 		statusCompleted bool
-		err             error
 	)
-	if s.tx != nil {
-		err = s.tx.InTx(ctx, func(ctx context.Context, txStore TxStore) error {
-			store = txStore
-			workItem, statusCompleted, err = adoptDeliverableInStore(ctx, txStore, workItemID, deliverableID)
-			return err
-		})
-	} else {
-		workItem, statusCompleted, err = adoptDeliverableInStore(ctx, store, workItemID, deliverableID)
-	}
+	// Each attempt re-reads the work item and its actions, so a concurrent
+	// edit that bumped a version is folded in instead of failing the adoption.
+	err := core.RetryOnConflict(func() error {
+		if s.tx != nil {
+			return s.tx.InTx(ctx, func(ctx context.Context, txStore TxStore) error {
+				var err error
+				workItem, statusCompleted, err = adoptDeliverableInStore(ctx, txStore, workItemID, deliverableID)
+				return err
+			})
+		}
+		var err error
+		workItem, statusCompleted, err = adoptDeliverableInStore(ctx, s.store, workItemID, deliverableID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
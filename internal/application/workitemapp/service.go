@@ -119,6 +119,9 @@ func (s *Service) UpdateWorkItem(ctx context.Context, input UpdateWorkItemInput)
 		}
 		return nil, err
 	}
+	if input.ExpectedVersion > 0 && input.ExpectedVersion != workItem.Version {
+		return nil, &core.VersionConflictError{Resource: "work item", ID: workItem.ID, Expected: input.ExpectedVersion, Actual: workItem.Version}
+	}
 
 	if input.ProjectID != nil {
 		if err := s.validateProject(ctx, input.ProjectID); err != nil {
@@ -328,8 +331,22 @@ func (s *Service) resetRetryableActions(ctx context.Context, actions []*core.Act
 		}
 		switch action.Status {
 		case core.ActionFailed, core.ActionBlocked:
-			action.Status = core.ActionPending
-			if err := s.store.UpdateAction(ctx, action); err != nil {
+			err := core.RetryOnConflict(func() error {
+				latest, err := s.store.GetAction(ctx, action.ID)
+				if err != nil {
+					return err
+				}
+				if latest.Status != core.ActionFailed && latest.Status != core.ActionBlocked {
+					return nil
+				}
+				latest.Status = core.ActionPending
+				if err := s.store.UpdateAction(ctx, latest); err != nil {
+					return err
+				}
+				*action = *latest
+				return nil
+			})
+			if err != nil {
 				if errors.Is(err, core.ErrNotFound) {
 					return newError(CodeWorkItemNotFound, "action not found while resetting for rerun", err)
 				}
@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// Run audit JSONL files are hash-chained line by line with the primitives
+// of the journal chain. A record hashes its own JSON without the "hash"
+// field, so the chain can be checked from the file alone.
+
+func chainRunAuditRecord(record *RunAuditRecord, head core.ChainLink) (core.ChainLink, error) {
+	record.Seq, record.PrevHash, record.Hash = head.Seq+1, head.Hash, ""
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return head, fmt.Errorf("encode run audit record: %w", err)
+	}
+	canonical, err := core.CanonicalJSON(raw)
+	if err != nil {
+		return head, err
+	}
+	record.Hash = core.ChainHash(record.PrevHash, canonical)
+	return core.ChainLink{Seq: record.Seq, PrevHash: record.PrevHash, Hash: record.Hash}, nil
+}
+
+// readChainHead returns the link of the last record in path, or the zero
+// link when the file does not exist yet.
+func readChainHead(path string) (core.ChainLink, error) {
+	var head core.ChainLink
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return head, nil
+	}
+	if err != nil {
+		return head, fmt.Errorf("open audit payload file: %w", err)
+	}
+	defer f.Close()
+	var last []byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			last = append(last[:0], line...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return head, fmt.Errorf("scan audit payload file: %w", err)
+	}
+	if len(last) > 0 {
+		// A record without chain fields (written before chaining) restarts
+		// the chain; verification reports it.
+		_ = json.Unmarshal(last, &head)
+	}
+	return head, nil
+}
+
+// runAuditLineLink decodes the chain fields of one JSONL line and returns
+// the canonical form they were hashed over.
+func runAuditLineLink(line []byte) (core.ChainLink, []byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return core.ChainLink{}, nil, err
+	}
+	var link core.ChainLink
+	if err := json.Unmarshal(line, &link); err != nil {
+		return core.ChainLink{}, nil, err
+	}
+	delete(fields, "hash")
+	canonical, err := json.Marshal(fields)
+	return link, canonical, err
+}
+
+// VerifyRunAuditFile checks the hash chain of one run audit JSONL file.
+func VerifyRunAuditFile(path string) (*core.ChainReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	report := &core.ChainReport{Source: path}
+	var verifier core.ChainVerifier
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		link, canonical, err := runAuditLineLink(line)
+		if err != nil {
+			report.Break = &core.ChainBreak{Seq: verifier.LastSeq + 1, Kind: core.ChainBreakEdited, Detail: "line is not valid JSON"}
+		} else {
+			report.Break = verifier.Check(link, canonical)
+		}
+		if report.Break != nil {
+			report.Break.Ref = "line " + strconv.Itoa(lineNo)
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan %s: %w", path, err)
+	}
+	report.Entries, report.FirstSeq, report.LastSeq = verifier.Entries, verifier.FirstSeq, verifier.LastSeq
+	// Files are never pruned from the front, so they must start at seq 1.
+	if report.Break == nil && report.FirstSeq > 1 {
+		report.Break = &core.ChainBreak{Seq: 1, Kind: core.ChainBreakGap, Ref: "line 1",
+			Detail: fmt.Sprintf("entries 1..%d are missing", report.FirstSeq-1)}
+	}
+	return report, nil
+}
+
+// VerifyRunAuditDir checks every run audit JSONL file under rootDir.
+func VerifyRunAuditDir(rootDir string) ([]*core.ChainReport, error) {
+	var reports []*core.ChainReport
+	err := filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == rootDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), "-audit.jsonl") {
+			return nil
+		}
+		report, err := VerifyRunAuditFile(path)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verify run audit files: %w", err)
+	}
+	return reports, nil
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestFileExporter_ChainsRunAuditRecords(t *testing.T) {
+	root := t.TempDir()
+	exporter := NewFileExporter(root)
+	logRef := buildRunAuditLogRef(7, time.Now())
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		record := RunAuditRecord{
+			EventName: "run.audit",
+			RunID:     7,
+			Kind:      "tool_call",
+			Status:    "ok",
+			Data:      map[string]any{"n": i, "nested": map[string]any{"b": 1, "a": 0.5}},
+			CreatedAt: time.Now().UTC(),
+		}
+		if err := exporter.ExportRunAudit(ctx, logRef, []RunAuditRecord{record}); err != nil {
+			t.Fatalf("ExportRunAudit: %v", err)
+		}
+	}
+
+	reports, err := VerifyRunAuditDir(root)
+	if err != nil || len(reports) != 1 {
+		t.Fatalf("VerifyRunAuditDir = %v, %v", reports, err)
+	}
+	if !reports[0].OK() || reports[0].Entries != 3 || reports[0].LastSeq != 3 {
+		t.Fatalf("report = %+v (%v)", reports[0], reports[0].Break)
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(logRef))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read audit file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	edited := strings.Replace(string(data), `"status":"ok"`, `"status":"failed"`, 2)
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertChainBreak(t, path, core.ChainBreakEdited, "line 1")
+
+	reordered := lines[1] + "\n" + lines[0] + "\n" + lines[2] + "\n"
+	if err := os.WriteFile(path, []byte(reordered), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertChainBreak(t, path, core.ChainBreakReordered, "line 2")
+
+	dropped := lines[0] + "\n" + lines[2] + "\n"
+	if err := os.WriteFile(path, []byte(dropped), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	assertChainBreak(t, path, core.ChainBreakGap, "line 2")
+}
+
+func assertChainBreak(t *testing.T, path string, kind core.ChainBreakKind, ref string) {
+	t.Helper()
+	report, err := VerifyRunAuditFile(path)
+	if err != nil {
+		t.Fatalf("VerifyRunAuditFile: %v", err)
+	}
+	if report.Break == nil || report.Break.Kind != kind || report.Break.Ref != ref {
+		t.Fatalf("break = %+v, want %s at %s", report.Break, kind, ref)
+	}
+}
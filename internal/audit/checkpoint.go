@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+// CheckpointScheduler periodically signs the head of the journal hash chain.
+type CheckpointScheduler struct {
+	store    core.JournalChainStore
+	key      []byte
+	interval time.Duration
+}
+
+// NewCheckpointScheduler creates a scheduler; interval defaults to 1h.
+func NewCheckpointScheduler(store core.JournalChainStore, key []byte, interval time.Duration) *CheckpointScheduler {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &CheckpointScheduler{store: store, key: key, interval: interval}
+}
+
+// Start writes a checkpoint immediately and then on every interval. Blocks
+// until ctx is cancelled.
+func (s *CheckpointScheduler) Start(ctx context.Context) {
+	slog.Info("journal checkpoint scheduler started", "interval", s.interval)
+	s.runOnce(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("journal checkpoint scheduler stopped")
+			return
+		case <-ticker.C:
+			s.runOnce(ctx)
+		}
+	}
+}
+
+func (s *CheckpointScheduler) runOnce(ctx context.Context) {
+	cp, err := s.store.CreateJournalCheckpoint(ctx, s.key)
+	if err != nil {
+		slog.Error("journal checkpoint failed", "error", err)
+		return
+	}
+	if cp != nil {
+		slog.Debug("journal checkpoint written", "seq", cp.Seq)
+	}
+}
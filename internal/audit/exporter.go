@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,6 +22,10 @@ type RunAuditRecord struct {
 	RedactionLevel string         `json:"redaction_level,omitempty"`
 	Data           map[string]any `json:"data,omitempty"`
 	CreatedAt      time.Time      `json:"created_at"`
+	// Each file is its own hash chain (see chain.go).
+	Seq      int64  `json:"seq,omitempty"`
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
 }
 
 type Exporter interface {
@@ -29,6 +34,8 @@ type Exporter interface {
 
 type FileExporter struct {
 	rootDir string
+	// mu serializes appends so each file's hash chain stays linear.
+	mu sync.Mutex
 }
 
 func NewFileExporter(rootDir string) *FileExporter {
@@ -36,7 +43,27 @@ func NewFileExporter(rootDir string) *FileExporter {
 }
 
 func (e *FileExporter) ExportRunAudit(_ context.Context, logRef string, records []RunAuditRecord) error {
-	return writeJSONLRecords(e.rootDir, logRef, records)
+	if len(records) == 0 {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	path, err := resolveLogPath(e.rootDir, logRef)
+	if err != nil {
+		return err
+	}
+	head, err := readChainHead(path)
+	if err != nil {
+		return err
+	}
+	chained := make([]RunAuditRecord, len(records))
+	for i, record := range records {
+		if head, err = chainRunAuditRecord(&record, head); err != nil {
+			return err
+		}
+		chained[i] = record
+	}
+	return writeJSONLRecords(e.rootDir, logRef, chained)
 }
 
 func buildRunAuditLogRef(runID int64, now time.Time) string {
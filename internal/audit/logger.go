@@ -273,6 +273,12 @@ func (l *Logger) handleToolCallFinished(ctx context.Context, scope Scope, update
 	return l.store.UpdateToolCallAudit(ctx, audit)
 }
 
+// ResolveLogPath maps a relative audit log_ref onto rootDir, rejecting refs
+// that escape the root.
+func ResolveLogPath(rootDir, logRef string) (string, error) {
+	return resolveLogPath(rootDir, logRef)
+}
+
 func resolveLogPath(rootDir, logRef string) (string, error) {
 	trimmedRoot := filepath.Clean(strings.TrimSpace(rootDir))
 	if trimmedRoot == "." || trimmedRoot == "" {
@@ -399,11 +405,19 @@ func (r *Redactor) Level() string {
 	return r.level
 }
 
+// RedactData redacts the string values of data, replacing those under
+// sensitive keys such as "password" or "token" entirely.
+func (r *Redactor) RedactData(data map[string]any) map[string]any {
+	return redactAuditData(r, data)
+}
+
+// Redact masks credentials matched by the redactor's patterns and any vault
+// secret registered with RegisterSecretValues.
 func (r *Redactor) Redact(raw string) string {
 	if r == nil || raw == "" {
 		return raw
 	}
-	redacted := raw
+	redacted := ScrubSecretValues(raw)
 	for _, pattern := range r.patterns {
 		redacted = pattern.ReplaceAllString(redacted, `${1}[REDACTED]${3}`)
 	}
@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// minSecretLength keeps short values such as "1" or "yes" from being scrubbed
+// out of every log line.
+const minSecretLength = 4
+
+// secretValues are the vault secrets known to this process. Every
+// Redactor replaces them verbatim, whatever the redaction level.
+var secretValues struct {
+	mu     sync.RWMutex
+	set    map[string]struct{}
+	sorted []string // longest first, so overlapping secrets are fully replaced
+}
+
+// RegisterSecretValues makes every Redactor scrub values from now on. The
+// vault registers each secret as it is set, and every stored one when a
+// server or executor process starts.
+func RegisterSecretValues(values ...string) {
+	secretValues.mu.Lock()
+	defer secretValues.mu.Unlock()
+	changed := false
+	for _, v := range values {
+		if len(v) < minSecretLength {
+			continue
+		}
+		if _, ok := secretValues.set[v]; ok {
+			continue
+		}
+		if secretValues.set == nil {
+			secretValues.set = make(map[string]struct{})
+		}
+		secretValues.set[v] = struct{}{}
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	sorted := make([]string, 0, len(secretValues.set))
+	for v := range secretValues.set {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	secretValues.sorted = sorted
+}
+
+// ScrubSecretValues replaces registered secret values in raw.
+func ScrubSecretValues(raw string) string {
+	secretValues.mu.RLock()
+	defer secretValues.mu.RUnlock()
+	for _, v := range secretValues.sorted {
+		if strings.Contains(raw, v) {
+			raw = strings.ReplaceAll(raw, v, "[REDACTED]")
+		}
+	}
+	return raw
+}
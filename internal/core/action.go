@@ -92,6 +92,8 @@ type Action struct {
 	RetryCount int            `json:"retry_count"`
 	Config     map[string]any `json:"config,omitempty"`
 
+	// Version is bumped on every write; see VersionConflictError.
+	Version   int64     `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -66,6 +66,7 @@ type ActionSignal struct {
 	SourceActionID int64          `json:"source_action_id,omitempty"`
 	Payload        map[string]any `json:"payload,omitempty"`
 	Actor          string         `json:"actor,omitempty"`
+	ActorUserID    int64          `json:"actor_user_id,omitempty"` // signed-in User behind a human signal
 	CreatedAt      time.Time      `json:"created_at"`
 }
 
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// APIToken is a long-lived bearer token issued through the API, e.g. for a
+// CI bot. Only the SHA-256 of the secret is stored; Prefix keeps the first
+// characters so operators can recognise a token in logs and listings.
+type APIToken struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	TokenHash string `json:"-"`
+	Prefix    string `json:"prefix"`
+	// Owner is the identity recorded as submitter for requests made with the
+	// token. CreatedBy is who issued it.
+	Owner     string   `json:"owner"`
+	CreatedBy string   `json:"created_by,omitempty"`
+	Scopes    []string `json:"scopes"`
+	// Projects restricts the token to these project IDs or names; empty
+	// means every project.
+	Projects   []string   `json:"projects,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Active reports whether the token may still authenticate at now.
+func (t *APIToken) Active(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+// APITokenStore persists issued API tokens and revocations of signed
+// short-lived tokens, which are not stored themselves.
+type APITokenStore interface {
+	CreateAPIToken(ctx context.Context, t *APIToken) (int64, error)
+	GetAPIToken(ctx context.Context, id int64) (*APIToken, error)
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error)
+	ListAPITokens(ctx context.Context) ([]*APIToken, error)
+	RevokeAPIToken(ctx context.Context, id int64, at time.Time) error
+	// TouchAPIToken records the last use of a token.
+	TouchAPIToken(ctx context.Context, id int64, at time.Time, ip string) error
+
+	// RevokeTokenHash records a revoked signed token until it expires.
+	RevokeTokenHash(ctx context.Context, tokenHash string, expiresAt time.Time) error
+	// ListRevokedTokenHashes returns revocations that have not expired at now.
+	ListRevokedTokenHashes(ctx context.Context, now time.Time) ([]string, error)
+}
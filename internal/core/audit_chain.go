@@ -0,0 +1,200 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Hash chain shared by the activity journal and the audit JSONL files. Every
+// entry stores the SHA-256 of its canonical form together with the previous
+// entry's hash, so editing, dropping or reordering an entry breaks the link
+// to its successor.
+
+// ChainLink is the position of one entry in a hash chain. Seq starts at 1;
+// the first entry has an empty PrevHash.
+type ChainLink struct {
+	Seq      int64  `json:"seq"`
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// CanonicalJSON re-encodes a JSON document with sorted object keys and
+// numbers kept verbatim, so a value hashes the same before it is stored and
+// after it is read back.
+func CanonicalJSON(raw []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return []byte("null"), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("canonical json: %w", err)
+	}
+	return json.Marshal(v)
+}
+
+// ChainHash returns the hex SHA-256 of prevHash and canonical.
+func ChainHash(prevHash string, canonical []byte) string {
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write([]byte{'\n'})
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainBreakKind classifies the first inconsistency found in a chain.
+type ChainBreakKind string
+
+const (
+	// ChainBreakEdited: the entry no longer matches its own hash.
+	ChainBreakEdited ChainBreakKind = "edited"
+	// ChainBreakLink: the entry does not point at its predecessor.
+	ChainBreakLink ChainBreakKind = "broken_link"
+	// ChainBreakGap: one or more entries are missing.
+	ChainBreakGap ChainBreakKind = "gap"
+	// ChainBreakReordered: the entry is stored out of sequence.
+	ChainBreakReordered ChainBreakKind = "reordered"
+	// ChainBreakUnchained: the entry carries no hash at all.
+	ChainBreakUnchained ChainBreakKind = "unchained"
+	// ChainBreakCheckpoint: a checkpoint's signature or hash does not match.
+	ChainBreakCheckpoint ChainBreakKind = "checkpoint"
+	// ChainBreakTruncated: entries after a checkpoint are gone.
+	ChainBreakTruncated ChainBreakKind = "truncated"
+)
+
+// ChainBreak is the first broken link of a chain.
+type ChainBreak struct {
+	Seq    int64          `json:"seq"`
+	Kind   ChainBreakKind `json:"kind"`
+	Detail string         `json:"detail"`
+	// Ref locates the entry in its source, e.g. "id 42" or "line 7".
+	Ref string `json:"ref,omitempty"`
+}
+
+func (b *ChainBreak) Error() string {
+	msg := fmt.Sprintf("%s at seq %d", b.Kind, b.Seq)
+	if b.Ref != "" {
+		msg += " (" + b.Ref + ")"
+	}
+	return msg + ": " + b.Detail
+}
+
+// ChainReport is the result of verifying one chain.
+type ChainReport struct {
+	Source   string `json:"source"`
+	Entries  int    `json:"entries"`
+	FirstSeq int64  `json:"first_seq,omitempty"`
+	LastSeq  int64  `json:"last_seq,omitempty"`
+	// Checkpoints counts the checkpoints matched against the chain;
+	// SignaturesChecked is false when no checkpoint key was available.
+	Checkpoints       int         `json:"checkpoints"`
+	SignaturesChecked bool        `json:"signatures_checked"`
+	Break             *ChainBreak `json:"break,omitempty"`
+}
+
+// OK reports whether the chain verified without a break.
+func (r *ChainReport) OK() bool { return r != nil && r.Break == nil }
+
+// ChainVerifier checks links in stored order and returns the first break.
+// A chain may start after seq 1 when older entries were pruned by retention;
+// entries removed on purpose inside the chain are declared with AllowErased.
+type ChainVerifier struct {
+	prev     *ChainLink
+	erased   map[int64]string
+	Entries  int
+	FirstSeq int64
+	LastSeq  int64
+}
+
+// AllowErased declares that the entry at seq with hash was deleted on
+// purpose, so the gap it leaves is not reported.
+func (v *ChainVerifier) AllowErased(seq int64, hash string) {
+	if v.erased == nil {
+		v.erased = make(map[int64]string)
+	}
+	v.erased[seq] = hash
+}
+
+// Check verifies the next stored link against the canonical form of its
+// entry and the link before it.
+func (v *ChainVerifier) Check(link ChainLink, canonical []byte) *ChainBreak {
+	if link.Hash == "" {
+		return &ChainBreak{Seq: link.Seq, Kind: ChainBreakUnchained, Detail: "entry has no hash"}
+	}
+	if got := ChainHash(link.PrevHash, canonical); got != link.Hash {
+		return &ChainBreak{Seq: link.Seq, Kind: ChainBreakEdited, Detail: "content does not match the stored hash"}
+	}
+	if v.prev == nil {
+		if link.Seq == 1 && link.PrevHash != "" {
+			return &ChainBreak{Seq: link.Seq, Kind: ChainBreakLink, Detail: "first entry has a previous hash"}
+		}
+		v.FirstSeq = link.Seq
+	} else {
+		if link.Seq <= v.prev.Seq {
+			return &ChainBreak{Seq: link.Seq, Kind: ChainBreakReordered,
+				Detail: fmt.Sprintf("follows seq %d", v.prev.Seq)}
+		}
+		prevHash := v.prev.Hash
+		for seq := v.prev.Seq + 1; seq < link.Seq; seq++ {
+			hash, ok := v.erased[seq]
+			if !ok {
+				return &ChainBreak{Seq: seq, Kind: ChainBreakGap,
+					Detail: fmt.Sprintf("entries %d..%d are missing", seq, link.Seq-1)}
+			}
+			prevHash = hash
+		}
+		if link.PrevHash != prevHash {
+			return &ChainBreak{Seq: link.Seq, Kind: ChainBreakLink, Detail: "previous hash does not match the preceding entry"}
+		}
+	}
+	v.prev = &link
+	v.Entries++
+	v.LastSeq = link.Seq
+	return nil
+}
+
+// ChainCheckpoint pins the head of the journal chain with an HMAC signature,
+// so that a rewritten chain cannot be passed off without the key.
+type ChainCheckpoint struct {
+	ID        int64     `json:"id"`
+	Seq       int64     `json:"seq"`
+	Hash      string    `json:"hash"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SignChainCheckpoint returns the hex HMAC-SHA256 of seq and hash under key.
+func SignChainCheckpoint(key []byte, seq int64, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("journal-checkpoint\n" + strconv.FormatInt(seq, 10) + "\n" + hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidSignature reports whether the checkpoint was signed with key.
+func (c *ChainCheckpoint) ValidSignature(key []byte) bool {
+	want, err := hex.DecodeString(c.Signature)
+	if err != nil {
+		return false
+	}
+	got, _ := hex.DecodeString(SignChainCheckpoint(key, c.Seq, c.Hash))
+	return hmac.Equal(want, got)
+}
+
+// JournalChainStore maintains the hash chain of the activity journal.
+type JournalChainStore interface {
+	// CreateJournalCheckpoint signs the current head of the chain. It returns
+	// nil when the journal is empty or the head is already checkpointed.
+	CreateJournalCheckpoint(ctx context.Context, key []byte) (*ChainCheckpoint, error)
+	ListJournalCheckpoints(ctx context.Context) ([]*ChainCheckpoint, error)
+	// VerifyJournalChain walks the whole journal and its checkpoints. A nil
+	// key skips signature checks.
+	VerifyJournalChain(ctx context.Context, key []byte) (*ChainReport, error)
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// AuditOutcome classifies the result of an audited request.
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess AuditOutcome = "success"
+	AuditOutcomeDenied  AuditOutcome = "denied" // 401 or 403
+	AuditOutcomeFailed  AuditOutcome = "failed"
+)
+
+// AuditLogEntry records one mutating API request made by a person or an
+// automation token. Agent activity is covered by the journal instead.
+type AuditLogEntry struct {
+	ID int64 `json:"id"`
+	// Actor is the user reference ("user:42") of a console session, or the
+	// submitter or role of the token used.
+	Actor  string `json:"actor"`
+	UserID int64  `json:"user_id,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Method string `json:"method"`
+	// Route is the matched route pattern, e.g. /api/actions/{actionID};
+	// Path is the concrete request path.
+	Route string `json:"route"`
+	Path  string `json:"path"`
+	// ResourceIDs holds the route parameters, e.g. {"actionID": "42"}.
+	ResourceIDs map[string]string `json:"resource_ids,omitempty"`
+	// Changes is the redacted JSON request body: the fields the request
+	// asked to change.
+	Changes    map[string]any `json:"changes,omitempty"`
+	Status     int            `json:"status"`
+	Outcome    AuditOutcome   `json:"outcome"`
+	Error      string         `json:"error,omitempty"`
+	RemoteIP   string         `json:"remote_ip,omitempty"`
+	UserAgent  string         `json:"user_agent,omitempty"`
+	DurationMs int64          `json:"duration_ms"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// AuditOutcomeForStatus maps an HTTP status to an outcome.
+func AuditOutcomeForStatus(status int) AuditOutcome {
+	switch {
+	case status == 401 || status == 403:
+		return AuditOutcomeDenied
+	case status >= 400:
+		return AuditOutcomeFailed
+	default:
+		return AuditOutcomeSuccess
+	}
+}
+
+// AuditLogFilter constrains audit log queries. Results are newest first.
+type AuditLogFilter struct {
+	Actor  string
+	Method string
+	// RoutePrefix matches the start of the route pattern, e.g. /api/actions.
+	RoutePrefix string
+	// Resource matches a route parameter value, e.g. "actionID=42".
+	Resource string
+	Outcome  AuditOutcome
+	Since    *time.Time
+	Until    *time.Time
+	Limit    int
+	Offset   int
+}
+
+// AuditLogStore persists the request audit log.
+type AuditLogStore interface {
+	AppendAuditLog(ctx context.Context, entry *AuditLogEntry) (int64, error)
+	ListAuditLog(ctx context.Context, filter AuditLogFilter) ([]*AuditLogEntry, error)
+}
@@ -13,6 +13,7 @@ type DAGTemplate struct {
 	Tags        []string            `json:"tags,omitempty"`
 	Metadata    map[string]string   `json:"metadata,omitempty"`
 	Actions     []DAGTemplateAction `json:"actions"`
+	Version     int64               `json:"version"`
 	CreatedAt   time.Time           `json:"created_at"`
 	UpdatedAt   time.Time           `json:"updated_at"`
 }
@@ -1,6 +1,9 @@
 package core
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrNotFound            = errors.New("not found")
@@ -15,4 +18,37 @@ var (
 	ErrMissingResult       = errors.New("run completed without result")
 	ErrDuplicateEntryKey   = errors.New("duplicate feature entry key in project")
 	ErrTokenBudgetExceeded = errors.New("token budget exceeded")
+	ErrVersionConflict     = errors.New("version conflict")
 )
+
+// VersionConflictError is returned by versioned updates (work items, actions,
+// DAG templates, threads, proposals) when the caller's Version no longer
+// matches the stored row. It unwraps to ErrVersionConflict.
+type VersionConflictError struct {
+	Resource string
+	ID       int64
+	Expected int64
+	Actual   int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s %d: version conflict (expected %d, current %d)", e.Resource, e.ID, e.Expected, e.Actual)
+}
+
+func (e *VersionConflictError) Unwrap() error { return ErrVersionConflict }
+
+// maxConflictRetries bounds RetryOnConflict.
+const maxConflictRetries = 5
+
+// RetryOnConflict runs fn until it succeeds, fails with an error other than
+// ErrVersionConflict, or conflicts maxConflictRetries times. fn must re-read
+// the row it updates on every call.
+func RetryOnConflict(fn func() error) error {
+	var err error
+	for range maxConflictRetries {
+		if err = fn(); !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+	}
+	return err
+}
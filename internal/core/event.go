@@ -100,9 +100,17 @@ const (
 	EventCategoryToolAudit = "tool_audit"
 )
 
+// EventStreamOverflow is delivered to subscribers that set
+// SubscribeOpts.NotifyOverflow when the bus had to drop events for them.
+// Data carries from_seq, to_seq and dropped.
+const EventStreamOverflow EventType = "stream.overflow"
+
 // Event is a domain event emitted during WorkItem execution.
 type Event struct {
-	ID         int64          `json:"id"`
+	ID int64 `json:"id"`
+	// Seq is the bus-assigned, monotonic sequence number. It is persisted
+	// with the event so clients can resume a stream from a known position.
+	Seq        int64          `json:"seq,omitempty"`
 	Type       EventType      `json:"type"`
 	Category   string         `json:"category,omitempty"`
 	WorkItemID int64          `json:"work_item_id,omitempty"`
@@ -123,6 +131,21 @@ type EventBus interface {
 type SubscribeOpts struct {
 	Types      []EventType
 	BufferSize int
+	// NotifyOverflow asks the bus to deliver an EventStreamOverflow marker
+	// once the subscriber catches up after events were dropped for it.
+	NotifyOverflow bool
+	// Backpressure asks the bus to make Publish wait, for a bounded time,
+	// while this subscriber's buffer is full instead of dropping events, so
+	// a slow consumer slows producers down. An event that still does not
+	// fit, or whose publisher's context ends, is dropped and reported with
+	// an EventStreamOverflow marker as if NotifyOverflow were set.
+	Backpressure bool
+}
+
+// SequencedBus is implemented by buses that assign Event.Seq.
+type SequencedBus interface {
+	// LastSeq returns the sequence number of the most recently published event.
+	LastSeq() int64
 }
 
 // Subscription represents an active event subscription.
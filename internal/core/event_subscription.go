@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// EventSubscription pushes matching domain events to an external endpoint as
+// signed CloudEvents.
+type EventSubscription struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	TargetURL string `json:"target_url"`
+	// EventTypes limits deliveries to these event types; empty means all
+	// non-transient events.
+	EventTypes []EventType `json:"event_types,omitempty"`
+	// ProjectID limits deliveries to events that belong to this project.
+	ProjectID *int64 `json:"project_id,omitempty"`
+	// Secret signs every delivery. It is never serialised.
+	Secret    string    `json:"-"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Matches reports whether ev passes the type filter. Project scope needs a
+// store lookup and is checked by the dispatcher.
+func (s *EventSubscription) Matches(ev Event) bool {
+	if s == nil || !s.Enabled {
+		return false
+	}
+	if len(s.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range s.EventTypes {
+		if t == ev.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// EventDeliveryStatus is the state of one delivery attempt chain.
+type EventDeliveryStatus string
+
+const (
+	// EventDeliveryPending is waiting for its first or next attempt.
+	EventDeliveryPending EventDeliveryStatus = "pending"
+	// EventDeliverySucceeded was acknowledged with a 2xx response.
+	EventDeliverySucceeded EventDeliveryStatus = "succeeded"
+	// EventDeliveryDead exhausted its attempts and will not be retried
+	// unless replayed.
+	EventDeliveryDead EventDeliveryStatus = "dead"
+)
+
+// EventDelivery records one CloudEvent sent (or to be sent) to a subscription.
+// Replays reuse CloudEventID so receivers can deduplicate.
+type EventDelivery struct {
+	ID             int64               `json:"id"`
+	SubscriptionID int64               `json:"subscription_id"`
+	CloudEventID   string              `json:"cloud_event_id"`
+	EventType      EventType           `json:"event_type"`
+	EventSeq       int64               `json:"event_seq,omitempty"`
+	Payload        string              `json:"payload"`
+	Status         EventDeliveryStatus `json:"status"`
+	Attempts       int                 `json:"attempts"`
+	NextAttemptAt  *time.Time          `json:"next_attempt_at,omitempty"`
+	LastError      string              `json:"last_error,omitempty"`
+	ResponseStatus int                 `json:"response_status,omitempty"`
+	ReplayOf       *int64              `json:"replay_of,omitempty"`
+	DeliveredAt    *time.Time          `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// EventDeliveryFilter constrains delivery log queries.
+type EventDeliveryFilter struct {
+	SubscriptionID *int64
+	Status         EventDeliveryStatus
+	Limit          int
+	Offset         int
+}
+
+// EventSubscriptionStore persists subscriptions and their delivery log.
+type EventSubscriptionStore interface {
+	CreateEventSubscription(ctx context.Context, sub *EventSubscription) (int64, error)
+	GetEventSubscription(ctx context.Context, id int64) (*EventSubscription, error)
+	ListEventSubscriptions(ctx context.Context) ([]*EventSubscription, error)
+	UpdateEventSubscription(ctx context.Context, sub *EventSubscription) error
+	// DeleteEventSubscription removes the subscription and its delivery log.
+	DeleteEventSubscription(ctx context.Context, id int64) error
+
+	CreateEventDelivery(ctx context.Context, d *EventDelivery) (int64, error)
+	GetEventDelivery(ctx context.Context, id int64) (*EventDelivery, error)
+	UpdateEventDelivery(ctx context.Context, d *EventDelivery) error
+	ListEventDeliveries(ctx context.Context, filter EventDeliveryFilter) ([]*EventDelivery, error)
+	// ListDueEventDeliveries returns pending deliveries whose next attempt is
+	// at or before now, oldest first.
+	ListDueEventDeliveries(ctx context.Context, now time.Time, limit int) ([]*EventDelivery, error)
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotencyRecord remembers the response to a request sent with an
+// Idempotency-Key header, so that a retry with the same key replays it
+// instead of creating the work item, thread or run a second time.
+type IdempotencyRecord struct {
+	// Scope is the caller the key belongs to, e.g. "user:42" or
+	// "token:<hash prefix>"; keys of different callers never collide.
+	Scope string
+	Key   string
+	// Method and Path are the first request's; RequestHash covers them and
+	// the body, so reusing a key for a different request is detected.
+	Method      string
+	Path        string
+	RequestHash string
+	// Status is 0 while the first request is still being handled.
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Pending reports whether the first request with the key has not finished.
+func (r *IdempotencyRecord) Pending() bool {
+	return r.Status == 0
+}
+
+// IdempotencyStore persists idempotency keys and their responses.
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey inserts rec as pending unless an unexpired record
+	// holds the same scope and key, in which case that record is returned
+	// with reserved false. Expired records are dropped on the way.
+	ReserveIdempotencyKey(ctx context.Context, rec *IdempotencyRecord) (existing *IdempotencyRecord, reserved bool, err error)
+	// CompleteIdempotencyKey stores the response of a reserved key and keeps
+	// it until expiresAt.
+	CompleteIdempotencyKey(ctx context.Context, scope, key string, status int, contentType string, body []byte, expiresAt time.Time) error
+	// ReleaseIdempotencyKey drops a pending reservation so that the key can
+	// be retried.
+	ReleaseIdempotencyKey(ctx context.Context, scope, key string) error
+}
@@ -24,6 +24,9 @@ const (
 	JournalSystem      JournalKind = "system"
 	JournalAssignment  JournalKind = "assignment"
 	JournalBackfill    JournalKind = "backfill"
+	// JournalErasure records entries deleted on purpose (with their work
+	// item) so the hash chain stays verifiable across the gap.
+	JournalErasure JournalKind = "erasure"
 )
 
 // JournalSource identifies who produced the entry.
@@ -64,8 +67,17 @@ type JournalFilter struct {
 	Offset     int
 }
 
+// JournalQueue accepts journal entries for write-behind persistence: an
+// entry is written with the next batch rather than in its own transaction.
+type JournalQueue interface {
+	EnqueueJournal(ctx context.Context, entry *JournalEntry) error
+}
+
 // JournalStore persists and queries unified activity journal entries.
+// EnqueueJournal is for producers that do not need the entry's id: it goes
+// through the store's write-behind queue when one is attached.
 type JournalStore interface {
+	JournalQueue
 	AppendJournal(ctx context.Context, entry *JournalEntry) (int64, error)
 	BatchAppendJournal(ctx context.Context, entries []*JournalEntry) error
 	ListJournal(ctx context.Context, filter JournalFilter) ([]*JournalEntry, error)
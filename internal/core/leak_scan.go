@@ -0,0 +1,13 @@
+package core
+
+// LeakFinding is a credential found by the leak scan in a diff or in text an
+// agent produced. It never carries the secret itself, only a masked preview.
+type LeakFinding struct {
+	Rule        string `json:"rule"`
+	Description string `json:"description"`
+	// File is the path in the diff, or the name of the scanned text such as
+	// "result_markdown".
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Preview string `json:"preview,omitempty"`
+}
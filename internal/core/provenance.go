@@ -0,0 +1,47 @@
+package core
+
+import "context"
+
+// ProvenanceKind says what a provenance statement attests.
+type ProvenanceKind string
+
+const (
+	// ProvenanceCommit attests a commit pushed by the git_commit_push builtin.
+	ProvenanceCommit ProvenanceKind = "commit"
+	// ProvenanceChangeRequest attests a change request merged by a gate.
+	ProvenanceChangeRequest ProvenanceKind = "change_request"
+)
+
+// ResourceRoleProvenance is the Resource role of stored provenance envelopes.
+const ResourceRoleProvenance = "provenance"
+
+// ProvenanceSubject identifies what to attest and where it came from.
+type ProvenanceSubject struct {
+	Kind       ProvenanceKind
+	WorkItemID int64
+	// ActionID and RunID are the action that published the subject: the
+	// git_commit_push action, or the gate that merged the change request.
+	ActionID int64
+	RunID    int64
+	// CommitSHA is the pushed commit, or the head of the change request.
+	CommitSHA string
+	Repo      string
+	Ref       string
+	// ChangeRequestNumber and ChangeRequestURL are set for change requests.
+	ChangeRequestNumber int
+	ChangeRequestURL    string
+	// WorkDir is the git worktree; when set and git notes are enabled the
+	// envelope is also attached to CommitSHA as a note.
+	WorkDir string
+}
+
+// ProvenanceRecorder signs and stores a provenance statement for a subject.
+type ProvenanceRecorder interface {
+	RecordProvenance(ctx context.Context, subject ProvenanceSubject) (*Resource, error)
+}
+
+// ProvenanceStore finds stored provenance envelopes by the commit they
+// attest. commitSHA may be an abbreviated hash.
+type ProvenanceStore interface {
+	ListProvenanceResources(ctx context.Context, commitSHA string) ([]*Resource, error)
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// RetentionTarget names a data set governed by the retention service.
+type RetentionTarget string
+
+const (
+	RetentionEventLog  RetentionTarget = "event_log"
+	RetentionJournal   RetentionTarget = "activity_journal"
+	RetentionRunOutput RetentionTarget = "run_output"
+	RetentionUsage     RetentionTarget = "usage_records"
+)
+
+// RetentionTargets lists every target in the order the service prunes them.
+func RetentionTargets() []RetentionTarget {
+	return []RetentionTarget{RetentionEventLog, RetentionJournal, RetentionRunOutput, RetentionUsage}
+}
+
+// Valid reports whether t is a known retention target.
+func (t RetentionTarget) Valid() bool {
+	switch t {
+	case RetentionEventLog, RetentionJournal, RetentionRunOutput, RetentionUsage:
+		return true
+	}
+	return false
+}
+
+// RetentionMode controls what happens to expired rows.
+type RetentionMode string
+
+const (
+	RetentionModeDelete  RetentionMode = "delete"  // roll up, then delete
+	RetentionModeArchive RetentionMode = "archive" // write to an archive file, roll up, then delete
+	RetentionModeKeep    RetentionMode = "keep"    // never prune
+)
+
+// Valid reports whether m is a known retention mode.
+func (m RetentionMode) Valid() bool {
+	switch m {
+	case RetentionModeDelete, RetentionModeArchive, RetentionModeKeep:
+		return true
+	}
+	return false
+}
+
+// RetentionArchiveFunc receives each batch of expired records before they are
+// removed. Returning an error aborts the prune for that target.
+type RetentionArchiveFunc func(records []any) error
+
+// RetentionPruneInput describes one prune pass over a target.
+type RetentionPruneInput struct {
+	Target    RetentionTarget
+	Before    time.Time
+	BatchSize int
+	Archive   RetentionArchiveFunc
+}
+
+// RetentionPruneResult reports what a prune pass removed.
+type RetentionPruneResult struct {
+	Pruned   int64 `json:"pruned"`
+	RolledUp int64 `json:"rolled_up"`
+	// AuditLogRefs lists audit JSONL files referenced by pruned run.audit events.
+	AuditLogRefs []string `json:"-"`
+}
+
+// RetentionTargetReport summarises one target in a retention run.
+type RetentionTargetReport struct {
+	Target      RetentionTarget `json:"target"`
+	Mode        RetentionMode   `json:"mode"`
+	Days        int             `json:"days"`
+	Before      *time.Time      `json:"before,omitempty"`
+	Expired     int64           `json:"expired"`
+	Pruned      int64           `json:"pruned"`
+	RolledUp    int64           `json:"rolled_up"`
+	ArchivePath string          `json:"archive_path,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// RetentionFileReport summarises audit file pruning.
+type RetentionFileReport struct {
+	Root    string `json:"root"`
+	Expired int    `json:"expired"`
+	Deleted int    `json:"deleted"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// RetentionReport is the outcome of a retention run (or a dry run).
+type RetentionReport struct {
+	DryRun       bool                    `json:"dry_run"`
+	StartedAt    time.Time               `json:"started_at"`
+	FinishedAt   time.Time               `json:"finished_at"`
+	Targets      []RetentionTargetReport `json:"targets"`
+	AuditFiles   RetentionFileReport     `json:"audit_files"`
+	Checkpointed bool                    `json:"checkpointed"`
+	Vacuumed     bool                    `json:"vacuumed"`
+}
+
+// RetentionStore prunes expired rows and performs storage maintenance.
+type RetentionStore interface {
+	CountExpired(ctx context.Context, target RetentionTarget, before time.Time) (int64, error)
+	PruneExpired(ctx context.Context, in RetentionPruneInput) (RetentionPruneResult, error)
+	CheckpointWAL(ctx context.Context) error
+	Vacuum(ctx context.Context) error
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrEmptySearchQuery is returned when a search has no usable terms.
+var ErrEmptySearchQuery = errors.New("search query is empty")
+
+// SearchKind identifies the kind of document in the full-text index.
+type SearchKind string
+
+const (
+	SearchKindWorkItem      SearchKind = "work_item"
+	SearchKindThreadMessage SearchKind = "thread_message"
+	SearchKindJournal       SearchKind = "journal"
+	SearchKindRun           SearchKind = "run"
+	SearchKindDeliverable   SearchKind = "deliverable"
+)
+
+// SearchKinds lists every indexed kind.
+func SearchKinds() []SearchKind {
+	return []SearchKind{
+		SearchKindWorkItem,
+		SearchKindThreadMessage,
+		SearchKindJournal,
+		SearchKindRun,
+		SearchKindDeliverable,
+	}
+}
+
+func (k SearchKind) Valid() bool {
+	for _, known := range SearchKinds() {
+		if k == known {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchQuery constrains a full-text search.
+type SearchQuery struct {
+	Query     string
+	Kinds     []SearchKind
+	ProjectID *int64
+	Limit     int
+	Offset    int
+}
+
+// SearchHit is one ranked match. Snippet marks matched text with <mark></mark>.
+type SearchHit struct {
+	Kind       SearchKind `json:"kind"`
+	RefID      int64      `json:"ref_id"`
+	ProjectID  *int64     `json:"project_id,omitempty"`
+	WorkItemID *int64     `json:"work_item_id,omitempty"`
+	ThreadID   *int64     `json:"thread_id,omitempty"`
+	Title      string     `json:"title"`
+	Snippet    string     `json:"snippet"`
+	Score      float64    `json:"score"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+// SearchStore queries the full-text index over work items, thread messages,
+// journal entries, run results and deliverables.
+type SearchStore interface {
+	Search(ctx context.Context, q SearchQuery) ([]SearchHit, error)
+}
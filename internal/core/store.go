@@ -57,6 +57,8 @@ type AgentContextStore interface {
 // EventStore persists domain events and tool call audits (unified in event_log).
 type EventStore interface {
 	CreateEvent(ctx context.Context, e *Event) (int64, error)
+	// BatchCreateEvents inserts events in a single transaction and fills their IDs.
+	BatchCreateEvents(ctx context.Context, events []*Event) error
 	ListEvents(ctx context.Context, filter EventFilter) ([]*Event, error)
 	GetLatestRunEventTime(ctx context.Context, runID int64, eventType EventType) (*time.Time, error)
 	// Tool call audit methods (stored as category='tool_audit' events in event_log).
@@ -97,6 +99,13 @@ type Store interface {
 	JournalStore
 	NotificationStore
 	InspectionStore
+	UserStore
+	APITokenStore
+	AuditLogStore
+	JournalChainStore
+	VaultStore
+	ProvenanceStore
+	IdempotencyStore
 	Close() error
 }
 
@@ -115,6 +124,9 @@ type EventFilter struct {
 	SessionID  string
 	Category   string
 	Types      []EventType
-	Limit      int
-	Offset     int
+	// SinceSeq returns only events with a greater sequence number, oldest
+	// first, so the last returned Seq can be used as the next cursor.
+	SinceSeq *int64
+	Limit    int
+	Offset   int
 }
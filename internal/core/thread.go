@@ -62,6 +62,7 @@ type Thread struct {
 	OwnerID        string         `json:"owner_id,omitempty"`
 	FocusProjectID int64          `json:"focus_project_id,omitempty"`
 	Metadata       map[string]any `json:"metadata,omitempty"`
+	Version        int64          `json:"version"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
 }
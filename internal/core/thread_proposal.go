@@ -81,6 +81,7 @@ type ThreadProposal struct {
 	SourceMessageID *int64                  `json:"source_message_id,omitempty"`
 	InitiativeID    *int64                  `json:"initiative_id,omitempty"`
 	Metadata        map[string]any          `json:"metadata,omitempty"`
+	Version         int64                   `json:"version"`
 	CreatedAt       time.Time               `json:"created_at"`
 	UpdatedAt       time.Time               `json:"updated_at"`
 }
@@ -0,0 +1,365 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// State entities recorded in state_change journal payloads.
+const (
+	StateEntityWorkItem = "work_item"
+	StateEntityAction   = "action"
+)
+
+// WorkItemState is the subset of a work item replayed by the timeline.
+type WorkItemState struct {
+	Title             string           `json:"title"`
+	Status            WorkItemStatus   `json:"status"`
+	Priority          WorkItemPriority `json:"priority"`
+	ActiveProfileID   string           `json:"active_profile_id"`
+	ExecutorProfileID string           `json:"executor_profile_id"`
+	ReviewerProfileID string           `json:"reviewer_profile_id"`
+	Archived          bool             `json:"archived"`
+}
+
+// WorkItemStateOf extracts the replayed fields of w.
+func WorkItemStateOf(w *WorkItem) WorkItemState {
+	return WorkItemState{
+		Title:             w.Title,
+		Status:            w.Status,
+		Priority:          w.Priority,
+		ActiveProfileID:   w.ActiveProfileID,
+		ExecutorProfileID: w.ExecutorProfileID,
+		ReviewerProfileID: w.ReviewerProfileID,
+		Archived:          w.ArchivedAt != nil,
+	}
+}
+
+// ActionState is the subset of an action replayed by the timeline.
+type ActionState struct {
+	Name       string       `json:"name"`
+	Type       ActionType   `json:"type"`
+	Status     ActionStatus `json:"status"`
+	Position   int          `json:"position"`
+	DependsOn  []int64      `json:"depends_on"`
+	AgentRole  string       `json:"agent_role"`
+	RetryCount int          `json:"retry_count"`
+}
+
+// ActionStateOf extracts the replayed fields of a.
+func ActionStateOf(a *Action) ActionState {
+	dependsOn := a.DependsOn
+	if dependsOn == nil {
+		dependsOn = []int64{}
+	}
+	return ActionState{
+		Name:       a.Name,
+		Type:       a.Type,
+		Status:     a.Status,
+		Position:   a.Position,
+		DependsOn:  dependsOn,
+		AgentRole:  a.AgentRole,
+		RetryCount: a.RetryCount,
+	}
+}
+
+// StateChangeJournalEntry builds a state_change entry recording the fields
+// that differ between before and after (WorkItemState or ActionState values).
+// A nil before records a creation with the full state, a nil after records a
+// deletion. It returns nil when nothing replayed by the timeline changed.
+//
+// Payload: {"entity", "state": changed fields after, "from": changed fields
+// before, "created"/"deleted": true}.
+func StateChangeJournalEntry(entity string, workItemID, actionID int64, before, after any) *JournalEntry {
+	payload := map[string]any{"entity": entity}
+	var summary string
+	switch {
+	case before == nil && after == nil:
+		return nil
+	case before == nil:
+		payload["created"] = true
+		payload["state"] = stateFields(after)
+		summary = fmt.Sprintf("%s created", entityLabel(entity))
+	case after == nil:
+		payload["deleted"] = true
+		summary = fmt.Sprintf("%s deleted", entityLabel(entity))
+	default:
+		from, to := stateFields(before), stateFields(after)
+		changedFrom := map[string]any{}
+		changedTo := map[string]any{}
+		for key, value := range to {
+			if !reflect.DeepEqual(from[key], value) {
+				changedFrom[key] = from[key]
+				changedTo[key] = value
+			}
+		}
+		if len(changedTo) == 0 {
+			return nil
+		}
+		payload["from"] = changedFrom
+		payload["state"] = changedTo
+		summary = summarizeStateChange(entity, changedFrom, changedTo)
+	}
+	return &JournalEntry{
+		WorkItemID: workItemID,
+		ActionID:   actionID,
+		Kind:       JournalStateChange,
+		Source:     JournalSourceSystem,
+		Summary:    summary,
+		Payload:    payload,
+	}
+}
+
+func entityLabel(entity string) string {
+	return strings.ReplaceAll(entity, "_", " ")
+}
+
+// summarizeStateChange spells out values only for enumerated fields; free
+// text such as titles stays in the payload so the search index does not keep
+// matching text the row no longer has.
+func summarizeStateChange(entity string, from, to map[string]any) string {
+	keys := sortedKeys(to)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		switch key {
+		case "title", "name", "depends_on":
+			parts = append(parts, key+" changed")
+		default:
+			parts = append(parts, fmt.Sprintf("%s %v → %v", key, from[key], to[key]))
+		}
+	}
+	return entityLabel(entity) + ": " + strings.Join(parts, ", ")
+}
+
+// stateFields flattens a state struct (or an already decoded payload map)
+// into its JSON form so values compare the same before and after a journal
+// round trip.
+func stateFields(v any) map[string]any {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return map[string]any{}
+	}
+	out := map[string]any{}
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GateVerdictState is the latest gate decision on an action as of a point in
+// time: "approve" or "reject" from a signal, or "pass" when the gate
+// completed without one.
+type GateVerdictState struct {
+	Verdict string    `json:"verdict"`
+	Source  string    `json:"source,omitempty"`
+	Actor   string    `json:"actor,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// ActionSnapshot is an action as reconstructed from the journal.
+type ActionSnapshot struct {
+	ActionID    int64             `json:"action_id"`
+	Deleted     bool              `json:"deleted,omitempty"`
+	State       ActionState       `json:"state"`
+	GateVerdict *GateVerdictState `json:"gate_verdict,omitempty"`
+}
+
+// WorkItemSnapshot is a work item and its actions as of At, rebuilt from
+// state_change and signal journal entries.
+type WorkItemSnapshot struct {
+	WorkItemID int64     `json:"work_item_id"`
+	At         time.Time `json:"at"`
+	Exists     bool      `json:"exists"`
+	// Complete is false when the journal does not reach back to the work
+	// item's creation (rows older than state journaling, or pruned entries),
+	// so fields never changed since then are unknown.
+	Complete bool              `json:"complete"`
+	State    WorkItemState     `json:"state"`
+	Actions  []*ActionSnapshot `json:"actions"`
+}
+
+// ReplayWorkItemTimeline folds entries (oldest first) recorded at or before
+// at into a snapshot of workItemID.
+func ReplayWorkItemTimeline(workItemID int64, entries []*JournalEntry, at time.Time) *WorkItemSnapshot {
+	snap := &WorkItemSnapshot{WorkItemID: workItemID, At: at}
+	actions := map[int64]*ActionSnapshot{}
+	action := func(id int64) *ActionSnapshot {
+		a, ok := actions[id]
+		if !ok {
+			a = &ActionSnapshot{ActionID: id}
+			actions[id] = a
+		}
+		return a
+	}
+
+	for _, entry := range entries {
+		if entry == nil || entry.WorkItemID != workItemID || entry.CreatedAt.After(at) {
+			continue
+		}
+		switch entry.Kind {
+		case JournalStateChange:
+			entity, _ := entry.Payload["entity"].(string)
+			created, _ := entry.Payload["created"].(bool)
+			deleted, _ := entry.Payload["deleted"].(bool)
+			switch entity {
+			case StateEntityWorkItem:
+				if created {
+					snap.Complete = true
+				}
+				snap.Exists = true
+				applyState(&snap.State, entry.Payload["state"])
+			case StateEntityAction:
+				if entry.ActionID == 0 {
+					continue
+				}
+				a := action(entry.ActionID)
+				if deleted {
+					a.Deleted = true
+					continue
+				}
+				if created {
+					a.Deleted = false
+				}
+				applyState(&a.State, entry.Payload["state"])
+				if a.State.Type == ActionGate && a.State.Status == ActionDone &&
+					(a.GateVerdict == nil || a.GateVerdict.Verdict == string(SignalReject)) {
+					a.GateVerdict = &GateVerdictState{Verdict: "pass", Source: string(entry.Source), At: entry.CreatedAt}
+				}
+			}
+		case JournalSignal:
+			signalType, _ := entry.Payload["signal_type"].(string)
+			if entry.ActionID == 0 || (signalType != string(SignalApprove) && signalType != string(SignalReject)) {
+				continue
+			}
+			reason, _ := entry.Payload["reason"].(string)
+			if reason == "" {
+				reason = entry.Summary
+			}
+			action(entry.ActionID).GateVerdict = &GateVerdictState{
+				Verdict: signalType,
+				Source:  string(entry.Source),
+				Actor:   entry.Actor,
+				Reason:  reason,
+				At:      entry.CreatedAt,
+			}
+		}
+	}
+
+	snap.Actions = make([]*ActionSnapshot, 0, len(actions))
+	for _, a := range actions {
+		snap.Actions = append(snap.Actions, a)
+	}
+	sort.Slice(snap.Actions, func(i, j int) bool {
+		if snap.Actions[i].State.Position != snap.Actions[j].State.Position {
+			return snap.Actions[i].State.Position < snap.Actions[j].State.Position
+		}
+		return snap.Actions[i].ActionID < snap.Actions[j].ActionID
+	})
+	return snap
+}
+
+// applyState merges a decoded "state" payload into dst; keys absent from the
+// payload keep their previous values.
+func applyState(dst any, state any) {
+	if state == nil {
+		return
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(raw, dst)
+}
+
+// TimelineChange is one field that differs between two snapshots.
+type TimelineChange struct {
+	Entity   string `json:"entity"`
+	ActionID int64  `json:"action_id,omitempty"`
+	Field    string `json:"field"`
+	From     any    `json:"from"`
+	To       any    `json:"to"`
+}
+
+// DiffWorkItemSnapshots lists the fields that changed from a to b.
+func DiffWorkItemSnapshots(a, b *WorkItemSnapshot) []TimelineChange {
+	changes := []TimelineChange{}
+	if a.Exists != b.Exists {
+		changes = append(changes, TimelineChange{Entity: StateEntityWorkItem, Field: "exists", From: a.Exists, To: b.Exists})
+	}
+	changes = append(changes, diffFields(StateEntityWorkItem, 0, stateFields(a.State), stateFields(b.State))...)
+
+	before := map[int64]*ActionSnapshot{}
+	for _, action := range a.Actions {
+		before[action.ActionID] = action
+	}
+	after := map[int64]*ActionSnapshot{}
+	ids := make([]int64, 0, len(a.Actions)+len(b.Actions))
+	for _, action := range a.Actions {
+		ids = append(ids, action.ActionID)
+	}
+	for _, action := range b.Actions {
+		after[action.ActionID] = action
+		if _, ok := before[action.ActionID]; !ok {
+			ids = append(ids, action.ActionID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		from, to := before[id], after[id]
+		fromExists := from != nil && !from.Deleted
+		toExists := to != nil && !to.Deleted
+		if fromExists != toExists {
+			changes = append(changes, TimelineChange{Entity: StateEntityAction, ActionID: id, Field: "exists", From: fromExists, To: toExists})
+		}
+		fromFields, toFields := map[string]any{}, map[string]any{}
+		var fromVerdict, toVerdict any
+		if from != nil {
+			fromFields = stateFields(from.State)
+			if from.GateVerdict != nil {
+				fromVerdict = from.GateVerdict.Verdict
+			}
+		}
+		if to != nil {
+			toFields = stateFields(to.State)
+			if to.GateVerdict != nil {
+				toVerdict = to.GateVerdict.Verdict
+			}
+		}
+		changes = append(changes, diffFields(StateEntityAction, id, fromFields, toFields)...)
+		if fromVerdict != toVerdict {
+			changes = append(changes, TimelineChange{Entity: StateEntityAction, ActionID: id, Field: "gate_verdict", From: fromVerdict, To: toVerdict})
+		}
+	}
+	return changes
+}
+
+func diffFields(entity string, actionID int64, from, to map[string]any) []TimelineChange {
+	keys := map[string]any{}
+	for key := range from {
+		keys[key] = nil
+	}
+	for key := range to {
+		keys[key] = nil
+	}
+	var out []TimelineChange
+	for _, key := range sortedKeys(keys) {
+		if reflect.DeepEqual(from[key], to[key]) {
+			continue
+		}
+		out = append(out, TimelineChange{Entity: entity, ActionID: actionID, Field: key, From: from[key], To: to[key]})
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStateChangeJournalEntryRecordsOnlyChangedFields(t *testing.T) {
+	before := WorkItemState{Title: "a", Status: WorkItemOpen, Priority: PriorityLow}
+	after := before
+	if entry := StateChangeJournalEntry(StateEntityWorkItem, 1, 0, before, after); entry != nil {
+		t.Fatalf("expected nil entry for unchanged state, got %+v", entry)
+	}
+
+	after.Status = WorkItemRunning
+	entry := StateChangeJournalEntry(StateEntityWorkItem, 1, 0, before, after)
+	if entry == nil || entry.Kind != JournalStateChange {
+		t.Fatalf("expected state_change entry, got %+v", entry)
+	}
+	state, _ := entry.Payload["state"].(map[string]any)
+	if len(state) != 1 || state["status"] != string(WorkItemRunning) {
+		t.Fatalf("expected only status in payload state, got %+v", state)
+	}
+}
+
+func TestReplayWorkItemTimeline(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return t0.Add(time.Duration(minutes) * time.Minute) }
+	stamp := func(e *JournalEntry, ts time.Time) *JournalEntry {
+		e.CreatedAt = ts
+		return e
+	}
+
+	item := WorkItemState{Title: "ship", Status: WorkItemOpen, Priority: PriorityMedium}
+	gate := ActionState{Name: "review", Type: ActionGate, Status: ActionPending, DependsOn: []int64{}}
+	running := item
+	running.Status = WorkItemRunning
+	gateDone := gate
+	gateDone.Status = ActionDone
+
+	entries := []*JournalEntry{
+		stamp(StateChangeJournalEntry(StateEntityWorkItem, 7, 0, nil, item), at(0)),
+		stamp(StateChangeJournalEntry(StateEntityAction, 7, 3, nil, gate), at(1)),
+		stamp(StateChangeJournalEntry(StateEntityWorkItem, 7, 0, item, running), at(2)),
+		stamp(&JournalEntry{WorkItemID: 7, ActionID: 3, Kind: JournalSignal, Source: JournalSourceHuman,
+			Payload: map[string]any{"signal_type": "reject", "reason": "tests missing"}}, at(3)),
+		stamp(StateChangeJournalEntry(StateEntityAction, 7, 3, gate, gateDone), at(4)),
+	}
+
+	early := ReplayWorkItemTimeline(7, entries, at(1))
+	if !early.Exists || !early.Complete || early.State.Status != WorkItemOpen {
+		t.Fatalf("unexpected early snapshot: %+v", early)
+	}
+	if len(early.Actions) != 1 || early.Actions[0].GateVerdict != nil {
+		t.Fatalf("expected gate without verdict, got %+v", early.Actions)
+	}
+
+	mid := ReplayWorkItemTimeline(7, entries, at(3))
+	if v := mid.Actions[0].GateVerdict; v == nil || v.Verdict != "reject" || v.Reason != "tests missing" {
+		t.Fatalf("expected reject verdict, got %+v", v)
+	}
+
+	late := ReplayWorkItemTimeline(7, entries, at(5))
+	if v := late.Actions[0].GateVerdict; v == nil || v.Verdict != "pass" {
+		t.Fatalf("expected pass verdict after gate done, got %+v", v)
+	}
+
+	changes := DiffWorkItemSnapshots(early, late)
+	fields := map[string]TimelineChange{}
+	for _, c := range changes {
+		fields[c.Entity+"."+c.Field] = c
+	}
+	if c, ok := fields["work_item.status"]; !ok || c.To != string(WorkItemRunning) {
+		t.Fatalf("expected work item status change, got %+v", changes)
+	}
+	if c, ok := fields["action.status"]; !ok || c.ActionID != 3 || c.To != string(ActionDone) {
+		t.Fatalf("expected action status change, got %+v", changes)
+	}
+	if c, ok := fields["action.gate_verdict"]; !ok || c.From != nil || c.To != "pass" {
+		t.Fatalf("expected gate verdict change, got %+v", changes)
+	}
+}
@@ -0,0 +1,178 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// User is a human account that signs in to the web console with a local
+// password. Admin users bypass project roles.
+type User struct {
+	ID          int64  `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name,omitempty"`
+	// PasswordHash is a bcrypt hash. It is never serialised.
+	PasswordHash string     `json:"-"`
+	Admin        bool       `json:"admin"`
+	Disabled     bool       `json:"disabled"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// userRefPrefix marks thread participant and owner IDs that point at a User.
+const userRefPrefix = "user:"
+
+// UserRef is the participant ID used for a user in threads ("user:42").
+func UserRef(id int64) string {
+	return userRefPrefix + strconv.FormatInt(id, 10)
+}
+
+// ParseUserRef returns the user ID of a UserRef, or false for free-form
+// participant IDs.
+func ParseUserRef(ref string) (int64, bool) {
+	raw, ok := strings.CutPrefix(strings.TrimSpace(ref), userRefPrefix)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// UserSession is a console login. Only the SHA-256 of the cookie value is
+// stored.
+type UserSession struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"user_id"`
+	TokenHash  string    `json:"-"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// UserIdentity links a User to an account at an external identity provider,
+// e.g. an OIDC issuer and subject. Users provisioned this way have no local
+// password.
+type UserIdentity struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email,omitempty"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ProjectRole is a user's role within one project. Roles are cumulative:
+// each one grants the scopes of the roles before it.
+type ProjectRole string
+
+const (
+	ProjectRoleViewer   ProjectRole = "viewer"
+	ProjectRoleOperator ProjectRole = "operator"
+	ProjectRoleApprover ProjectRole = "approver"
+	ProjectRoleAdmin    ProjectRole = "admin"
+)
+
+// Scopes in "resource:action" form checked against project roles, in
+// addition to the token scopes documented on config.TokenEntry.
+const (
+	ScopeGatesApprove = "gates:approve"
+	ScopeMembersRead  = "members:read"
+	ScopeMembersWrite = "members:write"
+	ScopeSecretsRead  = "secrets:read"
+	ScopeSecretsWrite = "secrets:write"
+)
+
+var projectRoleScopes = map[ProjectRole][]string{
+	ProjectRoleViewer: {
+		"projects:read", "issues:read", "runs:read", "chat:read", ScopeMembersRead,
+	},
+	ProjectRoleOperator: {
+		"issues:write", "runs:write", "chat:write",
+	},
+	ProjectRoleApprover: {
+		ScopeGatesApprove,
+	},
+	ProjectRoleAdmin: {
+		"projects:write", ScopeMembersWrite, ScopeSecretsRead, ScopeSecretsWrite,
+	},
+}
+
+var projectRoleOrder = []ProjectRole{ProjectRoleViewer, ProjectRoleOperator, ProjectRoleApprover, ProjectRoleAdmin}
+
+// ParseProjectRole validates a role name.
+func ParseProjectRole(s string) (ProjectRole, error) {
+	role := ProjectRole(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := projectRoleScopes[role]; !ok {
+		return "", fmt.Errorf("invalid project role %q (want viewer, operator, approver or admin)", s)
+	}
+	return role, nil
+}
+
+// Scopes returns every scope the role grants, including inherited ones.
+func (r ProjectRole) Scopes() []string {
+	var out []string
+	for _, role := range projectRoleOrder {
+		out = append(out, projectRoleScopes[role]...)
+		if role == r {
+			return out
+		}
+	}
+	return nil
+}
+
+// Allows reports whether the role grants scope.
+func (r ProjectRole) Allows(scope string) bool {
+	return slices.Contains(r.Scopes(), scope)
+}
+
+// AtLeast reports whether r includes every scope of other.
+func (r ProjectRole) AtLeast(other ProjectRole) bool {
+	return slices.Index(projectRoleOrder, r) >= slices.Index(projectRoleOrder, other)
+}
+
+// ProjectMember grants a user a role in a project.
+type ProjectMember struct {
+	ProjectID int64       `json:"project_id"`
+	UserID    int64       `json:"user_id"`
+	Role      ProjectRole `json:"role"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// UserStore persists users, their sessions and project memberships.
+type UserStore interface {
+	CreateUser(ctx context.Context, u *User) (int64, error)
+	GetUser(ctx context.Context, id int64) (*User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	ListUsers(ctx context.Context) ([]*User, error)
+	UpdateUser(ctx context.Context, u *User) error
+
+	CreateUserSession(ctx context.Context, s *UserSession) (int64, error)
+	GetUserSessionByTokenHash(ctx context.Context, tokenHash string) (*UserSession, error)
+	DeleteUserSession(ctx context.Context, id int64) error
+	// DeleteUserSessions ends every session of a user, e.g. after a password
+	// change or when the account is disabled.
+	DeleteUserSessions(ctx context.Context, userID int64) error
+	ListUserSessions(ctx context.Context, userID int64) ([]*UserSession, error)
+
+	CreateUserIdentity(ctx context.Context, id *UserIdentity) (int64, error)
+	GetUserIdentity(ctx context.Context, provider, subject string) (*UserIdentity, error)
+	UpdateUserIdentity(ctx context.Context, id *UserIdentity) error
+	ListUserIdentities(ctx context.Context, userID int64) ([]*UserIdentity, error)
+
+	// UpsertProjectMember sets the user's role in the project.
+	UpsertProjectMember(ctx context.Context, m *ProjectMember) error
+	DeleteProjectMember(ctx context.Context, projectID, userID int64) error
+	ListProjectMembers(ctx context.Context, projectID int64) ([]*ProjectMember, error)
+	ListUserMemberships(ctx context.Context, userID int64) ([]*ProjectMember, error)
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VaultSecret is a credential kept in the encrypted secret vault. Secrets are
+// scoped to a project and an agent profile; ProjectID 0 and an empty
+// ProfileID apply to every project and profile, and the most specific secret
+// of a name wins. Only the ciphertext is stored.
+type VaultSecret struct {
+	ID          int64  `json:"id"`
+	ProjectID   int64  `json:"project_id,omitempty"`
+	ProfileID   string `json:"profile_id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Ciphertext  []byte `json:"-"`
+	// KeyID fingerprints the master key the secret was sealed with.
+	KeyID     string    `json:"key_id"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Specificity orders secrets of the same name: project and profile scoped
+// beats project scoped, which beats profile scoped, which beats global.
+func (s *VaultSecret) Specificity() int {
+	n := 0
+	if s.ProjectID != 0 {
+		n += 2
+	}
+	if s.ProfileID != "" {
+		n++
+	}
+	return n
+}
+
+// VaultSecretFilter constrains ListVaultSecrets. Nil fields match any value.
+type VaultSecretFilter struct {
+	ProjectID *int64
+	ProfileID *string
+	Name      string
+}
+
+// VaultStore persists encrypted vault secrets.
+type VaultStore interface {
+	// UpsertVaultSecret replaces the secret with the same project, profile
+	// and name, keeping its ID and CreatedAt.
+	UpsertVaultSecret(ctx context.Context, s *VaultSecret) (int64, error)
+	GetVaultSecret(ctx context.Context, id int64) (*VaultSecret, error)
+	ListVaultSecrets(ctx context.Context, filter VaultSecretFilter) ([]*VaultSecret, error)
+	// ListVaultSecretsInScope returns every secret visible to profileID in
+	// projectID, including global and cross-profile ones.
+	ListVaultSecretsInScope(ctx context.Context, projectID int64, profileID string) ([]*VaultSecret, error)
+	DeleteVaultSecret(ctx context.Context, id int64) error
+}
+
+// SecretResolver returns the decrypted vault secrets, by name, visible to a
+// profile running on a work item.
+type SecretResolver interface {
+	ResolveSecrets(ctx context.Context, workItemID int64, profileID string) (map[string]string, error)
+}
+
+// VaultRefPrefix starts a vault reference in auth_secret_ref, e.g.
+// "vault.NPM_TOKEN". In env and config values secrets are referenced as
+// "${vault.NPM_TOKEN}".
+const VaultRefPrefix = "vault."
+
+var (
+	vaultNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	vaultRefPattern  = regexp.MustCompile(`\$\{vault\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// ValidVaultSecretName reports whether name can be used as a secret name,
+// which doubles as an environment variable name.
+func ValidVaultSecretName(name string) bool {
+	return len(name) <= 128 && vaultNamePattern.MatchString(name)
+}
+
+// VaultPlaceholder returns the "${vault.NAME}" reference to a secret.
+func VaultPlaceholder(name string) string {
+	return "${" + VaultRefPrefix + name + "}"
+}
+
+// HasVaultRefs reports whether s references a vault secret.
+func HasVaultRefs(s string) bool {
+	return strings.Contains(s, "${"+VaultRefPrefix) && vaultRefPattern.MatchString(s)
+}
+
+// VaultRefNames returns the secret names referenced in values, sorted and
+// without duplicates.
+func VaultRefNames(values ...string) []string {
+	seen := map[string]bool{}
+	for _, v := range values {
+		for _, m := range vaultRefPattern.FindAllStringSubmatch(v, -1) {
+			seen[m[1]] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandVaultRefs replaces every "${vault.NAME}" in s with the secret value.
+// It fails on the first reference missing from secrets.
+func ExpandVaultRefs(s string, secrets map[string]string) (string, error) {
+	if !HasVaultRefs(s) {
+		return s, nil
+	}
+	var missing string
+	out := vaultRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := vaultRefPattern.FindStringSubmatch(ref)[1]
+		value, ok := secrets[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("vault secret %q is not defined for this project and profile", missing)
+	}
+	return out, nil
+}
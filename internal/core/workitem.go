@@ -148,6 +148,9 @@ type WorkItem struct {
 	EscalationPath     []string       `json:"escalation_path,omitempty"`
 	Metadata           map[string]any `json:"metadata,omitempty"`
 
+	// Version is bumped on every write; see VersionConflictError.
+	Version int64 `json:"version"`
+
 	ArchivedAt *time.Time `json:"archived_at,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at"`
@@ -3,6 +3,7 @@ package appcmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/yoke233/zhanggui/internal/platform/config"
@@ -170,6 +171,69 @@ func TestResolveExecutorNATSURLPriority(t *testing.T) {
 	}
 }
 
+func TestResolveExecutorNATSConnUsesEmbeddedCredentials(t *testing.T) {
+	t.Setenv("AI_WORKFLOW_NATS_URL", "")
+	t.Setenv("AI_WORKFLOW_NATS_CREDS", "")
+	dataDir := t.TempDir()
+	storeDir := filepath.Join(dataDir, "nats")
+	if err := os.MkdirAll(storeDir, 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	creds := `{"url":"nats://127.0.0.1:4333","user":"ai-flow","password":"secret"}`
+	if err := os.WriteFile(filepath.Join(storeDir, "credentials.json"), []byte(creds), 0o600); err != nil {
+		t.Fatalf("WriteFile(credentials.json) error = %v", err)
+	}
+
+	cfg := config.Defaults()
+	cfg.Runtime.SessionManager.NATS.Embedded = true
+
+	url, opts, err := resolveExecutorNATSConn(executorCLIOptions{}, &cfg, dataDir)
+	if err != nil {
+		t.Fatalf("resolveExecutorNATSConn() error = %v", err)
+	}
+	if url != "nats://127.0.0.1:4333" || len(opts) != 1 {
+		t.Fatalf("resolveExecutorNATSConn() = %q, %d opts", url, len(opts))
+	}
+
+	url, _, err = resolveExecutorNATSConn(executorCLIOptions{natsURL: "nats://lan-host:4222"}, &cfg, dataDir)
+	if err != nil || url != "nats://lan-host:4222" {
+		t.Fatalf("explicit URL should override credentials URL, got %q, %v", url, err)
+	}
+
+	if _, _, err := resolveExecutorNATSConn(executorCLIOptions{natsCreds: filepath.Join(dataDir, "missing.json")}, &cfg, dataDir); err == nil {
+		t.Fatal("expected error for missing explicit credentials file")
+	}
+}
+
+func TestResolveExecutorNATSConnRejectsWildcardURL(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	storeDir := filepath.Join(dataDir, "nats")
+	if err := os.MkdirAll(storeDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	credsPath := filepath.Join(storeDir, "credentials.json")
+	creds := `{"url":"nats://0.0.0.0:4333","user":"ai-flow","password":"secret"}`
+	if err := os.WriteFile(credsPath, []byte(creds), 0o600); err != nil {
+		t.Fatalf("WriteFile(credentials.json) error = %v", err)
+	}
+
+	// A copied credentials file on another host needs an explicit URL.
+	if _, _, err := resolveExecutorNATSConn(executorCLIOptions{natsCreds: credsPath}, nil, dataDir); err == nil || !strings.Contains(err.Error(), "--nats-url") {
+		t.Fatalf("expected an error asking for --nats-url, got %v", err)
+	}
+
+	// On the server's own host the loopback address is used.
+	cfg := config.Defaults()
+	cfg.Runtime.SessionManager.NATS.Embedded = true
+	cfg.Runtime.SessionManager.NATS.EmbeddedListen = "0.0.0.0:4333"
+	url, _, err := resolveExecutorNATSConn(executorCLIOptions{}, &cfg, dataDir)
+	if err != nil || url != "nats://127.0.0.1:4333" {
+		t.Fatalf("resolveExecutorNATSConn() = %q, %v, want the loopback URL", url, err)
+	}
+}
+
 func TestBuildServerBaseURLFallsBackToLoopback(t *testing.T) {
 	t.Parallel()
 
@@ -177,3 +241,223 @@ func TestBuildServerBaseURLFallsBackToLoopback(t *testing.T) {
 		t.Fatalf("buildServerBaseURL() = %q, want %q", got, "http://127.0.0.1:8080")
 	}
 }
+
+func TestParseExecutorArgsLabels(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseExecutorArgs([]string{"--labels", "gpu=true, region = eu"})
+	if err != nil {
+		t.Fatalf("parseExecutorArgs() error = %v", err)
+	}
+	if opts.labels["gpu"] != "true" || opts.labels["region"] != "eu" {
+		t.Fatalf("labels = %#v", opts.labels)
+	}
+	if _, err := parseExecutorArgs([]string{"--labels=gpu"}); err == nil {
+		t.Fatal("expected error for label without value separator")
+	}
+}
+
+func TestParseExecutorArgsWorkerID(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseExecutorArgs([]string{"--worker-id", " exec-1 "})
+	if err != nil {
+		t.Fatalf("parseExecutorArgs() error = %v", err)
+	}
+	if opts.workerID != "exec-1" {
+		t.Fatalf("workerID = %q, want exec-1", opts.workerID)
+	}
+	opts, err = parseExecutorArgs(nil)
+	if err != nil {
+		t.Fatalf("parseExecutorArgs() error = %v", err)
+	}
+	if opts.workerID != "" {
+		t.Fatalf("default workerID = %q, want empty so the worker falls back to hostname-pid", opts.workerID)
+	}
+	if _, err := parseExecutorArgs([]string{"--worker-id"}); err == nil {
+		t.Fatal("expected error for --worker-id without value")
+	}
+}
+
+func TestParseDriverDoctorArgs(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseDriverDoctorArgs([]string{"codex-acp", "--json", "--step-timeout", "30s"})
+	if err != nil {
+		t.Fatalf("parseDriverDoctorArgs() error = %v", err)
+	}
+	if opts.DriverID != "codex-acp" || !opts.JSON || opts.StepTimeout.String() != "30s" {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	opts, err = parseDriverDoctorArgs([]string{"--json", "claude-acp"})
+	if err != nil || opts.DriverID != "claude-acp" {
+		t.Fatalf("flags before driver id: %+v, %v", opts, err)
+	}
+	if _, err := parseDriverDoctorArgs(nil); err == nil {
+		t.Fatal("expected error when driver id is missing")
+	}
+}
+
+func TestParseTraceArgs(t *testing.T) {
+	t.Parallel()
+
+	exportOpts, err := parseTraceExportArgs([]string{"--run", "42", "--out", "fx.json"})
+	if err != nil || exportOpts.RunID != 42 || exportOpts.Out != "fx.json" {
+		t.Fatalf("parseTraceExportArgs() = %+v, %v", exportOpts, err)
+	}
+	if _, err := parseTraceExportArgs([]string{"--run", "abc"}); err == nil {
+		t.Fatal("expected error for invalid run id")
+	}
+
+	recordOpts, err := parseTraceRecordArgs([]string{"--driver", "codex-acp", "--prompt", "one", "--prompt", "two", "--timeout", "1m"})
+	if err != nil {
+		t.Fatalf("parseTraceRecordArgs() error = %v", err)
+	}
+	if recordOpts.DriverID != "codex-acp" || len(recordOpts.Prompts) != 2 || recordOpts.Prompts[1] != "two" || recordOpts.Timeout.String() != "1m0s" {
+		t.Fatalf("unexpected record options: %+v", recordOpts)
+	}
+	if _, err := parseTraceRecordArgs([]string{"--driver", "codex-acp"}); err == nil {
+		t.Fatal("expected error when no prompt is given")
+	}
+}
+
+func TestParseDBPruneArgs(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseDBPruneArgs([]string{"--dry-run"})
+	if err != nil || !opts.DryRun || opts.Vacuum {
+		t.Fatalf("parseDBPruneArgs() = %+v, %v", opts, err)
+	}
+	if _, err := parseDBPruneArgs([]string{"--dry-run", "--vacuum"}); err == nil {
+		t.Fatal("expected error when combining --dry-run and --vacuum")
+	}
+	if _, err := parseDBPruneArgs([]string{"extra"}); err == nil {
+		t.Fatal("expected error for positional arguments")
+	}
+}
+
+func TestParseDBMigrateArgs(t *testing.T) {
+	t.Parallel()
+
+	if opts, err := parseDBMigrateArgs(nil); err != nil || opts.Action != "status" {
+		t.Fatalf("parseDBMigrateArgs(nil) = %+v, %v", opts, err)
+	}
+	if opts, err := parseDBMigrateArgs([]string{"to", "1"}); err != nil || opts.Action != "to" || opts.Target != 1 {
+		t.Fatalf("parseDBMigrateArgs(to 1) = %+v, %v", opts, err)
+	}
+	if opts, err := parseDBMigrateArgs([]string{"to", "0", "--force"}); err != nil || opts.Target != 0 || !opts.Force {
+		t.Fatalf("parseDBMigrateArgs(to 0 --force) = %+v, %v", opts, err)
+	}
+	for _, args := range [][]string{{"to"}, {"to", "-1"}, {"up", "2"}, {"sideways"}} {
+		if _, err := parseDBMigrateArgs(args); err == nil {
+			t.Fatalf("parseDBMigrateArgs(%v) expected error", args)
+		}
+	}
+}
+
+func TestParseBackupArgs(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseBackupCreateArgs([]string{"--out", "/tmp/b", "--exclude-secrets"})
+	if err != nil || opts.Out != "/tmp/b" || !opts.ExcludeSecrets {
+		t.Fatalf("parseBackupCreateArgs() = %+v, %v", opts, err)
+	}
+	if restore, err := parseBackupRestoreArgs([]string{"backup.tar.gz"}); err != nil || restore.Archive != "backup.tar.gz" {
+		t.Fatalf("parseBackupRestoreArgs() = %+v, %v", restore, err)
+	}
+	for _, args := range [][]string{nil, {"a", "b"}, {"--force"}} {
+		if _, err := parseBackupRestoreArgs(args); err == nil {
+			t.Fatalf("parseBackupRestoreArgs(%v) expected error", args)
+		}
+	}
+}
+
+func TestParseTokenArgs(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseTokenCreateArgs([]string{"--name", "ci", "--scopes", "issues:write, runs:read", "--projects", "backend", "--expires-in", "0"})
+	if err != nil || opts.Name != "ci" || len(opts.Scopes) != 2 || opts.Scopes[1] != "runs:read" || opts.Projects[0] != "backend" || opts.ExpiresIn != "0" {
+		t.Fatalf("parseTokenCreateArgs() = %+v, %v", opts, err)
+	}
+	for _, args := range [][]string{
+		{"--name", "ci"},
+		{"--scopes", "*"},
+		{"--name", "ci", "--scopes", "*", "--expires-in", "1h", "--expires-at", "2030-01-01T00:00:00Z"},
+	} {
+		if _, err := parseTokenCreateArgs(args); err == nil {
+			t.Fatalf("parseTokenCreateArgs(%v) expected error", args)
+		}
+	}
+	if id, err := parseTokenRevokeArgs([]string{"7"}); err != nil || id != 7 {
+		t.Fatalf("parseTokenRevokeArgs() = %d, %v", id, err)
+	}
+	if _, err := parseTokenRevokeArgs([]string{"abc"}); err == nil {
+		t.Fatalf("parseTokenRevokeArgs(abc) expected error")
+	}
+}
+
+func TestParseSecretArgs(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseSecretSetArgs([]string{"--name", "NPM_TOKEN", "--project", "3", "--profile", "worker"})
+	if err != nil || opts.Name != "NPM_TOKEN" || opts.ProjectID != 3 || opts.ProfileID != "worker" {
+		t.Fatalf("parseSecretSetArgs() = %+v, %v", opts, err)
+	}
+	for _, args := range [][]string{
+		{"--project", "3"},
+		{"--name", "npm-token"},
+		{"--name", "NPM_TOKEN", "--project", "-1"},
+		{"--name", "NPM_TOKEN", "value"},
+	} {
+		if _, err := parseSecretSetArgs(args); err == nil {
+			t.Fatalf("parseSecretSetArgs(%v) expected error", args)
+		}
+	}
+	list, err := parseSecretListArgs([]string{"--project", "0"})
+	if err != nil || !list.HasProject || list.ProjectID != 0 || list.HasProfile {
+		t.Fatalf("parseSecretListArgs() = %+v, %v", list, err)
+	}
+	if id, err := parseSecretDeleteArgs([]string{"4"}); err != nil || id != 4 {
+		t.Fatalf("parseSecretDeleteArgs() = %d, %v", id, err)
+	}
+}
+
+func TestParseProvenanceVerifyArgs(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseProvenanceVerifyArgs([]string{"3F786850E387", "--repo", "/src/app", "--json"})
+	if err != nil || opts.SHA != "3f786850e387" || opts.Repo != "/src/app" || !opts.JSON {
+		t.Fatalf("parseProvenanceVerifyArgs() = %+v, %v", opts, err)
+	}
+	opts, err = parseProvenanceVerifyArgs([]string{"--json", "3f786850"})
+	if err != nil || opts.SHA != "3f786850" || opts.Repo != "." {
+		t.Fatalf("parseProvenanceVerifyArgs(flags first) = %+v, %v", opts, err)
+	}
+	for _, args := range [][]string{
+		{},
+		{"--json"},
+		{"main"},
+		{"3f786850", "extra"},
+	} {
+		if _, err := parseProvenanceVerifyArgs(args); err == nil {
+			t.Fatalf("parseProvenanceVerifyArgs(%v) expected error", args)
+		}
+	}
+}
+
+func TestParseAuditVerifyArgs(t *testing.T) {
+	t.Parallel()
+
+	opts, err := parseAuditVerifyArgs([]string{"--journal-only", "--json"})
+	if err != nil || !opts.JournalOnly || opts.FilesOnly || !opts.JSON {
+		t.Fatalf("parseAuditVerifyArgs() = %+v, %v", opts, err)
+	}
+	for _, args := range [][]string{
+		{"--journal-only", "--files-only"},
+		{"extra"},
+	} {
+		if _, err := parseAuditVerifyArgs(args); err == nil {
+			t.Fatalf("parseAuditVerifyArgs(%v) expected error", args)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package appcmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/audit"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+const auditUsage = `usage:
+  ai-flow audit verify [--journal-only | --files-only] [--json]`
+
+type auditVerifyOptions struct {
+	JournalOnly bool
+	FilesOnly   bool
+	JSON        bool
+}
+
+// RunAudit checks the tamper-evident audit records.
+func RunAudit(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", auditUsage)
+	}
+	switch strings.TrimSpace(args[0]) {
+	case "verify":
+		opts, err := parseAuditVerifyArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runAuditVerify(opts)
+	default:
+		return fmt.Errorf("unknown audit command: %s", args[0])
+	}
+}
+
+func parseAuditVerifyArgs(args []string) (auditVerifyOptions, error) {
+	var opts auditVerifyOptions
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.JournalOnly, "journal-only", false, "Verify the activity journal only")
+	fs.BoolVar(&opts.FilesOnly, "files-only", false, "Verify the run audit JSONL files only")
+	fs.BoolVar(&opts.JSON, "json", false, "Emit JSON output")
+	if err := fs.Parse(args); err != nil {
+		return auditVerifyOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return auditVerifyOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if opts.JournalOnly && opts.FilesOnly {
+		return auditVerifyOptions{}, fmt.Errorf("--journal-only cannot be combined with --files-only")
+	}
+	return opts, nil
+}
+
+// runAuditVerify walks the journal chain and its checkpoints, then every run
+// audit file, and fails when any chain is broken.
+func runAuditVerify(opts auditVerifyOptions) error {
+	cfg, dataDir, runtimeDBPath, err := resolveRuntimeDBPath()
+	if err != nil {
+		return err
+	}
+	var reports []*core.ChainReport
+	if !opts.FilesOnly {
+		store, err := sqlite.New(runtimeDBPath)
+		if err != nil {
+			return fmt.Errorf("open runtime store: %w", err)
+		}
+		var key []byte
+		if k := strings.TrimSpace(cfg.Audit.Checkpoint.Key); k != "" {
+			key = []byte(k)
+		}
+		report, err := store.VerifyJournalChain(context.Background(), key)
+		store.Close()
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+	if !opts.JournalOnly {
+		fileReports, err := audit.VerifyRunAuditDir(audit.ResolveRootDir(dataDir, cfg.Audit.FallbackDir))
+		if err != nil {
+			return err
+		}
+		reports = append(reports, fileReports...)
+	}
+
+	broken := 0
+	for _, r := range reports {
+		if !r.OK() {
+			broken++
+		}
+	}
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	} else {
+		printAuditReports(reports)
+	}
+	if broken > 0 {
+		return fmt.Errorf("audit verification failed: %d of %d chains broken", broken, len(reports))
+	}
+	return nil
+}
+
+func printAuditReports(reports []*core.ChainReport) {
+	files := 0
+	for _, r := range reports {
+		isJournal := r.Source == "activity_journal"
+		switch {
+		case !r.OK():
+			fmt.Printf("BROKEN %s: %s\n", r.Source, r.Break.Error())
+		case !isJournal:
+			// Intact files are summarized below.
+			files++
+		default:
+			fmt.Printf("ok     %s: %d entries (seq %d..%d), %d checkpoints\n", r.Source, r.Entries, r.FirstSeq, r.LastSeq, r.Checkpoints)
+			if r.FirstSeq > 1 {
+				fmt.Printf("       entries before seq %d were pruned by retention\n", r.FirstSeq)
+			}
+		}
+		if isJournal && !r.SignaturesChecked {
+			fmt.Fprintln(os.Stderr, "warning: no [audit] checkpoint_key in secrets; checkpoint signatures were not checked")
+		}
+	}
+	if files > 0 {
+		fmt.Printf("ok     %d run audit files\n", files)
+	}
+}
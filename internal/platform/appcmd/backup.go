@@ -0,0 +1,153 @@
+package appcmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/yoke233/zhanggui/internal/platform/backup"
+)
+
+const backupUsage = `usage:
+  ai-flow backup create [--out dir] [--exclude-secrets]
+  ai-flow backup list
+  ai-flow backup restore <archive>   (stop the server first)`
+
+type backupCreateOptions struct {
+	Out            string
+	ExcludeSecrets bool
+}
+
+type backupRestoreOptions struct {
+	Archive string
+}
+
+func RunBackup(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", backupUsage)
+	}
+	switch strings.TrimSpace(args[0]) {
+	case "create":
+		opts, err := parseBackupCreateArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runBackupCreate(opts)
+	case "list":
+		if len(args) > 1 {
+			return fmt.Errorf("unexpected arguments: %s", strings.Join(args[1:], " "))
+		}
+		return runBackupList()
+	case "restore":
+		opts, err := parseBackupRestoreArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runBackupRestore(opts)
+	default:
+		return fmt.Errorf("unknown backup command: %s", args[0])
+	}
+}
+
+func parseBackupCreateArgs(args []string) (backupCreateOptions, error) {
+	var opts backupCreateOptions
+	fs := flag.NewFlagSet("backup create", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.Out, "out", "", "Output directory (default store.backup.dir)")
+	fs.BoolVar(&opts.ExcludeSecrets, "exclude-secrets", false, "Leave secrets.toml/secrets.yaml and key files out of the archive")
+	if err := fs.Parse(args); err != nil {
+		return backupCreateOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return backupCreateOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return opts, nil
+}
+
+func parseBackupRestoreArgs(args []string) (backupRestoreOptions, error) {
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		return backupRestoreOptions{}, fmt.Errorf("backup restore requires exactly one archive path")
+	}
+	return backupRestoreOptions{Archive: strings.TrimSpace(args[0])}, nil
+}
+
+func runBackupCreate(opts backupCreateOptions) error {
+	cfg, dataDir, runtimeDBPath, err := resolveRuntimeDBPath()
+	if err != nil {
+		return err
+	}
+	outDir := backup.ResolveDir(cfg.Store.Backup.Dir, dataDir)
+	if strings.TrimSpace(opts.Out) != "" {
+		outDir = opts.Out
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := backup.Create(ctx, backup.Options{
+		DataDir:        dataDir,
+		DBPath:         runtimeDBPath,
+		OutDir:         outDir,
+		ExcludeSecrets: opts.ExcludeSecrets,
+		SecretPaths:    backup.SecretPaths(cfg, dataDir),
+		Label:          "manual",
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s (%d files, %d bytes, schema version %d)\n",
+		result.Path, len(result.Manifest.Files), result.Size, result.Manifest.Database.SchemaVersion)
+	return nil
+}
+
+func runBackupList() error {
+	cfg, dataDir, _, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	list, err := backup.List(backup.ResolveDir(cfg.Store.Backup.Dir, dataDir))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
+
+func runBackupRestore(opts backupRestoreOptions) error {
+	cfg, dataDir, runtimeDBPath, err := resolveRuntimeDBPath()
+	if err != nil {
+		return err
+	}
+	archive, err := filepath.Abs(opts.Archive)
+	if err != nil {
+		return err
+	}
+	var preserve []string
+	if rel, err := filepath.Rel(dataDir, backup.ResolveDir(cfg.Store.Backup.Dir, dataDir)); err == nil && !strings.HasPrefix(rel, "..") {
+		preserve = append(preserve, filepath.ToSlash(rel))
+	}
+	result, err := backup.Restore(context.Background(), backup.RestoreOptions{
+		Archive:  archive,
+		DataDir:  dataDir,
+		DBPath:   runtimeDBPath,
+		Preserve: preserve,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored backup from %s (schema version %d)\n", result.Manifest.CreatedAt.Format("2006-01-02 15:04:05Z07:00"), result.Manifest.Database.SchemaVersion)
+	if result.PreviousDataDir != "" {
+		fmt.Printf("previous data dir kept at %s\n", result.PreviousDataDir)
+	}
+	if result.PreviousDBPath != "" {
+		fmt.Printf("previous database kept at %s\n", result.PreviousDBPath)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package appcmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	retentionapp "github.com/yoke233/zhanggui/internal/application/retention"
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+const dbUsage = `usage:
+  ai-flow db migrate status|up|down|to <version> [--force]
+  ai-flow db prune [--dry-run] [--vacuum]`
+
+type dbMigrateOptions struct {
+	Action string // status, up, down or to
+	Target int    // for "to"
+	// Force allows reverting the baseline (version 1), which drops every
+	// table. A snapshot of the database is written first.
+	Force bool
+}
+
+type dbPruneOptions struct {
+	DryRun bool
+	Vacuum bool
+}
+
+func RunDB(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", dbUsage)
+	}
+	switch strings.TrimSpace(args[0]) {
+	case "migrate":
+		opts, err := parseDBMigrateArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runDBMigrate(opts)
+	case "prune":
+		opts, err := parseDBPruneArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runDBPrune(opts)
+	default:
+		return fmt.Errorf("unknown db command: %s", args[0])
+	}
+}
+
+func parseDBMigrateArgs(args []string) (dbMigrateOptions, error) {
+	if len(args) == 0 {
+		return dbMigrateOptions{Action: "status"}, nil
+	}
+	opts := dbMigrateOptions{Action: strings.TrimSpace(args[0])}
+	var rest []string
+	for _, arg := range args[1:] {
+		if arg == "--force" {
+			opts.Force = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	switch opts.Action {
+	case "status", "up", "down":
+		if len(rest) > 0 {
+			return dbMigrateOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(rest, " "))
+		}
+	case "to":
+		if len(rest) != 1 {
+			return dbMigrateOptions{}, fmt.Errorf("db migrate to requires exactly one version")
+		}
+		v, err := strconv.Atoi(strings.TrimSpace(rest[0]))
+		if err != nil || v < 0 {
+			return dbMigrateOptions{}, fmt.Errorf("invalid schema version %q", rest[0])
+		}
+		opts.Target = v
+	default:
+		return dbMigrateOptions{}, fmt.Errorf("unknown db migrate command: %s", opts.Action)
+	}
+	return opts, nil
+}
+
+func parseDBPruneArgs(args []string) (dbPruneOptions, error) {
+	var opts dbPruneOptions
+	fs := flag.NewFlagSet("db prune", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "Report expired data without deleting it")
+	fs.BoolVar(&opts.Vacuum, "vacuum", false, "Run VACUUM after pruning")
+	if err := fs.Parse(args); err != nil {
+		return dbPruneOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return dbPruneOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if opts.DryRun && opts.Vacuum {
+		return dbPruneOptions{}, fmt.Errorf("--vacuum cannot be combined with --dry-run")
+	}
+	return opts, nil
+}
+
+func runDBMigrate(opts dbMigrateOptions) error {
+	_, _, runtimeDBPath, err := resolveRuntimeDBPath()
+	if err != nil {
+		return err
+	}
+	store, err := sqlite.NewWithOptions(runtimeDBPath, sqlite.OpenOptions{SkipMigrations: true})
+	if err != nil {
+		return fmt.Errorf("open runtime store: %w", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	current, err := store.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	target := current
+	switch opts.Action {
+	case "status":
+		return printMigrationStatus(ctx, store, runtimeDBPath)
+	case "up":
+		target = sqlite.LatestSchemaVersion()
+	case "down":
+		if current == 0 {
+			return fmt.Errorf("database is already at schema version 0")
+		}
+		target = current - 1
+	case "to":
+		target = opts.Target
+	}
+	if target < 1 && current >= 1 {
+		if !opts.Force {
+			return fmt.Errorf("reverting schema version 1 drops every table; rerun with --force to continue (a snapshot is written first)")
+		}
+		snapshot := runtimeDBPath + ".pre-migrate-" + time.Now().UTC().Format("20060102T150405Z")
+		if err := store.VacuumInto(ctx, snapshot); err != nil {
+			return err
+		}
+		fmt.Printf("snapshot written to %s\n", snapshot)
+	}
+	steps, err := store.MigrateTo(ctx, target)
+	for _, step := range steps {
+		verb := "applied"
+		if step.Down {
+			verb = "reverted"
+		}
+		fmt.Printf("%s %d %s\n", verb, step.Version, step.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		fmt.Printf("schema already at version %d\n", target)
+	}
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, store *sqlite.Store, path string) error {
+	status, err := store.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := store.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("database: %s\nschema version: %d (binary latest: %d)\n", path, current, sqlite.LatestSchemaVersion())
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, st := range status {
+		applied := "pending"
+		if st.AppliedAt != nil {
+			applied = st.AppliedAt.Local().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", st.Version, st.Name, applied)
+	}
+	return w.Flush()
+}
+
+// resolveRuntimeDBPath returns the loaded config, data dir and runtime DB path.
+func resolveRuntimeDBPath() (*config.Config, string, string, error) {
+	cfg, dataDir, _, err := LoadConfig()
+	if err != nil {
+		return nil, "", "", err
+	}
+	storePath := ExpandStorePath(cfg.Store.Path, dataDir)
+	return cfg, dataDir, strings.TrimSuffix(storePath, filepath.Ext(storePath)) + "_runtime.db", nil
+}
+
+func runDBPrune(opts dbPruneOptions) error {
+	cfg, dataDir, runtimeDBPath, err := resolveRuntimeDBPath()
+	if err != nil {
+		return err
+	}
+	store, err := sqlite.New(runtimeDBPath)
+	if err != nil {
+		return fmt.Errorf("open runtime store: %w", err)
+	}
+	defer store.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	svc := retentionapp.New(store, retentionapp.ConfigFromAudit(cfg.Audit, dataDir))
+	report, err := svc.Run(ctx, retentionapp.RunOptions{DryRun: opts.DryRun, Vacuum: opts.Vacuum})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
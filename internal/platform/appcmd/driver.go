@@ -0,0 +1,148 @@
+package appcmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpdoctor"
+	"github.com/yoke233/zhanggui/internal/adapters/sandbox"
+	"github.com/yoke233/zhanggui/internal/platform/configruntime"
+)
+
+type driverDoctorOptions struct {
+	DriverID    string
+	JSON        bool
+	StepTimeout time.Duration
+}
+
+func RunDriver(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: ai-flow driver doctor <driver-id> [--json] [--step-timeout 2m]")
+	}
+	switch strings.TrimSpace(args[0]) {
+	case "doctor":
+		opts, err := parseDriverDoctorArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runDriverDoctor(os.Stdout, opts)
+	default:
+		return fmt.Errorf("unknown driver command: %s", args[0])
+	}
+}
+
+func parseDriverDoctorArgs(args []string) (driverDoctorOptions, error) {
+	var opts driverDoctorOptions
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		opts.DriverID = strings.TrimSpace(args[0])
+		args = args[1:]
+	}
+	fs := flag.NewFlagSet("driver doctor", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.JSON, "json", false, "Print the report as JSON")
+	fs.DurationVar(&opts.StepTimeout, "step-timeout", 2*time.Minute, "Timeout for each protocol step")
+	if err := fs.Parse(args); err != nil {
+		return driverDoctorOptions{}, err
+	}
+	if opts.DriverID == "" && fs.NArg() > 0 {
+		opts.DriverID = strings.TrimSpace(fs.Arg(0))
+	} else if fs.NArg() > 0 {
+		return driverDoctorOptions{}, fmt.Errorf("driver doctor accepts a single driver id")
+	}
+	if opts.DriverID == "" {
+		return driverDoctorOptions{}, fmt.Errorf("usage: ai-flow driver doctor <driver-id> [--json] [--step-timeout 2m]")
+	}
+	return opts, nil
+}
+
+func runDriverDoctor(out io.Writer, opts driverDoctorOptions) error {
+	cfg, dataDir, _, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	manager, err := configruntime.NewManager(resolveGlobalConfigFilePath(dataDir), resolveSecretsFilePath(dataDir), configruntime.DisabledMCPEnv(), nil, nil)
+	if err != nil {
+		return fmt.Errorf("open runtime manager: %w", err)
+	}
+	driver, err := manager.ResolveDriverConfig(opts.DriverID)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := acpdoctor.Run(ctx, acpdoctor.Config{
+		DriverID:    opts.DriverID,
+		Driver:      *driver,
+		Sandbox:     sandbox.NewRuntimeSandbox(manager, cfg.Runtime.Sandbox, dataDir),
+		StepTimeout: opts.StepTimeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.JSON {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else if err := writeDoctorReport(out, report); err != nil {
+		return err
+	}
+	if !report.OK {
+		return fmt.Errorf("driver %s failed conformance checks", opts.DriverID)
+	}
+	return nil
+}
+
+func writeDoctorReport(out io.Writer, report *acpdoctor.Report) error {
+	fmt.Fprintf(out, "driver %s: %s %s\n\n", report.DriverID, report.Command, strings.Join(report.Args, " "))
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	for _, check := range report.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", check.Name, check.Status, check.Detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	m := report.Matrix
+	fmt.Fprintln(out, "\ncapability matrix (observed / capabilities_max):")
+	tw = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  fs_read\t%s\t%s\n", yesNo(m.FSRead), yesNo(report.CapabilitiesMax.FSRead))
+	fmt.Fprintf(tw, "  fs_write\t%s\t%s\n", yesNo(m.FSWrite), yesNo(report.CapabilitiesMax.FSWrite))
+	fmt.Fprintf(tw, "  terminal\t%s\t%s\n", yesNo(m.Terminal), yesNo(report.CapabilitiesMax.Terminal))
+	fmt.Fprintf(tw, "  permission\t%s\t-\n", yesNo(m.Permission))
+	fmt.Fprintf(tw, "  cancel\t%s\t-\n", yesNo(m.Cancel))
+	fmt.Fprintf(tw, "  load_session\t%s\t-\n", yesNo(m.LoadSession))
+	fmt.Fprintf(tw, "  mcp_sse\t%s\t-\n", yesNo(m.MCPSSE))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(report.Warnings) > 0 {
+		fmt.Fprintln(out, "\nwarnings:")
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(out, "  - %s\n", warning)
+		}
+	}
+	return nil
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
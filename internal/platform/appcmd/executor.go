@@ -2,19 +2,26 @@ package appcmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/exec"
 	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	v2sandbox "github.com/yoke233/zhanggui/internal/adapters/sandbox"
 	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/application/vaultapp"
+	"github.com/yoke233/zhanggui/internal/core"
 	"github.com/yoke233/zhanggui/internal/platform/bootstrap"
 	"github.com/yoke233/zhanggui/internal/platform/config"
+	"github.com/yoke233/zhanggui/internal/platform/natsembed"
 	agentruntime "github.com/yoke233/zhanggui/internal/runtime/agent"
 )
 
@@ -29,14 +36,22 @@ func RunExecutor(args []string) error {
 	if err != nil {
 		return err
 	}
+	dirLock, err := lockDataDir(dataDir)
+	if err != nil {
+		return err
+	}
+	defer dirLock.Release()
 	closeLog, err := InitAppLogger(dataDir, "executor")
 	if err != nil {
 		return err
 	}
 	defer closeLog()
-	natsURL := resolveExecutorNATSURL(opts.natsURL, cfg)
+	natsURL, natsOpts, err := resolveExecutorNATSConn(opts, cfg, dataDir)
+	if err != nil {
+		return err
+	}
 	if natsURL == "" {
-		return fmt.Errorf("--nats-url is required (or set AI_WORKFLOW_NATS_URL or runtime.session_manager.nats.url)")
+		return fmt.Errorf("--nats-url is required (or set AI_WORKFLOW_NATS_URL, runtime.session_manager.nats.url, or --nats-creds)")
 	}
 	dbPath := ExpandStorePath(cfg.Store.Path, dataDir)
 	runtimeDBPath := strings.TrimSuffix(dbPath, ".db") + "_runtime.db"
@@ -46,7 +61,15 @@ func RunExecutor(args []string) error {
 	}
 	defer store.Close()
 	bootstrap.SeedRegistry(context.Background(), store, cfg)
-	nc, err := nats.Connect(natsURL, nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1), nats.ReconnectWait(2*time.Second))
+	// Agent output is redacted in this process too, so it needs the vault
+	// values even though secrets are set through the server.
+	vault := vaultapp.New(vaultapp.Config{Store: store, KeyFile: vaultapp.ResolveKeyFile(dataDir, cfg.Vault.KeyFile)})
+	if err := vault.RegisterValues(ctx); err != nil {
+		slog.Warn("executor: failed to register vault secrets for redaction", "error", err)
+	}
+	go vault.WatchValues(ctx, vaultapp.RedactionRefreshInterval)
+	natsOpts = append([]nats.Option{nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1), nats.ReconnectWait(2 * time.Second)}, natsOpts...)
+	nc, err := nats.Connect(natsURL, natsOpts...)
 	if err != nil {
 		return fmt.Errorf("connect to NATS at %s: %w", natsURL, err)
 	}
@@ -55,20 +78,28 @@ func RunExecutor(args []string) error {
 	if cfg.Runtime.SessionManager.NATS.StreamPrefix != "" {
 		streamPrefix = cfg.Runtime.SessionManager.NATS.StreamPrefix
 	}
-	worker, err := agentruntime.NewExecutorWorker(agentruntime.ExecutorWorkerConfig{
+	workerCfg := agentruntime.ExecutorWorkerConfig{
 		NATSConn:       nc,
 		StreamPrefix:   streamPrefix,
-		WorkerID:       cfg.Runtime.SessionManager.ServerID,
+		WorkerID:       opts.workerID,
 		AgentTypes:     opts.agentTypes,
 		Store:          store,
 		Registry:       store,
 		DefaultWorkDir: resolveDefaultWorkDir(),
 		MaxConcurrent:  opts.maxConcurrent,
-	})
+		Labels:         opts.labels,
+		Drivers:        detectExecutorDrivers(ctx, store),
+	}
+	if cfg.Runtime.Sandbox.Enabled {
+		workerCfg.Sandbox = v2sandbox.FromRuntimeConfig(cfg.Runtime.Sandbox, dataDir)
+		workerCfg.SandboxKinds = []string{executorSandboxKind(cfg.Runtime.Sandbox.Provider)}
+	}
+	worker, err := agentruntime.NewExecutorWorker(workerCfg)
 	if err != nil {
 		return fmt.Errorf("create executor worker: %w", err)
 	}
-	slog.Info("executor: starting worker", "agents", opts.agentTypes, "max_concurrent", opts.maxConcurrent)
+	slog.Info("executor: starting worker", "agents", opts.agentTypes, "max_concurrent", opts.maxConcurrent,
+		"labels", opts.labels, "drivers", workerCfg.Drivers, "sandbox", workerCfg.SandboxKinds)
 	err = worker.Start(ctx)
 	worker.Stop()
 	if ctx.Err() != nil {
@@ -87,8 +118,11 @@ func resolveDefaultWorkDir() string {
 
 type executorCLIOptions struct {
 	natsURL       string
+	natsCreds     string
+	workerID      string
 	agentTypes    []string
 	maxConcurrent int
+	labels        map[string]string
 }
 
 func parseExecutorArgs(args []string) (executorCLIOptions, error) {
@@ -104,6 +138,22 @@ func parseExecutorArgs(args []string) (executorCLIOptions, error) {
 			opts.natsURL = strings.TrimSpace(args[i])
 		case strings.HasPrefix(arg, "--nats-url="):
 			opts.natsURL = strings.TrimSpace(strings.TrimPrefix(arg, "--nats-url="))
+		case arg == "--nats-creds":
+			i++
+			if i >= len(args) {
+				return executorCLIOptions{}, fmt.Errorf("missing value for --nats-creds")
+			}
+			opts.natsCreds = strings.TrimSpace(args[i])
+		case strings.HasPrefix(arg, "--nats-creds="):
+			opts.natsCreds = strings.TrimSpace(strings.TrimPrefix(arg, "--nats-creds="))
+		case arg == "--worker-id":
+			i++
+			if i >= len(args) {
+				return executorCLIOptions{}, fmt.Errorf("missing value for --worker-id")
+			}
+			opts.workerID = strings.TrimSpace(args[i])
+		case strings.HasPrefix(arg, "--worker-id="):
+			opts.workerID = strings.TrimSpace(strings.TrimPrefix(arg, "--worker-id="))
 		case arg == "--agents":
 			i++
 			if i >= len(args) {
@@ -128,6 +178,22 @@ func parseExecutorArgs(args []string) (executorCLIOptions, error) {
 				return executorCLIOptions{}, err
 			}
 			opts.maxConcurrent = n
+		case arg == "--labels":
+			i++
+			if i >= len(args) {
+				return executorCLIOptions{}, fmt.Errorf("missing value for --labels")
+			}
+			labels, err := parseExecutorLabels(args[i])
+			if err != nil {
+				return executorCLIOptions{}, err
+			}
+			opts.labels = labels
+		case strings.HasPrefix(arg, "--labels="):
+			labels, err := parseExecutorLabels(strings.TrimPrefix(arg, "--labels="))
+			if err != nil {
+				return executorCLIOptions{}, err
+			}
+			opts.labels = labels
 		default:
 			return executorCLIOptions{}, fmt.Errorf("unknown flag: %s", arg)
 		}
@@ -145,6 +211,64 @@ func parseAgentTypes(raw string) []string {
 	return agents
 }
 
+// parseExecutorLabels parses "k=v,k2=v2" into a label map.
+func parseExecutorLabels(raw string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid value for --labels: %q (want key=value)", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// detectExecutorDrivers reports the driver IDs whose launch command is
+// installed on this host, so the server only routes runs it can launch.
+func detectExecutorDrivers(ctx context.Context, registry core.AgentRegistry) []string {
+	profiles, err := registry.ListProfiles(ctx)
+	if err != nil {
+		slog.Warn("executor: list profiles for driver detection failed", "error", err)
+		return nil
+	}
+	seen := make(map[string]struct{})
+	var drivers []string
+	for _, profile := range profiles {
+		if profile == nil {
+			continue
+		}
+		driverID := strings.TrimSpace(profile.DriverID)
+		command := strings.TrimSpace(profile.Driver.LaunchCommand)
+		if driverID == "" || command == "" {
+			continue
+		}
+		if _, ok := seen[driverID]; ok {
+			continue
+		}
+		if _, err := exec.LookPath(command); err != nil {
+			continue
+		}
+		seen[driverID] = struct{}{}
+		drivers = append(drivers, driverID)
+	}
+	sort.Strings(drivers)
+	return drivers
+}
+
+func executorSandboxKind(provider string) string {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "" || provider == "noop" {
+		return "home_dir"
+	}
+	return provider
+}
+
 func parsePositiveInt(raw string, flagName string) (int, error) {
 	n, err := strconv.Atoi(strings.TrimSpace(raw))
 	if err != nil || n <= 0 {
@@ -163,3 +287,51 @@ func resolveExecutorNATSURL(cliValue string, cfg *config.Config) string {
 	}
 	return natsURL
 }
+
+// resolveExecutorNATSConn resolves the NATS URL and auth options for an executor.
+// Credentials come from --nats-creds, AI_WORKFLOW_NATS_CREDS, or — when the
+// local config runs an embedded server — the credentials file in its store dir.
+// An explicit URL always wins over the one recorded in the credentials file.
+func resolveExecutorNATSConn(opts executorCLIOptions, cfg *config.Config, dataDir string) (string, []nats.Option, error) {
+	natsURL := resolveExecutorNATSURL(opts.natsURL, cfg)
+
+	credsPath := strings.TrimSpace(opts.natsCreds)
+	if credsPath == "" {
+		credsPath = strings.TrimSpace(os.Getenv("AI_WORKFLOW_NATS_CREDS"))
+	}
+	explicitCreds := credsPath != ""
+	if credsPath == "" && cfg != nil && cfg.Runtime.SessionManager.NATS.Embedded {
+		storeDir := natsembed.ResolveStoreDir(dataDir, cfg.Runtime.SessionManager.NATS.EmbeddedDataDir)
+		credsPath = natsembed.CredentialsPath(storeDir)
+	}
+	if credsPath == "" {
+		return natsURL, nil, nil
+	}
+
+	creds, err := natsembed.LoadCredentials(credsPath)
+	if err != nil {
+		if !explicitCreds && errors.Is(err, os.ErrNotExist) {
+			return natsURL, nil, nil
+		}
+		return "", nil, err
+	}
+	if natsURL == "" {
+		natsURL = strings.TrimSpace(creds.URL)
+		if natsembed.IsWildcardURL(natsURL) {
+			// Written by an older server listening on 0.0.0.0.
+			natsURL = ""
+		}
+	}
+	if natsURL == "" && !explicitCreds {
+		// Same host as the embedded server: its loopback address works even
+		// when the server advertises no URL.
+		natsURL, err = natsembed.LocalURL(cfg.Runtime.SessionManager.NATS.EmbeddedListen)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if natsURL == "" {
+		return "", nil, fmt.Errorf("nats credentials %s record no reachable url; pass --nats-url or set embedded_advertise on the server", credsPath)
+	}
+	return natsURL, creds.Options(), nil
+}
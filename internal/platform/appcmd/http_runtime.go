@@ -1,12 +1,14 @@
 package appcmd
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
+	"github.com/yoke233/zhanggui/internal/platform/appdata"
 	"github.com/yoke233/zhanggui/internal/platform/bootstrap"
 	"github.com/yoke233/zhanggui/internal/platform/config"
 )
@@ -40,8 +42,13 @@ func BootstrapHTTPRuntime(opts HTTPRuntimeOptions) (*HTTPRuntime, error) {
 	}
 	serverPort := resolveServerPort(opts.ListenPort, cfg.Server.Port)
 
+	dirLock, err := lockDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
 	closeLog, err := InitAppLogger(dataDir, commandName)
 	if err != nil {
+		_ = dirLock.Release()
 		return nil, err
 	}
 
@@ -75,6 +82,7 @@ func BootstrapHTTPRuntime(opts HTTPRuntimeOptions) (*HTTPRuntime, error) {
 			cleanupFn()
 		}
 		_ = closeLog()
+		_ = dirLock.Release()
 	}
 
 	if store == nil || registrar == nil {
@@ -112,3 +120,13 @@ func (r *HTTPRuntime) NewServer(addr string, frontend fs.FS, apiOnly bool) *http
 		APIOnly:        apiOnly,
 	})
 }
+
+// lockDataDir holds the data dir shared for the life of the process so that
+// "backup restore" refuses to swap it out underneath.
+func lockDataDir(dataDir string) (*appdata.DataDirLock, error) {
+	lock, err := appdata.LockDataDir(dataDir, false)
+	if errors.Is(err, appdata.ErrDataDirLocked) {
+		return nil, fmt.Errorf("data dir %s is being restored; retry once the restore finishes", dataDir)
+	}
+	return lock, err
+}
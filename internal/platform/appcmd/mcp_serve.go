@@ -61,6 +61,12 @@ func RunMCPServe(args []string) error {
 		Description: "Get the run context: work item details, upstream action results, and your own rework history.",
 	}, handler.handleActionContext)
 
+	// search — available for all action types.
+	mcp.AddTool(srv, &mcp.Tool{
+		Name:        "search",
+		Description: "Full-text search over prior work: work items, thread messages, journal entries, run results and deliverables. Returns ranked snippets with matches wrapped in <mark></mark>.",
+	}, handler.handleSearch)
+
 	switch actionType {
 	case "exec":
 		mcp.AddTool(srv, &mcp.Tool{
@@ -194,6 +200,43 @@ func (h *mcpActionHandler) handleActionContext(ctx context.Context, req *mcp.Cal
 	return nil, out, nil
 }
 
+type searchInput struct {
+	Query     string   `json:"query" jsonschema:"Words to search for. Every word must match"`
+	Kinds     []string `json:"kinds,omitempty" jsonschema:"Restrict to kinds: work_item, thread_message, journal, run, deliverable"`
+	ProjectID int64    `json:"project_id,omitempty" jsonschema:"Restrict to one project"`
+	Limit     int      `json:"limit,omitempty" jsonschema:"Max results (default 10)"`
+}
+
+type searchOutput struct {
+	Results []core.SearchHit `json:"results"`
+}
+
+func (h *mcpActionHandler) handleSearch(ctx context.Context, req *mcp.CallToolRequest, input searchInput) (*mcp.CallToolResult, searchOutput, error) {
+	searcher, ok := h.store.(core.SearchStore)
+	if !ok {
+		return nil, searchOutput{}, fmt.Errorf("search is not supported by this store")
+	}
+	q := core.SearchQuery{Query: input.Query, Limit: input.Limit}
+	if q.Limit <= 0 {
+		q.Limit = 10
+	}
+	for _, k := range input.Kinds {
+		kind := core.SearchKind(strings.TrimSpace(k))
+		if !kind.Valid() {
+			return nil, searchOutput{}, fmt.Errorf("unknown search kind %q", k)
+		}
+		q.Kinds = append(q.Kinds, kind)
+	}
+	if input.ProjectID > 0 {
+		q.ProjectID = &input.ProjectID
+	}
+	hits, err := searcher.Search(ctx, q)
+	if err != nil {
+		return nil, searchOutput{}, fmt.Errorf("search: %w", err)
+	}
+	return nil, searchOutput{Results: hits}, nil
+}
+
 type actionCompleteInput struct {
 	Summary      string   `json:"summary" jsonschema:"One-sentence summary of what was accomplished"`
 	FilesChanged []string `json:"files_changed,omitempty" jsonschema:"File paths that were created or modified"`
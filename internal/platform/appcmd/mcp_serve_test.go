@@ -0,0 +1,35 @@
+package appcmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	sqlitestore "github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+func TestMCPSearchToolFindsPriorWork(t *testing.T) {
+	store, err := sqlitestore.New(filepath.Join(t.TempDir(), "mcp.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	wiID, err := store.CreateWorkItem(ctx, &core.WorkItem{Title: "Migrate billing exporter", Body: "Use the new queue.", Status: core.WorkItemDone})
+	if err != nil {
+		t.Fatalf("create work item: %v", err)
+	}
+
+	h := &mcpActionHandler{store: store}
+	_, out, err := h.handleSearch(ctx, nil, searchInput{Query: "billing", Kinds: []string{"work_item"}})
+	if err != nil {
+		t.Fatalf("handleSearch: %v", err)
+	}
+	if len(out.Results) != 1 || out.Results[0].RefID != wiID {
+		t.Fatalf("results = %+v", out.Results)
+	}
+	if _, _, err := h.handleSearch(ctx, nil, searchInput{Query: "billing", Kinds: []string{"nope"}}); err == nil {
+		t.Fatal("expected error for unknown kind")
+	}
+}
@@ -0,0 +1,153 @@
+package appcmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yoke233/zhanggui/internal/adapters/resource/filestore"
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/application/provenanceapp"
+)
+
+const provenanceUsage = `usage:
+  ai-flow provenance verify <sha> [--repo <dir>] [--json]`
+
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{4,64}$`)
+
+type provenanceVerifyOptions struct {
+	SHA  string
+	Repo string
+	JSON bool
+}
+
+// RunProvenance checks the signed provenance of agent-produced commits.
+func RunProvenance(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", provenanceUsage)
+	}
+	switch strings.TrimSpace(args[0]) {
+	case "verify":
+		opts, err := parseProvenanceVerifyArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runProvenanceVerify(opts)
+	default:
+		return fmt.Errorf("unknown provenance command: %s", args[0])
+	}
+}
+
+func parseProvenanceVerifyArgs(args []string) (provenanceVerifyOptions, error) {
+	var opts provenanceVerifyOptions
+	// The commit may come before or after the flags.
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		opts.SHA, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("provenance verify", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.Repo, "repo", ".", "Git checkout whose provenance note is checked too (empty to skip)")
+	fs.BoolVar(&opts.JSON, "json", false, "Emit JSON output")
+	if err := fs.Parse(args); err != nil {
+		return provenanceVerifyOptions{}, err
+	}
+	rest := fs.Args()
+	if opts.SHA == "" && len(rest) > 0 {
+		opts.SHA, rest = rest[0], rest[1:]
+	}
+	if len(rest) > 0 {
+		return provenanceVerifyOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(rest, " "))
+	}
+	if !commitSHAPattern.MatchString(opts.SHA) {
+		return provenanceVerifyOptions{}, fmt.Errorf("provenance verify requires a commit sha (4-64 hex characters)")
+	}
+	opts.SHA = strings.ToLower(opts.SHA)
+	return opts, nil
+}
+
+// runProvenanceVerify checks every envelope stored for the commit, and its
+// git note when a checkout is given, against the local signing key.
+func runProvenanceVerify(opts provenanceVerifyOptions) error {
+	cfg, dataDir, runtimeDBPath, err := resolveRuntimeDBPath()
+	if err != nil {
+		return err
+	}
+	store, err := sqlite.New(runtimeDBPath)
+	if err != nil {
+		return fmt.Errorf("open runtime store: %w", err)
+	}
+	defer store.Close()
+
+	svc := provenanceapp.New(provenanceapp.Config{
+		Store:    store,
+		Files:    filestore.NewLocal(filepath.Join(dataDir, "files")),
+		KeyFile:  provenanceapp.ResolveKeyFile(dataDir, cfg.Provenance.KeyFile),
+		NotesRef: cfg.Provenance.NotesRef,
+	})
+	results, err := svc.Verify(context.Background(), opts.SHA, opts.Repo)
+	if err != nil {
+		return err
+	}
+
+	invalid := 0
+	for _, r := range results {
+		if !r.Valid() {
+			invalid++
+		}
+	}
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		printProvenanceResults(results)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("provenance verification failed: %d of %d statements invalid", invalid, len(results))
+	}
+	return nil
+}
+
+func printProvenanceResults(results []provenanceapp.Verification) {
+	for _, r := range results {
+		source := r.Source
+		if r.ResourceID > 0 {
+			source = fmt.Sprintf("%s #%d", r.Source, r.ResourceID)
+		}
+		if !r.Valid() {
+			fmt.Printf("INVALID %s: %s\n", source, r.Error)
+			continue
+		}
+		def := r.Statement.Predicate.BuildDefinition
+		fmt.Printf("ok      %s: %s, work item %d, %s\n", source, r.Statement.Subject[0].Name,
+			def.ExternalParameters.WorkItemID, strings.TrimPrefix(def.BuildType, "https://"))
+		for _, step := range def.InternalParameters.Steps {
+			line := fmt.Sprintf("        action %d %q (%s) run %d", step.ActionID, step.Name, step.Type, step.RunID)
+			for _, part := range []struct{ label, value string }{
+				{"profile", step.ProfileID},
+				{"driver", step.DriverID},
+				{"model", step.ModelID},
+				{"verdict", step.Verdict},
+			} {
+				if part.value != "" {
+					line += " " + part.label + "=" + part.value
+				}
+			}
+			if len(step.ToolCalls) > 0 {
+				line += fmt.Sprintf(" tool_calls=%d", len(step.ToolCalls))
+			}
+			for _, sig := range step.Signals {
+				line += fmt.Sprintf(" %s_by=%s", sig.Type, firstNonEmpty(sig.Actor, sig.Source))
+			}
+			fmt.Println(line)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package appcmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/application/vaultapp"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+const secretUsage = `usage:
+  ai-flow secret set --name <NAME> [--project id] [--profile id] [--description text] [--from-file path]
+  ai-flow secret list [--project id] [--profile id] [--json]
+  ai-flow secret delete <id>
+
+set reads the value from --from-file, or from stdin when omitted. Without
+--project the secret is visible to every project; without --profile, to every
+profile. Reference secrets as ${vault.NAME} in driver env and action config
+env, or as auth_secret_ref = "vault.NAME" on MCP servers.`
+
+type secretSetOptions struct {
+	Name        string
+	ProjectID   int64
+	ProfileID   string
+	Description string
+	FromFile    string
+}
+
+type secretListOptions struct {
+	ProjectID  int64
+	HasProject bool
+	ProfileID  string
+	HasProfile bool
+	JSON       bool
+}
+
+// RunSecret manages the encrypted secret vault directly in the runtime
+// database, with the master key file of the data directory.
+func RunSecret(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", secretUsage)
+	}
+	switch strings.TrimSpace(args[0]) {
+	case "set":
+		opts, err := parseSecretSetArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runSecretSet(opts, os.Stdin)
+	case "list":
+		opts, err := parseSecretListArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runSecretList(opts)
+	case "delete":
+		id, err := parseSecretDeleteArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runSecretDelete(id)
+	default:
+		return fmt.Errorf("unknown secret command: %s", args[0])
+	}
+}
+
+func parseSecretSetArgs(args []string) (secretSetOptions, error) {
+	var opts secretSetOptions
+	fs := flag.NewFlagSet("secret set", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.Name, "name", "", "Secret name, usable as an env var name")
+	fs.Int64Var(&opts.ProjectID, "project", 0, "Project ID (default every project)")
+	fs.StringVar(&opts.ProfileID, "profile", "", "Agent profile ID (default every profile)")
+	fs.StringVar(&opts.Description, "description", "", "What the secret is for")
+	fs.StringVar(&opts.FromFile, "from-file", "", "Read the value from this file instead of stdin")
+	if err := fs.Parse(args); err != nil {
+		return secretSetOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return secretSetOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if !core.ValidVaultSecretName(strings.TrimSpace(opts.Name)) {
+		return secretSetOptions{}, fmt.Errorf("secret set requires --name with a valid environment variable name")
+	}
+	if opts.ProjectID < 0 {
+		return secretSetOptions{}, fmt.Errorf("invalid --project %d", opts.ProjectID)
+	}
+	return opts, nil
+}
+
+func parseSecretListArgs(args []string) (secretListOptions, error) {
+	var opts secretListOptions
+	fs := flag.NewFlagSet("secret list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Int64Var(&opts.ProjectID, "project", 0, "Only secrets of this project ID (0 for global ones)")
+	fs.StringVar(&opts.ProfileID, "profile", "", "Only secrets of this profile (empty for cross-profile ones)")
+	fs.BoolVar(&opts.JSON, "json", false, "Emit JSON output")
+	if err := fs.Parse(args); err != nil {
+		return secretListOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return secretListOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "project":
+			opts.HasProject = true
+		case "profile":
+			opts.HasProfile = true
+		}
+	})
+	return opts, nil
+}
+
+func parseSecretDeleteArgs(args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("secret delete requires exactly one secret ID")
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(args[0]), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid secret ID %q", args[0])
+	}
+	return id, nil
+}
+
+func openVaultService() (*vaultapp.Service, func(), error) {
+	cfg, dataDir, runtimeDBPath, err := resolveRuntimeDBPath()
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := sqlite.New(runtimeDBPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open runtime store: %w", err)
+	}
+	svc := vaultapp.New(vaultapp.Config{
+		Store:   store,
+		KeyFile: vaultapp.ResolveKeyFile(dataDir, cfg.Vault.KeyFile),
+	})
+	return svc, func() { store.Close() }, nil
+}
+
+func runSecretSet(opts secretSetOptions, stdin io.Reader) error {
+	var value []byte
+	var err error
+	if opts.FromFile != "" {
+		value, err = os.ReadFile(opts.FromFile)
+	} else {
+		value, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return fmt.Errorf("read secret value: %w", err)
+	}
+	// A trailing newline comes from echo or an editor, not the credential.
+	trimmed := strings.TrimRight(string(value), "\r\n")
+
+	svc, closeStore, err := openVaultService()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	secret, err := svc.Set(context.Background(), vaultapp.SetInput{
+		ProjectID:   opts.ProjectID,
+		ProfileID:   opts.ProfileID,
+		Name:        opts.Name,
+		Value:       trimmed,
+		Description: opts.Description,
+		CreatedBy:   "cli",
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("stored secret %d %s (%s)\n", secret.ID, secret.Name, formatSecretScope(secret))
+	return nil
+}
+
+func runSecretList(opts secretListOptions) error {
+	svc, closeStore, err := openVaultService()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	var filter core.VaultSecretFilter
+	if opts.HasProject {
+		filter.ProjectID = &opts.ProjectID
+	}
+	if opts.HasProfile {
+		filter.ProfileID = &opts.ProfileID
+	}
+	secrets, err := svc.List(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	if opts.JSON {
+		return encodeTokenJSON(secrets)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCOPE\tUPDATED\tDESCRIPTION")
+	for _, s := range secrets {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", s.ID, s.Name, formatSecretScope(s),
+			formatTokenTime(&s.UpdatedAt, ""), s.Description)
+	}
+	return w.Flush()
+}
+
+func runSecretDelete(id int64) error {
+	svc, closeStore, err := openVaultService()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	if err := svc.Delete(context.Background(), id); err != nil {
+		return fmt.Errorf("delete secret %d: %w", id, err)
+	}
+	fmt.Printf("deleted secret %d\n", id)
+	return nil
+}
+
+func formatSecretScope(s *core.VaultSecret) string {
+	project, profile := "all projects", "all profiles"
+	if s.ProjectID != 0 {
+		project = fmt.Sprintf("project %d", s.ProjectID)
+	}
+	if s.ProfileID != "" {
+		profile = "profile " + s.ProfileID
+	}
+	return project + ", " + profile
+}
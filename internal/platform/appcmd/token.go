@@ -0,0 +1,248 @@
+package appcmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/application/tokenapp"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+const tokenUsage = `usage:
+  ai-flow token create --name <name> --scopes <a,b> [--owner id] [--projects p1,p2] [--expires-in 720h|0 | --expires-at RFC3339] [--json]
+  ai-flow token list [--json]
+  ai-flow token revoke <id>`
+
+type tokenCreateOptions struct {
+	Name      string
+	Owner     string
+	Scopes    []string
+	Projects  []string
+	ExpiresIn string
+	ExpiresAt string
+	JSON      bool
+}
+
+type tokenListOptions struct {
+	JSON bool
+}
+
+// RunToken manages API tokens directly in the runtime database, so that an
+// operator can issue a CI token without one. Revocations take effect on the
+// next request of a running server.
+func RunToken(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", tokenUsage)
+	}
+	switch strings.TrimSpace(args[0]) {
+	case "create":
+		opts, err := parseTokenCreateArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runTokenCreate(opts)
+	case "list":
+		opts, err := parseTokenListArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runTokenList(opts)
+	case "revoke":
+		id, err := parseTokenRevokeArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runTokenRevoke(id)
+	default:
+		return fmt.Errorf("unknown token command: %s", args[0])
+	}
+}
+
+func parseTokenCreateArgs(args []string) (tokenCreateOptions, error) {
+	var opts tokenCreateOptions
+	var scopes, projects string
+	fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.Name, "name", "", "Token name")
+	fs.StringVar(&opts.Owner, "owner", "", "Identity recorded as submitter (default cli)")
+	fs.StringVar(&scopes, "scopes", "", "Comma-separated scopes")
+	fs.StringVar(&projects, "projects", "", "Comma-separated project IDs or names (default all)")
+	fs.StringVar(&opts.ExpiresIn, "expires-in", "", "Lifetime such as 720h; 0 never expires")
+	fs.StringVar(&opts.ExpiresAt, "expires-at", "", "Expiry time in RFC 3339")
+	fs.BoolVar(&opts.JSON, "json", false, "Emit JSON output")
+	if err := fs.Parse(args); err != nil {
+		return tokenCreateOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return tokenCreateOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if strings.TrimSpace(opts.Name) == "" || strings.TrimSpace(scopes) == "" {
+		return tokenCreateOptions{}, fmt.Errorf("token create requires --name and --scopes")
+	}
+	if opts.ExpiresIn != "" && opts.ExpiresAt != "" {
+		return tokenCreateOptions{}, fmt.Errorf("--expires-in cannot be combined with --expires-at")
+	}
+	opts.Scopes = parseCSV(scopes)
+	opts.Projects = parseCSV(projects)
+	return opts, nil
+}
+
+func parseTokenListArgs(args []string) (tokenListOptions, error) {
+	var opts tokenListOptions
+	fs := flag.NewFlagSet("token list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.JSON, "json", false, "Emit JSON output")
+	if err := fs.Parse(args); err != nil {
+		return tokenListOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return tokenListOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return opts, nil
+}
+
+func parseTokenRevokeArgs(args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("token revoke requires exactly one token ID")
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(args[0]), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid token ID %q", args[0])
+	}
+	return id, nil
+}
+
+func openTokenService() (*tokenapp.Service, func(), error) {
+	_, _, runtimeDBPath, err := resolveRuntimeDBPath()
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := sqlite.New(runtimeDBPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open runtime store: %w", err)
+	}
+	return tokenapp.New(tokenapp.Config{Store: store}), func() { store.Close() }, nil
+}
+
+func runTokenCreate(opts tokenCreateOptions) error {
+	in := tokenapp.CreateInput{
+		Name:      opts.Name,
+		Owner:     opts.Owner,
+		CreatedBy: "cli",
+		Scopes:    opts.Scopes,
+		Projects:  opts.Projects,
+	}
+	switch {
+	case opts.ExpiresAt != "":
+		exp, err := time.Parse(time.RFC3339, opts.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("invalid --expires-at: %w", err)
+		}
+		in.ExpiresAt = &exp
+	case opts.ExpiresIn != "":
+		ttl, err := time.ParseDuration(opts.ExpiresIn)
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("invalid --expires-in %q", opts.ExpiresIn)
+		}
+		if ttl == 0 {
+			in.NoExpiry = true
+		} else {
+			exp := time.Now().UTC().Add(ttl)
+			in.ExpiresAt = &exp
+		}
+	}
+
+	svc, closeStore, err := openTokenService()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	token, secret, err := svc.Create(context.Background(), in)
+	if err != nil {
+		return err
+	}
+	if opts.JSON {
+		return encodeTokenJSON(struct {
+			*core.APIToken
+			Token string `json:"token"`
+		}{token, secret})
+	}
+	fmt.Printf("created token %d %q (%s), expires %s\n", token.ID, token.Name, strings.Join(token.Scopes, ","), formatTokenTime(token.ExpiresAt, "never"))
+	fmt.Printf("%s\n", secret)
+	fmt.Fprintln(os.Stderr, "store this token now; it cannot be shown again")
+	return nil
+}
+
+func runTokenList(opts tokenListOptions) error {
+	svc, closeStore, err := openTokenService()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	tokens, err := svc.List(context.Background())
+	if err != nil {
+		return err
+	}
+	if opts.JSON {
+		return encodeTokenJSON(tokens)
+	}
+	now := time.Now().UTC()
+	w := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tOWNER\tSCOPES\tPROJECTS\tEXPIRES\tLAST USED\tSTATUS")
+	for _, t := range tokens {
+		status := "active"
+		switch {
+		case t.RevokedAt != nil:
+			status = "revoked"
+		case !t.Active(now):
+			status = "expired"
+		}
+		lastUsed := formatTokenTime(t.LastUsedAt, "never")
+		if t.LastUsedIP != "" {
+			lastUsed += " from " + t.LastUsedIP
+		}
+		projects := strings.Join(t.Projects, ",")
+		if projects == "" {
+			projects = "*"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s…\t%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Prefix, t.Owner,
+			strings.Join(t.Scopes, ","), projects, formatTokenTime(t.ExpiresAt, "never"), lastUsed, status)
+	}
+	return w.Flush()
+}
+
+func runTokenRevoke(id int64) error {
+	svc, closeStore, err := openTokenService()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+	token, err := svc.Revoke(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("revoke token %d: %w", id, err)
+	}
+	fmt.Printf("revoked token %d %q\n", token.ID, token.Name)
+	return nil
+}
+
+func formatTokenTime(t *time.Time, empty string) string {
+	if t == nil {
+		return empty
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func encodeTokenJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,221 @@
+package appcmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpreplay"
+	"github.com/yoke233/zhanggui/internal/adapters/sandbox"
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/configruntime"
+)
+
+const traceUsage = `usage:
+  ai-flow trace export --run <run-id> [--out fixture.json]
+  ai-flow trace record --driver <driver-id> --prompt <text> [--prompt <text>...] [--out fixture.json] [--workdir dir] [--timeout 10m]`
+
+type traceExportOptions struct {
+	RunID int64
+	Out   string
+}
+
+type traceRecordOptions struct {
+	DriverID string
+	Prompts  []string
+	Out      string
+	WorkDir  string
+	Timeout  time.Duration
+}
+
+type promptListFlag []string
+
+func (p *promptListFlag) String() string { return strings.Join(*p, "\n") }
+
+func (p *promptListFlag) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+func RunTrace(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", traceUsage)
+	}
+	switch strings.TrimSpace(args[0]) {
+	case "export":
+		opts, err := parseTraceExportArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runTraceExport(opts)
+	case "record":
+		opts, err := parseTraceRecordArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return runTraceRecord(opts)
+	default:
+		return fmt.Errorf("unknown trace command: %s", args[0])
+	}
+}
+
+func parseTraceExportArgs(args []string) (traceExportOptions, error) {
+	var opts traceExportOptions
+	var runID string
+	fs := flag.NewFlagSet("trace export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&runID, "run", "", "Run ID to export")
+	fs.StringVar(&opts.Out, "out", "", "Fixture output path (default stdout)")
+	if err := fs.Parse(args); err != nil {
+		return traceExportOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return traceExportOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(runID), 10, 64)
+	if err != nil || id <= 0 {
+		return traceExportOptions{}, fmt.Errorf("trace export requires a positive --run id")
+	}
+	opts.RunID = id
+	return opts, nil
+}
+
+func parseTraceRecordArgs(args []string) (traceRecordOptions, error) {
+	var opts traceRecordOptions
+	var prompts promptListFlag
+	fs := flag.NewFlagSet("trace record", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.DriverID, "driver", "", "Driver ID to launch")
+	fs.Var(&prompts, "prompt", "Prompt to send (repeat for multiple turns)")
+	fs.StringVar(&opts.Out, "out", "", "Fixture output path (default stdout)")
+	fs.StringVar(&opts.WorkDir, "workdir", "", "Working directory for the agent (default temp dir)")
+	fs.DurationVar(&opts.Timeout, "timeout", 10*time.Minute, "Timeout for each prompt")
+	if err := fs.Parse(args); err != nil {
+		return traceRecordOptions{}, err
+	}
+	if fs.NArg() > 0 {
+		return traceRecordOptions{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	opts.DriverID = strings.TrimSpace(opts.DriverID)
+	if opts.DriverID == "" {
+		return traceRecordOptions{}, fmt.Errorf("trace record requires --driver")
+	}
+	if len(prompts) == 0 {
+		return traceRecordOptions{}, fmt.Errorf("trace record requires at least one --prompt")
+	}
+	opts.Prompts = prompts
+	return opts, nil
+}
+
+func runTraceExport(opts traceExportOptions) error {
+	cfg, dataDir, _, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	storePath := ExpandStorePath(cfg.Store.Path, dataDir)
+	runtimeDBPath := strings.TrimSuffix(storePath, filepath.Ext(storePath)) + "_runtime.db"
+	store, err := sqlite.New(runtimeDBPath)
+	if err != nil {
+		return fmt.Errorf("open runtime store: %w", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	run, err := store.GetRun(ctx, opts.RunID)
+	if err != nil {
+		return fmt.Errorf("get run %d: %w", opts.RunID, err)
+	}
+	events, err := store.ListEvents(ctx, core.EventFilter{RunID: &opts.RunID, Types: []core.EventType{core.EventRunAgentOutput}})
+	if err != nil {
+		return err
+	}
+	usage, err := store.GetUsageByRun(ctx, opts.RunID)
+	if err != nil {
+		return err
+	}
+	fixture, err := acpreplay.FromRun(run, events, usage)
+	if err != nil {
+		return err
+	}
+	return writeTraceFixture(opts.Out, fixture)
+}
+
+func runTraceRecord(opts traceRecordOptions) error {
+	cfg, dataDir, _, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	manager, err := configruntime.NewManager(resolveGlobalConfigFilePath(dataDir), resolveSecretsFilePath(dataDir), configruntime.DisabledMCPEnv(), nil, nil)
+	if err != nil {
+		return fmt.Errorf("open runtime manager: %w", err)
+	}
+	driver, err := manager.ResolveDriverConfig(opts.DriverID)
+	if err != nil {
+		return err
+	}
+
+	workDir := strings.TrimSpace(opts.WorkDir)
+	if workDir == "" {
+		dir, err := os.MkdirTemp("", "ai-flow-trace-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		workDir = dir
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	launch := acpclient.LaunchConfig{
+		Command: driver.LaunchCommand,
+		Args:    append([]string(nil), driver.LaunchArgs...),
+		WorkDir: workDir,
+		Env:     acpclient.CloneEnv(driver.Env),
+	}
+	sb := sandbox.NewRuntimeSandbox(manager, cfg.Runtime.Sandbox, dataDir)
+	launch, err = sb.Prepare(ctx, sandbox.PrepareInput{
+		Profile: &core.AgentProfile{ID: "trace-" + opts.DriverID, DriverID: opts.DriverID, Driver: *driver, Role: core.RoleWorker},
+		Launch:  launch,
+		Scope:   fmt.Sprintf("trace-%s-%d", opts.DriverID, time.Now().UnixNano()),
+	})
+	if err != nil {
+		return fmt.Errorf("sandbox prepare: %w", err)
+	}
+
+	fixture, err := acpreplay.Record(ctx, acpreplay.RecordConfig{
+		DriverID: opts.DriverID,
+		Launch:   launch,
+		Prompts:  opts.Prompts,
+		Timeout:  opts.Timeout,
+	})
+	if err != nil {
+		return err
+	}
+	return writeTraceFixture(opts.Out, fixture)
+}
+
+func writeTraceFixture(out string, fixture *acpreplay.Fixture) error {
+	if strings.TrimSpace(out) != "" {
+		if err := acpreplay.Save(out, fixture); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %d turn(s) to %s\n", len(fixture.Turns), out)
+		return nil
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(fixture)
+}
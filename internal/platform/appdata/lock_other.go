@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package appdata
+
+import "os"
+
+// Platforms without file locking run unguarded.
+func lockFile(*os.File, bool) error { return nil }
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,407 @@
+// Package backup creates and restores consistent archives of the ai-flow data
+// directory while the server keeps running.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/application/provenanceapp"
+	"github.com/yoke233/zhanggui/internal/application/vaultapp"
+	"github.com/yoke233/zhanggui/internal/platform/config"
+	"github.com/yoke233/zhanggui/internal/platform/natsembed"
+)
+
+const (
+	// FormatVersion is bumped when the archive layout changes incompatibly.
+	FormatVersion = 1
+
+	manifestName  = "manifest.json"
+	dataPrefix    = "data/"
+	dbPrefix      = "database/"
+	archivePrefix = "ai-flow-backup-"
+	archiveSuffix = ".tar.gz"
+)
+
+// secretFiles are always left out of backups created with ExcludeSecrets;
+// SecretPaths adds the key files the configuration places in the data dir.
+var secretFiles = map[string]bool{"secrets.toml": true, "secrets.yaml": true}
+
+// SecretPaths returns the data-dir relative key and credential files cfg
+// resolves inside dataDir. Shipping them next to the data they protect would defeat
+// encryption at rest or let anyone holding the archive forge provenance
+// signatures, so ExcludeSecrets leaves them out as well.
+func SecretPaths(cfg *config.Config, dataDir string) []string {
+	if cfg == nil || strings.TrimSpace(dataDir) == "" {
+		return nil
+	}
+	root, err := filepath.Abs(dataDir)
+	if err != nil {
+		return nil
+	}
+	var out []string
+	add := func(path string) {
+		if abs, err := filepath.Abs(path); err == nil {
+			if rel, ok := relInside(root, abs); ok {
+				out = append(out, rel)
+			}
+		}
+	}
+	add(vaultapp.ResolveKeyFile(root, cfg.Vault.KeyFile))
+	add(provenanceapp.ResolveKeyFile(root, cfg.Provenance.KeyFile))
+	add(natsembed.CredentialsPath(natsembed.ResolveStoreDir(root, cfg.Runtime.SessionManager.NATS.EmbeddedDataDir)))
+	return out
+}
+
+// secretSet merges the built-in secret files with extra data-dir relative paths.
+func secretSet(extra []string) map[string]bool {
+	set := make(map[string]bool, len(secretFiles)+len(extra))
+	for name := range secretFiles {
+		set[name] = true
+	}
+	for _, rel := range extra {
+		if rel = path.Clean(filepath.ToSlash(strings.TrimSpace(rel))); rel != "." && rel != "" {
+			set[rel] = true
+		}
+	}
+	return set
+}
+
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// Options describes what to back up and where.
+type Options struct {
+	DataDir string
+	// DBPath is the runtime SQLite database. It may live outside DataDir.
+	DBPath string
+	// OutDir receives the archive. It is skipped when it lies inside DataDir.
+	OutDir         string
+	ExcludeSecrets bool
+	// SecretPaths are data-dir relative files excluded along with
+	// secrets.toml/secrets.yaml when ExcludeSecrets is set (see SecretPaths).
+	SecretPaths []string
+	// Label is embedded in the archive name; scheduled backups rotate by label.
+	Label string
+}
+
+// Manifest is stored as manifest.json inside every archive.
+type Manifest struct {
+	FormatVersion   int           `json:"format_version"`
+	CreatedAt       time.Time     `json:"created_at"`
+	DataDir         string        `json:"data_dir"`
+	Database        DatabaseEntry `json:"database"`
+	SecretsExcluded bool          `json:"secrets_excluded"`
+	// SecretPaths lists the data-dir relative files left out because they
+	// hold secrets; restore carries them over from the live data dir.
+	SecretPaths []string    `json:"secret_paths,omitempty"`
+	Files       []FileEntry `json:"files"`
+}
+
+// DatabaseEntry locates the runtime database inside the archive.
+type DatabaseEntry struct {
+	// Path is the archive path of the snapshot.
+	Path          string `json:"path"`
+	OriginalPath  string `json:"original_path"`
+	SchemaVersion int    `json:"schema_version"`
+}
+
+// FileEntry is one archived file with its checksum.
+type FileEntry struct {
+	Path   string      `json:"path"`
+	Size   int64       `json:"size"`
+	Mode   fs.FileMode `json:"mode"`
+	SHA256 string      `json:"sha256"`
+}
+
+// Result describes a created archive.
+type Result struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Manifest *Manifest `json:"manifest"`
+}
+
+// Create writes a tar.gz archive of the data directory. SQLite databases are
+// captured with VACUUM INTO, which takes a transactionally consistent snapshot
+// through SQLite's online copy path without blocking writers.
+func Create(ctx context.Context, opts Options) (*Result, error) {
+	dataDir, err := filepath.Abs(strings.TrimSpace(opts.DataDir))
+	if err != nil || strings.TrimSpace(opts.DataDir) == "" {
+		return nil, errors.New("backup: data dir is required")
+	}
+	outDir, err := filepath.Abs(strings.TrimSpace(opts.OutDir))
+	if err != nil || strings.TrimSpace(opts.OutDir) == "" {
+		return nil, errors.New("backup: output dir is required")
+	}
+	dbPath := ""
+	if strings.TrimSpace(opts.DBPath) != "" {
+		if dbPath, err = filepath.Abs(opts.DBPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, fmt.Errorf("backup: create output dir: %w", err)
+	}
+	scratch, err := os.MkdirTemp(outDir, ".snapshot-")
+	if err != nil {
+		return nil, fmt.Errorf("backup: create scratch dir: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+
+	now := time.Now().UTC()
+	label := strings.TrimSpace(opts.Label)
+	if label == "" {
+		label = "manual"
+	}
+	finalPath := filepath.Join(outDir, archivePrefix+label+"-"+now.Format("20060102T150405Z")+archiveSuffix)
+	partial := finalPath + ".partial"
+	f, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("backup: create archive: %w", err)
+	}
+	w := &archiveWriter{gz: gzip.NewWriter(f), scratch: scratch}
+	w.tw = tar.NewWriter(w.gz)
+	manifest := &Manifest{
+		FormatVersion:   FormatVersion,
+		CreatedAt:       now,
+		DataDir:         dataDir,
+		SecretsExcluded: opts.ExcludeSecrets,
+	}
+	secrets := secretSet(opts.SecretPaths)
+	if opts.ExcludeSecrets {
+		for rel := range secrets {
+			manifest.SecretPaths = append(manifest.SecretPaths, rel)
+		}
+		sort.Strings(manifest.SecretPaths)
+	}
+
+	err = func() error {
+		if dbPath != "" {
+			archivePath := dbPrefix + filepath.Base(dbPath)
+			if rel, ok := relInside(dataDir, dbPath); ok {
+				archivePath = dataPrefix + rel
+			}
+			entry, snapshot, err := w.addDatabase(ctx, dbPath, archivePath)
+			if err != nil {
+				return err
+			}
+			version, err := schemaVersion(snapshot)
+			if err != nil {
+				return err
+			}
+			manifest.Files = append(manifest.Files, entry)
+			manifest.Database = DatabaseEntry{Path: archivePath, OriginalPath: dbPath, SchemaVersion: version}
+		}
+		return filepath.WalkDir(dataDir, func(path string, d fs.DirEntry, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path == outDir {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() || path == dbPath || isSQLiteSidecar(path) || strings.HasPrefix(path, outDir+string(filepath.Separator)) {
+				return nil
+			}
+			rel, _ := filepath.Rel(dataDir, path)
+			rel = filepath.ToSlash(rel)
+			if opts.ExcludeSecrets && secrets[rel] {
+				return nil
+			}
+			var entry FileEntry
+			var err error
+			if isSQLiteFile(path) {
+				entry, _, err = w.addDatabase(ctx, path, dataPrefix+rel)
+			} else {
+				entry, err = w.addFile(path, dataPrefix+rel)
+			}
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // removed while we walked
+			}
+			if err != nil {
+				return err
+			}
+			manifest.Files = append(manifest.Files, entry)
+			return nil
+		})
+	}()
+	if err == nil {
+		err = w.addManifest(manifest)
+	}
+	if cerr := w.close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(partial)
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	if err := os.Rename(partial, finalPath); err != nil {
+		_ = os.Remove(partial)
+		return nil, fmt.Errorf("backup: finalize archive: %w", err)
+	}
+	info, err := os.Stat(finalPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Path: finalPath, Size: info.Size(), Manifest: manifest}, nil
+}
+
+type archiveWriter struct {
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	scratch string
+	seq     int
+}
+
+// addDatabase snapshots a live SQLite database and archives the snapshot.
+func (w *archiveWriter) addDatabase(ctx context.Context, src, archivePath string) (FileEntry, string, error) {
+	w.seq++
+	snapshot := filepath.Join(w.scratch, fmt.Sprintf("db-%d.sqlite", w.seq))
+	if err := snapshotSQLite(ctx, src, snapshot); err != nil {
+		return FileEntry{}, "", err
+	}
+	entry, err := w.addFile(snapshot, archivePath)
+	if err != nil {
+		return FileEntry{}, "", err
+	}
+	entry.Mode = 0o644
+	return entry, snapshot, nil
+}
+
+func (w *archiveWriter) addFile(src, archivePath string) (FileEntry, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return FileEntry{}, err
+	}
+	hdr := &tar.Header{
+		Name:    archivePath,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := w.tw.WriteHeader(hdr); err != nil {
+		return FileEntry{}, err
+	}
+	h := sha256.New()
+	// The header fixes the size; a file that shrinks while being read is
+	// zero-padded so the archive stays well-formed and the checksum matches.
+	n, err := io.Copy(io.MultiWriter(w.tw, h), io.LimitReader(f, info.Size()))
+	if err != nil {
+		return FileEntry{}, fmt.Errorf("archive %s: %w", archivePath, err)
+	}
+	if pad := info.Size() - n; pad > 0 {
+		zeros := bytes.NewReader(make([]byte, pad))
+		if _, err := io.Copy(io.MultiWriter(w.tw, h), zeros); err != nil {
+			return FileEntry{}, err
+		}
+	}
+	return FileEntry{
+		Path:   archivePath,
+		Size:   info.Size(),
+		Mode:   info.Mode().Perm(),
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+func (w *archiveWriter) addManifest(m *Manifest) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := w.tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0o644, Size: int64(len(raw)), ModTime: m.CreatedAt}); err != nil {
+		return err
+	}
+	_, err = w.tw.Write(raw)
+	return err
+}
+
+func (w *archiveWriter) close() error {
+	twErr := w.tw.Close()
+	gzErr := w.gz.Close()
+	if twErr != nil {
+		return twErr
+	}
+	return gzErr
+}
+
+// snapshotSQLite copies a live database into dst with VACUUM INTO.
+func snapshotSQLite(ctx context.Context, src, dst string) error {
+	db, err := sql.Open("sqlite", src+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return fmt.Errorf("open %s: %w", src, err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", dst); err != nil {
+		return fmt.Errorf("snapshot %s: %w", src, err)
+	}
+	return nil
+}
+
+func schemaVersion(dbPath string) (int, error) {
+	store, err := sqlite.NewWithOptions(dbPath, sqlite.OpenOptions{SkipMigrations: true})
+	if err != nil {
+		return 0, fmt.Errorf("open snapshot: %w", err)
+	}
+	defer store.Close()
+	return store.SchemaVersion(context.Background())
+}
+
+func isSQLiteFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(f, head); err != nil {
+		return false
+	}
+	return bytes.Equal(head, sqliteHeader)
+}
+
+func isSQLiteSidecar(path string) bool {
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// relInside returns path relative to root when path lies inside root.
+func relInside(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") || filepath.IsAbs(rel) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,369 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/appdata"
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(raw)
+}
+
+func newDataDir(t *testing.T) (string, string, *sqlite.Store) {
+	t.Helper()
+	dataDir := filepath.Join(t.TempDir(), ".ai-workflow")
+	dbPath := filepath.Join(dataDir, "data_runtime.db")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	store, err := sqlite.New(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	writeFile(t, filepath.Join(dataDir, "config.toml"), "[server]\nport = 8080\n")
+	writeFile(t, filepath.Join(dataDir, "secrets.toml"), "token = \"live\"\n")
+	writeFile(t, filepath.Join(dataDir, "threads", "1", "notes.md"), "thread notes")
+	writeFile(t, filepath.Join(dataDir, "skills", "demo", "SKILL.md"), "# demo")
+	return dataDir, dbPath, store
+}
+
+func TestCreateAndRestoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dataDir, dbPath, store := newDataDir(t)
+	projectID, err := store.CreateProject(ctx, &core.Project{Name: "before-backup", Kind: core.ProjectGeneral})
+	if err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+
+	svc := NewService(defaultBackupConfig(), dataDir, dbPath, nil)
+	result, err := svc.CreateBackup(ctx, true)
+	store.Close() // the server keeps running during Create; stop it before restoring
+	if err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+	if !strings.HasPrefix(result.Path, filepath.Join(dataDir, "backups")+string(filepath.Separator)) {
+		t.Fatalf("archive path = %s", result.Path)
+	}
+	m := result.Manifest
+	if m.Database.Path != "data/data_runtime.db" || m.Database.SchemaVersion != sqlite.LatestSchemaVersion() || !m.SecretsExcluded {
+		t.Fatalf("manifest = %+v", m)
+	}
+	for _, f := range m.Files {
+		if f.Path == "data/secrets.toml" || strings.HasPrefix(f.Path, "data/backups/") || strings.HasSuffix(f.Path, "-wal") {
+			t.Fatalf("manifest should not contain %s", f.Path)
+		}
+	}
+
+	// Diverge after the backup.
+	writeFile(t, filepath.Join(dataDir, "threads", "1", "notes.md"), "changed")
+	writeFile(t, filepath.Join(dataDir, "secrets.toml"), "token = \"rotated\"\n")
+	writeFile(t, filepath.Join(dataDir, "stray.txt"), "created after backup")
+
+	restored, err := Restore(ctx, RestoreOptions{Archive: result.Path, DataDir: dataDir, Preserve: []string{"backups"}})
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.PreviousDataDir == "" {
+		t.Fatal("previous data dir should be kept")
+	}
+	if got := readFile(t, filepath.Join(dataDir, "threads", "1", "notes.md")); got != "thread notes" {
+		t.Fatalf("thread notes = %q", got)
+	}
+	if got := readFile(t, filepath.Join(dataDir, "secrets.toml")); !strings.Contains(got, "rotated") {
+		t.Fatalf("secrets should be carried over from the live data dir, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "stray.txt")); !os.IsNotExist(err) {
+		t.Fatalf("stray file should not survive restore, err = %v", err)
+	}
+	if list, err := List(filepath.Join(dataDir, "backups")); err != nil || len(list) != 1 {
+		t.Fatalf("backups dir should be preserved, list = %v, err = %v", list, err)
+	}
+
+	reopened, err := sqlite.New(dbPath)
+	if err != nil {
+		t.Fatalf("reopen restored db: %v", err)
+	}
+	defer reopened.Close()
+	project, err := reopened.GetProject(ctx, projectID)
+	if err != nil || project.Name != "before-backup" {
+		t.Fatalf("restored project = %+v, %v", project, err)
+	}
+}
+
+func TestExcludeSecretsSkipsConfiguredKeyFiles(t *testing.T) {
+	ctx := context.Background()
+	dataDir, dbPath, store := newDataDir(t)
+	store.Close()
+	writeFile(t, filepath.Join(dataDir, "vault.key"), "master key")
+	writeFile(t, filepath.Join(dataDir, "keys", "signing.key"), "signing key")
+	writeFile(t, filepath.Join(dataDir, "nats", "credentials.json"), `{"password":"nats"}`)
+
+	cfg := &config.Config{}
+	cfg.Provenance.KeyFile = "keys/signing.key"
+	secretPaths := SecretPaths(cfg, dataDir)
+	if strings.Join(secretPaths, ",") != "vault.key,keys/signing.key,nats/credentials.json" {
+		t.Fatalf("SecretPaths = %v", secretPaths)
+	}
+	result, err := Create(ctx, Options{DataDir: dataDir, DBPath: dbPath, OutDir: t.TempDir(), ExcludeSecrets: true, SecretPaths: secretPaths})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, f := range result.Manifest.Files {
+		if f.Path == "data/vault.key" || f.Path == "data/keys/signing.key" || f.Path == "data/nats/credentials.json" {
+			t.Fatalf("%s should not be archived with ExcludeSecrets", f.Path)
+		}
+	}
+
+	writeFile(t, filepath.Join(dataDir, "vault.key"), "rotated key")
+	if _, err := Restore(ctx, RestoreOptions{Archive: result.Path, DataDir: dataDir}); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if got := readFile(t, filepath.Join(dataDir, "vault.key")); got != "rotated key" {
+		t.Fatalf("vault key = %q, want it carried over from the live data dir", got)
+	}
+	if got := readFile(t, filepath.Join(dataDir, "keys", "signing.key")); got != "signing key" {
+		t.Fatalf("signing key = %q, want it carried over from the live data dir", got)
+	}
+	if got := readFile(t, filepath.Join(dataDir, "nats", "credentials.json")); !strings.Contains(got, "nats") {
+		t.Fatalf("nats credentials = %q, want them carried over from the live data dir", got)
+	}
+}
+
+func TestRestoreRejectsTamperedArchive(t *testing.T) {
+	ctx := context.Background()
+	dataDir, dbPath, store := newDataDir(t)
+	store.Close()
+	result, err := Create(ctx, Options{DataDir: dataDir, DBPath: dbPath, OutDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	tampered := filepath.Join(t.TempDir(), "tampered.tar.gz")
+	rewriteArchive(t, result.Path, tampered, func(name string, body []byte) []byte {
+		if name == "data/config.toml" {
+			return []byte("[server]\nport = 9999\n")
+		}
+		return body
+	})
+
+	_, err = Restore(ctx, RestoreOptions{Archive: tampered, DataDir: dataDir})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Restore error = %v, want checksum mismatch", err)
+	}
+	if got := readFile(t, filepath.Join(dataDir, "config.toml")); !strings.Contains(got, "8080") {
+		t.Fatalf("data dir was modified by a failed restore: %q", got)
+	}
+}
+
+func TestRestoreRejectsDatabasePathOutsideArchive(t *testing.T) {
+	ctx := context.Background()
+	dataDir, dbPath, store := newDataDir(t)
+	store.Close()
+	result, err := Create(ctx, Options{DataDir: dataDir, DBPath: dbPath, OutDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, bad := range []string{"data/../../outside.db", "/tmp/outside.db", "data/missing.db"} {
+		tampered := filepath.Join(t.TempDir(), "tampered.tar.gz")
+		rewriteArchive(t, result.Path, tampered, func(name string, body []byte) []byte {
+			if name != manifestName {
+				return body
+			}
+			var m Manifest
+			if err := json.Unmarshal(body, &m); err != nil {
+				t.Fatal(err)
+			}
+			m.Database.Path = bad
+			out, _ := json.Marshal(m)
+			return out
+		})
+		_, err = Restore(ctx, RestoreOptions{Archive: tampered, DataDir: dataDir})
+		if err == nil || !strings.Contains(err.Error(), "manifest database path") {
+			t.Fatalf("Restore with database path %q error = %v, want it refused", bad, err)
+		}
+	}
+}
+
+func TestRestoreRefusesDataDirInUse(t *testing.T) {
+	ctx := context.Background()
+	dataDir, dbPath, store := newDataDir(t)
+	store.Close()
+	result, err := Create(ctx, Options{DataDir: dataDir, DBPath: dbPath, OutDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	lock, err := appdata.LockDataDir(dataDir, false)
+	if err != nil {
+		t.Fatalf("LockDataDir: %v", err)
+	}
+	_, err = Restore(ctx, RestoreOptions{Archive: result.Path, DataDir: dataDir})
+	if !errors.Is(err, appdata.ErrDataDirLocked) {
+		t.Fatalf("Restore error = %v, want ErrDataDirLocked", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, err := Restore(ctx, RestoreOptions{Archive: result.Path, DataDir: dataDir}); err != nil {
+		t.Fatalf("Restore after release: %v", err)
+	}
+}
+
+func TestRestoreKeepsCarriedFilesWhenSwapFails(t *testing.T) {
+	ctx := context.Background()
+	dataDir, dbPath, store := newDataDir(t)
+	svc := NewService(defaultBackupConfig(), dataDir, dbPath, nil)
+	result, err := svc.CreateBackup(ctx, true)
+	store.Close()
+	if err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+
+	orig := rename
+	rename = func(from, to string) error {
+		if strings.Contains(to, ".pre-restore-") {
+			return errors.New("simulated rename failure")
+		}
+		return orig(from, to)
+	}
+	t.Cleanup(func() { rename = orig })
+
+	_, err = Restore(ctx, RestoreOptions{Archive: result.Path, DataDir: dataDir, Preserve: []string{"backups"}})
+	if err == nil || !strings.Contains(err.Error(), "move current data dir aside") {
+		t.Fatalf("Restore error = %v, want data dir rename failure", err)
+	}
+	if got := readFile(t, filepath.Join(dataDir, "secrets.toml")); !strings.Contains(got, "live") {
+		t.Fatalf("secrets.toml = %q, want it back in the data dir", got)
+	}
+	if list, err := List(filepath.Join(dataDir, "backups")); err != nil || len(list) != 1 {
+		t.Fatalf("backups dir should survive a failed restore, list = %v, err = %v", list, err)
+	}
+}
+
+func TestRestoreRejectsNewerSchema(t *testing.T) {
+	ctx := context.Background()
+	dataDir, dbPath, store := newDataDir(t)
+	store.Close()
+	futureDB(t, dbPath)
+
+	result, err := Create(ctx, Options{DataDir: dataDir, DBPath: dbPath, OutDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_, err = Restore(ctx, RestoreOptions{Archive: result.Path, DataDir: filepath.Join(t.TempDir(), "target")})
+	if !errors.Is(err, sqlite.ErrSchemaTooNew) {
+		t.Fatalf("Restore error = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestRotateKeepsNewestScheduledBackups(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		name := archivePrefix + scheduledLabel + "-" + base.Add(time.Duration(i)*time.Hour).Format("20060102T150405Z") + archiveSuffix
+		writeFile(t, filepath.Join(dir, name), "x")
+	}
+	writeFile(t, filepath.Join(dir, archivePrefix+"manual-20250101T000000Z"+archiveSuffix), "x")
+
+	removed, err := Rotate(dir, scheduledLabel, 2)
+	if err != nil || len(removed) != 2 {
+		t.Fatalf("Rotate removed %v, err = %v", removed, err)
+	}
+	list, _ := List(dir)
+	if len(list) != 3 || !strings.Contains(list[0].Name, "T030000Z") {
+		t.Fatalf("remaining = %+v", list)
+	}
+}
+
+func defaultBackupConfig() config.StoreBackupConfig {
+	return config.StoreBackupConfig{Dir: "backups", Keep: 3}
+}
+
+// futureDB records a migration newer than this binary knows about.
+func futureDB(t *testing.T, dbPath string) {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'from_the_future', ?)`,
+		sqlite.LatestSchemaVersion()+1, time.Now().UTC()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rewriteArchive copies src to dst, letting edit replace entry bodies.
+func rewriteArchive(t *testing.T, src, dst string, edit func(name string, body []byte) []byte) {
+	t.Helper()
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	gzw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gzw)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(tr)
+		body = edit(hdr.Name, body)
+		hdr.Size = int64(len(body))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
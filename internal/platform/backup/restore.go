@@ -0,0 +1,349 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	"github.com/yoke233/zhanggui/internal/platform/appdata"
+)
+
+// rename is swapped out by tests to simulate filesystem failures.
+var rename = os.Rename
+
+// RestoreOptions describes where an archive is restored to.
+type RestoreOptions struct {
+	Archive string
+	DataDir string
+	// DBPath is where the runtime database is placed when the archive stored
+	// it outside the data dir. Defaults to the manifest's original path.
+	DBPath string
+	// Preserve lists data-dir relative paths carried over from the current
+	// data dir (for example the backups directory itself).
+	Preserve []string
+}
+
+// RestoreResult reports a completed restore.
+type RestoreResult struct {
+	Manifest *Manifest `json:"manifest"`
+	// PreviousDataDir holds the data dir that was replaced.
+	PreviousDataDir string `json:"previous_data_dir,omitempty"`
+	// PreviousDBPath holds the external database that was replaced.
+	PreviousDBPath string `json:"previous_db_path,omitempty"`
+}
+
+// Restore unpacks an archive next to the data dir, verifies every checksum and
+// the database schema version, then swaps it into place. The replaced data
+// dir is kept as "<data_dir>.pre-restore-<timestamp>". It refuses to run
+// while a server or executor holds the data dir lock.
+func Restore(ctx context.Context, opts RestoreOptions) (*RestoreResult, error) {
+	dataDir, err := filepath.Abs(strings.TrimSpace(opts.DataDir))
+	if err != nil || strings.TrimSpace(opts.DataDir) == "" {
+		return nil, errors.New("restore: data dir is required")
+	}
+	lock, err := appdata.LockDataDir(dataDir, true)
+	if errors.Is(err, appdata.ErrDataDirLocked) {
+		return nil, fmt.Errorf("restore: %s is in use; stop the server and executors first: %w", dataDir, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("restore: %w", err)
+	}
+	defer lock.Release()
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	staging := dataDir + ".restore-" + stamp
+	if err := os.MkdirAll(staging, 0o755); err != nil {
+		return nil, fmt.Errorf("restore: create staging dir: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = os.RemoveAll(staging)
+		}
+	}()
+
+	manifest, externalDB, err := extractArchive(ctx, opts.Archive, staging)
+	if err != nil {
+		return nil, fmt.Errorf("restore: %w", err)
+	}
+
+	stagedDB := ""
+	if p := manifest.Database.Path; p != "" {
+		stagedDB, err = stagedDatabase(staging, manifest)
+		if err != nil {
+			return nil, fmt.Errorf("restore: %w", err)
+		}
+		if err := validateSchema(stagedDB, manifest.Database.SchemaVersion); err != nil {
+			return nil, fmt.Errorf("restore: %w", err)
+		}
+	}
+
+	restoredData := filepath.Join(staging, "data")
+	if err := os.MkdirAll(restoredData, 0o755); err != nil {
+		return nil, err
+	}
+	carry := append([]string(nil), opts.Preserve...)
+	if manifest.SecretsExcluded {
+		for name := range secretSet(manifest.SecretPaths) {
+			carry = append(carry, name)
+		}
+	}
+	// Carried items are moved, not copied, so every failure below must put
+	// them back before the deferred cleanup removes staging.
+	var carried []string
+	putBack := func() {
+		for i := len(carried) - 1; i >= 0; i-- {
+			_ = moveEntry(restoredData, dataDir, carried[i])
+		}
+	}
+	for _, rel := range carry {
+		moved, err := carryOver(dataDir, restoredData, rel)
+		if moved {
+			carried = append(carried, rel)
+		}
+		if err != nil {
+			putBack()
+			return nil, fmt.Errorf("restore: keep %s: %w", rel, err)
+		}
+	}
+
+	result := &RestoreResult{Manifest: manifest}
+	if _, err := os.Stat(dataDir); err == nil {
+		result.PreviousDataDir = dataDir + ".pre-restore-" + stamp
+		if err := rename(dataDir, result.PreviousDataDir); err != nil {
+			putBack()
+			return nil, fmt.Errorf("restore: move current data dir aside: %w", err)
+		}
+	}
+	// unswap puts the previous data dir back in place.
+	unswap := func() error {
+		if result.PreviousDataDir == "" {
+			return os.MkdirAll(dataDir, 0o755)
+		}
+		return rename(result.PreviousDataDir, dataDir)
+	}
+	if err := rename(restoredData, dataDir); err != nil {
+		if uerr := unswap(); uerr == nil {
+			putBack()
+		}
+		return nil, fmt.Errorf("restore: swap data dir: %w", err)
+	}
+
+	if externalDB {
+		dbPath := strings.TrimSpace(opts.DBPath)
+		if dbPath == "" {
+			dbPath = manifest.Database.OriginalPath
+		}
+		prev, err := replaceFile(stagedDB, dbPath, stamp)
+		if err != nil {
+			if rerr := rename(dataDir, restoredData); rerr == nil {
+				if uerr := unswap(); uerr == nil {
+					putBack()
+				}
+			}
+			return nil, fmt.Errorf("restore: swap database: %w", err)
+		}
+		result.PreviousDBPath = prev
+	}
+	committed = true
+	_ = os.RemoveAll(staging)
+	return result, nil
+}
+
+// extractArchive unpacks every entry under staging and checks it against the
+// manifest. It reports whether the database lives outside the data dir.
+func extractArchive(ctx context.Context, archivePath, staging string) (*Manifest, bool, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, false, fmt.Errorf("read archive: %w", err)
+	}
+	defer gz.Close()
+
+	var manifest *Manifest
+	sums := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("read archive: %w", err)
+		}
+		name := path.Clean(hdr.Name)
+		if name == manifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, false, fmt.Errorf("decode manifest: %w", err)
+			}
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg || strings.HasPrefix(name, "../") || path.IsAbs(name) ||
+			!(strings.HasPrefix(name, dataPrefix) || strings.HasPrefix(name, dbPrefix)) {
+			return nil, false, fmt.Errorf("unexpected archive entry %q", hdr.Name)
+		}
+		dst := filepath.Join(staging, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return nil, false, err
+		}
+		out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode).Perm()|0o600)
+		if err != nil {
+			return nil, false, err
+		}
+		h := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, h), tr)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("extract %s: %w", name, err)
+		}
+		sums[name] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if manifest == nil {
+		return nil, false, errors.New("archive has no manifest")
+	}
+	if manifest.FormatVersion > FormatVersion {
+		return nil, false, fmt.Errorf("archive format %d is newer than supported %d", manifest.FormatVersion, FormatVersion)
+	}
+	for _, entry := range manifest.Files {
+		got, ok := sums[entry.Path]
+		if !ok {
+			return nil, false, fmt.Errorf("archive is missing %s", entry.Path)
+		}
+		if got != entry.SHA256 {
+			return nil, false, fmt.Errorf("checksum mismatch for %s", entry.Path)
+		}
+		delete(sums, entry.Path)
+	}
+	for name := range sums {
+		return nil, false, fmt.Errorf("archive entry %s is not listed in the manifest", name)
+	}
+	return manifest, strings.HasPrefix(manifest.Database.Path, dbPrefix), nil
+}
+
+// stagedDatabase locates the manifest's database inside the extracted
+// archive. The path must name an archived, checksummed file; anything that
+// could resolve outside staging is refused before it is opened.
+func stagedDatabase(staging string, manifest *Manifest) (string, error) {
+	p := manifest.Database.Path
+	if path.IsAbs(p) || filepath.IsAbs(filepath.FromSlash(p)) || filepath.VolumeName(filepath.FromSlash(p)) != "" ||
+		path.Clean(p) != p || slices.Contains(strings.Split(p, "/"), "..") ||
+		!(strings.HasPrefix(p, dataPrefix) || strings.HasPrefix(p, dbPrefix)) {
+		return "", fmt.Errorf("manifest database path %q is not inside the archive", p)
+	}
+	if !slices.ContainsFunc(manifest.Files, func(f FileEntry) bool { return f.Path == p }) {
+		return "", fmt.Errorf("manifest database path %q is not an archived file", p)
+	}
+	staged := filepath.Join(staging, filepath.FromSlash(p))
+	if _, ok := relInside(staging, staged); !ok {
+		return "", fmt.Errorf("manifest database path %q is not inside the archive", p)
+	}
+	return staged, nil
+}
+
+// validateSchema refuses databases that this binary cannot open.
+func validateSchema(dbPath string, recorded int) error {
+	store, err := sqlite.NewWithOptions(dbPath, sqlite.OpenOptions{SkipMigrations: true})
+	if err != nil {
+		return fmt.Errorf("open restored database: %w", err)
+	}
+	version, err := store.SchemaVersion(context.Background())
+	store.Close()
+	for _, suffix := range []string{"-wal", "-shm"} {
+		_ = os.Remove(dbPath + suffix)
+	}
+	if err != nil {
+		return fmt.Errorf("read restored schema version: %w", err)
+	}
+	if version != recorded {
+		return fmt.Errorf("restored database is at schema version %d, manifest says %d", version, recorded)
+	}
+	if latest := sqlite.LatestSchemaVersion(); version > latest {
+		return fmt.Errorf("%w: backup is at version %d, binary supports up to %d", sqlite.ErrSchemaTooNew, version, latest)
+	}
+	return nil
+}
+
+// carryOver moves rel from the current data dir into the restored one unless
+// the archive already provided it. It reports whether anything was moved.
+func carryOver(currentDir, restoredDir, rel string) (bool, error) {
+	src := filepath.Join(currentDir, filepath.FromSlash(rel))
+	dst := filepath.Join(restoredDir, filepath.FromSlash(rel))
+	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return false, nil
+	}
+	if err := moveEntry(currentDir, restoredDir, rel); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// moveEntry renames rel from one directory tree into another.
+func moveEntry(fromDir, toDir, rel string) error {
+	dst := filepath.Join(toDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return rename(filepath.Join(fromDir, filepath.FromSlash(rel)), dst)
+}
+
+// replaceFile swaps src into dst, keeping the old file (and its WAL sidecars)
+// as "<dst>.pre-restore-<stamp>".
+func replaceFile(src, dst, stamp string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", err
+	}
+	prev := ""
+	_, err := os.Stat(dst)
+	if err == nil {
+		prev = dst + ".pre-restore-" + stamp
+		if err := rename(dst, prev); err != nil {
+			return "", err
+		}
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(dst + suffix); err != nil {
+			continue
+		}
+		if prev == "" {
+			err = os.Remove(dst + suffix)
+		} else {
+			err = rename(dst+suffix, prev+suffix)
+		}
+		if err != nil {
+			return prev, err
+		}
+	}
+	if err := rename(src, dst); err != nil {
+		if prev != "" {
+			_ = rename(prev, dst)
+		}
+		return "", err
+	}
+	return prev, nil
+}
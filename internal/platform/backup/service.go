@@ -0,0 +1,208 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/platform/config"
+)
+
+const scheduledLabel = "scheduled"
+
+// Info describes an archive found in the backup directory.
+type Info struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Service creates, lists and rotates backups for one data dir.
+type Service struct {
+	dataDir        string
+	dbPath         string
+	dir            string
+	keep           int
+	excludeSecrets bool
+	secretPaths    []string
+
+	mu sync.Mutex
+}
+
+// NewService builds a service from [store.backup]. dbPath is the runtime
+// database; secretPaths are excluded with the secrets (see SecretPaths).
+func NewService(cfg config.StoreBackupConfig, dataDir, dbPath string, secretPaths []string) *Service {
+	return &Service{
+		dataDir:        dataDir,
+		dbPath:         dbPath,
+		dir:            ResolveDir(cfg.Dir, dataDir),
+		keep:           cfg.Keep,
+		excludeSecrets: cfg.ExcludeSecrets,
+		secretPaths:    secretPaths,
+	}
+}
+
+// ResolveDir resolves the configured backup directory against the data dir.
+func ResolveDir(dir, dataDir string) string {
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		dir = "backups"
+	}
+	if filepath.IsAbs(dir) || dataDir == "" {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(dataDir, dir)
+}
+
+// Dir returns the directory archives are written to.
+func (s *Service) Dir() string { return s.dir }
+
+// CreateBackup writes a manual backup archive.
+func (s *Service) CreateBackup(ctx context.Context, excludeSecrets bool) (*Result, error) {
+	return s.create(ctx, "manual", excludeSecrets)
+}
+
+func (s *Service) create(ctx context.Context, label string, excludeSecrets bool) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Create(ctx, Options{
+		DataDir:        s.dataDir,
+		DBPath:         s.dbPath,
+		OutDir:         s.dir,
+		ExcludeSecrets: excludeSecrets,
+		SecretPaths:    s.secretPaths,
+		Label:          label,
+	})
+}
+
+// ListBackups returns archives in the backup directory, newest first.
+func (s *Service) ListBackups() ([]Info, error) {
+	return List(s.dir)
+}
+
+// List returns the backup archives in dir, newest first.
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := []Info{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, archivePrefix) || !strings.HasSuffix(name, archiveSuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		out = append(out, Info{Name: name, Path: filepath.Join(dir, name), Size: info.Size(), CreatedAt: info.ModTime().UTC()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name > out[j].Name })
+	return out, nil
+}
+
+// Rotate deletes all but the newest keep archives carrying label.
+func Rotate(dir, label string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	all, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := archivePrefix + label + "-"
+	var removed []string
+	kept := 0
+	for _, b := range all { // newest first: timestamps sort lexically
+		if !strings.HasPrefix(b.Name, prefix) {
+			continue
+		}
+		if kept < keep {
+			kept++
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil {
+			return removed, fmt.Errorf("remove %s: %w", b.Name, err)
+		}
+		removed = append(removed, b.Path)
+	}
+	return removed, nil
+}
+
+// Scheduler takes periodic backups and rotates old ones.
+type Scheduler struct {
+	svc      *Service
+	interval time.Duration
+}
+
+// NewScheduler creates a scheduler; interval defaults to 24h.
+func NewScheduler(svc *Service, interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	return &Scheduler{svc: svc, interval: interval}
+}
+
+// Start blocks until ctx is cancelled. A backup is taken immediately when the
+// newest scheduled backup is older than the interval.
+func (s *Scheduler) Start(ctx context.Context) {
+	slog.Info("backup scheduler started", "interval", s.interval, "dir", s.svc.dir)
+	if s.due() {
+		s.runOnce(ctx)
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("backup scheduler stopped")
+			return
+		case <-ticker.C:
+			s.runOnce(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) due() bool {
+	all, err := List(s.svc.dir)
+	if err != nil {
+		return true
+	}
+	for _, b := range all {
+		if strings.HasPrefix(b.Name, archivePrefix+scheduledLabel+"-") {
+			return time.Since(b.CreatedAt) >= s.interval
+		}
+	}
+	return true
+}
+
+func (s *Scheduler) runOnce(ctx context.Context) {
+	result, err := s.svc.create(ctx, scheduledLabel, s.svc.excludeSecrets)
+	if err != nil {
+		slog.Error("backup scheduler: backup failed", "error", err)
+		return
+	}
+	removed, err := Rotate(s.svc.dir, scheduledLabel, s.svc.keep)
+	if err != nil {
+		slog.Warn("backup scheduler: rotation failed", "error", err)
+	}
+	slog.Info("backup scheduler: backup completed",
+		"path", result.Path,
+		"size", result.Size,
+		"files", len(result.Manifest.Files),
+		"rotated", len(removed),
+	)
+}
@@ -2,18 +2,26 @@ package bootstrap
 
 import (
 	"context"
+	"log/slog"
 	"path/filepath"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpdoctor"
 	chatacp "github.com/yoke233/zhanggui/internal/adapters/chat/acp"
 	api "github.com/yoke233/zhanggui/internal/adapters/http"
+	httpx "github.com/yoke233/zhanggui/internal/adapters/http/server"
 	llmplanning "github.com/yoke233/zhanggui/internal/adapters/planning/llm"
 	scmadapter "github.com/yoke233/zhanggui/internal/adapters/scm"
+	eventsubapp "github.com/yoke233/zhanggui/internal/application/eventsub"
 	inspectionapp "github.com/yoke233/zhanggui/internal/application/inspection"
 	planningapp "github.com/yoke233/zhanggui/internal/application/planning"
 	probeapp "github.com/yoke233/zhanggui/internal/application/probe"
+	retentionapp "github.com/yoke233/zhanggui/internal/application/retention"
+	"github.com/yoke233/zhanggui/internal/audit"
 	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/backup"
 	"github.com/yoke233/zhanggui/internal/platform/config"
+	"github.com/yoke233/zhanggui/internal/platform/configruntime"
 	agentruntime "github.com/yoke233/zhanggui/internal/runtime/agent"
 )
 
@@ -22,6 +30,9 @@ type apiStack struct {
 	threadPool       *agentruntime.ThreadSessionPool
 	probeSvc         *probeapp.RunProbeService
 	inspectionEngine *inspectionapp.Engine
+	retention        *retentionapp.Service
+	backup           *backup.Service
+	eventSubs        *eventsubapp.Service
 	registrar        func(chi.Router)
 }
 
@@ -30,7 +41,7 @@ func buildAPIStack(
 	flow *flowStack,
 	bootstrapCfg *config.Config,
 ) *apiStack {
-	sb := buildSandbox(bootstrapCfg, base.runtimeManager, base.dataDir)
+	sb := buildSandbox(bootstrapCfg, base.runtimeManager, base.dataDir, base.vault)
 	var llmCompleter chatacp.TextCompleter
 	if flow.llmClient != nil {
 		llmCompleter = flow.llmClient
@@ -61,6 +72,7 @@ func buildAPIStack(
 		DataDir:                base.dataDir,
 		ChangeRequestProviders: scmadapter.NewChangeRequestProviders,
 		GitPAT:                 gitPAT,
+		LeakScan:               base.leakScan,
 		GC: chatacp.GCConfig{
 			ArchiveCleanup: gcCfg.ArchiveCleanup,
 			StartupCleanup: gcCfg.StartupCleanup,
@@ -90,8 +102,18 @@ func buildAPIStack(
 	threadPool := agentruntime.NewThreadSessionPool(base.store, base.bus, base.registry, base.dataDir)
 	threadPool.SetBackgroundContext(base.appCtx)
 	threadPool.SetThreadSharedBootTemplate(bootstrapCfg.Runtime.Prompts.ThreadSharedBootTemplate)
+	threadPool.SetContextCompactor(flow.compactor)
 	if base.signalCfg != nil {
 		threadPool.SetSignalConfig(base.signalCfg.ServerAddr, base.signalCfg.TokenRegistry)
+		if base.signalCfg.TokenRegistry != nil {
+			// Console users sign in with a session cookie next to API tokens.
+			base.signalCfg.TokenRegistry.SetSessionResolver(api.NewSessionResolver(base.store))
+			// CI bots use tokens issued through /tokens.
+			base.signalCfg.TokenRegistry.SetStoredTokenResolver(api.NewStoredTokenResolver(base.store))
+			if err := base.signalCfg.TokenRegistry.SetRevocationStore(base.appCtx, base.store); err != nil {
+				slog.Warn("bootstrap: load token revocations", "error", err)
+			}
+		}
 	}
 
 	apiOpts := buildAPIOptions(bootstrapCfg, base.runtimeManager, leadAgent, flow.scheduler, base.registry, dagGen)
@@ -99,20 +121,71 @@ func buildAPIStack(
 	apiOpts = append(apiOpts, api.WithThreadAgentRuntime(threadPool))
 	if base.runtimeManager != nil {
 		apiOpts = append(apiOpts, api.WithDriverConfigService(base.runtimeManager))
+		apiOpts = append(apiOpts, api.WithDriverDoctor(acpdoctor.NewService(base.runtimeManager, sb)))
 	}
 	if base.dataDir != "" {
 		apiOpts = append(apiOpts, api.WithDataDir(base.dataDir))
 	}
+	if fleetMgr, ok := flow.sessionMgr.(interface {
+		Fleet() *agentruntime.ExecutorFleetRegistry
+	}); ok && fleetMgr.Fleet() != nil {
+		apiOpts = append(apiOpts, api.WithExecutorFleet(fleetMgr.Fleet()))
+	}
 	apiOpts = append(apiOpts, api.WithBackgroundContext(base.appCtx))
+	apiOpts = append(apiOpts, api.WithVault(base.vault))
+	if bootstrapCfg != nil {
+		apiOpts = append(apiOpts, api.WithAuditRedactor(audit.NewRedactor(bootstrapCfg.Audit.RedactionLevel)))
+		apiOpts = append(apiOpts, api.WithIdempotencyTTL(bootstrapCfg.Server.IdempotencyTTL.Duration))
+	}
 	if flow.llmClient != nil {
 		apiOpts = append(apiOpts, api.WithTextCompleter(flow.llmClient))
 		apiOpts = append(apiOpts, api.WithRequirementCompleter(llmplanning.NewCompleter(flow.llmClient)))
 	}
 
+	if bootstrapCfg != nil && bootstrapCfg.Server.OIDC.Enabled {
+		login, err := buildOIDCLogin(bootstrapCfg.Server.OIDC)
+		if err != nil {
+			slog.Warn("bootstrap: OIDC single sign-on disabled (invalid config)", "error", err)
+		} else {
+			apiOpts = append(apiOpts, api.WithOIDCLogin(login))
+		}
+	}
+
 	// Inspection engine for self-evolving system inspections.
 	inspEngine := inspectionapp.New(base.store, base.bus)
 	apiOpts = append(apiOpts, api.WithInspectionEngine(inspEngine))
 
+	// Data retention: always available for manual pruning; the lifecycle
+	// schedules it when audit.retention.enabled is set.
+	var retentionSvc *retentionapp.Service
+	if bootstrapCfg != nil {
+		retentionSvc = retentionapp.New(base.store, retentionapp.ConfigFromAudit(bootstrapCfg.Audit, base.dataDir))
+		apiOpts = append(apiOpts, api.WithRetentionService(retentionSvc))
+	}
+
+	var backupSvc *backup.Service
+	if bootstrapCfg != nil && base.dataDir != "" {
+		backupSvc = backup.NewService(bootstrapCfg.Store.Backup, base.dataDir, base.runtimeDBPath, backup.SecretPaths(bootstrapCfg, base.dataDir))
+		apiOpts = append(apiOpts, api.WithBackupService(backupSvc))
+	}
+
+	// Outbound event subscriptions: the lifecycle starts the dispatcher when
+	// runtime.event_subscriptions.enabled is set.
+	var eventSubSvc *eventsubapp.Service
+	if bootstrapCfg != nil && bootstrapCfg.Runtime.EventSubscriptions.Enabled {
+		eventSubSvc = eventsubapp.New(base.store, base.bus, eventsubapp.ConfigFromRuntime(bootstrapCfg.Runtime.EventSubscriptions))
+		eventSubSvc.SetEventLogSyncer(base.persister)
+		apiOpts = append(apiOpts, api.WithEventSubscriptions(eventSubSvc))
+	}
+
+	if writer := base.persister.Writer(); writer != nil {
+		apiOpts = append(apiOpts, api.WithWriterStats(writer))
+	}
+	apiOpts = append(apiOpts, api.WithEventLogSyncer(base.persister))
+	apiOpts = append(apiOpts, api.WithAPIRateLimiter(httpx.NewAPIRateLimiter(func() (config.RuntimeRateLimitConfig, int64) {
+		return currentRateLimitConfig(base.runtimeManager, bootstrapCfg)
+	})))
+
 	handler := api.NewHandler(base.store, base.bus, flow.engine, apiOpts...)
 
 	return &apiStack{
@@ -120,6 +193,24 @@ func buildAPIStack(
 		threadPool:       threadPool,
 		probeSvc:         probeSvc,
 		inspectionEngine: inspEngine,
+		retention:        retentionSvc,
+		backup:           backupSvc,
+		eventSubs:        eventSubSvc,
 		registrar:        func(r chi.Router) { handler.Register(r) },
 	}
 }
+
+// currentRateLimitConfig reads the API limits from the live runtime config so
+// that edits apply on the next request; the snapshot version tells the
+// limiter when to recompile.
+func currentRateLimitConfig(runtimeManager *configruntime.Manager, bootstrapCfg *config.Config) (config.RuntimeRateLimitConfig, int64) {
+	if runtimeManager != nil {
+		if snap := runtimeManager.Current(); snap != nil && snap.Config != nil {
+			return snap.Config.Runtime.RateLimit, snap.Version
+		}
+	}
+	if bootstrapCfg != nil {
+		return bootstrapCfg.Runtime.RateLimit, 0
+	}
+	return config.RuntimeRateLimitConfig{}, 0
+}
@@ -5,8 +5,10 @@ import (
 
 	"github.com/yoke233/zhanggui/internal/adapters/http"
 	"github.com/yoke233/zhanggui/internal/adapters/llmconfig"
+	"github.com/yoke233/zhanggui/internal/adapters/oidc"
 	"github.com/yoke233/zhanggui/internal/adapters/sandbox"
 	flowapp "github.com/yoke233/zhanggui/internal/application/flow"
+	"github.com/yoke233/zhanggui/internal/application/userapp"
 	"github.com/yoke233/zhanggui/internal/core"
 	"github.com/yoke233/zhanggui/internal/platform/appdata"
 	"github.com/yoke233/zhanggui/internal/platform/config"
@@ -54,3 +56,32 @@ func buildAPIOptions(
 		}),
 	}
 }
+
+// buildOIDCLogin turns server.oidc into console single sign-on. Provider
+// discovery is deferred to the first login.
+func buildOIDCLogin(cfg config.ServerOIDCConfig) (*api.OIDCLogin, error) {
+	provider, err := oidc.New(oidc.Config{
+		Issuer:       cfg.Issuer,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mapping := userapp.GroupMapping{AdminGroups: cfg.AdminGroups}
+	for _, gr := range cfg.GroupRoles {
+		role, err := core.ParseProjectRole(gr.Role)
+		if err != nil {
+			return nil, err
+		}
+		mapping.Roles = append(mapping.Roles, userapp.GroupRole{Group: gr.Group, Project: gr.Project, Role: role})
+	}
+	return &api.OIDCLogin{
+		Provider:      provider,
+		UsernameClaim: cfg.UsernameClaim,
+		GroupsClaim:   cfg.GroupsClaim,
+		Groups:        mapping,
+	}, nil
+}
@@ -9,8 +9,12 @@ import (
 
 	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
 	membus "github.com/yoke233/zhanggui/internal/adapters/events/memory"
+	"github.com/yoke233/zhanggui/internal/adapters/leakscan"
+	"github.com/yoke233/zhanggui/internal/adapters/resource/filestore"
 	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
 	flowapp "github.com/yoke233/zhanggui/internal/application/flow"
+	"github.com/yoke233/zhanggui/internal/application/provenanceapp"
+	"github.com/yoke233/zhanggui/internal/application/vaultapp"
 	"github.com/yoke233/zhanggui/internal/core"
 	"github.com/yoke233/zhanggui/internal/platform/appdata"
 	"github.com/yoke233/zhanggui/internal/platform/config"
@@ -26,11 +30,18 @@ type bootstrapBase struct {
 	registry       core.AgentRegistry
 	runtimeManager *configruntime.Manager
 	dataDir        string
+	vault          *vaultapp.Service
+	leakScan       *leakscan.Policy
+	provenance     core.ProvenanceRecorder
 	signalCfg      *AgentSignalConfig
 	appCtx         context.Context
 	appCancel      context.CancelFunc
 }
 
+// eventSeqReserveBlock is how many event sequence numbers the bus reserves
+// per store write.
+const eventSeqReserveBlock = 1000
+
 func initBootstrapBase(appCtx context.Context, appCancel context.CancelFunc, storePath string, roleResolver *acpclient.RoleResolver, bootstrapCfg *config.Config) (*bootstrapBase, error) {
 	runtimeDBPath := strings.TrimSuffix(storePath, filepath.Ext(storePath)) + "_runtime.db"
 	fmt.Println("[startup] init base: open runtime store")
@@ -38,15 +49,41 @@ func initBootstrapBase(appCtx context.Context, appCancel context.CancelFunc, sto
 	if err != nil {
 		return nil, fmt.Errorf("open runtime store %s: %w", runtimeDBPath, err)
 	}
+	var leakScan *leakscan.Policy
+	if bootstrapCfg != nil {
+		leakScan, err = leakscan.NewPolicy(bootstrapCfg.LeakScan, store)
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("build leak scan policy: %w", err)
+		}
+	}
 
 	fmt.Println("[startup] init base: create event bus")
-	bus := membus.NewBus()
+	lastSeq, err := store.MaxEventSeq(appCtx)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("read event sequence: %w", err)
+	}
+	// Sequence numbers are reserved in blocks so transient events, which are
+	// never persisted, are not numbered again after a restart.
+	bus := membus.NewBus(membus.WithStartSeq(lastSeq), membus.WithSeqReservation(eventSeqReserveBlock, store.ReserveEventSeq))
 	fmt.Println("[startup] init base: start event persister")
-	persister := flowapp.NewEventPersister(store, bus)
+	var persisterOpts []flowapp.EventPersisterOption
+	if bootstrapCfg != nil {
+		wb := bootstrapCfg.Store.WriteBehind
+		persisterOpts = append(persisterOpts, flowapp.WithPersisterBatching(flowapp.BatchWriterConfig{
+			BatchSize:     wb.BatchSize,
+			FlushInterval: wb.FlushInterval.Duration,
+			QueueSize:     wb.QueueSize,
+		}))
+	}
+	persister := flowapp.NewEventPersister(store, bus, persisterOpts...)
 	if err := persister.Start(appCtx); err != nil {
 		store.Close()
 		return nil, fmt.Errorf("start event persister: %w", err)
 	}
+	// Journal entries share the persister's batched writes.
+	store.SetJournalQueue(persister.Writer())
 
 	fmt.Println("[startup] init base: resolve data dir")
 	dataDir := ""
@@ -73,6 +110,31 @@ func initBootstrapBase(appCtx context.Context, appCancel context.CancelFunc, sto
 		registry = configruntime.NewResolvingRegistry(store, runtimeManager.ResolveDriverConfig, runtimeManager.ResolveLLMConfig)
 	}
 
+	vaultKeyFile := ""
+	if bootstrapCfg != nil {
+		vaultKeyFile = bootstrapCfg.Vault.KeyFile
+	}
+	vault := vaultapp.New(vaultapp.Config{
+		Store:   store,
+		KeyFile: vaultapp.ResolveKeyFile(dataDir, vaultKeyFile),
+	})
+	if err := vault.RegisterValues(appCtx); err != nil {
+		slog.Warn("bootstrap: failed to register vault secrets for redaction", "error", err)
+	}
+	go vault.WatchValues(appCtx, vaultapp.RedactionRefreshInterval)
+
+	var provenance core.ProvenanceRecorder
+	if bootstrapCfg != nil && bootstrapCfg.Provenance.Enabled && dataDir != "" {
+		provenance = provenanceapp.New(provenanceapp.Config{
+			Store:    store,
+			Files:    filestore.NewLocal(filepath.Join(dataDir, "files")),
+			Profiles: registry,
+			KeyFile:  provenanceapp.ResolveKeyFile(dataDir, bootstrapCfg.Provenance.KeyFile),
+			GitNotes: bootstrapCfg.Provenance.GitNotes,
+			NotesRef: bootstrapCfg.Provenance.NotesRef,
+		})
+	}
+
 	return &bootstrapBase{
 		runtimeDBPath:  runtimeDBPath,
 		store:          store,
@@ -81,6 +143,9 @@ func initBootstrapBase(appCtx context.Context, appCancel context.CancelFunc, sto
 		registry:       registry,
 		runtimeManager: runtimeManager,
 		dataDir:        dataDir,
+		vault:          vault,
+		leakScan:       leakScan,
+		provenance:     provenance,
 		appCtx:         appCtx,
 		appCancel:      appCancel,
 	}, nil
@@ -8,6 +8,7 @@ import (
 
 	acpproto "github.com/coder/acp-go-sdk"
 	executoradapter "github.com/yoke233/zhanggui/internal/adapters/executor"
+	"github.com/yoke233/zhanggui/internal/adapters/leakscan"
 	"github.com/yoke233/zhanggui/internal/adapters/llm"
 	resourceprovider "github.com/yoke233/zhanggui/internal/adapters/resource/provider"
 	scmadapter "github.com/yoke233/zhanggui/internal/adapters/scm"
@@ -26,19 +27,25 @@ type flowStack struct {
 	sessionMode   string
 	sessionMgr    runtimeapp.SessionManager
 	llmClient     *llm.Client
+	compactor     *agentruntime.ContextCompactor
 	engine        *flowapp.WorkItemEngine
 	scheduler     *flowapp.WorkItemScheduler
 	schedulerStop context.CancelFunc
 }
 
 func buildFlowStack(base *bootstrapBase, bootstrapCfg *config.Config, scmTokens SCMTokens, upgradeFn executoradapter.UpgradeFunc) (*flowStack, error) {
-	sb := buildSandbox(bootstrapCfg, base.runtimeManager, base.dataDir)
+	sb := buildSandbox(bootstrapCfg, base.runtimeManager, base.dataDir, base.vault)
 	acpPool := agentruntime.NewACPSessionPool(base.store, base.bus)
 
 	sessionMgr, sessionMode := buildSessionManager(bootstrapCfg, base.store, base.dataDir, acpPool, sb)
 	llmClient := buildLLMClient(bootstrapCfg)
-	executor := buildActionExecutor(base.store, base.bus, base.registry, sessionMgr, base.runtimeManager, bootstrapCfg, base.dataDir, scmTokens, upgradeFn, base.signalCfg)
-	engine := buildWorkItemEngine(base.store, base.bus, executor, base.registry, base.runtimeManager, bootstrapCfg, base.dataDir, scmTokens, llmClient)
+	var compactor *agentruntime.ContextCompactor
+	if llmClient != nil {
+		compactor = agentruntime.NewContextCompactor(llmClient, base.store)
+		acpPool.SetContextCompactor(compactor)
+	}
+	executor := buildActionExecutor(base.store, base.bus, base.registry, sessionMgr, base.runtimeManager, bootstrapCfg, base.dataDir, scmTokens, upgradeFn, base.signalCfg, base.vault, base.leakScan, base.provenance)
+	engine := buildWorkItemEngine(base.store, base.bus, executor, base.registry, base.runtimeManager, bootstrapCfg, base.dataDir, scmTokens, llmClient, base.leakScan, base.provenance)
 	schedulerCtx, schedulerStop := context.WithCancel(base.appCtx)
 	schedulerCfg := resolveWorkItemSchedulerConfig(bootstrapCfg)
 	scheduler := flowapp.NewWorkItemScheduler(engine, base.store, base.bus, schedulerCfg)
@@ -48,6 +55,7 @@ func buildFlowStack(base *bootstrapBase, bootstrapCfg *config.Config, scmTokens
 		sessionMode:   sessionMode,
 		sessionMgr:    sessionMgr,
 		llmClient:     llmClient,
+		compactor:     compactor,
 		engine:        engine,
 		scheduler:     scheduler,
 		schedulerStop: schedulerStop,
@@ -132,6 +140,9 @@ func buildActionExecutor(
 	scmTokens SCMTokens,
 	upgradeFn executoradapter.UpgradeFunc,
 	signalCfg *AgentSignalConfig,
+	secrets core.SecretResolver,
+	leakScan *leakscan.Policy,
+	provenance core.ProvenanceRecorder,
 ) flowapp.ActionExecutor {
 	mockEnabled := bootstrapCfg != nil && bootstrapCfg.Runtime.MockExecutor
 	if !mockEnabled {
@@ -166,6 +177,7 @@ func buildActionExecutor(
 			ContinueFollowupTemplate: continueFollowupTemplate(bootstrapCfg),
 			ActionContextBuilder:     skills.NewActionContextBuilder(store),
 			AuditLogger:              auditLogger,
+			Secrets:                  secrets,
 		}
 		if signalCfg != nil {
 			acpCfg.TokenRegistry = signalCfg.TokenRegistry
@@ -180,6 +192,8 @@ func buildActionExecutor(
 			GitHub: strings.TrimSpace(scmTokens.GitHub),
 			Codeup: strings.TrimSpace(scmTokens.Codeup),
 		},
+		LeakScan:    leakScan,
+		Provenance:  provenance,
 		UpgradeFunc: upgradeFn,
 		ACPExecutor: executor,
 	})
@@ -195,6 +209,8 @@ func buildWorkItemEngine(
 	_ string, // dataDir reserved for future use
 	scmTokens SCMTokens,
 	llmClient *llm.Client,
+	leakScan *leakscan.Policy,
+	provenance core.ProvenanceRecorder,
 ) *flowapp.WorkItemEngine {
 	// Build InputBuilder with optional registry + skills root for context injection.
 	inputBuilderOpts := []flowapp.InputBuilderOption{}
@@ -221,6 +237,12 @@ func buildWorkItemEngine(
 	if bootstrapCfg != nil && bootstrapCfg.Scheduler.MaxGlobalAgents > 0 {
 		opts = append(opts, flowapp.WithConcurrency(bootstrapCfg.Scheduler.MaxGlobalAgents))
 	}
+	if leakScan != nil {
+		opts = append(opts, flowapp.WithResultScanner(leakScan))
+	}
+	if provenance != nil {
+		opts = append(opts, flowapp.WithProvenanceRecorder(provenance))
+	}
 	return flowapp.New(store, bus, executor, opts...)
 }
 
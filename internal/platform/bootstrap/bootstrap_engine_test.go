@@ -42,7 +42,7 @@ func TestBuildWorkItemEngineAppliesConfiguredAgentConcurrency(t *testing.T) {
 	cfg := config.Defaults()
 	cfg.Scheduler.MaxGlobalAgents = 6
 
-	engine := buildWorkItemEngine(store, bus, noopActionExecutor, nil, nil, &cfg, "", SCMTokens{}, nil)
+	engine := buildWorkItemEngine(store, bus, noopActionExecutor, nil, nil, &cfg, "", SCMTokens{}, nil, nil, nil)
 	if got := engine.MaxConcurrency(); got != 6 {
 		t.Fatalf("engine.MaxConcurrency() = %d, want 6", got)
 	}
@@ -56,7 +56,7 @@ func TestBuildWorkItemEngineUsesDefaultConcurrencyWhenUnset(t *testing.T) {
 	cfg := config.Defaults()
 	cfg.Scheduler.MaxGlobalAgents = 0
 
-	engine := buildWorkItemEngine(store, bus, noopActionExecutor, nil, nil, &cfg, "", SCMTokens{}, nil)
+	engine := buildWorkItemEngine(store, bus, noopActionExecutor, nil, nil, &cfg, "", SCMTokens{}, nil, nil, nil)
 	if got := engine.MaxConcurrency(); got != 4 {
 		t.Fatalf("engine.MaxConcurrency() = %d, want 4", got)
 	}
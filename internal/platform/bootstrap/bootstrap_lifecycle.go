@@ -3,13 +3,18 @@ package bootstrap
 import (
 	"context"
 	"log/slog"
+	"strings"
 
 	chatacp "github.com/yoke233/zhanggui/internal/adapters/chat/acp"
 	cronapp "github.com/yoke233/zhanggui/internal/application/cron"
+	eventsubapp "github.com/yoke233/zhanggui/internal/application/eventsub"
 	flowapp "github.com/yoke233/zhanggui/internal/application/flow"
 	inspectionapp "github.com/yoke233/zhanggui/internal/application/inspection"
 	probeapp "github.com/yoke233/zhanggui/internal/application/probe"
+	retentionapp "github.com/yoke233/zhanggui/internal/application/retention"
+	"github.com/yoke233/zhanggui/internal/audit"
 	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/backup"
 	"github.com/yoke233/zhanggui/internal/platform/config"
 	"github.com/yoke233/zhanggui/internal/platform/configruntime"
 )
@@ -20,6 +25,11 @@ type bootstrapLifecycle struct {
 	cronCancel         context.CancelFunc
 	inspectionCancel   context.CancelFunc
 	gcCancel           context.CancelFunc
+	retentionCancel    context.CancelFunc
+	checkpointCancel   context.CancelFunc
+	backupCancel       context.CancelFunc
+	eventSubCancel     context.CancelFunc
+	eventSubs          *eventsubapp.Service
 	inspectionEngine   *inspectionapp.Engine
 }
 
@@ -34,12 +44,29 @@ func startBootstrapLifecycle(
 	startProbeWatchdog(lifecycle, base.store, apiStack.probeSvc, bootstrapCfg)
 	startCronTrigger(lifecycle, base.store, base.bus, flow.scheduler, bootstrapCfg)
 	startInspectionScheduler(lifecycle, apiStack.inspectionEngine, base.bus, bootstrapCfg)
+	startRetentionScheduler(lifecycle, apiStack.retention, bootstrapCfg)
+	startJournalCheckpointScheduler(lifecycle, base.store, bootstrapCfg)
+	startBackupScheduler(lifecycle, apiStack.backup, bootstrapCfg)
 	startLeadChatGC(lifecycle, apiStack.leadAgent)
+	startEventSubscriptionDispatcher(lifecycle, apiStack.eventSubs)
 
 	return func() {
+		if lifecycle.eventSubCancel != nil {
+			lifecycle.eventSubCancel()
+			lifecycle.eventSubs.Stop()
+		}
 		if lifecycle.gcCancel != nil {
 			lifecycle.gcCancel()
 		}
+		if lifecycle.backupCancel != nil {
+			lifecycle.backupCancel()
+		}
+		if lifecycle.retentionCancel != nil {
+			lifecycle.retentionCancel()
+		}
+		if lifecycle.checkpointCancel != nil {
+			lifecycle.checkpointCancel()
+		}
 		if lifecycle.inspectionCancel != nil {
 			lifecycle.inspectionCancel()
 		}
@@ -151,6 +178,68 @@ func startInspectionScheduler(
 	go scheduler.Start(ctx)
 }
 
+func startRetentionScheduler(
+	lifecycle *bootstrapLifecycle,
+	svc *retentionapp.Service,
+	bootstrapCfg *config.Config,
+) {
+	if bootstrapCfg == nil || !bootstrapCfg.Audit.Retention.Enabled || svc == nil {
+		return
+	}
+
+	scheduler := retentionapp.NewScheduler(svc, bootstrapCfg.Audit.Retention.Interval.Duration)
+	ctx, cancel := context.WithCancel(context.Background())
+	lifecycle.retentionCancel = cancel
+	go scheduler.Start(ctx)
+}
+
+// startJournalCheckpointScheduler signs the journal hash chain periodically
+// when a checkpoint key is configured.
+func startJournalCheckpointScheduler(
+	lifecycle *bootstrapLifecycle,
+	store core.JournalChainStore,
+	bootstrapCfg *config.Config,
+) {
+	if bootstrapCfg == nil || store == nil {
+		return
+	}
+	key := strings.TrimSpace(bootstrapCfg.Audit.Checkpoint.Key)
+	if key == "" {
+		slog.Info("journal checkpoints disabled: no [audit] checkpoint_key in secrets")
+		return
+	}
+
+	scheduler := audit.NewCheckpointScheduler(store, []byte(key), bootstrapCfg.Audit.Checkpoint.Interval.Duration)
+	ctx, cancel := context.WithCancel(context.Background())
+	lifecycle.checkpointCancel = cancel
+	go scheduler.Start(ctx)
+}
+
+func startBackupScheduler(
+	lifecycle *bootstrapLifecycle,
+	svc *backup.Service,
+	bootstrapCfg *config.Config,
+) {
+	if bootstrapCfg == nil || !bootstrapCfg.Store.Backup.Enabled || svc == nil {
+		return
+	}
+
+	scheduler := backup.NewScheduler(svc, bootstrapCfg.Store.Backup.Interval.Duration)
+	ctx, cancel := context.WithCancel(context.Background())
+	lifecycle.backupCancel = cancel
+	go scheduler.Start(ctx)
+}
+
+func startEventSubscriptionDispatcher(lifecycle *bootstrapLifecycle, svc *eventsubapp.Service) {
+	if svc == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	lifecycle.eventSubCancel = cancel
+	lifecycle.eventSubs = svc
+	svc.Start(ctx)
+}
+
 func startLeadChatGC(lifecycle *bootstrapLifecycle, leadAgent *chatacp.LeadAgent) {
 	if leadAgent == nil {
 		return
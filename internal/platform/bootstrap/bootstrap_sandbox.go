@@ -2,17 +2,23 @@ package bootstrap
 
 import (
 	"github.com/yoke233/zhanggui/internal/adapters/sandbox"
+	"github.com/yoke233/zhanggui/internal/core"
 	"github.com/yoke233/zhanggui/internal/platform/config"
 	"github.com/yoke233/zhanggui/internal/platform/configruntime"
 )
 
-func buildSandbox(cfg *config.Config, runtimeManager *configruntime.Manager, dataDir string) sandbox.Sandbox {
+// buildSandbox returns the configured sandbox behind the vault, which
+// expands secret references in the launch environment first.
+func buildSandbox(cfg *config.Config, runtimeManager *configruntime.Manager, dataDir string, secrets core.SecretResolver) sandbox.Sandbox {
 	fallback := config.RuntimeSandboxConfig{}
 	if cfg != nil {
 		fallback = cfg.Runtime.Sandbox
 	}
+	var base sandbox.Sandbox
 	if runtimeManager != nil {
-		return sandbox.NewRuntimeSandbox(runtimeManager, fallback, dataDir)
+		base = sandbox.NewRuntimeSandbox(runtimeManager, fallback, dataDir)
+	} else {
+		base = sandbox.FromRuntimeConfig(fallback, dataDir)
 	}
-	return sandbox.FromRuntimeConfig(fallback, dataDir)
+	return sandbox.VaultSandbox{Base: base, Secrets: secrets}
 }
@@ -11,6 +11,7 @@ import (
 	runtimeapp "github.com/yoke233/zhanggui/internal/application/runtime"
 	"github.com/yoke233/zhanggui/internal/core"
 	"github.com/yoke233/zhanggui/internal/platform/config"
+	"github.com/yoke233/zhanggui/internal/platform/natsembed"
 	agentruntime "github.com/yoke233/zhanggui/internal/runtime/agent"
 )
 
@@ -45,39 +46,86 @@ func buildSessionManager(
 	return local(), smMode
 }
 
-func buildNATSSessionManager(cfg *config.Config, store core.Store, _ string) (*agentruntime.NATSSessionManager, error) {
+func buildNATSSessionManager(cfg *config.Config, store core.Store, dataDir string) (runtimeapp.SessionManager, error) {
 	_ = store
 	if cfg == nil {
 		return nil, fmt.Errorf("config is nil")
 	}
 	natsCfg := cfg.Runtime.SessionManager.NATS
 
-	natsURL := strings.TrimSpace(natsCfg.URL)
-	if natsURL == "" && !natsCfg.Embedded {
-		return nil, fmt.Errorf("nats.url is required when mode=nats and embedded=false")
+	prefix := strings.TrimSpace(natsCfg.StreamPrefix)
+	if prefix == "" {
+		prefix = "aiworkflow"
 	}
-	if natsCfg.Embedded && natsURL == "" {
-		return nil, fmt.Errorf("embedded NATS not yet implemented; provide nats.url")
+	serverID := strings.TrimSpace(cfg.Runtime.SessionManager.ServerID)
+
+	natsURL := strings.TrimSpace(natsCfg.URL)
+	if !natsCfg.Embedded {
+		if natsURL == "" {
+			return nil, fmt.Errorf("nats.url is required when mode=nats and embedded=false")
+		}
+		nc, err := natsConnect(natsURL)
+		if err != nil {
+			return nil, fmt.Errorf("connect to NATS: %w", err)
+		}
+		return agentruntime.NewNATSSessionManager(agentruntime.NATSSessionManagerConfig{
+			NATSConn:     nc,
+			StreamPrefix: prefix,
+			ServerID:     serverID,
+		})
 	}
 
-	nc, err := natsConnect(natsURL)
+	if natsURL != "" {
+		slog.Warn("bootstrap: nats.url is ignored when embedded=true", "url", natsURL)
+	}
+	srv, err := natsembed.Start(natsembed.Options{
+		StoreDir:  natsembed.ResolveStoreDir(dataDir, natsCfg.EmbeddedDataDir),
+		Listen:    natsCfg.EmbeddedListen,
+		Advertise: natsCfg.EmbeddedAdvertise,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("connect to NATS: %w", err)
+		return nil, err
 	}
-
-	prefix := strings.TrimSpace(natsCfg.StreamPrefix)
-	if prefix == "" {
-		prefix = "aiworkflow"
+	nc, err := natsConnect(srv.ClientURL(), srv.Credentials().Options()...)
+	if err != nil {
+		srv.Shutdown()
+		return nil, fmt.Errorf("connect to embedded NATS: %w", err)
 	}
-
-	return agentruntime.NewNATSSessionManager(agentruntime.NATSSessionManagerConfig{
+	mgr, err := agentruntime.NewNATSSessionManager(agentruntime.NATSSessionManagerConfig{
 		NATSConn:     nc,
 		StreamPrefix: prefix,
-		ServerID:     strings.TrimSpace(cfg.Runtime.SessionManager.ServerID),
+		ServerID:     serverID,
 	})
+	if err != nil {
+		nc.Close()
+		srv.Shutdown()
+		return nil, err
+	}
+	return &embeddedNATSSessionManager{NATSSessionManager: mgr, nc: nc, server: srv}, nil
+}
+
+// embeddedNATSSessionManager owns the in-process NATS server backing the
+// session manager and shuts it down after the client connection drains.
+type embeddedNATSSessionManager struct {
+	*agentruntime.NATSSessionManager
+	nc     *nats.Conn
+	server *natsembed.Server
+}
+
+func (m *embeddedNATSSessionManager) Close() {
+	m.NATSSessionManager.Close()
+	waitConnClosed(m.nc, 5*time.Second)
+	m.server.Shutdown()
+}
+
+func waitConnClosed(nc *nats.Conn, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for !nc.IsClosed() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
 }
 
-func natsConnect(url string) (*nats.Conn, error) {
+func natsConnect(url string, extra ...nats.Option) (*nats.Conn, error) {
 	opts := []nats.Option{
 		nats.RetryOnFailedConnect(true),
 		nats.MaxReconnects(10),
@@ -86,5 +134,5 @@ func natsConnect(url string) (*nats.Conn, error) {
 	if strings.TrimSpace(url) == "" {
 		return nil, fmt.Errorf("empty nats url")
 	}
-	return nats.Connect(url, opts...)
+	return nats.Connect(url, append(opts, extra...)...)
 }
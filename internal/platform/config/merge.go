@@ -29,8 +29,10 @@ func MergeForRun(global *Config, project *ConfigLayer, override map[string]any)
 
 func cloneConfig(in Config) Config {
 	out := in
+	out.Server.OIDC = cloneServerOIDCConfig(in.Server.OIDC)
 	out.GitHub = cloneGitHubConfig(in.GitHub)
 	out.Audit = cloneAuditConfig(in.Audit)
+	out.LeakScan = cloneLeakScanConfig(in.LeakScan)
 	out.Runtime = cloneRuntimeConfig(in.Runtime)
 	return out
 }
@@ -91,6 +93,41 @@ func ApplyConfigLayer(cfg *Config, layer *ConfigLayer) {
 		if server.AuthRequired != nil {
 			cfg.Server.AuthRequired = server.AuthRequired
 		}
+		if server.IdempotencyTTL != nil {
+			cfg.Server.IdempotencyTTL = *server.IdempotencyTTL
+		}
+		if oidc := server.OIDC; oidc != nil {
+			if oidc.Enabled != nil {
+				cfg.Server.OIDC.Enabled = *oidc.Enabled
+			}
+			if oidc.Issuer != nil {
+				cfg.Server.OIDC.Issuer = *oidc.Issuer
+			}
+			if oidc.ClientID != nil {
+				cfg.Server.OIDC.ClientID = *oidc.ClientID
+			}
+			if oidc.ClientSecret != nil {
+				cfg.Server.OIDC.ClientSecret = *oidc.ClientSecret
+			}
+			if oidc.RedirectURL != nil {
+				cfg.Server.OIDC.RedirectURL = *oidc.RedirectURL
+			}
+			if oidc.Scopes != nil {
+				cfg.Server.OIDC.Scopes = cloneStringSlice(*oidc.Scopes)
+			}
+			if oidc.UsernameClaim != nil {
+				cfg.Server.OIDC.UsernameClaim = *oidc.UsernameClaim
+			}
+			if oidc.GroupsClaim != nil {
+				cfg.Server.OIDC.GroupsClaim = *oidc.GroupsClaim
+			}
+			if oidc.AdminGroups != nil {
+				cfg.Server.OIDC.AdminGroups = cloneStringSlice(*oidc.AdminGroups)
+			}
+			if oidc.GroupRoles != nil {
+				cfg.Server.OIDC.GroupRoles = append([]ServerOIDCGroupRole(nil), (*oidc.GroupRoles)...)
+			}
+		}
 	}
 
 	if github := layer.GitHub; github != nil {
@@ -165,6 +202,34 @@ func ApplyConfigLayer(cfg *Config, layer *ConfigLayer) {
 		if store.Path != nil {
 			cfg.Store.Path = *store.Path
 		}
+		if backup := store.Backup; backup != nil {
+			if backup.Enabled != nil {
+				cfg.Store.Backup.Enabled = *backup.Enabled
+			}
+			if backup.Interval != nil {
+				cfg.Store.Backup.Interval = *backup.Interval
+			}
+			if backup.Dir != nil {
+				cfg.Store.Backup.Dir = *backup.Dir
+			}
+			if backup.Keep != nil {
+				cfg.Store.Backup.Keep = *backup.Keep
+			}
+			if backup.ExcludeSecrets != nil {
+				cfg.Store.Backup.ExcludeSecrets = *backup.ExcludeSecrets
+			}
+		}
+		if wb := store.WriteBehind; wb != nil {
+			if wb.BatchSize != nil {
+				cfg.Store.WriteBehind.BatchSize = *wb.BatchSize
+			}
+			if wb.FlushInterval != nil {
+				cfg.Store.WriteBehind.FlushInterval = *wb.FlushInterval
+			}
+			if wb.QueueSize != nil {
+				cfg.Store.WriteBehind.QueueSize = *wb.QueueSize
+			}
+		}
 	}
 
 	if ctx := layer.Context; ctx != nil {
@@ -215,6 +280,82 @@ func ApplyConfigLayer(cfg *Config, layer *ConfigLayer) {
 				cfg.Audit.OTLP.Headers = CloneStringMap(*otlp.Headers)
 			}
 		}
+		if retention := audit.Retention; retention != nil {
+			if retention.Enabled != nil {
+				cfg.Audit.Retention.Enabled = *retention.Enabled
+			}
+			if retention.Interval != nil {
+				cfg.Audit.Retention.Interval = *retention.Interval
+			}
+			if retention.VacuumInterval != nil {
+				cfg.Audit.Retention.VacuumInterval = *retention.VacuumInterval
+			}
+			if retention.ArchiveDir != nil {
+				cfg.Audit.Retention.ArchiveDir = *retention.ArchiveDir
+			}
+			if retention.BatchSize != nil {
+				cfg.Audit.Retention.BatchSize = *retention.BatchSize
+			}
+			if retention.Tables != nil {
+				cfg.Audit.Retention.Tables = cloneRetentionTables(*retention.Tables)
+			}
+		}
+		if checkpoint := audit.Checkpoint; checkpoint != nil {
+			if checkpoint.Interval != nil {
+				cfg.Audit.Checkpoint.Interval = *checkpoint.Interval
+			}
+			if checkpoint.Key != nil {
+				cfg.Audit.Checkpoint.Key = *checkpoint.Key
+			}
+		}
+	}
+
+	if vault := layer.Vault; vault != nil {
+		if vault.KeyFile != nil {
+			cfg.Vault.KeyFile = *vault.KeyFile
+		}
+	}
+
+	if provenance := layer.Provenance; provenance != nil {
+		if provenance.Enabled != nil {
+			cfg.Provenance.Enabled = *provenance.Enabled
+		}
+		if provenance.KeyFile != nil {
+			cfg.Provenance.KeyFile = *provenance.KeyFile
+		}
+		if provenance.GitNotes != nil {
+			cfg.Provenance.GitNotes = *provenance.GitNotes
+		}
+		if provenance.NotesRef != nil {
+			cfg.Provenance.NotesRef = *provenance.NotesRef
+		}
+	}
+
+	if leakScan := layer.LeakScan; leakScan != nil {
+		if leakScan.Enabled != nil {
+			cfg.LeakScan.Enabled = *leakScan.Enabled
+		}
+		if leakScan.EntropyThreshold != nil {
+			cfg.LeakScan.EntropyThreshold = *leakScan.EntropyThreshold
+		}
+		if leakScan.EntropyMinLength != nil {
+			cfg.LeakScan.EntropyMinLength = *leakScan.EntropyMinLength
+		}
+		if leakScan.BlockedPaths != nil {
+			cfg.LeakScan.BlockedPaths = cloneStringSlice(*leakScan.BlockedPaths)
+		}
+		if leakScan.AllowPaths != nil {
+			cfg.LeakScan.AllowPaths = cloneStringSlice(*leakScan.AllowPaths)
+		}
+		if leakScan.AllowPatterns != nil {
+			cfg.LeakScan.AllowPatterns = cloneStringSlice(*leakScan.AllowPatterns)
+		}
+		if leakScan.Rules != nil {
+			cfg.LeakScan.Rules = append([]LeakScanRuleConfig(nil), (*leakScan.Rules)...)
+		}
+		if leakScan.Projects != nil {
+			cfg.LeakScan.Projects = cloneLeakScanProjects(*leakScan.Projects)
+		}
 	}
 
 	if llmFilter := layer.LLMFilter; llmFilter != nil {
@@ -342,6 +483,12 @@ func ApplyConfigLayer(cfg *Config, layer *ConfigLayer) {
 				if n.EmbeddedDataDir != nil {
 					cfg.Runtime.SessionManager.NATS.EmbeddedDataDir = *n.EmbeddedDataDir
 				}
+				if n.EmbeddedListen != nil {
+					cfg.Runtime.SessionManager.NATS.EmbeddedListen = *n.EmbeddedListen
+				}
+				if n.EmbeddedAdvertise != nil {
+					cfg.Runtime.SessionManager.NATS.EmbeddedAdvertise = *n.EmbeddedAdvertise
+				}
 				if n.StreamPrefix != nil {
 					cfg.Runtime.SessionManager.NATS.StreamPrefix = *n.StreamPrefix
 				}
@@ -375,6 +522,46 @@ func ApplyConfigLayer(cfg *Config, layer *ConfigLayer) {
 				cfg.Runtime.Cron.Interval = *cron.Interval
 			}
 		}
+		if subs := runtime.EventSubscriptions; subs != nil {
+			if subs.Enabled != nil {
+				cfg.Runtime.EventSubscriptions.Enabled = *subs.Enabled
+			}
+			if subs.MaxAttempts != nil {
+				cfg.Runtime.EventSubscriptions.MaxAttempts = *subs.MaxAttempts
+			}
+			if subs.InitialBackoff != nil {
+				cfg.Runtime.EventSubscriptions.InitialBackoff = *subs.InitialBackoff
+			}
+			if subs.MaxBackoff != nil {
+				cfg.Runtime.EventSubscriptions.MaxBackoff = *subs.MaxBackoff
+			}
+			if subs.Timeout != nil {
+				cfg.Runtime.EventSubscriptions.Timeout = *subs.Timeout
+			}
+			if subs.PollInterval != nil {
+				cfg.Runtime.EventSubscriptions.PollInterval = *subs.PollInterval
+			}
+			if subs.Source != nil {
+				cfg.Runtime.EventSubscriptions.Source = *subs.Source
+			}
+		}
+		if limit := runtime.RateLimit; limit != nil {
+			if limit.Enabled != nil {
+				cfg.Runtime.RateLimit.Enabled = *limit.Enabled
+			}
+			if limit.RequestsPerMinute != nil {
+				cfg.Runtime.RateLimit.RequestsPerMinute = *limit.RequestsPerMinute
+			}
+			if limit.Burst != nil {
+				cfg.Runtime.RateLimit.Burst = *limit.Burst
+			}
+			if limit.Groups != nil {
+				cfg.Runtime.RateLimit.Groups = cloneRateLimitGroups(*limit.Groups)
+			}
+			if limit.Quotas != nil {
+				cfg.Runtime.RateLimit.Quotas = append([]RuntimeRateLimitQuotaConfig(nil), (*limit.Quotas)...)
+			}
+		}
 		if inspection := runtime.Inspection; inspection != nil {
 			if inspection.Enabled != nil {
 				cfg.Runtime.Inspection.Enabled = *inspection.Enabled
@@ -468,9 +655,60 @@ func cloneGitHubConfig(in GitHubConfig) GitHubConfig {
 	return out
 }
 
+func cloneServerOIDCConfig(in ServerOIDCConfig) ServerOIDCConfig {
+	out := in
+	out.Scopes = cloneStringSlice(in.Scopes)
+	out.AdminGroups = cloneStringSlice(in.AdminGroups)
+	out.GroupRoles = append([]ServerOIDCGroupRole(nil), in.GroupRoles...)
+	return out
+}
+
 func cloneAuditConfig(in AuditConfig) AuditConfig {
 	out := in
 	out.OTLP.Headers = CloneStringMap(in.OTLP.Headers)
+	out.Retention.Tables = cloneRetentionTables(in.Retention.Tables)
+	return out
+}
+
+func cloneLeakScanConfig(in LeakScanConfig) LeakScanConfig {
+	out := in
+	out.BlockedPaths = cloneStringSlice(in.BlockedPaths)
+	out.AllowPaths = cloneStringSlice(in.AllowPaths)
+	out.AllowPatterns = cloneStringSlice(in.AllowPatterns)
+	out.Rules = append([]LeakScanRuleConfig(nil), in.Rules...)
+	out.Projects = cloneLeakScanProjects(in.Projects)
+	return out
+}
+
+func cloneLeakScanProjects(in []LeakScanProjectConfig) []LeakScanProjectConfig {
+	if in == nil {
+		return nil
+	}
+	out := make([]LeakScanProjectConfig, len(in))
+	for i, p := range in {
+		out[i] = p
+		if p.Enabled != nil {
+			v := *p.Enabled
+			out[i].Enabled = &v
+		}
+		if p.EntropyThreshold != nil {
+			v := *p.EntropyThreshold
+			out[i].EntropyThreshold = &v
+		}
+		out[i].AllowPaths = cloneStringSlice(p.AllowPaths)
+		out[i].AllowPatterns = cloneStringSlice(p.AllowPatterns)
+	}
+	return out
+}
+
+func cloneRetentionTables(in map[string]AuditRetentionTableConfig) map[string]AuditRetentionTableConfig {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]AuditRetentionTableConfig, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
 	return out
 }
 
@@ -506,6 +744,22 @@ func cloneRuntimeConfig(in RuntimeConfig) RuntimeConfig {
 	out.Agents.Profiles = cloneRuntimeProfiles(in.Agents.Profiles)
 	out.MCP.Servers = cloneRuntimeMCPServers(in.MCP.Servers)
 	out.MCP.ProfileBindings = cloneRuntimeMCPBindings(in.MCP.ProfileBindings)
+	out.RateLimit.Groups = cloneRateLimitGroups(in.RateLimit.Groups)
+	if in.RateLimit.Quotas != nil {
+		out.RateLimit.Quotas = append([]RuntimeRateLimitQuotaConfig(nil), in.RateLimit.Quotas...)
+	}
+	return out
+}
+
+func cloneRateLimitGroups(in []RuntimeRateLimitGroupConfig) []RuntimeRateLimitGroupConfig {
+	if in == nil {
+		return nil
+	}
+	out := make([]RuntimeRateLimitGroupConfig, len(in))
+	for i := range in {
+		out[i] = in[i]
+		out[i].Routes = cloneStringSlice(in[i].Routes)
+	}
 	return out
 }
 
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"regexp"
 	"slices"
 	"strings"
 
@@ -33,7 +34,124 @@ func validateConfig(cfg *Config) error {
 	if err := validateAuditConfig(cfg); err != nil {
 		return err
 	}
+	if err := validateServerOIDCConfig(cfg.Server.OIDC); err != nil {
+		return err
+	}
+	if err := validateLeakScanConfig(cfg.LeakScan); err != nil {
+		return err
+	}
+	if cfg.Provenance.GitNotes && strings.TrimSpace(cfg.Provenance.NotesRef) == "" {
+		return fmt.Errorf("provenance.notes_ref is required when git_notes is enabled")
+	}
+	if cfg.Server.IdempotencyTTL.Duration < 0 {
+		return fmt.Errorf("server.idempotency_ttl must be >= 0")
+	}
+	if cfg.Store.Backup.Keep < 0 {
+		return fmt.Errorf("store.backup.keep must be >= 0")
+	}
+	if cfg.Store.WriteBehind.BatchSize < 0 || cfg.Store.WriteBehind.QueueSize < 0 || cfg.Store.WriteBehind.FlushInterval.Duration < 0 {
+		return fmt.Errorf("store.write_behind values must be >= 0")
+	}
+	if subs := cfg.Runtime.EventSubscriptions; subs.MaxAttempts < 0 || subs.InitialBackoff.Duration < 0 ||
+		subs.MaxBackoff.Duration < 0 || subs.Timeout.Duration < 0 || subs.PollInterval.Duration < 0 {
+		return fmt.Errorf("runtime.event_subscriptions values must be >= 0")
+	}
+	if err := validateRateLimitConfig(cfg.Runtime.RateLimit); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateServerOIDCConfig(oidc ServerOIDCConfig) error {
+	if !oidc.Enabled {
+		return nil
+	}
+	if strings.TrimSpace(oidc.Issuer) == "" || strings.TrimSpace(oidc.ClientID) == "" || strings.TrimSpace(oidc.RedirectURL) == "" {
+		return fmt.Errorf("server.oidc: issuer, client_id and redirect_url are required when enabled")
+	}
+	for i, gr := range oidc.GroupRoles {
+		if strings.TrimSpace(gr.Group) == "" || strings.TrimSpace(gr.Project) == "" {
+			return fmt.Errorf("server.oidc.group_roles[%d]: group and project are required", i)
+		}
+		switch strings.ToLower(strings.TrimSpace(gr.Role)) {
+		case "viewer", "operator", "approver", "admin":
+		default:
+			return fmt.Errorf("server.oidc.group_roles[%d]: role must be viewer, operator, approver or admin", i)
+		}
+	}
+	return nil
+}
+
+func validateRateLimitConfig(limit RuntimeRateLimitConfig) error {
+	if limit.RequestsPerMinute < 0 || limit.Burst < 0 {
+		return fmt.Errorf("runtime.rate_limit: requests_per_minute and burst must be >= 0")
+	}
+	if limit.Enabled && limit.RequestsPerMinute == 0 {
+		return fmt.Errorf("runtime.rate_limit.requests_per_minute is required when rate limiting is enabled")
+	}
+	groups := make(map[string]bool, len(limit.Groups))
+	for i, group := range limit.Groups {
+		name := strings.TrimSpace(group.Name)
+		if name == "" {
+			return fmt.Errorf("runtime.rate_limit.groups[%d]: name is required", i)
+		}
+		if groups[name] {
+			return fmt.Errorf("runtime.rate_limit.groups[%d]: duplicate group %q", i, name)
+		}
+		groups[name] = true
+		if group.RequestsPerMinute <= 0 || group.Burst < 0 {
+			return fmt.Errorf("runtime.rate_limit.groups[%d]: requests_per_minute must be > 0 and burst >= 0", i)
+		}
+		for _, route := range group.Routes {
+			fields := strings.Fields(route)
+			if len(fields) == 0 || len(fields) > 2 || !strings.HasPrefix(fields[len(fields)-1], "/") {
+				return fmt.Errorf("runtime.rate_limit.groups[%d]: route %q must be \"METHOD /path\" or \"/path\"", i, route)
+			}
+		}
+	}
+	for i, quota := range limit.Quotas {
+		kind, value, ok := strings.Cut(strings.TrimSpace(quota.Caller), ":")
+		if !ok || strings.TrimSpace(value) == "" {
+			return fmt.Errorf("runtime.rate_limit.quotas[%d]: caller must be user:, submitter:, role: or token: followed by a value", i)
+		}
+		switch kind {
+		case "user", "submitter", "role", "token":
+		default:
+			return fmt.Errorf("runtime.rate_limit.quotas[%d]: unknown caller kind %q", i, kind)
+		}
+		if group := strings.TrimSpace(quota.Group); group != "" && !groups[group] {
+			return fmt.Errorf("runtime.rate_limit.quotas[%d]: unknown group %q", i, group)
+		}
+		if quota.RequestsPerMinute <= 0 || quota.Burst < 0 {
+			return fmt.Errorf("runtime.rate_limit.quotas[%d]: requests_per_minute must be > 0 and burst >= 0", i)
+		}
+	}
+	return nil
+}
 
+func validateLeakScanConfig(scan LeakScanConfig) error {
+	if scan.EntropyThreshold < 0 || scan.EntropyMinLength < 0 {
+		return fmt.Errorf("leak_scan: entropy_threshold and entropy_min_length must be >= 0")
+	}
+	patterns := slices.Clone(scan.AllowPatterns)
+	for i, rule := range scan.Rules {
+		if strings.TrimSpace(rule.ID) == "" || strings.TrimSpace(rule.Pattern) == "" {
+			return fmt.Errorf("leak_scan.rules[%d]: id and pattern are required", i)
+		}
+		patterns = append(patterns, rule.Pattern)
+	}
+	for i, project := range scan.Projects {
+		if strings.TrimSpace(project.Project) == "" {
+			return fmt.Errorf("leak_scan.projects[%d]: project is required", i)
+		}
+		patterns = append(patterns, project.AllowPatterns...)
+	}
+	for _, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("leak_scan: invalid pattern %q: %w", pattern, err)
+		}
+	}
 	return nil
 }
 
@@ -47,6 +165,24 @@ func validateAuditConfig(cfg *Config) error {
 	if cfg.Audit.RetentionDays < 0 {
 		return fmt.Errorf("audit.retention_days must be >= 0")
 	}
+	if cfg.Audit.Retention.BatchSize < 0 {
+		return fmt.Errorf("audit.retention.batch_size must be >= 0")
+	}
+	for name, table := range cfg.Audit.Retention.Tables {
+		switch name {
+		case "event_log", "activity_journal", "run_output", "usage_records":
+		default:
+			return fmt.Errorf("audit.retention.tables: unknown table %q", name)
+		}
+		if table.Days < 0 {
+			return fmt.Errorf("audit.retention.tables.%s.days must be >= 0", name)
+		}
+		switch strings.TrimSpace(table.Mode) {
+		case "", "delete", "archive", "keep":
+		default:
+			return fmt.Errorf("audit.retention.tables.%s.mode must be delete, archive or keep", name)
+		}
+	}
 	return nil
 }
 
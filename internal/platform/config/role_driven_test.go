@@ -147,3 +147,32 @@ func TestValidateRuntimeAgentBindingsRejectsSelfManagerProfile(t *testing.T) {
 		t.Fatalf("Validate() error = %v, want self manager_profile_id rejection", err)
 	}
 }
+
+func TestValidateRateLimitConfig(t *testing.T) {
+	defaults := Defaults()
+	cfg := &defaults
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate(defaults) error = %v", err)
+	}
+	if len(cfg.Runtime.RateLimit.Groups) != 2 {
+		t.Fatalf("default rate limit groups = %+v", cfg.Runtime.RateLimit.Groups)
+	}
+
+	cfg.Runtime.RateLimit.Quotas = []RuntimeRateLimitQuotaConfig{{Caller: "user:7", Group: "agents", RequestsPerMinute: 60}}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	cfg.Runtime.RateLimit.Quotas[0].Group = "missing"
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), `unknown group "missing"`) {
+		t.Fatalf("Validate() error = %v, want unknown group", err)
+	}
+	cfg.Runtime.RateLimit.Quotas[0] = RuntimeRateLimitQuotaConfig{Caller: "ip:10.0.0.1", RequestsPerMinute: 60}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), `unknown caller kind "ip"`) {
+		t.Fatalf("Validate() error = %v, want unknown caller kind", err)
+	}
+	cfg.Runtime.RateLimit.Quotas = nil
+	cfg.Runtime.RateLimit.Groups[0].Routes = append(cfg.Runtime.RateLimit.Groups[0].Routes, "chat")
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), `route "chat"`) {
+		t.Fatalf("Validate() error = %v, want invalid route", err)
+	}
+}
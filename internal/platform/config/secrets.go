@@ -17,6 +17,8 @@ type Secrets struct {
 	Tokens map[string]TokenEntry `toml:"tokens" yaml:"tokens"`
 	GitHub GitHubSecrets         `toml:"github" yaml:"github"`
 	Codeup CodeupSecrets         `toml:"codeup" yaml:"codeup"`
+	OIDC   OIDCSecrets           `toml:"oidc"   yaml:"oidc"`
+	Audit  AuditSecrets          `toml:"audit"  yaml:"audit"`
 }
 
 // TokenEntry defines a named token with scoped permissions.
@@ -30,6 +32,8 @@ type Secrets struct {
 //	"runs:write"     — create/cancel runs
 //	"projects:read"  — list/get projects
 //	"projects:write" — create/update projects
+//	"secrets:read"   — list vault secrets of a project (never their values)
+//	"secrets:write"  — set and delete vault secrets of a project
 //	"chat:read"      — read chat sessions
 //	"chat:write"     — send chat messages
 //	"admin"          — admin operations (restart, force-ready, audit)
@@ -55,6 +59,16 @@ type CodeupSecrets struct {
 	PAT   string `toml:"pat"   yaml:"pat"`
 }
 
+// OIDCSecrets holds the console single sign-on client secret.
+type OIDCSecrets struct {
+	ClientSecret string `toml:"client_secret" yaml:"client_secret"`
+}
+
+// AuditSecrets holds the key that signs journal hash-chain checkpoints.
+type AuditSecrets struct {
+	CheckpointKey string `toml:"checkpoint_key" yaml:"checkpoint_key"`
+}
+
 // AdminToken returns the token value for the "admin" role entry, or empty if none.
 func (s *Secrets) AdminToken() string {
 	if s == nil {
@@ -109,7 +123,8 @@ func SaveSecrets(path string, s *Secrets) error {
 	return os.WriteFile(path, data, 0o600)
 }
 
-// ApplySecrets merges loaded secrets into a Config (GitHub credentials only).
+// ApplySecrets merges loaded secrets into a Config (GitHub credentials, the
+// OIDC client secret and the audit checkpoint key).
 // Token-based auth is handled by TokenRegistry, not Config fields.
 func ApplySecrets(cfg *Config, s *Secrets) {
 	if cfg == nil || s == nil {
@@ -124,4 +139,10 @@ func ApplySecrets(cfg *Config, s *Secrets) {
 	if s.GitHub.WebhookSecret != "" {
 		cfg.GitHub.WebhookSecret = s.GitHub.WebhookSecret
 	}
+	if s.OIDC.ClientSecret != "" {
+		cfg.Server.OIDC.ClientSecret = s.OIDC.ClientSecret
+	}
+	if s.Audit.CheckpointKey != "" {
+		cfg.Audit.Checkpoint.Key = s.Audit.CheckpointKey
+	}
 }
@@ -34,14 +34,117 @@ type Config struct {
 	Audit     AuditConfig     `toml:"audit"      yaml:"audit"`
 	LLMFilter LLMFilterConfig `toml:"llm_filter" yaml:"llm_filter"`
 	Runtime   RuntimeConfig   `toml:"runtime"    yaml:"runtime"`
+	Vault     VaultConfig     `toml:"vault"      yaml:"vault"`
+	LeakScan  LeakScanConfig  `toml:"leak_scan"  yaml:"leak_scan"`
+	// Provenance configures signed attestations of agent-produced commits.
+	Provenance ProvenanceConfig `toml:"provenance" yaml:"provenance"`
+}
+
+// ProvenanceConfig configures the in-toto provenance statements signed for
+// commits pushed by the git_commit_push builtin and change requests merged by
+// gates.
+type ProvenanceConfig struct {
+	Enabled bool `toml:"enabled" yaml:"enabled"`
+	// KeyFile is the ed25519 signing key path, relative to the data directory
+	// unless absolute (default "provenance.key"). It is created on first use.
+	KeyFile string `toml:"key_file" yaml:"key_file"`
+	// GitNotes also attaches each signed envelope to its commit as a git note
+	// under NotesRef, and pushes the notes ref with the commit.
+	GitNotes bool   `toml:"git_notes" yaml:"git_notes"`
+	NotesRef string `toml:"notes_ref" yaml:"notes_ref"`
+}
+
+// VaultConfig configures the encrypted per-project secret vault.
+type VaultConfig struct {
+	// KeyFile is the master key path, relative to the data directory unless
+	// absolute (default "vault.key"). It is created on first use; losing it
+	// makes every stored secret unreadable.
+	KeyFile string `toml:"key_file" yaml:"key_file"`
+}
+
+// LeakScanConfig configures the credential scan that runs on the staged diff
+// before builtin pushes, chat submits and change requests, and on run results
+// before they are persisted.
+type LeakScanConfig struct {
+	Enabled bool `toml:"enabled" yaml:"enabled"`
+	// EntropyThreshold flags tokens of at least EntropyMinLength characters
+	// whose Shannon entropy (bits per character) reaches it; 0 disables it.
+	EntropyThreshold float64 `toml:"entropy_threshold"  yaml:"entropy_threshold"`
+	EntropyMinLength int     `toml:"entropy_min_length" yaml:"entropy_min_length"`
+	// BlockedPaths are file globs that must never be committed, e.g. ".env".
+	BlockedPaths []string `toml:"blocked_paths" yaml:"blocked_paths"`
+	// AllowPaths are file globs that are never scanned; AllowPatterns are
+	// regular expressions that clear a matched value.
+	AllowPaths    []string `toml:"allow_paths"    yaml:"allow_paths"`
+	AllowPatterns []string `toml:"allow_patterns" yaml:"allow_patterns"`
+	// Rules are extra regex rules on top of the built-in ones.
+	Rules []LeakScanRuleConfig `toml:"rules" yaml:"rules"`
+	// Projects override the scan per project, matched by project name.
+	Projects []LeakScanProjectConfig `toml:"projects" yaml:"projects"`
+}
+
+// LeakScanRuleConfig is a regex rule. When the pattern has a capture group,
+// the first group is the secret; otherwise the whole match is.
+type LeakScanRuleConfig struct {
+	ID          string `toml:"id"          yaml:"id"`
+	Description string `toml:"description" yaml:"description"`
+	Pattern     string `toml:"pattern"     yaml:"pattern"`
+}
+
+// LeakScanProjectConfig adjusts the scan for one project. Allow lists are
+// added to the global ones.
+type LeakScanProjectConfig struct {
+	Project          string   `toml:"project"           yaml:"project"`
+	Enabled          *bool    `toml:"enabled"           yaml:"enabled"`
+	EntropyThreshold *float64 `toml:"entropy_threshold" yaml:"entropy_threshold"`
+	AllowPaths       []string `toml:"allow_paths"       yaml:"allow_paths"`
+	AllowPatterns    []string `toml:"allow_patterns"    yaml:"allow_patterns"`
 }
 
 type AuditConfig struct {
-	Enabled        bool            `toml:"enabled"         yaml:"enabled"`
-	FallbackDir    string          `toml:"fallback_dir"    yaml:"fallback_dir"`
-	RetentionDays  int             `toml:"retention_days"  yaml:"retention_days"`
-	RedactionLevel string          `toml:"redaction_level" yaml:"redaction_level"`
-	OTLP           AuditOTLPConfig `toml:"otlp"            yaml:"otlp"`
+	Enabled        bool                  `toml:"enabled"         yaml:"enabled"`
+	FallbackDir    string                `toml:"fallback_dir"    yaml:"fallback_dir"`
+	RetentionDays  int                   `toml:"retention_days"  yaml:"retention_days"`
+	RedactionLevel string                `toml:"redaction_level" yaml:"redaction_level"`
+	OTLP           AuditOTLPConfig       `toml:"otlp"            yaml:"otlp"`
+	Retention      AuditRetentionConfig  `toml:"retention"       yaml:"retention"`
+	Checkpoint     AuditCheckpointConfig `toml:"checkpoint"      yaml:"checkpoint"`
+}
+
+// AuditCheckpointConfig configures the signed checkpoints of the activity
+// journal hash chain. Keep Key in secrets.toml ([audit] checkpoint_key);
+// without it no checkpoints are written.
+type AuditCheckpointConfig struct {
+	// Interval between checkpoints (default "1h").
+	Interval Duration `toml:"interval" yaml:"interval" json:"interval"`
+	Key      string   `toml:"key"      yaml:"key"      json:"-"`
+}
+
+// AuditRetentionConfig configures the background data-retention service that
+// enforces RetentionDays.
+type AuditRetentionConfig struct {
+	Enabled bool `toml:"enabled" yaml:"enabled" json:"enabled"`
+	// Interval is how often the service prunes (default "24h").
+	Interval Duration `toml:"interval" yaml:"interval" json:"interval"`
+	// VacuumInterval is the minimum time between VACUUMs. Zero disables VACUUM;
+	// the WAL is still checkpointed after every run.
+	VacuumInterval Duration `toml:"vacuum_interval" yaml:"vacuum_interval" json:"vacuum_interval"`
+	// ArchiveDir receives rows of tables in "archive" mode. Relative paths
+	// resolve against the data dir. Default: "<data_dir>/archive".
+	ArchiveDir string `toml:"archive_dir" yaml:"archive_dir" json:"archive_dir"`
+	// BatchSize is the number of rows pruned per transaction (default 500).
+	BatchSize int `toml:"batch_size" yaml:"batch_size" json:"batch_size"`
+	// Tables overrides the policy per target: event_log, activity_journal,
+	// run_output, usage_records.
+	Tables map[string]AuditRetentionTableConfig `toml:"tables" yaml:"tables" json:"tables"`
+}
+
+// AuditRetentionTableConfig is the retention policy for one target.
+type AuditRetentionTableConfig struct {
+	// Days overrides audit.retention_days for this target. Zero inherits it.
+	Days int `toml:"days" yaml:"days" json:"days"`
+	// Mode is "delete" (default), "archive" or "keep".
+	Mode string `toml:"mode" yaml:"mode" json:"mode"`
 }
 
 type AuditOTLPConfig struct {
@@ -70,6 +173,43 @@ type RuntimeConfig struct {
 	RunProbe       RuntimeRunProbeConfig       `toml:"run_probe" yaml:"run_probe" json:"run_probe"`
 	Cron           RuntimeCronConfig           `toml:"cron" yaml:"cron" json:"cron"`
 	Inspection     RuntimeInspectionConfig     `toml:"inspection" yaml:"inspection" json:"inspection"`
+	// EventSubscriptions configures outbound CloudEvents delivery.
+	EventSubscriptions RuntimeEventSubscriptionsConfig `toml:"event_subscriptions" yaml:"event_subscriptions" json:"event_subscriptions"`
+	// RateLimit throttles API callers; changes apply without a restart.
+	RateLimit RuntimeRateLimitConfig `toml:"rate_limit" yaml:"rate_limit" json:"rate_limit"`
+}
+
+// RuntimeRateLimitConfig configures token-bucket limits on the HTTP API.
+// Every caller (token, user or client IP) has a default bucket; requests that
+// match a group also draw from that group's stricter bucket.
+type RuntimeRateLimitConfig struct {
+	Enabled           bool                          `toml:"enabled"             yaml:"enabled" json:"enabled"`
+	RequestsPerMinute int                           `toml:"requests_per_minute" yaml:"requests_per_minute" json:"requests_per_minute"` // default bucket refill rate (default 600)
+	Burst             int                           `toml:"burst"               yaml:"burst" json:"burst"`                             // default bucket size; 0 uses requests_per_minute (default 120)
+	Groups            []RuntimeRateLimitGroupConfig `toml:"groups"              yaml:"groups" json:"groups"`
+	Quotas            []RuntimeRateLimitQuotaConfig `toml:"quotas"              yaml:"quotas" json:"quotas"`
+}
+
+// RuntimeRateLimitGroupConfig is a named set of routes sharing a bucket. As
+// everywhere in rate_limit, a zero burst uses requests_per_minute.
+type RuntimeRateLimitGroupConfig struct {
+	Name string `toml:"name" yaml:"name" json:"name"`
+	// Routes are "METHOD /path" or "/path" patterns relative to /api. "*"
+	// matches one path segment and a trailing "/**" matches everything below.
+	Routes            []string `toml:"routes"              yaml:"routes" json:"routes"`
+	RequestsPerMinute int      `toml:"requests_per_minute" yaml:"requests_per_minute" json:"requests_per_minute"`
+	Burst             int      `toml:"burst"               yaml:"burst" json:"burst"`
+}
+
+// RuntimeRateLimitQuotaConfig overrides the limits of a single caller.
+type RuntimeRateLimitQuotaConfig struct {
+	// Caller is "user:<id>", "submitter:<name>", "role:<role>" or
+	// "token:<sha256 prefix>".
+	Caller string `toml:"caller" yaml:"caller" json:"caller"`
+	// Group restricts the quota to one group; empty overrides the default bucket.
+	Group             string `toml:"group"               yaml:"group" json:"group"`
+	RequestsPerMinute int    `toml:"requests_per_minute" yaml:"requests_per_minute" json:"requests_per_minute"`
+	Burst             int    `toml:"burst"               yaml:"burst" json:"burst"`
 }
 
 // RuntimeInspectionConfig configures the self-evolving inspection system.
@@ -85,6 +225,18 @@ type RuntimeCronConfig struct {
 	Interval Duration `toml:"interval" yaml:"interval" json:"interval"` // scan interval (default "1m")
 }
 
+// RuntimeEventSubscriptionsConfig configures delivery of domain events to
+// external event subscriptions.
+type RuntimeEventSubscriptionsConfig struct {
+	Enabled        bool     `toml:"enabled"         yaml:"enabled" json:"enabled"`
+	MaxAttempts    int      `toml:"max_attempts"    yaml:"max_attempts" json:"max_attempts"`       // attempts before a delivery is dead-lettered (default 8)
+	InitialBackoff Duration `toml:"initial_backoff" yaml:"initial_backoff" json:"initial_backoff"` // first retry delay, doubled per attempt (default "10s")
+	MaxBackoff     Duration `toml:"max_backoff"     yaml:"max_backoff" json:"max_backoff"`         // retry delay cap (default "1h")
+	Timeout        Duration `toml:"timeout"         yaml:"timeout" json:"timeout"`                 // per-request timeout (default "10s")
+	PollInterval   Duration `toml:"poll_interval"   yaml:"poll_interval" json:"poll_interval"`     // due-delivery scan interval (default "5s")
+	Source         string   `toml:"source"          yaml:"source" json:"source"`                   // CloudEvents source attribute (default "/ai-flow")
+}
+
 // RuntimeSessionManagerConfig configures the session manager mode.
 type RuntimeSessionManagerConfig struct {
 	// Mode selects the session manager implementation: "local" (default) or "nats".
@@ -113,15 +265,27 @@ type RuntimeRunProbeConfig struct {
 // RuntimeNATSConfig configures the NATS connection and JetStream settings.
 type RuntimeNATSConfig struct {
 	// URL is the NATS server URL (e.g., "nats://localhost:4222").
-	// The current implementation requires an external NATS server.
+	// Required unless Embedded is true.
 	URL string `toml:"url" yaml:"url" json:"url"`
 
-	// Embedded is reserved for a future in-process NATS mode and is not wired yet.
+	// Embedded starts an in-process JetStream server inside `ai-flow server`.
+	// Executors on the same host pick up the generated credentials from
+	// EmbeddedDataDir; remote executors use `--nats-creds`.
 	Embedded bool `toml:"embedded" yaml:"embedded" json:"embedded"`
 
-	// EmbeddedDataDir is reserved for the future embedded NATS mode.
+	// EmbeddedDataDir holds the JetStream store and generated credentials.
+	// Relative paths resolve against the data dir. Default: "<data_dir>/nats".
 	EmbeddedDataDir string `toml:"embedded_data_dir" yaml:"embedded_data_dir" json:"embedded_data_dir"`
 
+	// EmbeddedListen is the host:port the embedded server listens on.
+	// Use "0.0.0.0:4222" to accept executors from the LAN. Default: "127.0.0.1:4222".
+	EmbeddedListen string `toml:"embedded_listen" yaml:"embedded_listen" json:"embedded_listen"`
+
+	// EmbeddedAdvertise is the host[:port] remote executors dial, recorded in
+	// the generated credentials. Set it when EmbeddedListen is a wildcard
+	// address; otherwise remote executors need `--nats-url`.
+	EmbeddedAdvertise string `toml:"embedded_advertise" yaml:"embedded_advertise" json:"embedded_advertise"`
+
 	// StreamPrefix is the NATS JetStream stream name prefix. Default: "aiworkflow".
 	StreamPrefix string `toml:"stream_prefix" yaml:"stream_prefix" json:"stream_prefix"`
 }
@@ -314,6 +478,38 @@ type ServerConfig struct {
 	Host         string `toml:"host"          yaml:"host"`
 	Port         int    `toml:"port"          yaml:"port"`
 	AuthRequired *bool  `toml:"auth_required" yaml:"auth_required"`
+	// IdempotencyTTL is how long the response to a request sent with an
+	// Idempotency-Key header is kept for replay (default "24h").
+	IdempotencyTTL Duration `toml:"idempotency_ttl" yaml:"idempotency_ttl"`
+	// OIDC enables single sign-on for the web console.
+	OIDC ServerOIDCConfig `toml:"oidc" yaml:"oidc"`
+}
+
+// ServerOIDCConfig configures OpenID Connect single sign-on (authorization
+// code + PKCE) for the web console. Keep ClientSecret in secrets.toml
+// ([oidc] client_secret).
+type ServerOIDCConfig struct {
+	Enabled       bool     `toml:"enabled"        yaml:"enabled"`
+	Issuer        string   `toml:"issuer"         yaml:"issuer"`
+	ClientID      string   `toml:"client_id"      yaml:"client_id"`
+	ClientSecret  string   `toml:"client_secret"  yaml:"client_secret"`
+	RedirectURL   string   `toml:"redirect_url"   yaml:"redirect_url"`   // e.g. "https://host/api/auth/oidc/callback"
+	Scopes        []string `toml:"scopes"         yaml:"scopes"`         // default ["openid", "profile", "email"]
+	UsernameClaim string   `toml:"username_claim" yaml:"username_claim"` // default "preferred_username"
+	GroupsClaim   string   `toml:"groups_claim"   yaml:"groups_claim"`   // default "groups"
+	// AdminGroups, when set, decides the admin flag of SSO users on every
+	// login.
+	AdminGroups []string `toml:"admin_groups" yaml:"admin_groups"`
+	// GroupRoles maps groups to project roles. Memberships in the projects
+	// listed here follow the groups; other projects are managed by hand.
+	GroupRoles []ServerOIDCGroupRole `toml:"group_roles" yaml:"group_roles"`
+}
+
+// ServerOIDCGroupRole grants Role in Project to members of Group.
+type ServerOIDCGroupRole struct {
+	Group   string `toml:"group"   yaml:"group"`
+	Project string `toml:"project" yaml:"project"` // project ID or name
+	Role    string `toml:"role"    yaml:"role"`    // viewer, operator, approver or admin
 }
 
 // IsAuthRequired returns whether token authentication is enabled.
@@ -353,8 +549,35 @@ type GitHubPRConfig struct {
 }
 
 type StoreConfig struct {
-	Driver string `toml:"driver" yaml:"driver"`
-	Path   string `toml:"path"   yaml:"path"`
+	Driver      string                 `toml:"driver" yaml:"driver"`
+	Path        string                 `toml:"path"   yaml:"path"`
+	Backup      StoreBackupConfig      `toml:"backup"       yaml:"backup"`
+	WriteBehind StoreWriteBehindConfig `toml:"write_behind" yaml:"write_behind"`
+}
+
+// StoreBackupConfig configures scheduled local backups of the data directory.
+type StoreBackupConfig struct {
+	Enabled bool `toml:"enabled" yaml:"enabled"`
+	// Interval between scheduled backups (default "24h").
+	Interval Duration `toml:"interval" yaml:"interval"`
+	// Dir receives backup archives. Relative paths resolve against the data dir.
+	Dir string `toml:"dir" yaml:"dir"`
+	// Keep is the number of scheduled backups to retain; older ones are removed.
+	Keep int `toml:"keep" yaml:"keep"`
+	// ExcludeSecrets leaves secrets.toml/secrets.yaml and key files in the data
+	// dir (the vault master key, provenance signing key and embedded NATS
+	// credentials) out of scheduled backups.
+	ExcludeSecrets bool `toml:"exclude_secrets" yaml:"exclude_secrets"`
+}
+
+// StoreWriteBehindConfig tunes the batched writer that persists bus events.
+type StoreWriteBehindConfig struct {
+	// BatchSize flushes once this many records are buffered (default 256).
+	BatchSize int `toml:"batch_size" yaml:"batch_size"`
+	// FlushInterval flushes buffered records at least this often (default "50ms").
+	FlushInterval Duration `toml:"flush_interval" yaml:"flush_interval"`
+	// QueueSize bounds the intake queue; producers block when it is full (default 4096).
+	QueueSize int `toml:"queue_size" yaml:"queue_size"`
 }
 
 type ContextConfig struct {
@@ -371,24 +594,65 @@ type LogConfig struct {
 
 // ConfigLayer 表示可选覆盖层。nil 字段表示"未设置"，用于多层配置继承合并。
 type ConfigLayer struct {
-	Run       *RunLayer       `toml:"run"       yaml:"run"`
-	Scheduler *SchedulerLayer `toml:"scheduler" yaml:"scheduler"`
-	Server    *ServerLayer    `toml:"server"    yaml:"server"`
-	GitHub    *GitHubLayer    `toml:"github"    yaml:"github"`
-	Store     *StoreLayer     `toml:"store"     yaml:"store"`
-	Context   *ContextLayer   `toml:"context"   yaml:"context"`
-	Log       *LogLayer       `toml:"log"       yaml:"log"`
-	Audit     *AuditLayer     `toml:"audit"     yaml:"audit"`
-	LLMFilter *LLMFilterLayer `toml:"llm_filter" yaml:"llm_filter"`
-	Runtime   *RuntimeLayer   `toml:"runtime"   yaml:"runtime"`
+	Run        *RunLayer        `toml:"run"       yaml:"run"`
+	Scheduler  *SchedulerLayer  `toml:"scheduler" yaml:"scheduler"`
+	Server     *ServerLayer     `toml:"server"    yaml:"server"`
+	GitHub     *GitHubLayer     `toml:"github"    yaml:"github"`
+	Store      *StoreLayer      `toml:"store"     yaml:"store"`
+	Context    *ContextLayer    `toml:"context"   yaml:"context"`
+	Log        *LogLayer        `toml:"log"       yaml:"log"`
+	Audit      *AuditLayer      `toml:"audit"     yaml:"audit"`
+	LLMFilter  *LLMFilterLayer  `toml:"llm_filter" yaml:"llm_filter"`
+	Runtime    *RuntimeLayer    `toml:"runtime"   yaml:"runtime"`
+	Vault      *VaultLayer      `toml:"vault"     yaml:"vault"`
+	LeakScan   *LeakScanLayer   `toml:"leak_scan" yaml:"leak_scan"`
+	Provenance *ProvenanceLayer `toml:"provenance" yaml:"provenance"`
+}
+
+type ProvenanceLayer struct {
+	Enabled  *bool   `toml:"enabled" yaml:"enabled"`
+	KeyFile  *string `toml:"key_file" yaml:"key_file"`
+	GitNotes *bool   `toml:"git_notes" yaml:"git_notes"`
+	NotesRef *string `toml:"notes_ref" yaml:"notes_ref"`
+}
+
+type LeakScanLayer struct {
+	Enabled          *bool                    `toml:"enabled" yaml:"enabled"`
+	EntropyThreshold *float64                 `toml:"entropy_threshold" yaml:"entropy_threshold"`
+	EntropyMinLength *int                     `toml:"entropy_min_length" yaml:"entropy_min_length"`
+	BlockedPaths     *[]string                `toml:"blocked_paths" yaml:"blocked_paths"`
+	AllowPaths       *[]string                `toml:"allow_paths" yaml:"allow_paths"`
+	AllowPatterns    *[]string                `toml:"allow_patterns" yaml:"allow_patterns"`
+	Rules            *[]LeakScanRuleConfig    `toml:"rules" yaml:"rules"`
+	Projects         *[]LeakScanProjectConfig `toml:"projects" yaml:"projects"`
+}
+
+type VaultLayer struct {
+	KeyFile *string `toml:"key_file" yaml:"key_file"`
 }
 
 type AuditLayer struct {
-	Enabled        *bool           `toml:"enabled" yaml:"enabled"`
-	FallbackDir    *string         `toml:"fallback_dir" yaml:"fallback_dir"`
-	RetentionDays  *int            `toml:"retention_days" yaml:"retention_days"`
-	RedactionLevel *string         `toml:"redaction_level" yaml:"redaction_level"`
-	OTLP           *AuditOTLPLayer `toml:"otlp" yaml:"otlp"`
+	Enabled        *bool                 `toml:"enabled" yaml:"enabled"`
+	FallbackDir    *string               `toml:"fallback_dir" yaml:"fallback_dir"`
+	RetentionDays  *int                  `toml:"retention_days" yaml:"retention_days"`
+	RedactionLevel *string               `toml:"redaction_level" yaml:"redaction_level"`
+	OTLP           *AuditOTLPLayer       `toml:"otlp" yaml:"otlp"`
+	Retention      *AuditRetentionLayer  `toml:"retention" yaml:"retention"`
+	Checkpoint     *AuditCheckpointLayer `toml:"checkpoint" yaml:"checkpoint"`
+}
+
+type AuditCheckpointLayer struct {
+	Interval *Duration `toml:"interval" yaml:"interval"`
+	Key      *string   `toml:"key" yaml:"key"`
+}
+
+type AuditRetentionLayer struct {
+	Enabled        *bool                                 `toml:"enabled" yaml:"enabled"`
+	Interval       *Duration                             `toml:"interval" yaml:"interval"`
+	VacuumInterval *Duration                             `toml:"vacuum_interval" yaml:"vacuum_interval"`
+	ArchiveDir     *string                               `toml:"archive_dir" yaml:"archive_dir"`
+	BatchSize      *int                                  `toml:"batch_size" yaml:"batch_size"`
+	Tables         *map[string]AuditRetentionTableConfig `toml:"tables" yaml:"tables"`
 }
 
 type AuditOTLPLayer struct {
@@ -414,6 +678,9 @@ type RuntimeLayer struct {
 	RunProbe       *RuntimeRunProbeLayer       `toml:"run_probe" yaml:"run_probe"`
 	Cron           *RuntimeCronLayer           `toml:"cron" yaml:"cron"`
 	Inspection     *RuntimeInspectionLayer     `toml:"inspection" yaml:"inspection"`
+
+	EventSubscriptions *RuntimeEventSubscriptionsLayer `toml:"event_subscriptions" yaml:"event_subscriptions"`
+	RateLimit          *RuntimeRateLimitLayer          `toml:"rate_limit" yaml:"rate_limit"`
 }
 
 type RuntimeInspectionLayer struct {
@@ -442,11 +709,31 @@ type RuntimeCronLayer struct {
 	Interval *Duration `toml:"interval" yaml:"interval"`
 }
 
+type RuntimeEventSubscriptionsLayer struct {
+	Enabled        *bool     `toml:"enabled" yaml:"enabled"`
+	MaxAttempts    *int      `toml:"max_attempts" yaml:"max_attempts"`
+	InitialBackoff *Duration `toml:"initial_backoff" yaml:"initial_backoff"`
+	MaxBackoff     *Duration `toml:"max_backoff" yaml:"max_backoff"`
+	Timeout        *Duration `toml:"timeout" yaml:"timeout"`
+	PollInterval   *Duration `toml:"poll_interval" yaml:"poll_interval"`
+	Source         *string   `toml:"source" yaml:"source"`
+}
+
+type RuntimeRateLimitLayer struct {
+	Enabled           *bool                          `toml:"enabled" yaml:"enabled"`
+	RequestsPerMinute *int                           `toml:"requests_per_minute" yaml:"requests_per_minute"`
+	Burst             *int                           `toml:"burst" yaml:"burst"`
+	Groups            *[]RuntimeRateLimitGroupConfig `toml:"groups" yaml:"groups"`
+	Quotas            *[]RuntimeRateLimitQuotaConfig `toml:"quotas" yaml:"quotas"`
+}
+
 type RuntimeNATSLayer struct {
-	URL             *string `toml:"url"               yaml:"url"`
-	Embedded        *bool   `toml:"embedded"          yaml:"embedded"`
-	EmbeddedDataDir *string `toml:"embedded_data_dir" yaml:"embedded_data_dir"`
-	StreamPrefix    *string `toml:"stream_prefix"     yaml:"stream_prefix"`
+	URL               *string `toml:"url"                yaml:"url"`
+	Embedded          *bool   `toml:"embedded"           yaml:"embedded"`
+	EmbeddedDataDir   *string `toml:"embedded_data_dir"  yaml:"embedded_data_dir"`
+	EmbeddedListen    *string `toml:"embedded_listen"    yaml:"embedded_listen"`
+	EmbeddedAdvertise *string `toml:"embedded_advertise" yaml:"embedded_advertise"`
+	StreamPrefix      *string `toml:"stream_prefix"      yaml:"stream_prefix"`
 }
 
 type RuntimeSandboxLayer struct {
@@ -553,9 +840,24 @@ type WatchdogLayer struct {
 }
 
 type ServerLayer struct {
-	Host         *string `toml:"host"          yaml:"host"`
-	Port         *int    `toml:"port"          yaml:"port"`
-	AuthRequired *bool   `toml:"auth_required" yaml:"auth_required"`
+	Host           *string          `toml:"host"          yaml:"host"`
+	Port           *int             `toml:"port"          yaml:"port"`
+	AuthRequired   *bool            `toml:"auth_required" yaml:"auth_required"`
+	IdempotencyTTL *Duration        `toml:"idempotency_ttl" yaml:"idempotency_ttl"`
+	OIDC           *ServerOIDCLayer `toml:"oidc"          yaml:"oidc"`
+}
+
+type ServerOIDCLayer struct {
+	Enabled       *bool                  `toml:"enabled"        yaml:"enabled"`
+	Issuer        *string                `toml:"issuer"         yaml:"issuer"`
+	ClientID      *string                `toml:"client_id"      yaml:"client_id"`
+	ClientSecret  *string                `toml:"client_secret"  yaml:"client_secret"`
+	RedirectURL   *string                `toml:"redirect_url"   yaml:"redirect_url"`
+	Scopes        *[]string              `toml:"scopes"         yaml:"scopes"`
+	UsernameClaim *string                `toml:"username_claim" yaml:"username_claim"`
+	GroupsClaim   *string                `toml:"groups_claim"   yaml:"groups_claim"`
+	AdminGroups   *[]string              `toml:"admin_groups"   yaml:"admin_groups"`
+	GroupRoles    *[]ServerOIDCGroupRole `toml:"group_roles"    yaml:"group_roles"`
 }
 
 type GitHubLayer struct {
@@ -586,8 +888,24 @@ type GitHubPRLayer struct {
 }
 
 type StoreLayer struct {
-	Driver *string `toml:"driver" yaml:"driver"`
-	Path   *string `toml:"path"   yaml:"path"`
+	Driver      *string                `toml:"driver" yaml:"driver"`
+	Path        *string                `toml:"path"   yaml:"path"`
+	Backup      *StoreBackupLayer      `toml:"backup"       yaml:"backup"`
+	WriteBehind *StoreWriteBehindLayer `toml:"write_behind" yaml:"write_behind"`
+}
+
+type StoreBackupLayer struct {
+	Enabled        *bool     `toml:"enabled" yaml:"enabled"`
+	Interval       *Duration `toml:"interval" yaml:"interval"`
+	Dir            *string   `toml:"dir" yaml:"dir"`
+	Keep           *int      `toml:"keep" yaml:"keep"`
+	ExcludeSecrets *bool     `toml:"exclude_secrets" yaml:"exclude_secrets"`
+}
+
+type StoreWriteBehindLayer struct {
+	BatchSize     *int      `toml:"batch_size" yaml:"batch_size"`
+	FlushInterval *Duration `toml:"flush_interval" yaml:"flush_interval"`
+	QueueSize     *int      `toml:"queue_size" yaml:"queue_size"`
 }
 
 type ContextLayer struct {
@@ -204,7 +204,7 @@ func (m *Manager) ResolveDriverConfig(driverID string) (*core.DriverConfig, erro
 		}
 		return &cfg, nil
 	}
-	return nil, fmt.Errorf("driver %q not found", driverID)
+	return nil, fmt.Errorf("%w: %q", ErrDriverNotFound, driverID)
 }
 
 func (m *Manager) ResolveLLMConfig(llmConfigID string) (*config.RuntimeLLMEntryConfig, error) {
@@ -947,11 +947,20 @@ func hasLegacyProfileMCP(profiles []*core.AgentProfile) bool {
 	return false
 }
 
+// resolveSecretRef resolves "tokens.NAME" from secrets.toml. A vault
+// reference ("vault.NAME") depends on the project of the run, so it becomes
+// a "${vault.NAME}" placeholder that the action executor expands.
 func resolveSecretRef(secrets *config.Secrets, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if name, ok := strings.CutPrefix(ref, core.VaultRefPrefix); ok {
+		if !core.ValidVaultSecretName(name) {
+			return "", fmt.Errorf("invalid vault secret name %q", name)
+		}
+		return core.VaultPlaceholder(name), nil
+	}
 	if secrets == nil {
 		return "", fmt.Errorf("secrets unavailable")
 	}
-	ref = strings.TrimSpace(ref)
 	if ref == "" {
 		return "", nil
 	}
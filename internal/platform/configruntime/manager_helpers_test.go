@@ -305,6 +305,12 @@ func TestResolveSecretRefAndHelpers(t *testing.T) {
 	if err != nil || got != "secret-token" {
 		t.Fatalf("resolveSecretRef(found) = %q, %v", got, err)
 	}
+	if got, err := resolveSecretRef(nil, "vault.NPM_TOKEN"); err != nil || got != "${vault.NPM_TOKEN}" {
+		t.Fatalf("resolveSecretRef(vault) = %q, %v", got, err)
+	}
+	if _, err := resolveSecretRef(nil, "vault.bad-name"); err == nil {
+		t.Fatalf("resolveSecretRef(invalid vault name) should fail")
+	}
 
 	keys := mapKeys(map[string][]int{"a": {1}, "b": {2}})
 	if len(keys) != 2 {
@@ -0,0 +1,72 @@
+// Package keyfile loads and creates the secret key files kept beside the
+// runtime data, such as the vault master key and the provenance signing key.
+package keyfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolve returns the key path for a configured value, which is relative to
+// dataDir unless absolute. An empty value falls back to defaultName.
+func Resolve(dataDir, keyFile, defaultName string) string {
+	keyFile = strings.TrimSpace(keyFile)
+	if keyFile == "" {
+		keyFile = defaultName
+	}
+	if filepath.IsAbs(keyFile) || strings.TrimSpace(dataDir) == "" {
+		return filepath.Clean(keyFile)
+	}
+	return filepath.Join(dataDir, keyFile)
+}
+
+// LoadOrCreate returns the contents of the key file at path, creating it from
+// generate when it does not exist. The new key is written and synced to a
+// temp file in the same directory, then hard-linked into place: readers never
+// observe a partial file, and when processes race the first link wins and
+// every other caller returns the winner's key.
+func LoadOrCreate(path string, generate func() ([]byte, error)) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err == nil {
+		return raw, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	data, err := generate()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create key dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("create key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o600)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("write key file: %w", err)
+	}
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return os.ReadFile(path)
+		}
+		return nil, fmt.Errorf("create key file: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,70 @@
+package keyfile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestLoadOrCreateConcurrentCallersShareOneKey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys", "test.key")
+	generate := func() ([]byte, error) {
+		key := make([]byte, 32)
+		_, err := rand.Read(key)
+		return key, err
+	}
+
+	const callers = 16
+	keys := make([][]byte, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keys[i], errs[i] = LoadOrCreate(path, generate)
+		}()
+	}
+	wg.Wait()
+
+	onDisk, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read key: %v", err)
+	}
+	if len(onDisk) != 32 {
+		t.Fatalf("key file holds %d bytes, want 32", len(onDisk))
+	}
+	for i := range callers {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: %v", i, errs[i])
+		}
+		if !bytes.Equal(keys[i], onDisk) {
+			t.Fatalf("caller %d got a different key than the one on disk", i)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("key dir has %d entries, want only the key file", len(entries))
+	}
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Fatalf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestResolve(t *testing.T) {
+	if got := Resolve("/data", "", "vault.key"); got != filepath.Join("/data", "vault.key") {
+		t.Fatalf("default = %q", got)
+	}
+	abs := filepath.Join(t.TempDir(), "k.key")
+	if got := Resolve("/data", abs, "vault.key"); got != abs {
+		t.Fatalf("absolute = %q", got)
+	}
+}
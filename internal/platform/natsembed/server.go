@@ -0,0 +1,359 @@
+// Package natsembed runs an in-process NATS JetStream server so that
+// `ai-flow server` can host distributed execution without an external broker.
+package natsembed
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// DefaultListen is the listen address used when Options.Listen is empty.
+	DefaultListen = "127.0.0.1:4222"
+
+	// CredentialsFileName is the file (inside the store dir) that holds the
+	// generated executor credentials.
+	CredentialsFileName = "credentials.json"
+
+	defaultUser  = "ai-flow"
+	readyTimeout = 10 * time.Second
+)
+
+// Options configures the embedded server.
+type Options struct {
+	// StoreDir holds the JetStream file store and the generated credentials.
+	StoreDir string
+
+	// Listen is the host:port to accept client connections on.
+	// Port 0 or -1 picks a random free port (useful for tests).
+	Listen string
+
+	// Advertise is the host or host:port remote executors dial, written to
+	// the credentials file. Required for a remote-reachable URL when Listen
+	// is a wildcard address such as 0.0.0.0, which is not dialable.
+	Advertise string
+
+	// ServerName identifies the server in JetStream metadata. Default: "ai-flow-embedded".
+	ServerName string
+}
+
+// Credentials are the connection details executors need to reach the embedded server.
+type Credentials struct {
+	URL      string `json:"url"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// Options returns NATS client options that authenticate with these credentials.
+func (c Credentials) Options() []nats.Option {
+	if strings.TrimSpace(c.User) == "" {
+		return nil
+	}
+	return []nats.Option{nats.UserInfo(c.User, c.Password)}
+}
+
+// Server is a running embedded NATS JetStream server.
+type Server struct {
+	ns    *server.Server
+	creds Credentials
+	// localURL reaches the server from this host; it differs from creds.URL
+	// when Listen is a wildcard address.
+	localURL string
+}
+
+// Start launches the embedded server and blocks until it accepts connections.
+// Credentials are generated on first start and reused afterwards so that
+// executors configured with a copied credentials file keep working across restarts.
+func Start(opts Options) (*Server, error) {
+	storeDir := strings.TrimSpace(opts.StoreDir)
+	if storeDir == "" {
+		return nil, fmt.Errorf("embedded nats: store dir is required")
+	}
+	if err := os.MkdirAll(storeDir, 0o700); err != nil {
+		return nil, fmt.Errorf("embedded nats: create store dir: %w", err)
+	}
+
+	host, port, err := splitListen(opts.Listen)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := loadOrCreateCredentials(storeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(opts.ServerName)
+	if name == "" {
+		name = "ai-flow-embedded"
+	}
+
+	// nats-server compares bcrypt hashes natively, so the plaintext password
+	// only ever lives in the credentials file handed to executors.
+	hashed, err := bcrypt.GenerateFromPassword([]byte(creds.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("embedded nats: hash password: %w", err)
+	}
+
+	advertise := strings.TrimSpace(opts.Advertise)
+	if advertise != "" {
+		if u, err := url.Parse(advertise); err == nil && u.Scheme == "nats" {
+			advertise = u.Host
+		}
+		if _, _, err := net.SplitHostPort(advertise); err != nil {
+			if port <= 0 {
+				return nil, fmt.Errorf("embedded nats: advertise %q needs a port when the listen port is random", opts.Advertise)
+			}
+			advertise = net.JoinHostPort(advertise, strconv.Itoa(port))
+		}
+	}
+
+	ns, err := server.NewServer(&server.Options{
+		ServerName:      name,
+		Host:            host,
+		Port:            port,
+		ClientAdvertise: advertise,
+		JetStream:       true,
+		StoreDir:        filepath.Join(storeDir, "jetstream"),
+		Username:        creds.User,
+		Password:        string(hashed),
+		NoSigs:          true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedded nats: create server: %w", err)
+	}
+	ns.SetLoggerV2(slogLogger{}, false, false, false)
+
+	go ns.Start()
+	if !ns.ReadyForConnections(readyTimeout) {
+		ns.Shutdown()
+		return nil, fmt.Errorf("embedded nats: server not ready within %s", readyTimeout)
+	}
+
+	localURL := ns.ClientURL()
+	if tcp, ok := ns.Addr().(*net.TCPAddr); ok {
+		localURL = "nats://" + net.JoinHostPort(loopbackFor(host), strconv.Itoa(tcp.Port))
+	}
+	switch {
+	case advertise != "":
+		creds.URL = "nats://" + advertise
+	case isWildcardHost(host):
+		// A wildcard URL would point remote executors at themselves; leave it
+		// out so that they must be given --nats-url.
+		creds.URL = ""
+		slog.Warn("embedded nats: listening on a wildcard address without embedded_advertise; remote executors need --nats-url",
+			"listen", net.JoinHostPort(host, strconv.Itoa(port)))
+	default:
+		creds.URL = localURL
+	}
+	if err := writeCredentials(storeDir, creds); err != nil {
+		ns.Shutdown()
+		return nil, err
+	}
+
+	slog.Info("embedded nats: started", "url", localURL, "advertised_url", creds.URL, "store_dir", storeDir)
+	return &Server{ns: ns, creds: creds, localURL: localURL}, nil
+}
+
+// ClientURL returns the URL in-process clients connect to.
+func (s *Server) ClientURL() string {
+	if s == nil {
+		return ""
+	}
+	return s.localURL
+}
+
+// Credentials returns the generated executor credentials.
+func (s *Server) Credentials() Credentials {
+	if s == nil {
+		return Credentials{}
+	}
+	return s.creds
+}
+
+// Connect opens a client connection to the embedded server.
+func (s *Server) Connect(extra ...nats.Option) (*nats.Conn, error) {
+	if s == nil {
+		return nil, fmt.Errorf("embedded nats: server is nil")
+	}
+	opts := append(s.creds.Options(), extra...)
+	return nats.Connect(s.localURL, opts...)
+}
+
+// Shutdown stops the server and waits for it to exit.
+func (s *Server) Shutdown() {
+	if s == nil || s.ns == nil {
+		return
+	}
+	s.ns.Shutdown()
+	s.ns.WaitForShutdown()
+}
+
+// ResolveStoreDir resolves the configured embedded data dir against dataDir.
+// An empty value defaults to "<dataDir>/nats".
+func ResolveStoreDir(dataDir string, configured string) string {
+	configured = strings.TrimSpace(configured)
+	if configured == "" {
+		return filepath.Join(dataDir, "nats")
+	}
+	if filepath.IsAbs(configured) {
+		return configured
+	}
+	return filepath.Join(dataDir, configured)
+}
+
+// CredentialsPath returns where the credentials file lives inside storeDir.
+func CredentialsPath(storeDir string) string {
+	return filepath.Join(storeDir, CredentialsFileName)
+}
+
+// LoadCredentials reads a credentials file written by Start.
+func LoadCredentials(path string) (Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("read nats credentials: %w", err)
+	}
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return Credentials{}, fmt.Errorf("parse nats credentials %s: %w", path, err)
+	}
+	return creds, nil
+}
+
+func loadOrCreateCredentials(storeDir string) (Credentials, error) {
+	creds, err := LoadCredentials(CredentialsPath(storeDir))
+	if err == nil && strings.TrimSpace(creds.User) != "" && strings.TrimSpace(creds.Password) != "" {
+		return creds, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("embedded nats: regenerating unreadable credentials", "error", err)
+	}
+	password, err := randomHex(24)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("embedded nats: generate password: %w", err)
+	}
+	return Credentials{User: defaultUser, Password: password}, nil
+}
+
+func writeCredentials(storeDir string, creds Credentials) error {
+	data, err := json.MarshalIndent(creds, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal nats credentials: %w", err)
+	}
+	if err := os.WriteFile(CredentialsPath(storeDir), data, 0o600); err != nil {
+		return fmt.Errorf("write nats credentials: %w", err)
+	}
+	return nil
+}
+
+func splitListen(listen string) (string, int, error) {
+	listen = strings.TrimSpace(listen)
+	if listen == "" {
+		listen = DefaultListen
+	}
+	if u, err := url.Parse(listen); err == nil && u.Scheme == "nats" {
+		listen = u.Host
+	}
+	host, portStr, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "", 0, fmt.Errorf("embedded nats: invalid listen address %q: %w", listen, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, fmt.Errorf("embedded nats: invalid listen port %q", portStr)
+	}
+	if port == 0 {
+		port = server.RANDOM_PORT
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return host, port, nil
+}
+
+// LocalURL returns the loopback URL of an embedded server listening on
+// listen, for executors running on the same host.
+func LocalURL(listen string) (string, error) {
+	host, port, err := splitListen(listen)
+	if err != nil {
+		return "", err
+	}
+	if port <= 0 {
+		return "", fmt.Errorf("embedded nats: listen address %q has no fixed port", listen)
+	}
+	return "nats://" + net.JoinHostPort(loopbackFor(host), strconv.Itoa(port)), nil
+}
+
+// IsWildcardURL reports whether rawURL names a wildcard host such as
+// 0.0.0.0, which clients cannot dial.
+func IsWildcardURL(rawURL string) bool {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return isWildcardHost(u.Hostname())
+}
+
+func isWildcardHost(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "" || (ip != nil && ip.IsUnspecified())
+}
+
+// loopbackFor maps a wildcard listen host to the matching loopback address.
+func loopbackFor(host string) string {
+	if !isWildcardHost(host) {
+		return host
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		return "::1"
+	}
+	return "127.0.0.1"
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// slogLogger routes nats-server logs into the application logger.
+type slogLogger struct{}
+
+func (slogLogger) Noticef(format string, v ...any) {
+	slog.Debug("embedded nats: " + fmt.Sprintf(format, v...))
+}
+
+func (slogLogger) Warnf(format string, v ...any) {
+	slog.Warn("embedded nats: " + fmt.Sprintf(format, v...))
+}
+
+func (slogLogger) Fatalf(format string, v ...any) {
+	slog.Error("embedded nats: " + fmt.Sprintf(format, v...))
+}
+
+func (slogLogger) Errorf(format string, v ...any) {
+	slog.Error("embedded nats: " + fmt.Sprintf(format, v...))
+}
+
+func (slogLogger) Debugf(format string, v ...any) {
+	slog.Debug("embedded nats: " + fmt.Sprintf(format, v...))
+}
+
+func (slogLogger) Tracef(string, ...any) {}
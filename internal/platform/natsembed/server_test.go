@@ -0,0 +1,133 @@
+package natsembed
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStartReusesCredentialsAcrossRestarts(t *testing.T) {
+	storeDir := filepath.Join(t.TempDir(), "nats")
+
+	first, err := Start(Options{StoreDir: storeDir, Listen: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("first Start() error = %v", err)
+	}
+	firstCreds := first.Credentials()
+	first.Shutdown()
+
+	second, err := Start(Options{StoreDir: storeDir, Listen: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("second Start() error = %v", err)
+	}
+	defer second.Shutdown()
+
+	if second.Credentials().Password != firstCreds.Password {
+		t.Fatal("expected credentials to be reused across restarts")
+	}
+	saved, err := LoadCredentials(CredentialsPath(storeDir))
+	if err != nil {
+		t.Fatalf("LoadCredentials() error = %v", err)
+	}
+	if saved.URL != second.ClientURL() {
+		t.Fatalf("saved URL = %q, want %q", saved.URL, second.ClientURL())
+	}
+
+	nc, err := second.Connect()
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	defer nc.Close()
+	if _, err := nc.JetStream(); err != nil {
+		t.Fatalf("JetStream() error = %v", err)
+	}
+}
+
+func TestResolveStoreDir(t *testing.T) {
+	dataDir := filepath.Join("/var", "lib", "ai-flow")
+	cases := map[string]string{
+		"":                filepath.Join(dataDir, "nats"),
+		"broker":          filepath.Join(dataDir, "broker"),
+		"/srv/nats-store": "/srv/nats-store",
+	}
+	for configured, want := range cases {
+		if got := ResolveStoreDir(dataDir, configured); got != want {
+			t.Fatalf("ResolveStoreDir(%q) = %q, want %q", configured, got, want)
+		}
+	}
+}
+
+func TestSplitListenRejectsInvalidAddress(t *testing.T) {
+	if _, _, err := splitListen("not-an-address"); err == nil {
+		t.Fatal("expected invalid listen address error")
+	}
+	host, port, err := splitListen("")
+	if err != nil || host != "127.0.0.1" || port != 4222 {
+		t.Fatalf("splitListen(\"\") = %q, %d, %v", host, port, err)
+	}
+}
+
+func TestStartWithWildcardListenKeepsWildcardOutOfCredentials(t *testing.T) {
+	storeDir := filepath.Join(t.TempDir(), "nats")
+
+	srv, err := Start(Options{StoreDir: storeDir, Listen: "0.0.0.0:0"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Shutdown()
+
+	saved, err := LoadCredentials(CredentialsPath(storeDir))
+	if err != nil {
+		t.Fatalf("LoadCredentials() error = %v", err)
+	}
+	if saved.URL != "" {
+		t.Fatalf("saved URL = %q, want none without an advertise address", saved.URL)
+	}
+	if !strings.HasPrefix(srv.ClientURL(), "nats://127.0.0.1:") {
+		t.Fatalf("ClientURL() = %q, want a loopback URL", srv.ClientURL())
+	}
+	nc, err := srv.Connect()
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	nc.Close()
+}
+
+func TestStartRecordsAdvertisedURL(t *testing.T) {
+	storeDir := filepath.Join(t.TempDir(), "nats")
+
+	srv, err := Start(Options{StoreDir: storeDir, Listen: "0.0.0.0:0", Advertise: "nats.lan:4333"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Shutdown()
+
+	saved, err := LoadCredentials(CredentialsPath(storeDir))
+	if err != nil {
+		t.Fatalf("LoadCredentials() error = %v", err)
+	}
+	if saved.URL != "nats://nats.lan:4333" {
+		t.Fatalf("saved URL = %q, want the advertised address", saved.URL)
+	}
+
+	if _, err := Start(Options{StoreDir: t.TempDir(), Listen: "0.0.0.0:0", Advertise: "nats.lan"}); err == nil {
+		t.Fatal("expected an error for an advertise address without a port on a random listen port")
+	}
+}
+
+func TestLocalURL(t *testing.T) {
+	cases := map[string]string{
+		"":              "nats://127.0.0.1:4222",
+		"0.0.0.0:4333":  "nats://127.0.0.1:4333",
+		"[::]:4333":     "nats://[::1]:4333",
+		"10.0.0.5:4222": "nats://10.0.0.5:4222",
+	}
+	for listen, want := range cases {
+		if got, err := LocalURL(listen); err != nil || got != want {
+			t.Fatalf("LocalURL(%q) = %q, %v, want %q", listen, got, err, want)
+		}
+	}
+	if !IsWildcardURL("nats://0.0.0.0:4222") || IsWildcardURL("nats://10.0.0.5:4222") {
+		t.Fatal("IsWildcardURL misclassified a URL")
+	}
+}
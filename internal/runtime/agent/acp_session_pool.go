@@ -32,6 +32,11 @@ type pooledACPSession struct {
 	turns        int
 	inputTokens  int64 // cumulative input tokens in this session
 	outputTokens int64 // cumulative output tokens in this session
+
+	// history and seed are guarded by mu. seed carries a compacted summary
+	// into the first prompt of a fresh session.
+	history conversationLog
+	seed    string
 }
 
 type acpSessionFlight struct {
@@ -76,6 +81,10 @@ type ACPSessionPool struct {
 	inflight        map[acpSessionKey]*acpSessionFlight
 	createSessionFn func(context.Context, acpSessionKey, acpSessionAcquireInput) (*pooledACPSession, *core.AgentContext, error)
 
+	// compactor summarises sessions nearing their token budget so runs can
+	// continue in a fresh session instead of failing.
+	compactor *ContextCompactor
+
 	sub *core.Subscription
 }
 
@@ -102,6 +111,19 @@ func NewACPSessionPool(store AgentContextStore, bus core.EventBus) *ACPSessionPo
 	return p
 }
 
+// SetContextCompactor enables automatic context compaction for pooled sessions.
+func (p *ACPSessionPool) SetContextCompactor(compactor *ContextCompactor) {
+	if p == nil {
+		return
+	}
+	p.compactor = compactor
+}
+
+// CompactionEnabled reports whether sessions over budget can be compacted.
+func (p *ACPSessionPool) CompactionEnabled() bool {
+	return p != nil && p.compactor.Enabled()
+}
+
 func (p *ACPSessionPool) Close() {
 	if p == nil {
 		return
@@ -340,6 +362,87 @@ func (p *ACPSessionPool) Invalidate(ctx context.Context, sess *pooledACPSession,
 	}
 }
 
+// Compact summarises sess's conversation into ac.Summary, discards the ACP
+// session and acquires a fresh one whose first prompt is seeded with the
+// summary. Caller holds sess.mu.
+func (p *ACPSessionPool) Compact(ctx context.Context, sess *pooledACPSession, ac *core.AgentContext, in acpSessionAcquireInput) (*pooledACPSession, *core.AgentContext, error) {
+	if !p.CompactionEnabled() {
+		return nil, nil, fmt.Errorf("context compaction is not configured")
+	}
+	if sess == nil || in.Profile == nil {
+		return nil, nil, fmt.Errorf("session and profile required")
+	}
+	priorSummary := ""
+	if ac != nil {
+		priorSummary = ac.Summary
+	}
+	summary, err := p.compactor.Summarize(ctx, compactionRequest{
+		AgentID:      in.Profile.ID,
+		Scope:        fmt.Sprintf("work item %d", in.WorkItemID),
+		PriorSummary: priorSummary,
+		Turns:        sess.history.snapshot(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	_, _, inputTokens, outputTokens := sess.statsSnapshot()
+	previousSessionID := string(sess.sessionID)
+
+	p.Invalidate(ctx, sess, ac)
+	if ac != nil {
+		ac.Summary = summary
+		ac.TurnCount = 0
+		ac.UpdatedAt = time.Now().UTC()
+		if err := p.store.UpdateAgentContext(ctx, ac); err != nil {
+			slog.Warn("runtime acp pool: persist compacted summary failed",
+				"agent", ac.AgentID, "workitem_id", ac.WorkItemID, "error", err)
+		}
+	}
+
+	next, nextAC, err := p.Acquire(ctx, in)
+	if err != nil {
+		return nil, nextAC, fmt.Errorf("acquire compacted session: %w", err)
+	}
+	if nextAC == nil {
+		nextAC = ac
+	}
+	next.seed = compactionSeedPrefix(summary)
+
+	var agentCtxID int64
+	if nextAC != nil {
+		agentCtxID = nextAC.ID
+	}
+	p.compactor.recordJournal(ctx, &core.JournalEntry{
+		WorkItemID: in.WorkItemID,
+		ActionID:   in.ActionID,
+		RunID:      in.RunID,
+		Summary:    fmt.Sprintf("context compacted for agent %s", in.Profile.ID),
+		Payload: map[string]any{
+			"agent_id":            in.Profile.ID,
+			"agent_context_id":    agentCtxID,
+			"previous_session_id": previousSessionID,
+			"session_id":          string(next.sessionID),
+			"input_tokens":        inputTokens,
+			"output_tokens":       outputTokens,
+			"max_tokens":          in.Profile.Session.MaxContextTokens,
+			"summary":             summary,
+		},
+		Actor: in.Profile.ID,
+	})
+
+	slog.Info("runtime acp pool: context compacted",
+		"workitem_id", in.WorkItemID, "agent", in.Profile.ID,
+		"previous_session_id", previousSessionID, "session_id", string(next.sessionID))
+	return next, nextAC, nil
+}
+
+// takeSeed returns and clears the pending compaction seed. Caller holds s.mu.
+func (s *pooledACPSession) takeSeed() string {
+	seed := s.seed
+	s.seed = ""
+	return seed
+}
+
 func (p *ACPSessionPool) findAgentContext(ctx context.Context, agentID string, workItemID int64) (*core.AgentContext, error) {
 	if p == nil || p.store == nil {
 		return nil, core.ErrNotFound
@@ -0,0 +1,149 @@
+package agentruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+const (
+	compactionHistoryTurns = 40
+	compactionMessageChars = 4000
+	compactionTimeout      = 2 * time.Minute
+)
+
+// TextCompleter generates free-form text from a prompt. Implemented by *llm.Client.
+type TextCompleter interface {
+	CompleteText(ctx context.Context, prompt string) (string, error)
+}
+
+// ContextCompactor summarises a session's conversation when it nears the
+// profile's context budget so the agent can continue in a fresh ACP session.
+type ContextCompactor struct {
+	llm     TextCompleter
+	journal core.JournalStore
+}
+
+// NewContextCompactor creates a compactor. journal is optional; when set,
+// every compaction is recorded as a context journal entry.
+func NewContextCompactor(llm TextCompleter, journal core.JournalStore) *ContextCompactor {
+	return &ContextCompactor{llm: llm, journal: journal}
+}
+
+// Enabled reports whether the compactor can summarise.
+func (c *ContextCompactor) Enabled() bool {
+	return c != nil && c.llm != nil
+}
+
+// compactionTurn is one prompt/reply exchange kept for summarisation.
+type compactionTurn struct {
+	prompt string
+	reply  string
+}
+
+// conversationLog keeps the most recent exchanges of a session. Callers
+// synchronise access.
+type conversationLog struct {
+	turns []compactionTurn
+}
+
+func (l *conversationLog) add(prompt, reply string) {
+	l.turns = append(l.turns, compactionTurn{
+		prompt: truncateCompactionText(prompt),
+		reply:  truncateCompactionText(reply),
+	})
+	if extra := len(l.turns) - compactionHistoryTurns; extra > 0 {
+		l.turns = append([]compactionTurn(nil), l.turns[extra:]...)
+	}
+}
+
+func (l *conversationLog) snapshot() []compactionTurn {
+	return append([]compactionTurn(nil), l.turns...)
+}
+
+type compactionRequest struct {
+	AgentID      string
+	Scope        string // human-readable owner, e.g. "thread 12" or "work item 5"
+	PriorSummary string
+	Turns        []compactionTurn
+}
+
+// Summarize asks the LLM for a hand-off summary of the conversation so far.
+func (c *ContextCompactor) Summarize(ctx context.Context, req compactionRequest) (string, error) {
+	if !c.Enabled() {
+		return "", errors.New("context compaction is not configured")
+	}
+	if len(req.Turns) == 0 && strings.TrimSpace(req.PriorSummary) == "" {
+		return "", errors.New("nothing to compact")
+	}
+	ctx, cancel := context.WithTimeout(ctx, compactionTimeout)
+	defer cancel()
+	summary, err := c.llm.CompleteText(ctx, buildCompactionPrompt(req))
+	if err != nil {
+		return "", fmt.Errorf("summarise context: %w", err)
+	}
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return "", errors.New("summarise context: empty summary")
+	}
+	return summary, nil
+}
+
+func (c *ContextCompactor) recordJournal(ctx context.Context, entry *core.JournalEntry) {
+	if c == nil || c.journal == nil || entry == nil {
+		return
+	}
+	entry.Kind = core.JournalContext
+	entry.Source = core.JournalSourceSystem
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now().UTC()
+	}
+	if err := c.journal.EnqueueJournal(ctx, entry); err != nil {
+		slog.Warn("context compaction: append journal failed", "actor", entry.Actor, "error", err)
+	}
+}
+
+func buildCompactionPrompt(req compactionRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are compacting the working context of agent %q", req.AgentID)
+	if req.Scope != "" {
+		fmt.Fprintf(&b, " in %s", req.Scope)
+	}
+	b.WriteString(". The agent will continue in a fresh session that only sees your summary.\n")
+	b.WriteString("Write a concise hand-off (under 600 words) covering: the goal, decisions made, work completed, files or artifacts touched, open questions, and the immediate next steps. Do not invent details.\n\n")
+	if prior := strings.TrimSpace(req.PriorSummary); prior != "" {
+		b.WriteString("## Earlier summary\n")
+		b.WriteString(prior)
+		b.WriteString("\n\n")
+	}
+	if len(req.Turns) > 0 {
+		b.WriteString("## Conversation since then\n")
+		for i, turn := range req.Turns {
+			fmt.Fprintf(&b, "### Turn %d\n[input]\n%s\n[agent]\n%s\n", i+1, turn.prompt, turn.reply)
+		}
+	}
+	return b.String()
+}
+
+// compactionSeedPrefix is prepended to the first prompt of a compacted run
+// session so the agent picks up where the previous session left off.
+func compactionSeedPrefix(summary string) string {
+	summary = strings.TrimSpace(summary)
+	if summary == "" {
+		return ""
+	}
+	return "## Context carried over from your previous session\n" + summary + "\n\n---\n\n"
+}
+
+func truncateCompactionText(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= compactionMessageChars {
+		return s
+	}
+	return s[:compactionMessageChars] + "...(truncated)"
+}
@@ -0,0 +1,212 @@
+package agentruntime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yoke233/zhanggui/internal/adapters/agent/acpclient"
+	membus "github.com/yoke233/zhanggui/internal/adapters/events/memory"
+	runtimeapp "github.com/yoke233/zhanggui/internal/application/runtime"
+	"github.com/yoke233/zhanggui/internal/core"
+)
+
+type fakeTextCompleter struct {
+	mu      sync.Mutex
+	reply   string
+	prompts []string
+}
+
+func (f *fakeTextCompleter) CompleteText(_ context.Context, prompt string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prompts = append(f.prompts, prompt)
+	return f.reply, nil
+}
+
+func (f *fakeTextCompleter) getPrompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.prompts...)
+}
+
+func TestConversationLogKeepsRecentTurns(t *testing.T) {
+	var log conversationLog
+	for i := 0; i < compactionHistoryTurns+5; i++ {
+		log.add(fmt.Sprintf("prompt-%d", i), strings.Repeat("x", compactionMessageChars+10))
+	}
+	turns := log.snapshot()
+	if len(turns) != compactionHistoryTurns {
+		t.Fatalf("turns = %d, want %d", len(turns), compactionHistoryTurns)
+	}
+	if turns[0].prompt != "prompt-5" {
+		t.Fatalf("oldest prompt = %q, want prompt-5", turns[0].prompt)
+	}
+	if !strings.HasSuffix(turns[0].reply, "...(truncated)") {
+		t.Fatalf("reply was not truncated: %d chars", len(turns[0].reply))
+	}
+}
+
+func TestThreadPoolCompactsContextNearBudget(t *testing.T) {
+	server := &fakeACPServer{replyText: "budget reply"}
+	profile := newTestProfile("agent-compact")
+	profile.Session.MaxContextTokens = 500
+	profile.Session.ContextWarnRatio = 0.5
+
+	store := newThreadSessionPoolTestStore(t)
+	bus := membus.NewBus()
+	ctx := context.Background()
+	llm := &fakeTextCompleter{reply: "Goal: ship it. Next: write tests."}
+
+	pool := NewThreadSessionPool(store, bus, &mockRegistry{
+		profiles: map[string]*core.AgentProfile{profile.ID: profile},
+	}, t.TempDir())
+	pool.SetContextCompactor(NewContextCompactor(llm, store))
+	var boots int
+	pool.bootstrapFn = func(ctx context.Context, cfg acpclient.BootstrapConfig) (*acpclient.BootstrapResult, error) {
+		boots++
+		return testBootstrapFn(server, fmt.Sprintf("session-%d", boots))(ctx, cfg)
+	}
+	defer pool.Close()
+
+	threadID, _ := store.CreateThread(ctx, &core.Thread{
+		Title: "Compact Thread", OwnerID: "owner-1", Status: core.ThreadActive,
+	})
+	if _, err := pool.InviteAgent(ctx, threadID, profile.ID); err != nil {
+		t.Fatalf("InviteAgent: %v", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := pool.WaitAgentReady(waitCtx, threadID, profile.ID); err != nil {
+		t.Fatalf("WaitAgentReady: %v", err)
+	}
+
+	// 150 tokens per prompt: the second message crosses the 250 token threshold.
+	for i := 0; i < 3; i++ {
+		if err := pool.SendMessage(ctx, threadID, profile.ID, fmt.Sprintf("msg-%d", i)); err != nil {
+			t.Fatalf("SendMessage[%d]: %v", i, err)
+		}
+	}
+
+	llmPrompts := llm.getPrompts()
+	if len(llmPrompts) != 1 || !strings.Contains(llmPrompts[0], "msg-0") || !strings.Contains(llmPrompts[0], "msg-1") {
+		t.Fatalf("summary prompts = %q", llmPrompts)
+	}
+	pooled := pool.sessionForKey(threadSessionKey{threadID: threadID, agentID: profile.ID})
+	if pooled == nil || pooled.sessionID != "session-2" {
+		t.Fatalf("active session = %+v, want session-2", pooled)
+	}
+	if pooled.turns != 3 || pooled.contextBase != 300 {
+		t.Fatalf("turns=%d contextBase=%d, want 3/300", pooled.turns, pooled.contextBase)
+	}
+
+	var seeded bool
+	for _, prompt := range server.getPrompts() {
+		if strings.Contains(prompt, "Goal: ship it.") {
+			seeded = true
+		}
+	}
+	if !seeded {
+		t.Fatal("fresh session boot prompt did not include the compacted summary")
+	}
+
+	member := pool.findActiveAgentMember(ctx, threadID, profile.ID)
+	if member == nil || memberGetString(member, "acp_session_id") != "session-2" {
+		t.Fatalf("member = %+v", member)
+	}
+	ac, err := store.GetAgentContext(ctx, memberGetInt64(member, "agent_context_id"))
+	if err != nil || ac.Summary != llm.reply || ac.SessionID != "session-2" {
+		t.Fatalf("agent context = %+v, err = %v", ac, err)
+	}
+
+	msgs, _ := store.ListThreadMessages(ctx, threadID, 50, 0)
+	var announced bool
+	for _, msg := range msgs {
+		if msg.Role == "system" && msg.Metadata["type"] == "context_compacted" {
+			announced = true
+		}
+	}
+	if !announced {
+		t.Fatal("expected context_compacted system message")
+	}
+	entries, err := store.ListJournal(ctx, core.JournalFilter{Kinds: []core.JournalKind{core.JournalContext}})
+	if err != nil || len(entries) != 1 || entries[0].Actor != profile.ID {
+		t.Fatalf("journal = %+v, err = %v", entries, err)
+	}
+}
+
+func TestLocalSessionManagerCompactsInsteadOfFailing(t *testing.T) {
+	server := &fakeACPServer{replyText: "run reply"}
+	store := newRuntimeTestStore(t)
+	ctx := context.Background()
+	llm := &fakeTextCompleter{reply: "Carry-over summary."}
+
+	pool := NewACPSessionPool(store, nil)
+	pool.SetContextCompactor(NewContextCompactor(llm, store))
+	var boots int
+	pool.createSessionFn = func(ctx context.Context, key acpSessionKey, in acpSessionAcquireInput) (*pooledACPSession, *core.AgentContext, error) {
+		boots++
+		sessionID := fmt.Sprintf("run-session-%d", boots)
+		switcher := &switchingEventHandler{}
+		boot, err := testBootstrapFn(server, sessionID)(ctx, acpclient.BootstrapConfig{EventHandler: switcher})
+		if err != nil {
+			return nil, nil, err
+		}
+		ac, err := store.FindAgentContext(ctx, in.Profile.ID, in.WorkItemID)
+		if err == core.ErrNotFound {
+			ac = &core.AgentContext{AgentID: in.Profile.ID, WorkItemID: in.WorkItemID}
+			ac.ID, err = store.CreateAgentContext(ctx, ac)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		ac.SessionID = sessionID
+		_ = store.UpdateAgentContext(ctx, ac)
+		return &pooledACPSession{key: key, client: boot.Client, sessionID: boot.Session.ID, events: switcher}, ac, nil
+	}
+	mgr := NewLocalSessionManager(pool, nil)
+	defer mgr.Close()
+
+	profile := newTestProfile("run-agent")
+	// 150 tokens per run: the second run starts above the warning threshold.
+	profile.Session.MaxContextTokens = 200
+	profile.Session.ContextWarnRatio = 0.5
+
+	for i := 0; i < 2; i++ {
+		handle, err := mgr.Acquire(ctx, runtimeapp.SessionAcquireInput{
+			Profile:    profile,
+			WorkDir:    t.TempDir(),
+			WorkItemID: 9,
+			Reuse:      true,
+		})
+		if err != nil {
+			t.Fatalf("Acquire[%d]: %v", i, err)
+		}
+		if _, err := mgr.StartRun(ctx, handle, fmt.Sprintf("step-%d", i)); err != nil {
+			t.Fatalf("StartRun[%d]: %v", i, err)
+		}
+		_ = mgr.Release(ctx, handle)
+	}
+
+	if boots != 2 {
+		t.Fatalf("sessions booted = %d, want 2", boots)
+	}
+	prompts := server.getPrompts()
+	if len(prompts) != 2 || !strings.HasPrefix(prompts[1], compactionSeedPrefix(llm.reply)) || !strings.HasSuffix(prompts[1], "step-1") {
+		t.Fatalf("prompts = %q", prompts)
+	}
+	if sess := pool.sessions[acpSessionKey{workItemID: 9, agentID: profile.ID}]; sess == nil || sess.seed != "" {
+		t.Fatalf("seed should be consumed by the first prompt, session = %+v", sess)
+	}
+	ac, err := store.FindAgentContext(ctx, profile.ID, 9)
+	if err != nil || ac.Summary != llm.reply || ac.SessionID != "run-session-2" {
+		t.Fatalf("agent context = %+v, err = %v", ac, err)
+	}
+	entries, err := store.ListJournal(ctx, core.JournalFilter{Kinds: []core.JournalKind{core.JournalContext}})
+	if err != nil || len(entries) != 1 || entries[0].WorkItemID != 9 {
+		t.Fatalf("journal = %+v, err = %v", entries, err)
+	}
+}
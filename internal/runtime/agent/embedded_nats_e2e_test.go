@@ -0,0 +1,295 @@
+package agentruntime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/yoke233/zhanggui/internal/adapters/store/sqlite"
+	runtimeapp "github.com/yoke233/zhanggui/internal/application/runtime"
+	"github.com/yoke233/zhanggui/internal/core"
+	"github.com/yoke233/zhanggui/internal/platform/natsembed"
+)
+
+func fixtureWorkerProfile(t *testing.T) *core.AgentProfile {
+	t.Helper()
+	_, thisFile, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("runtime.Caller failed")
+	}
+	repoRoot := filepath.Clean(filepath.Join(filepath.Dir(thisFile), "..", "..", ".."))
+	testdata := filepath.Join(repoRoot, "internal", "adapters", "agent", "acpclient", "testdata")
+	return &core.AgentProfile{
+		ID:   "fixture-worker",
+		Name: "Fixture Worker",
+		Role: core.RoleWorker,
+		Driver: core.DriverConfig{
+			LaunchCommand: "go",
+			LaunchArgs: []string{
+				"run", filepath.Join(testdata, "fixture_agent.go"),
+				filepath.Join(testdata, "codex_fixtures.json"),
+				"new_session_simple_prompt",
+			},
+		},
+	}
+}
+
+// TestEmbeddedNATS_ServerWithTwoExecutors runs the NATS session manager and two
+// executor workers in-process against an embedded JetStream server.
+func TestEmbeddedNATS_ServerWithTwoExecutors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping embedded NATS e2e test in short mode")
+	}
+
+	baseDir := t.TempDir()
+	srv, err := natsembed.Start(natsembed.Options{
+		StoreDir: filepath.Join(baseDir, "nats"),
+		Listen:   "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("start embedded nats: %v", err)
+	}
+	t.Cleanup(srv.Shutdown)
+
+	serverConn, err := srv.Connect()
+	if err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	t.Cleanup(serverConn.Close)
+
+	mgr, err := NewNATSSessionManager(NATSSessionManagerConfig{
+		NATSConn: serverConn,
+		ServerID: "e2e-server",
+	})
+	if err != nil {
+		t.Fatalf("new nats session manager: %v", err)
+	}
+
+	profile := fixtureWorkerProfile(t)
+	registry := &mockRegistry{profiles: map[string]*core.AgentProfile{profile.ID: profile}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	// Executors authenticate with the credentials file the server wrote,
+	// exactly like `ai-flow executor --nats-creds` does.
+	creds, err := natsembed.LoadCredentials(natsembed.CredentialsPath(filepath.Join(baseDir, "nats")))
+	if err != nil {
+		t.Fatalf("load credentials: %v", err)
+	}
+
+	var workersWG sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		workerID := fmt.Sprintf("executor-%d", i+1)
+		store, err := sqlite.New(filepath.Join(baseDir, workerID+".db"))
+		if err != nil {
+			t.Fatalf("open %s store: %v", workerID, err)
+		}
+		t.Cleanup(func() { _ = store.Close() })
+
+		nc, err := connectWithCredentials(creds)
+		if err != nil {
+			t.Fatalf("connect %s: %v", workerID, err)
+		}
+		t.Cleanup(nc.Close)
+
+		worker, err := NewExecutorWorker(ExecutorWorkerConfig{
+			NATSConn:       nc,
+			WorkerID:       workerID,
+			Store:          store,
+			Registry:       registry,
+			DefaultWorkDir: baseDir,
+			MaxConcurrent:  2,
+		})
+		if err != nil {
+			t.Fatalf("new executor worker: %v", err)
+		}
+		workersWG.Add(1)
+		go func() {
+			defer workersWG.Done()
+			_ = worker.Start(ctx)
+		}()
+		t.Cleanup(worker.Stop)
+	}
+
+	const runs = 3
+	results := make([]*runtimeapp.RunResult, runs)
+	errs := make([]error, runs)
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		handle, err := mgr.Acquire(ctx, runtimeapp.SessionAcquireInput{
+			Profile:    profile,
+			WorkItemID: int64(100 + i),
+			ActionID:   int64(i + 1),
+			RunID:      int64(i + 1),
+			WorkDir:    baseDir,
+		})
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		invocationID, err := mgr.StartRun(ctx, handle, "hello")
+		if err != nil {
+			t.Fatalf("start run: %v", err)
+		}
+		wg.Add(1)
+		go func(i int, invocationID string) {
+			defer wg.Done()
+			results[i], errs[i] = mgr.WatchRun(ctx, invocationID, 0, nil)
+		}(i, invocationID)
+	}
+	wg.Wait()
+
+	for i := 0; i < runs; i++ {
+		if errs[i] != nil {
+			t.Fatalf("run %d: %v", i, errs[i])
+		}
+		if results[i] == nil || results[i].Text != "HELLO_ACP_TEST" {
+			t.Fatalf("run %d result = %+v, want HELLO_ACP_TEST", i, results[i])
+		}
+	}
+
+	cancel()
+	workersWG.Wait()
+}
+
+func TestEmbeddedNATS_RejectsMissingCredentials(t *testing.T) {
+	srv, err := natsembed.Start(natsembed.Options{
+		StoreDir: filepath.Join(t.TempDir(), "nats"),
+		Listen:   "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("start embedded nats: %v", err)
+	}
+	t.Cleanup(srv.Shutdown)
+
+	if _, err := connectWithCredentials(natsembed.Credentials{URL: srv.ClientURL()}); err == nil {
+		t.Fatal("expected anonymous connection to be rejected")
+	}
+}
+
+func connectWithCredentials(creds natsembed.Credentials) (*nats.Conn, error) {
+	return nats.Connect(creds.URL, creds.Options()...)
+}
+
+// TestEmbeddedNATS_ReassignsRunFromLostExecutor routes a run to an executor
+// that stops heartbeating and checks it is re-dispatched to a live one.
+func TestEmbeddedNATS_ReassignsRunFromLostExecutor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping embedded NATS e2e test in short mode")
+	}
+
+	baseDir := t.TempDir()
+	srv, err := natsembed.Start(natsembed.Options{
+		StoreDir: filepath.Join(baseDir, "nats"),
+		Listen:   "127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("start embedded nats: %v", err)
+	}
+	t.Cleanup(srv.Shutdown)
+
+	serverConn, err := srv.Connect()
+	if err != nil {
+		t.Fatalf("connect server: %v", err)
+	}
+	t.Cleanup(serverConn.Close)
+
+	mgr, err := NewNATSSessionManager(NATSSessionManagerConfig{
+		NATSConn: serverConn,
+		ServerID: "e2e-server",
+		Fleet:    ExecutorFleetConfig{StaleAfter: 500 * time.Millisecond, OfflineAfter: time.Second},
+	})
+	if err != nil {
+		t.Fatalf("new nats session manager: %v", err)
+	}
+	t.Cleanup(mgr.Close)
+
+	// A "ghost" executor that heartbeats once and then disappears.
+	ghost, _ := json.Marshal(executorHeartbeat{WorkerID: "ghost", Labels: map[string]string{"pool": "e2e"}, Capacity: 1})
+	if err := serverConn.Publish(executorHeartbeatSubject("aiworkflow", "ghost"), ghost); err != nil {
+		t.Fatalf("publish ghost heartbeat: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for mgr.Fleet().Size() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("ghost executor never registered")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	profile := fixtureWorkerProfile(t)
+	registry := &mockRegistry{profiles: map[string]*core.AgentProfile{profile.ID: profile}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	handle, err := mgr.Acquire(ctx, runtimeapp.SessionAcquireInput{
+		Profile:      profile,
+		WorkItemID:   200,
+		ActionID:     1,
+		RunID:        1,
+		WorkDir:      baseDir,
+		Requirements: map[string]string{"pool": "e2e"},
+	})
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	invocationID, err := mgr.StartRun(ctx, handle, "hello")
+	if err != nil {
+		t.Fatalf("start run: %v", err)
+	}
+	if owner := mgr.Fleet().OwnerOf(invocationID); owner != "" {
+		t.Fatalf("OwnerOf() = %q before any executor picked the run up", owner)
+	}
+
+	// Start the real executor only after the run has been routed to the ghost.
+	store, err := sqlite.New(filepath.Join(baseDir, "live.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	nc, err := srv.Connect()
+	if err != nil {
+		t.Fatalf("connect executor: %v", err)
+	}
+	t.Cleanup(nc.Close)
+	worker, err := NewExecutorWorker(ExecutorWorkerConfig{
+		NATSConn:          nc,
+		WorkerID:          "live",
+		Store:             store,
+		Registry:          registry,
+		DefaultWorkDir:    baseDir,
+		Labels:            map[string]string{"pool": "e2e"},
+		HeartbeatInterval: 200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("new executor worker: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = worker.Start(ctx)
+	}()
+
+	result, err := mgr.WatchRun(ctx, invocationID, 0, nil)
+	if err != nil {
+		t.Fatalf("watch run: %v", err)
+	}
+	if result == nil || result.Text != "HELLO_ACP_TEST" {
+		t.Fatalf("result = %+v, want HELLO_ACP_TEST", result)
+	}
+
+	info, err := mgr.Fleet().GetExecutor(ctx, "ghost")
+	if err != nil || info.Health != runtimeapp.ExecutorOffline {
+		t.Fatalf("ghost executor = %+v, %v; want offline", info, err)
+	}
+
+	cancel()
+	<-done
+}
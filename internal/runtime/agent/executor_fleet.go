@@ -0,0 +1,370 @@
+package agentruntime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	runtimeapp "github.com/yoke233/zhanggui/internal/application/runtime"
+)
+
+const (
+	defaultHeartbeatInterval = 5 * time.Second
+	defaultStaleAfter        = 15 * time.Second
+	defaultOfflineAfter      = 30 * time.Second
+	executorControlTimeout   = 5 * time.Second
+)
+
+// executorHeartbeat is published by executors on {prefix}.executor.heartbeat.{worker_id}.
+type executorHeartbeat struct {
+	WorkerID          string            `json:"worker_id"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Drivers           []string          `json:"drivers,omitempty"`
+	SandboxKinds      []string          `json:"sandbox_kinds,omitempty"`
+	Capacity          int               `json:"capacity"`
+	ActiveRuns        []int64           `json:"active_runs,omitempty"`
+	ActiveInvocations []string          `json:"active_invocations,omitempty"`
+	Cordoned          bool              `json:"cordoned,omitempty"`
+	Draining          bool              `json:"draining,omitempty"`
+	StartedAt         time.Time         `json:"started_at"`
+	SentAt            time.Time         `json:"sent_at"`
+}
+
+// executorControlMessage is sent via request-reply on {prefix}.executor.control.{worker_id}.
+type executorControlMessage struct {
+	Action runtimeapp.ExecutorControlAction `json:"action"`
+}
+
+type executorControlReply struct {
+	Cordoned bool   `json:"cordoned"`
+	Draining bool   `json:"draining"`
+	Error    string `json:"error,omitempty"`
+}
+
+func executorHeartbeatSubject(prefix, workerID string) string {
+	return fmt.Sprintf("%s.executor.heartbeat.%s", prefix, workerID)
+}
+
+func executorControlSubject(prefix, workerID string) string {
+	return fmt.Sprintf("%s.executor.control.%s", prefix, workerID)
+}
+
+func executorTargetSubject(prefix, workerID string) string {
+	return fmt.Sprintf("%s.invocation.target.%s", prefix, workerID)
+}
+
+// natsToken makes s safe for use as a single NATS subject token or consumer name.
+func natsToken(s string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "unknown"
+	}
+	return b.String()
+}
+
+// ExecutorFleetConfig tunes heartbeat-derived health.
+type ExecutorFleetConfig struct {
+	// StaleAfter marks an executor stale (not routable) after this long without a heartbeat.
+	StaleAfter time.Duration
+	// OfflineAfter marks an executor offline; its in-flight runs are reassigned.
+	OfflineAfter time.Duration
+}
+
+type fleetEntry struct {
+	info        runtimeapp.ExecutorInfo
+	invocations []string
+	// reserved counts invocations routed to this executor that have not yet
+	// shown up in a heartbeat, so bursts do not overload one executor.
+	reserved map[string]struct{}
+	offline  bool
+}
+
+// ExecutorFleetRegistry tracks remote executors from their heartbeats and
+// picks routing targets for invocations. It implements runtimeapp.ExecutorFleet.
+type ExecutorFleetRegistry struct {
+	nc     *nats.Conn
+	prefix string
+	cfg    ExecutorFleetConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	executors map[string]*fleetEntry
+}
+
+var _ runtimeapp.ExecutorFleet = (*ExecutorFleetRegistry)(nil)
+
+// NewExecutorFleetRegistry creates an empty fleet registry.
+// nc may be nil, in which case ControlExecutor only updates local state.
+func NewExecutorFleetRegistry(nc *nats.Conn, prefix string, cfg ExecutorFleetConfig) *ExecutorFleetRegistry {
+	if cfg.StaleAfter <= 0 {
+		cfg.StaleAfter = defaultStaleAfter
+	}
+	if cfg.OfflineAfter <= 0 {
+		cfg.OfflineAfter = defaultOfflineAfter
+	}
+	if cfg.OfflineAfter < cfg.StaleAfter {
+		cfg.OfflineAfter = cfg.StaleAfter
+	}
+	return &ExecutorFleetRegistry{
+		nc:        nc,
+		prefix:    prefix,
+		cfg:       cfg,
+		now:       func() time.Time { return time.Now().UTC() },
+		executors: make(map[string]*fleetEntry),
+	}
+}
+
+// Observe records a heartbeat.
+func (f *ExecutorFleetRegistry) Observe(hb executorHeartbeat) {
+	id := strings.TrimSpace(hb.WorkerID)
+	if id == "" {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry := f.executors[id]
+	if entry == nil {
+		entry = &fleetEntry{reserved: make(map[string]struct{})}
+		f.executors[id] = entry
+		slog.Info("executor fleet: executor registered", "worker_id", id, "drivers", hb.Drivers, "sandbox_kinds", hb.SandboxKinds)
+	} else if entry.offline {
+		slog.Info("executor fleet: executor back online", "worker_id", id)
+	}
+	entry.offline = false
+	entry.info = runtimeapp.ExecutorInfo{
+		ID:              id,
+		Labels:          hb.Labels,
+		Drivers:         hb.Drivers,
+		SandboxKinds:    hb.SandboxKinds,
+		Capacity:        hb.Capacity,
+		ActiveRuns:      append([]int64{}, hb.ActiveRuns...),
+		Cordoned:        hb.Cordoned,
+		Draining:        hb.Draining,
+		StartedAt:       hb.StartedAt,
+		LastHeartbeatAt: f.now(),
+	}
+	entry.invocations = append([]string(nil), hb.ActiveInvocations...)
+	for _, inv := range hb.ActiveInvocations {
+		delete(entry.reserved, inv)
+	}
+}
+
+// Pick selects the least-loaded healthy, schedulable executor that satisfies
+// reqs, reserving a slot for invocationID. Executors in exclude are skipped.
+func (f *ExecutorFleetRegistry) Pick(invocationID string, reqs map[string]string, exclude ...string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	bestID := ""
+	bestLoad := 0.0
+	ids := make([]string, 0, len(f.executors))
+	for id := range f.executors {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		entry := f.executors[id]
+		if slices.Contains(exclude, id) {
+			continue
+		}
+		if f.healthLocked(entry, now) != runtimeapp.ExecutorHealthy || entry.info.Cordoned || entry.info.Draining {
+			continue
+		}
+		if !executorMatches(entry.info, reqs) {
+			continue
+		}
+		capacity := entry.info.Capacity
+		if capacity <= 0 {
+			capacity = 1
+		}
+		used := len(entry.info.ActiveRuns) + len(entry.reserved)
+		if used >= capacity {
+			continue
+		}
+		load := float64(used) / float64(capacity)
+		if bestID == "" || load < bestLoad {
+			bestID, bestLoad = id, load
+		}
+	}
+	if bestID == "" {
+		return "", false
+	}
+	if invocationID != "" {
+		f.executors[bestID].reserved[invocationID] = struct{}{}
+	}
+	return bestID, true
+}
+
+// Release drops a reservation once an invocation completes.
+func (f *ExecutorFleetRegistry) Release(workerID, invocationID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if entry := f.executors[workerID]; entry != nil {
+		delete(entry.reserved, invocationID)
+	}
+}
+
+// Size returns the number of executors ever seen (including offline ones).
+func (f *ExecutorFleetRegistry) Size() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.executors)
+}
+
+// OwnerOf returns the executor currently reporting invocationID as active.
+func (f *ExecutorFleetRegistry) OwnerOf(invocationID string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for id, entry := range f.executors {
+		if slices.Contains(entry.invocations, invocationID) {
+			return id
+		}
+	}
+	return ""
+}
+
+// SweepOffline marks executors whose heartbeat has lapsed past OfflineAfter and
+// returns the IDs that transitioned to offline during this sweep.
+func (f *ExecutorFleetRegistry) SweepOffline() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	var lost []string
+	for id, entry := range f.executors {
+		if entry.offline {
+			continue
+		}
+		if f.healthLocked(entry, now) == runtimeapp.ExecutorOffline {
+			entry.offline = true
+			entry.reserved = make(map[string]struct{})
+			lost = append(lost, id)
+			slog.Warn("executor fleet: executor offline", "worker_id", id, "last_heartbeat_at", entry.info.LastHeartbeatAt)
+		}
+	}
+	sort.Strings(lost)
+	return lost
+}
+
+func (f *ExecutorFleetRegistry) ListExecutors(_ context.Context) []runtimeapp.ExecutorInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	out := make([]runtimeapp.ExecutorInfo, 0, len(f.executors))
+	for _, entry := range f.executors {
+		info := entry.info
+		info.Health = f.healthLocked(entry, now)
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (f *ExecutorFleetRegistry) GetExecutor(_ context.Context, id string) (*runtimeapp.ExecutorInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry := f.executors[strings.TrimSpace(id)]
+	if entry == nil {
+		return nil, runtimeapp.ErrExecutorNotFound
+	}
+	info := entry.info
+	info.Health = f.healthLocked(entry, f.now())
+	return &info, nil
+}
+
+// ControlExecutor asks the executor to cordon, uncordon or drain itself and
+// mirrors the acknowledged state locally so routing reacts immediately.
+func (f *ExecutorFleetRegistry) ControlExecutor(ctx context.Context, id string, action runtimeapp.ExecutorControlAction) (*runtimeapp.ExecutorInfo, error) {
+	id = strings.TrimSpace(id)
+	if !action.Valid() {
+		return nil, fmt.Errorf("invalid executor control action %q", action)
+	}
+	if _, err := f.GetExecutor(ctx, id); err != nil {
+		return nil, err
+	}
+
+	reply := executorControlReply{
+		Cordoned: action != runtimeapp.ExecutorUncordon,
+		Draining: action == runtimeapp.ExecutorDrain,
+	}
+	if f.nc != nil {
+		payload, err := json.Marshal(executorControlMessage{Action: action})
+		if err != nil {
+			return nil, err
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, executorControlTimeout)
+		defer cancel()
+		msg, err := f.nc.RequestWithContext(reqCtx, executorControlSubject(f.prefix, id), payload)
+		if err != nil {
+			return nil, fmt.Errorf("executor %s unreachable: %w", id, err)
+		}
+		if err := json.Unmarshal(msg.Data, &reply); err != nil {
+			return nil, fmt.Errorf("decode executor control reply: %w", err)
+		}
+		if reply.Error != "" {
+			return nil, fmt.Errorf("executor %s rejected %s: %s", id, action, reply.Error)
+		}
+	}
+
+	f.mu.Lock()
+	if entry := f.executors[id]; entry != nil {
+		entry.info.Cordoned = reply.Cordoned
+		entry.info.Draining = reply.Draining
+	}
+	f.mu.Unlock()
+	return f.GetExecutor(ctx, id)
+}
+
+func (f *ExecutorFleetRegistry) healthLocked(entry *fleetEntry, now time.Time) runtimeapp.ExecutorHealth {
+	age := now.Sub(entry.info.LastHeartbeatAt)
+	switch {
+	case entry.offline || age > f.cfg.OfflineAfter:
+		return runtimeapp.ExecutorOffline
+	case age > f.cfg.StaleAfter:
+		return runtimeapp.ExecutorStale
+	default:
+		return runtimeapp.ExecutorHealthy
+	}
+}
+
+// executorMatches reports whether info satisfies every requirement.
+func executorMatches(info runtimeapp.ExecutorInfo, reqs map[string]string) bool {
+	for key, want := range reqs {
+		key = strings.TrimSpace(key)
+		want = strings.TrimSpace(want)
+		if key == "" || want == "" {
+			continue
+		}
+		switch key {
+		case runtimeapp.ExecutorRequirementDriver:
+			if !slices.Contains(info.Drivers, want) {
+				return false
+			}
+		case runtimeapp.ExecutorRequirementSandbox:
+			if !slices.Contains(info.SandboxKinds, want) {
+				return false
+			}
+		default:
+			if info.Labels[key] != want {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package agentruntime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	runtimeapp "github.com/yoke233/zhanggui/internal/application/runtime"
+)
+
+func newTestFleet(now *time.Time) *ExecutorFleetRegistry {
+	f := NewExecutorFleetRegistry(nil, "test", ExecutorFleetConfig{StaleAfter: 10 * time.Second, OfflineAfter: 20 * time.Second})
+	f.now = func() time.Time { return *now }
+	return f
+}
+
+func TestExecutorFleetPickMatchesRequirements(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := newTestFleet(&now)
+	f.Observe(executorHeartbeat{WorkerID: "cpu", Labels: map[string]string{"os": "linux"}, Drivers: []string{"codex"}, SandboxKinds: []string{"home_dir"}, Capacity: 2})
+	f.Observe(executorHeartbeat{WorkerID: "gpu", Labels: map[string]string{"os": "linux", "gpu": "true"}, Drivers: []string{"claude"}, SandboxKinds: []string{"docker"}, Capacity: 2})
+
+	cases := []struct {
+		name string
+		reqs map[string]string
+		want string
+		ok   bool
+	}{
+		{name: "label", reqs: map[string]string{"gpu": "true"}, want: "gpu", ok: true},
+		{name: "driver", reqs: map[string]string{runtimeapp.ExecutorRequirementDriver: "codex"}, want: "cpu", ok: true},
+		{name: "sandbox", reqs: map[string]string{runtimeapp.ExecutorRequirementSandbox: "docker"}, want: "gpu", ok: true},
+		{name: "unsatisfiable", reqs: map[string]string{"os": "windows"}, ok: false},
+	}
+	for _, tc := range cases {
+		got, ok := f.Pick("", tc.reqs)
+		if ok != tc.ok || got != tc.want {
+			t.Fatalf("%s: Pick() = %q, %v; want %q, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestExecutorFleetPickBalancesAndRespectsCapacity(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := newTestFleet(&now)
+	f.Observe(executorHeartbeat{WorkerID: "a", Capacity: 1})
+	f.Observe(executorHeartbeat{WorkerID: "b", Capacity: 1})
+
+	first, ok := f.Pick("inv-1", nil)
+	if !ok {
+		t.Fatal("expected first pick to succeed")
+	}
+	second, ok := f.Pick("inv-2", nil)
+	if !ok || second == first {
+		t.Fatalf("second pick = %q, %v; want the other executor", second, ok)
+	}
+	if _, ok := f.Pick("inv-3", nil); ok {
+		t.Fatal("expected pick to fail once every executor is at capacity")
+	}
+	f.Release(first, "inv-1")
+	if got, ok := f.Pick("inv-3", nil); !ok || got != first {
+		t.Fatalf("pick after release = %q, %v; want %q", got, ok, first)
+	}
+	if got, ok := f.Pick("inv-4", nil, first); ok {
+		t.Fatalf("pick excluding %q = %q; want none", first, got)
+	}
+}
+
+func TestExecutorFleetHealthAndSweep(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := newTestFleet(&now)
+	f.Observe(executorHeartbeat{WorkerID: "a", Capacity: 1})
+
+	now = now.Add(15 * time.Second)
+	info, err := f.GetExecutor(context.Background(), "a")
+	if err != nil || info.Health != runtimeapp.ExecutorStale {
+		t.Fatalf("GetExecutor() = %+v, %v; want stale", info, err)
+	}
+	if _, ok := f.Pick("inv", nil); ok {
+		t.Fatal("stale executors must not receive new work")
+	}
+	if lost := f.SweepOffline(); len(lost) != 0 {
+		t.Fatalf("SweepOffline() = %v before offline threshold", lost)
+	}
+
+	now = now.Add(10 * time.Second)
+	if lost := f.SweepOffline(); len(lost) != 1 || lost[0] != "a" {
+		t.Fatalf("SweepOffline() = %v, want [a]", lost)
+	}
+	if lost := f.SweepOffline(); len(lost) != 0 {
+		t.Fatalf("second SweepOffline() = %v, want none", lost)
+	}
+
+	f.Observe(executorHeartbeat{WorkerID: "a", Capacity: 1})
+	if list := f.ListExecutors(context.Background()); len(list) != 1 || list[0].Health != runtimeapp.ExecutorHealthy {
+		t.Fatalf("ListExecutors() = %+v, want one healthy executor", list)
+	}
+}
+
+func TestExecutorFleetSkipsCordonedAndDraining(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := newTestFleet(&now)
+	f.Observe(executorHeartbeat{WorkerID: "a", Capacity: 1, Cordoned: true})
+	f.Observe(executorHeartbeat{WorkerID: "b", Capacity: 1, Cordoned: true, Draining: true})
+	if got, ok := f.Pick("inv", nil); ok {
+		t.Fatalf("Pick() = %q; want none", got)
+	}
+
+	info, err := f.ControlExecutor(context.Background(), "a", runtimeapp.ExecutorUncordon)
+	if err != nil || info.Cordoned {
+		t.Fatalf("ControlExecutor(uncordon) = %+v, %v", info, err)
+	}
+	if got, ok := f.Pick("inv", nil); !ok || got != "a" {
+		t.Fatalf("Pick() after uncordon = %q, %v; want a", got, ok)
+	}
+	if _, err := f.ControlExecutor(context.Background(), "missing", runtimeapp.ExecutorCordon); !errors.Is(err, runtimeapp.ErrExecutorNotFound) {
+		t.Fatalf("ControlExecutor(missing) error = %v, want ErrExecutorNotFound", err)
+	}
+}
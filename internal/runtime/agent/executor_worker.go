@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	goruntime "runtime"
 	"strings"
 	"sync"
 	"time"
@@ -53,6 +54,18 @@ type ExecutorWorkerConfig struct {
 
 	// MaxConcurrent limits parallel run execution. Default: 2.
 	MaxConcurrent int
+
+	// Labels are free-form routing labels (os, arch and hostname are added automatically).
+	Labels map[string]string
+
+	// Drivers lists the driver IDs this worker can launch.
+	Drivers []string
+
+	// SandboxKinds lists the sandbox providers this worker applies (e.g. "home_dir", "docker").
+	SandboxKinds []string
+
+	// HeartbeatInterval controls how often the worker announces itself. Default: 5s.
+	HeartbeatInterval time.Duration
 }
 
 type activeRunProbeTarget struct {
@@ -73,12 +86,17 @@ type ExecutorWorker struct {
 	prefix string
 	pool   *ACPSessionPool
 
-	mu         sync.Mutex
-	running    int
-	cancel     context.CancelFunc
-	activeRuns map[int64]*activeRunProbeTarget
-	probeSub   *nats.Subscription
-	wg         sync.WaitGroup
+	mu          sync.Mutex
+	running     int
+	cancel      context.CancelFunc
+	activeRuns  map[int64]*activeRunProbeTarget
+	invocations map[string]int64
+	cordoned    bool
+	draining    bool
+	startedAt   time.Time
+	probeSub    *nats.Subscription
+	controlSub  *nats.Subscription
+	wg          sync.WaitGroup
 }
 
 // NewExecutorWorker creates a new remote executor worker.
@@ -112,6 +130,10 @@ func NewExecutorWorker(cfg ExecutorWorkerConfig) (*ExecutorWorker, error) {
 		maxConc = 2
 	}
 	cfg.MaxConcurrent = maxConc
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	cfg.Labels = withDefaultExecutorLabels(cfg.Labels)
 
 	var pool *ACPSessionPool
 	if cfg.Store != nil {
@@ -119,19 +141,40 @@ func NewExecutorWorker(cfg ExecutorWorkerConfig) (*ExecutorWorker, error) {
 	}
 
 	return &ExecutorWorker{
-		cfg:        cfg,
-		js:         js,
-		prefix:     prefix,
-		pool:       pool,
-		activeRuns: make(map[int64]*activeRunProbeTarget),
+		cfg:         cfg,
+		js:          js,
+		prefix:      prefix,
+		pool:        pool,
+		activeRuns:  make(map[int64]*activeRunProbeTarget),
+		invocations: make(map[string]int64),
 	}, nil
 }
 
-// Start begins consuming run messages. Blocks until ctx is cancelled.
+func withDefaultExecutorLabels(labels map[string]string) map[string]string {
+	out := map[string]string{
+		"os":   goruntime.GOOS,
+		"arch": goruntime.GOARCH,
+	}
+	if hostname, _ := os.Hostname(); hostname != "" {
+		out["hostname"] = hostname
+	}
+	for k, v := range labels {
+		if k = strings.TrimSpace(k); k != "" {
+			out[k] = strings.TrimSpace(v)
+		}
+	}
+	return out
+}
+
+// Start begins consuming run messages. Blocks until ctx is cancelled, or
+// returns nil once a drained worker has finished its active runs.
 func (w *ExecutorWorker) Start(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	w.cancel = cancel
 	defer cancel()
+	w.mu.Lock()
+	w.startedAt = time.Now().UTC()
+	w.mu.Unlock()
 
 	probeSubject := fmt.Sprintf("%s.probe.request.%s", w.prefix, w.cfg.WorkerID)
 	probeSub, err := w.cfg.NATSConn.Subscribe(probeSubject, w.handleProbeRequest)
@@ -141,32 +184,86 @@ func (w *ExecutorWorker) Start(ctx context.Context) error {
 	w.probeSub = probeSub
 	defer probeSub.Unsubscribe()
 
+	controlSub, err := w.cfg.NATSConn.Subscribe(executorControlSubject(w.prefix, w.cfg.WorkerID), w.handleControlRequest)
+	if err != nil {
+		return fmt.Errorf("subscribe control subject: %w", err)
+	}
+	w.controlSub = controlSub
+	defer controlSub.Unsubscribe()
+
 	// Determine subjects to consume.
 	subjects := w.buildSubjects()
 	slog.Info("executor worker: starting",
 		"worker_id", w.cfg.WorkerID,
 		"subjects", subjects,
+		"labels", w.cfg.Labels,
+		"drivers", w.cfg.Drivers,
 		"max_concurrent", w.cfg.MaxConcurrent)
 
-	// Create durable consumer with queue group for load balancing.
-	consumer, err := w.js.CreateOrUpdateConsumer(ctx, w.prefix+"_invocations", buildExecutorConsumerConfig(w.prefix, w.cfg.MaxConcurrent, subjects))
+	// Shared durable consumer with queue semantics for untargeted runs.
+	shared, err := w.js.CreateOrUpdateConsumer(ctx, w.prefix+"_invocations", buildExecutorConsumerConfig(w.prefix, w.cfg.MaxConcurrent, subjects))
 	if err != nil {
 		return fmt.Errorf("create consumer: %w", err)
 	}
+	// Per-worker durable consumer for runs the server routed here explicitly.
+	targeted, err := w.js.CreateOrUpdateConsumer(ctx, w.prefix+"_invocations", buildTargetedConsumerConfig(w.prefix, w.cfg.WorkerID, w.cfg.MaxConcurrent))
+	if err != nil {
+		return fmt.Errorf("create targeted consumer: %w", err)
+	}
 
-	// Consume messages with concurrency control.
+	w.publishHeartbeat()
+	go w.heartbeatLoop(ctx)
+
+	// Consume messages with concurrency control shared by both consumers.
 	sem := make(chan struct{}, w.cfg.MaxConcurrent)
-	for {
+	var loops sync.WaitGroup
+	loops.Add(2)
+	go func() {
+		defer loops.Done()
+		w.consumeLoop(ctx, targeted, sem, false)
+	}()
+	go func() {
+		defer loops.Done()
+		w.consumeLoop(ctx, shared, sem, true)
+	}()
+
+	drained := w.waitDrained(ctx)
+	cancel()
+	loops.Wait()
+	w.wg.Wait()
+	if drained {
+		slog.Info("executor worker: drained, exiting", "worker_id", w.cfg.WorkerID)
+		return nil
+	}
+	return ctx.Err()
+}
+
+// consumeLoop fetches invocations one at a time while a concurrency slot is free.
+// Cordoned or draining workers stop pulling from the shared pool but still
+// accept runs already routed to them explicitly.
+func (w *ExecutorWorker) consumeLoop(ctx context.Context, consumer jetstream.Consumer, sem chan struct{}, shared bool) {
+	for ctx.Err() == nil {
+		if shared && !w.schedulable() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
 		msgs, err := consumer.Fetch(1, jetstream.FetchMaxWait(5*time.Second))
 		if err != nil {
-			if ctx.Err() != nil {
-				return ctx.Err()
-			}
+			<-sem
 			continue
 		}
-
+		received := false
 		for msg := range msgs.Messages() {
-			sem <- struct{}{}
+			received = true
 			w.wg.Add(1)
 			go func(m jetstream.Msg) {
 				defer func() {
@@ -176,14 +273,116 @@ func (w *ExecutorWorker) Start(ctx context.Context) error {
 				w.handleMessage(ctx, m)
 			}(msg)
 		}
+		if !received {
+			<-sem
+		}
+	}
+}
 
-		if ctx.Err() != nil {
-			w.wg.Wait()
-			return ctx.Err()
+// waitDrained blocks until ctx is done (false) or the worker is draining with
+// no active runs left (true).
+func (w *ExecutorWorker) waitDrained(ctx context.Context) bool {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			w.mu.Lock()
+			done := w.draining && len(w.invocations) == 0
+			w.mu.Unlock()
+			if done {
+				return true
+			}
 		}
 	}
 }
 
+func (w *ExecutorWorker) schedulable() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.cordoned && !w.draining
+}
+
+func (w *ExecutorWorker) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.publishHeartbeat()
+		}
+	}
+}
+
+func (w *ExecutorWorker) heartbeat() executorHeartbeat {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	hb := executorHeartbeat{
+		WorkerID:     w.cfg.WorkerID,
+		Labels:       w.cfg.Labels,
+		Drivers:      w.cfg.Drivers,
+		SandboxKinds: w.cfg.SandboxKinds,
+		Capacity:     w.cfg.MaxConcurrent,
+		Cordoned:     w.cordoned,
+		Draining:     w.draining,
+		StartedAt:    w.startedAt,
+		SentAt:       time.Now().UTC(),
+	}
+	for inv, runID := range w.invocations {
+		hb.ActiveInvocations = append(hb.ActiveInvocations, inv)
+		hb.ActiveRuns = append(hb.ActiveRuns, runID)
+	}
+	return hb
+}
+
+func (w *ExecutorWorker) publishHeartbeat() {
+	data, err := json.Marshal(w.heartbeat())
+	if err != nil {
+		slog.Error("executor worker: marshal heartbeat failed", "error", err)
+		return
+	}
+	if err := w.cfg.NATSConn.Publish(executorHeartbeatSubject(w.prefix, w.cfg.WorkerID), data); err != nil {
+		slog.Warn("executor worker: publish heartbeat failed", "error", err)
+	}
+}
+
+func (w *ExecutorWorker) handleControlRequest(msg *nats.Msg) {
+	var req executorControlMessage
+	reply := executorControlReply{}
+	if err := json.Unmarshal(msg.Data, &req); err != nil {
+		reply.Error = fmt.Sprintf("invalid control request: %v", err)
+	} else {
+		w.mu.Lock()
+		switch req.Action {
+		case runtimeapp.ExecutorCordon:
+			w.cordoned = true
+		case runtimeapp.ExecutorDrain:
+			w.cordoned = true
+			w.draining = true
+		case runtimeapp.ExecutorUncordon:
+			w.cordoned = false
+			w.draining = false
+		default:
+			reply.Error = fmt.Sprintf("unknown action %q", req.Action)
+		}
+		reply.Cordoned = w.cordoned
+		reply.Draining = w.draining
+		w.mu.Unlock()
+		if reply.Error == "" {
+			slog.Info("executor worker: control applied", "worker_id", w.cfg.WorkerID, "action", req.Action)
+		}
+	}
+	data, _ := json.Marshal(reply)
+	if err := msg.Respond(data); err != nil {
+		slog.Error("executor worker: respond control failed", "error", err)
+	}
+	w.publishHeartbeat()
+}
+
 func (w *ExecutorWorker) buildSubjects() []string {
 	if len(w.cfg.AgentTypes) == 0 {
 		return []string{fmt.Sprintf("%s.invocation.submit.>", w.prefix)}
@@ -210,6 +409,16 @@ func buildExecutorConsumerConfig(prefix string, maxConcurrent int, subjects []st
 	return cfg
 }
 
+func buildTargetedConsumerConfig(prefix string, workerID string, maxConcurrent int) jetstream.ConsumerConfig {
+	return jetstream.ConsumerConfig{
+		Durable:       prefix + "_executor_" + natsToken(workerID),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		MaxAckPending: maxConcurrent,
+		AckWait:       10 * time.Minute,
+		FilterSubject: executorTargetSubject(prefix, workerID),
+	}
+}
+
 func (w *ExecutorWorker) handleMessage(ctx context.Context, msg jetstream.Msg) {
 	var invocation natsInvocationMessage
 	if err := json.Unmarshal(msg.Data(), &invocation); err != nil {
@@ -220,7 +429,16 @@ func (w *ExecutorWorker) handleMessage(ctx context.Context, msg jetstream.Msg) {
 	invocation.normalize()
 
 	slog.Info("executor worker: executing run",
-		"run_id", invocation.RunID, "agent", invocation.AgentID, "workitem_id", invocation.WorkItemID)
+		"run_id", invocation.RunID, "agent", invocation.AgentID, "workitem_id", invocation.WorkItemID, "attempt", invocation.Attempt)
+
+	w.mu.Lock()
+	w.invocations[invocation.InvocationID] = invocation.RunID
+	w.mu.Unlock()
+	defer func() {
+		w.mu.Lock()
+		delete(w.invocations, invocation.InvocationID)
+		w.mu.Unlock()
+	}()
 
 	// Create event forwarder that publishes to NATS.
 	eventSeq := int64(0)
@@ -237,6 +455,8 @@ func (w *ExecutorWorker) handleMessage(ctx context.Context, msg jetstream.Msg) {
 	// Publish result.
 	resultMsg := natsInvocationResult{
 		InvocationID: invocation.InvocationID,
+		WorkerID:     w.cfg.WorkerID,
+		Attempt:      invocation.Attempt,
 	}
 	if execErr != nil {
 		resultMsg.Error = execErr.Error()
@@ -294,9 +514,10 @@ func (w *ExecutorWorker) executeRun(ctx context.Context, invocation *natsInvocat
 	sandboxedLaunch := launchCfg
 	if !acpclient.UsesInProcAdapterProfile(profile) {
 		sandboxedLaunch, err = sb.Prepare(ctx, v2sandbox.PrepareInput{
-			Profile: profile,
-			Launch:  launchCfg,
-			Scope:   fmt.Sprintf("workitem-%d-run-%d", invocation.WorkItemID, invocation.RunID),
+			Profile:    profile,
+			Launch:     launchCfg,
+			Scope:      fmt.Sprintf("workitem-%d-run-%d", invocation.WorkItemID, invocation.RunID),
+			WorkItemID: invocation.WorkItemID,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("prepare sandbox: %w", err)
@@ -532,6 +753,9 @@ func (w *ExecutorWorker) Stop() {
 	if w.probeSub != nil {
 		_ = w.probeSub.Unsubscribe()
 	}
+	if w.controlSub != nil {
+		_ = w.controlSub.Unsubscribe()
+	}
 	w.wg.Wait()
 	if w.pool != nil {
 		w.pool.Close()
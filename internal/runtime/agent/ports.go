@@ -19,4 +19,5 @@ type ThreadSessionStore interface {
 	core.WorkItemStore
 	core.ProjectStore
 	core.ResourceSpaceStore
+	core.AgentContextStore
 }
@@ -48,6 +48,7 @@ type localHandle struct {
 	workDir    string
 	mcpServers []acpproto.McpServer
 	reuse      bool
+	acquire    acpSessionAcquireInput
 	workItemID int64
 	actionID   int64
 	runID      int64
@@ -106,6 +107,7 @@ func (m *LocalSessionManager) Acquire(ctx context.Context, in runtimeapp.Session
 			Scope:           scope,
 			ExtraSkills:     in.ExtraSkills,
 			EphemeralSkills: in.EphemeralSkills,
+			WorkItemID:      in.WorkItemID,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("prepare sandbox: %w", err)
@@ -128,7 +130,7 @@ func (m *LocalSessionManager) Acquire(ctx context.Context, in runtimeapp.Session
 	}
 
 	if in.Reuse && m.pool != nil {
-		sess, ac, err := m.pool.Acquire(ctx, acpSessionAcquireInput{
+		lh.acquire = acpSessionAcquireInput{
 			Profile:    in.Profile,
 			Launch:     sandboxedLaunch,
 			Caps:       in.Caps,
@@ -139,7 +141,8 @@ func (m *LocalSessionManager) Acquire(ctx context.Context, in runtimeapp.Session
 			RunID:      in.RunID,
 			IdleTTL:    in.IdleTTL,
 			MaxTurns:   in.MaxTurns,
-		})
+		}
+		sess, ac, err := m.pool.Acquire(ctx, lh.acquire)
 		if err != nil {
 			return nil, err
 		}
@@ -248,14 +251,6 @@ func (m *LocalSessionManager) StartRun(ctx context.Context, handle *runtimeapp.S
 }
 
 func (m *LocalSessionManager) executeRun(ctx context.Context, lh *localHandle, text string, inv *localInvocation) (*runtimeapp.RunResult, error) {
-	// Capture events for the invocation record.
-	collector := &eventCollector{inv: inv, mu: &m.mu}
-
-	if lh.events != nil {
-		lh.events.Set(collector)
-		defer lh.events.Set(nil)
-	}
-
 	var client *acpclient.Client
 	var unlock func()
 	if lh.reuse && lh.pooled != nil {
@@ -265,10 +260,29 @@ func (m *LocalSessionManager) executeRun(ctx context.Context, lh *localHandle, t
 	} else {
 		client = lh.standalone
 	}
+	defer func() {
+		if unlock != nil {
+			unlock()
+		}
+	}()
 
 	// Pre-run token budget check (reuse sessions only).
 	if lh.reuse && lh.pooled != nil && m.pool != nil && lh.profile != nil {
 		status := m.pool.CheckTokenBudget(lh.pooled, lh.profile)
+		if status != TokenBudgetOK && m.pool.CompactionEnabled() {
+			next, ac, err := m.pool.Compact(ctx, lh.pooled, lh.agentCtx, lh.acquire)
+			if err != nil {
+				slog.Warn("context compaction failed", "agent", lh.profile.ID, "workitem_id", lh.workItemID, "error", err)
+			} else {
+				unlock()
+				next.mu.Lock()
+				unlock = next.mu.Unlock
+				lh.pooled, lh.agentCtx = next, ac
+				lh.sessionID, lh.events = next.sessionID, next.events
+				client = next.client
+				status = TokenBudgetOK
+			}
+		}
 		if status == TokenBudgetExceeded {
 			input, output := m.pool.SessionTokenUsage(lh.pooled)
 			return nil, fmt.Errorf("token budget exceeded for agent %s (used %d input + %d output, limit %d): %w",
@@ -285,7 +299,22 @@ func (m *LocalSessionManager) executeRun(ctx context.Context, lh *localHandle, t
 		}
 	}
 
-	result, err := client.PromptText(ctx, lh.sessionID, text)
+	// Capture events for the invocation record.
+	collector := &eventCollector{inv: inv, mu: &m.mu}
+
+	if lh.events != nil {
+		lh.events.Set(collector)
+		defer lh.events.Set(nil)
+	}
+
+	promptText := text
+	if lh.reuse && lh.pooled != nil {
+		promptText = lh.pooled.takeSeed() + text
+	}
+	result, err := client.PromptText(ctx, lh.sessionID, promptText)
+	if err == nil && lh.reuse && lh.pooled != nil {
+		lh.pooled.history.add(text, result.Text)
+	}
 	if unlock != nil {
 		unlock()
 		unlock = nil
@@ -30,6 +30,9 @@ type NATSSessionManagerConfig struct {
 	// Used as a prefix in invocation IDs to avoid collisions across servers.
 	// Auto-generated from hostname + PID if empty.
 	ServerID string
+
+	// Fleet tunes executor heartbeat health thresholds.
+	Fleet ExecutorFleetConfig
 }
 
 // NATSSessionManager implements SessionManager using NATS JetStream.
@@ -38,24 +41,40 @@ type NATSSessionManagerConfig struct {
 //
 // Subject layout:
 //
-//	{prefix}.invocation.submit.{agent_type}  — run submission (consumed by executors)
-//	{prefix}.invocation.result.{invocation_id}   — final result
-//	{prefix}.invocation.events.{invocation_id}   — streaming events during a run
-//	{prefix}.executor.register           — executor heartbeat/registration
+//	{prefix}.invocation.submit.{agent_type}     — untargeted run submission (shared executor pool)
+//	{prefix}.invocation.target.{worker_id}      — run routed to one executor by the fleet registry
+//	{prefix}.invocation.result.{invocation_id}  — final result
+//	{prefix}.invocation.events.{invocation_id}  — streaming events during a run
+//	{prefix}.executor.heartbeat.{worker_id}     — executor registration + heartbeat
+//	{prefix}.executor.control.{worker_id}       — cordon/drain requests (request-reply)
 type NATSSessionManager struct {
 	nc       *nats.Conn
 	js       jetstream.JetStream
 	prefix   string
 	serverID string
+	fleet    *ExecutorFleetRegistry
+
+	mu       sync.Mutex
+	handles  map[string]*natsHandle
+	inflight map[string]*natsInflight
+	nextID   int64
 
-	mu      sync.Mutex
-	handles map[string]*natsHandle
-	nextID  int64
+	heartbeatSub *nats.Subscription
+	stopSweep    chan struct{}
+	closeOnce    sync.Once
 
 	activeCount atomic.Int32
 	drainWg     sync.WaitGroup
 }
 
+// natsInflight remembers a dispatched invocation so it can be re-routed when
+// the executor running it stops heartbeating.
+type natsInflight struct {
+	msg      natsInvocationMessage
+	explicit bool
+	workerID string
+}
+
 type natsHandle struct {
 	id        string
 	sessionIn runtimeapp.SessionAcquireInput
@@ -74,6 +93,11 @@ type natsInvocationMessage struct {
 	AgentID   string `json:"agent_id"`
 	ProfileID string `json:"profile_id"`
 	WorkDir   string `json:"work_dir"`
+
+	// Requirements are the routing constraints the target executor satisfied.
+	Requirements map[string]string `json:"requirements,omitempty"`
+	// Attempt increases each time the invocation is reassigned to another executor.
+	Attempt int `json:"attempt,omitempty"`
 }
 
 func (m *natsInvocationMessage) normalize() {
@@ -95,6 +119,8 @@ type natsInvocationResult struct {
 	ModelID          string `json:"model_id,omitempty"`
 	AgentContextID   *int64 `json:"agent_context_id,omitempty"`
 	Error            string `json:"error,omitempty"`
+	WorkerID         string `json:"worker_id,omitempty"`
+	Attempt          int    `json:"attempt,omitempty"`
 }
 
 // natsEventMessage wraps a streaming event for NATS transport.
@@ -130,25 +156,40 @@ func NewNATSSessionManager(cfg NATSSessionManagerConfig) (*NATSSessionManager, e
 	}
 
 	m := &NATSSessionManager{
-		nc:       cfg.NATSConn,
-		js:       js,
-		prefix:   prefix,
-		serverID: serverID,
-		handles:  make(map[string]*natsHandle),
+		nc:        cfg.NATSConn,
+		js:        js,
+		prefix:    prefix,
+		serverID:  serverID,
+		fleet:     NewExecutorFleetRegistry(cfg.NATSConn, prefix, cfg.Fleet),
+		handles:   make(map[string]*natsHandle),
+		inflight:  make(map[string]*natsInflight),
+		stopSweep: make(chan struct{}),
 	}
 
 	if err := m.ensureStreams(context.Background()); err != nil {
 		return nil, fmt.Errorf("ensure JetStream streams: %w", err)
 	}
 
+	sub, err := cfg.NATSConn.Subscribe(executorHeartbeatSubject(prefix, "*"), m.handleHeartbeat)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe executor heartbeats: %w", err)
+	}
+	m.heartbeatSub = sub
+	go m.sweepLoop(m.fleet.cfg.StaleAfter / 3)
+
 	return m, nil
 }
 
+// Fleet returns the executor registry fed by executor heartbeats.
+func (m *NATSSessionManager) Fleet() *ExecutorFleetRegistry {
+	return m.fleet
+}
+
 func (m *NATSSessionManager) ensureStreams(ctx context.Context) error {
 	// Run submission stream — consumed by executor workers.
 	_, err := m.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
 		Name:      m.prefix + "_invocations",
-		Subjects:  []string{m.prefix + ".invocation.submit.>"},
+		Subjects:  []string{m.prefix + ".invocation.submit.>", m.prefix + ".invocation.target.>"},
 		Retention: jetstream.WorkQueuePolicy,
 		MaxAge:    24 * time.Hour,
 		Storage:   jetstream.FileStorage,
@@ -229,31 +270,160 @@ func (m *NATSSessionManager) StartRun(ctx context.Context, handle *runtimeapp.Se
 	if nh.sessionIn.Profile != nil {
 		msg.ProfileID = strings.TrimSpace(nh.sessionIn.Profile.ID)
 	}
+	explicit := len(nh.sessionIn.Requirements) > 0
+	msg.Requirements = invocationRequirements(nh.sessionIn)
 
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return "", fmt.Errorf("marshal run message: %w", err)
-	}
-
-	subject := fmt.Sprintf("%s.invocation.submit.%s", m.prefix, agentType)
-	_, err = m.js.Publish(ctx, subject, data)
+	workerID, err := m.dispatch(ctx, &msg, explicit)
 	if err != nil {
-		return "", fmt.Errorf("publish run to NATS: %w", err)
+		return "", err
 	}
+	m.mu.Lock()
+	m.inflight[invocationID] = &natsInflight{msg: msg, explicit: explicit, workerID: workerID}
+	m.mu.Unlock()
 
 	m.activeCount.Add(1)
 	m.drainWg.Add(1)
 
 	slog.Info("nats session manager: run dispatched",
-		"run_id", msg.RunID, "agent", agentType, "workitem_id", msg.WorkItemID)
+		"run_id", msg.RunID, "agent", agentType, "workitem_id", msg.WorkItemID, "executor", workerID)
 
 	return invocationID, nil
 }
 
+// invocationRequirements merges explicit action requirements with the
+// profile's driver so executors lacking the driver binary are never picked.
+func invocationRequirements(in runtimeapp.SessionAcquireInput) map[string]string {
+	reqs := make(map[string]string, len(in.Requirements)+1)
+	for k, v := range in.Requirements {
+		if k = strings.TrimSpace(k); k != "" && strings.TrimSpace(v) != "" {
+			reqs[k] = strings.TrimSpace(v)
+		}
+	}
+	if in.Profile != nil && reqs[runtimeapp.ExecutorRequirementDriver] == "" {
+		if driverID := strings.TrimSpace(in.Profile.DriverID); driverID != "" {
+			reqs[runtimeapp.ExecutorRequirementDriver] = driverID
+		}
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+	return reqs
+}
+
+// dispatch publishes msg to the best matching executor's target subject.
+// When no registered executor qualifies, untargeted runs fall back to the
+// shared submit subject; runs with explicit requirements fail instead.
+func (m *NATSSessionManager) dispatch(ctx context.Context, msg *natsInvocationMessage, explicit bool, exclude ...string) (string, error) {
+	subject := fmt.Sprintf("%s.invocation.submit.%s", m.prefix, msg.AgentID)
+	workerID, ok := m.fleet.Pick(msg.InvocationID, msg.Requirements, exclude...)
+	if ok {
+		subject = executorTargetSubject(m.prefix, workerID)
+	} else if explicit {
+		return "", fmt.Errorf("%w for requirements %v", runtimeapp.ErrNoEligibleExecutor, msg.Requirements)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return "", fmt.Errorf("marshal run message: %w", err)
+	}
+	if _, err := m.js.Publish(ctx, subject, data); err != nil {
+		if workerID != "" {
+			m.fleet.Release(workerID, msg.InvocationID)
+		}
+		return "", fmt.Errorf("publish run to NATS: %w", err)
+	}
+	return workerID, nil
+}
+
+func (m *NATSSessionManager) handleHeartbeat(msg *nats.Msg) {
+	var hb executorHeartbeat
+	if err := json.Unmarshal(msg.Data, &hb); err != nil {
+		slog.Warn("nats session manager: invalid executor heartbeat", "error", err)
+		return
+	}
+	m.fleet.Observe(hb)
+}
+
+func (m *NATSSessionManager) sweepLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stopSweep:
+			return
+		case <-ticker.C:
+			for _, workerID := range m.fleet.SweepOffline() {
+				m.reassignFrom(workerID)
+			}
+		}
+	}
+}
+
+// reassignFrom re-dispatches every in-flight invocation owned by a lost executor.
+func (m *NATSSessionManager) reassignFrom(lostWorker string) {
+	m.mu.Lock()
+	var moved []*natsInflight
+	for id, inf := range m.inflight {
+		owner := inf.workerID
+		if owner == "" {
+			owner = m.fleet.OwnerOf(id)
+		}
+		if owner == lostWorker {
+			moved = append(moved, inf)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, inf := range moved {
+		m.mu.Lock()
+		inf.msg.Attempt++
+		msg := inf.msg
+		m.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		workerID, err := m.dispatch(ctx, &msg, inf.explicit, lostWorker)
+		cancel()
+		if err != nil {
+			slog.Error("nats session manager: reassign run failed",
+				"run_id", msg.RunID, "invocation_id", msg.InvocationID, "lost_executor", lostWorker, "error", err)
+			continue
+		}
+		m.mu.Lock()
+		inf.workerID = workerID
+		m.mu.Unlock()
+		slog.Warn("nats session manager: run reassigned",
+			"run_id", msg.RunID, "invocation_id", msg.InvocationID,
+			"lost_executor", lostWorker, "executor", workerID, "attempt", msg.Attempt)
+	}
+}
+
+func (m *NATSSessionManager) finishInflight(invocationID string) {
+	m.mu.Lock()
+	inf := m.inflight[invocationID]
+	delete(m.inflight, invocationID)
+	m.mu.Unlock()
+	if inf != nil && inf.workerID != "" {
+		m.fleet.Release(inf.workerID, invocationID)
+	}
+}
+
+func (m *NATSSessionManager) currentAttempt(invocationID string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if inf := m.inflight[invocationID]; inf != nil {
+		return inf.msg.Attempt
+	}
+	return 0
+}
+
 // WatchRun subscribes to the result and event subjects for a given invocation.
 // It blocks until the result is received or ctx is cancelled.
 func (m *NATSSessionManager) WatchRun(ctx context.Context, invocationID string, lastEventSeq int64, sink runtimeapp.EventSink) (*runtimeapp.RunResult, error) {
 	defer func() {
+		m.finishInflight(invocationID)
 		m.activeCount.Add(-1)
 		m.drainWg.Done()
 	}()
@@ -303,6 +473,11 @@ func (m *NATSSessionManager) WatchRun(ctx context.Context, invocationID string,
 			}
 			_ = msg.Ack()
 
+			if result.Attempt < m.currentAttempt(invocationID) {
+				slog.Warn("nats watch: ignoring result from superseded attempt",
+					"invocation_id", invocationID, "executor", result.WorkerID, "attempt", result.Attempt)
+				continue
+			}
 			if result.Error != "" {
 				return nil, fmt.Errorf("remote run failed: %s", result.Error)
 			}
@@ -445,9 +620,15 @@ func (m *NATSSessionManager) ActiveCount() int {
 	return int(m.activeCount.Load())
 }
 
-// Close drains the NATS connection.
+// Close stops fleet tracking and drains the NATS connection.
 func (m *NATSSessionManager) Close() {
-	if m.nc != nil {
-		m.nc.Drain()
-	}
+	m.closeOnce.Do(func() {
+		close(m.stopSweep)
+		if m.heartbeatSub != nil {
+			_ = m.heartbeatSub.Unsubscribe()
+		}
+		if m.nc != nil {
+			m.nc.Drain()
+		}
+	})
 }
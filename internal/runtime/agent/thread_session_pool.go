@@ -40,6 +40,13 @@ type threadPooledSession struct {
 	turns        int
 	inputTokens  int64
 	outputTokens int64
+
+	// summary is the compacted context the session was booted with; history
+	// holds the exchanges since then. contextBase is the share of the
+	// cumulative token counters consumed by earlier, compacted sessions.
+	summary     string
+	history     conversationLog
+	contextBase int64
 }
 
 // TokenGenerator creates scoped tokens for agent signal APIs.
@@ -62,6 +69,10 @@ type ThreadSessionPool struct {
 	serverAddr    string
 	tokenRegistry TokenGenerator
 
+	// compactor summarises agents nearing their context budget so they can
+	// continue in a fresh session. Nil keeps the warning-only behavior.
+	compactor *ContextCompactor
+
 	mu       sync.Mutex
 	sessions map[threadSessionKey]*threadPooledSession
 
@@ -111,6 +122,14 @@ func (p *ThreadSessionPool) SetThreadSharedBootTemplate(template string) {
 	p.threadSharedBootTemplate = strings.TrimSpace(template)
 }
 
+// SetContextCompactor enables automatic context compaction for thread agents.
+func (p *ThreadSessionPool) SetContextCompactor(compactor *ContextCompactor) {
+	if p == nil {
+		return
+	}
+	p.compactor = compactor
+}
+
 func updateThreadAgentStatus(m *core.ThreadMember, next core.ThreadAgentStatus) error {
 	if m == nil {
 		return fmt.Errorf("thread member is nil")
@@ -256,11 +275,39 @@ func (p *ThreadSessionPool) backgroundContext() context.Context {
 
 func (p *ThreadSessionPool) bootSession(ctx context.Context, member *core.ThreadMember, profile *core.AgentProfile, priorSummary string, priorSessionID string) (*core.ThreadMember, error) {
 	key := threadSessionKey{threadID: member.ThreadID, agentID: profile.ID}
-	workspaceDir, scopeCfg, err := p.prepareThreadWorkspace(ctx, member.ThreadID)
+	pooled, err := p.startThreadSession(ctx, member.ThreadID, profile, priorSummary, priorSessionID)
 	if err != nil {
 		_ = updateThreadAgentStatus(member, core.ThreadAgentFailed)
 		_ = p.store.UpdateThreadMember(ctx, member)
-		return member, fmt.Errorf("prepare thread workspace: %w", err)
+		p.publishThreadEvent(ctx, core.EventThreadAgentFailed, member.ThreadID, profile.ID, map[string]any{"error": err.Error()})
+		return member, err
+	}
+
+	memberSetAgentData(member, "acp_session_id", string(pooled.sessionID))
+
+	p.mu.Lock()
+	p.sessions[key] = pooled
+	p.mu.Unlock()
+
+	// Update DB to active.
+	if err := updateThreadAgentStatus(member, core.ThreadAgentActive); err != nil {
+		return nil, err
+	}
+	_ = p.store.UpdateThreadMember(ctx, member)
+
+	p.publishThreadEvent(ctx, core.EventThreadAgentBooted, member.ThreadID, profile.ID, nil)
+	p.publishThreadEvent(ctx, core.EventThreadAgentJoined, member.ThreadID, profile.ID, nil)
+
+	slog.Info("thread pool: agent joined", "thread_id", member.ThreadID, "profile", profile.ID, "session_id", string(pooled.sessionID))
+	return member, nil
+}
+
+// startThreadSession prepares the thread workspace, launches the agent and
+// sends the boot prompt. The returned session is not yet registered in the pool.
+func (p *ThreadSessionPool) startThreadSession(ctx context.Context, threadID int64, profile *core.AgentProfile, priorSummary string, priorSessionID string) (*threadPooledSession, error) {
+	workspaceDir, scopeCfg, err := p.prepareThreadWorkspace(ctx, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("prepare thread workspace: %w", err)
 	}
 
 	// Install thread-scoped skills into workspace agent home directories.
@@ -269,14 +316,14 @@ func (p *ThreadSessionPool) bootSession(ctx context.Context, member *core.Thread
 	// Build extra env vars for signal callback.
 	extraEnv := map[string]string{}
 	if p.tokenRegistry != nil && p.serverAddr != "" {
-		scope := fmt.Sprintf("thread:%d:agent:%s", member.ThreadID, profile.ID)
+		scope := fmt.Sprintf("thread:%d:agent:%s", threadID, profile.ID)
 		tok, tokErr := p.tokenRegistry.GenerateScopedToken(
-			fmt.Sprintf("thread-agent-%d-%s", member.ThreadID, profile.ID),
+			fmt.Sprintf("thread-agent-%d-%s", threadID, profile.ID),
 			[]string{scope},
-			fmt.Sprintf("thread/%d", member.ThreadID),
+			fmt.Sprintf("thread/%d", threadID),
 		)
 		if tokErr != nil {
-			slog.Warn("thread pool: failed to generate signal token", "thread_id", member.ThreadID, "error", tokErr)
+			slog.Warn("thread pool: failed to generate signal token", "thread_id", threadID, "error", tokErr)
 		} else {
 			extraEnv["AI_WORKFLOW_SERVER_ADDR"] = p.serverAddr
 			extraEnv["AI_WORKFLOW_API_TOKEN"] = tok
@@ -284,7 +331,7 @@ func (p *ThreadSessionPool) bootSession(ctx context.Context, member *core.Thread
 	}
 
 	bridge := eventbridge.New(p.bus, core.EventThreadAgentOutput, eventbridge.Scope{
-		SessionID: fmt.Sprintf("thread-%d-%s", member.ThreadID, profile.ID),
+		SessionID: fmt.Sprintf("thread-%d-%s", threadID, profile.ID),
 	})
 	switcher := &switchingEventHandler{}
 	switcher.Set(bridge)
@@ -308,18 +355,13 @@ func (p *ThreadSessionPool) bootSession(ctx context.Context, member *core.Thread
 		},
 	})
 	if err != nil {
-		_ = updateThreadAgentStatus(member, core.ThreadAgentFailed)
-		_ = p.store.UpdateThreadMember(ctx, member)
-		p.publishThreadEvent(ctx, core.EventThreadAgentFailed, member.ThreadID, profile.ID, map[string]any{"error": err.Error()})
-		return member, err
+		return nil, err
 	}
 	client := bootResult.Client
 	acpSessionID := bootResult.Session.ID
 
-	memberSetAgentData(member, "acp_session_id", string(acpSessionID))
-
 	// Build boot prompt.
-	bootPrompt, err := p.buildBootPrompt(ctx, member.ThreadID, profile, priorSummary)
+	bootPrompt, err := p.buildBootPrompt(ctx, threadID, profile, priorSummary)
 	if err != nil {
 		slog.Warn("thread pool: build boot prompt failed, proceeding without", "error", err)
 	}
@@ -331,33 +373,18 @@ func (p *ThreadSessionPool) bootSession(ctx context.Context, member *core.Thread
 		_, err = client.PromptText(bootCtx, acpSessionID, bootPrompt)
 		bridge.FlushPending(ctx)
 		if err != nil {
-			slog.Warn("thread pool: boot prompt failed", "thread_id", member.ThreadID, "profile", profile.ID, "error", err)
+			slog.Warn("thread pool: boot prompt failed", "thread_id", threadID, "profile", profile.ID, "error", err)
 		}
 	}
 
-	pooled := &threadPooledSession{
+	return &threadPooledSession{
 		client:    client,
 		sessionID: acpSessionID,
 		events:    switcher,
 		bridge:    bridge,
+		summary:   strings.TrimSpace(priorSummary),
 		lastUsed:  time.Now().UTC(),
-	}
-
-	p.mu.Lock()
-	p.sessions[key] = pooled
-	p.mu.Unlock()
-
-	// Update DB to active.
-	if err := updateThreadAgentStatus(member, core.ThreadAgentActive); err != nil {
-		return nil, err
-	}
-	_ = p.store.UpdateThreadMember(ctx, member)
-
-	p.publishThreadEvent(ctx, core.EventThreadAgentBooted, member.ThreadID, profile.ID, nil)
-	p.publishThreadEvent(ctx, core.EventThreadAgentJoined, member.ThreadID, profile.ID, nil)
-
-	slog.Info("thread pool: agent joined", "thread_id", member.ThreadID, "profile", profile.ID, "session_id", string(acpSessionID))
-	return member, nil
+	}, nil
 }
 
 func (p *ThreadSessionPool) buildBootPrompt(ctx context.Context, threadID int64, profile *core.AgentProfile, priorSummary string) (string, error) {
@@ -564,10 +591,18 @@ func (p *ThreadSessionPool) PromptAgent(ctx context.Context, threadID int64, pro
 	}
 
 	pooled.mu.Lock()
-	defer pooled.mu.Unlock()
-	if pooled.closing {
-		return nil, fmt.Errorf("session for profile %q in thread %d is closing", profileID, threadID)
+	for pooled.closing {
+		// A compaction may have swapped in a fresh session while we waited.
+		next := p.sessionForKey(key)
+		if next == nil || next == pooled {
+			pooled.mu.Unlock()
+			return nil, fmt.Errorf("session for profile %q in thread %d is closing", profileID, threadID)
+		}
+		pooled.mu.Unlock()
+		pooled = next
+		pooled.mu.Lock()
 	}
+	defer func() { pooled.mu.Unlock() }()
 
 	promptCtx, cancel := context.WithTimeout(ctx, 120*time.Second)
 	defer cancel()
@@ -599,13 +634,19 @@ func (p *ThreadSessionPool) PromptAgent(ctx context.Context, threadID int64, pro
 		}
 	}
 
+	pooled.history.add(message, reply)
+
 	// Persist token usage periodically (every 5 turns or on demand).
 	if pooled.turns%5 == 0 {
 		p.persistTokenUsage(ctx, threadID, profileID, pooled)
 	}
 
-	// Check context budget warning.
-	p.checkContextBudget(ctx, threadID, profileID, pooled)
+	// Compact (or warn) when the session nears its context budget.
+	if next := p.checkContextBudget(ctx, threadID, profileID, pooled); next != nil {
+		pooled.mu.Unlock()
+		pooled = next
+		pooled.mu.Lock()
+	}
 
 	return &core.ThreadAgentPromptResult{
 		Content:      reply,
@@ -837,16 +878,20 @@ func (p *ThreadSessionPool) persistTokenUsage(ctx context.Context, threadID int6
 	}
 }
 
-func (p *ThreadSessionPool) checkContextBudget(ctx context.Context, threadID int64, profileID string, pooled *threadPooledSession) {
+// checkContextBudget compacts the session when it crosses the profile's
+// context warning threshold, falling back to a system warning when compaction
+// is unavailable. It returns the replacement session, if any. Caller holds
+// pooled.mu.
+func (p *ThreadSessionPool) checkContextBudget(ctx context.Context, threadID int64, profileID string, pooled *threadPooledSession) *threadPooledSession {
 	// Look up the profile to check MaxContextTokens.
 	profile, err := p.registry.ResolveByID(ctx, profileID)
 	if err != nil || profile == nil {
-		return
+		return nil
 	}
 
 	maxTokens := profile.Session.MaxContextTokens
 	if maxTokens <= 0 {
-		return
+		return nil
 	}
 
 	warnRatio := profile.Session.ContextWarnRatio
@@ -854,22 +899,202 @@ func (p *ThreadSessionPool) checkContextBudget(ctx context.Context, threadID int
 		warnRatio = 0.8
 	}
 
-	totalUsed := pooled.inputTokens + pooled.outputTokens
+	totalUsed := pooled.inputTokens + pooled.outputTokens - pooled.contextBase
 	threshold := int64(float64(maxTokens) * warnRatio)
+	if totalUsed < threshold {
+		return nil
+	}
 
-	if totalUsed >= threshold {
-		// Publish system warning.
-		p.bus.Publish(ctx, core.Event{
-			Type: core.EventThreadMessage,
-			Data: map[string]any{
-				"thread_id": threadID,
-				"type":      "system_warning",
-				"sender_id": "system",
-				"message":   fmt.Sprintf("Agent %s is approaching context budget limit (%d/%d tokens, %.0f%%)", profileID, totalUsed, maxTokens, float64(totalUsed)/float64(maxTokens)*100),
-			},
-			Timestamp: time.Now().UTC(),
-		})
+	if p.compactor.Enabled() {
+		next, err := p.compactSession(ctx, threadID, profile, pooled, totalUsed)
+		if err == nil {
+			return next
+		}
+		slog.Warn("thread pool: context compaction failed", "thread_id", threadID, "profile", profileID, "error", err)
+	}
+
+	// Publish system warning.
+	p.bus.Publish(ctx, core.Event{
+		Type: core.EventThreadMessage,
+		Data: map[string]any{
+			"thread_id": threadID,
+			"type":      "system_warning",
+			"sender_id": "system",
+			"message":   fmt.Sprintf("Agent %s is approaching context budget limit (%d/%d tokens, %.0f%%)", profileID, totalUsed, maxTokens, float64(totalUsed)/float64(maxTokens)*100),
+		},
+		Timestamp: time.Now().UTC(),
+	})
+	return nil
+}
+
+// compactSession summarises the agent's conversation, persists the summary as
+// an AgentContext and replaces the pooled session with a fresh one seeded with
+// the summary and the thread's pinned context. Caller holds pooled.mu; the old
+// session is marked closing and shut down.
+func (p *ThreadSessionPool) compactSession(ctx context.Context, threadID int64, profile *core.AgentProfile, pooled *threadPooledSession, usedTokens int64) (*threadPooledSession, error) {
+	key := threadSessionKey{threadID: threadID, agentID: profile.ID}
+	member := p.findActiveAgentMember(ctx, threadID, profile.ID)
+	if member == nil {
+		return nil, fmt.Errorf("no active member for profile %q in thread %d", profile.ID, threadID)
+	}
+
+	summary, err := p.compactor.Summarize(ctx, compactionRequest{
+		AgentID:      profile.ID,
+		Scope:        fmt.Sprintf("thread %d", threadID),
+		PriorSummary: pooled.summary,
+		Turns:        pooled.history.snapshot(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := p.startThreadSession(ctx, threadID, profile, summary, "")
+	if err != nil {
+		return nil, fmt.Errorf("start compacted session: %w", err)
+	}
+	next.turns = pooled.turns
+	next.inputTokens = pooled.inputTokens
+	next.outputTokens = pooled.outputTokens
+	next.contextBase = pooled.inputTokens + pooled.outputTokens
+
+	p.mu.Lock()
+	if p.sessions[key] != pooled {
+		p.mu.Unlock()
+		_ = next.client.Close(context.Background())
+		return nil, fmt.Errorf("session for profile %q in thread %d was replaced", profile.ID, threadID)
+	}
+	p.sessions[key] = next
+	p.mu.Unlock()
+
+	pooled.closing = true
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	_ = pooled.client.Close(closeCtx)
+	cancel()
+
+	agentCtxID := p.saveCompactedContext(ctx, member, profile.ID, summary, string(next.sessionID))
+	previousSessionID := string(pooled.sessionID)
+	compactions := memberGetInt(member, "context_compactions") + 1
+	memberSetAgentData(member, "acp_session_id", string(next.sessionID))
+	memberSetAgentData(member, "progress_summary", summary)
+	memberSetAgentData(member, "context_compactions", compactions)
+	memberSetAgentData(member, "turn_count", next.turns)
+	memberSetAgentData(member, "total_input_tokens", next.inputTokens)
+	memberSetAgentData(member, "total_output_tokens", next.outputTokens)
+	if agentCtxID > 0 {
+		memberSetAgentData(member, "agent_context_id", agentCtxID)
+	}
+	_ = p.store.UpdateThreadMember(ctx, member)
+
+	maxTokens := profile.Session.MaxContextTokens
+	metadata := map[string]any{
+		"type":                "context_compacted",
+		"profile_id":          profile.ID,
+		"previous_session_id": previousSessionID,
+		"session_id":          string(next.sessionID),
+		"used_tokens":         usedTokens,
+		"max_tokens":          maxTokens,
+	}
+	if agentCtxID > 0 {
+		metadata["agent_context_id"] = agentCtxID
+	}
+	p.publishThreadSystemMessage(ctx, threadID, fmt.Sprintf(
+		"Agent %s reached %d/%d context tokens; its conversation was summarised and it continues in a fresh session.",
+		profile.ID, usedTokens, maxTokens), metadata)
+
+	p.compactor.recordJournal(ctx, &core.JournalEntry{
+		Summary: fmt.Sprintf("context compacted for agent %s in thread %d", profile.ID, threadID),
+		Payload: map[string]any{
+			"thread_id":           threadID,
+			"agent_id":            profile.ID,
+			"agent_context_id":    agentCtxID,
+			"previous_session_id": previousSessionID,
+			"session_id":          string(next.sessionID),
+			"used_tokens":         usedTokens,
+			"max_tokens":          maxTokens,
+			"summary":             summary,
+		},
+		Ref:   fmt.Sprintf("thread/%d", threadID),
+		Actor: profile.ID,
+	})
+
+	slog.Info("thread pool: context compacted", "thread_id", threadID, "profile", profile.ID,
+		"previous_session_id", previousSessionID, "session_id", string(next.sessionID), "used_tokens", usedTokens)
+	return next, nil
+}
+
+func (p *ThreadSessionPool) findActiveAgentMember(ctx context.Context, threadID int64, profileID string) *core.ThreadMember {
+	members, err := p.store.ListThreadMembers(ctx, threadID)
+	if err != nil {
+		return nil
 	}
+	for _, m := range members {
+		if m.Kind == core.ThreadMemberKindAgent && m.AgentProfileID == profileID && (m.Status == core.ThreadAgentActive || m.Status == core.ThreadAgentBooting) {
+			return m
+		}
+	}
+	return nil
+}
+
+// saveCompactedContext persists the summary on the member's thread-scoped
+// AgentContext, creating it on first compaction. Returns 0 on failure.
+func (p *ThreadSessionPool) saveCompactedContext(ctx context.Context, member *core.ThreadMember, profileID string, summary string, sessionID string) int64 {
+	now := time.Now().UTC()
+	if id := memberGetInt64(member, "agent_context_id"); id > 0 {
+		if ac, err := p.store.GetAgentContext(ctx, id); err == nil {
+			ac.Summary = summary
+			ac.SessionID = sessionID
+			ac.TurnCount = 0
+			ac.UpdatedAt = now
+			if err := p.store.UpdateAgentContext(ctx, ac); err != nil {
+				slog.Warn("thread pool: update agent context failed", "thread_id", member.ThreadID, "profile", profileID, "error", err)
+			}
+			return ac.ID
+		}
+	}
+	id, err := p.store.CreateAgentContext(ctx, &core.AgentContext{
+		AgentID:   profileID,
+		SessionID: sessionID,
+		Summary:   summary,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		slog.Warn("thread pool: create agent context failed", "thread_id", member.ThreadID, "profile", profileID, "error", err)
+		return 0
+	}
+	return id
+}
+
+func (p *ThreadSessionPool) publishThreadSystemMessage(ctx context.Context, threadID int64, content string, metadata map[string]any) {
+	msg := &core.ThreadMessage{
+		ThreadID: threadID,
+		SenderID: "system",
+		Role:     "system",
+		Content:  content,
+		Metadata: metadata,
+	}
+	id, err := p.store.CreateThreadMessage(ctx, msg)
+	if err != nil {
+		slog.Warn("thread pool: persist system message failed", "thread_id", threadID, "error", err)
+		return
+	}
+	msg.ID = id
+	if p.bus == nil {
+		return
+	}
+	p.bus.Publish(ctx, core.Event{
+		Type: core.EventThreadMessage,
+		Data: map[string]any{
+			"thread_id":  threadID,
+			"message_id": msg.ID,
+			"message":    msg.Content,
+			"content":    msg.Content,
+			"sender_id":  msg.SenderID,
+			"role":       msg.Role,
+			"metadata":   metadata,
+		},
+		Timestamp: time.Now().UTC(),
+	})
 }
 
 func (p *ThreadSessionPool) publishThreadEvent(ctx context.Context, eventType core.EventType, threadID int64, profileID string, extra map[string]any) {
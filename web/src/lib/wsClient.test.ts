@@ -1,4 +1,4 @@
-import { describe, expect, test } from "vitest";
+import { describe, expect, test, vi } from "vitest";
 import { WsClient } from "./wsClient";
 
 class FakeWebSocket {
@@ -73,4 +73,47 @@ describe("WsClient", () => {
 
     expect(received).toEqual({ ok: true, count: 3 });
   });
+
+  test("resumes from the last seen seq after reconnect and overflow", () => {
+    vi.useFakeTimers();
+    FakeWebSocket.createdUrls = [];
+    FakeWebSocket.instances = [];
+
+    const client = new WsClient(
+      { baseUrl: "http://127.0.0.1:8080/api", reconnectIntervalMs: 10 },
+      FakeWebSocket as unknown as new (url: string) => WebSocket,
+    );
+    client.connect();
+    expect(FakeWebSocket.createdUrls[0]).not.toContain("since_seq");
+
+    const first = FakeWebSocket.instances[0];
+    first.open();
+    first.emitMessage(JSON.stringify({ type: "work_item.started", seq: 7, data: {} }));
+    expect(client.getLastSeq()).toBe(7);
+    client.send({ type: "subscribe_thread", data: { thread_id: 3 } });
+
+    first.emitMessage(
+      JSON.stringify({
+        type: "stream.overflow",
+        data: { from_seq: 8, to_seq: 12, dropped: 5 },
+      }),
+    );
+    vi.advanceTimersByTime(10);
+    expect(FakeWebSocket.createdUrls).toHaveLength(2);
+    expect(FakeWebSocket.createdUrls[1]).toContain("since_seq=7");
+    expect(FakeWebSocket.createdUrls[1]).toContain("thread_ids=3");
+
+    const second = FakeWebSocket.instances[1];
+    second.open();
+    second.emitMessage(
+      JSON.stringify({
+        type: "stream.resync",
+        data: { reason: "too_far_behind", since_seq: 7, last_seq: 4000 },
+      }),
+    );
+    expect(client.getLastSeq()).toBe(4000);
+
+    client.disconnect();
+    vi.useRealTimers();
+  });
 });
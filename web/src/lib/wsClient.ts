@@ -6,7 +6,12 @@ export type ConnectionStatusHandler = (status: ConnectionStatus) => void;
 
 type WebSocketFactory = new (url: string) => WebSocket;
 
-const toWsUrl = (baseUrl: string, token?: string | null): string => {
+const toWsUrl = (
+  baseUrl: string,
+  token?: string | null,
+  sinceSeq?: number,
+  threadIds?: Iterable<number>,
+): string => {
   const url = (() => {
     if (/^wss?:\/\//.test(baseUrl) || /^https?:\/\//.test(baseUrl)) {
       return new URL(baseUrl);
@@ -33,6 +38,21 @@ const toWsUrl = (baseUrl: string, token?: string | null): string => {
     url.searchParams.delete("token");
   }
 
+  if (sinceSeq && sinceSeq > 0) {
+    url.searchParams.set("since_seq", String(sinceSeq));
+  } else {
+    url.searchParams.delete("since_seq");
+  }
+
+  // Subscribed threads are named up front so their missed events are
+  // replayed along with the rest.
+  const threads = threadIds ? Array.from(threadIds) : [];
+  if (threads.length > 0) {
+    url.searchParams.set("thread_ids", threads.join(","));
+  } else {
+    url.searchParams.delete("thread_ids");
+  }
+
   return url.toString();
 };
 
@@ -45,6 +65,11 @@ export class WsClient {
   private socket: WebSocket | null = null;
   private reconnectTimer: ReturnType<typeof setTimeout> | null = null;
   private reconnectAttempt = 0;
+  // Highest event sequence seen; reconnects resume from here so the server
+  // replays what was missed while disconnected.
+  private lastSeq = 0;
+  // Threads subscribed on this connection, re-announced on reconnect.
+  private readonly threadIds = new Set<number>();
   private manuallyClosed = false;
   private status: ConnectionStatus = "idle";
   private readonly listeners = new Map<string, Set<WsEventHandler>>();
@@ -87,6 +112,9 @@ export class WsClient {
     }
     const payload = typeof data === "string" ? data : JSON.stringify(data);
     this.socket.send(payload);
+    if (typeof data !== "string") {
+      this.trackThreadSubscription(data);
+    }
   }
 
   subscribe<TPayload = unknown>(
@@ -120,9 +148,28 @@ export class WsClient {
     return this.status;
   }
 
+  getLastSeq(): number {
+    return this.lastSeq;
+  }
+
+  private trackThreadSubscription(envelope: WsEnvelope): void {
+    const threadId = (envelope.data as { thread_id?: unknown } | undefined)
+      ?.thread_id;
+    if (typeof threadId !== "number") {
+      return;
+    }
+    if (envelope.type === "subscribe_thread") {
+      this.threadIds.add(threadId);
+    } else if (envelope.type === "unsubscribe_thread") {
+      this.threadIds.delete(threadId);
+    }
+  }
+
   private openSocket(): void {
     const token = this.getToken?.();
-    const socket = new this.wsFactory(toWsUrl(this.baseUrl, token));
+    const socket = new this.wsFactory(
+      toWsUrl(this.baseUrl, token, this.lastSeq, this.threadIds),
+    );
     this.socket = socket;
 
     socket.onopen = () => {
@@ -163,12 +210,28 @@ export class WsClient {
     }
 
     if (envelope && typeof envelope.type === "string") {
+      if (typeof envelope.seq === "number" && envelope.seq > this.lastSeq) {
+        this.lastSeq = envelope.seq;
+      }
+      if (envelope.type === "stream.resync") {
+        // The gap is too large to replay; listeners reload state and the
+        // stream continues from the server's current position.
+        const lastSeq = (envelope.data as { last_seq?: unknown } | undefined)
+          ?.last_seq;
+        if (typeof lastSeq === "number") {
+          this.lastSeq = lastSeq;
+        }
+      }
       this.emit(
         envelope.type,
         envelope.data !== undefined ? envelope.data : envelope.payload,
         event,
       );
       this.emit("*", envelope, event);
+      if (envelope.type === "stream.overflow") {
+        // Events were skipped server-side; reconnect to replay the gap.
+        this.socket?.close();
+      }
       return;
     }
 
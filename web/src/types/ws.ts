@@ -2,6 +2,8 @@ import type { Notification } from "./apiV2";
 
 export interface WsEnvelope<TPayload = unknown> {
   type: string;
+  /** Bus sequence number; used to resume the stream after a reconnect. */
+  seq?: number;
   run_id?: string;
   project_id?: string;
   work_item_id?: string;